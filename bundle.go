@@ -0,0 +1,157 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	"github.com/clastix/talos-csr-signer/pkg/bundle"
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/events"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/policy"
+	"github.com/clastix/talos-csr-signer/pkg/server"
+	"github.com/clastix/talos-csr-signer/pkg/token"
+)
+
+// loadBundle returns the configuration bundle at the configured path, along with the configuration overridden by its
+// settings: without a bundle, the configuration is returned as is.
+func loadBundle(ctx context.Context, v *viper.Viper, cfg *config.Config) (*bundle.Bundle, *config.Config, error) {
+	path := cfg.Bundle.Path
+	if path == "" {
+		return nil, cfg, nil
+	}
+
+	configBundle, err := bundle.Load(path)
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck
+	}
+
+	overridden, err := config.Override(v, configBundle.Settings())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "bundle "+path)
+	}
+
+	logging.FromContext(ctx).Info("Loaded the configuration bundle", "path", path)
+
+	return configBundle, overridden, nil
+}
+
+// newBundleBackend returns the signing backend of the CA held by the bundle, replaced when the bundle is reloaded:
+// nil when the bundle doesn't hold the CA.
+func newBundleBackend(configBundle *bundle.Bundle) (*backend.Reloadable, error) {
+	if configBundle == nil || !configBundle.HasCA() {
+		return nil, nil //nolint:nilnil
+	}
+
+	local, err := loadBundleBackend(configBundle)
+	if err != nil {
+		return nil, err
+	}
+
+	return backend.NewReloadable(local), nil
+}
+
+// loadBundleBackend returns the local backend signing with the CA held by the bundle.
+func loadBundleBackend(configBundle *bundle.Bundle) (*backend.Local, error) {
+	caPrivateKey, err := parsePrivateKey(configBundle.CAPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return backend.NewLocal("local", configBundle.CACertificate, caPrivateKey) //nolint:wrapcheck
+}
+
+// loadBundleCA returns the CA certificate and its private key held by the bundle.
+func loadBundleCA(configBundle *bundle.Bundle) (*x509.Certificate, crypto.Signer, error) {
+	caPrivateKey, err := parsePrivateKey(configBundle.CAPrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	local, err := backend.NewLocal("local", configBundle.CACertificate, caPrivateKey)
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck
+	}
+
+	return local.Certificate(), caPrivateKey, nil
+}
+
+// watchBundle reloads the configuration bundle when modified until the context is done, replacing the CA, the tokens,
+// the signing policy, and the profiles the server issues the certificates with. A bundle failing to load is ignored,
+// keeping the previous one.
+func watchBundle(ctx context.Context, v *viper.Viper, cfg *config.Config, current *bundle.Bundle, srv *server.Server, ca *backend.Reloadable, validators []policy.Validator) {
+	path := cfg.Bundle.Path
+
+	bundle.Watch(ctx, path, cfg.Bundle.ReloadInterval, current, func(configBundle *bundle.Bundle) {
+		if err := reloadBundle(ctx, v, configBundle, srv, ca, validators); err != nil {
+			logging.FromContext(ctx).Warn("Failed to reload the configuration bundle, keeping the previous one", "path", path,
+				"error", err)
+
+			return
+		}
+
+		logging.FromContext(ctx).Info("Reloaded the configuration bundle", "path", path)
+	})
+}
+
+// reloadBundle replaces the CA, the tokens, the signing policy, and the profiles of the server with the ones of the
+// bundle, once all of them are valid.
+func reloadBundle(ctx context.Context, v *viper.Viper, configBundle *bundle.Bundle, srv *server.Server, ca *backend.Reloadable, validators []policy.Validator) error {
+	cfg, err := config.Override(v, configBundle.Settings())
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	signingPolicy, roles, err := newSigningPolicy(cfg, srv.Ledger, validators)
+	if err != nil {
+		return err
+	}
+
+	var tokens *token.Set
+
+	if configBundle.Tokens != "" && srv.Tokens != nil {
+		if tokens, err = token.Parse([]byte(configBundle.Tokens)); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	var local *backend.Local
+
+	if ca != nil {
+		if !configBundle.HasCA() {
+			return errors.Wrap(pkgerrors.ErrBundle, "the CA cannot be removed from the bundle while serving")
+		}
+
+		if local, err = loadBundleBackend(configBundle); err != nil {
+			return err
+		}
+	}
+
+	srv.Reload(signingPolicy, roles)
+
+	if tokens != nil {
+		srv.Tokens.Update(tokens)
+	}
+
+	if local != nil && !local.Certificate().Equal(ca.Certificate()) {
+		ca.Replace(local)
+
+		logging.FromContext(ctx).Info("Reloaded the signing CA", "serial", local.Certificate().SerialNumber.Text(16))
+		srv.Events.Emit(events.Event{
+			Type:    events.TypeCAReloaded,
+			Serial:  local.Certificate().SerialNumber.Text(16),
+			Backend: local.Name(),
+		})
+	}
+
+	return nil
+}
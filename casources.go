@@ -0,0 +1,184 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	"github.com/clastix/talos-csr-signer/pkg/bundle"
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// caSources are the sources the CA may be held by in place of the CA files, nil when not configured. Vault, AWS KMS,
+// and the upstream signer are configured by their settings only, their backend being built once validated.
+type caSources struct {
+	plugins      *loadedPlugins
+	configBundle *bundle.Bundle
+	bundle       *backend.Reloadable
+	secret       *secretCA
+	pkcs12       *pkcs12CA
+	cloud        *cloudCA
+	env          *envCA
+}
+
+// startupFiles returns the mounted files the server waits for at startup: the serving certificate, along with the
+// files of the CA source.
+func startupFiles(cfg *config.Config, plugins *loadedPlugins) []string {
+	paths := []string{
+		cfg.Server.TLSCertificatePath,
+		cfg.Server.TLSPrivateKeyPath,
+	}
+
+	switch {
+	case cfg.Bundle.Path != "":
+		paths = append(paths, cfg.Bundle.Path)
+	case cfg.CA.PKCS12Path != "":
+		paths = append(paths, cfg.CA.PKCS12Path)
+	case cfg.CA.CertificateB64 != "":
+		// The CA is held by the settings, with no file to wait for
+	case plugins.signer == nil && cfg.Vault.Address == "" && cfg.KMS.KeyARN == "" && cfg.CA.SecretRef == "" && cfg.CA.Source == "" && cfg.Upstream.Endpoint == "":
+		paths = append(paths, cfg.CA.CertificatePath, cfg.CA.PrivateKeyPath)
+	case cfg.KMS.KeyARN != "", cfg.Upstream.Endpoint != "":
+		paths = append(paths, cfg.CA.CertificatePath)
+	}
+
+	if fallbackKeyPath := cfg.CA.FallbackPrivateKeyPath; fallbackKeyPath != "" {
+		paths = append(paths, fallbackKeyPath, cfg.CA.FallbackCertificatePath)
+	}
+
+	return paths
+}
+
+// loadCASources reads the CA of the configured sources, applying the Talos tokens of the secret of the CA source to
+// the configuration. The sources are not validated: see validate.
+func loadCASources(ctx context.Context, cfg *config.Config, configBundle *bundle.Bundle, plugins *loadedPlugins) (*caSources, error) {
+	sources := &caSources{plugins: plugins, configBundle: configBundle}
+
+	var err error
+
+	if sources.bundle, err = newBundleBackend(configBundle); err != nil {
+		return nil, err
+	}
+
+	if sources.secret, err = newSecretCA(ctx, cfg.CA.SecretRef); err != nil {
+		return nil, err
+	}
+
+	if sources.pkcs12, err = loadPKCS12CA(ctx, cfg.CA.PKCS12Path); err != nil {
+		return nil, err
+	}
+
+	if sources.cloud, err = newCloudCA(ctx, cfg.CA.Source, cfg.KMS); err != nil {
+		return nil, err
+	}
+
+	if sources.cloud != nil {
+		if err = sources.cloud.applyTokens(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if sources.env, err = loadEnvCA(ctx, cfg.CA.CertificateB64, cfg.CA.PrivateKeyB64); err != nil {
+		return nil, err
+	}
+
+	return sources, nil
+}
+
+// validate checks the CA is held by one source at most, and the CA chain is read from one place only.
+func (s *caSources) validate(cfg *config.Config) error {
+	plugin, vault, kms, upstream := s.plugins.signer != nil, cfg.Vault.Address != "", cfg.KMS.KeyARN != "", cfg.Upstream.Endpoint != ""
+
+	switch {
+	case s.env != nil && (plugin || s.bundle != nil || vault || kms || s.secret != nil || s.pkcs12 != nil || s.cloud != nil || upstream):
+		return errors.Wrap(pkgerrors.ErrConfig, "the CA cannot be held by both the base64 encoded settings and the signer plugin, the configuration bundle, Vault, AWS KMS, the Kubernetes Secret, the PKCS#12 bundle, the secret of the CA source, or the upstream signer")
+	case s.cloud != nil && (plugin || s.bundle != nil || vault || kms || s.secret != nil || s.pkcs12 != nil || upstream):
+		return errors.Wrap(pkgerrors.ErrConfig, "the CA cannot be held by both the secret "+cfg.CA.Source+" and the signer plugin, the configuration bundle, Vault, AWS KMS, the Kubernetes Secret, the PKCS#12 bundle, or the upstream signer")
+	case s.pkcs12 != nil && (plugin || s.bundle != nil || vault || kms || s.secret != nil || upstream):
+		return errors.Wrap(pkgerrors.ErrConfig, "the CA cannot be held by both the PKCS#12 bundle and the signer plugin, the configuration bundle, Vault, AWS KMS, the Kubernetes Secret, or the upstream signer")
+	case s.pkcs12 != nil && len(s.pkcs12.chain) > 0 && cfg.CA.ChainPath != "":
+		return errors.Wrap(pkgerrors.ErrConfig, "the CA chain cannot be read from both the PKCS#12 bundle and "+config.KeyCAChainPath)
+	case s.secret != nil && (plugin || s.bundle != nil || vault || kms):
+		return errors.Wrap(pkgerrors.ErrConfig, "the CA cannot be held by both the Kubernetes Secret and the signer plugin, the bundle, Vault, or AWS KMS")
+	case upstream && (plugin || s.bundle != nil || vault || kms || s.secret != nil):
+		return errors.Wrap(pkgerrors.ErrConfig, "the CSRs cannot be both forwarded to the upstream signer and signed with the CA held elsewhere")
+	case plugin && s.bundle != nil:
+		return errors.Wrap(pkgerrors.ErrBundle, "the CA cannot be held by both the signer plugin and the bundle")
+	case vault && (plugin || s.bundle != nil):
+		return errors.Wrap(pkgerrors.ErrConfig, "the CA cannot be held by both Vault and the signer plugin or the bundle")
+	case kms && (plugin || s.bundle != nil || vault):
+		return errors.Wrap(pkgerrors.ErrConfig, "the CA cannot be held by both AWS KMS and the signer plugin, the bundle, or Vault")
+	}
+
+	return nil
+}
+
+// backend returns the backend of the validated source holding the CA, along with the name of its holder and the
+// function releasing it, nil when the CA is held by the CA files.
+func (s *caSources) backend(ctx context.Context, cfg *config.Config) (backend.Backend, string, func(), error) {
+	noop := func() {}
+
+	switch {
+	case s.env != nil:
+		return s.env.ca, "base64 encoded settings " + config.KeyCACertificateB64 + " and " + config.KeyCAPrivateKeyB64, noop, nil
+	case s.pkcs12 != nil:
+		return s.pkcs12.ca, "PKCS#12 bundle " + cfg.CA.PKCS12Path, noop, nil
+	case s.cloud != nil:
+		return s.cloud.ca, "secret " + cfg.CA.Source, noop, nil
+	case s.plugins.signer != nil:
+		return s.plugins.signer, "signer plugin", noop, nil
+	case s.bundle != nil:
+		return s.bundle, "configuration bundle", noop, nil
+	case cfg.Vault.Address != "":
+		vault, err := newVaultBackend(ctx, cfg.Vault)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		return vault, "Vault PKI secrets engine", noop, nil
+	case cfg.KMS.KeyARN != "":
+		kms, err := newKMSBackend(ctx, cfg.KMS, cfg.CA.CertificatePath)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		return kms, "AWS KMS key " + cfg.KMS.KeyARN, noop, nil
+	case s.secret != nil:
+		return s.secret.ca, "Kubernetes Secret " + cfg.CA.SecretRef, noop, nil
+	case cfg.Upstream.Endpoint != "":
+		upstream, err := newUpstreamBackend(ctx, cfg.Upstream, cfg.CA.CertificatePath)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		return upstream, "upstream signer " + cfg.Upstream.Endpoint, func() { _ = upstream.Close() }, nil
+	}
+
+	return nil, "", noop, nil
+}
+
+// signingKey returns the CA certificate and its private key, such as to sign the CRL, read from the source holding
+// them or from the CA files.
+func (s *caSources) signingKey(ctx context.Context, cfg *config.Config) (*x509.Certificate, crypto.Signer, error) {
+	switch {
+	case s.bundle != nil:
+		return loadBundleCA(s.configBundle)
+	case s.secret != nil:
+		return s.secret.load(ctx)
+	case s.env != nil:
+		return s.env.ca.Certificate(), s.env.key, nil
+	case s.pkcs12 != nil:
+		return s.pkcs12.ca.Certificate(), s.pkcs12.key, nil
+	case s.cloud != nil:
+		return s.cloud.load(ctx)
+	default:
+		return loadCA(cfg.CA.CertificatePath, cfg.CA.PrivateKeyPath)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+	"github.com/clastix/talos-csr-signer/pkg/server"
+)
+
+const (
+	// caURLTimeout bounds each request to the artifact server.
+	caURLTimeout = 30 * time.Second
+	// caURLMaxSize is the largest CA bundle read from the artifact server.
+	caURLMaxSize = 1 << 20
+)
+
+// urlBundle is the CA bundle of an artifact server, returned to the nodes along with the signing CA, and pinned by
+// its SHA-256 checksums: the one of the current bundle, and the ones of the next bundles accepted ahead of an update.
+type urlBundle struct {
+	url    string
+	pins   []string
+	client *http.Client
+	// data is the last bundle fetched.
+	data []byte
+}
+
+// newURLBundle fetches the CA bundle of the URL, nil when not configured.
+func newURLBundle(ctx context.Context, cfg config.CA) (*urlBundle, error) {
+	if cfg.CertURL == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	b := &urlBundle{url: cfg.CertURL, client: &http.Client{Timeout: caURLTimeout}}
+
+	for _, pin := range cfg.CertURLSHA256 {
+		pin = strings.ToLower(strings.TrimPrefix(pin, "sha256:"))
+		if decoded, err := hex.DecodeString(pin); err != nil || len(decoded) != sha256.Size {
+			return nil, errors.Wrap(pkgerrors.ErrConfig, "invalid "+config.KeyCACertURLSHA256+" "+pin+", expected a hex encoded SHA-256 checksum")
+		}
+
+		b.pins = append(b.pins, pin)
+	}
+
+	data, err := b.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b.data = data
+
+	logging.FromContext(ctx).Info("Returning the CA bundle along with the signing CA", "url", b.url)
+
+	return b, nil
+}
+
+// fetch returns the CA bundle of the URL, once its checksum matched one of the pins and its certificates parsed.
+func (b *urlBundle) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.url, nil)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrCABundleURL, err.Error())
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrCABundleURL, err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Wrap(pkgerrors.ErrCABundleURL, fmt.Sprintf("GET %s: %s", b.url, resp.Status))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, caURLMaxSize+1))
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrCABundleURL, err.Error())
+	}
+
+	if len(data) > caURLMaxSize {
+		return nil, errors.Wrap(pkgerrors.ErrCABundleURL, fmt.Sprintf("the CA bundle %s is larger than %d bytes", b.url, caURLMaxSize))
+	}
+
+	sum := sha256.Sum256(data)
+	if checksum := hex.EncodeToString(sum[:]); !slices.Contains(b.pins, checksum) {
+		return nil, errors.Wrap(pkgerrors.ErrCABundleURL, "the CA bundle "+b.url+" doesn't match the pinned checksums, its checksum being "+checksum)
+	}
+
+	if _, err = pki.ParseCertificates(data); err != nil {
+		return nil, errors.Wrap(err, "CA bundle "+b.url)
+	}
+
+	return data, nil
+}
+
+// refresh fetches the CA bundle again at every interval until the context is done, replacing the one returned to
+// the nodes when updated. A bundle failing to be fetched or to match the pins is ignored, keeping the previous one.
+func (b *urlBundle) refresh(ctx context.Context, srv *server.Server, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		data, err := b.fetch(ctx)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to refresh the CA bundle, keeping the previous one", "url", b.url, "error", err)
+
+			continue
+		}
+
+		if bytes.Equal(data, b.data) {
+			continue
+		}
+
+		b.data = data
+		srv.ReloadTrustBundle(data)
+
+		logging.FromContext(ctx).Info("Reloaded the CA bundle", "url", b.url)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	"github.com/clastix/talos-csr-signer/pkg/cloudsecret"
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/events"
+	"github.com/clastix/talos-csr-signer/pkg/kms"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/server"
+	"github.com/clastix/talos-csr-signer/pkg/token"
+)
+
+// cloudSecretTimeout bounds each request to the secret manager.
+const cloudSecretTimeout = 30 * time.Second
+
+// cloudCA is the CA held by a secret of AWS Secrets Manager or GCP Secret Manager, along with the Talos tokens,
+// replaced while serving when the secret is updated.
+type cloudCA struct {
+	uri  string
+	opts cloudsecret.Options
+	ca   *backend.Reloadable
+	// tokens are the Talos tokens of the token key of the secret, in the format of the token files, nil when none.
+	tokens []byte
+}
+
+// newCloudCA reads the CA of the secret of the CA source, nil when not configured. The AWS credentials are the ones of
+// the shared credentials file of the AWS KMS settings when set.
+func newCloudCA(ctx context.Context, uri string, kmsCfg config.KMS) (*cloudCA, error) {
+	if uri == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	client := &http.Client{Timeout: cloudSecretTimeout}
+	c := &cloudCA{uri: uri, opts: cloudsecret.Options{Client: client}}
+
+	if kmsCfg.CredentialsPath != "" {
+		c.opts.AWSCredentials = &kms.CredentialsProvider{
+			Region:  kmsCfg.Region,
+			Client:  client,
+			File:    kmsCfg.CredentialsPath,
+			Profile: kmsCfg.Profile,
+		}
+	}
+
+	secret, err := cloudsecret.Fetch(ctx, uri, c.opts)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	local, _, err := parseCAKeys(secret, pkgerrors.ErrCloudSecret, "secret "+uri)
+	if err != nil {
+		return nil, err
+	}
+
+	c.ca, c.tokens = backend.NewReloadable(local), secret["token"]
+
+	logging.FromContext(ctx).Info("Loaded the CA from the secret", "uri", uri, "serial", local.Certificate().SerialNumber.Text(16))
+
+	return c, nil
+}
+
+// applyTokens makes the Talos tokens of the secret the ones of the configuration, which cannot also read them from
+// a file.
+func (c *cloudCA) applyTokens(cfg *config.Config) error {
+	if len(c.tokens) == 0 {
+		return nil
+	}
+
+	if cfg.Tokens.Path != "" {
+		return errors.Wrap(pkgerrors.ErrConfig, "the Talos token cannot be read from both the secret "+c.uri+" and "+config.KeyTalosTokenPath)
+	}
+
+	cfg.Tokens.Token = string(c.tokens)
+
+	return nil
+}
+
+// load reads the CA certificate and its private key from the secret.
+func (c *cloudCA) load(ctx context.Context) (*x509.Certificate, crypto.Signer, error) {
+	secret, err := cloudsecret.Fetch(ctx, c.uri, c.opts)
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck
+	}
+
+	local, caPrivateKey, err := parseCAKeys(secret, pkgerrors.ErrCloudSecret, "secret "+c.uri)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return local.Certificate(), caPrivateKey, nil
+}
+
+// refresh reads the secret again at every interval until the context is done, replacing the CA and the Talos tokens
+// when updated. A secret failing to be read or to parse is ignored, keeping the previous ones.
+func (c *cloudCA) refresh(ctx context.Context, srv *server.Server, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := c.reload(ctx, srv); err != nil {
+			logging.FromContext(ctx).Warn("Failed to refresh the CA from the secret, keeping the previous one", "uri", c.uri,
+				"error", err)
+		}
+	}
+}
+
+// reload replaces the CA and the Talos tokens of the server with the ones of the secret, once both are valid.
+func (c *cloudCA) reload(ctx context.Context, srv *server.Server) error {
+	secret, err := cloudsecret.Fetch(ctx, c.uri, c.opts)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	local, _, err := parseCAKeys(secret, pkgerrors.ErrCloudSecret, "secret "+c.uri)
+	if err != nil {
+		return err
+	}
+
+	var tokens *token.Set
+
+	if len(c.tokens) > 0 && srv.Tokens != nil && !bytes.Equal(secret["token"], c.tokens) {
+		if tokens, err = token.Parse(secret["token"]); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	if tokens != nil {
+		srv.Tokens.Update(tokens)
+		c.tokens = secret["token"]
+
+		logging.FromContext(ctx).Info("Reloaded the tokens from the secret", "uri", c.uri)
+	}
+
+	if local.Certificate().Equal(c.ca.Certificate()) {
+		return nil
+	}
+
+	c.ca.Replace(local)
+
+	logging.FromContext(ctx).Info("Reloaded the signing CA from the secret", "uri", c.uri,
+		"serial", local.Certificate().SerialNumber.Text(16))
+	srv.Events.Emit(events.Event{
+		Type:    events.TypeCAReloaded,
+		Serial:  local.Certificate().SerialNumber.Text(16),
+		Backend: local.Name(),
+	})
+
+	return nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+
+	"github.com/clastix/talos-csr-signer/pkg/clock"
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/events"
+	"github.com/clastix/talos-csr-signer/pkg/features"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+	pb "github.com/clastix/talos-csr-signer/pkg/proto"
+)
+
+// clusterServer is a cluster served by the process along with the top level one, on its own listener.
+type clusterServer struct {
+	name       string
+	ledger     ledger.Ledger
+	grpcServer *grpc.Server
+	listener   net.Listener
+	closeCA    func()
+}
+
+// newClusterServer returns the server of the cluster, with its own CA, tokens, ledger, and policy, listening on its
+// port. The plugins serve the top level cluster only, while the feature gates and the events bus are shared.
+func newClusterServer(ctx context.Context, cluster config.Cluster, gates *features.Gates, bus *events.Bus) (*clusterServer, error) {
+	configBundle, cfg, err := loadBundle(ctx, cluster.Settings, cluster.Config)
+	if err != nil {
+		return nil, errors.Wrap(err, "cluster "+cluster.Name)
+	}
+
+	policyFile, cfg, err := loadPolicyFile(ctx, cluster.Settings, cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "cluster "+cluster.Name)
+	}
+
+	sources, err := loadCASources(ctx, cfg, configBundle, &loadedPlugins{})
+	if err != nil {
+		return nil, errors.Wrap(err, "cluster "+cluster.Name)
+	}
+
+	if err = sources.validate(cfg); err != nil {
+		return nil, errors.Wrap(err, "cluster "+cluster.Name)
+	}
+
+	heldCA, _, closeCA, err := sources.backend(ctx, cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "cluster "+cluster.Name)
+	}
+
+	// Release the backend of the CA, such as the connection to the upstream signer, when the cluster is not served
+	defer func() {
+		if err != nil {
+			closeCA()
+		}
+	}()
+
+	signingBackend, err := newSigningBackend(cfg.CA, heldCA)
+	if err != nil {
+		return nil, errors.Wrap(err, "cluster "+cluster.Name)
+	}
+
+	if signingBackend, err = newHybridBackend(ctx, cfg.CA, gates, signingBackend); err != nil {
+		return nil, errors.Wrap(err, "cluster "+cluster.Name)
+	}
+
+	creds, err := newServerCredentials(cfg.Server)
+	if err != nil {
+		return nil, errors.Wrap(err, "cluster "+cluster.Name)
+	}
+
+	issuanceLedger, err := ledger.New(cfg.Ledger.URL, cfg.Ledger.KeyPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "cluster "+cluster.Name)
+	}
+
+	srv, err := newServer(ctx, cfg, signingBackend, issuanceLedger, sources.plugins)
+	if err != nil {
+		_ = issuanceLedger.Close()
+
+		return nil, errors.Wrap(err, "cluster "+cluster.Name)
+	}
+
+	srv.Features = gates
+	srv.Events = bus
+
+	if sources.pkcs12 != nil && len(sources.pkcs12.chain) > 0 {
+		srv.CAChain = pki.EncodeCertificates(sources.pkcs12.chain...)
+	}
+
+	caURLBundle, err := newURLBundle(ctx, cfg.CA)
+	if err != nil {
+		_ = issuanceLedger.Close()
+
+		return nil, errors.Wrap(err, "cluster "+cluster.Name)
+	}
+
+	if caURLBundle != nil {
+		srv.TrustBundle = caURLBundle.data
+	}
+
+	srv.Logger = logging.FromContext(ctx).With("cluster", cluster.Name)
+
+	if configBundle != nil {
+		go watchBundle(ctx, cluster.Settings, cfg, configBundle, srv, sources.bundle, nil)
+	}
+
+	if policyFile != nil {
+		go watchPolicyFile(ctx, cluster.Settings, cfg, policyFile, srv, nil)
+	}
+
+	if srv.DenyList != nil {
+		go watchDenyList(ctx, cfg, srv)
+	}
+
+	if sources.secret != nil {
+		go sources.secret.watch(ctx, srv)
+	}
+
+	if sources.cloud != nil && cfg.CA.SourceRefreshInterval > 0 {
+		go sources.cloud.refresh(ctx, srv, cfg.CA.SourceRefreshInterval)
+	}
+
+	if caURLBundle != nil && cfg.CA.CertURLRefreshInterval > 0 {
+		go caURLBundle.refresh(ctx, srv, cfg.CA.CertURLRefreshInterval)
+	}
+
+	if srv.Clock, err = clock.NewChecker(signingBackend.Certificate(), cfg.Clock.NTPServer, cfg.Clock.MaxSkew, cfg.Clock.SkewAction); err != nil {
+		_ = issuanceLedger.Close()
+
+		return nil, errors.Wrap(err, "cluster "+cluster.Name)
+	}
+
+	_ = srv.Clock.Check(ctx)
+
+	go srv.Clock.Run(ctx, cfg.Clock.CheckInterval)
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Server.Port))
+	if err != nil {
+		_ = issuanceLedger.Close()
+
+		return nil, errors.Wrap(pkgerrors.ErrServerListen, fmt.Sprintf("cluster %s, %d: %s", cluster.Name, cfg.Server.Port, err.Error()))
+	}
+
+	grpcServer := grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionAge:      cfg.Server.MaxConnectionAge,
+			MaxConnectionAgeGrace: cfg.Server.MaxConnectionAgeGrace,
+		}),
+	)
+	pb.RegisterSecurityServiceServer(grpcServer, srv)
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+
+	return &clusterServer{
+		name:       cluster.Name,
+		ledger:     issuanceLedger,
+		grpcServer: grpcServer,
+		listener:   listener,
+		closeCA:    closeCA,
+	}, nil
+}
+
+// serve serves the nodes of the cluster until the context is done, draining the in-flight requests.
+func (c *clusterServer) serve(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		c.grpcServer.GracefulStop()
+	}()
+
+	logging.FromContext(ctx).Info("Cluster listening with TLS enabled", "cluster", c.name, "address", c.listener.Addr().String())
+
+	if err := c.grpcServer.Serve(c.listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		logging.FromContext(ctx).Error("Failed to serve the cluster", "cluster", c.name,
+			"error", errors.Wrap(pkgerrors.ErrGRPCServerServe, err.Error()))
+	}
+}
+
+// close releases the ledger and the CA backend of the cluster.
+func (c *clusterServer) close() {
+	_ = c.ledger.Close()
+	c.closeCA()
+}
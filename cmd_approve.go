@@ -0,0 +1,130 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/clastix/talos-csr-signer/pkg/admin"
+	"github.com/clastix/talos-csr-signer/pkg/approval"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/events"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+// approverTokenEnv is the environment variable holding the token of the approver.
+const approverTokenEnv = "APPROVER_TOKEN"
+
+// approveRequest is the body of the POST /approvals/{id}/approve admin API endpoint.
+type approveRequest struct {
+	Token string `json:"token"`
+}
+
+// newApproveCommand returns the command approving a privileged certificate request through the admin API.
+func newApproveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "approve [approval-id]",
+		Short: "Approve a privileged certificate request through the admin API",
+		Args:  cobra.ExactArgs(1),
+		// Usage errors are reported before reaching the admin API.
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			adminURL, _ := cmd.Flags().GetString(cliAdminURL)
+
+			approverToken := os.Getenv(approverTokenEnv)
+			if approverToken == "" {
+				return errors.Wrap(pkgerrors.ErrApprover, "the "+approverTokenEnv+" environment variable is required")
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+			defer cancel()
+
+			var response approval.Request
+			if err := callAdmin(ctx, http.MethodPost, adminURL, "/approvals/"+args[0]+"/approve", approveRequest{Token: approverToken}, &response); err != nil {
+				return err
+			}
+
+			logging.FromContext(ctx).Info("Approved the certificate request", "id", response.ID, "common_name", response.CommonName,
+				"approvals", len(response.Approvals), "threshold", response.Threshold)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String(cliAdminURL, "http://127.0.0.1:8080", "Signer admin API URL, authenticated with the ADMIN_TOKEN environment variable, "+
+		"the approver with the "+approverTokenEnv+" one")
+
+	return cmd
+}
+
+// approvalHandler serves the pending privileged certificate request, along with its approvals.
+func approvalHandler(queue *approval.Queue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request, found, err := queue.Get(r.Context(), r.PathValue("id"))
+
+		switch {
+		case err != nil:
+			admin.WriteError(w, http.StatusServiceUnavailable, err)
+		case !found:
+			admin.WriteError(w, http.StatusNotFound, pkgerrors.ErrApprovalNotFound)
+		default:
+			admin.WriteJSON(w, http.StatusOK, request)
+		}
+	}
+}
+
+// approveHandler records the approval of the privileged certificate request by the approver of the token,
+// publishing the event: the certificate is released to the node retrying once the threshold is reached.
+func approveHandler(queue *approval.Queue, bus *events.Bus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body approveRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&body); err != nil {
+			admin.WriteError(w, http.StatusBadRequest, err)
+
+			return
+		}
+
+		request, approver, err := queue.Approve(r.Context(), r.PathValue("id"), body.Token)
+
+		switch {
+		case errors.Is(err, pkgerrors.ErrApprover):
+			admin.WriteError(w, http.StatusForbidden, err)
+
+			return
+		case errors.Is(err, pkgerrors.ErrApprovalNotFound):
+			admin.WriteError(w, http.StatusNotFound, err)
+
+			return
+		case errors.Is(err, pkgerrors.ErrApproval):
+			admin.WriteError(w, http.StatusConflict, err)
+
+			return
+		case err != nil:
+			admin.WriteError(w, http.StatusServiceUnavailable, err)
+
+			return
+		}
+
+		logging.FromContext(r.Context()).Info("Approved the certificate request", "approver", approver, "id", request.ID,
+			"common_name", request.CommonName, "approvals", len(request.Approvals), "threshold", request.Threshold)
+
+		bus.Emit(events.Event{
+			Type:       events.TypeApproved,
+			Time:       request.Approvals[len(request.Approvals)-1].Time,
+			CommonName: request.CommonName,
+			Approval:   request.ID,
+			Approver:   approver,
+		})
+
+		admin.WriteJSON(w, http.StatusOK, request)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/admin"
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	"github.com/clastix/talos-csr-signer/pkg/features"
+)
+
+// redacted replaces the secret values in the effective configuration.
+const redacted = "<redacted>"
+
+// sensitiveSettings are the configuration keys holding secrets.
+var sensitiveSettings = []string{config.KeyTalosToken, config.KeyTalosPreviousTokens, config.KeyAdminToken, config.KeyStandbyPrimaryToken, config.KeyVaultToken, config.KeyUpstreamToken, config.KeyCAKeyPassphrase, config.KeyCAPKCS12Password, config.KeyCAPrivateKeyB64, config.KeySOPSAgeKey, config.KeyEventSASLPassword, config.KeyPolicyOPABundlePassword}
+
+// effectiveConfig returns the fully merged configuration (defaults, flags, and environment), with secrets redacted,
+// along with the resolved state of the feature gates.
+func effectiveConfig(gates *features.Gates) map[string]any {
+	settings := viper.AllSettings()
+	redact(settings)
+
+	settings["feature-gates-state"] = gates.All()
+
+	return settings
+}
+
+// redact replaces the secret values of the settings, walking the nested ones such as the settings of the clusters.
+func redact(settings map[string]any) {
+	for key, value := range settings {
+		switch value := value.(type) {
+		case map[string]any:
+			redact(value)
+		case []any:
+			for _, item := range value {
+				if nested, ok := item.(map[string]any); ok {
+					redact(nested)
+				}
+			}
+		}
+
+		if slices.Contains(sensitiveSettings, key) && value != "" && value != nil {
+			settings[key] = redacted
+		}
+	}
+
+	// URLs may carry credentials, such as the Redis password.
+	for _, key := range []string{config.KeyLedgerURL, config.KeyEventSinkURL} {
+		if value, ok := settings[key].(string); ok {
+			settings[key] = redactedURL(value)
+		}
+	}
+}
+
+// redactedURL returns the URL with the password redacted, if any.
+func redactedURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return value
+	}
+
+	return u.Redacted()
+}
+
+// newConfigCommand returns the command printing the effective configuration.
+func newConfigCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "config",
+		Short: "Print the effective configuration, with secrets redacted",
+		RunE: func(*cobra.Command, []string) error {
+			gates, err := features.Parse(viper.GetString(config.KeyFeatureGates))
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.SetEscapeHTML(false)
+
+			return encoder.Encode(effectiveConfig(gates)) //nolint:wrapcheck
+		},
+	}
+}
+
+// configHandler serves the effective configuration of the running signer.
+func configHandler(gates *features.Gates) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, effectiveConfig(gates))
+	}
+}
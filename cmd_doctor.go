@@ -0,0 +1,355 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/clastix/talos-csr-signer/pkg/clock"
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+	"github.com/clastix/talos-csr-signer/pkg/preflight"
+	pb "github.com/clastix/talos-csr-signer/pkg/proto"
+	"github.com/clastix/talos-csr-signer/pkg/server"
+)
+
+const (
+	cliDoctorCA         = "ca"
+	cliDoctorToken      = "token"
+	cliDoctorCommonName = "common-name"
+	cliDoctorMaxSkew    = "max-skew"
+)
+
+// newDoctorCommand returns the command diagnosing the connectivity to the signer from the node perspective.
+func newDoctorCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose the connectivity to a signer from the node perspective",
+		Long: `Dial the signer endpoint as a node would, reporting what's wrong: name resolution, TCP reachability,
+the TLS chain verified against the Machine CA, the clock skew, and, when a token is given, a dry run certificate
+request validated by the signer without issuing any certificate.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			endpoint, _ := cmd.Flags().GetString(cliGetCAEndpoint)
+			serverName, _ := cmd.Flags().GetString(cliGetCAServerName)
+			caPath, _ := cmd.Flags().GetString(cliDoctorCA)
+			token, _ := cmd.Flags().GetString(cliDoctorToken)
+			commonName, _ := cmd.Flags().GetString(cliDoctorCommonName)
+			maxSkew, _ := cmd.Flags().GetDuration(cliDoctorMaxSkew)
+			ntpServer, _ := cmd.Flags().GetString(config.KeyNTPServer)
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+			defer cancel()
+
+			report := &preflight.Report{}
+			diagnose(ctx, report, doctorOptions{
+				endpoint:   endpoint,
+				serverName: serverName,
+				caPath:     caPath,
+				token:      token,
+				commonName: commonName,
+				maxSkew:    maxSkew,
+				ntpServer:  ntpServer,
+			})
+			report.Print(os.Stdout)
+
+			if failed := report.Count(preflight.StatusFail); failed > 0 {
+				return errors.Wrapf(pkgerrors.ErrDiagnosis, "%d failed checks", failed)
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	hostname, _ := os.Hostname()
+
+	cmd.Flags().String(cliGetCAEndpoint, "127.0.0.1:50001", "Signer gRPC endpoint, as host:port")
+	cmd.Flags().String(cliGetCAServerName, "", "Server name verified against the serving certificate, defaults to the endpoint host")
+	cmd.Flags().String(cliDoctorCA, "", "Machine CA certificate the serving certificate is verified against, skipping the verification when empty")
+	cmd.Flags().String(cliDoctorToken, "", "Talos token used for the dry run certificate request, skipping it when empty")
+	cmd.Flags().String(cliDoctorCommonName, hostname, "Common Name of the dry run certificate request")
+	cmd.Flags().Duration(cliDoctorMaxSkew, 30*time.Second, "Maximum tolerated clock skew from the signer, or the NTP server")
+	cmd.Flags().String(config.KeyNTPServer, "", "NTP server the local clock is compared to, skipping the check when empty")
+
+	return cmd
+}
+
+type doctorOptions struct {
+	endpoint   string
+	serverName string
+	caPath     string
+	token      string
+	commonName string
+	maxSkew    time.Duration
+	ntpServer  string
+}
+
+// diagnose runs the connectivity checks, stopping at the first failure the next ones depend on.
+func diagnose(ctx context.Context, report *preflight.Report, opts doctorOptions) {
+	host, _, err := net.SplitHostPort(opts.endpoint)
+	if err != nil {
+		report.Fail("endpoint", "%v: expected host:port", err)
+
+		return
+	}
+
+	if opts.serverName == "" {
+		opts.serverName = host
+	}
+	// Name resolution
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		report.Fail("dns", "%v: check the endpoint name and the node resolvers", err)
+
+		return
+	}
+
+	report.Pass("dns", "%s resolves to %v", host, addrs)
+	// TCP reachability
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", opts.endpoint)
+	if err != nil {
+		report.Fail("tcp", "%v: check the signer is running, and the firewalls or network policies in between", err)
+
+		return
+	}
+
+	_ = conn.Close()
+
+	report.Pass("tcp", "%s is reachable", opts.endpoint)
+	// TLS chain
+	tlsConfig, chain, ok := diagnoseTLS(ctx, report, opts)
+	if !ok {
+		return
+	}
+	// Clock skew, according to the node perspective
+	now := time.Now()
+	if leaf := chain[0]; now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+		report.Fail("clock", "local time %s is outside the serving certificate validity [%s, %s]: check the node clock",
+			now.Format(time.RFC3339), leaf.NotBefore.Format(time.RFC3339), leaf.NotAfter.Format(time.RFC3339))
+	} else {
+		report.Pass("clock", "local time %s is within the serving certificate validity", now.Format(time.RFC3339))
+	}
+
+	if opts.ntpServer != "" {
+		offset, ntpErr := clock.QueryNTP(ctx, opts.ntpServer)
+
+		switch {
+		case ntpErr != nil:
+			report.Warn("clock/ntp", "%v", ntpErr)
+		case offset.Abs() > opts.maxSkew:
+			report.Fail("clock/ntp", "local clock is %s off %s, exceeding %s", offset, opts.ntpServer, opts.maxSkew)
+		default:
+			report.Pass("clock/ntp", "local clock is %s off %s", offset, opts.ntpServer)
+		}
+	} else {
+		report.Skip("clock/ntp", "no NTP server given")
+	}
+	// Dry run certificate request
+	if opts.token == "" {
+		report.Skip("dry-run", "no token given")
+
+		return
+	}
+
+	diagnoseDryRun(ctx, report, opts, tlsConfig)
+}
+
+// diagnoseTLS performs the TLS handshake, verifying the serving certificate against the Machine CA when given.
+func diagnoseTLS(ctx context.Context, report *preflight.Report, opts doctorOptions) (*tls.Config, []*x509.Certificate, bool) {
+	tlsConfig := &tls.Config{
+		ServerName: opts.serverName,
+		NextProtos: []string{"h2"},
+		MinVersion: tls.VersionTLS12,
+	}
+
+	var roots *x509.CertPool
+
+	if opts.caPath != "" {
+		caPEM, err := os.ReadFile(opts.caPath)
+		if err != nil {
+			report.Fail("tls", "%v", err)
+
+			return nil, nil, false
+		}
+
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(caPEM) {
+			report.Fail("tls", "no PEM certificate found in %s", opts.caPath)
+
+			return nil, nil, false
+		}
+	}
+	// The chain is verified below, to diagnose the failure rather than aborting the handshake.
+	tlsConfig.InsecureSkipVerify = true //nolint:gosec
+
+	dialer := &tls.Dialer{Config: tlsConfig}
+
+	conn, err := dialer.DialContext(ctx, "tcp", opts.endpoint)
+	if err != nil {
+		report.Fail("tls", "handshake failed: %v: check the endpoint is the signer gRPC port", err)
+
+		return nil, nil, false
+	}
+	defer func() { _ = conn.Close() }()
+
+	state := conn.(*tls.Conn).ConnectionState() //nolint:forcetypeassert
+	chain := state.PeerCertificates
+	leaf := chain[0]
+
+	report.Pass("tls", "%s, serving certificate %q for DNS names %v and IPs %v",
+		tls.VersionName(state.Version), leaf.Subject, leaf.DNSNames, leaf.IPAddresses)
+
+	if roots == nil {
+		report.Skip("tls/verify", "no CA given, the serving certificate is not verified")
+
+		return tlsConfig, chain, true
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       opts.serverName,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostname         x509.HostnameError
+		invalid          x509.CertificateInvalidError
+	)
+
+	switch {
+	case err == nil:
+		report.Pass("tls/verify", "serving certificate is issued by the Machine CA and valid for %s", opts.serverName)
+	case errors.As(err, &unknownAuthority):
+		report.Fail("tls/verify", "serving certificate is issued by %q, not by the given Machine CA: regenerate it with gen-server-cert", leaf.Issuer)
+	case errors.As(err, &hostname):
+		report.Fail("tls/verify", "serving certificate is not valid for %s: add it to its SANs, or connect with one of them", opts.serverName)
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		report.Fail("tls/verify", "serving certificate is expired, or not yet valid: check its renewal and the node clock")
+	default:
+		report.Fail("tls/verify", "%v", err)
+	}
+
+	if err != nil {
+		return nil, nil, false
+	}
+
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.InsecureSkipVerify = false
+	tlsConfig.RootCAs = roots
+
+	return tlsConfig, chain, true
+}
+
+// diagnoseDryRun performs a certificate request validated by the signer without signing it.
+func diagnoseDryRun(ctx context.Context, report *preflight.Report, opts doctorOptions, tlsConfig *tls.Config) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		report.Fail("dry-run", "%v", err)
+
+		return
+	}
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: opts.commonName, Organization: []string{"os:admin"}},
+	}, key)
+	if err != nil {
+		report.Fail("dry-run", "%v", err)
+
+		return
+	}
+
+	conn, err := grpc.NewClient(opts.endpoint, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	if err != nil {
+		report.Fail("dry-run", "%v", err)
+
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "token", opts.token, server.DryRunMetadataKey, "true")
+
+	var header, trailer metadata.MD
+
+	resp, err := pb.NewSecurityServiceClient(conn).Certificate(ctx, &pb.CertificateRequest{
+		Csr: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}),
+	}, grpc.Header(&header), grpc.Trailer(&trailer))
+
+	if values := header.Get(server.TimeMetadataKey); len(values) > 0 {
+		if signerTime, parseErr := time.Parse(time.RFC3339Nano, values[0]); parseErr == nil {
+			if skew := time.Since(signerTime); skew.Abs() > opts.maxSkew {
+				report.Fail("clock/signer", "local clock is %s off the signer one, exceeding %s: certificates may be rejected as not yet valid", skew.Round(time.Second), opts.maxSkew)
+			} else {
+				report.Pass("clock/signer", "local clock is %s off the signer one", skew.Round(time.Millisecond))
+			}
+		}
+	}
+
+	switch status.Code(err) {
+	case codes.OK:
+		if len(resp.GetCrt()) > 0 {
+			report.Warn("dry-run", "the signer doesn't support dry runs and issued a certificate: upgrade it")
+
+			return
+		}
+
+		if _, parseErr := pki.ParseCertificates(resp.GetCa()); parseErr != nil {
+			report.Fail("dry-run", "the signer returned no CA certificate")
+
+			return
+		}
+
+		report.Pass("dry-run", "token and CSR accepted for %s", opts.commonName)
+
+		// Older signers don't describe the would-be certificate
+		if values := trailer.Get(server.DryRunCertificateMetadataKey); len(values) > 0 {
+			var certificate server.DryRunCertificate
+			if jsonErr := json.Unmarshal([]byte(values[0]), &certificate); jsonErr != nil {
+				report.Warn("dry-run/certificate", "invalid certificate description: %v", jsonErr)
+
+				return
+			}
+
+			report.Pass("dry-run/certificate", "%s would be issued as %s, valid until %s, with the %s key usages and the %s extended key usages",
+				certificate.Subject, certificate.Role, certificate.NotAfter.Format(time.RFC3339),
+				strings.Join(certificate.KeyUsages, ","), strings.Join(certificate.ExtKeyUsages, ","))
+		}
+	case codes.Unauthenticated:
+		report.Fail("dry-run", "%s: check the token matches the machine configuration cluster token", status.Convert(err).Message())
+	case codes.ResourceExhausted:
+		report.Fail("dry-run", "%s: the node requested too many certificates, wait for the quota window", status.Convert(err).Message())
+	case codes.Unavailable:
+		report.Fail("dry-run", "%s: check the signer logs, its backend, ledger, and clock", status.Convert(err).Message())
+	default:
+		report.Fail("dry-run", "%v", err)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	"github.com/clastix/talos-csr-signer/pkg/crl"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+const (
+	cliExportCRLOut        = "out"
+	cliExportCRLFormat     = "format"
+	cliExportCRLSignerCert = "signer-cert-path"
+	cliExportCRLSignerKey  = "signer-key-path"
+)
+
+// newExportCRLCommand returns the command generating the CRL from the ledger on demand,
+// for the distribution workflows that cannot reach the CRL admin endpoint.
+func newExportCRLCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-crl",
+		Short: "Generate the Certificate Revocation List from the ledger",
+		Long: `Generate the Certificate Revocation List of the revoked certificates recorded in the ledger,
+signed by the Machine CA, or by a CRL-signing delegate issued by it, and valid for --crl-validity.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			out, _ := cmd.Flags().GetString(cliExportCRLOut)
+			format, _ := cmd.Flags().GetString(cliExportCRLFormat)
+			signerCertPath, _ := cmd.Flags().GetString(cliExportCRLSignerCert)
+			signerKeyPath, _ := cmd.Flags().GetString(cliExportCRLSignerKey)
+
+			if format != "pem" && format != "der" {
+				return errors.Wrapf(pkgerrors.ErrCRL, "unknown format %q, expected pem or der", format)
+			}
+
+			issuer, signer, err := loadSettingsCA(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			if signerCertPath != "" {
+				delegate, delegateSigner, delegateErr := loadCA(signerCertPath, signerKeyPath)
+				if delegateErr != nil {
+					return delegateErr
+				}
+
+				if delegateErr = delegate.CheckSignatureFrom(issuer); delegateErr != nil {
+					return errors.Wrap(pkgerrors.ErrCRL, "the CRL signer is not issued by the CA: "+delegateErr.Error())
+				}
+
+				if delegate.KeyUsage&x509.KeyUsageCRLSign == 0 {
+					return errors.Wrap(pkgerrors.ErrCRL, "the CRL signer lacks the cRLSign key usage")
+				}
+
+				issuer, signer = delegate, delegateSigner
+			}
+
+			l, err := ledger.New(viper.GetString(config.KeyLedgerURL), viper.GetString(config.KeyLedgerKeyPrefix))
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+			defer func() { _ = l.Close() }()
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), time.Minute)
+			defer cancel()
+
+			// The Unix time keeps the number increasing, as the CRL served by the signer.
+			der, err := crl.Build(ctx, l, issuer, signer, viper.GetDuration(config.KeyCRLValidity), big.NewInt(time.Now().Unix()))
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			if format == "pem" {
+				der = pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+			}
+
+			if out == "-" {
+				_, err = os.Stdout.Write(der)
+
+				return err //nolint:wrapcheck
+			}
+
+			if err = os.WriteFile(out, der, 0o644); err != nil { //nolint:gosec
+				return errors.Wrap(pkgerrors.ErrWriteFile, err.Error())
+			}
+
+			logging.FromContext(ctx).Info("Wrote the CRL", "issuer", issuer.Subject.String(), "path", out)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String(cliExportCRLOut, "-", "Path the CRL is written to, - for the standard output")
+	cmd.Flags().String(cliExportCRLFormat, "pem", "Encoding of the CRL: pem or der")
+	cmd.Flags().String(cliExportCRLSignerCert, "", "Path to the certificate of the CRL-signing delegate, signing with the CA when empty")
+	cmd.Flags().String(cliExportCRLSignerKey, "", "Path to the private key of the CRL-signing delegate")
+
+	return cmd
+}
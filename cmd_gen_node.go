@@ -0,0 +1,180 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+)
+
+const (
+	cliGenNodeOrganizations = "organization"
+	// genNodeBackend is the backend recorded in the ledger for the pre-issued certificates.
+	genNodeBackend = "gen-node"
+)
+
+// newGenNodeCommand returns the command pre-issuing a node certificate with the CA, for the nodes
+// that cannot reach the signer at first boot.
+func newGenNodeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gen-node",
+		Short: "Pre-issue a node certificate with the Machine CA, for air-gapped installs",
+		Long: `Generate the node key and CSR locally, sign the CSR with the Machine CA as the signer would, and write the
+node.crt, node.key, and ca.crt files, along with node.yaml holding the base64 encoded pair in the crt and key fields
+of the machine configuration. The certificate is recorded in the ledger at --ledger-url, to be listed and revoked.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			commonName, _ := cmd.Flags().GetString(cliServerCertCommonName)
+			organizations, _ := cmd.Flags().GetStringSlice(cliGenNodeOrganizations)
+			dnsNames, _ := cmd.Flags().GetStringSlice(cliServerCertDNSNames)
+			rawIPs, _ := cmd.Flags().GetStringSlice(cliServerCertIPs)
+			validity, _ := cmd.Flags().GetDuration(cliServerCertValidity)
+			algorithm, _ := cmd.Flags().GetString(cliServerCertAlgorithm)
+			outDir, _ := cmd.Flags().GetString(cliServerCertOutDir)
+
+			ips := make([]net.IP, 0, len(rawIPs))
+
+			for _, rawIP := range rawIPs {
+				ip := net.ParseIP(rawIP)
+				if ip == nil {
+					return errors.Wrap(pkgerrors.ErrInvalidIP, rawIP)
+				}
+
+				ips = append(ips, ip)
+			}
+
+			caCert, caKey, err := loadSettingsCA(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			caBackend, err := backend.NewLocal(genNodeBackend, pki.EncodeCertificates(caCert), caKey)
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			key, err := pki.GenerateKey(algorithm)
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			// The CSR is generated as the node would, so the certificate matches the ones issued by the signer.
+			csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+				Subject:     pkix.Name{CommonName: commonName, Organization: organizations},
+				DNSNames:    dnsNames,
+				IPAddresses: ips,
+			}, key)
+			if err != nil {
+				return errors.Wrap(pkgerrors.ErrCSR, err.Error())
+			}
+
+			csr, err := x509.ParseCertificateRequest(csrDER)
+			if err != nil {
+				return errors.Wrap(pkgerrors.ErrCSR, err.Error())
+			}
+
+			profile := signer.DefaultProfile
+			profile.Validity = validity
+
+			serialFormat, err := pki.ParseSerialFormat(viper.GetInt(config.KeySerialBits), viper.GetString(config.KeySerialPrefix))
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			cert, caPEM, err := signer.New(signer.Options{
+				Backend:      caBackend,
+				CAExpiry:     viper.GetString(config.KeyCAExpiry),
+				SerialNumber: func(context.Context) (*big.Int, error) { return serialFormat.Generate() },
+			}).Sign(cmd.Context(), csr, profile)
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			if err = recordPreIssued(cmd.Context(), cert); err != nil {
+				return err
+			}
+
+			certPEM := pki.EncodeCertificates(cert)
+
+			keyPEM, err := pki.EncodePrivateKey(key)
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			if err = os.MkdirAll(outDir, 0o700); err != nil {
+				return errors.Wrap(pkgerrors.ErrWriteFile, err.Error())
+			}
+
+			files := map[string][]byte{
+				"node.crt":  certPEM,
+				"node.key":  keyPEM,
+				"ca.crt":    caPEM,
+				"node.yaml": nodeConfig(commonName, certPEM, keyPEM),
+			}
+
+			for name, data := range files {
+				path := filepath.Join(outDir, name)
+				if err = os.WriteFile(path, data, 0o600); err != nil {
+					return errors.Wrap(pkgerrors.ErrWriteFile, err.Error())
+				}
+
+				logging.FromContext(cmd.Context()).Info("Wrote the file", "path", path)
+			}
+
+			logging.FromContext(cmd.Context()).Info("Pre-issued the certificate", "serial", cert.SerialNumber.Text(16),
+				"common_name", commonName, "dns_names", dnsNames, "ips", rawIPs, "not_after", cert.NotAfter.Format(time.RFC3339))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String(cliServerCertCommonName, "", "Common Name of the node certificate, usually the node hostname")
+	cmd.Flags().StringSlice(cliGenNodeOrganizations, []string{"os:server"}, "Organizations of the node certificate, comma separated or repeated")
+	cmd.Flags().StringSlice(cliServerCertDNSNames, nil, "DNS names of the node, comma separated or repeated")
+	cmd.Flags().StringSlice(cliServerCertIPs, nil, "IP addresses of the node, comma separated or repeated")
+	cmd.Flags().Duration(cliServerCertValidity, 365*24*time.Hour, "Validity of the node certificate")
+	cmd.Flags().String(cliServerCertAlgorithm, "ed25519", "Key algorithm: ed25519, ecdsa, or rsa")
+	cmd.Flags().String(cliServerCertOutDir, ".", "Directory the node files are written to")
+	_ = cmd.MarkFlagRequired(cliServerCertCommonName)
+
+	return cmd
+}
+
+// recordPreIssued stores the pre-issued certificate in the ledger, as the signer does for the issued ones.
+func recordPreIssued(ctx context.Context, cert *x509.Certificate) error {
+	l, err := ledger.New(viper.GetString(config.KeyLedgerURL), viper.GetString(config.KeyLedgerKeyPrefix))
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	defer func() { _ = l.Close() }()
+
+	return l.Store(ctx, ledger.NewRecord(cert, genNodeBackend)) //nolint:wrapcheck
+}
+
+// nodeConfig returns the YAML snippet holding the base64 encoded certificate and key, as the machine configuration does.
+func nodeConfig(commonName string, certPEM, keyPEM []byte) []byte {
+	return fmt.Appendf(nil, "# Certificate of %s, pre-issued by the Machine CA.\ncrt: %s\nkey: %s\n", commonName,
+		base64.StdEncoding.EncodeToString(certPEM), base64.StdEncoding.EncodeToString(keyPEM))
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+)
+
+const (
+	cliServerCertCommonName = "common-name"
+	cliServerCertDNSNames   = "dns"
+	cliServerCertIPs        = "ip"
+	cliServerCertValidity   = "validity"
+	cliServerCertAlgorithm  = "key-algorithm"
+	cliServerCertOutDir     = "out-dir"
+)
+
+// newGenServerCertCommand returns the command generating the TLS serving certificate of the signer,
+// signed by the Machine CA so the nodes trust it.
+func newGenServerCertCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gen-server-cert",
+		Short: "Generate the signer TLS serving certificate, signed by the Machine CA",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			commonName, _ := cmd.Flags().GetString(cliServerCertCommonName)
+			dnsNames, _ := cmd.Flags().GetStringSlice(cliServerCertDNSNames)
+			rawIPs, _ := cmd.Flags().GetStringSlice(cliServerCertIPs)
+			validity, _ := cmd.Flags().GetDuration(cliServerCertValidity)
+			algorithm, _ := cmd.Flags().GetString(cliServerCertAlgorithm)
+			outDir, _ := cmd.Flags().GetString(cliServerCertOutDir)
+
+			ips := make([]net.IP, 0, len(rawIPs))
+
+			for _, rawIP := range rawIPs {
+				ip := net.ParseIP(rawIP)
+				if ip == nil {
+					return errors.Wrap(pkgerrors.ErrInvalidIP, rawIP)
+				}
+
+				ips = append(ips, ip)
+			}
+
+			caCert, caKey, err := loadSettingsCA(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			key, err := pki.GenerateKey(algorithm)
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			serial, err := pki.SerialNumber()
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			now := time.Now()
+			template := &x509.Certificate{
+				SerialNumber: serial,
+				Subject:      pkix.Name{CommonName: commonName},
+				DNSNames:     dnsNames,
+				IPAddresses:  ips,
+				NotBefore:    now.Add(-5 * time.Minute), // Tolerate small clock skews
+				NotAfter:     now.Add(validity),
+				KeyUsage:     x509.KeyUsageDigitalSignature,
+				ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			}
+			if _, isRSA := key.(*rsa.PrivateKey); isRSA {
+				template.KeyUsage |= x509.KeyUsageKeyEncipherment
+			}
+
+			cert, err := pki.Sign(template, key.Public(), caCert, caKey)
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			// Serve the CA along with the certificate, letting get-ca fetch it from the TLS chain.
+			certPEM := pki.EncodeCertificates(cert, caCert)
+
+			keyPEM, err := pki.EncodePrivateKey(key)
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			if err = os.MkdirAll(outDir, 0o700); err != nil {
+				return errors.Wrap(pkgerrors.ErrWriteFile, err.Error())
+			}
+
+			files := map[string][]byte{"tls.crt": certPEM, "tls.key": keyPEM}
+
+			if name, _ := cmd.Flags().GetString(cliSecretName); name != "" {
+				namespace, _ := cmd.Flags().GetString(cliSecretNamespace)
+				files = map[string][]byte{
+					"secret.yaml": secretManifest(name, namespace, "kubernetes.io/tls", map[string][]byte{
+						"tls.crt": certPEM,
+						"tls.key": keyPEM,
+						"ca.crt":  pki.EncodeCertificates(caCert),
+					}),
+				}
+			}
+
+			for name, data := range files {
+				path := filepath.Join(outDir, name)
+				if err = os.WriteFile(path, data, 0o600); err != nil {
+					return errors.Wrap(pkgerrors.ErrWriteFile, err.Error())
+				}
+
+				logging.FromContext(cmd.Context()).Info("Wrote the file", "path", path)
+			}
+
+			logging.FromContext(cmd.Context()).Info("Generated the serving certificate", "common_name", commonName,
+				"dns_names", dnsNames, "ips", rawIPs, "not_after", cert.NotAfter.Format(time.RFC3339))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String(cliServerCertCommonName, "talos-csr-signer", "Common Name of the serving certificate")
+	cmd.Flags().StringSlice(cliServerCertDNSNames, nil, "DNS names the nodes reach the signer at, comma separated or repeated")
+	cmd.Flags().StringSlice(cliServerCertIPs, []string{"127.0.0.1"}, "IP addresses the nodes reach the signer at, comma separated or repeated")
+	cmd.Flags().Duration(cliServerCertValidity, 365*24*time.Hour, "Validity of the serving certificate")
+	cmd.Flags().String(cliServerCertAlgorithm, "ed25519", "Key algorithm: ed25519, ecdsa, or rsa")
+	cmd.Flags().String(cliServerCertOutDir, ".", "Directory the tls.crt and tls.key files, or the secret.yaml manifest, are written to")
+	cmd.Flags().String(cliSecretName, "", "Name of the kubernetes.io/tls Secret: when set, its manifest is written rather than the files")
+	cmd.Flags().String(cliSecretNamespace, "default", "Namespace of the Secret")
+
+	return cmd
+}
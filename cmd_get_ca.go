@@ -0,0 +1,227 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+	"github.com/clastix/talos-csr-signer/pkg/server"
+)
+
+const (
+	cliGetCAEndpoint    = "endpoint"
+	cliGetCASource      = "source"
+	cliAdminURL         = "admin-url"
+	cliGetCAServerName  = "server-name"
+	cliGetCAFingerprint = "fingerprint"
+	cliGetCAOutput      = "output"
+
+	sourceTLS   = "tls"
+	sourceAdmin = "admin"
+)
+
+// newGetCACommand returns the command fetching the trust bundle from a running signer.
+func newGetCACommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get-ca",
+		Short: "Fetch and verify the trust bundle of a running signer",
+		Long: `Fetch the trust bundle of a running signer, verify it, and write it to a file.
+
+With --source tls the CA certificates are taken from the TLS chain served by --endpoint, verifying the serving
+certificate is issued by them. With --source admin the bundle is fetched from the GET /ca admin API endpoint.
+Pin the expected CA with --fingerprint, since the bundle is otherwise trusted on first use.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			source, _ := cmd.Flags().GetString(cliGetCASource)
+			fingerprint, _ := cmd.Flags().GetString(cliGetCAFingerprint)
+			output, _ := cmd.Flags().GetString(cliGetCAOutput)
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), 15*time.Second)
+			defer cancel()
+
+			var (
+				certs []*x509.Certificate
+				err   error
+			)
+
+			switch source {
+			case sourceTLS:
+				endpoint, _ := cmd.Flags().GetString(cliGetCAEndpoint)
+				serverName, _ := cmd.Flags().GetString(cliGetCAServerName)
+				certs, err = fetchCAFromTLS(ctx, endpoint, serverName)
+			case sourceAdmin:
+				adminURL, _ := cmd.Flags().GetString(cliAdminURL)
+				certs, err = fetchCAFromAdmin(ctx, adminURL, viper.GetString(config.KeyAdminToken))
+			default:
+				err = errors.Wrap(pkgerrors.ErrGetCA, "unsupported source "+source+", expected tls or admin")
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if fingerprint != "" {
+				if err = verifyFingerprint(certs, fingerprint); err != nil {
+					return err
+				}
+			} else {
+				logging.FromContext(ctx).Warn("No --fingerprint given, the bundle is trusted on first use")
+			}
+
+			for _, cert := range certs {
+				logging.FromContext(ctx).Info("Fetched the CA", "subject", cert.Subject.String(), "not_after", cert.NotAfter.Format(time.RFC3339),
+					"fingerprint", "sha256:"+certFingerprint(cert))
+			}
+
+			bundle := pki.EncodeCertificates(certs...)
+
+			if output == "-" {
+				_, err = os.Stdout.Write(bundle)
+
+				return err //nolint:wrapcheck
+			}
+
+			if err = os.WriteFile(output, bundle, 0o644); err != nil { //nolint:gosec
+				return errors.Wrap(pkgerrors.ErrWriteFile, err.Error())
+			}
+
+			logging.FromContext(ctx).Info("Wrote the trust bundle", "path", output)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String(cliGetCAEndpoint, "127.0.0.1:50001", "Signer gRPC endpoint, as host:port")
+	cmd.Flags().String(cliGetCASource, sourceTLS, "Where the bundle is fetched from: tls, the chain served by the endpoint, or admin, the admin API")
+	cmd.Flags().String(cliAdminURL, "http://127.0.0.1:8080", "Signer admin API URL, authenticated with the ADMIN_TOKEN environment variable")
+	cmd.Flags().String(cliGetCAServerName, "", "Server name sent with SNI, defaults to the endpoint host")
+	cmd.Flags().String(cliGetCAFingerprint, "", "Expected SHA-256 fingerprint of a CA of the bundle, as hex optionally prefixed by sha256:")
+	cmd.Flags().StringP(cliGetCAOutput, "o", "ca.crt", "File the bundle is written to, - for the standard output")
+
+	return cmd
+}
+
+// fetchCAFromTLS returns the CA certificates of the TLS chain served by the endpoint,
+// verifying they issued the serving certificate.
+func fetchCAFromTLS(ctx context.Context, endpoint, serverName string) ([]*x509.Certificate, error) {
+	if serverName == "" {
+		host, _, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrGetCA, err.Error())
+		}
+
+		serverName = host
+	}
+
+	dialer := &tls.Dialer{Config: &tls.Config{
+		ServerName: serverName,
+		// The CA is not known yet: the chain is verified against the fetched CA below.
+		InsecureSkipVerify: true, //nolint:gosec
+		NextProtos:         []string{"h2"},
+	}}
+
+	conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrGetCA, err.Error())
+	}
+	defer func() { _ = conn.Close() }()
+
+	chain := conn.(*tls.Conn).ConnectionState().PeerCertificates //nolint:forcetypeassert
+
+	roots := x509.NewCertPool()
+
+	var cas []*x509.Certificate
+
+	for _, cert := range chain[1:] {
+		if cert.IsCA {
+			cas = append(cas, cert)
+			roots.AddCert(cert)
+		}
+	}
+
+	if len(cas) == 0 {
+		return nil, errors.Wrap(pkgerrors.ErrGetCA, "the served chain doesn't include the CA, use --source admin")
+	}
+
+	if _, err = chain[0].Verify(x509.VerifyOptions{Roots: roots, DNSName: serverName, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrGetCA, err.Error())
+	}
+
+	return cas, nil
+}
+
+// fetchCAFromAdmin returns the CA certificates served by the admin API.
+func fetchCAFromAdmin(ctx context.Context, adminURL, token string) ([]*x509.Certificate, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(adminURL, "/")+"/ca", nil)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrGetCA, err.Error())
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrGetCA, err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrGetCA, err.Error())
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Wrap(pkgerrors.ErrGetCA, fmt.Sprintf("%s: %s", resp.Status, strings.TrimSpace(string(body))))
+	}
+
+	return pki.ParseCertificates(body) //nolint:wrapcheck
+}
+
+// verifyFingerprint checks one of the certificates has the expected SHA-256 fingerprint.
+func verifyFingerprint(certs []*x509.Certificate, fingerprint string) error {
+	expected := normalizeFingerprint(fingerprint)
+
+	for _, cert := range certs {
+		if certFingerprint(cert) == expected {
+			return nil
+		}
+	}
+
+	return errors.Wrap(pkgerrors.ErrGetCA, "no CA matches the fingerprint "+fingerprint)
+}
+
+// certFingerprint returns the hex encoded SHA-256 fingerprint of the certificate.
+func certFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// caHandler serves the trust bundle returned to the nodes: the signing CA and its chain, along with the rotation bundle.
+func caHandler(srv *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		_, _ = w.Write(srv.CABundle())
+	}
+}
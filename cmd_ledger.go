@@ -0,0 +1,197 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/admin"
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+const (
+	cliLedgerOutput = "output"
+	cliLedgerInput  = "input"
+)
+
+// newLedgerCommand returns the command managing the ledger backups, also used to migrate between backends,
+// and its retention.
+func newLedgerCommand() *cobra.Command {
+	ledgerCmd := &cobra.Command{
+		Use:   "ledger",
+		Short: "Back up, restore, and prune the issuance ledger",
+	}
+
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Write a snapshot of the ledger records",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			l, err := ledger.New(viper.GetString(config.KeyLedgerURL), viper.GetString(config.KeyLedgerKeyPrefix))
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+			defer func() { _ = l.Close() }()
+
+			var w io.Writer = os.Stdout
+
+			if output, _ := cmd.Flags().GetString(cliLedgerOutput); output != "-" {
+				file, fileErr := os.Create(output)
+				if fileErr != nil {
+					return errors.Wrap(pkgerrors.ErrLedgerSnapshot, fileErr.Error())
+				}
+				defer func() { _ = file.Close() }()
+
+				w = file
+			}
+
+			count, err := ledger.Backup(cmd.Context(), l, w)
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			logging.FromContext(cmd.Context()).Info("Backed up the ledger records", "count", count)
+
+			return nil
+		},
+	}
+	backupCmd.Flags().StringP(cliLedgerOutput, "o", "-", "Snapshot file to write, - for the standard output")
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Import the records of a snapshot into the ledger",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			l, err := ledger.New(viper.GetString(config.KeyLedgerURL), viper.GetString(config.KeyLedgerKeyPrefix))
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+			defer func() { _ = l.Close() }()
+
+			var r io.Reader = os.Stdin
+
+			if input, _ := cmd.Flags().GetString(cliLedgerInput); input != "-" {
+				file, fileErr := os.Open(input)
+				if fileErr != nil {
+					return errors.Wrap(pkgerrors.ErrLedgerSnapshot, fileErr.Error())
+				}
+				defer func() { _ = file.Close() }()
+
+				r = file
+			}
+
+			count, err := ledger.Restore(cmd.Context(), l, r)
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			logging.FromContext(cmd.Context()).Info("Restored the ledger records", "count", count)
+
+			return nil
+		},
+	}
+	restoreCmd.Flags().StringP(cliLedgerInput, "i", "-", "Snapshot file to read, - for the standard input")
+
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove the ledger records exceeding the retention policy",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			l, err := ledger.New(viper.GetString(config.KeyLedgerURL), viper.GetString(config.KeyLedgerKeyPrefix))
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+			defer func() { _ = l.Close() }()
+
+			count, err := ledger.Prune(cmd.Context(), l, ledgerRetention(config.Read(viper.GetViper()).Ledger))
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			logging.FromContext(cmd.Context()).Info("Pruned the ledger records", "count", count)
+
+			return nil
+		},
+	}
+
+	ledgerCmd.AddCommand(backupCmd, restoreCmd, pruneCmd)
+
+	return ledgerCmd
+}
+
+// snapshotLedger periodically writes a ledger snapshot to the given directory until the context is done.
+func snapshotLedger(ctx context.Context, l ledger.Ledger, dir string, interval time.Duration, retain int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		path, err := ledger.WriteSnapshot(ctx, l, dir, retain)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to write the ledger snapshot", "error", err)
+
+			continue
+		}
+
+		logging.FromContext(ctx).Info("Wrote the ledger snapshot", "path", path)
+	}
+}
+
+// ledgerRetention returns the configured retention policy of the ledger records.
+func ledgerRetention(cfg config.Ledger) ledger.Retention {
+	return ledger.Retention{
+		MaxAge:     cfg.Retention,
+		MaxRecords: cfg.MaxRecords,
+	}
+}
+
+// pruneLedger periodically enforces the retention policy until the context is done.
+func pruneLedger(ctx context.Context, l ledger.Ledger, retention ledger.Retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := ledger.Prune(ctx, l, retention)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to prune the ledger", "error", err)
+		} else if count > 0 {
+			logging.FromContext(ctx).Info("Pruned the ledger records", "count", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ledgerSnapshotHandler serves the snapshot of the ledger records, replicated by the standby signers.
+func ledgerSnapshotHandler(l ledger.Ledger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var snapshot bytes.Buffer
+		if _, err := ledger.Backup(r.Context(), l, &snapshot); err != nil {
+			admin.WriteError(w, http.StatusServiceUnavailable, err)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(snapshot.Bytes())
+	}
+}
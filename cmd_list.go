@@ -0,0 +1,240 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/admin"
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+)
+
+const (
+	cliListCommonName     = "cn"
+	cliListExpiringWithin = "expiring-within"
+	cliListRevoked        = "revoked"
+	cliListOutput         = "output"
+)
+
+// recordFilter selects the ledger records listed.
+type recordFilter struct {
+	// CommonName matches the records with the given Common Name, or prefix when ending with *.
+	CommonName string
+	// ExpiringWithin matches the unexpired records expiring within the duration: zero disables it.
+	ExpiringWithin time.Duration
+	// Revoked matches the revoked records only.
+	Revoked bool
+}
+
+// match returns true when the record satisfies the filter.
+func (f recordFilter) match(record ledger.Record, now time.Time) bool {
+	if prefix, isPrefix := strings.CutSuffix(f.CommonName, "*"); isPrefix {
+		if !strings.HasPrefix(record.CommonName, prefix) {
+			return false
+		}
+	} else if f.CommonName != "" && record.CommonName != f.CommonName {
+		return false
+	}
+
+	if f.ExpiringWithin > 0 && (now.After(record.NotAfter) || record.NotAfter.Sub(now) > f.ExpiringWithin) {
+		return false
+	}
+
+	return !f.Revoked || record.Revoked()
+}
+
+// filterRecords returns the records satisfying the filter.
+func filterRecords(records []ledger.Record, filter recordFilter) []ledger.Record {
+	now := time.Now()
+	filtered := make([]ledger.Record, 0, len(records))
+
+	for _, record := range records {
+		if filter.match(record, now) {
+			filtered = append(filtered, record)
+		}
+	}
+
+	return filtered
+}
+
+// parseDays parses the duration, also accepting the number of days with the d suffix, such as 30d.
+func parseDays(value string) (time.Duration, error) {
+	if days, isDays := strings.CutSuffix(value, "d"); isDays {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, errors.Wrap(pkgerrors.ErrInvalidDuration, value)
+		}
+
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Wrap(pkgerrors.ErrInvalidDuration, value)
+	}
+
+	return d, nil
+}
+
+// newListCommand returns the command listing the issued certificates, through the admin API or from the ledger.
+func newListCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the issued certificates, through the admin API or from the ledger",
+		Long: `List the issued certificates matching the filters.
+
+With --admin-url the records are queried through the admin API of a running signer, authenticated with
+the ADMIN_TOKEN environment variable; otherwise they are read from the ledger at --ledger-url.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			adminURL, _ := cmd.Flags().GetString(cliAdminURL)
+			output, _ := cmd.Flags().GetString(cliListOutput)
+			expiringWithin, _ := cmd.Flags().GetString(cliListExpiringWithin)
+
+			var (
+				filter recordFilter
+				err    error
+			)
+
+			filter.CommonName, _ = cmd.Flags().GetString(cliListCommonName)
+			filter.Revoked, _ = cmd.Flags().GetBool(cliListRevoked)
+
+			if expiringWithin != "" {
+				if filter.ExpiringWithin, err = parseDays(expiringWithin); err != nil {
+					return err
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), time.Minute)
+			defer cancel()
+
+			var records []ledger.Record
+
+			if adminURL != "" {
+				query := url.Values{}
+				query.Set(cliListCommonName, filter.CommonName)
+				query.Set(cliListExpiringWithin, expiringWithin)
+				query.Set(cliListRevoked, strconv.FormatBool(filter.Revoked))
+
+				err = callAdmin(ctx, http.MethodGet, adminURL, "/certificates?"+query.Encode(), nil, &records)
+			} else {
+				records, err = listLedger(ctx, filter)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			return printRecords(records, output)
+		},
+	}
+
+	cmd.Flags().String(cliAdminURL, "", "Signer admin API URL, reading the ledger directly when empty")
+	cmd.Flags().String(cliListCommonName, "", "Common Name of the certificates, or its prefix when ending with *")
+	cmd.Flags().String(cliListExpiringWithin, "", "List the certificates expiring within the duration, such as 30d or 12h")
+	cmd.Flags().Bool(cliListRevoked, false, "List the revoked certificates only")
+	cmd.Flags().StringP(cliListOutput, "o", "table", "Output format: table or json")
+
+	return cmd
+}
+
+// listLedger returns the records of the ledger satisfying the filter.
+func listLedger(ctx context.Context, filter recordFilter) ([]ledger.Record, error) {
+	l, err := ledger.New(viper.GetString(config.KeyLedgerURL), viper.GetString(config.KeyLedgerKeyPrefix))
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	defer func() { _ = l.Close() }()
+
+	records, err := l.List(ctx)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return filterRecords(records, filter), nil
+}
+
+// printRecords writes the records to the standard output, as a table or JSON.
+func printRecords(records []ledger.Record, output string) error {
+	if output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(records) //nolint:wrapcheck
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(w, "SERIAL\tCOMMON NAME\tNOT AFTER\tSTATUS\tBACKEND")
+
+	for _, record := range records {
+		state := "valid"
+
+		switch {
+		case record.Revoked():
+			state = "revoked"
+		case now.After(record.NotAfter):
+			state = "expired"
+		}
+
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", record.Serial, record.CommonName, record.NotAfter.Format(time.RFC3339), state, record.Backend)
+	}
+
+	return w.Flush() //nolint:wrapcheck
+}
+
+// certificatesHandler serves the ledger records satisfying the filter of the query parameters.
+func certificatesHandler(l ledger.Ledger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := recordFilter{CommonName: query.Get(cliListCommonName)}
+
+		if value := query.Get(cliListExpiringWithin); value != "" {
+			expiringWithin, err := parseDays(value)
+			if err != nil {
+				admin.WriteError(w, http.StatusBadRequest, err)
+
+				return
+			}
+
+			filter.ExpiringWithin = expiringWithin
+		}
+
+		if value := query.Get(cliListRevoked); value != "" {
+			revoked, err := strconv.ParseBool(value)
+			if err != nil {
+				admin.WriteError(w, http.StatusBadRequest, err)
+
+				return
+			}
+
+			filter.Revoked = revoked
+		}
+
+		records, err := l.List(r.Context())
+		if err != nil {
+			admin.WriteError(w, http.StatusServiceUnavailable, err)
+
+			return
+		}
+
+		admin.WriteJSON(w, http.StatusOK, filterRecords(records, filter))
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/admin"
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	"github.com/clastix/talos-csr-signer/pkg/crl"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/events"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+const (
+	cliRevokeFingerprint   = "fingerprint"
+	cliRevokeReason        = "reason"
+	cliRevokeRegenerateCRL = "regenerate-crl"
+)
+
+// revokeRequest is the body of the POST /revoke admin API endpoint.
+type revokeRequest struct {
+	Serial        string `json:"serial,omitempty"`
+	Fingerprint   string `json:"fingerprint,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+	RegenerateCRL bool   `json:"regenerateCRL,omitempty"`
+}
+
+// revokeResponse is the response of the POST /revoke admin API endpoint.
+type revokeResponse struct {
+	Record         ledger.Record `json:"record"`
+	CRLRegenerated bool          `json:"crlRegenerated"`
+	Warning        string        `json:"warning,omitempty"`
+}
+
+// newRevokeCommand returns the command revoking a certificate through the admin API.
+func newRevokeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "revoke [serial]",
+		Short: "Revoke a certificate by serial or fingerprint through the admin API",
+		Args:  cobra.MaximumNArgs(1),
+		// Usage errors are reported before reaching the admin API.
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			adminURL, _ := cmd.Flags().GetString(cliAdminURL)
+			fingerprint, _ := cmd.Flags().GetString(cliRevokeFingerprint)
+			reason, _ := cmd.Flags().GetString(cliRevokeReason)
+			regenerateCRL, _ := cmd.Flags().GetBool(cliRevokeRegenerateCRL)
+
+			request := revokeRequest{Fingerprint: fingerprint, Reason: reason, RegenerateCRL: regenerateCRL}
+			if len(args) > 0 {
+				request.Serial = args[0]
+			}
+
+			if (request.Serial == "") == (request.Fingerprint == "") {
+				return errors.Wrap(pkgerrors.ErrRevoke, "either the serial or the fingerprint is required")
+			}
+			// Validate the reason before reaching the signer.
+			if _, err := crl.ParseReason(reason); err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+			defer cancel()
+
+			var response revokeResponse
+			if err := callAdmin(ctx, http.MethodPost, adminURL, "/revoke", request, &response); err != nil {
+				return err
+			}
+
+			logging.FromContext(ctx).Info("Revoked the certificate", "serial", response.Record.Serial,
+				"common_name", response.Record.CommonName, "reason", reason)
+
+			if response.CRLRegenerated {
+				logging.FromContext(ctx).Info("Regenerated the CRL")
+			}
+
+			if response.Warning != "" {
+				logging.FromContext(ctx).Warn(response.Warning)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String(cliAdminURL, "http://127.0.0.1:8080", "Signer admin API URL, authenticated with the ADMIN_TOKEN environment variable")
+	cmd.Flags().String(cliRevokeFingerprint, "", "SHA-256 fingerprint of the certificate to revoke, as hex optionally prefixed by sha256:")
+	cmd.Flags().String(cliRevokeReason, "unspecified", "RFC 5280 revocation reason, such as keyCompromise, superseded, or cessationOfOperation")
+	cmd.Flags().Bool(cliRevokeRegenerateCRL, false, "Regenerate the CRL immediately, rather than at the next interval")
+
+	return cmd
+}
+
+// callAdmin sends the JSON request to the admin API, decoding the JSON response.
+func callAdmin(ctx context.Context, method, adminURL, path string, request, response any) error {
+	var body io.Reader
+
+	if request != nil {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return errors.Wrap(pkgerrors.ErrAdminRequest, err.Error())
+		}
+
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(adminURL, "/")+path, body)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrAdminRequest, err.Error())
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if token := viper.GetString(config.KeyAdminToken); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrAdminRequest, err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrAdminRequest, err.Error())
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = strings.TrimSpace(string(data))
+		}
+
+		return errors.Wrap(pkgerrors.ErrAdminRequest, fmt.Sprintf("%s: %s", resp.Status, apiErr.Error))
+	}
+
+	if response == nil {
+		return nil
+	}
+
+	if err = json.Unmarshal(data, response); err != nil {
+		return errors.Wrap(pkgerrors.ErrAdminRequest, err.Error())
+	}
+
+	return nil
+}
+
+// normalizeFingerprint returns the lower case hex fingerprint, without the algorithm prefix and the separators.
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.NewReplacer("sha256:", "", "SHA256:", "", ":", "").Replace(fingerprint))
+}
+
+// findRecord returns the ledger record matching the serial, or the fingerprint.
+func findRecord(ctx context.Context, l ledger.Ledger, serial, fingerprint string) (ledger.Record, error) {
+	if serial != "" {
+		number, ok := new(big.Int).SetString(strings.ReplaceAll(strings.TrimPrefix(serial, "0x"), ":", ""), 16)
+		if !ok {
+			return ledger.Record{}, errors.Wrap(pkgerrors.ErrRevoke, "invalid serial "+serial)
+		}
+
+		return l.Get(ctx, number.Text(16)) //nolint:wrapcheck
+	}
+
+	records, err := l.List(ctx)
+	if err != nil {
+		return ledger.Record{}, err //nolint:wrapcheck
+	}
+
+	fingerprint = normalizeFingerprint(fingerprint)
+
+	for _, record := range records {
+		if record.Fingerprint == fingerprint {
+			return record, nil
+		}
+	}
+
+	return ledger.Record{}, pkgerrors.ErrLedgerNotFound
+}
+
+// revokeHandler revokes the certificate in the ledger, publishing the event, and regenerating the CRL when requested.
+func revokeHandler(l ledger.Ledger, bus *events.Bus, crlCache *crl.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request revokeRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&request); err != nil {
+			admin.WriteError(w, http.StatusBadRequest, err)
+
+			return
+		}
+
+		reason, err := crl.ParseReason(request.Reason)
+		if err != nil {
+			admin.WriteError(w, http.StatusBadRequest, err)
+
+			return
+		}
+
+		record, err := findRecord(r.Context(), l, request.Serial, request.Fingerprint)
+
+		switch {
+		case errors.Is(err, pkgerrors.ErrLedgerNotFound):
+			admin.WriteError(w, http.StatusNotFound, err)
+
+			return
+		case errors.Is(err, pkgerrors.ErrRevoke):
+			admin.WriteError(w, http.StatusBadRequest, err)
+
+			return
+		case err != nil:
+			admin.WriteError(w, http.StatusServiceUnavailable, err)
+
+			return
+		case record.Revoked():
+			admin.WriteError(w, http.StatusConflict, errors.Wrap(pkgerrors.ErrRevoke, "already revoked at "+record.RevokedAt.Format(time.RFC3339)))
+
+			return
+		}
+
+		revokedAt := time.Now().UTC()
+		if err = l.Revoke(r.Context(), record.Serial, reason, revokedAt); err != nil {
+			admin.WriteError(w, http.StatusServiceUnavailable, err)
+
+			return
+		}
+
+		record.RevokedAt, record.RevocationReason = &revokedAt, reason
+
+		logging.FromContext(r.Context()).Info("Revoked the certificate", "serial", record.Serial, "common_name", record.CommonName,
+			"reason", reason)
+
+		bus.Emit(events.Event{
+			Type:        events.TypeRevoked,
+			Time:        revokedAt,
+			Serial:      record.Serial,
+			Fingerprint: record.Fingerprint,
+			SPKIHash:    record.SPKIHash,
+			CommonName:  record.CommonName,
+			NotAfter:    &record.NotAfter,
+			Reason:      request.Reason,
+		})
+
+		response := revokeResponse{Record: record}
+
+		if request.RegenerateCRL {
+			if err = crlCache.Regenerate(r.Context()); err != nil {
+				response.Warning = err.Error()
+			} else {
+				response.CRLRegenerated = true
+			}
+		}
+
+		admin.WriteJSON(w, http.StatusOK, response)
+	}
+}
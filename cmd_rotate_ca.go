@@ -0,0 +1,351 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+)
+
+const (
+	cliRotationDir      = "dir"
+	cliRotationNewCert  = "new-ca-cert"
+	cliRotationNewKey   = "new-ca-key"
+	cliRotationValidity = "validity"
+	cliSecretName       = "secret-name"
+	cliSecretNamespace  = "secret-namespace"
+
+	// Files of the rotation directory.
+	rotationNewCert     = "ca.crt"
+	rotationNewKey      = "ca.key"
+	rotationOldCert     = "old-ca.crt"
+	rotationOldKey      = "old-ca.key"
+	rotationCrossSigned = "cross-signed.crt"
+	rotationBundle      = "ca-bundle.crt"
+	rotationSecret      = "secret.yaml"
+)
+
+// newRotateCACommand returns the command orchestrating the Machine CA rotation in three steps:
+// prepare the new CA distributing both to the nodes, promote it to sign, and retire the old one.
+func newRotateCACommand() *cobra.Command {
+	rotateCmd := &cobra.Command{
+		Use:   "rotate-ca",
+		Short: "Rotate the Machine CA: prepare, promote, and retire",
+		Long: `Rotate the Machine CA without breaking the trust of the nodes:
+
+  1. prepare: generate (or import) the new CA, cross-sign it with the current one, and write the
+     dual-trust bundle to --ca-bundle-path: the signer keeps signing with the current CA, returning both.
+  2. promote: once the nodes trust both CAs, replace the CA at --ca-cert-path and --ca-key-path with the new one.
+  3. retire: once all the certificates are renewed, drop the old CA from the bundle and delete its backed up key.
+
+The signer must be restarted after each step to load the updated files.`,
+	}
+	rotateCmd.PersistentFlags().String(cliRotationDir, "ca-rotation", "Directory holding the rotation artifacts")
+	rotateCmd.PersistentFlags().String(cliSecretName, "", "Name of the Secret holding the CA: when set, a Secret manifest is written in the rotation directory")
+	rotateCmd.PersistentFlags().String(cliSecretNamespace, "default", "Namespace of the Secret holding the CA")
+
+	prepareCmd := &cobra.Command{
+		Use:   "prepare",
+		Short: "Generate the new CA and the dual-trust bundle",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			dir, _ := cmd.Flags().GetString(cliRotationDir)
+			newCertPath, _ := cmd.Flags().GetString(cliRotationNewCert)
+			newKeyPath, _ := cmd.Flags().GetString(cliRotationNewKey)
+			validity, _ := cmd.Flags().GetDuration(cliRotationValidity)
+
+			oldCert, oldKey, err := loadCA(viper.GetString(config.KeyCACertificatePath), viper.GetString(config.KeyCAPrivateKeyPath))
+			if err != nil {
+				return err
+			}
+
+			var (
+				newCert *x509.Certificate
+				newKey  crypto.Signer
+			)
+
+			switch {
+			case newCertPath != "" && newKeyPath != "":
+				if newCert, newKey, err = loadCA(newCertPath, newKeyPath); err != nil {
+					return err
+				}
+
+				logging.FromContext(cmd.Context()).Info("Imported the new CA", "subject", newCert.Subject.String())
+			case newCertPath != "" || newKeyPath != "":
+				return errors.Wrap(pkgerrors.ErrCARotation, "both --new-ca-cert and --new-ca-key are required to import the new CA")
+			default:
+				if newKey, err = pki.GenerateKeyLike(oldCert.PublicKey); err != nil {
+					return err //nolint:wrapcheck
+				}
+
+				template, templateErr := pki.CATemplate(oldCert.Subject, validity)
+				if templateErr != nil {
+					return templateErr //nolint:wrapcheck
+				}
+
+				if newCert, err = pki.Sign(template, newKey.Public(), nil, newKey); err != nil {
+					return err //nolint:wrapcheck
+				}
+
+				logging.FromContext(cmd.Context()).Info("Generated the new CA", "subject", newCert.Subject.String(),
+					"not_after", newCert.NotAfter.Format(time.RFC3339))
+			}
+			// The cross-signed certificate lets the certificates issued by the new CA chain to the old one.
+			crossTemplate, err := pki.CATemplate(newCert.Subject, time.Until(oldCert.NotAfter))
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			crossTemplate.SubjectKeyId = newCert.SubjectKeyId
+			// Set explicitly, since it's omitted when the issuer and the subject names are the same.
+			crossTemplate.AuthorityKeyId = oldCert.SubjectKeyId
+
+			crossSigned, err := pki.Sign(crossTemplate, newCert.PublicKey, oldCert, oldKey)
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			newKeyPEM, err := pki.EncodePrivateKey(newKey)
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			bundle := pki.EncodeCertificates(oldCert, newCert)
+
+			if err = os.MkdirAll(dir, 0o700); err != nil {
+				return errors.Wrap(pkgerrors.ErrCARotation, err.Error())
+			}
+
+			files := map[string][]byte{
+				rotationNewCert:     pki.EncodeCertificates(newCert),
+				rotationNewKey:      newKeyPEM,
+				rotationCrossSigned: pki.EncodeCertificates(crossSigned),
+				rotationBundle:      bundle,
+			}
+			for name, data := range files {
+				if err = writeRotationFile(filepath.Join(dir, name), data); err != nil {
+					return err
+				}
+			}
+
+			if err = updateBundle(cmd.Context(), bundle); err != nil {
+				return err
+			}
+
+			oldCertPEM, err := os.ReadFile(viper.GetString(config.KeyCACertificatePath))
+			if err != nil {
+				return errors.Wrap(pkgerrors.ErrReadFile, err.Error())
+			}
+
+			oldKeyPEM, err := os.ReadFile(viper.GetString(config.KeyCAPrivateKeyPath))
+			if err != nil {
+				return errors.Wrap(pkgerrors.ErrReadFile, err.Error())
+			}
+
+			if err = writeSecretManifest(cmd, oldCertPEM, oldKeyPEM, bundle); err != nil {
+				return err
+			}
+
+			logging.FromContext(cmd.Context()).Info("Next: restart the signer, wait for the nodes to trust both CAs, then run: rotate-ca promote")
+
+			return nil
+		},
+	}
+	prepareCmd.Flags().String(cliRotationNewCert, "", "Existing new CA certificate, generated when empty")
+	prepareCmd.Flags().String(cliRotationNewKey, "", "Existing new CA private key, generated when empty")
+	prepareCmd.Flags().Duration(cliRotationValidity, 10*365*24*time.Hour, "Validity of the generated CA")
+
+	promoteCmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Sign with the new CA, still trusting the old one",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			dir, _ := cmd.Flags().GetString(cliRotationDir)
+			certPath, keyPath := viper.GetString(config.KeyCACertificatePath), viper.GetString(config.KeyCAPrivateKeyPath)
+
+			newCert, _, err := loadCA(filepath.Join(dir, rotationNewCert), filepath.Join(dir, rotationNewKey))
+			if err != nil {
+				return err
+			}
+
+			if current, _, currentErr := loadCA(certPath, keyPath); currentErr == nil && current.Equal(newCert) {
+				return errors.Wrap(pkgerrors.ErrCARotation, "the new CA has been already promoted")
+			}
+			// Back up the old CA, so the rotation can be rolled back until it's retired.
+			for source, target := range map[string]string{certPath: rotationOldCert, keyPath: rotationOldKey} {
+				data, readErr := os.ReadFile(source)
+				if readErr != nil {
+					return errors.Wrap(pkgerrors.ErrReadFile, readErr.Error())
+				}
+
+				if err = writeRotationFile(filepath.Join(dir, target), data); err != nil {
+					return err
+				}
+			}
+
+			newCertPEM, err := os.ReadFile(filepath.Join(dir, rotationNewCert))
+			if err != nil {
+				return errors.Wrap(pkgerrors.ErrReadFile, err.Error())
+			}
+
+			newKeyPEM, err := os.ReadFile(filepath.Join(dir, rotationNewKey))
+			if err != nil {
+				return errors.Wrap(pkgerrors.ErrReadFile, err.Error())
+			}
+
+			bundle, err := os.ReadFile(filepath.Join(dir, rotationBundle))
+			if err != nil {
+				return errors.Wrap(pkgerrors.ErrReadFile, err.Error())
+			}
+
+			if err = writeSecretManifest(cmd, newCertPEM, newKeyPEM, bundle); err != nil {
+				return err
+			}
+
+			// The mounted Secret files are read-only: the Secret manifest updates them.
+			if name, _ := cmd.Flags().GetString(cliSecretName); name == "" {
+				if err = writeRotationFile(certPath, newCertPEM); err != nil {
+					return err
+				}
+
+				if err = writeRotationFile(keyPath, newKeyPEM); err != nil {
+					return err
+				}
+
+				logging.FromContext(cmd.Context()).Info("Promoted the new CA", "subject", newCert.Subject.String(), "path", certPath)
+			}
+
+			logging.FromContext(cmd.Context()).Info("Next: restart the signer, wait for all the certificates to be renewed, then run: rotate-ca retire")
+
+			return nil
+		},
+	}
+
+	retireCmd := &cobra.Command{
+		Use:   "retire",
+		Short: "Drop the old CA from the trust bundle",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			dir, _ := cmd.Flags().GetString(cliRotationDir)
+
+			newCertPEM, err := os.ReadFile(filepath.Join(dir, rotationNewCert))
+			if err != nil {
+				return errors.Wrap(pkgerrors.ErrReadFile, err.Error())
+			}
+
+			currentPEM, err := os.ReadFile(viper.GetString(config.KeyCACertificatePath))
+			if err != nil {
+				return errors.Wrap(pkgerrors.ErrReadFile, err.Error())
+			}
+
+			if strings.TrimSpace(string(currentPEM)) != strings.TrimSpace(string(newCertPEM)) {
+				return errors.Wrap(pkgerrors.ErrCARotation, "the new CA has not been promoted yet")
+			}
+
+			if err = updateBundle(cmd.Context(), newCertPEM); err != nil {
+				return err
+			}
+
+			newKeyPEM, err := os.ReadFile(filepath.Join(dir, rotationNewKey))
+			if err != nil {
+				return errors.Wrap(pkgerrors.ErrReadFile, err.Error())
+			}
+
+			if err = writeSecretManifest(cmd, newCertPEM, newKeyPEM, newCertPEM); err != nil {
+				return err
+			}
+
+			if err = os.Remove(filepath.Join(dir, rotationOldKey)); err != nil && !os.IsNotExist(err) {
+				return errors.Wrap(pkgerrors.ErrCARotation, err.Error())
+			}
+
+			logging.FromContext(cmd.Context()).Info("Retired the old CA: its backed up private key has been deleted")
+
+			return nil
+		},
+	}
+
+	rotateCmd.AddCommand(prepareCmd, promoteCmd, retireCmd)
+
+	return rotateCmd
+}
+
+// loadCA returns the CA certificate and its private key.
+func loadCA(certPath, keyPath string) (*x509.Certificate, crypto.Signer, error) {
+	caBackend, err := loadLocalBackend("rotation", certPath, keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := loadPrivateKey(keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return caBackend.Certificate(), key, nil
+}
+
+// updateBundle writes the trust bundle to the configured path, if any.
+func updateBundle(ctx context.Context, bundle []byte) error {
+	bundlePath := viper.GetString(config.KeyCABundlePath)
+	if bundlePath == "" {
+		logging.FromContext(ctx).Warn("No --ca-bundle-path configured: distribute the bundle of the rotation directory manually")
+
+		return nil
+	}
+
+	if err := writeRotationFile(bundlePath, bundle); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("Updated the CA bundle", "path", bundlePath)
+
+	return nil
+}
+
+// writeRotationFile writes the file readable by the owner only, since most of them are private keys.
+func writeRotationFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return errors.Wrap(pkgerrors.ErrCARotation, err.Error())
+	}
+
+	return nil
+}
+
+// writeSecretManifest writes the manifest of the Secret holding the CA, applied with kubectl apply:
+// the keys not managed by the rotation, such as the token, are preserved.
+func writeSecretManifest(cmd *cobra.Command, certPEM, keyPEM, bundle []byte) error {
+	name, _ := cmd.Flags().GetString(cliSecretName)
+	if name == "" {
+		return nil
+	}
+
+	dir, _ := cmd.Flags().GetString(cliRotationDir)
+	namespace, _ := cmd.Flags().GetString(cliSecretNamespace)
+	path := filepath.Join(dir, rotationSecret)
+
+	manifest := secretManifest(name, namespace, "Opaque", map[string][]byte{
+		"tls.crt":       certPEM,
+		"tls.key":       keyPEM,
+		"ca-bundle.crt": bundle,
+	})
+	if err := writeRotationFile(path, manifest); err != nil {
+		return err
+	}
+
+	logging.FromContext(cmd.Context()).Info("Wrote the Secret manifest, apply it with kubectl apply -f", "path", path)
+
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/token"
+)
+
+const (
+	cliTokenGracePeriod = "grace-period"
+	cliTokenOutDir      = "out-dir"
+)
+
+// loadTokens returns the source of the Talos tokens: the file when configured, the static value otherwise.
+func loadTokens(cfg config.Tokens) (*token.Source, error) {
+	if cfg.Path != "" {
+		return token.NewFile(cfg.Path) //nolint:wrapcheck
+	}
+
+	return token.NewStatic(cfg.Token) //nolint:wrapcheck
+}
+
+// newTokenCommand returns the command managing the Talos tokens.
+func newTokenCommand() *cobra.Command {
+	tokenCmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage the Talos tokens accepted by the signer",
+	}
+
+	tokenCmd.AddCommand(newTokenRotateCommand(), newTokenHashCommand())
+
+	return tokenCmd
+}
+
+// newTokenRotateCommand returns the command replacing the current token with a new one.
+func newTokenRotateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Generate a new token, keeping the replaced one valid for a grace period",
+		Long: `Generate a new Talos token, replacing the current one of the configured source: the --talos-token-path file,
+reloaded by the running signer, or the Secret holding the token, whose manifest is written to --out-dir when
+--secret-name is set. With --grace-period the replaced token is still accepted until the nodes are patched.
+
+The machine configuration patch setting the new token is printed, to be applied to the nodes with
+talosctl patch machineconfig.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			grace, _ := cmd.Flags().GetDuration(cliTokenGracePeriod)
+			outDir, _ := cmd.Flags().GetString(cliTokenOutDir)
+			secretName, _ := cmd.Flags().GetString(cliSecretName)
+			secretNamespace, _ := cmd.Flags().GetString(cliSecretNamespace)
+			tokenPath := viper.GetString(config.KeyTalosTokenPath)
+
+			if tokenPath == "" && secretName == "" {
+				return errors.Wrap(pkgerrors.ErrToken, "no token source to update: set --talos-token-path or --secret-name")
+			}
+
+			source, err := loadTokens(config.Read(viper.GetViper()).Tokens)
+			if err != nil {
+				return err
+			}
+
+			current, err := token.Generate()
+			if err != nil {
+				return err //nolint:wrapcheck
+			}
+
+			now := time.Now()
+			tokens := source.Get(cmd.Context()).Rotate(current, grace, now).Encode(now)
+
+			if secretName != "" {
+				if err = os.MkdirAll(outDir, 0o700); err != nil {
+					return errors.Wrap(pkgerrors.ErrWriteFile, err.Error())
+				}
+
+				path := filepath.Join(outDir, "secret.yaml")
+				if err = os.WriteFile(path, secretManifest(secretName, secretNamespace, "Opaque", map[string][]byte{"token": tokens}), 0o600); err != nil {
+					return errors.Wrap(pkgerrors.ErrWriteFile, err.Error())
+				}
+
+				logging.FromContext(cmd.Context()).Info("Wrote the Secret manifest, apply it with kubectl apply -f", "path", path)
+			} else {
+				if err = writeTokens(tokenPath, tokens); err != nil {
+					return err
+				}
+
+				logging.FromContext(cmd.Context()).Info("Updated the tokens, reloaded by the running signer", "path", tokenPath)
+			}
+
+			if grace > 0 {
+				logging.FromContext(cmd.Context()).Info("The replaced token is still accepted: patch the nodes meanwhile",
+					"until", now.Add(grace).Format(time.RFC3339))
+			} else {
+				logging.FromContext(cmd.Context()).Warn("The replaced token is not accepted anymore: patch the nodes immediately")
+			}
+
+			_, err = fmt.Fprintf(os.Stdout, "machine:\n  token: %s\n", current)
+
+			return err //nolint:wrapcheck
+		},
+	}
+
+	cmd.Flags().Duration(cliTokenGracePeriod, 0, "Time the replaced token is still accepted, zero to reject it immediately")
+	cmd.Flags().String(cliTokenOutDir, ".", "Directory the Secret manifest is written to")
+	cmd.Flags().String(cliSecretName, "", "Name of the Secret holding the token: when set, its manifest is written rather than updating --talos-token-path")
+	cmd.Flags().String(cliSecretNamespace, "default", "Namespace of the Secret holding the token")
+
+	return cmd
+}
+
+// newTokenHashCommand returns the command hashing the tokens, to be configured in place of their plaintext.
+func newTokenHashCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "hash",
+		Short: "Hash the tokens read from the standard input, one per line",
+		Long: `Hash the Talos tokens read from the standard input, one per line, with a random salt: the hashes are accepted
+in place of the tokens by TALOS_TOKEN, TALOS_PREVIOUS_TOKENS, the TALOS_TOKEN_PATH file, and the token classes,
+keeping the plaintext tokens out of the flags, the environment, and the files of the signer.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			scanner := bufio.NewScanner(cmd.InOrStdin())
+
+			for scanner.Scan() {
+				if value := strings.TrimSpace(scanner.Text()); value != "" {
+					if _, err := fmt.Fprintln(cmd.OutOrStdout(), token.Hash(value)); err != nil {
+						return err //nolint:wrapcheck
+					}
+				}
+			}
+
+			if err := scanner.Err(); err != nil {
+				return errors.Wrap(pkgerrors.ErrToken, err.Error())
+			}
+
+			return nil
+		},
+	}
+}
+
+// writeTokens replaces the tokens file atomically, so the running signer never reads it partially written.
+func writeTokens(path string, tokens []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrWriteFile, err.Error())
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err = tmp.Write(tokens); err != nil {
+		_ = tmp.Close()
+
+		return errors.Wrap(pkgerrors.ErrWriteFile, err.Error())
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errors.Wrap(pkgerrors.ErrWriteFile, err.Error())
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrap(pkgerrors.ErrWriteFile, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	"github.com/clastix/talos-csr-signer/pkg/denylist"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/policy"
+	"github.com/clastix/talos-csr-signer/pkg/preflight"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+)
+
+// newValidateCSRCommand returns the command evaluating a CSR against the signing policy, offline.
+func newValidateCSRCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate-csr <csr-file>",
+		Short: "Evaluate a CSR against the signing policy, offline",
+		Long: `Evaluate the PEM encoded CSR, read from the file or the standard input with -, against the rules
+enforced by the signer with the current configuration, reporting which ones pass or fail without contacting
+the signer. The rules depending on the live state, such as the issuance quota, are reported as skipped.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			var (
+				csrPEM []byte
+				err    error
+			)
+
+			if args[0] == "-" {
+				csrPEM, err = io.ReadAll(os.Stdin)
+			} else {
+				csrPEM, err = os.ReadFile(args[0])
+			}
+
+			if err != nil {
+				return errors.Wrap(pkgerrors.ErrReadFile, err.Error())
+			}
+
+			report := &preflight.Report{}
+			validateCSR(report, config.Read(viper.GetViper()), csrPEM)
+			report.Print(os.Stdout)
+
+			if failed := report.Count(preflight.StatusFail); failed > 0 {
+				return errors.Wrapf(pkgerrors.ErrPolicyViolation, "%d failed rules", failed)
+			}
+
+			return nil
+		},
+	}
+}
+
+// validateCSR adds to the report the rules the signer evaluates before issuing a certificate for the CSR.
+func validateCSR(report *preflight.Report, cfg *config.Config, csrPEM []byte) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil {
+		report.Fail("pem", "failed to decode PEM CSR")
+
+		return
+	}
+
+	report.Pass("pem", "decoded the %s block", block.Type)
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		report.Fail("parse", "failed to parse CSR: %v", err)
+
+		return
+	}
+
+	report.Pass("parse", "subject %q, DNS names %v, IP addresses %v", csr.Subject.String(), csr.DNSNames, csr.IPAddresses)
+
+	if denyListPath := cfg.Policy.DenyListPath; denyListPath != "" {
+		list, listErr := denylist.Load(denyListPath)
+
+		switch denied, found := list.Match(csr, ""); {
+		case listErr != nil:
+			report.Fail("denylist", "%v", listErr)
+		case found:
+			report.Fail("denylist", "the %s is deny-listed", denied)
+		default:
+			report.Pass("denylist", "no identity of the CSR is deny-listed")
+		}
+	} else {
+		report.Pass("denylist", "deny-list disabled")
+	}
+
+	chain, err := newPolicy(cfg.Policy)
+	if err != nil {
+		report.Fail("policy", "%v", err)
+
+		return
+	}
+
+	roles, err := newRoles(cfg.Roles)
+	if err != nil {
+		report.Fail("profile", "%v", err)
+
+		return
+	}
+
+	chain = chain.Then(policy.ProfileKeyPolicy{Roles: roles})
+
+	for _, verdict := range (policy.Chain{policy.Signature{}}).Then(chain...).Evaluate(context.Background(), csr) {
+		switch {
+		case verdict.Allowed():
+			report.Pass(verdict.Validator, "%s", verdict.Reason)
+		case verdict.Rule != "":
+			report.Fail(verdict.Validator, "%s (%s)", verdict.Reason, verdict.Rule)
+		default:
+			report.Fail(verdict.Validator, "%s", verdict.Reason)
+		}
+	}
+
+	if cfg.Policy.DNSVerification {
+		report.Skip("dns", "DNS names %v must resolve to the peer address, evaluated by the live signer", csr.DNSNames)
+	} else {
+		report.Pass("dns", "DNS verification disabled")
+	}
+
+	if windows := cfg.Policy.EnrollmentWindows; windows != "" {
+		report.Skip("enrollment", "new identities are enrolled in the %q windows (%s), renewals evaluated against the live ledger",
+			windows, cfg.Policy.EnrollmentTimezone)
+	} else {
+		report.Pass("enrollment", "enrollment windows disabled")
+	}
+
+	if quota := cfg.Issuance.Quota; quota > 0 {
+		report.Skip("quota", "%d certificates per %s for %q, evaluated against the live ledger",
+			quota, cfg.Issuance.QuotaWindow, csr.Subject.CommonName)
+	} else {
+		report.Pass("quota", "issuance quota disabled")
+	}
+
+	if cooldown := cfg.Issuance.ReissueCooldown; cooldown > 0 {
+		report.Skip("cooldown", "a new certificate for the same identity is refused for %s, evaluated against the live ledger", cooldown)
+	} else {
+		report.Pass("cooldown", "re-issuance cooldown disabled")
+	}
+
+	if ttl := cfg.Issuance.ProofOfPossessionTTL; ttl > 0 {
+		report.Skip("proof-of-possession", "a nonce valid for %s must be signed with the CSR private key, evaluated by the live signer", ttl)
+	} else {
+		report.Pass("proof-of-possession", "proof-of-possession challenge disabled")
+	}
+
+	report.Pass("profile", "%s", issuedProfile(csr, roles))
+}
+
+// issuedProfile describes the certificate the signer issues for the CSR.
+func issuedProfile(csr *x509.CertificateRequest, roles *signer.Roles) string {
+	organizations := "none"
+	if len(csr.Subject.Organization) > 0 {
+		organizations = strings.Join(csr.Subject.Organization, ",")
+	}
+
+	role := roles.Detect(csr)
+	profile := roles.Profile(role)
+
+	return fmt.Sprintf("would issue a %s certificate for the %s %q (organizations: %s), valid for %s",
+		strings.Join(extKeyUsageNames(profile.ExtKeyUsage), "+"), role, csr.Subject.CommonName, organizations, profile.Validity)
+}
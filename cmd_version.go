@@ -0,0 +1,56 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clastix/talos-csr-signer/pkg/admin"
+	"github.com/clastix/talos-csr-signer/pkg/version"
+)
+
+// newVersionCommand returns the command printing the build metadata.
+func newVersionCommand() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the version and build metadata",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			info := version.Get()
+			out := cmd.OutOrStdout()
+
+			if output == "json" {
+				encoder := json.NewEncoder(out)
+				encoder.SetIndent("", "  ")
+
+				return encoder.Encode(info) //nolint:wrapcheck
+			}
+
+			_, err := fmt.Fprintf(out, "Version:    %s\n"+
+				"Git commit: %s\n"+
+				"Build date: %s\n"+
+				"Go:         %s\n"+
+				"Protobuf:   %s\n"+
+				"gRPC:       %s\n"+
+				"Platform:   %s\n",
+				info.Version, info.GitCommit, info.BuildDate, info.GoVersion, info.ProtobufVersion, info.GRPCVersion, info.Platform)
+
+			return err //nolint:wrapcheck
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "Output format: text or json")
+
+	return cmd
+}
+
+// versionHandler serves the build metadata of the running signer.
+func versionHandler(w http.ResponseWriter, _ *http.Request) {
+	admin.WriteJSON(w, http.StatusOK, version.Get())
+}
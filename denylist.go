@@ -0,0 +1,25 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	"github.com/clastix/talos-csr-signer/pkg/denylist"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/server"
+)
+
+// watchDenyList reloads the deny-list of the server when modified, until the context is done. A deny-list failing to
+// load is ignored, keeping the previous one.
+func watchDenyList(ctx context.Context, cfg *config.Config, srv *server.Server) {
+	path := cfg.Policy.DenyListPath
+
+	denylist.Watch(ctx, path, cfg.Policy.DenyListReloadInterval, srv.DenyList, func(list *denylist.List) {
+		srv.ReloadDenyList(list)
+
+		logging.FromContext(ctx).Info("Reloaded the deny-list", "path", path)
+	})
+}
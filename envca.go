@@ -0,0 +1,92 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+// envCA is the CA of the base64 encoded settings, such as the CA_CERT_B64 and CA_KEY_B64 environment variables, for
+// the environments without volumes such as the serverless or the scratch containers.
+type envCA struct {
+	ca  *backend.Local
+	key crypto.Signer
+}
+
+// loadEnvCA decodes the base64 encoded PEM CA certificate and private key, nil when not configured. The private key
+// may be encrypted, with a passphrase or by sops or age, as the one of the CA files.
+func loadEnvCA(ctx context.Context, certB64, keyB64 string) (*envCA, error) {
+	if certB64 == "" && keyB64 == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	caCertPEM, err := decodeB64Setting(config.KeyCACertificateB64, certB64)
+	if err != nil {
+		return nil, err
+	}
+
+	caKeyPEM, err := decodeB64Setting(config.KeyCAPrivateKeyB64, keyB64)
+	if err != nil {
+		return nil, err
+	}
+
+	if caKeyPEM, err = decryptFile(config.KeyCAPrivateKeyB64, caKeyPEM); err != nil {
+		return nil, err
+	}
+
+	caPrivateKey, err := parsePrivateKey(caKeyPEM)
+	if err != nil {
+		return nil, errors.Wrap(err, config.KeyCAPrivateKeyB64)
+	}
+
+	local, err := backend.NewLocal("local", caCertPEM, caPrivateKey)
+	if err != nil {
+		return nil, errors.Wrap(err, config.KeyCACertificateB64)
+	}
+
+	logging.FromContext(ctx).Info("Loaded the CA from the base64 encoded settings", "serial", local.Certificate().SerialNumber.Text(16))
+
+	return &envCA{ca: local, key: caPrivateKey}, nil
+}
+
+// decodeB64Setting decodes the base64 value of the setting, ignoring the line breaks of the wrapped encodings.
+func decodeB64Setting(key, value string) ([]byte, error) {
+	if value == "" {
+		return nil, errors.Wrap(pkgerrors.ErrConfig, key+" is missing, the base64 encoded CA certificate and private key must be set together")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrConfig, key+" is not base64 encoded: "+err.Error())
+	}
+
+	return data, nil
+}
+
+// loadSettingsCA returns the CA certificate and its private key of the settings, for the commands signing with the
+// CA: the base64 encoded ones when set, otherwise the ones of the CA files.
+func loadSettingsCA(ctx context.Context) (*x509.Certificate, crypto.Signer, error) {
+	held, err := loadEnvCA(ctx, viper.GetString(config.KeyCACertificateB64), viper.GetString(config.KeyCAPrivateKeyB64))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if held != nil {
+		return held.ca.Certificate(), held.key, nil
+	}
+
+	return loadCA(viper.GetString(config.KeyCACertificatePath), viper.GetString(config.KeyCAPrivateKeyPath))
+}
@@ -0,0 +1,45 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Command plugin is an example plugin serving a policy validator, rejecting the CSRs whose Common Name is
+// listed in the comma separated DENIED_COMMON_NAMES environment variable. Build it with go build, and
+// start the signer with PLUGINS=/path/to/plugin.
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"os"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/clastix/talos-csr-signer/pkg/plugin"
+	"github.com/clastix/talos-csr-signer/pkg/policy"
+)
+
+// denyList rejects the denied Common Names.
+type denyList struct {
+	denied []string
+}
+
+// Name implements policy.Validator.
+func (denyList) Name() string {
+	return "deny-list"
+}
+
+// Validate implements policy.Validator.
+func (d denyList) Validate(_ context.Context, csr *x509.CertificateRequest) policy.Verdict {
+	if slices.Contains(d.denied, csr.Subject.CommonName) {
+		return policy.Deny("deny-list", codes.PermissionDenied, "Common Name %q is denied", csr.Subject.CommonName)
+	}
+
+	return policy.Allow("deny-list", "Common Name %q is not denied", csr.Subject.CommonName)
+}
+
+func main() {
+	plugin.Serve(plugin.Plugins{
+		Validator: denyList{denied: strings.Split(os.Getenv("DENIED_COMMON_NAMES"), ",")},
+	})
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+)
+
+// sanList is a list of SANs, either a YAML sequence or a comma separated string.
+type sanList []string
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (l *sanList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = config.SplitList(node.Value)
+
+		return nil
+	}
+
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	*l = values
+
+	return nil
+}
+
+// newExtraSANs returns the SANs added to every certificate, along with the ones added to the certificates of the nodes
+// read from the configured file.
+func newExtraSANs(cfg config.Issuance) (signer.SANs, map[string]signer.SANs, error) {
+	extraSANs, err := signer.ParseSANs(cfg.ExtraSANs)
+	if err != nil {
+		return signer.SANs{}, nil, err //nolint:wrapcheck
+	}
+
+	if cfg.ExtraSANsPath == "" {
+		return extraSANs, nil, nil
+	}
+
+	nodeSANs, err := loadNodeSANs(cfg.ExtraSANsPath)
+	if err != nil {
+		return signer.SANs{}, nil, err
+	}
+
+	return extraSANs, nodeSANs, nil
+}
+
+// loadNodeSANs returns the SANs added to the certificates of the nodes, keyed by their Common Name in the YAML file,
+// such as:
+//
+//	worker-1: [worker-1.example.com, 203.0.113.11]
+//	worker-2: worker-2.example.com,203.0.113.12
+func loadNodeSANs(path string) (map[string]signer.SANs, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrReadFile, err.Error())
+	}
+
+	var table map[string]sanList
+	if err := yaml.Unmarshal(data, &table); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrProfile, "invalid extra SANs file "+path+": "+err.Error())
+	}
+
+	nodeSANs := make(map[string]signer.SANs, len(table))
+
+	for commonName, values := range table {
+		if nodeSANs[commonName], err = signer.ParseSANs(values); err != nil {
+			return nil, errors.Wrap(err, "extra SANs of "+commonName)
+		}
+	}
+
+	return nodeSANs, nil
+}
@@ -6,157 +6,61 @@ package main
 
 import (
 	"context"
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
-	"log"
-	"net"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
 
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	"github.com/clastix/talos-csr-signer/pkg/config"
 	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
-	pb "github.com/clastix/talos-csr-signer/pkg/proto"
+	"github.com/clastix/talos-csr-signer/pkg/events"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
 	"github.com/clastix/talos-csr-signer/pkg/server"
 )
 
-const (
-	cliPortName           = "port"
-	cliCACertificatePath  = "ca-cert-path"
-	cliCAPrivateKeyPath   = "ca-key-path"
-	cliTLSCertificatePath = "tls-cert-path"
-	cliTLSPrivateKeyPath  = "tls-key-path"
-	cliTalosToken         = "talos-token"
-)
-
 func main() {
 	rootCmd := &cobra.Command{
 		Use:   "talos-csr-signer",
 		Short: "gRPC server for signing Talos CSR",
-		PreRunE: func(*cobra.Command, []string) error {
-			switch {
-			case viper.GetInt(cliPortName) <= 0:
-				return pkgerrors.ErrMissingPort
-			case viper.GetInt(cliPortName) > 65535:
-				return pkgerrors.ErrPortOutOfRange
-			case viper.GetString(cliTalosToken) == "":
-				return pkgerrors.ErrMissingToken
-			case viper.GetString(cliCACertificatePath) == "":
-				return errors.Wrap(pkgerrors.ErrMissingPath, "CA certificate path is missing")
-			case viper.GetString(cliCAPrivateKeyPath) == "":
-				return errors.Wrap(pkgerrors.ErrMissingPath, "CA private key path is missing")
-			case viper.GetString(cliTLSCertificatePath) == "":
-				return errors.Wrap(pkgerrors.ErrMissingPath, "server certificate path is missing")
-			case viper.GetString(cliTLSPrivateKeyPath) == "":
-				return errors.Wrap(pkgerrors.ErrMissingPath, "server private key path is missing")
-			}
-
-			return nil
+		// The configuration file is merged before running any subcommand
+		PersistentPreRunE: func(*cobra.Command, []string) error {
+			return config.ReadFile(viper.GetViper()) //nolint:wrapcheck
 		},
-		RunE: func(*cobra.Command, []string) error {
-			// Load CA certificate
-			caCertPEM, caCertErr := os.ReadFile(viper.GetString(cliCACertificatePath))
-			if caCertErr != nil {
-				return errors.Wrap(pkgerrors.ErrReadFile, "failed to read CA certificate: "+caCertErr.Error())
-			}
-			// Load CA private key
-			caKeyPEM, caKeyErr := os.ReadFile(viper.GetString(cliCAPrivateKeyPath))
-			if caKeyErr != nil {
-				return errors.Wrap(pkgerrors.ErrReadFile, "failed to read CA private key: "+caKeyErr.Error())
-			}
-			// Parse CA private key
-			block, _ := pem.Decode(caKeyPEM)
-			if block == nil {
-				return pkgerrors.ErrPemDecoding
-			}
-
-			var caPrivateKey interface{}
-			var privateKeyErr error
-
-			switch block.Type {
-			case "ED25519 PRIVATE KEY":
-				caPrivateKey, privateKeyErr = x509.ParsePKCS8PrivateKey(block.Bytes)
-			case "EC PRIVATE KEY":
-				caPrivateKey, privateKeyErr = x509.ParseECPrivateKey(block.Bytes)
-			case "RSA PRIVATE KEY":
-				caPrivateKey, privateKeyErr = x509.ParsePKCS1PrivateKey(block.Bytes)
-			case "PRIVATE KEY":
-				caPrivateKey, privateKeyErr = x509.ParsePKCS8PrivateKey(block.Bytes)
-			default:
-				return errors.Wrap(pkgerrors.ErrUnsupportedBlockType, block.Type)
-			}
-
-			if privateKeyErr != nil {
-				return errors.Wrap(pkgerrors.ErrParseCertificate, privateKeyErr.Error())
-			}
-
-			cert, crtErr := tls.LoadX509KeyPair(viper.GetString(cliTLSCertificatePath), viper.GetString(cliTLSPrivateKeyPath))
-			if crtErr != nil {
-				return errors.Wrap(pkgerrors.ErrLoadingCertificate, crtErr.Error())
-			}
-			// Create TLS credentials
-			tlsConfig := &tls.Config{ //nolint:gosec
-				Certificates: []tls.Certificate{cert},
-				ClientAuth:   tls.NoClientCert, // Don't require client certificates
-			}
-			creds := credentials.NewTLS(tlsConfig)
-			// Create gRPC Server with TLS
-			srv := &server.Server{
-				CACert:       caCertPEM,
-				CAPrivateKey: caPrivateKey,
-				ValidToken:   viper.GetString(cliTalosToken),
-			}
-
-			port := viper.GetInt(cliPortName)
-			lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := config.Load(viper.GetViper())
 			if err != nil {
-				return errors.Wrap(pkgerrors.ErrServerListen, fmt.Sprintf("%d: %s", port, err.Error()))
+				return err //nolint:wrapcheck
 			}
 
-			grpcServer := grpc.NewServer(grpc.Creds(creds))
-			pb.RegisterSecurityServiceServer(grpcServer, srv)
-
-			log.Printf("Talos CSR Signer listening on port %d with TLS enabled", port)
-
-			if err = grpcServer.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
-				return errors.Wrap(pkgerrors.ErrGRPCServerServe, err.Error())
+			clusters, err := config.Clusters(viper.GetViper())
+			if err != nil {
+				return err //nolint:wrapcheck
 			}
 
-			return nil
+			return run(cmd.Context(), cfg, clusters)
 		},
 	}
 
-	// Flags with their defaults
-	rootCmd.Flags().Int(cliPortName, 50001, "Port to listen on")
-	rootCmd.Flags().String(cliCACertificatePath, "/etc/talos-ca/tls.crt", "Path to CA certificate")
-	rootCmd.Flags().String(cliCAPrivateKeyPath, "/etc/talos-ca/tls.key", "Path to CA private key")
-	rootCmd.Flags().String(cliTLSCertificatePath, "/etc/talos-server-crt/tls.crt", "Path to the Server TLS certificate")
-	rootCmd.Flags().String(cliTLSPrivateKeyPath, "/etc/talos-server-crt/tls.key", "Path to Server TLS private key")
-	rootCmd.Flags().String(cliTalosToken, "", "Talos token")
-	// Bind flags to viper keys
-	_ = viper.BindPFlag(cliPortName, rootCmd.Flags().Lookup(cliPortName))
-	_ = viper.BindPFlag(cliCACertificatePath, rootCmd.Flags().Lookup(cliCACertificatePath))
-	_ = viper.BindPFlag(cliCAPrivateKeyPath, rootCmd.Flags().Lookup(cliCAPrivateKeyPath))
-	_ = viper.BindPFlag(cliTLSCertificatePath, rootCmd.Flags().Lookup(cliTLSCertificatePath))
-	_ = viper.BindPFlag(cliTLSPrivateKeyPath, rootCmd.Flags().Lookup(cliTLSPrivateKeyPath))
-	_ = viper.BindPFlag(cliTalosToken, rootCmd.Flags().Lookup(cliTalosToken))
+	// Flags with their defaults, bound to the viper keys along with their environment variables
+	config.Bind(viper.GetViper(), rootCmd.Flags(), rootCmd.PersistentFlags())
 	// Allow reading from env variables automatically. Env keys are uppercased and `.` replaced with `_`.
 	viper.SetEnvPrefix("")
 	viper.AutomaticEnv()
-	// Explicit env key mapping (to allow different names if desired)
-	_ = viper.BindEnv(cliPortName, "PORT")
-	_ = viper.BindEnv(cliCACertificatePath, "CA_CERT_PATH")
-	_ = viper.BindEnv(cliCAPrivateKeyPath, "CA_KEY_PATH")
-	_ = viper.BindEnv(cliTLSCertificatePath, "TLS_CERT_PATH")
-	_ = viper.BindEnv(cliTLSPrivateKeyPath, "TLS_KEY_PATH")
-	_ = viper.BindEnv(cliTalosToken, "TALOS_TOKEN")
+
+	rootCmd.AddCommand(newLedgerCommand(), newConfigCommand(), newVersionCommand(), newRotateCACommand(), newGenServerCertCommand(), newGetCACommand(), newDoctorCommand(), newRevokeCommand(), newApproveCommand(), newListCommand(), newExportCRLCommand(), newValidateCSRCommand(), newGenNodeCommand(), newTokenCommand())
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -166,3 +70,185 @@ func main() {
 		os.Exit(1) //nolint:gocritic
 	}
 }
+
+// loadLocalBackend reads the CA certificate and its private key from the given paths.
+func loadLocalBackend(name, caCertPath, caKeyPath string) (*backend.Local, error) {
+	// Load CA certificate
+	caCertPEM, caCertErr := os.ReadFile(caCertPath)
+	if caCertErr != nil {
+		return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read CA certificate: "+caCertErr.Error())
+	}
+
+	caPrivateKey, err := loadPrivateKey(caKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return backend.NewLocal(name, caCertPEM, caPrivateKey) //nolint:wrapcheck
+}
+
+// loadPrivateKey reads and parses the PEM encoded CA private key.
+func loadPrivateKey(caKeyPath string) (crypto.Signer, error) {
+	// Load CA private key
+	caKeyPEM, caKeyErr := os.ReadFile(caKeyPath)
+	if caKeyErr != nil {
+		return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read CA private key: "+caKeyErr.Error())
+	}
+
+	if caKeyPEM, caKeyErr = decryptFile(caKeyPath, caKeyPEM); caKeyErr != nil {
+		return nil, caKeyErr
+	}
+
+	return parsePrivateKey(caKeyPEM)
+}
+
+// parsePrivateKey parses the PEM encoded CA private key, which must be able to sign, decrypting it with the CA key
+// passphrase when encrypted.
+func parsePrivateKey(caKeyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(caKeyPEM)
+	if block == nil {
+		return nil, pkgerrors.ErrPemDecoding
+	}
+
+	if pki.IsEncryptedKey(block) {
+		passphrase, err := caKeyPassphrase()
+		if err != nil {
+			return nil, err
+		}
+
+		if block, err = pki.DecryptKey(block, passphrase); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+	}
+
+	var caPrivateKey interface{}
+	var privateKeyErr error
+
+	switch block.Type {
+	case "ED25519 PRIVATE KEY":
+		caPrivateKey, privateKeyErr = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		caPrivateKey, privateKeyErr = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		caPrivateKey, privateKeyErr = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		caPrivateKey, privateKeyErr = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, errors.Wrap(pkgerrors.ErrUnsupportedBlockType, block.Type)
+	}
+
+	if privateKeyErr != nil {
+		return nil, errors.Wrap(pkgerrors.ErrParseCertificate, privateKeyErr.Error())
+	}
+
+	signer, ok := caPrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.Wrapf(pkgerrors.ErrUnsupportedKey, "%T", caPrivateKey)
+	}
+
+	return signer, nil
+}
+
+// newNodeUUIDOptions returns the handling of the node UUID sent by the clients.
+func newNodeUUIDOptions(cfg config.Issuance) (server.NodeUUIDOptions, error) {
+	var opts server.NodeUUIDOptions
+
+	switch cfg.NodeUUID {
+	case "", "disabled":
+		return opts, nil
+	case "optional":
+		opts.Enabled = true
+	case "required":
+		opts.Enabled, opts.Required = true, true
+	default:
+		return opts, errors.Wrap(pkgerrors.ErrConfig, "node UUID must be disabled, optional, or required: "+cfg.NodeUUID)
+	}
+
+	if oid := cfg.NodeUUIDExtensionOID; oid != "" {
+		for _, arc := range strings.Split(oid, ".") {
+			value, err := strconv.Atoi(arc)
+			if err != nil || value < 0 {
+				return opts, errors.Wrap(pkgerrors.ErrConfig, "invalid node UUID extension OID "+oid)
+			}
+
+			opts.ExtensionOID = append(opts.ExtensionOID, value)
+		}
+
+		if len(opts.ExtensionOID) < 2 {
+			return opts, errors.Wrap(pkgerrors.ErrConfig, "invalid node UUID extension OID "+oid)
+		}
+	}
+
+	return opts, nil
+}
+
+// newEventPublisher connects to the event broker, returning the Publisher of the certificate lifecycle events
+// emitted on the Bus.
+func newEventPublisher(ctx context.Context, bus *events.Bus, cfg config.Events) (*events.Publisher, error) {
+	var tlsConfig *tls.Config
+
+	if cfg.TLS {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+
+		if caPath := cfg.TLSCAPath; caPath != "" {
+			caPEM, err := os.ReadFile(caPath)
+			if err != nil {
+				return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read event broker CA: "+err.Error())
+			}
+
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(caPEM) {
+				return nil, errors.Wrap(pkgerrors.ErrPemDecoding, "event broker CA")
+			}
+		}
+	}
+
+	sink, err := events.NewSink(cfg.SinkURL, cfg.Topic, tlsConfig, events.SASL{
+		Mechanism: cfg.SASLMechanism,
+		Username:  cfg.SASLUsername,
+		Password:  cfg.SASLPassword,
+	})
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	logging.FromContext(ctx).Info("Publishing the certificate lifecycle events", "sink", redactedURL(cfg.SinkURL))
+
+	return events.NewPublisher(bus, sink, cfg.BufferSize, 10*time.Second), nil
+}
+
+// waitForFiles waits with an exponential backoff until all the given paths exist, or the timeout expires.
+func waitForFiles(ctx context.Context, timeout time.Duration, paths ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := 500 * time.Millisecond
+
+	for {
+		var missing []string
+
+		for _, path := range paths {
+			if path == "" {
+				continue
+			}
+
+			if _, err := os.Stat(path); err != nil {
+				missing = append(missing, path)
+			}
+		}
+
+		if len(missing) == 0 {
+			return nil
+		}
+
+		logging.FromContext(ctx).Info("Waiting for the mounted files", "backoff", backoff, "missing", missing)
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(pkgerrors.ErrStartupTimeout, fmt.Sprintf("%v", missing))
+		case <-time.After(backoff):
+		}
+
+		backoff = min(2*backoff, 10*time.Second)
+	}
+}
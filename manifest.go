@@ -0,0 +1,31 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// secretManifest returns the YAML manifest of the Secret with the given data, sorted by key.
+func secretManifest(name, namespace, secretType string, data map[string][]byte) []byte {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var manifest strings.Builder
+
+	fmt.Fprintf(&manifest, "apiVersion: v1\nkind: Secret\nmetadata:\n  name: %s\n  namespace: %s\ntype: %s\ndata:\n", name, namespace, secretType)
+
+	for _, key := range keys {
+		fmt.Fprintf(&manifest, "  %s: %s\n", key, base64.StdEncoding.EncodeToString(data[key]))
+	}
+
+	return []byte(manifest.String())
+}
@@ -0,0 +1,60 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/oci"
+	"github.com/clastix/talos-csr-signer/pkg/opabundle"
+)
+
+// newOPABundle returns the Syncer pushing the signed bundle of the OCI registry to the Open Policy Agent of the
+// decision, nil when not configured, once the bundle was pushed so the first CSRs are decided by its policy.
+func newOPABundle(ctx context.Context, cfg config.Policy) (*opabundle.Syncer, error) {
+	if cfg.OPABundle == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	reference, err := oci.ParseReference(cfg.OPABundle)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrConfig, err.Error())
+	}
+
+	data, err := os.ReadFile(cfg.OPABundleKeyPath)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read the Open Policy Agent bundle key: "+err.Error())
+	}
+
+	publicKey, err := opabundle.ParsePublicKey(data)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	// The agent serves the Policy API next to the Data API of the decision
+	agentURL, _, _ := strings.Cut(cfg.OPAURL, "/v1/data")
+
+	syncer := opabundle.NewSyncer(opabundle.Options{
+		Reference: reference,
+		Registry:  oci.NewClient(cfg.OPABundleUsername, cfg.OPABundlePassword, cfg.OPATimeout),
+		PublicKey: publicKey,
+		AgentURL:  agentURL,
+		Timeout:   cfg.OPATimeout,
+	})
+
+	if _, err = syncer.Sync(ctx); err != nil {
+		return nil, errors.Wrap(err, "Open Policy Agent bundle "+reference.String())
+	}
+
+	logging.FromContext(ctx).Info("Pushed the Open Policy Agent bundle", "reference", reference.String(), "digest", syncer.Digest())
+
+	return syncer, nil
+}
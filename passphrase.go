@@ -0,0 +1,86 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/kms"
+)
+
+// caKeyPassphrase returns the passphrase of the encrypted CA private keys, in order: the one of the setting, the one
+// read from the file, and the one decrypted by AWS KMS.
+func caKeyPassphrase() ([]byte, error) {
+	if passphrase := viper.GetString(config.KeyCAKeyPassphrase); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+
+	if path := viper.GetString(config.KeyCAKeyPassphrasePath); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read the CA key passphrase: "+err.Error())
+		}
+
+		return bytes.TrimRight(data, "\r\n"), nil
+	}
+
+	if path := viper.GetString(config.KeyCAKeyPassphraseKMSPath); path != "" {
+		return decryptKMSPassphrase(path)
+	}
+
+	return nil, errors.Wrap(pkgerrors.ErrKeyPassphrase, "the CA private key is encrypted, set "+config.KeyCAKeyPassphrase+
+		", "+config.KeyCAKeyPassphrasePath+", or "+config.KeyCAKeyPassphraseKMSPath)
+}
+
+// decryptKMSPassphrase returns the passphrase of the ciphertext blob encrypted by AWS KMS, either raw or base64
+// encoded such as printed by `aws kms encrypt`.
+func decryptKMSPassphrase(path string) ([]byte, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read the CA key passphrase blob: "+err.Error())
+	}
+
+	if decoded, decodeErr := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(blob))); decodeErr == nil {
+		blob = decoded
+	}
+
+	timeout := viper.GetDuration(config.KeyKMSTimeout)
+	client := &http.Client{Timeout: timeout}
+
+	ctx := context.Background()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	passphrase, err := kms.Decrypt(ctx, kms.Options{
+		KeyID:    viper.GetString(config.KeyKMSKeyARN),
+		Region:   viper.GetString(config.KeyKMSRegion),
+		Endpoint: viper.GetString(config.KeyKMSEndpoint),
+		Client:   client,
+		Credentials: &kms.CredentialsProvider{
+			Region:  viper.GetString(config.KeyKMSRegion),
+			Client:  client,
+			File:    viper.GetString(config.KeyKMSCredentialsPath),
+			Profile: viper.GetString(config.KeyKMSProfile),
+		},
+	}, blob)
+	if err != nil {
+		return nil, errors.Wrap(err, "CA key passphrase")
+	}
+
+	return passphrase, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"os"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+)
+
+// pkcs12CA is the CA read from a PKCS#12 bundle, along with the chain up to the root when an intermediate CA.
+type pkcs12CA struct {
+	ca    *backend.Local
+	key   crypto.Signer
+	chain []*x509.Certificate
+}
+
+// loadPKCS12CA reads the CA of the PKCS#12 bundle, nil when not configured: the CA certificate is the one of the
+// private key, the other certificates being its chain, ordered from the issuer of the CA up to the root.
+func loadPKCS12CA(ctx context.Context, path string) (*pkcs12CA, error) {
+	if path == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read the PKCS#12 bundle: "+err.Error())
+	}
+
+	password, err := pkcs12Password()
+	if err != nil {
+		return nil, err
+	}
+
+	caPrivateKey, certs, err := pki.DecodePKCS12(data, password)
+	if err != nil {
+		return nil, errors.Wrap(err, "PKCS#12 bundle "+path)
+	}
+
+	index := slices.IndexFunc(certs, func(cert *x509.Certificate) bool {
+		publicKey, ok := cert.PublicKey.(interface{ Equal(x crypto.PublicKey) bool })
+
+		return ok && publicKey.Equal(caPrivateKey.Public())
+	})
+	if index < 0 {
+		return nil, errors.Wrap(pkgerrors.ErrPKCS12, "the bundle "+path+" holds no certificate of its private key")
+	}
+
+	caCert := certs[index]
+	others := slices.Delete(certs, index, index+1)
+
+	var chain []*x509.Certificate
+
+	for child := caCert; len(others) > 0 && child.CheckSignatureFrom(child) != nil; {
+		parent := slices.IndexFunc(others, func(cert *x509.Certificate) bool {
+			return child.CheckSignatureFrom(cert) == nil
+		})
+		if parent < 0 {
+			break
+		}
+
+		child = others[parent]
+		chain = append(chain, child)
+		others = slices.Delete(others, parent, parent+1)
+	}
+
+	if len(others) > 0 {
+		return nil, errors.Wrap(pkgerrors.ErrCAChain, "the bundle "+path+" holds certificates outside of the chain of the CA "+caCert.Subject.String())
+	}
+
+	if len(chain) > 0 {
+		if err = pki.VerifyChain(caCert, chain); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+	}
+
+	local, err := backend.NewLocal("local", pki.EncodeCertificates(caCert), caPrivateKey)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	logging.FromContext(ctx).Info("Loaded the CA from the PKCS#12 bundle", "path", path, "serial", caCert.SerialNumber.Text(16),
+		"chain_certificates", len(chain))
+
+	return &pkcs12CA{ca: local, key: caPrivateKey, chain: chain}, nil
+}
+
+// pkcs12Password returns the password of the PKCS#12 bundle: the one of the setting, otherwise the CA key passphrase
+// when configured, and the empty password of the bundles exported without one.
+func pkcs12Password() ([]byte, error) {
+	if password := viper.GetString(config.KeyCAPKCS12Password); password != "" {
+		return []byte(password), nil
+	}
+
+	for _, key := range []string{config.KeyCAKeyPassphrase, config.KeyCAKeyPassphrasePath, config.KeyCAKeyPassphraseKMSPath} {
+		if viper.GetString(key) != "" {
+			return caKeyPassphrase()
+		}
+	}
+
+	return nil, nil
+}
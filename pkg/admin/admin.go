@@ -0,0 +1,121 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package admin is the HTTP API used by the operators to inspect and manage the running signer.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+// shutdownTimeout is the time given to the in-flight admin requests when the server stops.
+const shutdownTimeout = 5 * time.Second
+
+// Server is the admin HTTP server, protected by a bearer token when configured.
+type Server struct {
+	mux   *http.ServeMux
+	grpc  http.Handler
+	token string
+}
+
+// New returns an admin Server requiring the given bearer token, when not empty.
+func New(token string) *Server {
+	return &Server{
+		mux:   http.NewServeMux(),
+		token: token,
+	}
+}
+
+// Handle registers the handler for the given pattern.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// HandleFunc registers the handler function for the given pattern.
+func (s *Server) HandleFunc(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, handler)
+}
+
+// HandleGRPC registers the handler of the gRPC requests, served over unencrypted HTTP/2 along with the HTTP API,
+// such as a grpc.Server with the channelz service.
+func (s *Server) HandleGRPC(handler http.Handler) {
+	s.grpc = handler
+}
+
+// ServeHTTP implements http.Handler, enforcing the bearer token authentication.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			WriteError(w, http.StatusUnauthorized, pkgerrors.ErrAdminUnauthorized)
+
+			return
+		}
+	}
+
+	if s.grpc != nil && r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		s.grpc.ServeHTTP(w, r)
+
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// Serve accepts the admin requests on the listener until the context is done.
+func (s *Server) Serve(ctx context.Context, lis net.Listener) error {
+	httpServer := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	if s.grpc != nil {
+		httpServer.Protocols = new(http.Protocols)
+		httpServer.Protocols.SetHTTP1(true)
+		httpServer.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		_ = httpServer.Shutdown(shutdownCtx) //nolint:contextcheck
+	}()
+
+	logging.FromContext(ctx).Info("Admin API listening", "address", lis.Addr().String())
+
+	if err := httpServer.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.Wrap(pkgerrors.ErrAdminServe, err.Error())
+	}
+
+	return nil
+}
+
+// WriteJSON writes the value as the JSON response body with the given status code.
+func WriteJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+}
+
+// WriteError writes the error as a JSON response body with the given status code.
+func WriteError(w http.ResponseWriter, status int, err error) {
+	WriteJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,219 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package approval implements the dual control of the privileged certificates: their requests are held in the ledger
+// until M of the N configured approvers approved them through the admin API, the approval trail being recorded along
+// with the issued certificate.
+package approval
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+)
+
+// keyPrefix is the prefix of the ledger keys holding the approval requests.
+const keyPrefix = "approval:"
+
+// Approver is an administrator allowed to approve the privileged certificates.
+type Approver struct {
+	Name  string
+	Token string
+}
+
+// ParseApprovers returns the approvers encoded one per line, as their name followed by their token.
+// Empty lines and the ones starting with # are ignored.
+func ParseApprovers(data []byte) ([]Approver, error) {
+	var approvers []Approver
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, errors.Wrap(pkgerrors.ErrApproval, "an approver must be followed by its token")
+		}
+
+		for _, approver := range approvers {
+			if approver.Name == fields[0] {
+				return nil, errors.Wrap(pkgerrors.ErrApproval, "duplicate approver "+fields[0])
+			}
+		}
+
+		approvers = append(approvers, Approver{Name: fields[0], Token: fields[1]})
+	}
+
+	return approvers, nil
+}
+
+// Request is a privileged certificate request held until approved, identified by the digest of its CSR.
+type Request struct {
+	ID            string            `json:"id"`
+	CommonName    string            `json:"commonName"`
+	Organizations []string          `json:"organizations,omitempty"`
+	RequestedAt   time.Time         `json:"requestedAt"`
+	Approvals     []ledger.Approval `json:"approvals,omitempty"`
+	Threshold     int               `json:"threshold"`
+}
+
+// Approved reports whether the request collected the approvals of its threshold.
+func (r *Request) Approved() bool {
+	return len(r.Approvals) >= r.Threshold
+}
+
+// Queue holds the privileged certificate requests in the ledger, shared across the replicas.
+type Queue struct {
+	// Ledger stores the requests and their approvals.
+	Ledger ledger.Ledger
+	// Organizations are the subject organizations marking a CSR as privileged.
+	Organizations []string
+	// Approvers are the N administrators allowed to approve the requests.
+	Approvers []Approver
+	// Threshold is the M approvals a request requires.
+	Threshold int
+	// TTL is the time a request waits for its approvals before expiring.
+	TTL time.Duration
+
+	// mu serializes the approvals of this replica, the ledger not offering a compare-and-swap.
+	mu sync.Mutex
+}
+
+// Validate checks the threshold against the approvers.
+func (q *Queue) Validate() error {
+	switch {
+	case len(q.Approvers) == 0:
+		return errors.Wrap(pkgerrors.ErrApproval, "no approvers")
+	case q.Threshold < 1 || q.Threshold > len(q.Approvers):
+		return errors.Wrap(pkgerrors.ErrApproval, "the threshold must be between 1 and the number of approvers")
+	case q.TTL <= 0:
+		return errors.Wrap(pkgerrors.ErrApproval, "the approval TTL must be positive")
+	}
+
+	return nil
+}
+
+// Privileged reports whether the CSR requests one of the privileged organizations.
+func (q *Queue) Privileged(csr *x509.CertificateRequest) bool {
+	for _, organization := range csr.Subject.Organization {
+		if slices.Contains(q.Organizations, organization) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Submit returns the request of the CSR identified by the given digest, queuing it when new.
+func (q *Queue) Submit(ctx context.Context, id string, csr *x509.CertificateRequest) (*Request, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	request, found, err := q.Get(ctx, id)
+	if err != nil || found {
+		return request, false, err
+	}
+
+	request = &Request{
+		ID:            id,
+		CommonName:    csr.Subject.CommonName,
+		Organizations: csr.Subject.Organization,
+		RequestedAt:   time.Now().UTC(),
+		Threshold:     q.Threshold,
+	}
+
+	return request, true, q.store(ctx, request)
+}
+
+// Get returns the request of the given ID, if pending.
+func (q *Queue) Get(ctx context.Context, id string) (*Request, bool, error) {
+	data, found, err := q.Ledger.CachedResponse(ctx, keyPrefix+id)
+	if err != nil || !found {
+		return nil, false, err //nolint:wrapcheck
+	}
+
+	var request Request
+	if err = json.Unmarshal(data, &request); err != nil {
+		return nil, false, errors.Wrap(pkgerrors.ErrApproval, "malformed request "+id+": "+err.Error())
+	}
+
+	return &request, true, nil
+}
+
+// Approve records the approval of the request by the approver owning the given token, returning the approver name.
+func (q *Queue) Approve(ctx context.Context, id, approverToken string) (*Request, string, error) {
+	approver, ok := q.authenticate(approverToken)
+	if !ok {
+		return nil, "", errors.Wrap(pkgerrors.ErrApprover, "unknown approver token")
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	request, found, err := q.Get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !found {
+		return nil, "", errors.Wrap(pkgerrors.ErrApprovalNotFound, id)
+	}
+
+	for _, approval := range request.Approvals {
+		if approval.Approver == approver {
+			return nil, "", errors.Wrap(pkgerrors.ErrApproval, approver+" already approved the request")
+		}
+	}
+
+	request.Approvals = append(request.Approvals, ledger.Approval{Approver: approver, Time: time.Now().UTC()})
+
+	return request, approver, q.store(ctx, request)
+}
+
+// authenticate returns the name of the approver owning the given token, comparing all of them in constant time.
+func (q *Queue) authenticate(approverToken string) (string, bool) {
+	digest := sha256.Sum256([]byte(approverToken))
+
+	var name string
+
+	for _, approver := range q.Approvers {
+		candidate := sha256.Sum256([]byte(approver.Token))
+		if subtle.ConstantTimeCompare(digest[:], candidate[:]) == 1 {
+			name = approver.Name
+		}
+	}
+
+	return name, name != ""
+}
+
+// store writes the request to the ledger until its expiration, counted from its submission.
+func (q *Queue) store(ctx context.Context, request *Request) error {
+	ttl := time.Until(request.RequestedAt.Add(q.TTL))
+	if ttl <= 0 {
+		return errors.Wrap(pkgerrors.ErrApprovalNotFound, request.ID+" expired")
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrApproval, err.Error())
+	}
+
+	return q.Ledger.CacheResponse(ctx, keyPrefix+request.ID, data, ttl) //nolint:wrapcheck
+}
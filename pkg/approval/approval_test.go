@@ -0,0 +1,127 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package approval
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+)
+
+func TestParseApprovers(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		approvers int
+		expected  error
+	}{
+		{name: "approvers", data: "# security team\nalice secret-1\n\nbob secret-2\n", approvers: 2},
+		{name: "empty", data: "\n"},
+		{name: "missing token", data: "alice\n", expected: pkgerrors.ErrApproval},
+		{name: "extra field", data: "alice secret-1 extra\n", expected: pkgerrors.ErrApproval},
+		{name: "duplicate approver", data: "alice secret-1\nalice secret-2\n", expected: pkgerrors.ErrApproval},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			approvers, err := ParseApprovers([]byte(tt.data))
+
+			if tt.expected != nil {
+				if !errors.Is(err, tt.expected) {
+					t.Fatalf("expected %v, got %v", tt.expected, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(approvers) != tt.approvers {
+				t.Fatalf("unexpected approvers %+v", approvers)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	approvers := []Approver{{Name: "alice", Token: "secret-1"}, {Name: "bob", Token: "secret-2"}}
+
+	tests := []struct {
+		name  string
+		queue *Queue
+		valid bool
+	}{
+		{name: "valid", queue: &Queue{Approvers: approvers, Threshold: 2, TTL: time.Hour}, valid: true},
+		{name: "no approvers", queue: &Queue{Threshold: 1, TTL: time.Hour}},
+		{name: "threshold above the approvers", queue: &Queue{Approvers: approvers, Threshold: 3, TTL: time.Hour}},
+		{name: "zero threshold", queue: &Queue{Approvers: approvers, TTL: time.Hour}},
+		{name: "no TTL", queue: &Queue{Approvers: approvers, Threshold: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.queue.Validate(); (err == nil) != tt.valid {
+				t.Fatalf("expected valid %t, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestQueue(t *testing.T) {
+	queue := &Queue{
+		Ledger:        ledger.NewMemory(),
+		Organizations: []string{"system:masters"},
+		Approvers:     []Approver{{Name: "alice", Token: "secret-1"}, {Name: "bob", Token: "secret-2"}, {Name: "carol", Token: "secret-3"}},
+		Threshold:     2,
+		TTL:           time.Hour,
+	}
+
+	csr := &x509.CertificateRequest{Subject: pkix.Name{CommonName: "admin", Organization: []string{"system:masters"}}}
+
+	if !queue.Privileged(csr) || queue.Privileged(&x509.CertificateRequest{Subject: pkix.Name{Organization: []string{"os:reader"}}}) {
+		t.Fatal("expected the CSRs of the privileged organizations only to be held")
+	}
+
+	request, created, err := queue.Submit(t.Context(), "digest", csr)
+	if err != nil || !created || request.Approved() {
+		t.Fatalf("expected a new pending request, got %+v, %t, %v", request, created, err)
+	}
+
+	if _, created, _ = queue.Submit(t.Context(), "digest", csr); created {
+		t.Fatal("expected the resubmitted CSR to get the pending request")
+	}
+
+	if _, _, err = queue.Approve(t.Context(), "digest", "unknown"); !errors.Is(err, pkgerrors.ErrApprover) {
+		t.Fatalf("expected the unknown approver to be rejected, got %v", err)
+	}
+
+	if _, _, err = queue.Approve(t.Context(), "other", "secret-1"); !errors.Is(err, pkgerrors.ErrApprovalNotFound) {
+		t.Fatalf("expected the unknown request to be reported, got %v", err)
+	}
+
+	request, approver, err := queue.Approve(t.Context(), "digest", "secret-1")
+	if err != nil || approver != "alice" || request.Approved() {
+		t.Fatalf("expected the first approval to be recorded, got %+v, %s, %v", request, approver, err)
+	}
+
+	if _, _, err = queue.Approve(t.Context(), "digest", "secret-1"); !errors.Is(err, pkgerrors.ErrApproval) {
+		t.Fatalf("expected the approver to approve once, got %v", err)
+	}
+
+	if _, _, err = queue.Approve(t.Context(), "digest", "secret-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	request, found, err := queue.Get(t.Context(), "digest")
+	if err != nil || !found || !request.Approved() || len(request.Approvals) != 2 {
+		t.Fatalf("expected the request to be approved, got %+v, %t, %v", request, found, err)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package backend contains the signing backends holding the Talos Machine CA key material.
+package backend
+
+import (
+	"context"
+	"crypto/x509"
+)
+
+// Result is the outcome of a successful signing operation.
+type Result struct {
+	// Certificate is the DER encoded signed certificate.
+	Certificate []byte
+	// CA is the PEM encoded CA certificate the issued certificate chains to.
+	CA []byte
+	// Backend is the name of the backend which signed the certificate.
+	Backend string
+}
+
+// Backend signs certificate templates with the CA key material it holds.
+type Backend interface {
+	// Name returns the identifier of the backend, used to mark which backend signed a certificate.
+	Name() string
+	// Certificate returns the CA certificate of the backend.
+	Certificate() *x509.Certificate
+	// Sign issues the certificate from the given template for the provided public key.
+	Sign(ctx context.Context, template *x509.Certificate, publicKey any) (*Result, error)
+}
+
+// csrContextKey is the context key of the CSR being signed.
+type csrContextKey struct{}
+
+// NewCSRContext returns the context carrying the CSR the certificate is signed for, needed by the backends delegating
+// the issuance to an external CA signing the CSR itself.
+func NewCSRContext(ctx context.Context, csr *x509.CertificateRequest) context.Context {
+	return context.WithValue(ctx, csrContextKey{}, csr)
+}
+
+// CSRFromContext returns the CSR the certificate is signed for, nil when not set.
+func CSRFromContext(ctx context.Context) *x509.CertificateRequest {
+	csr, _ := ctx.Value(csrContextKey{}).(*x509.CertificateRequest)
+
+	return csr
+}
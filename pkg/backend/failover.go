@@ -0,0 +1,121 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"context"
+	"crypto/x509"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+// Failover is the Backend signing with a primary backend, falling back to a secondary one
+// when the primary is failing. Consecutive primary failures open the circuit: while open,
+// requests go straight to the fallback until the cooldown elapses and a single trial request
+// probes the primary again (half-open), closing the circuit on success.
+type Failover struct {
+	primary   Backend
+	fallback  Backend
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewFailover returns a Failover backend opening the circuit after threshold consecutive failures of the primary.
+func NewFailover(primary, fallback Backend, threshold int, cooldown time.Duration) *Failover {
+	return &Failover{
+		primary:   primary,
+		fallback:  fallback,
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+	}
+}
+
+// Name implements Backend.
+func (f *Failover) Name() string {
+	return f.primary.Name() + "+" + f.fallback.Name()
+}
+
+// Certificate implements Backend, returning the primary one.
+func (f *Failover) Certificate() *x509.Certificate {
+	return f.primary.Certificate()
+}
+
+// Sign implements Backend.
+func (f *Failover) Sign(ctx context.Context, template *x509.Certificate, publicKey any) (*Result, error) {
+	if f.allowPrimary(ctx) {
+		result, err := f.primary.Sign(ctx, template, publicKey)
+		f.report(ctx, err)
+
+		if err == nil {
+			return result, nil
+		}
+
+		logging.FromContext(ctx).Warn("Primary signing backend failed, using the fallback",
+			"backend", f.primary.Name(), "fallback", f.fallback.Name(), "error", err)
+	}
+
+	result, err := f.fallback.Sign(ctx, template, publicKey)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrBackendUnavailable, err.Error())
+	}
+
+	return result, nil
+}
+
+// allowPrimary returns true when the request can be served by the primary backend.
+func (f *Failover) allowPrimary(ctx context.Context) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failures < f.threshold {
+		return true
+	}
+
+	if f.probing || time.Since(f.openedAt) < f.cooldown {
+		return false
+	}
+
+	logging.FromContext(ctx).Info("Circuit of the signing backend is half-open, probing it", "backend", f.primary.Name())
+
+	f.probing = true
+
+	return true
+}
+
+// report updates the circuit state with the outcome of a primary backend request.
+func (f *Failover) report(ctx context.Context, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.probing = false
+
+	if err == nil {
+		if f.failures >= f.threshold {
+			logging.FromContext(ctx).Info("Circuit of the signing backend is closed", "backend", f.primary.Name())
+		}
+
+		f.failures = 0
+
+		return
+	}
+
+	f.failures++
+
+	if f.failures >= f.threshold {
+		logging.FromContext(ctx).Warn("Circuit of the signing backend is open", "backend", f.primary.Name(),
+			"cooldown", f.cooldown)
+
+		f.openedAt = time.Now()
+	}
+}
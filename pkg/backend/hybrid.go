@@ -0,0 +1,340 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"slices"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+)
+
+// The extensions of the alternative signature of the hybrid certificates, defined by the X.509 (10/2019) standard.
+var (
+	oidSubjectAltPublicKeyInfo = asn1.ObjectIdentifier{2, 5, 29, 72}
+	oidAltSignatureAlgorithm   = asn1.ObjectIdentifier{2, 5, 29, 73}
+	oidAltSignatureValue       = asn1.ObjectIdentifier{2, 5, 29, 74}
+)
+
+// mldsaOIDs are the algorithms of the ML-DSA keys, also identifying their signatures.
+var mldsaOIDs = []asn1.ObjectIdentifier{
+	{2, 16, 840, 1, 101, 3, 4, 3, 17},
+	{2, 16, 840, 1, 101, 3, 4, 3, 18},
+	{2, 16, 840, 1, 101, 3, 4, 3, 19},
+}
+
+// Hybrid is the Backend adding a post-quantum ML-DSA signature to the certificates signed by the wrapped backend, in
+// their non-critical alternative signature extensions: the clients ignoring them verify the classical signature only,
+// while the PQ-capable ones verify both. The nodes get a hybrid version of the CA, re-issued by the wrapped backend
+// with the same subject and key along with the ML-DSA public key, chaining the hybrid certificates to it.
+type Hybrid struct {
+	backend      Backend
+	altKey       crypto.Signer
+	altSPKI      []byte
+	altAlgorithm []byte
+
+	mu sync.Mutex
+	// issuedFor is the raw CA certificate of the wrapped backend the hybrid CA was issued for, re-issued on rotation.
+	issuedFor []byte
+	ca        *x509.Certificate
+	caPEM     []byte
+}
+
+// NewHybrid returns a Hybrid backend adding the signature of the ML-DSA key to the certificates of the given backend,
+// issuing the hybrid version of its CA, which must be a self-signed root.
+func NewHybrid(ctx context.Context, backend Backend, altKey crypto.Signer) (*Hybrid, error) {
+	altSPKI, err := x509.MarshalPKIXPublicKey(altKey.Public())
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, err.Error())
+	}
+
+	var spki struct {
+		Algorithm asn1.RawValue
+		PublicKey asn1.BitString
+	}
+
+	if _, err = asn1.Unmarshal(altSPKI, &spki); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, err.Error())
+	}
+
+	var algorithm pkix.AlgorithmIdentifier
+	if _, err = asn1.Unmarshal(spki.Algorithm.FullBytes, &algorithm); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, err.Error())
+	}
+
+	if !slices.ContainsFunc(mldsaOIDs, algorithm.Algorithm.Equal) {
+		return nil, errors.Wrapf(pkgerrors.ErrHybridSigning, "the alternative key must be an ML-DSA one, not %T", altKey)
+	}
+
+	h := &Hybrid{
+		backend:      backend,
+		altKey:       altKey,
+		altSPKI:      altSPKI,
+		altAlgorithm: spki.Algorithm.FullBytes,
+	}
+
+	if _, err = h.hybridCA(ctx); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Name implements Backend.
+func (h *Hybrid) Name() string {
+	return h.backend.Name()
+}
+
+// Certificate implements Backend, returning the hybrid CA, or the CA of the wrapped backend while its hybrid version
+// wasn't issued yet after a rotation.
+func (h *Hybrid) Certificate() *x509.Certificate {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ca := h.backend.Certificate()
+	if h.ca != nil && bytes.Equal(h.issuedFor, ca.Raw) {
+		return h.ca
+	}
+
+	return ca
+}
+
+// Sign implements Backend.
+func (h *Hybrid) Sign(ctx context.Context, template *x509.Certificate, publicKey any) (*Result, error) {
+	caPEM, err := h.hybridCA(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := h.sign(ctx, template, publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	result.CA = caPEM
+
+	return result, nil
+}
+
+// hybridCA returns the PEM encoded hybrid CA, issuing it for the current CA of the wrapped backend when rotated.
+func (h *Hybrid) hybridCA(ctx context.Context) ([]byte, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ca := h.backend.Certificate()
+	if h.ca != nil && bytes.Equal(h.issuedFor, ca.Raw) {
+		return h.caPEM, nil
+	}
+
+	if !bytes.Equal(ca.RawIssuer, ca.RawSubject) || ca.CheckSignatureFrom(ca) != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, "the CA must be a self-signed root to be re-issued as a hybrid one")
+	}
+
+	serialNumber, err := pki.SerialNumber()
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, err.Error())
+	}
+
+	template := *ca
+	template.SerialNumber = serialNumber
+	template.ExtraExtensions = []pkix.Extension{{Id: oidSubjectAltPublicKeyInfo, Value: h.altSPKI}}
+
+	result, err := h.sign(ctx, &template, ca.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	hybrid, err := x509.ParseCertificate(result.Certificate)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, err.Error())
+	}
+
+	if err = hybrid.CheckSignatureFrom(ca); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, "the hybrid CA isn't signed by the CA: "+err.Error())
+	}
+
+	h.issuedFor, h.ca, h.caPEM = ca.Raw, hybrid, pki.EncodeCertificates(hybrid)
+
+	return h.caPEM, nil
+}
+
+// sign issues the certificate of the template with the wrapped backend, along with the alternative signature of its
+// pre-TBS certificate: the TBS certificate without its signature algorithm and alternative signature value, computed
+// by signing the template beforehand with a throwaway key.
+func (h *Hybrid) sign(ctx context.Context, template *x509.Certificate, publicKey any) (*Result, error) {
+	tpl := *template
+	tpl.ExtraExtensions = append(slices.Clip(template.ExtraExtensions), pkix.Extension{Id: oidAltSignatureAlgorithm, Value: h.altAlgorithm})
+
+	if tpl.SerialNumber == nil {
+		serialNumber, err := pki.SerialNumber()
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrHybridSigning, err.Error())
+		}
+
+		tpl.SerialNumber = serialNumber
+	}
+
+	_, throwawayKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, err.Error())
+	}
+
+	draftParent := *h.backend.Certificate()
+	draftParent.PublicKey = throwawayKey.Public()
+
+	draftTemplate := tpl
+	draftTemplate.SignatureAlgorithm = x509.UnknownSignatureAlgorithm
+
+	draftDER, err := x509.CreateCertificate(rand.Reader, &draftTemplate, &draftParent, publicKey, throwawayKey)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, err.Error())
+	}
+
+	draft, err := x509.ParseCertificate(draftDER)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, err.Error())
+	}
+
+	preTBS, err := preTBSCertificate(draft.RawTBSCertificate)
+	if err != nil {
+		return nil, err
+	}
+
+	altSignature, err := h.altKey.Sign(rand.Reader, preTBS, crypto.Hash(0))
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, err.Error())
+	}
+
+	altSignatureValue, err := asn1.Marshal(asn1.BitString{Bytes: altSignature, BitLength: 8 * len(altSignature)})
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, err.Error())
+	}
+
+	tpl.ExtraExtensions = append(tpl.ExtraExtensions, pkix.Extension{Id: oidAltSignatureValue, Value: altSignatureValue})
+
+	result, err := h.backend.Sign(ctx, &tpl, publicKey)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	// The backends issuing the certificates of their own templates, such as Vault, break the alternative signature
+	cert, err := x509.ParseCertificate(result.Certificate)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, err.Error())
+	}
+
+	issuedPreTBS, err := preTBSCertificate(cert.RawTBSCertificate)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(issuedPreTBS, preTBS) {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, "the backend "+h.backend.Name()+" didn't issue the certificate of the template, breaking its alternative signature")
+	}
+
+	return result, nil
+}
+
+// preTBSCertificate returns the TBS certificate without its signature algorithm field and its alternative signature
+// value extension, the message of the alternative signature.
+func preTBSCertificate(rawTBS []byte) ([]byte, error) {
+	var tbs asn1.RawValue
+	if rest, err := asn1.Unmarshal(rawTBS, &tbs); err != nil || len(rest) > 0 {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, "malformed TBS certificate")
+	}
+
+	fields, err := asn1Elements(tbs.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	// version, serialNumber, signature, issuer, validity, subject, subjectPublicKeyInfo, ..., extensions
+	if len(fields) < 8 || fields[0].Class != asn1.ClassContextSpecific || fields[0].Tag != 0 {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, "the TBS certificate isn't a v3 one")
+	}
+
+	extensions := fields[len(fields)-1]
+	if extensions.Class != asn1.ClassContextSpecific || extensions.Tag != 3 {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, "the TBS certificate has no extensions")
+	}
+
+	var sequence asn1.RawValue
+	if _, err = asn1.Unmarshal(extensions.Bytes, &sequence); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, "malformed extensions: "+err.Error())
+	}
+
+	entries, err := asn1Elements(sequence.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var kept []byte
+
+	for _, entry := range entries {
+		var extension pkix.Extension
+		if _, err = asn1.Unmarshal(entry.FullBytes, &extension); err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrHybridSigning, "malformed extension: "+err.Error())
+		}
+
+		if !extension.Id.Equal(oidAltSignatureValue) {
+			kept = append(kept, entry.FullBytes...)
+		}
+	}
+
+	sequenceDER, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSequence, IsCompound: true, Bytes: kept})
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, err.Error())
+	}
+
+	extensionsDER, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 3, IsCompound: true, Bytes: sequenceDER})
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, err.Error())
+	}
+
+	var content []byte
+
+	for i, field := range fields[:len(fields)-1] {
+		if i != 2 {
+			content = append(content, field.FullBytes...)
+		}
+	}
+
+	content = append(content, extensionsDER...)
+
+	preTBS, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSequence, IsCompound: true, Bytes: content})
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrHybridSigning, err.Error())
+	}
+
+	return preTBS, nil
+}
+
+// asn1Elements returns the DER elements of the content of a constructed value.
+func asn1Elements(data []byte) ([]asn1.RawValue, error) {
+	var elements []asn1.RawValue
+
+	for len(data) > 0 {
+		var element asn1.RawValue
+
+		rest, err := asn1.Unmarshal(data, &element)
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrHybridSigning, "malformed TBS certificate: "+err.Error())
+		}
+
+		elements, data = append(elements, element), rest
+	}
+
+	return elements, nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/mldsa"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+)
+
+// newLocal returns the Local backend of a new self-signed CA.
+func newLocal(t *testing.T) *Local {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template, err := pki.CATemplate(pkix.Name{CommonName: "talos"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := pki.Sign(template, key.Public(), template, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local, err := NewLocal("local", pki.EncodeCertificates(cert), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return local
+}
+
+// loadKey returns the private key of the PEM encoded PKCS #8 form, as the signer reads the hybrid key file.
+func loadKey(t *testing.T, key crypto.Signer) crypto.Signer {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, _ := pem.Decode(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		t.Fatalf("unexpected key %T", parsed)
+	}
+
+	return signer
+}
+
+// extension returns the value of the extension of the certificate, nil when missing.
+func extension(cert *x509.Certificate, id asn1.ObjectIdentifier) []byte {
+	index := slices.IndexFunc(cert.Extensions, func(ext pkix.Extension) bool { return ext.Id.Equal(id) })
+	if index < 0 {
+		return nil
+	}
+
+	return cert.Extensions[index].Value
+}
+
+func TestHybrid(t *testing.T) {
+	mldsaKey, err := mldsa.GenerateKey(mldsa.MLDSA65())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	altKey := loadKey(t, mldsaKey)
+
+	hybrid, err := NewHybrid(t.Context(), newLocal(t), altKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca := hybrid.Certificate()
+
+	altSPKI, err := x509.MarshalPKIXPublicKey(altKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(extension(ca, oidSubjectAltPublicKeyInfo)) != string(altSPKI) {
+		t.Fatal("expected the hybrid CA to carry the ML-DSA public key")
+	}
+
+	nodeKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := hybrid.Sign(t.Context(), &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "worker-1"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, nodeKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(result.Certificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cert.CheckSignatureFrom(ca); err != nil {
+		t.Fatalf("expected the certificate to chain to the hybrid CA: %v", err)
+	}
+
+	var altSignature asn1.BitString
+	if _, err = asn1.Unmarshal(extension(cert, oidAltSignatureValue), &altSignature); err != nil {
+		t.Fatal(err)
+	}
+
+	preTBS, err := preTBSCertificate(cert.RawTBSCertificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = mldsa.Verify(mldsaKey.PublicKey(), preTBS, altSignature.Bytes, nil); err != nil {
+		t.Fatalf("expected the alternative signature to be valid: %v", err)
+	}
+}
+
+func TestHybridKeyType(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewHybrid(t.Context(), newLocal(t), loadKey(t, ecKey)); !errors.Is(err, pkgerrors.ErrHybridSigning) {
+		t.Fatalf("expected the ECDSA key to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// Local is the Backend signing with a crypto.Signer of the CA private key: loaded in memory, or held by an HSM, a KMS,
+// or a remote signer implementing crypto.Signer, the key never leaving it.
+type Local struct {
+	name       string
+	caCertPEM  []byte
+	caCert     *x509.Certificate
+	privateKey crypto.Signer
+}
+
+// NewLocal returns a Local backend for the given PEM encoded CA certificate and the signer of its private key.
+func NewLocal(name string, caCertPEM []byte, privateKey crypto.Signer) (*Local, error) {
+	block, _ := pem.Decode(caCertPEM)
+	if block == nil {
+		return nil, pkgerrors.ErrDecodedCACertificate
+	}
+
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrDecodedCACertificate, err.Error())
+	}
+
+	// Refuse the mismatched keys upfront rather than at the first signing
+	if publicKey, ok := privateKey.Public().(interface{ Equal(x crypto.PublicKey) bool }); ok && !publicKey.Equal(caCert.PublicKey) {
+		return nil, errors.Wrap(pkgerrors.ErrCAKeyMismatch, caCert.Subject.String())
+	}
+
+	return &Local{
+		name:       name,
+		caCertPEM:  caCertPEM,
+		caCert:     caCert,
+		privateKey: privateKey,
+	}, nil
+}
+
+// Name implements Backend.
+func (l *Local) Name() string {
+	return l.name
+}
+
+// Certificate implements Backend.
+func (l *Local) Certificate() *x509.Certificate {
+	return l.caCert
+}
+
+// Sign implements Backend.
+func (l *Local) Sign(_ context.Context, template *x509.Certificate, publicKey any) (*Result, error) {
+	certDER, err := x509.CreateCertificate(nil, template, l.caCert, publicKey, l.privateKey)
+	if err != nil {
+		// The remote signers, such as a KMS, may fail temporarily
+		if errors.Is(err, pkgerrors.ErrBackendTransient) {
+			return nil, err //nolint:wrapcheck
+		}
+
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, err.Error())
+	}
+
+	return &Result{
+		Certificate: certDER,
+		CA:          l.caCertPEM,
+		Backend:     l.name,
+	}, nil
+}
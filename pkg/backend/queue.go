@@ -0,0 +1,93 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"context"
+	"crypto/x509"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+// Queue is the Backend holding the requests received during a short outage of the wrapped backend,
+// retrying them until it recovers instead of failing immediately. The queue is bounded: once full,
+// requests fail straight away. Queued requests give up at the request deadline or after the maximum wait.
+// Only the Transient errors are queued, the other ones, such as a rejected template, failing immediately.
+type Queue struct {
+	backend       Backend
+	slots         chan struct{}
+	retryInterval time.Duration
+	maxWait       time.Duration
+}
+
+// NewQueue returns a Queue backend holding up to size requests while the given backend is failing.
+func NewQueue(backend Backend, size int, retryInterval, maxWait time.Duration) *Queue {
+	return &Queue{
+		backend:       backend,
+		slots:         make(chan struct{}, size),
+		retryInterval: retryInterval,
+		maxWait:       maxWait,
+	}
+}
+
+// Name implements Backend.
+func (q *Queue) Name() string {
+	return q.backend.Name()
+}
+
+// Certificate implements Backend.
+func (q *Queue) Certificate() *x509.Certificate {
+	return q.backend.Certificate()
+}
+
+// Sign implements Backend.
+func (q *Queue) Sign(ctx context.Context, template *x509.Certificate, publicKey any) (*Result, error) {
+	result, err := q.backend.Sign(ctx, template, publicKey)
+	if err == nil || !Transient(err) {
+		return result, err //nolint:wrapcheck
+	}
+
+	select {
+	case q.slots <- struct{}{}:
+		defer func() { <-q.slots }()
+	default:
+		return nil, errors.Wrap(pkgerrors.ErrBackendQueueFull, err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, q.maxWait)
+	defer cancel()
+
+	logger := logging.FromContext(ctx).With("backend", q.backend.Name())
+	logger.Warn("Signing backend unavailable, queueing request", "queued", len(q.slots), "capacity", cap(q.slots), "error", err)
+
+	ticker := time.NewTicker(q.retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(pkgerrors.ErrBackendUnavailable, "queued request expired: "+err.Error())
+		case <-ticker.C:
+		}
+
+		if result, err = q.backend.Sign(ctx, template, publicKey); err == nil {
+			logger.Info("Signing backend recovered, queued request signed")
+
+			return result, nil
+		}
+
+		if !Transient(err) {
+			return nil, err //nolint:wrapcheck
+		}
+	}
+}
+
+// Transient returns true when the signing error is worth retrying: the backend being unreachable or overloaded.
+func Transient(err error) bool {
+	return errors.Is(err, pkgerrors.ErrBackendTransient) || errors.Is(err, pkgerrors.ErrBackendUnavailable)
+}
@@ -0,0 +1,53 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"context"
+	"crypto/x509"
+	"sync"
+)
+
+// Reloadable is the Backend whose CA key material is replaced while serving, such as after the configuration
+// bundle holding it was modified: the requests being signed complete with the replaced backend.
+type Reloadable struct {
+	mu      sync.RWMutex
+	current Backend
+}
+
+// NewReloadable returns a Reloadable backend signing with the given one until replaced.
+func NewReloadable(current Backend) *Reloadable {
+	return &Reloadable{current: current}
+}
+
+// Replace signs the next requests with the given backend.
+func (r *Reloadable) Replace(current Backend) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.current = current
+}
+
+// get returns the backend signing the requests.
+func (r *Reloadable) get() Backend {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.current
+}
+
+// Name implements Backend.
+func (r *Reloadable) Name() string {
+	return r.get().Name()
+}
+
+// Certificate implements Backend.
+func (r *Reloadable) Certificate() *x509.Certificate {
+	return r.get().Certificate()
+}
+
+// Sign implements Backend.
+func (r *Reloadable) Sign(ctx context.Context, template *x509.Certificate, publicKey any) (*Result, error) {
+	return r.get().Sign(ctx, template, publicKey) //nolint:wrapcheck
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	pb "github.com/clastix/talos-csr-signer/pkg/proto"
+)
+
+// requestIDMetadataKey is the gRPC metadata key of the request ID, forwarded to the upstream signer so both
+// logs can be correlated.
+const requestIDMetadataKey = "x-request-id"
+
+// UpstreamOptions configures the Upstream backend.
+type UpstreamOptions struct {
+	// Endpoint is the host:port of the upstream signer.
+	Endpoint string
+	// Token is the Talos token authenticating the requests to the upstream signer.
+	Token string
+	// TLSConfig verifies the upstream signer, along with the client certificate presented to it for mutual TLS.
+	TLSConfig *tls.Config
+	// CACertPEM is the PEM encoded CA certificate the upstream signer issues the certificates with.
+	CACertPEM []byte
+}
+
+// Upstream is the Backend forwarding the CSRs to another talos-csr-signer, or to trustd, over gRPC: the local
+// signer enforces the tokens and the policy without holding the CA key. The certificates are issued by the upstream
+// signer after its own profile, the template only checking the returned public key.
+type Upstream struct {
+	conn      *grpc.ClientConn
+	client    pb.SecurityServiceClient
+	token     string
+	caCertPEM []byte
+	caCert    *x509.Certificate
+}
+
+// NewUpstream returns the Upstream backend, connecting lazily to the upstream signer.
+func NewUpstream(opts UpstreamOptions) (*Upstream, error) {
+	block, _ := pem.Decode(opts.CACertPEM)
+	if block == nil {
+		return nil, errors.Wrap(pkgerrors.ErrDecodedCACertificate, "upstream CA")
+	}
+
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrDecodedCACertificate, err.Error())
+	}
+
+	conn, err := grpc.NewClient(opts.Endpoint, grpc.WithTransportCredentials(credentials.NewTLS(opts.TLSConfig)))
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, err.Error())
+	}
+
+	return &Upstream{
+		conn:      conn,
+		client:    pb.NewSecurityServiceClient(conn),
+		token:     opts.Token,
+		caCertPEM: opts.CACertPEM,
+		caCert:    caCert,
+	}, nil
+}
+
+// Name implements Backend.
+func (u *Upstream) Name() string {
+	return "upstream"
+}
+
+// Certificate implements Backend.
+func (u *Upstream) Certificate() *x509.Certificate {
+	return u.caCert
+}
+
+// Sign implements Backend, forwarding the CSR of the context.
+func (u *Upstream) Sign(ctx context.Context, _ *x509.Certificate, publicKey any) (*Result, error) {
+	csr := CSRFromContext(ctx)
+	if csr == nil {
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, "the upstream signer signs the CSRs, none was provided")
+	}
+
+	outgoing := metadata.Pairs("token", u.token)
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDMetadataKey); len(values) > 0 {
+			outgoing.Set(requestIDMetadataKey, values[0])
+		}
+	}
+
+	resp, err := u.client.Certificate(metadata.NewOutgoingContext(ctx, outgoing), &pb.CertificateRequest{
+		Csr: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr.Raw}),
+	})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+			return nil, errors.Wrap(pkgerrors.ErrBackendTransient, "upstream signer: "+status.Convert(err).Message())
+		default:
+			return nil, errors.Wrap(pkgerrors.ErrBackendSign, "upstream signer: "+status.Convert(err).Message())
+		}
+	}
+
+	block, _ := pem.Decode(resp.GetCrt())
+	if block == nil {
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, "the upstream signer returned no certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, err.Error())
+	}
+
+	if key, ok := publicKey.(interface{ Equal(x crypto.PublicKey) bool }); !ok || !key.Equal(cert.PublicKey) {
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, "the upstream signer signed another public key")
+	}
+
+	ca := resp.GetCa()
+	if len(ca) == 0 {
+		ca = u.caCertPEM
+	}
+
+	return &Result{
+		Certificate: block.Bytes,
+		CA:          ca,
+		Backend:     u.Name(),
+	}, nil
+}
+
+// Close closes the connection to the upstream signer.
+func (u *Upstream) Close() error {
+	return u.conn.Close() //nolint:wrapcheck
+}
@@ -0,0 +1,277 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+)
+
+// vaultKeyUsages are the names of the key usages in the Vault PKI API.
+var vaultKeyUsages = map[x509.KeyUsage]string{
+	x509.KeyUsageDigitalSignature:  "DigitalSignature",
+	x509.KeyUsageContentCommitment: "ContentCommitment",
+	x509.KeyUsageKeyEncipherment:   "KeyEncipherment",
+	x509.KeyUsageDataEncipherment:  "DataEncipherment",
+	x509.KeyUsageKeyAgreement:      "KeyAgreement",
+	x509.KeyUsageCertSign:          "CertSign",
+	x509.KeyUsageCRLSign:           "CRLSign",
+	x509.KeyUsageEncipherOnly:      "EncipherOnly",
+	x509.KeyUsageDecipherOnly:      "DecipherOnly",
+}
+
+// vaultExtKeyUsages are the names of the extended key usages in the Vault PKI API.
+var vaultExtKeyUsages = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "Any",
+	x509.ExtKeyUsageServerAuth:      "ServerAuth",
+	x509.ExtKeyUsageClientAuth:      "ClientAuth",
+	x509.ExtKeyUsageCodeSigning:     "CodeSigning",
+	x509.ExtKeyUsageEmailProtection: "EmailProtection",
+	x509.ExtKeyUsageTimeStamping:    "TimeStamping",
+	x509.ExtKeyUsageOCSPSigning:     "OCSPSigning",
+}
+
+// VaultOptions configures the Vault backend.
+type VaultOptions struct {
+	// Address is the URL of the Vault server.
+	Address string
+	// Mount is the path the PKI secrets engine is mounted at.
+	Mount string
+	// Role is the PKI role constraining the sign-verbatim requests: empty uses the defaults of the engine.
+	Role string
+	// Token authenticates the requests, when TokenPath is empty.
+	Token string
+	// TokenPath is the file holding the token, read at every request so the ones renewed by a Vault agent are used.
+	TokenPath string
+	// Namespace is the Vault Enterprise namespace of the mount: empty for the root one.
+	Namespace string
+	// Client sends the requests to Vault: nil uses http.DefaultClient.
+	Client *http.Client
+}
+
+// Vault is the Backend delegating the issuance to the sign-verbatim endpoint of a Vault PKI secrets engine, the CA
+// private key never leaving Vault. The CSR subject and SANs are copied verbatim by Vault, along with the validity and
+// the key usages of the template, while the serial number and the extensions of the template are not.
+type Vault struct {
+	opts      VaultOptions
+	caCertPEM []byte
+	caCert    *x509.Certificate
+}
+
+// vaultResponse is the response of the Vault PKI API.
+type vaultResponse struct {
+	Data struct {
+		Certificate string   `json:"certificate"`
+		IssuingCA   string   `json:"issuing_ca"`
+		CAChain     []string `json:"ca_chain"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+// NewVault returns the Vault backend, reading the CA certificate of the PKI secrets engine.
+func NewVault(ctx context.Context, opts VaultOptions) (*Vault, error) {
+	if opts.Mount == "" {
+		opts.Mount = "pki"
+	}
+
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+
+	v := &Vault{opts: opts}
+
+	caCertPEM, err := v.call(ctx, http.MethodGet, "ca/pem", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(caCertPEM)
+	if block == nil {
+		return nil, errors.Wrap(pkgerrors.ErrDecodedCACertificate, "Vault CA")
+	}
+
+	if v.caCert, err = x509.ParseCertificate(block.Bytes); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrDecodedCACertificate, err.Error())
+	}
+
+	v.caCertPEM = pem.EncodeToMemory(block)
+
+	return v, nil
+}
+
+// Name implements Backend.
+func (v *Vault) Name() string {
+	return "vault"
+}
+
+// Certificate implements Backend.
+func (v *Vault) Certificate() *x509.Certificate {
+	return v.caCert
+}
+
+// Sign implements Backend, signing the CSR of the context.
+func (v *Vault) Sign(ctx context.Context, template *x509.Certificate, publicKey any) (*Result, error) {
+	csr := CSRFromContext(ctx)
+	if csr == nil {
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, "Vault signs the CSRs, none was provided")
+	}
+
+	keyUsages := make([]string, 0, len(vaultKeyUsages))
+
+	for usage, name := range vaultKeyUsages {
+		if template.KeyUsage&usage != 0 {
+			keyUsages = append(keyUsages, name)
+		}
+	}
+
+	sort.Strings(keyUsages)
+
+	extKeyUsages := make([]string, 0, len(template.ExtKeyUsage))
+
+	for _, usage := range template.ExtKeyUsage {
+		if name, ok := vaultExtKeyUsages[usage]; ok {
+			extKeyUsages = append(extKeyUsages, name)
+		}
+	}
+
+	path := "sign-verbatim"
+	if v.opts.Role != "" {
+		path += "/" + v.opts.Role
+	}
+
+	data, err := v.call(ctx, http.MethodPost, path, map[string]any{
+		"csr":           string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr.Raw})),
+		"ttl":           fmt.Sprintf("%ds", int64(time.Until(template.NotAfter).Seconds())),
+		"key_usage":     keyUsages,
+		"ext_key_usage": extKeyUsages,
+		"format":        "pem",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var response vaultResponse
+	if err = json.Unmarshal(data, &response); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, err.Error())
+	}
+
+	block, _ := pem.Decode([]byte(response.Data.Certificate))
+	if block == nil {
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, "Vault returned no certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, err.Error())
+	}
+
+	if key, ok := publicKey.(interface{ Equal(x crypto.PublicKey) bool }); !ok || !key.Equal(cert.PublicKey) {
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, "Vault signed another public key")
+	}
+
+	ca := pki.MergeBundles(append([][]byte{[]byte(response.Data.IssuingCA)}, toBytes(response.Data.CAChain)...)...)
+	if len(ca) == 0 {
+		ca = v.caCertPEM
+	}
+
+	return &Result{
+		Certificate: block.Bytes,
+		CA:          ca,
+		Backend:     v.Name(),
+	}, nil
+}
+
+// call sends the request to the PKI secrets engine, returning the response body.
+func (v *Vault) call(ctx context.Context, method, path string, body any) ([]byte, error) {
+	var reader io.Reader
+
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrBackendSign, err.Error())
+		}
+
+		reader = bytes.NewReader(data)
+	}
+
+	endpoint := strings.TrimSuffix(v.opts.Address, "/") + "/v1/" + strings.Trim(v.opts.Mount, "/") + "/" + path
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, err.Error())
+	}
+
+	token := v.opts.Token
+
+	if v.opts.TokenPath != "" {
+		data, readErr := os.ReadFile(v.opts.TokenPath)
+		if readErr != nil {
+			return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read the Vault token: "+readErr.Error())
+		}
+
+		token = strings.TrimSpace(string(data))
+	}
+
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+
+	if v.opts.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.opts.Namespace)
+	}
+
+	resp, err := v.opts.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrBackendTransient, "Vault: "+err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, err.Error())
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// The sealed, overloaded, or rate limiting Vault may answer later, unlike the rejected requests
+		cause := pkgerrors.ErrBackendSign
+		if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+			cause = pkgerrors.ErrBackendTransient
+		}
+
+		var response vaultResponse
+		if json.Unmarshal(data, &response) == nil && len(response.Errors) > 0 {
+			return nil, errors.Wrap(cause, fmt.Sprintf("Vault %s: %s", resp.Status, strings.Join(response.Errors, "; ")))
+		}
+
+		return nil, errors.Wrap(cause, "Vault "+resp.Status)
+	}
+
+	return data, nil
+}
+
+// toBytes returns the PEM strings as byte slices.
+func toBytes(values []string) [][]byte {
+	out := make([][]byte, 0, len(values))
+	for _, value := range values {
+		out = append(out, []byte(value))
+	}
+
+	return out
+}
@@ -0,0 +1,176 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package bundle contains the configuration bundle of a signer: the CA, the tokens, the signing policy, and the
+// profiles in a single YAML document, mounted from one Secret in place of the separate files and variables.
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+// roles are the machine roles whose profile is configured by the bundle.
+var roles = []string{"controlplane", "worker"}
+
+// Bundle is the configuration of a signer, such as:
+//
+//	ca:
+//	  cert: |
+//	    -----BEGIN CERTIFICATE-----
+//	  key: |
+//	    -----BEGIN ED25519 PRIVATE KEY-----
+//	tokens: |
+//	  j7vu1i.fje22qrlfvsu346w
+//	policy:
+//	  dns-names: "*.nodes.example.com"
+//	profiles:
+//	  controlplane:
+//	    usages: server,client
+//
+// The policy and the profiles settings are keyed by the flag names, without their policy- and role prefix.
+type Bundle struct {
+	// CACertificate is the PEM encoded CA certificate: empty reads it from the configured path.
+	CACertificate []byte
+	// CAPrivateKey is the PEM encoded CA private key: empty reads it from the configured path.
+	CAPrivateKey []byte
+	// Tokens are the Talos tokens, in the format of the tokens file: empty uses the configured ones.
+	Tokens string
+	// Policy holds the signing policy settings.
+	Policy map[string]any
+	// Profiles holds the settings of the profile of each machine role.
+	Profiles map[string]map[string]any
+
+	digest [sha256.Size]byte
+}
+
+// Parse returns the Bundle of the YAML document.
+func Parse(data []byte) (*Bundle, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrBundle, err.Error())
+	}
+
+	for _, key := range v.AllKeys() {
+		section, _, _ := strings.Cut(key, ".")
+
+		switch section {
+		case "ca", "tokens", "policy", "profiles":
+		default:
+			return nil, errors.Wrap(pkgerrors.ErrBundle, "unknown section "+section)
+		}
+	}
+
+	b := &Bundle{
+		CACertificate: []byte(v.GetString("ca.cert")),
+		CAPrivateKey:  []byte(v.GetString("ca.key")),
+		Tokens:        v.GetString("tokens"),
+		Policy:        v.GetStringMap("policy"),
+		Profiles:      make(map[string]map[string]any),
+		digest:        sha256.Sum256(data),
+	}
+
+	if (len(b.CACertificate) == 0) != (len(b.CAPrivateKey) == 0) {
+		return nil, errors.Wrap(pkgerrors.ErrBundle, "the CA certificate and private key must be both set")
+	}
+
+	for role := range v.GetStringMap("profiles") {
+		if !slices.Contains(roles, role) {
+			return nil, errors.Wrap(pkgerrors.ErrBundle, "unknown machine role "+role)
+		}
+
+		b.Profiles[role] = v.GetStringMap("profiles." + role)
+	}
+
+	return b, nil
+}
+
+// Load returns the Bundle read from the file.
+func Load(path string) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrReadFile, err.Error())
+	}
+
+	return Parse(data)
+}
+
+// HasCA returns true when the bundle holds the CA, in place of the configured files.
+func (b *Bundle) HasCA() bool {
+	return len(b.CACertificate) > 0
+}
+
+// Settings returns the settings of the bundle keyed by the flag names, overriding the configured ones.
+func (b *Bundle) Settings() map[string]any {
+	settings := make(map[string]any)
+
+	for key, value := range b.Policy {
+		settings["policy-"+key] = value
+	}
+
+	for role, profile := range b.Profiles {
+		for key, value := range profile {
+			settings[role+"-"+key] = value
+		}
+	}
+
+	if b.Tokens != "" {
+		settings["talos-token"] = b.Tokens
+		settings["talos-token-path"] = ""
+	}
+
+	return settings
+}
+
+// Watch reads the bundle file every interval until the context is done, calling onChange with the bundle when its
+// content differs from the given one, such as a mounted Secret being updated. Invalid bundles are ignored, keeping
+// the previous one.
+func Watch(ctx context.Context, path string, interval time.Duration, current *Bundle, onChange func(*Bundle)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to read the configuration bundle, keeping the previous one", "path", path,
+				"error", err)
+
+			continue
+		}
+
+		if sha256.Sum256(data) == current.digest {
+			continue
+		}
+
+		b, err := Parse(data)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to reload the configuration bundle, keeping the previous one", "path", path,
+				"error", err)
+
+			continue
+		}
+
+		current = b
+
+		onChange(b)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package clock checks the sanity of the system time, since a skewed signer mints certificates
+// the nodes immediately reject.
+package clock
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+const (
+	// ActionWarn logs the clock skew, issuing certificates anyway.
+	ActionWarn = "warn"
+	// ActionRefuse refuses to issue certificates while the clock is skewed.
+	ActionRefuse = "refuse"
+)
+
+// ntpEpochOffset is the number of seconds between the NTP epoch (1900) and the Unix one (1970).
+const ntpEpochOffset = 2208988800
+
+// CheckCA verifies the current time falls in the validity period of the CA certificate.
+func CheckCA(now time.Time, ca *x509.Certificate) error {
+	switch {
+	case now.Before(ca.NotBefore):
+		return errors.Wrapf(pkgerrors.ErrClockSkew, "current time %s is before the CA NotBefore %s", now.Format(time.RFC3339), ca.NotBefore.Format(time.RFC3339))
+	case now.After(ca.NotAfter):
+		return errors.Wrapf(pkgerrors.ErrClockSkew, "current time %s is after the CA NotAfter %s", now.Format(time.RFC3339), ca.NotAfter.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// QueryNTP returns the offset of the local clock from the given NTP server (host or host:port), using SNTP.
+func QueryNTP(ctx context.Context, server string) (time.Duration, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return 0, errors.Wrap(pkgerrors.ErrNTPQuery, err.Error())
+	}
+	defer func() { _ = conn.Close() }()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+
+	_ = conn.SetDeadline(deadline)
+
+	request := make([]byte, 48)
+	request[0] = 0x23 // LI = 0, VN = 4, Mode = 3 (client)
+
+	sentAt := time.Now()
+	if _, err = conn.Write(request); err != nil {
+		return 0, errors.Wrap(pkgerrors.ErrNTPQuery, err.Error())
+	}
+
+	response := make([]byte, 48)
+	if _, err = conn.Read(response); err != nil {
+		return 0, errors.Wrap(pkgerrors.ErrNTPQuery, err.Error())
+	}
+
+	receivedAt := time.Now()
+	// Receive (T2) and transmit (T3) timestamps of the server.
+	serverReceived := ntpTime(response[32:40])
+	serverTransmitted := ntpTime(response[40:48])
+
+	return (serverReceived.Sub(sentAt) + serverTransmitted.Sub(receivedAt)) / 2, nil
+}
+
+func ntpTime(data []byte) time.Time {
+	seconds := int64(binary.BigEndian.Uint32(data[0:4])) - ntpEpochOffset
+	fraction := int64(binary.BigEndian.Uint32(data[4:8]))
+
+	return time.Unix(seconds, (fraction*int64(time.Second))>>32)
+}
+
+// Checker periodically verifies the clock against the CA validity and, optionally, an NTP server.
+// A nil Checker never reports a skew.
+type Checker struct {
+	ca        *x509.Certificate
+	ntpServer string
+	maxSkew   time.Duration
+	action    string
+
+	mu  sync.RWMutex
+	err error
+}
+
+// NewChecker returns a Checker for the given CA, tolerating maxSkew from the NTP server when not empty.
+func NewChecker(ca *x509.Certificate, ntpServer string, maxSkew time.Duration, action string) (*Checker, error) {
+	if action != ActionWarn && action != ActionRefuse {
+		return nil, errors.Wrap(pkgerrors.ErrClockSkewAction, action)
+	}
+
+	return &Checker{
+		ca:        ca,
+		ntpServer: ntpServer,
+		maxSkew:   maxSkew,
+		action:    action,
+	}, nil
+}
+
+// Check verifies the clock sanity, logging the outcome.
+func (c *Checker) Check(ctx context.Context) error {
+	err := CheckCA(time.Now(), c.ca)
+
+	if err == nil && c.ntpServer != "" {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		offset, ntpErr := QueryNTP(ctx, c.ntpServer)
+
+		switch {
+		case ntpErr != nil:
+			// An unreachable NTP server doesn't prove the clock is skewed.
+			logging.FromContext(ctx).Warn("Clock check against the NTP server failed", "server", c.ntpServer, "error", ntpErr)
+		case offset.Abs() > c.maxSkew:
+			err = errors.Wrap(pkgerrors.ErrClockSkew, fmt.Sprintf("offset from NTP server %s is %s, exceeding %s", c.ntpServer, offset, c.maxSkew))
+		}
+	}
+
+	if err != nil {
+		logging.FromContext(ctx).Warn("Clock sanity check failed", "action", c.action, "error", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err == nil && c.err != nil {
+		logging.FromContext(ctx).Info("Clock sanity check recovered")
+	}
+
+	c.err = err
+
+	return err
+}
+
+// Run checks the clock sanity at the given interval until the context is done.
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = c.Check(ctx)
+		}
+	}
+}
+
+// Err returns the clock skew error when issuance must be refused, nil otherwise.
+func (c *Checker) Err() error {
+	if c == nil || c.action != ActionRefuse {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.err
+}
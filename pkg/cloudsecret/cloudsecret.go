@@ -0,0 +1,268 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cloudsecret reads the secrets of AWS Secrets Manager and GCP Secret Manager, addressed by the awssm:// and
+// gcpsm:// URIs, each one a JSON object of string values such as the key/value secrets of the AWS console.
+package cloudsecret
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/kms"
+)
+
+const (
+	// SchemeAWS is the scheme of the secrets of AWS Secrets Manager, as awssm://<name or ARN>[?region=<region>].
+	SchemeAWS = "awssm://"
+	// SchemeGCP is the scheme of the secrets of GCP Secret Manager, as
+	// gcpsm://projects/<project>/secrets/<secret>[/versions/<version>].
+	SchemeGCP = "gcpsm://"
+)
+
+// gcpMetadataTokenURL is the address of the access token of the service account attached to the GCE instance, or
+// bound to the Kubernetes one by the GKE Workload Identity.
+const gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// Options configures the requests to the secret managers.
+type Options struct {
+	// Client sends the requests: nil uses http.DefaultClient.
+	Client *http.Client
+	// AWSCredentials sign the requests to AWS Secrets Manager: nil uses the standard AWS environment variables and the
+	// instance role.
+	AWSCredentials *kms.CredentialsProvider
+}
+
+// Fetch returns the values of the secret of the URI, keyed by their name.
+func Fetch(ctx context.Context, uri string, opts Options) (map[string][]byte, error) {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+
+	switch {
+	case strings.HasPrefix(uri, SchemeAWS):
+		data, err = fetchAWS(ctx, uri, opts)
+	case strings.HasPrefix(uri, SchemeGCP):
+		data, err = fetchGCP(ctx, uri, opts)
+	default:
+		err = errors.Wrap(pkgerrors.ErrCloudSecret, "unsupported secret "+uri+", expected "+SchemeAWS+" or "+SchemeGCP)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]string
+	if err = json.Unmarshal(data, &values); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrCloudSecret, "the secret "+uri+" is not a JSON object of strings: "+err.Error())
+	}
+
+	secret := make(map[string][]byte, len(values))
+	for key, value := range values {
+		secret[key] = []byte(value)
+	}
+
+	return secret, nil
+}
+
+// parseAWS returns the secret ID and the region of the awssm:// URI: the one of the query, of the ARN, or of the
+// AWS_REGION environment variable.
+func parseAWS(uri string) (string, string, error) {
+	secretID, query, _ := strings.Cut(strings.TrimPrefix(uri, SchemeAWS), "?")
+	if secretID == "" {
+		return "", "", errors.Wrap(pkgerrors.ErrCloudSecret, "the secret name is missing from "+uri)
+	}
+
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return "", "", errors.Wrap(pkgerrors.ErrCloudSecret, "invalid query of "+uri+": "+err.Error())
+	}
+
+	region := params.Get("region")
+	if parts := strings.SplitN(secretID, ":", 7); region == "" && len(parts) == 7 && parts[0] == "arn" {
+		region = parts[3]
+	}
+
+	for _, env := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region == "" {
+			region = os.Getenv(env)
+		}
+	}
+
+	if region == "" {
+		return "", "", errors.Wrap(pkgerrors.ErrCloudSecret, "the region of "+uri+" is missing, set it with ?region=")
+	}
+
+	return secretID, region, nil
+}
+
+// fetchAWS returns the value of the secret of AWS Secrets Manager, its SecretString or its SecretBinary.
+func fetchAWS(ctx context.Context, uri string, opts Options) ([]byte, error) {
+	secretID, region, err := parseAWS(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := opts.AWSCredentials
+	if credentials == nil {
+		credentials = &kms.CredentialsProvider{Region: region, Client: opts.Client}
+	}
+
+	creds, err := credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": secretID})
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrCloudSecret, err.Error())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://secretsmanager."+region+".amazonaws.com/", bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrCloudSecret, err.Error())
+	}
+
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+
+	if err = kms.SignV4(ctx, req, body, creds, region, "secretsmanager", time.Now()); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrCloudSecret, err.Error())
+	}
+
+	data, err := do(opts.Client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		SecretString string `json:"SecretString"`
+		SecretBinary []byte `json:"SecretBinary"`
+	}
+
+	if err = json.Unmarshal(data, &response); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrCloudSecret, "GetSecretValue: "+err.Error())
+	}
+
+	if response.SecretString != "" {
+		return []byte(response.SecretString), nil
+	}
+
+	return response.SecretBinary, nil
+}
+
+// parseGCP returns the resource name of the secret version of the gcpsm:// URI, the latest one by default.
+func parseGCP(uri string) (string, error) {
+	name := strings.TrimPrefix(uri, SchemeGCP)
+
+	parts := strings.Split(name, "/")
+	switch {
+	case len(parts) == 4 && parts[0] == "projects" && parts[2] == "secrets" && parts[1] != "" && parts[3] != "":
+		return name + "/versions/latest", nil
+	case len(parts) == 6 && parts[0] == "projects" && parts[2] == "secrets" && parts[4] == "versions" && parts[1] != "" && parts[3] != "" && parts[5] != "":
+		return name, nil
+	default:
+		return "", errors.Wrap(pkgerrors.ErrCloudSecret, "invalid secret "+uri+", expected "+SchemeGCP+"projects/<project>/secrets/<secret>[/versions/<version>]")
+	}
+}
+
+// fetchGCP returns the payload of the secret version of GCP Secret Manager, accessed with the token of the
+// GOOGLE_OAUTH_ACCESS_TOKEN environment variable, otherwise with the one of the metadata server.
+func fetchGCP(ctx context.Context, uri string, opts Options) ([]byte, error) {
+	name, err := parseGCP(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	if accessToken == "" {
+		if accessToken, err = gcpMetadataToken(ctx, opts.Client); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://secretmanager.googleapis.com/v1/"+name+":access", nil)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrCloudSecret, err.Error())
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	data, err := do(opts.Client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Payload struct {
+			Data []byte `json:"data"`
+		} `json:"payload"`
+	}
+
+	if err = json.Unmarshal(data, &response); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrCloudSecret, "AccessSecretVersion: "+err.Error())
+	}
+
+	return response.Payload.Data, nil
+}
+
+// gcpMetadataToken returns the access token of the service account of the metadata server.
+func gcpMetadataToken(ctx context.Context, client *http.Client) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataTokenURL, nil)
+	if err != nil {
+		return "", errors.Wrap(pkgerrors.ErrCloudSecret, err.Error())
+	}
+
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	data, err := do(client, req)
+	if err != nil {
+		return "", err
+	}
+
+	var response struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	if err = json.Unmarshal(data, &response); err != nil || response.AccessToken == "" {
+		return "", errors.Wrap(pkgerrors.ErrCloudSecret, "no access token from the metadata server")
+	}
+
+	return response.AccessToken, nil
+}
+
+// do sends the request, returning the body of the successful responses.
+func do(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrCloudSecret, err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrCloudSecret, err.Error())
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Wrap(pkgerrors.ErrCloudSecret, fmt.Sprintf("%s %s: %s %s", req.Method, req.URL.Host, resp.Status, bytes.TrimSpace(data)))
+	}
+
+	return data, nil
+}
@@ -0,0 +1,801 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package config loads the signer configuration from the flags, the environment, and the configuration file,
+// sharing a single source of truth between the CLI and the programs embedding the signer.
+package config
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/policy"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+)
+
+// Config is the configuration of the signer.
+type Config struct {
+	Server   Server
+	CA       CA
+	Vault    Vault
+	KMS      KMS
+	Upstream Upstream
+	Tokens   Tokens
+	Instance InstanceIdentity
+	Bundle   Bundle
+	Ledger   Ledger
+	Standby  Standby
+	Issuance Issuance
+	Approval Approval
+	Policy   Policy
+	Roles    Roles
+	Clock    Clock
+	Watchdog Watchdog
+	Events   Events
+	CRL      CRL
+	Admin    Admin
+	Log      Log
+}
+
+// Server is the configuration of the gRPC server.
+type Server struct {
+	Port                  int
+	TLSCertificatePath    string
+	TLSPrivateKeyPath     string
+	ClientCAPath          string
+	MaxConnectionAge      time.Duration
+	MaxConnectionAgeGrace time.Duration
+	StartupWaitTimeout    time.Duration
+	StrictStartup         bool
+	JournalDir            string
+	FeatureGates          string
+	Plugins               []string
+}
+
+// CA is the configuration of the signing CA and of its backends.
+type CA struct {
+	CertificatePath         string
+	PrivateKeyPath          string
+	CertificateB64          string
+	PrivateKeyB64           string
+	BundlePath              string
+	SecretRef               string
+	ChainPath               string
+	ExtraRootsPaths         []string
+	CertURL                 string
+	CertURLSHA256           []string
+	CertURLRefreshInterval  time.Duration
+	PKCS12Path              string
+	Source                  string
+	SourceRefreshInterval   time.Duration
+	Dir                     string
+	DirRefreshInterval      time.Duration
+	DirSharding             bool
+	DirShardID              string
+	DirShardHeartbeat       time.Duration
+	HybridKeyPath           string
+	ExpiryWarning           time.Duration
+	FallbackCertificatePath string
+	FallbackPrivateKeyPath  string
+	CircuitFailureThreshold int
+	CircuitCooldown         time.Duration
+	QueueSize               int
+	QueueRetryInterval      time.Duration
+	QueueMaxWait            time.Duration
+}
+
+// Vault is the configuration of the Vault PKI secrets engine signing the certificates.
+type Vault struct {
+	Address         string
+	Mount           string
+	Role            string
+	Token           string
+	TokenPath       string
+	Namespace       string
+	CertificatePath string
+	Timeout         time.Duration
+}
+
+// KMS is the configuration of the AWS KMS key signing the certificates.
+type KMS struct {
+	KeyARN          string
+	Region          string
+	Endpoint        string
+	CredentialsPath string
+	Profile         string
+	Timeout         time.Duration
+}
+
+// Upstream is the configuration of the upstream signer the CSRs are forwarded to.
+type Upstream struct {
+	Endpoint           string
+	Token              string
+	TLSCertificatePath string
+	TLSPrivateKeyPath  string
+	CAPath             string
+	ServerName         string
+}
+
+// Tokens is the configuration of the Talos tokens accepted from the nodes.
+type Tokens struct {
+	Token       string
+	Path        string
+	Previous    []string
+	ClassesPath string
+}
+
+// InstanceIdentity is the configuration of the cloud instance identity documents authenticating the nodes.
+type InstanceIdentity struct {
+	Mode         string
+	Accounts     []string
+	AWSCertsPath string
+	AWSMaxAge    time.Duration
+	Audience     string
+	GCPKeysURL   string
+	AzureKeysURL string
+	AzureTenants []string
+}
+
+// Bundle is the configuration of the configuration bundle.
+type Bundle struct {
+	Path           string
+	ReloadInterval time.Duration
+}
+
+// Ledger is the configuration of the issuance state.
+type Ledger struct {
+	URL              string
+	KeyPrefix        string
+	Retention        time.Duration
+	MaxRecords       int
+	PruneInterval    time.Duration
+	SnapshotDir      string
+	SnapshotInterval time.Duration
+	SnapshotRetain   int
+}
+
+// Standby is the configuration of the warm standby replicating the primary signer.
+type Standby struct {
+	PrimaryURL   string
+	PrimaryToken string
+	SyncInterval time.Duration
+}
+
+// Issuance is the configuration of the retry cache, of the quota, of the re-issuance cooldown, of the
+// proof-of-possession challenge, of the serial numbers, of the fingerprint trailers, of the node UUID, of the extra
+// extensions and certificate policies, of the extra SANs, of the TPM attestation, and of the transparency log.
+type Issuance struct {
+	RetryCacheTTL        time.Duration
+	Quota                int64
+	QuotaWindow          time.Duration
+	ReissueCooldown      time.Duration
+	ProofOfPossessionTTL time.Duration
+	SerialBits           int
+	SerialPrefix         string
+	CAExpiry             string
+	MaxTTL               time.Duration
+	FingerprintTrailers  bool
+	NodeUUID             string
+	NodeUUIDExtensionOID string
+	Extensions           []string
+
+	CertificatePolicies       []string
+	CertificatePoliciesCPSURI string
+
+	ExtraSANs     []string
+	ExtraSANsPath string
+
+	SubordinateCA bool
+
+	SPIFFEIDTemplate string
+
+	TPMAttestation       string
+	TPMEndorsementRoots  string
+	TransparencyLogURL   string
+	TransparencyTimeout  time.Duration
+	TransparencyRequired bool
+}
+
+// Approval is the configuration of the dual control of the privileged certificates.
+type Approval struct {
+	Organizations []string
+	Threshold     int
+	TTL           time.Duration
+	ApproversPath string
+}
+
+// Policy is the configuration of the signing policy.
+type Policy struct {
+	KeyAlgorithms []string
+	MinRSABits    int
+	ExtKeyUsages  []string
+	DNSNames      []string
+	DNSRegexps    []string
+	IPRanges      []string
+	CommonName    string
+	Wildcards     string
+	StripLocalIPs bool
+
+	EmailAction    string
+	EmailAddresses []string
+	URIAction      string
+	URIs           []string
+
+	MaxDNSNames    int
+	MaxIPAddresses int
+	MaxExtensions  int
+
+	Organizations      []string
+	OrganizationAction string
+	CEL                string
+	OPAURL             string
+	OPATimeout         time.Duration
+	OPABundle          string
+	OPABundleInterval  time.Duration
+	OPABundleKeyPath   string
+	OPABundleUsername  string
+	OPABundlePassword  string
+	Simulate           []string
+
+	SubjectOrganizations      []string
+	SubjectDrop               []string
+	SubjectCommonNameTemplate string
+
+	FilePath           string
+	FileReloadInterval time.Duration
+
+	DenyListPath           string
+	DenyListReloadInterval time.Duration
+
+	DNSVerification         bool
+	DNSVerificationRanges   []string
+	DNSVerificationBypass   []string
+	DNSVerificationCacheTTL time.Duration
+
+	EnrollmentWindows  string
+	EnrollmentTimezone string
+}
+
+// Roles is the configuration of the machine roles detection, and of the certificates issued per role or per named
+// profile.
+type Roles struct {
+	ControlPlaneOrganizations []string
+	ControlPlaneCommonName    string
+	ControlPlaneValidity      time.Duration
+	ControlPlaneUsages        []string
+	ControlPlaneKeyAlgorithms []string
+	WorkerValidity            time.Duration
+	WorkerUsages              []string
+	WorkerKeyAlgorithms       []string
+	ProfilesPath              string
+}
+
+// Clock is the configuration of the clock sanity checks.
+type Clock struct {
+	SkewAction    string
+	MaxSkew       time.Duration
+	CheckInterval time.Duration
+	NTPServer     string
+}
+
+// Watchdog is the configuration of the internal failures detection.
+type Watchdog struct {
+	Threshold int
+	Exit      bool
+}
+
+// Events is the configuration of the event broker.
+type Events struct {
+	SinkURL       string
+	Topic         string
+	BufferSize    int
+	TLS           bool
+	TLSCAPath     string
+	SASLMechanism string
+	SASLUsername  string
+	SASLPassword  string
+}
+
+// CRL is the configuration of the Certificate Revocation List.
+type CRL struct {
+	Validity time.Duration
+	Interval time.Duration
+}
+
+// Admin is the configuration of the admin API.
+type Admin struct {
+	Address  string
+	Token    string
+	Channelz bool
+}
+
+// Log is the configuration of the log file rotation.
+type Log struct {
+	File       string
+	MaxSize    int
+	MaxAge     int
+	MaxBackups int
+	Compress   bool
+}
+
+// Load returns the validated configuration of the signer.
+func Load(v *viper.Viper) (*Config, error) {
+	cfg := Read(v)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Read returns the configuration without validating it, as needed by the subcommands using a part of it.
+func Read(v *viper.Viper) *Config {
+	return &Config{
+		Server: Server{
+			Port:                  v.GetInt(KeyPort),
+			TLSCertificatePath:    v.GetString(KeyTLSCertificatePath),
+			TLSPrivateKeyPath:     v.GetString(KeyTLSPrivateKeyPath),
+			ClientCAPath:          v.GetString(KeyClientCAPath),
+			MaxConnectionAge:      v.GetDuration(KeyMaxConnectionAge),
+			MaxConnectionAgeGrace: v.GetDuration(KeyMaxConnectionAgeGrace),
+			StartupWaitTimeout:    v.GetDuration(KeyStartupWaitTimeout),
+			StrictStartup:         v.GetBool(KeyStrictStartup),
+			JournalDir:            v.GetString(KeyJournalDir),
+			FeatureGates:          v.GetString(KeyFeatureGates),
+			Plugins:               SplitList(v.GetString(KeyPlugins)),
+		},
+		CA: CA{
+			CertificatePath:         v.GetString(KeyCACertificatePath),
+			PrivateKeyPath:          v.GetString(KeyCAPrivateKeyPath),
+			CertificateB64:          v.GetString(KeyCACertificateB64),
+			PrivateKeyB64:           v.GetString(KeyCAPrivateKeyB64),
+			BundlePath:              v.GetString(KeyCABundlePath),
+			SecretRef:               v.GetString(KeyCASecretRef),
+			ChainPath:               v.GetString(KeyCAChainPath),
+			ExtraRootsPaths:         SplitList(v.GetString(KeyCAExtraRootsPath)),
+			CertURL:                 v.GetString(KeyCACertURL),
+			CertURLSHA256:           SplitList(v.GetString(KeyCACertURLSHA256)),
+			CertURLRefreshInterval:  v.GetDuration(KeyCACertURLRefreshInterval),
+			PKCS12Path:              v.GetString(KeyCAPKCS12Path),
+			Source:                  v.GetString(KeyCASource),
+			SourceRefreshInterval:   v.GetDuration(KeyCASourceRefreshInterval),
+			Dir:                     v.GetString(KeyCADir),
+			DirRefreshInterval:      v.GetDuration(KeyCADirRefreshInterval),
+			DirSharding:             v.GetBool(KeyCADirSharding),
+			DirShardID:              v.GetString(KeyCADirShardID),
+			DirShardHeartbeat:       v.GetDuration(KeyCADirShardHeartbeat),
+			HybridKeyPath:           v.GetString(KeyCAHybridKeyPath),
+			ExpiryWarning:           v.GetDuration(KeyCAExpiryWarning),
+			FallbackCertificatePath: v.GetString(KeyFallbackCACertificatePath),
+			FallbackPrivateKeyPath:  v.GetString(KeyFallbackCAPrivateKeyPath),
+			CircuitFailureThreshold: v.GetInt(KeyCircuitFailureThreshold),
+			CircuitCooldown:         v.GetDuration(KeyCircuitCooldown),
+			QueueSize:               v.GetInt(KeyQueueSize),
+			QueueRetryInterval:      v.GetDuration(KeyQueueRetryInterval),
+			QueueMaxWait:            v.GetDuration(KeyQueueMaxWait),
+		},
+		Vault: Vault{
+			Address:         v.GetString(KeyVaultAddress),
+			Mount:           v.GetString(KeyVaultMount),
+			Role:            v.GetString(KeyVaultRole),
+			Token:           v.GetString(KeyVaultToken),
+			TokenPath:       v.GetString(KeyVaultTokenPath),
+			Namespace:       v.GetString(KeyVaultNamespace),
+			CertificatePath: v.GetString(KeyVaultCACertificatePath),
+			Timeout:         v.GetDuration(KeyVaultTimeout),
+		},
+		KMS: KMS{
+			KeyARN:          v.GetString(KeyKMSKeyARN),
+			Region:          v.GetString(KeyKMSRegion),
+			Endpoint:        v.GetString(KeyKMSEndpoint),
+			CredentialsPath: v.GetString(KeyKMSCredentialsPath),
+			Profile:         v.GetString(KeyKMSProfile),
+			Timeout:         v.GetDuration(KeyKMSTimeout),
+		},
+		Upstream: Upstream{
+			Endpoint:           v.GetString(KeyUpstreamEndpoint),
+			Token:              v.GetString(KeyUpstreamToken),
+			TLSCertificatePath: v.GetString(KeyUpstreamTLSCertPath),
+			TLSPrivateKeyPath:  v.GetString(KeyUpstreamTLSKeyPath),
+			CAPath:             v.GetString(KeyUpstreamCAPath),
+			ServerName:         v.GetString(KeyUpstreamServerName),
+		},
+		Tokens: Tokens{
+			Token:       v.GetString(KeyTalosToken),
+			Path:        v.GetString(KeyTalosTokenPath),
+			Previous:    SplitList(v.GetString(KeyTalosPreviousTokens)),
+			ClassesPath: v.GetString(KeyTokenClassesPath),
+		},
+		Instance: InstanceIdentity{
+			Mode:         v.GetString(KeyInstanceIdentity),
+			Accounts:     SplitList(v.GetString(KeyInstanceIdentityAccounts)),
+			AWSCertsPath: v.GetString(KeyInstanceIdentityAWSCerts),
+			AWSMaxAge:    v.GetDuration(KeyInstanceIdentityAWSMaxAge),
+			Audience:     v.GetString(KeyInstanceIdentityAudience),
+			GCPKeysURL:   v.GetString(KeyInstanceIdentityGCPKeys),
+			AzureKeysURL: v.GetString(KeyInstanceIdentityAzureKeys),
+			AzureTenants: SplitList(v.GetString(KeyInstanceIdentityAzureTenants)),
+		},
+		Bundle: Bundle{
+			Path:           v.GetString(KeyBundlePath),
+			ReloadInterval: v.GetDuration(KeyBundleReloadInterval),
+		},
+		Ledger: Ledger{
+			URL:              v.GetString(KeyLedgerURL),
+			KeyPrefix:        v.GetString(KeyLedgerKeyPrefix),
+			Retention:        v.GetDuration(KeyLedgerRetention),
+			MaxRecords:       v.GetInt(KeyLedgerMaxRecords),
+			PruneInterval:    v.GetDuration(KeyLedgerPruneInterval),
+			SnapshotDir:      v.GetString(KeyLedgerSnapshotDir),
+			SnapshotInterval: v.GetDuration(KeyLedgerSnapshotInterval),
+			SnapshotRetain:   v.GetInt(KeyLedgerSnapshotRetain),
+		},
+		Standby: Standby{
+			PrimaryURL:   v.GetString(KeyStandbyPrimaryURL),
+			PrimaryToken: v.GetString(KeyStandbyPrimaryToken),
+			SyncInterval: v.GetDuration(KeyStandbySyncInterval),
+		},
+		Issuance: Issuance{
+			RetryCacheTTL:        v.GetDuration(KeyRetryCacheTTL),
+			Quota:                v.GetInt64(KeyIssuanceQuota),
+			QuotaWindow:          v.GetDuration(KeyQuotaWindow),
+			ReissueCooldown:      v.GetDuration(KeyReissueCooldown),
+			ProofOfPossessionTTL: v.GetDuration(KeyProofOfPossessionTTL),
+			SerialBits:           v.GetInt(KeySerialBits),
+			SerialPrefix:         v.GetString(KeySerialPrefix),
+			CAExpiry:             v.GetString(KeyCAExpiry),
+			MaxTTL:               v.GetDuration(KeyMaxTTL),
+			FingerprintTrailers:  v.GetBool(KeyFingerprintTrailers),
+			NodeUUID:             v.GetString(KeyNodeUUID),
+			NodeUUIDExtensionOID: v.GetString(KeyNodeUUIDExtensionOID),
+			Extensions:           SplitList(v.GetString(KeyExtensions)),
+
+			CertificatePolicies:       SplitList(v.GetString(KeyCertPolicies)),
+			CertificatePoliciesCPSURI: v.GetString(KeyCertPoliciesCPSURI),
+
+			ExtraSANs:     SplitList(v.GetString(KeyExtraSANs)),
+			ExtraSANsPath: v.GetString(KeyExtraSANsPath),
+
+			SubordinateCA: v.GetBool(KeySubordinateCA),
+
+			SPIFFEIDTemplate: v.GetString(KeySPIFFEIDTemplate),
+
+			TPMAttestation:       v.GetString(KeyTPMAttestation),
+			TPMEndorsementRoots:  v.GetString(KeyTPMEndorsementRootsPath),
+			TransparencyLogURL:   v.GetString(KeyTransparencyLogURL),
+			TransparencyTimeout:  v.GetDuration(KeyTransparencyLogTimeout),
+			TransparencyRequired: v.GetBool(KeyTransparencyLogRequired),
+		},
+		Approval: Approval{
+			Organizations: SplitList(v.GetString(KeyApprovalOrganizations)),
+			Threshold:     v.GetInt(KeyApprovalThreshold),
+			TTL:           v.GetDuration(KeyApprovalTTL),
+			ApproversPath: v.GetString(KeyApproversPath),
+		},
+		Policy: Policy{
+			KeyAlgorithms: SplitList(v.GetString(KeyPolicyKeyAlgorithms)),
+			MinRSABits:    v.GetInt(KeyPolicyMinRSABits),
+			ExtKeyUsages:  SplitList(v.GetString(KeyPolicyExtKeyUsages)),
+			DNSNames:      SplitList(v.GetString(KeyPolicyDNSNames)),
+			DNSRegexps:    SplitList(v.GetString(KeyPolicyDNSRegexps)),
+			IPRanges:      SplitList(v.GetString(KeyPolicyIPRanges)),
+			CommonName:    v.GetString(KeyPolicyCommonName),
+			Wildcards:     v.GetString(KeyPolicyWildcards),
+			StripLocalIPs: v.GetBool(KeyPolicyStripLocalIPs),
+
+			EmailAction:    v.GetString(KeyPolicyEmailAction),
+			EmailAddresses: SplitList(v.GetString(KeyPolicyEmailAddresses)),
+			URIAction:      v.GetString(KeyPolicyURIAction),
+			URIs:           SplitList(v.GetString(KeyPolicyURIs)),
+
+			MaxDNSNames:    v.GetInt(KeyPolicyMaxDNSNames),
+			MaxIPAddresses: v.GetInt(KeyPolicyMaxIPAddresses),
+			MaxExtensions:  v.GetInt(KeyPolicyMaxExtensions),
+
+			Organizations:      SplitList(v.GetString(KeyPolicyOrganizations)),
+			OrganizationAction: v.GetString(KeyPolicyOrganizationAction),
+			CEL:                v.GetString(KeyPolicyCEL),
+			OPAURL:             v.GetString(KeyPolicyOPAURL),
+			OPATimeout:         v.GetDuration(KeyPolicyOPATimeout),
+			OPABundle:          v.GetString(KeyPolicyOPABundle),
+			OPABundleInterval:  v.GetDuration(KeyPolicyOPABundleInterval),
+			OPABundleKeyPath:   v.GetString(KeyPolicyOPABundlePublicKey),
+			OPABundleUsername:  v.GetString(KeyPolicyOPABundleUsername),
+			OPABundlePassword:  v.GetString(KeyPolicyOPABundlePassword),
+			Simulate:           SplitList(v.GetString(KeyPolicySimulate)),
+
+			SubjectOrganizations:      SplitList(v.GetString(KeyPolicySubjectOrgs)),
+			SubjectDrop:               SplitList(v.GetString(KeyPolicySubjectDrop)),
+			SubjectCommonNameTemplate: v.GetString(KeyPolicySubjectCNTemplate),
+
+			FilePath:           v.GetString(KeyPolicyFilePath),
+			FileReloadInterval: v.GetDuration(KeyPolicyFileReloadInterval),
+
+			DenyListPath:           v.GetString(KeyDenyListPath),
+			DenyListReloadInterval: v.GetDuration(KeyDenyListReloadInterval),
+
+			DNSVerification:         v.GetBool(KeyPolicyDNSVerification),
+			DNSVerificationRanges:   SplitList(v.GetString(KeyPolicyDNSVerifyRanges)),
+			DNSVerificationBypass:   SplitList(v.GetString(KeyPolicyDNSVerifyBypass)),
+			DNSVerificationCacheTTL: v.GetDuration(KeyPolicyDNSVerifyCacheTTL),
+
+			EnrollmentWindows:  v.GetString(KeyEnrollmentWindows),
+			EnrollmentTimezone: v.GetString(KeyEnrollmentTimezone),
+		},
+		Roles: Roles{
+			ControlPlaneOrganizations: SplitList(v.GetString(KeyRoleControlPlaneOrgs)),
+			ControlPlaneCommonName:    v.GetString(KeyRoleControlPlaneCN),
+			ControlPlaneValidity:      v.GetDuration(KeyControlPlaneValidity),
+			ControlPlaneUsages:        SplitList(v.GetString(KeyControlPlaneUsages)),
+			ControlPlaneKeyAlgorithms: SplitList(v.GetString(KeyControlPlaneKeyAlgorithms)),
+			WorkerValidity:            v.GetDuration(KeyWorkerValidity),
+			WorkerUsages:              SplitList(v.GetString(KeyWorkerUsages)),
+			WorkerKeyAlgorithms:       SplitList(v.GetString(KeyWorkerKeyAlgorithms)),
+			ProfilesPath:              v.GetString(KeyProfilesPath),
+		},
+		Clock: Clock{
+			SkewAction:    v.GetString(KeyClockSkewAction),
+			MaxSkew:       v.GetDuration(KeyClockMaxSkew),
+			CheckInterval: v.GetDuration(KeyClockCheckInterval),
+			NTPServer:     v.GetString(KeyNTPServer),
+		},
+		Watchdog: Watchdog{
+			Threshold: v.GetInt(KeyWatchdogThreshold),
+			Exit:      v.GetBool(KeyWatchdogExit),
+		},
+		Events: Events{
+			SinkURL:       v.GetString(KeyEventSinkURL),
+			Topic:         v.GetString(KeyEventTopic),
+			BufferSize:    v.GetInt(KeyEventBufferSize),
+			TLS:           v.GetBool(KeyEventTLS),
+			TLSCAPath:     v.GetString(KeyEventTLSCAPath),
+			SASLMechanism: v.GetString(KeyEventSASLMechanism),
+			SASLUsername:  v.GetString(KeyEventSASLUsername),
+			SASLPassword:  v.GetString(KeyEventSASLPassword),
+		},
+		CRL: CRL{
+			Validity: v.GetDuration(KeyCRLValidity),
+			Interval: v.GetDuration(KeyCRLInterval),
+		},
+		Admin: Admin{
+			Address:  v.GetString(KeyAdminAddress),
+			Token:    v.GetString(KeyAdminToken),
+			Channelz: v.GetBool(KeyAdminChannelz),
+		},
+		Log: Log{
+			File:       v.GetString(KeyLogFile),
+			MaxSize:    v.GetInt(KeyLogMaxSize),
+			MaxAge:     v.GetInt(KeyLogMaxAge),
+			MaxBackups: v.GetInt(KeyLogMaxBackups),
+			Compress:   v.GetBool(KeyLogCompress),
+		},
+	}
+}
+
+// Validate returns an error when the configuration cannot be used to serve the nodes.
+func (c *Config) Validate() error {
+	switch {
+	case c.Server.Port <= 0:
+		return pkgerrors.ErrMissingPort
+	case c.Server.Port > 65535:
+		return pkgerrors.ErrPortOutOfRange
+	// The token may be validated by an authenticator plugin, checked once the plugins are loaded, or held by the bundle
+	// or by the secret of the CA source
+	case c.Tokens.Token == "" && c.Tokens.Path == "" && c.Tokens.ClassesPath == "" && len(c.Server.Plugins) == 0 &&
+		c.Bundle.Path == "" && c.CA.Source == "":
+		return pkgerrors.ErrMissingToken
+	// The previous tokens are only accepted along with a current one
+	case len(c.Tokens.Previous) > 0 && c.Tokens.Token == "" && c.Tokens.Path == "" && c.Bundle.Path == "" && c.CA.Source == "":
+		return errors.Wrap(pkgerrors.ErrToken, "previous tokens require TALOS_TOKEN or TALOS_TOKEN_PATH")
+	case c.CA.CertificatePath == "":
+		return errors.Wrap(pkgerrors.ErrMissingPath, "CA certificate path is missing")
+	case c.CA.PrivateKeyPath == "":
+		return errors.Wrap(pkgerrors.ErrMissingPath, "CA private key path is missing")
+	case c.Server.TLSCertificatePath == "":
+		return errors.Wrap(pkgerrors.ErrMissingPath, "server certificate path is missing")
+	case c.Server.TLSPrivateKeyPath == "":
+		return errors.Wrap(pkgerrors.ErrMissingPath, "server private key path is missing")
+	case c.Events.BufferSize < 0, c.CA.QueueSize < 0, c.Issuance.Quota < 0, c.Issuance.ReissueCooldown < 0,
+		c.Issuance.ProofOfPossessionTTL < 0:
+		return errors.Wrap(pkgerrors.ErrConfig, "buffer, queue, quota, cooldown, and nonce lifetime cannot be negative")
+	case c.Policy.MaxDNSNames < 0, c.Policy.MaxIPAddresses < 0, c.Policy.MaxExtensions < 0:
+		return errors.Wrap(pkgerrors.ErrConfig, "the maximum numbers of DNS names, IP addresses, and extensions cannot be negative")
+	case c.Bundle.Path != "" && c.Bundle.ReloadInterval <= 0:
+		return errors.Wrap(pkgerrors.ErrConfig, "bundle reload interval must be positive")
+	case c.Policy.FilePath != "" && c.Policy.FileReloadInterval <= 0:
+		return errors.Wrap(pkgerrors.ErrConfig, "policy file reload interval must be positive")
+	case c.Policy.FilePath != "" && c.Bundle.Path != "":
+		return errors.Wrap(pkgerrors.ErrConfig, "the policy file cannot be used along with the configuration bundle, holding the policy")
+	case c.Policy.DenyListPath != "" && c.Policy.DenyListReloadInterval <= 0:
+		return errors.Wrap(pkgerrors.ErrConfig, "deny-list reload interval must be positive")
+	case (c.CA.CertificateB64 == "") != (c.CA.PrivateKeyB64 == ""):
+		return errors.Wrap(pkgerrors.ErrConfig, "the base64 encoded CA certificate and private key must be set together")
+	case c.CA.CertURL != "" && !strings.HasPrefix(c.CA.CertURL, "https://"):
+		return errors.Wrap(pkgerrors.ErrConfig, "the CA certificates URL must be an https:// one")
+	case c.CA.CertURL != "" && len(c.CA.CertURLSHA256) == 0:
+		return errors.Wrap(pkgerrors.ErrConfig, "the SHA-256 checksum of the CA certificates URL is missing")
+	case c.CA.CertURL != "" && c.CA.BundlePath != "":
+		return errors.Wrap(pkgerrors.ErrConfig, "the CA bundle cannot be read from both the URL and the file")
+	case c.CA.CertURLRefreshInterval < 0:
+		return errors.Wrap(pkgerrors.ErrConfig, "CA certificates URL refresh interval cannot be negative")
+	case c.CA.DirRefreshInterval < 0:
+		return errors.Wrap(pkgerrors.ErrConfig, "CA directory refresh interval cannot be negative")
+	case c.CA.DirSharding && c.CA.Dir == "":
+		return errors.Wrap(pkgerrors.ErrConfig, "CA directory sharding requires the CA directory")
+	case c.CA.DirSharding && !strings.HasPrefix(c.Ledger.URL, "redis://") && !strings.HasPrefix(c.Ledger.URL, "rediss://"):
+		return errors.Wrap(pkgerrors.ErrConfig, "CA directory sharding requires a redis:// or rediss:// ledger shared by the replicas")
+	case c.CA.DirSharding && c.CA.DirShardHeartbeat <= 0:
+		return errors.Wrap(pkgerrors.ErrConfig, "CA directory shard heartbeat interval must be positive")
+	case c.CA.ExpiryWarning < 0:
+		return errors.Wrap(pkgerrors.ErrConfig, "CA expiry warning cannot be negative")
+	case c.Issuance.MaxTTL < 0:
+		return errors.Wrap(pkgerrors.ErrConfig, "maximum TTL cannot be negative")
+	case c.Issuance.CAExpiry != signer.CAExpiryTruncate && c.Issuance.CAExpiry != signer.CAExpiryReject:
+		return errors.Wrap(pkgerrors.ErrConfig, "unsupported CA expiry action "+c.Issuance.CAExpiry+", expected truncate or reject")
+	case c.Policy.OPAURL != "" && c.Policy.OPATimeout <= 0:
+		return errors.Wrap(pkgerrors.ErrConfig, "the Open Policy Agent timeout must be positive")
+	case c.Policy.OPABundle != "" && !strings.Contains(c.Policy.OPAURL, "/v1/data"):
+		return errors.Wrap(pkgerrors.ErrConfig, "the Open Policy Agent bundle requires the URL of a Data API decision")
+	case c.Policy.OPABundle != "" && c.Policy.OPABundleKeyPath == "":
+		return errors.Wrap(pkgerrors.ErrConfig, "the Open Policy Agent bundle requires the public key verifying its signature")
+	case c.Policy.OPABundleInterval < 0:
+		return errors.Wrap(pkgerrors.ErrConfig, "the Open Policy Agent bundle interval cannot be negative")
+	case c.Policy.OrganizationAction != policy.OrganizationsReject && c.Policy.OrganizationAction != policy.OrganizationsStrip:
+		return errors.Wrap(pkgerrors.ErrConfig, "unsupported organization action "+c.Policy.OrganizationAction+", expected reject or strip")
+	case !slices.Contains([]string{policy.WildcardsAllow, policy.WildcardsReject, policy.WildcardsStrip}, c.Policy.Wildcards):
+		return errors.Wrap(pkgerrors.ErrConfig, "unsupported wildcard DNS names action "+c.Policy.Wildcards+", expected allow, reject, or strip")
+	case !slices.Contains([]string{policy.SANsDrop, policy.SANsPass, policy.SANsReject}, c.Policy.EmailAction):
+		return errors.Wrap(pkgerrors.ErrConfig, "unsupported email address action "+c.Policy.EmailAction+", expected drop, pass, or reject")
+	case !slices.Contains([]string{policy.SANsDrop, policy.SANsPass, policy.SANsReject}, c.Policy.URIAction):
+		return errors.Wrap(pkgerrors.ErrConfig, "unsupported URI action "+c.Policy.URIAction+", expected drop, pass, or reject")
+	case c.CA.SourceRefreshInterval < 0:
+		return errors.Wrap(pkgerrors.ErrConfig, "CA source refresh interval cannot be negative")
+	case c.Upstream.Endpoint != "" && c.Upstream.Token == "":
+		return errors.Wrap(pkgerrors.ErrConfig, "the upstream signer token is missing")
+	case (c.Upstream.TLSCertificatePath == "") != (c.Upstream.TLSPrivateKeyPath == ""):
+		return errors.Wrap(pkgerrors.ErrConfig, "the upstream client certificate and private key must be set together")
+	case c.Roles.ControlPlaneValidity <= 0, c.Roles.WorkerValidity <= 0:
+		return errors.Wrap(pkgerrors.ErrConfig, "control-plane and worker validity must be positive")
+	}
+
+	return nil
+}
+
+// SplitList returns the non-empty values of the comma separated list.
+func SplitList(value string) []string {
+	var values []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+
+	return values
+}
+
+// KeyClusters is the configuration file key of the clusters served by the process, each one on its own listener.
+const KeyClusters = "clusters"
+
+// processSettings are the settings shared by all the clusters of the process, which cannot be overridden per cluster.
+var processSettings = []string{
+	KeyConfigFile, KeyPlugins, KeyFeatureGates, KeyAdminAddress, KeyAdminToken, KeyAdminChannelz, KeyLogFile, KeyLogMaxSize, KeyLogMaxAge,
+	KeyLogMaxBackups, KeyLogCompress, KeyEventSinkURL, KeyEventTopic, KeyEventBufferSize, KeyEventTLS, KeyEventTLSCAPath,
+	KeyEventSASLMechanism, KeyEventSASLUsername, KeyEventSASLPassword, KeyWatchdogThreshold, KeyWatchdogExit,
+}
+
+// Cluster is a cluster served by the process along with the top level one, on its own listener.
+type Cluster struct {
+	Name string
+	*Config
+	// Settings are the ones the configuration is read from, overridden by the configuration bundle.
+	Settings *viper.Viper
+}
+
+// Clusters returns the validated configuration of the clusters listed in the configuration file, each one a map of
+// its name and of the settings, keyed by the flag names, overriding the top level ones. The clusters don't share the
+// ledger keys, nor the journal, unless configured.
+func Clusters(v *viper.Viper) ([]Cluster, error) {
+	var entries []map[string]any
+
+	if err := v.UnmarshalKey(KeyClusters, &entries); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrConfig, "invalid clusters: "+err.Error())
+	}
+
+	known := make(map[string]bool, len(options))
+	for _, opt := range options {
+		known[opt.key] = true
+	}
+
+	for _, key := range processSettings {
+		known[key] = false
+	}
+
+	clusters := make([]Cluster, 0, len(entries))
+	ports := map[int]string{v.GetInt(KeyPort): "the top level cluster"}
+	names := make(map[string]bool, len(entries))
+
+	for i, entry := range entries {
+		name, _ := entry["name"].(string)
+		if name == "" {
+			return nil, errors.Wrapf(pkgerrors.ErrConfig, "cluster %d has no name", i)
+		}
+
+		if names[name] {
+			return nil, errors.Wrapf(pkgerrors.ErrConfig, "cluster %s is listed twice", name)
+		}
+
+		names[name] = true
+
+		child := clone(v)
+
+		child.Set(KeyLedgerKeyPrefix, v.GetString(KeyLedgerKeyPrefix)+":"+name)
+		child.Set(KeyJournalDir, "")
+
+		for key, value := range entry {
+			if key == "name" {
+				continue
+			}
+
+			if !known[key] {
+				return nil, errors.Wrapf(pkgerrors.ErrConfig, "cluster %s: %s is not a cluster setting", name, key)
+			}
+
+			child.Set(key, value)
+		}
+
+		cfg := Read(child)
+		if err := cfg.Validate(); err != nil {
+			return nil, errors.Wrap(err, "cluster "+name)
+		}
+
+		if other, found := ports[cfg.Server.Port]; found {
+			return nil, errors.Wrapf(pkgerrors.ErrConfig, "cluster %s listens on the port %d of %s", name, cfg.Server.Port, other)
+		}
+
+		ports[cfg.Server.Port] = "cluster " + name
+		clusters = append(clusters, Cluster{Name: name, Config: cfg, Settings: child})
+	}
+
+	return clusters, nil
+}
+
+// Override returns the validated configuration of the viper instance with the given settings, keyed by the flag
+// names, overriding its ones, such as the ones of the configuration bundle. The viper instance is left untouched.
+func Override(v *viper.Viper, settings map[string]any) (*Config, error) {
+	known := make(map[string]bool, len(options))
+	for _, opt := range options {
+		known[opt.key] = true
+	}
+
+	overridden := clone(v)
+
+	for key, value := range settings {
+		if !known[key] {
+			return nil, errors.Wrap(pkgerrors.ErrConfig, "unknown setting "+key)
+		}
+
+		overridden.Set(key, value)
+	}
+
+	return Load(overridden)
+}
+
+// clone returns a viper instance holding the settings of the given one, but the clusters.
+func clone(v *viper.Viper) *viper.Viper {
+	cloned := viper.New()
+
+	for _, key := range v.AllKeys() {
+		if key != KeyClusters {
+			cloned.Set(key, v.Get(key))
+		}
+	}
+
+	return cloned
+}
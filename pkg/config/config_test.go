@@ -0,0 +1,110 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// newViper returns the viper instance with the options bound, as done by the root command.
+func newViper(t *testing.T) *viper.Viper {
+	t.Helper()
+
+	v := viper.New()
+	Bind(v, pflag.NewFlagSet("flags", pflag.ContinueOnError), pflag.NewFlagSet("persistent", pflag.ContinueOnError))
+
+	return v
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]any
+		expected error
+	}{
+		{name: "defaults"},
+		{name: "missing token", settings: map[string]any{KeyTalosToken: ""}, expected: pkgerrors.ErrMissingToken},
+		{name: "token file", settings: map[string]any{KeyTalosToken: "", KeyTalosTokenPath: "/etc/talos-token/token"}},
+		{name: "missing port", settings: map[string]any{KeyPort: 0}, expected: pkgerrors.ErrMissingPort},
+		{name: "port out of range", settings: map[string]any{KeyPort: 65536}, expected: pkgerrors.ErrPortOutOfRange},
+		{name: "missing CA certificate", settings: map[string]any{KeyCACertificatePath: ""}, expected: pkgerrors.ErrMissingPath},
+		{name: "missing server private key", settings: map[string]any{KeyTLSPrivateKeyPath: ""}, expected: pkgerrors.ErrMissingPath},
+		{name: "negative quota", settings: map[string]any{KeyIssuanceQuota: int64(-1)}, expected: pkgerrors.ErrConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newViper(t)
+			v.Set(KeyTalosToken, "abcdef.0123456789abcdef")
+
+			for key, value := range tt.settings {
+				v.Set(key, value)
+			}
+
+			cfg, err := Load(v)
+
+			if tt.expected != nil {
+				if !errors.Is(err, tt.expected) {
+					t.Fatalf("expected %v, got %v", tt.expected, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.Server.Port != 50001 || cfg.CA.CertificatePath != "/etc/talos-ca/tls.crt" {
+				t.Fatalf("unexpected defaults %+v", cfg.Server)
+			}
+		})
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("port: 50002\nissuance-quota: 5\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PORT", "50003")
+
+	v := newViper(t)
+	v.Set(KeyConfigFile, path)
+
+	if err := ReadFile(v); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Read(v)
+	if cfg.Server.Port != 50003 || cfg.Issuance.Quota != 5 {
+		t.Fatalf("expected the environment to override the file, got port %d and quota %d", cfg.Server.Port, cfg.Issuance.Quota)
+	}
+
+	v.Set(KeyConfigFile, filepath.Join(t.TempDir(), "missing.yaml"))
+
+	if err := ReadFile(v); !errors.Is(err, pkgerrors.ErrConfigFile) {
+		t.Fatalf("expected the missing file to be reported, got %v", err)
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	if values := SplitList(" ecdsa, ,ed25519 ,"); !slices.Equal(values, []string{"ecdsa", "ed25519"}) {
+		t.Fatalf("unexpected values %v", values)
+	}
+
+	if values := SplitList(""); values != nil {
+		t.Fatalf("expected no values, got %v", values)
+	}
+}
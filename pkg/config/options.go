@@ -0,0 +1,549 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/clock"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/features"
+	"github.com/clastix/talos-csr-signer/pkg/kms"
+	"github.com/clastix/talos-csr-signer/pkg/policy"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+	"github.com/clastix/talos-csr-signer/pkg/sops"
+	"github.com/clastix/talos-csr-signer/pkg/watchdog"
+)
+
+// KeyConfigFile is the key of the configuration file path.
+const KeyConfigFile = "config"
+
+// The configuration keys, named after their flags.
+const (
+	KeyPort                         = "port"
+	KeyCACertificatePath            = "ca-cert-path"
+	KeyCAPrivateKeyPath             = "ca-key-path"
+	KeyCACertificateB64             = "ca-cert-b64"
+	KeyCAPrivateKeyB64              = "ca-key-b64"
+	KeyCAKeyPassphrase              = "ca-key-passphrase"
+	KeyCAKeyPassphrasePath          = "ca-key-passphrase-path"
+	KeyCAKeyPassphraseKMSPath       = "ca-key-passphrase-kms-blob-path"
+	KeyCABundlePath                 = "ca-bundle-path"
+	KeyCASecretRef                  = "ca-secret-ref"
+	KeyCAChainPath                  = "ca-chain-path"
+	KeyCAExtraRootsPath             = "ca-extra-roots-path"
+	KeyCACertURL                    = "ca-cert-url"
+	KeyCACertURLSHA256              = "ca-cert-url-sha256"
+	KeyCACertURLRefreshInterval     = "ca-cert-url-refresh-interval"
+	KeyCAPKCS12Path                 = "ca-pkcs12-path"
+	KeyCAPKCS12Password             = "ca-pkcs12-password"
+	KeyCASource                     = "ca-source"
+	KeyCASourceRefreshInterval      = "ca-source-refresh-interval"
+	KeyCADir                        = "ca-dir"
+	KeyCADirRefreshInterval         = "ca-dir-refresh-interval"
+	KeyCADirSharding                = "ca-dir-sharding"
+	KeyCADirShardID                 = "ca-dir-shard-id"
+	KeyCADirShardHeartbeat          = "ca-dir-shard-heartbeat"
+	KeyCAHybridKeyPath              = "ca-hybrid-key-path"
+	KeyCAExpiryWarning              = "ca-expiry-warning"
+	KeyTLSCertificatePath           = "tls-cert-path"
+	KeyTLSPrivateKeyPath            = "tls-key-path"
+	KeyVaultAddress                 = "vault-addr"
+	KeyVaultMount                   = "vault-pki-mount"
+	KeyVaultRole                    = "vault-pki-role"
+	KeyVaultToken                   = "vault-token"
+	KeyVaultTokenPath               = "vault-token-path"
+	KeyVaultNamespace               = "vault-namespace"
+	KeyVaultCACertificatePath       = "vault-ca-cert-path"
+	KeyVaultTimeout                 = "vault-timeout"
+	KeyKMSKeyARN                    = "kms-key-arn"
+	KeyKMSRegion                    = "kms-region"
+	KeyKMSEndpoint                  = "kms-endpoint"
+	KeyKMSCredentialsPath           = "kms-credentials-path"
+	KeyKMSProfile                   = "kms-profile"
+	KeyKMSTimeout                   = "kms-timeout"
+	KeySOPSAgeKey                   = "sops-age-key"
+	KeySOPSAgeKeyPath               = "sops-age-key-path"
+	KeyUpstreamEndpoint             = "upstream-endpoint"
+	KeyUpstreamToken                = "upstream-token"
+	KeyUpstreamTLSCertPath          = "upstream-tls-cert-path"
+	KeyUpstreamTLSKeyPath           = "upstream-tls-key-path"
+	KeyUpstreamCAPath               = "upstream-ca-path"
+	KeyUpstreamServerName           = "upstream-server-name"
+	KeyTalosToken                   = "talos-token"
+	KeyTalosTokenPath               = "talos-token-path"
+	KeyTalosPreviousTokens          = "talos-previous-tokens"
+	KeyTokenClassesPath             = "token-classes-path"
+	KeyInstanceIdentity             = "instance-identity"
+	KeyInstanceIdentityAccounts     = "instance-identity-accounts"
+	KeyInstanceIdentityAWSCerts     = "instance-identity-aws-certs-path"
+	KeyInstanceIdentityAudience     = "instance-identity-audience"
+	KeyInstanceIdentityGCPKeys      = "instance-identity-gcp-keys-url"
+	KeyInstanceIdentityAzureKeys    = "instance-identity-azure-keys-url"
+	KeyInstanceIdentityAWSMaxAge    = "instance-identity-aws-max-age"
+	KeyInstanceIdentityAzureTenants = "instance-identity-azure-tenants"
+	KeyBundlePath                   = "bundle-path"
+	KeyBundleReloadInterval         = "bundle-reload-interval"
+	KeyPolicyFilePath               = "policy-file-path"
+	KeyPolicyFileReloadInterval     = "policy-file-reload-interval"
+	KeyDenyListPath                 = "denylist-path"
+	KeyDenyListReloadInterval       = "denylist-reload-interval"
+	KeyLedgerURL                    = "ledger-url"
+	KeyLedgerKeyPrefix              = "ledger-key-prefix"
+	KeyRetryCacheTTL                = "retry-cache-ttl"
+	KeyIssuanceQuota                = "issuance-quota"
+	KeyQuotaWindow                  = "quota-window"
+	KeyReissueCooldown              = "reissue-cooldown"
+	KeyProofOfPossessionTTL         = "proof-of-possession-ttl"
+	KeyApprovalOrganizations        = "approval-organizations"
+	KeyApprovalThreshold            = "approval-threshold"
+	KeyApprovalTTL                  = "approval-ttl"
+	KeyApproversPath                = "approvers-path"
+	KeySerialBits                   = "serial-bits"
+	KeySerialPrefix                 = "serial-prefix"
+	KeyCAExpiry                     = "ca-expiry"
+	KeyMaxTTL                       = "max-ttl"
+	KeyFingerprintTrailers          = "fingerprint-trailers"
+	KeyNodeUUID                     = "node-uuid"
+	KeyNodeUUIDExtensionOID         = "node-uuid-extension-oid"
+	KeyExtensions                   = "extensions"
+	KeyCertPolicies                 = "certificate-policies"
+	KeyCertPoliciesCPSURI           = "certificate-policies-cps-uri"
+	KeyExtraSANs                    = "extra-sans"
+	KeyExtraSANsPath                = "extra-sans-path"
+	KeySubordinateCA                = "subordinate-ca"
+	KeySPIFFEIDTemplate             = "spiffe-id-template"
+	KeyTransparencyLogURL           = "transparency-log-url"
+	KeyTransparencyLogTimeout       = "transparency-log-timeout"
+	KeyTransparencyLogRequired      = "transparency-log-required"
+	KeyTPMAttestation               = "tpm-attestation"
+	KeyTPMEndorsementRootsPath      = "tpm-endorsement-roots-path"
+	KeyLedgerSnapshotDir            = "ledger-snapshot-dir"
+	KeyStandbyPrimaryURL            = "standby-primary-url"
+	KeyStandbyPrimaryToken          = "standby-primary-token"
+	KeyStandbySyncInterval          = "standby-sync-interval"
+	KeyLedgerSnapshotInterval       = "ledger-snapshot-interval"
+	KeyLedgerSnapshotRetain         = "ledger-snapshot-retain"
+	KeyLedgerRetention              = "ledger-retention"
+	KeyLedgerMaxRecords             = "ledger-max-records"
+	KeyLedgerPruneInterval          = "ledger-prune-interval"
+	KeyFallbackCACertificatePath    = "fallback-ca-cert-path"
+	KeyFallbackCAPrivateKeyPath     = "fallback-ca-key-path"
+	KeyCircuitFailureThreshold      = "circuit-failure-threshold"
+	KeyCircuitCooldown              = "circuit-cooldown"
+	KeyQueueSize                    = "queue-size"
+	KeyQueueRetryInterval           = "queue-retry-interval"
+	KeyQueueMaxWait                 = "queue-max-wait"
+	KeyStartupWaitTimeout           = "startup-wait-timeout"
+	KeyWatchdogThreshold            = "watchdog-failure-threshold"
+	KeyWatchdogExit                 = "watchdog-exit"
+	KeyJournalDir                   = "journal-dir"
+	KeyMaxConnectionAge             = "max-connection-age"
+	KeyMaxConnectionAgeGrace        = "max-connection-age-grace"
+	KeyLogFile                      = "log-file"
+	KeyLogMaxSize                   = "log-max-size"
+	KeyLogMaxAge                    = "log-max-age"
+	KeyLogMaxBackups                = "log-max-backups"
+	KeyLogCompress                  = "log-compress"
+	KeyClientCAPath                 = "client-ca-path"
+	KeyAdminAddress                 = "admin-address"
+	KeyAdminToken                   = "admin-token"
+	KeyAdminChannelz                = "admin-channelz"
+	KeyFeatureGates                 = "feature-gates"
+	KeyClockSkewAction              = "clock-skew-action"
+	KeyClockMaxSkew                 = "clock-max-skew"
+	KeyClockCheckInterval           = "clock-check-interval"
+	KeyNTPServer                    = "ntp-server"
+	KeyStrictStartup                = "strict-startup"
+	KeyEventSinkURL                 = "event-sink-url"
+	KeyEventTopic                   = "event-topic"
+	KeyEventBufferSize              = "event-buffer-size"
+	KeyEventTLS                     = "event-tls"
+	KeyEventTLSCAPath               = "event-tls-ca-path"
+	KeyEventSASLMechanism           = "event-sasl-mechanism"
+	KeyEventSASLUsername            = "event-sasl-username"
+	KeyEventSASLPassword            = "event-sasl-password"
+	KeyCRLValidity                  = "crl-validity"
+	KeyCRLInterval                  = "crl-interval"
+	KeyPolicyKeyAlgorithms          = "policy-key-algorithms"
+	KeyPolicyMinRSABits             = "policy-min-rsa-bits"
+	KeyPolicyExtKeyUsages           = "policy-extended-key-usages"
+	KeyPolicyDNSNames               = "policy-dns-names"
+	KeyPolicyDNSRegexps             = "policy-dns-regexps"
+	KeyPolicyIPRanges               = "policy-ip-ranges"
+	KeyPolicyWildcards              = "policy-wildcard-dns-names"
+	KeyPolicyStripLocalIPs          = "policy-strip-local-ips"
+	KeyPolicyEmailAction            = "policy-email-action"
+	KeyPolicyEmailAddresses         = "policy-email-addresses"
+	KeyPolicyURIAction              = "policy-uri-action"
+	KeyPolicyURIs                   = "policy-uris"
+	KeyPolicyMaxDNSNames            = "policy-max-dns-names"
+	KeyPolicyMaxIPAddresses         = "policy-max-ip-addresses"
+	KeyPolicyMaxExtensions          = "policy-max-extensions"
+	KeyPolicyCommonName             = "policy-common-name"
+	KeyPolicyOrganizations          = "policy-organizations"
+	KeyPolicyOrganizationAction     = "policy-organization-action"
+	KeyPolicySubjectOrgs            = "policy-subject-organizations"
+	KeyPolicySubjectDrop            = "policy-subject-drop"
+	KeyPolicySubjectCNTemplate      = "policy-subject-common-name-template"
+	KeyPolicyCEL                    = "policy-cel"
+	KeyPolicyOPAURL                 = "policy-opa-url"
+	KeyPolicyOPATimeout             = "policy-opa-timeout"
+	KeyPolicyOPABundle              = "policy-opa-bundle"
+	KeyPolicyOPABundleInterval      = "policy-opa-bundle-interval"
+	KeyPolicyOPABundlePublicKey     = "policy-opa-bundle-public-key-path"
+	KeyPolicyOPABundleUsername      = "policy-opa-bundle-username"
+	KeyPolicyOPABundlePassword      = "policy-opa-bundle-password"
+	KeyPolicySimulate               = "policy-simulate"
+	KeyPolicyDNSVerification        = "policy-dns-verification"
+	KeyPolicyDNSVerifyRanges        = "policy-dns-verification-ranges"
+	KeyPolicyDNSVerifyBypass        = "policy-dns-verification-bypass"
+	KeyPolicyDNSVerifyCacheTTL      = "policy-dns-verification-cache-ttl"
+	KeyEnrollmentWindows            = "enrollment-windows"
+	KeyEnrollmentTimezone           = "enrollment-timezone"
+	KeyRoleControlPlaneOrgs         = "role-controlplane-organizations"
+	KeyRoleControlPlaneCN           = "role-controlplane-common-name"
+	KeyControlPlaneValidity         = "controlplane-validity"
+	KeyControlPlaneUsages           = "controlplane-usages"
+	KeyWorkerValidity               = "worker-validity"
+	KeyWorkerUsages                 = "worker-usages"
+	KeyControlPlaneKeyAlgorithms    = "controlplane-key-algorithms"
+	KeyWorkerKeyAlgorithms          = "worker-key-algorithms"
+	KeyProfilesPath                 = "profiles-path"
+	KeyPlugins                      = "plugins"
+)
+
+// options are the settings of the signer, in the order of the flags help.
+var options = []option{
+	{key: KeyConfigFile, env: "CONFIG_FILE", value: "", usage: "Path to the YAML, JSON, or TOML configuration file, keyed by the flag names, overridden by the flags and the environment, possibly encrypted by sops or age", persistent: true},
+	{key: KeyPort, env: "PORT", value: 50001, usage: "Port to listen on"},
+	{key: KeyCACertificatePath, env: "CA_CERT_PATH", value: "/etc/talos-ca/tls.crt", usage: "Path to CA certificate", persistent: true},
+	{key: KeyCAPrivateKeyPath, env: "CA_KEY_PATH", value: "/etc/talos-ca/tls.key", usage: "Path to CA private key", persistent: true},
+	{key: KeyCACertificateB64, env: "CA_CERT_B64", value: "", usage: "Base64 encoded PEM CA certificate, in place of the CA files along with the CA private key one, for the environments without volumes, empty to disable it", persistent: true},
+	{key: KeyCAPrivateKeyB64, env: "CA_KEY_B64", value: "", usage: "Base64 encoded PEM CA private key, in place of the CA files along with the CA certificate one, empty to disable it", persistent: true},
+	{key: KeyCAKeyPassphrase, env: "CA_KEY_PASSPHRASE", value: "", usage: "Passphrase of the encrypted CA private keys", persistent: true},
+	{key: KeyCAKeyPassphrasePath, env: "CA_KEY_PASSPHRASE_PATH", value: "", usage: "Path to the passphrase of the encrypted CA private keys", persistent: true},
+	{key: KeyCAKeyPassphraseKMSPath, env: "CA_KEY_PASSPHRASE_KMS_BLOB_PATH", value: "", usage: "Path to the passphrase of the encrypted CA private keys, encrypted by AWS KMS and decrypted with the KMS settings", persistent: true},
+	{key: KeyCABundlePath, env: "CA_BUNDLE_PATH", value: "", usage: "Path to the additional CA certificates returned to the nodes, trusting both the current and the next CA during a rotation", persistent: true},
+	{key: KeyCACertURL, env: "CA_CERT_URL", value: "", usage: "HTTPS URL of the additional CA certificates returned to the nodes, in place of the CA bundle file, such as on an internal artifact server, empty to disable it", persistent: true},
+	{key: KeyCACertURLSHA256, env: "CA_CERT_URL_SHA256", value: "", usage: "Hex encoded SHA-256 checksums the CA certificates of the URL must match, comma separated to accept the next ones ahead of an update, required with the URL", persistent: true},
+	{key: KeyCACertURLRefreshInterval, env: "CA_CERT_URL_REFRESH_INTERVAL", value: time.Hour, usage: "Interval the CA certificates of the URL are fetched again, replacing them when updated to ones matching a checksum, 0 to disable it", persistent: true},
+	{key: KeyCAExtraRootsPath, env: "CA_EXTRA_ROOTS_PATH", value: "", usage: "Comma separated paths to the additional root certificates returned to the nodes after the CA bundle, such as the previous CA or the OS-level extra roots, empty to disable it", persistent: true},
+	{key: KeyCAChainPath, env: "CA_CHAIN_PATH", value: "", usage: "Path to the chain of the intermediate signing CA up to the root, returned to the nodes after the signing CA, empty when the signing CA is the root", persistent: true},
+	{key: KeyCAPKCS12Path, env: "CA_PKCS12_PATH", value: "", usage: "Path to the PKCS#12 bundle (.p12 or .pfx) holding the CA certificate, its private key, and optionally its chain, in place of the CA files, empty to disable it", persistent: true},
+	{key: KeyCAPKCS12Password, env: "CA_PKCS12_PASSWORD", value: "", usage: "Password of the PKCS#12 bundle, empty to use the CA key passphrase settings, if any", persistent: true},
+	{key: KeyCASource, env: "CA_SOURCE", value: "", usage: "Secret of AWS Secrets Manager (awssm://<name or ARN>[?region=<region>]) or GCP Secret Manager (gcpsm://projects/<project>/secrets/<secret>[/versions/<version>]) holding the CA and optionally the Talos token, as a JSON object of its ca.crt, ca.key, and token keys, in place of the CA files, empty to disable it", persistent: true},
+	{key: KeyCASourceRefreshInterval, env: "CA_SOURCE_REFRESH_INTERVAL", value: 5 * time.Minute, usage: "Interval the secret of the CA source is read again, replacing the CA and the token when updated, 0 to disable it", persistent: true},
+	{key: KeyCADir, env: "CA_DIR", value: "", usage: "Directory of the CAs of the tenant clusters, one subdirectory per cluster ID holding its ca.crt and ca.key (or tls.crt and tls.key) files and optionally its token file, the requests being routed by their cluster ID metadata or TLS server name, requires the MultiTenantRouting feature gate, empty to disable it", persistent: true},
+	{key: KeyCADirRefreshInterval, env: "CA_DIR_REFRESH_INTERVAL", value: time.Minute, usage: "Interval the CA directory is read again, adding, replacing, and removing the tenant clusters, 0 to disable it", persistent: true},
+	{key: KeyCADirSharding, env: "CA_DIR_SHARDING", value: false, usage: "Shard the tenant clusters of the CA directory across the replicas sharing the ledger with consistent hashing, each one only loading the CAs of its tenants, requires a redis:// or rediss:// ledger", persistent: true},
+	{key: KeyCADirShardID, env: "CA_DIR_SHARD_ID", value: "", usage: "Identifier of the replica among the shards, such as the pod name, empty for the hostname", persistent: true},
+	{key: KeyCADirShardHeartbeat, env: "CA_DIR_SHARD_HEARTBEAT", value: 10 * time.Second, usage: "Interval of the shard heartbeats in the ledger, the replicas missing three of them being considered gone", persistent: true},
+	{key: KeyCAExpiryWarning, env: "CA_EXPIRY_WARNING", value: 30 * 24 * time.Hour, usage: "Remaining validity of the CA below which the startup checks warn, failing the startup with the strict one, 0 to disable it", persistent: true},
+	{key: KeyCAHybridKeyPath, env: "CA_HYBRID_KEY_PATH", value: "", usage: "Path to the PKCS#8 ML-DSA private key adding a post-quantum alternative signature to the certificates and to a hybrid version of the root CA returned to the nodes, requires the HybridSigning feature gate, empty to disable it", persistent: true},
+	{key: KeyCASecretRef, env: "CA_SECRET_REF", value: "", usage: "Kubernetes Secret holding the CA in its ca.crt and ca.key (or tls.crt and tls.key) keys, as namespace/name, watched for updates in place of the CA files, empty to disable it", persistent: true},
+	{key: KeyTLSCertificatePath, env: "TLS_CERT_PATH", value: "/etc/talos-server-crt/tls.crt", usage: "Path to the Server TLS certificate"},
+	{key: KeyTLSPrivateKeyPath, env: "TLS_KEY_PATH", value: "/etc/talos-server-crt/tls.key", usage: "Path to Server TLS private key"},
+	{key: KeyVaultAddress, env: "VAULT_ADDR", value: "", usage: "URL of the Vault server whose PKI secrets engine signs the certificates in place of the CA key, empty to disable it", persistent: true},
+	{key: KeyVaultMount, env: "VAULT_PKI_MOUNT", value: "pki", usage: "Path the Vault PKI secrets engine is mounted at", persistent: true},
+	{key: KeyVaultRole, env: "VAULT_PKI_ROLE", value: "", usage: "Vault PKI role constraining the sign-verbatim requests, empty for the defaults of the engine", persistent: true},
+	{key: KeyVaultToken, env: "VAULT_TOKEN", value: "", usage: "Vault token", persistent: true},
+	{key: KeyVaultTokenPath, env: "VAULT_TOKEN_PATH", value: "", usage: "Path to the Vault token, read at every request, such as the sink of a Vault agent", persistent: true},
+	{key: KeyVaultNamespace, env: "VAULT_NAMESPACE", value: "", usage: "Vault Enterprise namespace of the PKI secrets engine", persistent: true},
+	{key: KeyVaultCACertificatePath, env: "VAULT_CA_CERT_PATH", value: "", usage: "Path to the CA certificates verifying the Vault server, empty for the system ones", persistent: true},
+	{key: KeyVaultTimeout, env: "VAULT_TIMEOUT", value: 10 * time.Second, usage: "Timeout of the requests to Vault"},
+	{key: KeyKMSKeyARN, env: "KMS_KEY_ARN", value: "", usage: "ARN of the AWS KMS key signing the certificates in place of the CA key, along with the CA certificate, empty to disable it", persistent: true},
+	{key: KeyKMSRegion, env: "KMS_REGION", value: "", usage: "AWS region of the KMS key, empty for the one of the key ARN", persistent: true},
+	{key: KeyKMSEndpoint, env: "KMS_ENDPOINT", value: "", usage: "URL of the AWS KMS API, such as a VPC endpoint, empty for the regional one", persistent: true},
+	{key: KeyKMSCredentialsPath, env: "KMS_CREDENTIALS_PATH", value: "", usage: "Path to the AWS shared credentials file, empty for the AWS environment variables, the web identity, or the instance role", persistent: true},
+	{key: KeyKMSProfile, env: "KMS_PROFILE", value: "default", usage: "Profile of the AWS shared credentials file", persistent: true},
+	{key: KeyKMSTimeout, env: "KMS_TIMEOUT", value: 10 * time.Second, usage: "Timeout of the requests to AWS KMS"},
+	{key: KeySOPSAgeKey, env: "SOPS_AGE_KEY", value: "", usage: "age identities (AGE-SECRET-KEY-1...) decrypting the CA key, the TLS key, and the configuration file encrypted by sops or age, whose sops data keys may also be encrypted by AWS KMS", persistent: true},
+	{key: KeySOPSAgeKeyPath, env: "SOPS_AGE_KEY_FILE", value: "", usage: "Path to the age identities, such as the keys.txt file of age-keygen", persistent: true},
+	{key: KeyUpstreamEndpoint, env: "UPSTREAM_ENDPOINT", value: "", usage: "host:port of the upstream talos-csr-signer or trustd the CSRs are forwarded to once validated, in place of the CA key, empty to disable it", persistent: true},
+	{key: KeyUpstreamToken, env: "UPSTREAM_TOKEN", value: "", usage: "Talos token of the upstream signer", persistent: true},
+	{key: KeyUpstreamTLSCertPath, env: "UPSTREAM_TLS_CERT_PATH", value: "", usage: "Path to the client certificate presented to the upstream signer, empty to disable mutual TLS", persistent: true},
+	{key: KeyUpstreamTLSKeyPath, env: "UPSTREAM_TLS_KEY_PATH", value: "", usage: "Path to the private key of the client certificate presented to the upstream signer", persistent: true},
+	{key: KeyUpstreamCAPath, env: "UPSTREAM_CA_PATH", value: "", usage: "Path to the CA certificates verifying the upstream signer, empty for the CA certificate", persistent: true},
+	{key: KeyUpstreamServerName, env: "UPSTREAM_SERVER_NAME", value: "", usage: "Server name verified in the certificate of the upstream signer, empty for the endpoint host", persistent: true},
+	{key: KeyTalosToken, env: "TALOS_TOKEN", value: "", usage: "Talos token", persistent: true},
+	{key: KeyTalosTokenPath, env: "TALOS_TOKEN_PATH", value: "", usage: "Path to the Talos tokens, reloaded when modified: the current one, then the previous ones optionally followed by their expiration", persistent: true},
+	{key: KeyTalosPreviousTokens, env: "TALOS_PREVIOUS_TOKENS", value: "", usage: "Comma separated previous Talos tokens, accepted along with the current one until removed, letting the nodes be patched with the rotated token", persistent: true},
+	{key: KeyTokenClassesPath, env: "TOKEN_CLASSES_PATH", value: "", usage: "Path to the YAML token classes, such as the control-plane and the worker ones, each issuing the certificates with the profile of a machine role or a named one, empty to disable them", persistent: true},
+	{key: KeyInstanceIdentity, env: "INSTANCE_IDENTITY", value: "disabled", usage: "Cloud instance identity document authenticating the nodes along with the token: disabled, optional, or required", persistent: true},
+	{key: KeyInstanceIdentityAccounts, env: "INSTANCE_IDENTITY_ACCOUNTS", value: "", usage: "Comma separated list of the accounts the instances must belong to, as provider:account (e.g. aws:123456789012, gcp:my-project, azure:<subscription ID>)", persistent: true},
+	{key: KeyInstanceIdentityAWSCerts, env: "INSTANCE_IDENTITY_AWS_CERTS_PATH", value: "", usage: "Path to the PEM encoded AWS public certificates of the regions, verifying the instance identity documents", persistent: true},
+	{key: KeyInstanceIdentityAWSMaxAge, env: "INSTANCE_IDENTITY_AWS_MAX_AGE", value: 24 * time.Hour, usage: "Maximum time since the instance was launched (its pendingTime) the AWS instance identity documents are accepted for, 0 to accept them regardless", persistent: true},
+	{key: KeyInstanceIdentityAudience, env: "INSTANCE_IDENTITY_AUDIENCE", value: "", usage: "Audience the GCP and Azure identity tokens are requested for", persistent: true},
+	{key: KeyInstanceIdentityGCPKeys, env: "INSTANCE_IDENTITY_GCP_KEYS_URL", value: "https://www.googleapis.com/oauth2/v3/certs", usage: "URL of the keys signing the GCP identity tokens", persistent: true},
+	{key: KeyInstanceIdentityAzureKeys, env: "INSTANCE_IDENTITY_AZURE_KEYS_URL", value: "https://login.microsoftonline.com/common/discovery/v2.0/keys", usage: "URL of the keys signing the Azure managed identity tokens", persistent: true},
+	{key: KeyInstanceIdentityAzureTenants, env: "INSTANCE_IDENTITY_AZURE_TENANTS", value: "", usage: "Comma separated list of the IDs of the Microsoft Entra tenants issuing the Azure managed identity tokens, required by the Azure accounts", persistent: true},
+	{key: KeyBundlePath, env: "BUNDLE_PATH", value: "", usage: "Path to the YAML configuration bundle holding the CA, the tokens, the signing policy, and the profiles, overriding the other settings", persistent: true},
+	{key: KeyBundleReloadInterval, env: "BUNDLE_RELOAD_INTERVAL", value: 10 * time.Second, usage: "Interval the configuration bundle is checked for changes at, reloading it while serving"},
+	{key: KeyPolicyFilePath, env: "POLICY_FILE_PATH", value: "", usage: "Path to the YAML policy file holding the TTLs, the key, SAN, and subject rules, the admission expressions, and the quotas, overriding the other settings and reloaded when modified or on SIGHUP, empty to disable it", persistent: true},
+	{key: KeyPolicyFileReloadInterval, env: "POLICY_FILE_RELOAD_INTERVAL", value: 10 * time.Second, usage: "Interval the policy file is checked for changes at, reloading it while serving"},
+	{key: KeyDenyListPath, env: "DENYLIST_PATH", value: "", usage: "Path to the YAML deny-list of the Common Names, DNS names, IP addresses, public keys, and node UUIDs never issued a certificate, reloaded when modified, empty to disable it", persistent: true},
+	{key: KeyDenyListReloadInterval, env: "DENYLIST_RELOAD_INTERVAL", value: 10 * time.Second, usage: "Interval the deny-list is checked for changes at, reloading it while serving"},
+	{key: KeyLedgerURL, env: "LEDGER_URL", value: "memory://", usage: "Ledger backend URL: memory:// for a single replica, file:// for the embedded one, redis:// or rediss:// to share the state across replicas", persistent: true},
+	{key: KeyLedgerKeyPrefix, env: "LEDGER_KEY_PREFIX", value: "talos-csr-signer", usage: "Prefix of the keys stored in a shared ledger", persistent: true},
+	{key: KeyLedgerRetention, env: "LEDGER_RETENTION", value: time.Duration(0), usage: "Time the ledger records are retained after the certificate expiration, zero to retain them", persistent: true},
+	{key: KeyLedgerMaxRecords, env: "LEDGER_MAX_RECORDS", value: 0, usage: "Number of ledger records above which the oldest expired ones are pruned, zero for no limit", persistent: true},
+	{key: KeyLedgerPruneInterval, env: "LEDGER_PRUNE_INTERVAL", value: time.Hour, usage: "Interval the ledger retention is enforced at"},
+	{key: KeyLedgerSnapshotDir, env: "LEDGER_SNAPSHOT_DIR", value: "", usage: "Directory of the scheduled ledger snapshots"},
+	{key: KeyLedgerSnapshotInterval, env: "LEDGER_SNAPSHOT_INTERVAL", value: time.Duration(0), usage: "Interval of the scheduled ledger snapshots, zero to disable them"},
+	{key: KeyLedgerSnapshotRetain, env: "LEDGER_SNAPSHOT_RETAIN", value: 7, usage: "Number of scheduled ledger snapshots to retain"},
+	{key: KeyStandbyPrimaryURL, env: "STANDBY_PRIMARY_URL", value: "", usage: "Admin API URL of the primary signer replicated by this warm standby, empty to serve as a primary"},
+	{key: KeyStandbyPrimaryToken, env: "STANDBY_PRIMARY_TOKEN", value: "", usage: "Bearer token of the admin API of the primary signer"},
+	{key: KeyStandbySyncInterval, env: "STANDBY_SYNC_INTERVAL", value: 30 * time.Second, usage: "Interval the standby replicates the ledger and the configuration of the primary at"},
+	{key: KeyRetryCacheTTL, env: "RETRY_CACHE_TTL", value: time.Duration(0), usage: "Duration a signed certificate is served again for the same CSR, zero to disable"},
+	{key: KeyIssuanceQuota, env: "ISSUANCE_QUOTA", value: int64(0), usage: "Maximum certificates issued per Common Name in the quota window, zero to disable"},
+	{key: KeyQuotaWindow, env: "QUOTA_WINDOW", value: time.Hour, usage: "Time window the issuance quota is accounted on"},
+	{key: KeyReissueCooldown, env: "REISSUE_COOLDOWN", value: time.Duration(0), usage: "Duration a new certificate for the same Common Name and SANs is refused for, unless authenticated with the current certificate, zero to disable"},
+	{key: KeyProofOfPossessionTTL, env: "PROOF_OF_POSSESSION_TTL", value: time.Duration(0), usage: "Lifetime of the nonces the clients sign with the CSR private key before a certificate is released, zero to disable the challenge"},
+	{key: KeyApprovalOrganizations, env: "APPROVAL_ORGANIZATIONS", value: "", usage: "Comma-separated subject organizations of the privileged certificates held until approved, with the ApprovalQueue feature gate (e.g. os:admin)"},
+	{key: KeyApprovalThreshold, env: "APPROVAL_THRESHOLD", value: 2, usage: "Approvals of distinct approvers a privileged certificate requires"},
+	{key: KeyApprovalTTL, env: "APPROVAL_TTL", value: 24 * time.Hour, usage: "Time a privileged certificate request waits for its approvals before expiring"},
+	{key: KeyApproversPath, env: "APPROVERS_PATH", value: "", usage: "Path to the file of the approvers, one per line as their name followed by their token"},
+	{key: KeySerialBits, env: "SERIAL_BITS", value: 128, usage: "Size of the serial numbers of the issued certificates, from 64 to 160 bits, prefix included", persistent: true},
+	{key: KeySerialPrefix, env: "SERIAL_PREFIX", value: "", usage: "Hex encoded value of the high bits of the serial numbers, 4 bits per digit (e.g. a cluster identifier), empty to disable it", persistent: true},
+	{key: KeyCAExpiry, env: "CA_EXPIRY", value: signer.CAExpiryTruncate, usage: "Action taken when a certificate would outlive the CA: truncate its validity to the CA expiration, or reject it", persistent: true},
+	{key: KeyMaxTTL, env: "MAX_TTL", value: time.Duration(0), usage: "Longest validity the clients may request with the x-ttl metadata, shortening the one of the machine role, 0 to refuse the requested TTLs"},
+	{key: KeyNodeUUID, env: "NODE_UUID", value: "disabled", usage: "Node UUID sent by the clients in the x-node-uuid metadata, embedded into the issued certificates: disabled, optional, or required"},
+	{key: KeyNodeUUIDExtensionOID, env: "NODE_UUID_EXTENSION_OID", value: "", usage: "OID of the custom extension the node UUID is embedded in (e.g. 1.3.6.1.4.1.99999.1), empty for an urn:uuid URI SAN"},
+	{key: KeyExtensions, env: "EXTENSIONS", value: "", usage: "Comma separated list of the extensions added to every issued certificate, as [critical:]<oid>=<type>:<value> with the utf8, ia5, integer, or der type (e.g. 1.3.6.1.4.1.99999.2=utf8:asset-tag)", persistent: true},
+	{key: KeyCertPolicies, env: "CERTIFICATE_POLICIES", value: "", usage: "Comma separated list of the certificate policy OIDs embedded into every issued certificate (e.g. 1.3.6.1.4.1.99999.10.1), empty to disable them", persistent: true},
+	{key: KeyCertPoliciesCPSURI, env: "CERTIFICATE_POLICIES_CPS_URI", value: "", usage: "URI of the Certification Practice Statement qualifying the certificate policies (e.g. https://pki.example.com/cps)", persistent: true},
+	{key: KeyExtraSANs, env: "EXTRA_SANS", value: "", usage: "Comma separated list of the DNS names and IP addresses added to every issued certificate beyond the CSR ones (e.g. a stable virtual IP)", persistent: true},
+	{key: KeyExtraSANsPath, env: "EXTRA_SANS_PATH", value: "", usage: "Path to the YAML file of the DNS names and IP addresses added to the certificates of the nodes, keyed by their Common Name, empty to disable it", persistent: true},
+	{key: KeySubordinateCA, env: "SUBORDINATE_CA", value: false, usage: "Issue subordinate CA certificates, with a path length of 0, through the named profiles with the ca setting, to the CSRs of their token class requesting CA:TRUE", persistent: true},
+	{key: KeySPIFFEIDTemplate, env: "SPIFFE_ID_TEMPLATE", value: "", usage: "Go template of the SPIFFE ID URI SAN of the certificates of the machine roles and the named profiles without their own, making them X.509 SVIDs (e.g. spiffe://example.com/talos/{{.CommonName}}), empty to disable it", persistent: true},
+	{key: KeyTransparencyLogURL, env: "TRANSPARENCY_LOG_URL", value: "", usage: "URL of the Sigstore Rekor server the issued certificates are published to (e.g. https://rekor.example.com), empty to disable it"},
+	{key: KeyTransparencyLogTimeout, env: "TRANSPARENCY_LOG_TIMEOUT", value: 10 * time.Second, usage: "Timeout of the publication of a certificate to the transparency log"},
+	{key: KeyTransparencyLogRequired, env: "TRANSPARENCY_LOG_REQUIRED", value: false, usage: "Fail the issuance when the certificate cannot be published to the transparency log"},
+	{key: KeyTPMAttestation, env: "TPM_ATTESTATION", value: "disabled", usage: "TPM quote binding the CSR to the node hardware, signed by an attestation key endorsed by the --tpm-endorsement-roots-path certificates: disabled, optional, or required"},
+	{key: KeyTPMEndorsementRootsPath, env: "TPM_ENDORSEMENT_ROOTS_PATH", value: "", usage: "Path to the PEM encoded endorsement roots and intermediates the attestation key certificates chain to"},
+	{key: KeyFingerprintTrailers, env: "FINGERPRINT_TRAILERS", value: false, usage: "Answer the SHA-256 fingerprint and SPKI hash of the issued certificates in the response trailers"},
+	{key: KeyFallbackCACertificatePath, env: "FALLBACK_CA_CERT_PATH", value: "", usage: "Path to the fallback backend CA certificate, defaults to the primary CA certificate"},
+	{key: KeyFallbackCAPrivateKeyPath, env: "FALLBACK_CA_KEY_PATH", value: "", usage: "Path to the fallback backend CA private key, used when the primary backend is failing"},
+	{key: KeyCircuitFailureThreshold, env: "CIRCUIT_FAILURE_THRESHOLD", value: 3, usage: "Consecutive primary backend failures opening the circuit towards the fallback backend"},
+	{key: KeyCircuitCooldown, env: "CIRCUIT_COOLDOWN", value: 30 * time.Second, usage: "Duration the circuit stays open before probing the primary backend again"},
+	{key: KeyQueueSize, env: "QUEUE_SIZE", value: 0, usage: "Requests queued while the signing backend is unavailable, zero to fail them immediately"},
+	{key: KeyQueueRetryInterval, env: "QUEUE_RETRY_INTERVAL", value: 2 * time.Second, usage: "Interval queued requests are retried against the signing backend"},
+	{key: KeyQueueMaxWait, env: "QUEUE_MAX_WAIT", value: 30 * time.Second, usage: "Maximum time a request is queued, bounded by the request deadline"},
+	{key: KeyWatchdogThreshold, env: "WATCHDOG_FAILURE_THRESHOLD", value: 0, usage: "Consecutive internal failures flipping the health to NOT_SERVING, zero to disable the watchdog"},
+	{key: KeyWatchdogExit, env: "WATCHDOG_EXIT", value: false, usage: fmt.Sprintf("Exit with code %d when the watchdog trips", watchdog.ExitCode)},
+	{key: KeyJournalDir, env: "JOURNAL_DIR", value: "", usage: "Directory persisting the in-flight signings, replayed after a restart, empty to disable"},
+	{key: KeyMaxConnectionAge, env: "MAX_CONNECTION_AGE", value: 30 * time.Minute, usage: "Maximum age of a client connection before it's gracefully closed with GOAWAY, rebalancing the connections across replicas"},
+	{key: KeyMaxConnectionAgeGrace, env: "MAX_CONNECTION_AGE_GRACE", value: time.Minute, usage: "Time given to the pending RPCs of a connection closed for its age"},
+	{key: KeyFeatureGates, env: "FEATURE_GATES", value: "", usage: "Comma separated list of Feature=bool pairs, known features: " + strings.Join(features.Names(), ", "), persistent: true},
+	{key: KeyClockSkewAction, env: "CLOCK_SKEW_ACTION", value: clock.ActionWarn, usage: "Action taken when the clock is skewed: warn, or refuse to issue certificates"},
+	{key: KeyClockMaxSkew, env: "CLOCK_MAX_SKEW", value: 30 * time.Second, usage: "Maximum tolerated offset from the NTP server"},
+	{key: KeyClockCheckInterval, env: "CLOCK_CHECK_INTERVAL", value: 5 * time.Minute, usage: "Interval the clock sanity is checked at"},
+	{key: KeyNTPServer, env: "NTP_SERVER", value: "", usage: "NTP server the clock is compared to (host or host:port), empty to only check against the CA validity"},
+	{key: KeyStrictStartup, env: "STRICT_STARTUP", value: false, usage: "Fail the startup when a startup check reports a warning, such as a certificate expiring soon"},
+	{key: KeyEventSinkURL, env: "EVENT_SINK_URL", value: "", usage: "Broker the certificate lifecycle events are published to: kafka://broker1:9092,broker2:9092 or nats://host:4222, empty to disable"},
+	{key: KeyEventTopic, env: "EVENT_TOPIC", value: "talos-csr-signer.events", usage: "Kafka topic, or NATS subject, the events are published to"},
+	{key: KeyEventBufferSize, env: "EVENT_BUFFER_SIZE", value: 1000, usage: "Number of events buffered while the broker is slow or unreachable, dropped when full"},
+	{key: KeyEventTLS, env: "EVENT_TLS", value: false, usage: "Connect to the event broker with TLS"},
+	{key: KeyEventTLSCAPath, env: "EVENT_TLS_CA_PATH", value: "", usage: "CA bundle verifying the event broker certificate, empty for the system roots"},
+	{key: KeyEventSASLMechanism, env: "EVENT_SASL_MECHANISM", value: "", usage: "Kafka SASL mechanism: plain, scram-sha-256, or scram-sha-512, empty to disable"},
+	{key: KeyEventSASLUsername, env: "EVENT_SASL_USERNAME", value: "", usage: "Username authenticating to the event broker"},
+	{key: KeyEventSASLPassword, env: "EVENT_SASL_PASSWORD", value: "", usage: "Password authenticating to the event broker"},
+	{key: KeyCRLValidity, env: "CRL_VALIDITY", value: 24 * time.Hour, usage: "Validity of the generated CRL, its next update", persistent: true},
+	{key: KeyCRLInterval, env: "CRL_INTERVAL", value: time.Hour, usage: "Interval the CRL is regenerated at, shorter than its validity"},
+	{key: KeyPolicyKeyAlgorithms, env: "POLICY_KEY_ALGORITHMS", value: "ed25519,ecdsa,rsa", usage: "Comma separated list of the CSR key algorithms allowed: ed25519, ecdsa, and rsa", persistent: true},
+	{key: KeyPolicyMinRSABits, env: "POLICY_MIN_RSA_BITS", value: 2048, usage: "Minimum size of the CSR RSA keys", persistent: true},
+	{key: KeyPolicyExtKeyUsages, env: "POLICY_EXTENDED_KEY_USAGES", value: "", usage: "Comma separated list of the extended key usages the CSRs may request, server and client, issued in place of the ones of the profile, empty to ignore the requested ones", persistent: true},
+	{key: KeyPolicyDNSNames, env: "POLICY_DNS_NAMES", value: "", usage: "Comma separated list of the DNS name patterns allowed in the CSRs (e.g. *.nodes.example.com), empty to allow any", persistent: true},
+	{key: KeyPolicyDNSRegexps, env: "POLICY_DNS_REGEXPS", value: "", usage: "Comma separated list of the regular expressions of the DNS names allowed in the CSRs, matching the whole name, along with the patterns of policy-dns-names", persistent: true},
+	{key: KeyPolicyIPRanges, env: "POLICY_IP_RANGES", value: "", usage: "Comma separated list of the networks the CSR IP addresses must belong to (e.g. 10.0.0.0/8), empty to allow any", persistent: true},
+	{key: KeyPolicyWildcards, env: "POLICY_WILDCARD_DNS_NAMES", value: policy.WildcardsReject, usage: "Action taken on the CSR wildcard DNS names (e.g. *.svc.cluster.local): reject the CSR, strip them from the certificate, or allow them", persistent: true},
+	{key: KeyPolicyStripLocalIPs, env: "POLICY_STRIP_LOCAL_IPS", value: false, usage: "Strip the loopback and link-local IP addresses, such as 127.0.0.1 and ::1, from the CSR IP addresses before issuance", persistent: true},
+	{key: KeyPolicyEmailAction, env: "POLICY_EMAIL_ACTION", value: policy.SANsDrop, usage: "Action taken on the CSR email addresses: drop them from the certificate, pass them, or reject the CSR", persistent: true},
+	{key: KeyPolicyEmailAddresses, env: "POLICY_EMAIL_ADDRESSES", value: "", usage: "Comma separated list of the email address patterns passed to the certificate (e.g. *@example.com), empty to allow any", persistent: true},
+	{key: KeyPolicyURIAction, env: "POLICY_URI_ACTION", value: policy.SANsDrop, usage: "Action taken on the CSR URIs: drop them from the certificate, pass them, or reject the CSR", persistent: true},
+	{key: KeyPolicyURIs, env: "POLICY_URIS", value: "", usage: "Comma separated list of the URI patterns passed to the certificate (e.g. spiffe://example.com/ns/*/sa/*), empty to allow any", persistent: true},
+	{key: KeyPolicyMaxDNSNames, env: "POLICY_MAX_DNS_NAMES", value: 100, usage: "Maximum number of the CSR DNS names, 0 to allow any", persistent: true},
+	{key: KeyPolicyMaxIPAddresses, env: "POLICY_MAX_IP_ADDRESSES", value: 100, usage: "Maximum number of the CSR IP addresses, 0 to allow any", persistent: true},
+	{key: KeyPolicyMaxExtensions, env: "POLICY_MAX_EXTENSIONS", value: 20, usage: "Maximum number of the CSR extensions, 0 to allow any", persistent: true},
+	{key: KeyPolicyCommonName, env: "POLICY_COMMON_NAME", value: "", usage: "Regular expression the CSR Common Name must match, empty to allow any", persistent: true},
+	{key: KeyPolicyOrganizations, env: "POLICY_ORGANIZATIONS", value: "", usage: "Comma separated list of the CSR subject organizations allowed (e.g. os:reader), empty to allow any", persistent: true},
+	{key: KeyPolicyOrganizationAction, env: "POLICY_ORGANIZATION_ACTION", value: policy.OrganizationsReject, usage: "Action taken on the CSR subject organizations left out of policy-organizations: reject, or strip them from the certificate", persistent: true},
+	{key: KeyPolicySubjectOrgs, env: "POLICY_SUBJECT_ORGANIZATIONS", value: "", usage: "Comma separated list of the organizations of the issued certificates, replacing the CSR subject ones, empty to keep them", persistent: true},
+	{key: KeyPolicySubjectDrop, env: "POLICY_SUBJECT_DROP", value: "", usage: "Comma separated list of the CSR subject fields left out of the issued certificates: organizational-unit, country, province, locality, street-address, postal-code, and serial-number", persistent: true},
+	{key: KeyPolicySubjectCNTemplate, env: "POLICY_SUBJECT_COMMON_NAME_TEMPLATE", value: "", usage: "Go template deriving the Common Name of the issued certificates from the CSR, such as system:node:{{.CommonName}}, empty to keep it", persistent: true},
+	{key: KeyPolicyCEL, env: "POLICY_CEL", value: "", usage: "CEL expression over the csr and the request metadata which must evaluate to true for the CSR to be signed, empty to disable it", persistent: true},
+	{key: KeyPolicyOPAURL, env: "POLICY_OPA_URL", value: "", usage: "URL of the Open Policy Agent Data API decision the CSRs are POSTed to (e.g. http://localhost:8181/v1/data/talos/signer), empty to disable it", persistent: true},
+	{key: KeyPolicyOPATimeout, env: "POLICY_OPA_TIMEOUT", value: 5 * time.Second, usage: "Timeout of the Open Policy Agent decision", persistent: true},
+	{key: KeyPolicyOPABundle, env: "POLICY_OPA_BUNDLE", value: "", usage: "OCI reference of the signed Open Policy Agent bundle pushed to the agent of the decision (e.g. ghcr.io/acme/talos-policy:v1, or @sha256:<digest> to pin it), empty to disable it", persistent: true},
+	{key: KeyPolicyOPABundleInterval, env: "POLICY_OPA_BUNDLE_INTERVAL", value: 5 * time.Minute, usage: "Interval the Open Policy Agent bundle is pulled again, pushed to the agent when its digest changed, 0 to disable it", persistent: true},
+	{key: KeyPolicyOPABundlePublicKey, env: "POLICY_OPA_BUNDLE_PUBLIC_KEY_PATH", value: "", usage: "Path to the PEM encoded public key verifying the signature of the Open Policy Agent bundles", persistent: true},
+	{key: KeyPolicyOPABundleUsername, env: "POLICY_OPA_BUNDLE_USERNAME", value: "", usage: "Username of the OCI registry of the Open Policy Agent bundle, empty for the anonymous pulls", persistent: true},
+	{key: KeyPolicyOPABundlePassword, env: "POLICY_OPA_BUNDLE_PASSWORD", value: "", usage: "Password, or token, of the OCI registry of the Open Policy Agent bundle", persistent: true},
+	{key: KeyPolicySimulate, env: "POLICY_SIMULATE", value: "", usage: "Comma separated names of the policy validators, such as san, whose rejections are only logged and counted, the CSRs being signed anyway", persistent: true},
+	{key: KeyPolicyDNSVerification, env: "POLICY_DNS_VERIFICATION", value: false, usage: "Require the CSR DNS names to resolve to the peer address, or to the --policy-dns-verification-ranges networks", persistent: true},
+	{key: KeyPolicyDNSVerifyRanges, env: "POLICY_DNS_VERIFICATION_RANGES", value: "", usage: "Comma separated list of the networks the CSR DNS names may resolve to in place of the peer address (e.g. 10.0.0.0/8)", persistent: true},
+	{key: KeyPolicyDNSVerifyBypass, env: "POLICY_DNS_VERIFICATION_BYPASS", value: "", usage: "Comma separated list of the DNS name patterns not verified (e.g. *.internal)", persistent: true},
+	{key: KeyPolicyDNSVerifyCacheTTL, env: "POLICY_DNS_VERIFICATION_CACHE_TTL", value: 5 * time.Minute, usage: "Duration the resolved addresses of the CSR DNS names are cached for, zero to disable the cache", persistent: true},
+	{key: KeyEnrollmentWindows, env: "ENROLLMENT_WINDOWS", value: "", usage: "Semicolon separated list of the windows new identities are enrolled in, as a cron expression followed by the duration (e.g. 0 22 * * 6 4h), empty to always allow", persistent: true},
+	{key: KeyEnrollmentTimezone, env: "ENROLLMENT_TIMEZONE", value: "UTC", usage: "Time zone the enrollment windows are evaluated in", persistent: true},
+	{key: KeyRoleControlPlaneOrgs, env: "ROLE_CONTROLPLANE_ORGANIZATIONS", value: "", usage: "Comma separated list of the CSR subject organizations identifying the control-plane nodes", persistent: true},
+	{key: KeyRoleControlPlaneCN, env: "ROLE_CONTROLPLANE_COMMON_NAME", value: "", usage: "Regular expression the CSR Common Name of the control-plane nodes matches, empty to disable it", persistent: true},
+	{key: KeyControlPlaneValidity, env: "CONTROLPLANE_VALIDITY", value: 365 * 24 * time.Hour, usage: "Validity of the certificates issued to the control-plane nodes", persistent: true},
+	{key: KeyControlPlaneUsages, env: "CONTROLPLANE_USAGES", value: "server", usage: "Comma separated list of the extended key usages of the control-plane certificates: server, and client", persistent: true},
+	{key: KeyWorkerValidity, env: "WORKER_VALIDITY", value: 365 * 24 * time.Hour, usage: "Validity of the certificates issued to the worker nodes", persistent: true},
+	{key: KeyWorkerUsages, env: "WORKER_USAGES", value: "server", usage: "Comma separated list of the extended key usages of the worker certificates: server, and client", persistent: true},
+	{key: KeyControlPlaneKeyAlgorithms, env: "CONTROLPLANE_KEY_ALGORITHMS", value: "", usage: "Comma separated list of the CSR key algorithms required for the control-plane certificates: ed25519, ecdsa, ecdsa-p256, ecdsa-p384, ecdsa-p521, and rsa, empty to allow any", persistent: true},
+	{key: KeyWorkerKeyAlgorithms, env: "WORKER_KEY_ALGORITHMS", value: "", usage: "Comma separated list of the CSR key algorithms required for the worker certificates: ed25519, ecdsa, ecdsa-p256, ecdsa-p384, ecdsa-p521, and rsa, empty to allow any", persistent: true},
+	{key: KeyProfilesPath, env: "PROFILES_PATH", value: "", usage: "Path to the YAML file of the named profiles the clients may request with the x-profile metadata, in place of the one of their machine role, empty to disable them", persistent: true},
+	{key: KeyPlugins, env: "PLUGINS", value: "", usage: "Comma separated list of the plugin binaries serving an authenticator, a policy validator, or a signing backend"},
+	{key: KeyAdminAddress, env: "ADMIN_ADDRESS", value: "", usage: "Address the admin API listens on (e.g. 127.0.0.1:8080), empty to disable it"},
+	{key: KeyAdminToken, env: "ADMIN_TOKEN", value: "", usage: "Bearer token required by the admin API, empty to not require authentication"},
+	{key: KeyAdminChannelz, env: "ADMIN_CHANNELZ", value: false, usage: "Serve the gRPC channelz service on the admin address, inspecting the live connections, streams, and sockets"},
+	{key: KeyClientCAPath, env: "CLIENT_CA_PATH", value: "", usage: "Path to the CA bundle verifying the client certificates, when presented"},
+	{key: KeyLogFile, env: "LOG_FILE", value: "", usage: "File the logs are written to with rotation, empty for the standard error"},
+	{key: KeyLogMaxSize, env: "LOG_MAX_SIZE", value: 100, usage: "Size in megabytes of the log file before it gets rotated"},
+	{key: KeyLogMaxAge, env: "LOG_MAX_AGE", value: 28, usage: "Days to retain the rotated log files, zero to retain them regardless of the age"},
+	{key: KeyLogMaxBackups, env: "LOG_MAX_BACKUPS", value: 3, usage: "Number of rotated log files to retain, zero to retain all of them"},
+	{key: KeyLogCompress, env: "LOG_COMPRESS", value: false, usage: "Compress the rotated log files with gzip"},
+	{key: KeyStartupWaitTimeout, env: "STARTUP_WAIT_TIMEOUT", value: time.Duration(0), usage: "Maximum time to wait for the CA and TLS files to be mounted at startup, zero to fail immediately"},
+}
+
+// option is a setting exposed as a flag, an environment variable, and a configuration file key.
+type option struct {
+	key   string
+	env   string
+	value any
+	usage string
+	// persistent options are inherited by the subcommands.
+	persistent bool
+}
+
+// Bind registers the flags of the options, the persistent ones being inherited by the subcommands,
+// and binds them to the viper keys along with their environment variables.
+func Bind(v *viper.Viper, flags, persistentFlags *pflag.FlagSet) {
+	for _, opt := range options {
+		set := flags
+		if opt.persistent {
+			set = persistentFlags
+		}
+
+		switch value := opt.value.(type) {
+		case string:
+			set.String(opt.key, value, opt.usage)
+		case int:
+			set.Int(opt.key, value, opt.usage)
+		case int64:
+			set.Int64(opt.key, value, opt.usage)
+		case bool:
+			set.Bool(opt.key, value, opt.usage)
+		case time.Duration:
+			set.Duration(opt.key, value, opt.usage)
+		default:
+			panic(fmt.Sprintf("unsupported type %T of the %s option", value, opt.key))
+		}
+
+		_ = v.BindPFlag(opt.key, set.Lookup(opt.key))
+		_ = v.BindEnv(opt.key, opt.env)
+	}
+}
+
+// ReadFile merges the configuration file, when set, under the flags and the environment. The file encrypted by sops
+// or age is decrypted with the keys of the flags and the environment.
+func ReadFile(v *viper.Viper) error {
+	path := v.GetString(KeyConfigFile)
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrConfigFile, err.Error())
+	}
+
+	if !sops.IsEncrypted(data) {
+		v.SetConfigFile(path)
+
+		if err = v.ReadInConfig(); err != nil {
+			return errors.Wrap(pkgerrors.ErrConfigFile, err.Error())
+		}
+
+		return nil
+	}
+
+	keys, err := DecryptionKeys(v)
+	if err != nil {
+		return err
+	}
+
+	// The documents of sops are decrypted to YAML, the age files to the format of their name without the .age suffix.
+	configType := "yaml"
+	if ext := filepath.Ext(strings.TrimSuffix(path, ".age")); sops.IsAge(data) && ext != "" {
+		configType = strings.TrimPrefix(ext, ".")
+	}
+
+	if data, err = sops.Decrypt(context.Background(), data, keys); err != nil {
+		return errors.Wrap(err, "configuration file "+path)
+	}
+
+	v.SetConfigType(configType)
+
+	if err = v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return errors.Wrap(pkgerrors.ErrConfigFile, err.Error())
+	}
+
+	return nil
+}
+
+// DecryptionKeys returns the keys decrypting the files encrypted by sops or age: the age identities of the settings,
+// and the AWS KMS ones of the AWS KMS settings.
+func DecryptionKeys(v *viper.Viper) (sops.Keys, error) {
+	identities := []byte(v.GetString(KeySOPSAgeKey))
+
+	if path := v.GetString(KeySOPSAgeKeyPath); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return sops.Keys{}, errors.Wrap(pkgerrors.ErrReadFile, "failed to read the age identities: "+err.Error())
+		}
+
+		identities = append(append(identities, '\n'), data...)
+	}
+
+	ageIdentities, err := sops.ParseAgeIdentities(identities)
+	if err != nil {
+		return sops.Keys{}, err //nolint:wrapcheck
+	}
+
+	timeout := v.GetDuration(KeyKMSTimeout)
+	keys := sops.Keys{
+		AgeIdentities: ageIdentities,
+		KMS: kms.Options{
+			Endpoint: v.GetString(KeyKMSEndpoint),
+			Timeout:  timeout,
+			Client:   &http.Client{Timeout: timeout},
+		},
+	}
+
+	if credentialsPath := v.GetString(KeyKMSCredentialsPath); credentialsPath != "" {
+		keys.KMS.Credentials = &kms.CredentialsProvider{
+			Region:  v.GetString(KeyKMSRegion),
+			Client:  keys.KMS.Client,
+			File:    credentialsPath,
+			Profile: v.GetString(KeyKMSProfile),
+		}
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package crl builds the Certificate Revocation List of the certificates revoked in the ledger.
+package crl
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+// Reasons are the revocation reason codes of RFC 5280, by name.
+var Reasons = map[string]int{
+	"unspecified":          0,
+	"keyCompromise":        1,
+	"cACompromise":         2,
+	"affiliationChanged":   3,
+	"superseded":           4,
+	"cessationOfOperation": 5,
+	"certificateHold":      6,
+	"privilegeWithdrawn":   9,
+	"aACompromise":         10,
+}
+
+// ParseReason returns the reason code of the given name, case-insensitive.
+func ParseReason(name string) (int, error) {
+	if name == "" {
+		return Reasons["unspecified"], nil
+	}
+
+	for reason, code := range Reasons {
+		if strings.EqualFold(reason, name) {
+			return code, nil
+		}
+	}
+
+	names := make([]string, 0, len(Reasons))
+	for reason := range Reasons {
+		names = append(names, reason)
+	}
+
+	sort.Strings(names)
+
+	return 0, errors.Wrapf(pkgerrors.ErrRevocationReason, "%s, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// Build returns the DER encoded CRL of the revoked certificates of the ledger, signed by the issuer,
+// valid for the given duration. The number must increase with every generated CRL.
+func Build(ctx context.Context, l ledger.Ledger, issuer *x509.Certificate, signer crypto.Signer, validity time.Duration, number *big.Int) ([]byte, error) {
+	records, err := l.List(ctx)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	now := time.Now()
+	template := &x509.RevocationList{
+		Number:     number,
+		ThisUpdate: now,
+		NextUpdate: now.Add(validity),
+	}
+
+	for _, record := range records {
+		// Expired certificates are not listed anymore, as allowed by RFC 5280.
+		if !record.Revoked() || now.After(record.NotAfter) {
+			continue
+		}
+
+		serial, ok := new(big.Int).SetString(record.Serial, 16)
+		if !ok {
+			logging.FromContext(ctx).Warn("Skipping the revoked certificate with an invalid serial", "serial", record.Serial)
+
+			continue
+		}
+
+		template.RevokedCertificateEntries = append(template.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: *record.RevokedAt,
+			ReasonCode:     record.RevocationReason,
+		})
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, template, issuer, signer)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrCRL, err.Error())
+	}
+
+	return der, nil
+}
+
+// Cache holds the last generated CRL, regenerated periodically and on demand.
+// A nil Cache never generates any CRL.
+type Cache struct {
+	ledger   ledger.Ledger
+	issuer   *x509.Certificate
+	signer   crypto.Signer
+	validity time.Duration
+
+	mu     sync.RWMutex
+	der    []byte
+	number int64
+}
+
+// NewCache returns the Cache of the CRL signed by the issuer, valid for the given duration.
+func NewCache(l ledger.Ledger, issuer *x509.Certificate, signer crypto.Signer, validity time.Duration) *Cache {
+	return &Cache{
+		ledger:   l,
+		issuer:   issuer,
+		signer:   signer,
+		validity: validity,
+	}
+}
+
+// Regenerate builds a new CRL from the ledger.
+func (c *Cache) Regenerate(ctx context.Context) error {
+	if c == nil {
+		return pkgerrors.ErrCRLDisabled
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The Unix time keeps the number increasing across restarts and replicas.
+	number := max(time.Now().Unix(), c.number+1)
+
+	der, err := Build(ctx, c.ledger, c.issuer, c.signer, c.validity, big.NewInt(number))
+	if err != nil {
+		return err
+	}
+
+	c.der, c.number = der, number
+
+	return nil
+}
+
+// Run regenerates the CRL at the given interval until the context is done.
+func (c *Cache) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Regenerate(ctx); err != nil {
+				logging.FromContext(ctx).Error("Failed to regenerate the CRL", "error", err)
+			}
+		}
+	}
+}
+
+// ServeHTTP serves the last generated CRL, DER encoded, or PEM encoded when requested with ?format=pem.
+func (c *Cache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.RLock()
+	der := c.der
+	c.mu.RUnlock()
+
+	if der == nil {
+		http.Error(w, "CRL not generated yet", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	if r.URL.Query().Get("format") == "pem" {
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		_ = pem.Encode(w, &pem.Block{Type: "X509 CRL", Bytes: der})
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pkix-crl")
+	_, _ = w.Write(der)
+}
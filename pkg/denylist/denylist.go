@@ -0,0 +1,244 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package denylist contains the identities that must never be issued a certificate, such as the ones of the
+// decommissioned or compromised nodes, checked before any other policy.
+package denylist
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.yaml.in/yaml/v3"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+// document is the YAML deny-list.
+type document struct {
+	CommonNames []string `yaml:"common-names"`
+	DNSNames    []string `yaml:"dns-names"`
+	IPAddresses []string `yaml:"ip-addresses"`
+	PublicKeys  []string `yaml:"public-keys"`
+	NodeUUIDs   []string `yaml:"node-uuids"`
+}
+
+// List holds the denied identities, such as:
+//
+//	common-names: [worker-3]
+//	dns-names: [worker-3.nodes.example.com]
+//	ip-addresses: [10.0.0.13, 10.0.1.0/24]
+//	public-keys: ["sha256:1f0e..."]
+//	node-uuids: [4c4c4544-0042-3510-8051-b2c04f4e3232]
+//
+// The public keys are the SHA-256 hashes of their Subject Public Key Info, either base64 encoded as in the ledger
+// records, or hex encoded optionally prefixed by sha256:. The node UUIDs are the SMBIOS UUIDs of the machines.
+type List struct {
+	commonNames map[string]bool
+	dnsNames    map[string]bool
+	ipPrefixes  []netip.Prefix
+	publicKeys  map[[sha256.Size]byte]bool
+	nodeUUIDs   map[string]bool
+	digest      [sha256.Size]byte
+}
+
+// Parse returns the List of the YAML document, refusing the unknown fields.
+func Parse(data []byte) (*List, error) {
+	var doc document
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, errors.Wrap(pkgerrors.ErrDenyList, err.Error())
+	}
+
+	l := &List{
+		commonNames: make(map[string]bool, len(doc.CommonNames)),
+		dnsNames:    make(map[string]bool, len(doc.DNSNames)),
+		publicKeys:  make(map[[sha256.Size]byte]bool, len(doc.PublicKeys)),
+		nodeUUIDs:   make(map[string]bool, len(doc.NodeUUIDs)),
+		digest:      sha256.Sum256(data),
+	}
+
+	for _, name := range doc.CommonNames {
+		l.commonNames[name] = true
+	}
+
+	for _, name := range doc.DNSNames {
+		l.dnsNames[strings.ToLower(name)] = true
+	}
+
+	for _, address := range doc.IPAddresses {
+		prefix, err := parsePrefix(address)
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrDenyList, "invalid IP address "+address)
+		}
+
+		l.ipPrefixes = append(l.ipPrefixes, prefix)
+	}
+
+	for _, key := range doc.PublicKeys {
+		hash, err := parseHash(key)
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrDenyList, "invalid public key hash "+key)
+		}
+
+		l.publicKeys[hash] = true
+	}
+
+	for _, nodeUUID := range doc.NodeUUIDs {
+		l.nodeUUIDs[strings.ToLower(nodeUUID)] = true
+	}
+
+	return l, nil
+}
+
+// parsePrefix returns the prefix of the IP address, or of the CIDR range.
+func parsePrefix(address string) (netip.Prefix, error) {
+	if strings.Contains(address, "/") {
+		prefix, err := netip.ParsePrefix(address)
+
+		return prefix.Masked(), err //nolint:wrapcheck
+	}
+
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return netip.Prefix{}, err //nolint:wrapcheck
+	}
+
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// parseHash returns the SHA-256 hash, hex encoded optionally prefixed by sha256:, or base64 encoded.
+func parseHash(encoded string) ([sha256.Size]byte, error) {
+	var hash [sha256.Size]byte
+
+	decoded, err := hex.DecodeString(strings.NewReplacer("sha256:", "", "SHA256:", "", ":", "").Replace(encoded))
+	if err != nil || len(decoded) != sha256.Size {
+		if decoded, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return hash, err //nolint:wrapcheck
+		}
+	}
+
+	if len(decoded) != sha256.Size {
+		return hash, errors.New("not a SHA-256 hash")
+	}
+
+	copy(hash[:], decoded)
+
+	return hash, nil
+}
+
+// Load returns the List read from the path.
+func Load(path string) (*List, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrReadFile, err.Error())
+	}
+
+	l, err := Parse(data)
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+
+	return l, nil
+}
+
+// Match returns the denied identity of the CSR, sent along with the node UUID, and true when one is denied.
+func (l *List) Match(csr *x509.CertificateRequest, nodeUUID string) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+
+	if l.commonNames[csr.Subject.CommonName] {
+		return "Common Name " + csr.Subject.CommonName, true
+	}
+
+	for _, name := range csr.DNSNames {
+		if l.dnsNames[strings.ToLower(name)] {
+			return "DNS name " + name, true
+		}
+	}
+
+	for _, ip := range csr.IPAddresses {
+		addr, ok := netip.AddrFromSlice(ip)
+		if !ok {
+			continue
+		}
+
+		for _, prefix := range l.ipPrefixes {
+			if prefix.Contains(addr.Unmap()) {
+				return "IP address " + addr.Unmap().String(), true
+			}
+		}
+	}
+
+	if l.publicKeys[sha256.Sum256(csr.RawSubjectPublicKeyInfo)] {
+		return "public key", true
+	}
+
+	nodeUUIDs := []string{nodeUUID}
+	for _, uri := range csr.URIs {
+		if uri.Scheme == "urn" && strings.HasPrefix(strings.ToLower(uri.Opaque), "uuid:") {
+			nodeUUIDs = append(nodeUUIDs, uri.Opaque[len("uuid:"):])
+		}
+	}
+
+	for _, candidate := range nodeUUIDs {
+		if candidate != "" && l.nodeUUIDs[strings.ToLower(candidate)] {
+			return "node UUID " + candidate, true
+		}
+	}
+
+	return "", false
+}
+
+// Watch reads the deny-list every interval until the context is done, calling onChange with the list when its content
+// differs from the given one, such as a mounted ConfigMap being updated. Invalid lists are ignored, keeping the
+// previous one.
+func Watch(ctx context.Context, path string, interval time.Duration, current *List, onChange func(*List)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to read the deny-list, keeping the previous one", "path", path, "error", err)
+
+			continue
+		}
+
+		if sha256.Sum256(data) == current.digest {
+			continue
+		}
+
+		l, err := Parse(data)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to reload the deny-list, keeping the previous one", "path", path, "error", err)
+
+			continue
+		}
+
+		current = l
+
+		onChange(l)
+	}
+}
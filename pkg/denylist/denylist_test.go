@@ -0,0 +1,177 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package denylist
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// newCSR returns the CSR of the template signed by a new key.
+func newCSR(t *testing.T, template *x509.CertificateRequest) *x509.CertificateRequest {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return csr
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{name: "empty"},
+		{name: "identities", data: "common-names: [worker-3]\nip-addresses: [10.0.0.13, 10.0.1.0/24, '::ffff:10.0.2.1']\npublic-keys: ['sha256:" + hex.EncodeToString(make([]byte, 32)) + "']\n"},
+		{name: "unknown field", data: "hostnames: [worker-3]\n", err: pkgerrors.ErrDenyList},
+		{name: "invalid IP address", data: "ip-addresses: [10.0.0.256]\n", err: pkgerrors.ErrDenyList},
+		{name: "invalid CIDR range", data: "ip-addresses: [10.0.0.0/33]\n", err: pkgerrors.ErrDenyList},
+		{name: "invalid public key hash", data: "public-keys: [sha256:abcd]\n", err: pkgerrors.ErrDenyList},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	denied := newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-9"}})
+	hash := sha256.Sum256(denied.RawSubjectPublicKeyInfo)
+	nodeURI, _ := url.Parse("urn:uuid:4C4C4544-0042-3510-8051-B2C04F4E3232")
+
+	tests := []struct {
+		name      string
+		publicKey string
+		csr       *x509.CertificateRequest
+		nodeUUID  string
+		expected  string
+	}{
+		{name: "allowed", csr: newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, DNSNames: []string{"worker-1"}, IPAddresses: []net.IP{net.ParseIP("10.0.2.1")}})},
+		{name: "Common Name", csr: newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-3"}}), expected: "Common Name worker-3"},
+		{name: "DNS name", csr: newCSR(t, &x509.CertificateRequest{DNSNames: []string{"Worker-3.Nodes.Example.com"}}), expected: "DNS name Worker-3.Nodes.Example.com"},
+		{name: "IP address", csr: newCSR(t, &x509.CertificateRequest{IPAddresses: []net.IP{net.ParseIP("10.0.0.13")}}), expected: "IP address 10.0.0.13"},
+		{name: "IP address of a range", csr: newCSR(t, &x509.CertificateRequest{IPAddresses: []net.IP{net.ParseIP("10.0.1.200")}}), expected: "IP address 10.0.1.200"},
+		{name: "hex public key", publicKey: "sha256:" + hex.EncodeToString(hash[:]), csr: denied, expected: "public key"},
+		{name: "base64 public key", publicKey: base64.StdEncoding.EncodeToString(hash[:]), csr: denied, expected: "public key"},
+		{name: "node UUID", csr: newCSR(t, &x509.CertificateRequest{}), nodeUUID: "4c4c4544-0042-3510-8051-b2c04f4e3232", expected: "node UUID 4c4c4544-0042-3510-8051-b2c04f4e3232"},
+		{name: "node UUID URI SAN", csr: newCSR(t, &x509.CertificateRequest{URIs: []*url.URL{nodeURI}}), expected: "node UUID 4C4C4544-0042-3510-8051-B2C04F4E3232"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publicKey := "sha256:" + hex.EncodeToString(make([]byte, 32))
+			if tt.publicKey != "" {
+				publicKey = tt.publicKey
+			}
+
+			l, err := Parse([]byte(`common-names: [worker-3]
+dns-names: [worker-3.nodes.example.com]
+ip-addresses: [10.0.0.13, 10.0.1.0/24]
+public-keys: ["` + publicKey + `"]
+node-uuids: [4C4C4544-0042-3510-8051-B2C04F4E3232]
+`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			identity, matched := l.Match(tt.csr, tt.nodeUUID)
+			if identity != tt.expected || matched != (tt.expected != "") {
+				t.Fatalf("expected the denied identity %q, got %q", tt.expected, identity)
+			}
+		})
+	}
+
+	var l *List
+	if _, matched := l.Match(denied, ""); matched {
+		t.Fatal("expected the nil list to deny nothing")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.yaml")
+	if err := os.WriteFile(path, []byte("common-names: [worker-3]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	changes := make(chan *List)
+
+	go Watch(ctx, path, 10*time.Millisecond, current, func(l *List) { changes <- l })
+
+	csr := &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-4"}}
+
+	// The invalid lists are ignored, the valid one replacing the current list: renamed into place, as a ConfigMap
+	// update, so that the watcher never reads a truncated one
+	for _, data := range []string{"common-names: worker-4\n", "common-names: [worker-4]\n"} {
+		if err = os.WriteFile(path+".tmp", []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = os.Rename(path+".tmp", path); err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	select {
+	case l := <-changes:
+		if _, matched := l.Match(csr, ""); !matched {
+			t.Fatal("expected the reloaded list to deny worker-4")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the modified list to be reloaded")
+	}
+}
@@ -11,6 +11,10 @@ import (
 var (
 	// ErrDecodedCACertificate is the error when Certificate Authority decoding has failed.
 	ErrDecodedCACertificate = errors.New("failed to decode CA certificate")
+	// ErrCAKeyMismatch is the error when the CA private key doesn't match the public key of the CA certificate.
+	ErrCAKeyMismatch = errors.New("the CA private key doesn't match the CA certificate")
+	// ErrOutlivesCA is the error when the certificate would outlive the CA signing it.
+	ErrOutlivesCA = errors.New("the certificate would outlive the CA")
 	// ErrMissingPort is the error when a zero value for port is defined.
 	ErrMissingPort = errors.New("missing gRPC server port")
 	// ErrPortOutOfRange is the error when a port is out of range.
@@ -33,4 +37,165 @@ var (
 	ErrServerListen = errors.New("failed to listen on given port")
 	// ErrGRPCServerServe is the error when the gRPC server is not hable to serve requests.
 	ErrGRPCServerServe = errors.New("failed to serve gRPC")
+	// ErrLedgerURL is the error when the ledger URL cannot be parsed.
+	ErrLedgerURL = errors.New("invalid ledger URL")
+	// ErrUnsupportedLedger is the error when the ledger URL scheme is not handled.
+	ErrUnsupportedLedger = errors.New("unsupported ledger backend")
+	// ErrLedgerBackend is the error when the ledger backend fails to serve a request.
+	ErrLedgerBackend = errors.New("ledger backend failure")
+	// ErrLedgerNotFound is the error when a ledger record does not exist.
+	ErrLedgerNotFound = errors.New("ledger record not found")
+	// ErrSerialCollision is the error when no unique serial number could be reserved.
+	ErrSerialCollision = errors.New("unable to reserve a unique serial number")
+	// ErrSerialNumber is the error when the serial number of a certificate cannot be generated.
+	ErrSerialNumber = errors.New("failed to generate the serial number")
+	// ErrSerialFormat is the error when the format of the serial numbers is not valid.
+	ErrSerialFormat = errors.New("invalid serial number format")
+	// ErrLedgerSnapshot is the error when a ledger snapshot cannot be written or read.
+	ErrLedgerSnapshot = errors.New("ledger snapshot failure")
+	// ErrBackendSign is the error when a signing backend fails to sign a certificate.
+	ErrBackendSign = errors.New("signing backend failed to sign the certificate")
+	// ErrBackendUnavailable is the error when none of the configured signing backends is able to sign.
+	ErrBackendUnavailable = errors.New("no signing backend available")
+	// ErrBackendTransient is the error when a signing backend is temporarily unavailable, such as unreachable or
+	// overloaded, the request being worth retrying.
+	ErrBackendTransient = errors.New("signing backend temporarily unavailable")
+	// ErrBackendQueueFull is the error when a request cannot be queued while the signing backend is unavailable.
+	ErrBackendQueueFull = errors.New("signing backend queue is full")
+	// ErrStartupTimeout is the error when the required files are not available before the startup timeout.
+	ErrStartupTimeout = errors.New("timed out waiting for files")
+	// ErrJournal is the error when the journal of the pending work cannot be read or written.
+	ErrJournal = errors.New("journal failure")
+	// ErrAdminUnauthorized is the error when an admin API request is missing a valid bearer token.
+	ErrAdminUnauthorized = errors.New("unauthorized")
+	// ErrAdminServe is the error when the admin API server is not able to serve requests.
+	ErrAdminServe = errors.New("failed to serve the admin API")
+	// ErrFeatureGate is the error when a feature gate is not in the Feature=bool format.
+	ErrFeatureGate = errors.New("invalid feature gate, expected Feature=bool")
+	// ErrUnknownFeatureGate is the error when a feature gate is not known.
+	ErrUnknownFeatureGate = errors.New("unknown feature gate")
+	// ErrClockSkew is the error when the system clock is considered skewed.
+	ErrClockSkew = errors.New("clock skew detected")
+	// ErrClockSkewAction is the error when the action taken on a clock skew is not supported.
+	ErrClockSkewAction = errors.New("unsupported clock skew action, expected warn or refuse")
+	// ErrNTPQuery is the error when the NTP server cannot be queried.
+	ErrNTPQuery = errors.New("failed to query the NTP server")
+	// ErrStartupCheck is the error when the startup checks didn't pass.
+	ErrStartupCheck = errors.New("startup checks failed")
+	// ErrEventSinkURL is the error when the event sink URL cannot be parsed.
+	ErrEventSinkURL = errors.New("invalid event sink URL")
+	// ErrUnsupportedEventSink is the error when the event sink URL scheme is not supported.
+	ErrUnsupportedEventSink = errors.New("unsupported event sink, expected kafka or nats")
+	// ErrUnsupportedSASL is the error when the SASL mechanism is not supported.
+	ErrUnsupportedSASL = errors.New("unsupported SASL mechanism, expected plain, scram-sha-256, or scram-sha-512")
+	// ErrEventSink is the error when the events cannot be published.
+	ErrEventSink = errors.New("event sink failure")
+	// ErrPKCS12 is the error when the PKCS#12 bundle cannot be decoded.
+	ErrPKCS12 = errors.New("invalid PKCS#12 bundle")
+	// ErrKeyPassphrase is the error when the encrypted private key cannot be decrypted with the passphrase.
+	ErrKeyPassphrase = errors.New("missing or wrong private key passphrase")
+	// ErrUnsupportedKey is the error when the key algorithm is not supported.
+	ErrUnsupportedKey = errors.New("unsupported key algorithm")
+	// ErrGenerateKey is the error when a key, or a serial number, cannot be generated.
+	ErrGenerateKey = errors.New("failed to generate key")
+	// ErrCARotation is the error when a CA rotation step cannot be performed.
+	ErrCARotation = errors.New("CA rotation failure")
+	// ErrInvalidIP is the error when an IP address cannot be parsed.
+	ErrInvalidIP = errors.New("invalid IP address")
+	// ErrWriteFile is the error when a file cannot be written.
+	ErrWriteFile = errors.New("failed to write file")
+	// ErrCSR is the error when a certificate request cannot be generated.
+	ErrCSR = errors.New("failed to generate the certificate request")
+	// ErrToken is the error when the Talos tokens cannot be generated or parsed.
+	ErrToken = errors.New("invalid Talos tokens")
+	// ErrGetCA is the error when the trust bundle cannot be fetched, or verified.
+	ErrGetCA = errors.New("failed to get the trust bundle")
+	// ErrDiagnosis is the error when the connectivity diagnosis found failures.
+	ErrDiagnosis = errors.New("the signer connectivity diagnosis found failures")
+	// ErrPolicyViolation is the error when a CSR does not satisfy the signing policy.
+	ErrPolicyViolation = errors.New("the CSR violates the signing policy")
+	// ErrPolicy is the error when the signing policy configuration is not valid.
+	ErrPolicy = errors.New("invalid signing policy")
+	// ErrOPA is the error when the policy decision cannot be queried from the Open Policy Agent.
+	ErrOPA = errors.New("failed to query the Open Policy Agent")
+	// ErrOCI is the error when an artifact cannot be pulled from the OCI registry.
+	ErrOCI = errors.New("failed to pull the OCI artifact")
+	// ErrOPABundle is the error when the Open Policy Agent bundle is not valid, or not signed by the trusted key.
+	ErrOPABundle = errors.New("invalid Open Policy Agent bundle")
+	// ErrPlugin is the error when a plugin cannot be started.
+	ErrPlugin = errors.New("failed to load the plugin")
+	// ErrPluginEmpty is the error when a plugin serves no implementation.
+	ErrPluginEmpty = errors.New("the plugin serves no implementation")
+	// ErrRevocationReason is the error when the revocation reason is not known.
+	ErrRevocationReason = errors.New("unknown revocation reason")
+	// ErrCRL is the error when the Certificate Revocation List cannot be generated.
+	ErrCRL = errors.New("failed to generate the CRL")
+	// ErrCRLDisabled is the error when the CRL is requested while the CRLServing feature is disabled.
+	ErrCRLDisabled = errors.New("CRL serving is disabled, enable the CRLServing feature gate")
+	// ErrRevoke is the error when a certificate cannot be revoked.
+	ErrRevoke = errors.New("failed to revoke the certificate")
+	// ErrAdminRequest is the error when the admin API request fails.
+	ErrAdminRequest = errors.New("admin API request failed")
+	// ErrProofOfPossession is the error when the proof of possession of the CSR private key is not valid.
+	ErrProofOfPossession = errors.New("invalid proof of possession")
+	// ErrSchedule is the error when a time window spec is not valid.
+	ErrSchedule = errors.New("invalid time window")
+	// ErrProfile is the error when a certificate profile configuration is not valid.
+	ErrProfile = errors.New("invalid certificate profile")
+	// ErrSocketActivation is the error when a socket passed by systemd cannot be used.
+	ErrSocketActivation = errors.New("invalid socket activation")
+	// ErrNotify is the error when the state cannot be notified to systemd.
+	ErrNotify = errors.New("failed to notify systemd")
+	// ErrAttestation is the error when the TPM attestation of a node cannot be verified.
+	ErrAttestation = errors.New("invalid TPM attestation")
+	// ErrInstanceIdentity is the error when the cloud instance identity of a node cannot be verified.
+	ErrInstanceIdentity = errors.New("invalid instance identity")
+	// ErrJWT is the error when a JSON Web Token is malformed, or not signed by the key.
+	ErrJWT = errors.New("invalid JSON Web Token")
+	// ErrTransparencyLog is the error when an issued certificate cannot be published to the transparency log.
+	ErrTransparencyLog = errors.New("failed to publish to the transparency log")
+	// ErrApproval is the error when a privileged certificate request cannot be approved.
+	ErrApproval = errors.New("invalid approval")
+	// ErrApprover is the error when the approver of a request cannot be authenticated.
+	ErrApprover = errors.New("invalid approver")
+	// ErrApprovalNotFound is the error when an approval request is unknown or expired.
+	ErrApprovalNotFound = errors.New("approval request not found")
+	// ErrStandby is the error when the standby cannot replicate the primary signer.
+	ErrStandby = errors.New("failed to replicate the primary")
+	// ErrStandbyReadOnly is the error when the state of a standby is changed before its promotion.
+	ErrStandbyReadOnly = errors.New("the standby is read-only until promoted")
+	// ErrPromoted is the error when a standby is promoted twice.
+	ErrPromoted = errors.New("already promoted")
+	// ErrCAChain is the error when the chain of the intermediate signing CA doesn't lead to a root.
+	ErrCAChain = errors.New("invalid CA chain")
+	// ErrKMS is the error when the CA key held by AWS KMS cannot sign.
+	ErrKMS = errors.New("AWS KMS request failed")
+	// ErrDecrypt is the error when the sops or age encrypted file cannot be decrypted.
+	ErrDecrypt = errors.New("failed to decrypt the encrypted file")
+	// ErrCloudSecret is the error when the secret of AWS Secrets Manager or GCP Secret Manager cannot be read.
+	ErrCloudSecret = errors.New("cloud secret manager request failed")
+	// ErrCABundleURL is the error when the CA bundle cannot be fetched from its URL, or doesn't match its checksum.
+	ErrCABundleURL = errors.New("failed to fetch the CA bundle")
+	// ErrHybridSigning is the error when the post-quantum alternative signature cannot be added to a certificate.
+	ErrHybridSigning = errors.New("hybrid signing failed")
+	// ErrKubernetes is the error when the Kubernetes API server cannot be reached or answers with an error.
+	ErrKubernetes = errors.New("kubernetes API request failed")
+	// ErrBundle is the error when the configuration bundle cannot be read or is not valid.
+	ErrBundle = errors.New("invalid configuration bundle")
+	// ErrPolicyFile is the error when the policy file is not valid.
+	ErrPolicyFile = errors.New("invalid policy file")
+	// ErrSubject is the error when the subject of the certificate cannot be derived from the CSR.
+	ErrSubject = errors.New("failed to rewrite the subject")
+	// ErrNameConstraints is the error when a SAN of the certificate violates the name constraints of the CA.
+	ErrNameConstraints = errors.New("name constraints violated")
+	// ErrSPIFFEID is the error when the SPIFFE ID of the certificate cannot be derived from the CSR.
+	ErrSPIFFEID = errors.New("failed to derive the SPIFFE ID")
+	// ErrDenyList is the error when the deny-list is not valid.
+	ErrDenyList = errors.New("invalid deny-list")
+	// ErrConfigFile is the error when the configuration file cannot be read.
+	ErrConfigFile = errors.New("failed to read the configuration file")
+	// ErrConfig is the error when the configuration is not valid.
+	ErrConfig = errors.New("invalid configuration")
+	// ErrInvalidDuration is the error when a duration cannot be parsed.
+	ErrInvalidDuration = errors.New("invalid duration, expected a Go duration or a number of days such as 30d")
 )
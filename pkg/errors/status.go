@@ -0,0 +1,170 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package errors
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorInfoDomain is the domain of the ErrorInfo details attached to the gRPC statuses.
+const ErrorInfoDomain = "talos-csr-signer.clastix.io"
+
+// Kind is the category of the errors answered to the clients, mapped to a gRPC status code.
+type Kind string
+
+const (
+	// KindAuth is the kind of the errors of the requests not authenticated.
+	KindAuth Kind = "auth"
+	// KindInvalid is the kind of the errors of the malformed requests.
+	KindInvalid Kind = "invalid"
+	// KindPolicy is the kind of the errors of the requests rejected by the signing policy.
+	KindPolicy Kind = "policy"
+	// KindQuota is the kind of the errors of the requests exceeding a quota.
+	KindQuota Kind = "quota"
+	// KindChallenge is the kind of the errors of the requests to retry answering a challenge.
+	KindChallenge Kind = "challenge"
+	// KindBackend is the kind of the errors of the signing backends, and of the ledger.
+	KindBackend Kind = "backend"
+	// KindUnavailable is the kind of the errors of the requests the signer refuses to serve for now.
+	KindUnavailable Kind = "unavailable"
+	// KindInternal is the kind of the unexpected errors.
+	KindInternal Kind = "internal"
+)
+
+// kindCodes is the mapping of the error kinds to the gRPC status codes.
+var kindCodes = map[Kind]codes.Code{
+	KindAuth:        codes.Unauthenticated,
+	KindInvalid:     codes.InvalidArgument,
+	KindPolicy:      codes.PermissionDenied,
+	KindQuota:       codes.ResourceExhausted,
+	KindChallenge:   codes.FailedPrecondition,
+	KindBackend:     codes.Unavailable,
+	KindUnavailable: codes.Unavailable,
+	KindInternal:    codes.Internal,
+}
+
+// Code returns the gRPC status code of the kind.
+func (k Kind) Code() codes.Code {
+	if code, ok := kindCodes[k]; ok {
+		return code
+	}
+
+	return codes.Internal
+}
+
+// KindOf returns the kind of the errors answered with the given gRPC status code,
+// letting the validators keep choosing the code of their denials.
+func KindOf(code codes.Code) Kind {
+	for kind, kindCode := range kindCodes {
+		// KindBackend and KindUnavailable share their code: the former is the one of the external failures.
+		if kindCode == code && kind != KindUnavailable {
+			return kind
+		}
+	}
+
+	return KindInternal
+}
+
+// The ErrorInfo reasons of the errors answered to the clients.
+const (
+	ReasonNotServing               = "NOT_SERVING"
+	ReasonStandby                  = "STANDBY"
+	ReasonClockSkew                = "CLOCK_SKEW"
+	ReasonMissingMetadata          = "MISSING_METADATA"
+	ReasonMissingToken             = "MISSING_TOKEN"
+	ReasonInvalidToken             = "INVALID_TOKEN"
+	ReasonAuthenticatorUnavailable = "AUTHENTICATOR_UNAVAILABLE"
+	ReasonMalformedCSR             = "MALFORMED_CSR"
+	ReasonInvalidNodeUUID          = "INVALID_NODE_UUID"
+	ReasonInvalidTTL               = "INVALID_TTL"
+	ReasonUnknownProfile           = "UNKNOWN_PROFILE"
+	ReasonProfileNotAllowed        = "PROFILE_NOT_ALLOWED"
+	ReasonInvalidAttestation       = "INVALID_ATTESTATION"
+	ReasonInvalidInstanceIdentity  = "INVALID_INSTANCE_IDENTITY"
+	ReasonProofRequired            = "PROOF_OF_POSSESSION_REQUIRED"
+	ReasonInvalidProof             = "INVALID_PROOF_OF_POSSESSION"
+	ReasonPolicyDenied             = "POLICY_DENIED"
+	ReasonDenyListed               = "DENY_LISTED"
+	ReasonNameConstraints          = "NAME_CONSTRAINTS_VIOLATED"
+	ReasonCANotAllowed             = "CA_NOT_ALLOWED"
+	ReasonTokenIdentity            = "TOKEN_IDENTITY_MISMATCH"
+	ReasonApprovalRequired         = "APPROVAL_REQUIRED"
+	ReasonKeyAlgorithm             = "KEY_ALGORITHM_MISMATCH"
+	ReasonValidatorFailed          = "VALIDATOR_FAILED"
+	ReasonLedgerUnavailable        = "LEDGER_UNAVAILABLE"
+	ReasonSerialNumber             = "SERIAL_NUMBER"
+	ReasonBackendUnavailable       = "BACKEND_UNAVAILABLE"
+	ReasonCAExpiring               = "CA_EXPIRING"
+	ReasonTransparencyUnavailable  = "TRANSPARENCY_LOG_UNAVAILABLE"
+	ReasonUnknownCluster           = "UNKNOWN_CLUSTER"
+	ReasonWrongShard               = "WRONG_SHARD"
+)
+
+// Error is an error answered to the clients: its message, reason, and metadata are exposed to them,
+// while the cause is only meant for the logs.
+type Error struct {
+	Kind     Kind
+	Reason   string
+	Message  string
+	Metadata map[string]string
+	Cause    error
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	if e.Cause == nil {
+		return e.Message
+	}
+
+	return e.Message + ": " + e.Cause.Error()
+}
+
+// Unwrap returns the cause of the error.
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// GRPCStatus returns the gRPC status answered to the clients, with the ErrorInfo details:
+// the gRPC server uses it when the error is returned by an RPC.
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(e.Kind.Code(), e.Message)
+
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   e.Reason,
+		Domain:   ErrorInfoDomain,
+		Metadata: e.Metadata,
+	})
+	if err != nil {
+		return st
+	}
+
+	return detailed
+}
+
+// Auth returns the error of a request not authenticated.
+func Auth(reason, message string) *Error {
+	return &Error{Kind: KindAuth, Reason: reason, Message: message}
+}
+
+// Invalid returns the error of a malformed request.
+func Invalid(reason, message string) *Error {
+	return &Error{Kind: KindInvalid, Reason: reason, Message: message}
+}
+
+// Backend returns the error of a signing backend, or ledger, failure.
+func Backend(reason, message string, cause error) *Error {
+	return &Error{Kind: KindBackend, Reason: reason, Message: message, Cause: cause}
+}
+
+// Unavailable returns the error of a request the signer refuses to serve for now.
+func Unavailable(reason, message string, cause error) *Error {
+	return &Error{Kind: KindUnavailable, Reason: reason, Message: message, Cause: cause}
+}
+
+// Internal returns the error of an unexpected failure.
+func Internal(reason, message string, cause error) *Error {
+	return &Error{Kind: KindInternal, Reason: reason, Message: message, Cause: cause}
+}
@@ -0,0 +1,58 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package errors
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestKindOf(t *testing.T) {
+	for kind, code := range kindCodes {
+		if kind == KindUnavailable {
+			if KindOf(code) != KindBackend {
+				t.Fatalf("expected %s to be reported as a backend error, got %s", code, KindOf(code))
+			}
+
+			continue
+		}
+
+		if KindOf(code) != kind || kind.Code() != code {
+			t.Fatalf("expected %s to be mapped to %s, got %s", kind, code, KindOf(code))
+		}
+	}
+
+	if KindOf(codes.DataLoss) != KindInternal || Kind("unknown").Code() != codes.Internal {
+		t.Fatal("expected the unknown kinds and codes to be internal")
+	}
+}
+
+func TestGRPCStatus(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := Backend(ReasonLedgerUnavailable, "ledger unavailable", cause)
+	err.Metadata = map[string]string{"validator": "quota"}
+
+	if !errors.Is(err, cause) || err.Error() != "ledger unavailable: connection refused" {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.Unavailable || st.Message() != "ledger unavailable" {
+		t.Fatalf("unexpected status %v, the cause must not be answered", st)
+	}
+
+	details := st.Details()
+	if len(details) != 1 {
+		t.Fatalf("expected the ErrorInfo details, got %v", details)
+	}
+
+	info, ok := details[0].(*errdetails.ErrorInfo)
+	if !ok || info.GetReason() != ReasonLedgerUnavailable || info.GetDomain() != ErrorInfoDomain || info.GetMetadata()["validator"] != "quota" {
+		t.Fatalf("unexpected details %v", details[0])
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Bus fans out the lifecycle events to its subscribers: the broker publishers, and the embedders of the server.
+// Each subscriber has its own buffer, so a slow one never delays the issuance nor the other subscribers: its events
+// are dropped when the buffer is full. A nil Bus discards all the events.
+type Bus struct {
+	// Logger logs the dropped events: nil uses slog.Default().
+	Logger *slog.Logger
+
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewBus returns a Bus with no subscribers.
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the events emitted on the Bus after it subscribed.
+type Subscription struct {
+	name   string
+	bus    *Bus
+	events chan Event
+	once   sync.Once
+}
+
+// Subscribe returns the named Subscription, buffering up to size events.
+// The subscriber must drain Events until the channel is closed, by Close or by closing the Bus.
+func (b *Bus) Subscribe(name string, size int) *Subscription {
+	sub := &Subscription{name: name, bus: b, events: make(chan Event, size)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(sub.events)
+
+		return sub
+	}
+
+	b.subscribers[sub] = struct{}{}
+
+	return sub
+}
+
+// Handle subscribes the callback, invoked sequentially with the events until the subscription is closed.
+func (b *Bus) Handle(name string, size int, fn func(Event)) *Subscription {
+	sub := b.Subscribe(name, size)
+
+	go func() {
+		for event := range sub.Events() {
+			fn(event)
+		}
+	}()
+
+	return sub
+}
+
+// Emit delivers the event to the subscribers, setting its time when missing.
+func (b *Bus) Emit(event Event) {
+	if b == nil {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			b.logger().Warn("Event buffer of the subscriber is full, dropping the event",
+				"subscriber", sub.name, "type", event.Type, "common_name", event.CommonName)
+		}
+	}
+}
+
+// logger returns the Logger of the Bus, or the default one.
+func (b *Bus) logger() *slog.Logger {
+	if b.Logger != nil {
+		return b.Logger
+	}
+
+	return slog.Default()
+}
+
+// Close closes all the subscriptions: the queued events are still delivered to the subscribers.
+func (b *Bus) Close() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+
+	for sub := range b.subscribers {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Events returns the channel of the events, closed along with the subscription.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close unsubscribes from the Bus, closing the channel of the events.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		defer s.bus.mu.Unlock()
+
+		if _, ok := s.bus.subscribers[s]; ok {
+			delete(s.bus.subscribers, s)
+			close(s.events)
+		}
+	})
+}
@@ -0,0 +1,141 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package events fans out the certificate lifecycle events to the subscribers of a Bus, such as the publishers
+// delivering them to a message broker like Kafka or NATS, letting the platform pipelines consume them in real time.
+package events
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+)
+
+// Type is the kind of certificate lifecycle event.
+type Type string
+
+const (
+	// TypeIssued is the event of a signed certificate.
+	TypeIssued Type = "issued"
+	// TypeDenied is the event of a rejected certificate request.
+	TypeDenied Type = "denied"
+	// TypeRevoked is the event of a revoked certificate.
+	TypeRevoked Type = "revoked"
+	// TypeApprovalRequested is the event of a privileged certificate request held until approved.
+	TypeApprovalRequested Type = "approval-requested"
+	// TypeApproved is the event of an approval of a privileged certificate request, by the Approver.
+	TypeApproved Type = "approved"
+	// TypeCAReloaded is the event of a signing CA reloaded by the embedders, the Serial being the one of the new CA.
+	TypeCAReloaded Type = "ca-reloaded"
+)
+
+// Event is a certificate lifecycle event, published as JSON.
+type Event struct {
+	Type        Type         `json:"type"`
+	Time        time.Time    `json:"time"`
+	Serial      string       `json:"serial,omitempty"`
+	Fingerprint string       `json:"fingerprint,omitempty"`
+	SPKIHash    string       `json:"spkiSHA256,omitempty"`
+	CommonName  string       `json:"commonName,omitempty"`
+	NotAfter    *time.Time   `json:"notAfter,omitempty"`
+	Backend     string       `json:"backend,omitempty"`
+	Reason      string       `json:"reason,omitempty"`
+	Policy      string       `json:"policy,omitempty"`
+	Approval    string       `json:"approval,omitempty"`
+	Approver    string       `json:"approver,omitempty"`
+	Peer        *ledger.Peer `json:"peer,omitempty"`
+}
+
+// Sink delivers the events to a message broker.
+type Sink interface {
+	// Publish delivers the JSON encoded event, keyed by the given key.
+	Publish(ctx context.Context, key string, value []byte) error
+	// Close releases the connections to the broker.
+	Close() error
+}
+
+// SASL holds the credentials used to authenticate to the broker.
+type SASL struct {
+	// Mechanism is one of plain, scram-sha-256, or scram-sha-512: Kafka only, NATS always uses the user and password.
+	Mechanism string
+	Username  string
+	Password  string
+}
+
+// NewSink returns the Sink matching the URL scheme: kafka:// lists the comma separated brokers, nats:// the servers.
+// A nil TLS configuration disables TLS.
+func NewSink(sinkURL, topic string, tlsConfig *tls.Config, sasl SASL) (Sink, error) {
+	u, err := url.Parse(sinkURL)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrEventSinkURL, err.Error())
+	}
+
+	switch u.Scheme {
+	case "kafka":
+		return newKafka(u, topic, tlsConfig, sasl)
+	case "nats":
+		return newNATS(u, topic, tlsConfig, sasl)
+	default:
+		return nil, errors.Wrap(pkgerrors.ErrUnsupportedEventSink, u.Scheme)
+	}
+}
+
+// Publisher publishes the events of a Bus subscription to the Sink, so a slow or unreachable broker never delays
+// the issuance.
+type Publisher struct {
+	sink    Sink
+	sub     *Subscription
+	timeout time.Duration
+	done    chan struct{}
+}
+
+// NewPublisher subscribes to the Bus, buffering up to size events, each one delivered to the Sink within the timeout.
+func NewPublisher(bus *Bus, sink Sink, size int, timeout time.Duration) *Publisher {
+	p := &Publisher{
+		sink:    sink,
+		sub:     bus.Subscribe("publisher", size),
+		timeout: timeout,
+		done:    make(chan struct{}),
+	}
+
+	go p.run()
+
+	return p
+}
+
+func (p *Publisher) run() {
+	defer close(p.done)
+
+	for event := range p.sub.Events() {
+		value, err := json.Marshal(event)
+		if err != nil {
+			p.sub.bus.logger().Error("Failed to encode the event", "type", event.Type, "error", err)
+
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+
+		if err = p.sink.Publish(ctx, event.CommonName, value); err != nil {
+			p.sub.bus.logger().Error("Failed to publish the event", "type", event.Type, "common_name", event.CommonName,
+				"error", err)
+		}
+
+		cancel()
+	}
+}
+
+// Close unsubscribes from the Bus, flushes the queued events, and closes the sink.
+func (p *Publisher) Close() error {
+	p.sub.Close()
+	<-p.done
+
+	return p.sink.Close()
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"context"
+	"crypto/tls"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// Kafka is the Sink producing the events to a Kafka topic, partitioned by the key.
+type Kafka struct {
+	writer *kafka.Writer
+}
+
+func newKafka(u *url.URL, topic string, tlsConfig *tls.Config, credentials SASL) (*Kafka, error) {
+	var (
+		mechanism sasl.Mechanism
+		err       error
+	)
+
+	switch strings.ToLower(credentials.Mechanism) {
+	case "":
+	case "plain":
+		mechanism = plain.Mechanism{Username: credentials.Username, Password: credentials.Password}
+	case "scram-sha-256":
+		mechanism, err = scram.Mechanism(scram.SHA256, credentials.Username, credentials.Password)
+	case "scram-sha-512":
+		mechanism, err = scram.Mechanism(scram.SHA512, credentials.Username, credentials.Password)
+	default:
+		return nil, errors.Wrap(pkgerrors.ErrUnsupportedSASL, credentials.Mechanism)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrEventSink, err.Error())
+	}
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(strings.Split(u.Host, ",")...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		Transport: &kafka.Transport{
+			DialTimeout: 10 * time.Second,
+			TLS:         tlsConfig,
+			SASL:        mechanism,
+		},
+	}
+
+	return &Kafka{writer: writer}, nil
+}
+
+// Publish implements Sink.
+func (k *Kafka) Publish(ctx context.Context, key string, value []byte) error {
+	if err := k.writer.WriteMessages(ctx, kafka.Message{Key: []byte(key), Value: value}); err != nil {
+		return errors.Wrap(pkgerrors.ErrEventSink, err.Error())
+	}
+
+	return nil
+}
+
+// Close implements Sink.
+func (k *Kafka) Close() error {
+	return k.writer.Close() //nolint:wrapcheck
+}
@@ -0,0 +1,58 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"context"
+	"crypto/tls"
+	"net/url"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// NATS is the Sink publishing the events to a NATS subject.
+type NATS struct {
+	conn    *nats.Conn
+	subject string
+}
+
+func newNATS(u *url.URL, subject string, tlsConfig *tls.Config, sasl SASL) (*NATS, error) {
+	opts := []nats.Option{nats.Name("talos-csr-signer")}
+
+	if tlsConfig != nil {
+		opts = append(opts, nats.Secure(tlsConfig))
+	}
+
+	if sasl.Username != "" {
+		opts = append(opts, nats.UserInfo(sasl.Username, sasl.Password))
+	}
+
+	conn, err := nats.Connect(u.String(), opts...)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrEventSink, err.Error())
+	}
+
+	return &NATS{conn: conn, subject: subject}, nil
+}
+
+// Publish implements Sink: the key is not used by NATS.
+func (n *NATS) Publish(ctx context.Context, _ string, value []byte) error {
+	if err := n.conn.Publish(n.subject, value); err != nil {
+		return errors.Wrap(pkgerrors.ErrEventSink, err.Error())
+	}
+	// Wait for the server to acknowledge the published messages.
+	if err := n.conn.FlushWithContext(ctx); err != nil {
+		return errors.Wrap(pkgerrors.ErrEventSink, err.Error())
+	}
+
+	return nil
+}
+
+// Close implements Sink.
+func (n *NATS) Close() error {
+	return n.conn.Drain() //nolint:wrapcheck
+}
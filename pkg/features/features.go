@@ -0,0 +1,117 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package features contains the feature gates, letting risky subsystems ship disabled by default
+// and be enabled per deployment.
+package features
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// Feature is the name of a feature gate.
+type Feature string
+
+const (
+	// ApprovalQueue holds the certificate requests requiring a manual approval before being issued.
+	ApprovalQueue Feature = "ApprovalQueue"
+	// CRLServing serves the Certificate Revocation List of the issued certificates.
+	CRLServing Feature = "CRLServing"
+	// MultiTenantRouting routes the requests to the CA of the cluster they belong to.
+	MultiTenantRouting Feature = "MultiTenantRouting"
+	// HybridSigning adds a post-quantum alternative signature to the issued certificates.
+	HybridSigning Feature = "HybridSigning"
+)
+
+// Spec describes a feature gate.
+type Spec struct {
+	// Default is the state of the feature gate when not explicitly set.
+	Default bool
+	// Stage is the maturity of the feature: Alpha, Beta, or GA.
+	Stage string
+}
+
+// Known are the feature gates supported by the signer.
+var Known = map[Feature]Spec{
+	ApprovalQueue:      {Default: false, Stage: "Alpha"},
+	CRLServing:         {Default: false, Stage: "Alpha"},
+	MultiTenantRouting: {Default: false, Stage: "Alpha"},
+	HybridSigning:      {Default: false, Stage: "Alpha"},
+}
+
+// Gates holds the state of the feature gates. A nil Gates reports the defaults.
+type Gates struct {
+	enabled map[Feature]bool
+}
+
+// Parse returns the Gates from the comma separated list of Feature=bool pairs.
+func Parse(value string) (*Gates, error) {
+	g := &Gates{enabled: make(map[Feature]bool, len(Known))}
+
+	for feature, spec := range Known {
+		g.enabled[feature] = spec.Default
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, state, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, errors.Wrap(pkgerrors.ErrFeatureGate, pair)
+		}
+
+		feature := Feature(strings.TrimSpace(name))
+		if _, ok := Known[feature]; !ok {
+			return nil, errors.Wrap(pkgerrors.ErrUnknownFeatureGate, string(feature))
+		}
+
+		enabled, err := strconv.ParseBool(strings.TrimSpace(state))
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrFeatureGate, pair)
+		}
+
+		g.enabled[feature] = enabled
+	}
+
+	return g, nil
+}
+
+// Enabled returns true when the feature is enabled.
+func (g *Gates) Enabled(feature Feature) bool {
+	if g == nil {
+		return Known[feature].Default
+	}
+
+	return g.enabled[feature]
+}
+
+// All returns the state of every known feature gate.
+func (g *Gates) All() map[Feature]bool {
+	all := make(map[Feature]bool, len(Known))
+	for feature := range Known {
+		all[feature] = g.Enabled(feature)
+	}
+
+	return all
+}
+
+// Names returns the sorted names of the known feature gates, with their default and stage.
+func Names() []string {
+	names := make([]string, 0, len(Known))
+	for feature, spec := range Known {
+		names = append(names, string(feature)+"="+strconv.FormatBool(spec.Default)+" ("+spec.Stage+")")
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,281 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package identity verifies the cloud instance identity documents presented by the nodes as an additional
+// authentication factor: the AWS documents signed by the instance metadata service, and the GCP and Azure
+// identity tokens, restricting the issuance to the instances of the allowed accounts.
+package identity
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+const (
+	// ProviderMetadataKey is the metadata key of the cloud provider of the instance: aws, gcp, or azure.
+	ProviderMetadataKey = "x-instance-identity-provider"
+	// DocumentMetadataKey is the metadata key of the identity document: the base64 encoded AWS instance identity
+	// document, or the GCP and Azure identity token.
+	DocumentMetadataKey = "x-instance-identity"
+	// SignatureMetadataKey is the metadata key of the base64 encoded signature of the AWS instance identity document.
+	SignatureMetadataKey = "x-instance-identity-signature"
+)
+
+const (
+	// ProviderAWS is the Amazon Web Services provider.
+	ProviderAWS = "aws"
+	// ProviderGCP is the Google Cloud provider.
+	ProviderGCP = "gcp"
+	// ProviderAzure is the Microsoft Azure provider.
+	ProviderAzure = "azure"
+)
+
+const (
+	// DefaultGCPKeysURL is the URL of the keys signing the GCP identity tokens.
+	DefaultGCPKeysURL = "https://www.googleapis.com/oauth2/v3/certs"
+	// DefaultAzureKeysURL is the URL of the keys signing the Azure managed identity tokens.
+	DefaultAzureKeysURL = "https://login.microsoftonline.com/common/discovery/v2.0/keys"
+)
+
+// GCPIssuers are the issuers of the GCP identity tokens.
+var GCPIssuers = []string{"https://accounts.google.com", "accounts.google.com"}
+
+// AzureIssuers returns the issuers of the Azure managed identity tokens of the Microsoft Entra tenant, in the v1 and
+// v2 formats.
+func AzureIssuers(tenant string) []string {
+	return []string{"https://sts.windows.net/" + tenant + "/", "https://login.microsoftonline.com/" + tenant + "/v2.0"}
+}
+
+// Identity is the verified identity of a cloud instance.
+type Identity struct {
+	Provider string
+	Account  string
+	Instance string
+}
+
+// String returns the identity as provider:account:instance.
+func (i *Identity) String() string {
+	return i.Provider + ":" + i.Account + ":" + i.Instance
+}
+
+// Options configures the Verifier.
+type Options struct {
+	// Accounts are the allowed accounts as provider:account, such as aws:123456789012, gcp:my-project, or
+	// azure:<subscription ID>: the providers without any account are not accepted.
+	Accounts []string
+	// AWSCertificates holds the PEM encoded AWS public certificates of the regions the instances run in.
+	AWSCertificates []byte
+	// AWSMaxAge is the maximum time since the instance was launched, its pendingTime, the AWS documents are accepted
+	// for, bounding their replay: zero accepts them regardless.
+	AWSMaxAge time.Duration
+	// Audience is the audience the GCP and Azure identity tokens are requested for.
+	Audience string
+	// GCPKeysURL is the URL of the JSON Web Key Set signing the GCP identity tokens: empty uses DefaultGCPKeysURL.
+	GCPKeysURL string
+	// AzureKeysURL is the URL of the JSON Web Key Set signing the Azure identity tokens: empty uses
+	// DefaultAzureKeysURL.
+	AzureKeysURL string
+	// AzureTenants are the IDs of the Microsoft Entra tenants issuing the Azure identity tokens.
+	AzureTenants []string
+}
+
+// Verifier verifies the instance identity documents.
+type Verifier struct {
+	accounts  map[string][]string
+	aws       []*x509.Certificate
+	awsMaxAge time.Duration
+	audience  string
+	gcp       *KeySet
+	azure     *KeySet
+}
+
+// NewVerifier returns the Verifier of the options.
+func NewVerifier(opts Options) (*Verifier, error) {
+	v := &Verifier{accounts: make(map[string][]string), awsMaxAge: opts.AWSMaxAge, audience: opts.Audience}
+
+	for _, entry := range opts.Accounts {
+		provider, account, found := strings.Cut(entry, ":")
+		if !found || account == "" {
+			return nil, errors.Wrap(pkgerrors.ErrInstanceIdentity, "account must be provider:account: "+entry)
+		}
+
+		switch provider {
+		case ProviderAWS, ProviderGCP, ProviderAzure:
+			v.accounts[provider] = append(v.accounts[provider], account)
+		default:
+			return nil, errors.Wrap(pkgerrors.ErrInstanceIdentity, "unknown provider "+provider)
+		}
+	}
+
+	if len(v.accounts) == 0 {
+		return nil, errors.Wrap(pkgerrors.ErrInstanceIdentity, "no allowed account")
+	}
+
+	for rest := opts.AWSCertificates; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrInstanceIdentity, "invalid AWS certificate: "+err.Error())
+		}
+
+		v.aws = append(v.aws, cert)
+	}
+
+	if len(v.accounts[ProviderAWS]) > 0 && len(v.aws) == 0 {
+		return nil, errors.Wrap(pkgerrors.ErrInstanceIdentity, "AWS accounts require the AWS public certificates")
+	}
+
+	if (len(v.accounts[ProviderGCP]) > 0 || len(v.accounts[ProviderAzure]) > 0) && v.audience == "" {
+		return nil, errors.Wrap(pkgerrors.ErrInstanceIdentity, "GCP and Azure accounts require the token audience")
+	}
+
+	if len(v.accounts[ProviderAzure]) > 0 && len(opts.AzureTenants) == 0 {
+		return nil, errors.Wrap(pkgerrors.ErrInstanceIdentity, "Azure accounts require the tenants issuing the tokens")
+	}
+
+	var azureIssuers []string
+	for _, tenant := range opts.AzureTenants {
+		azureIssuers = append(azureIssuers, AzureIssuers(tenant)...)
+	}
+
+	gcpKeysURL, azureKeysURL := opts.GCPKeysURL, opts.AzureKeysURL
+	if gcpKeysURL == "" {
+		gcpKeysURL = DefaultGCPKeysURL
+	}
+
+	if azureKeysURL == "" {
+		azureKeysURL = DefaultAzureKeysURL
+	}
+
+	v.gcp = NewKeySet(gcpKeysURL, GCPIssuers)
+	v.azure = NewKeySet(azureKeysURL, azureIssuers)
+
+	return v, nil
+}
+
+// Verify returns the identity of the instance once its document is verified, and its account allowed.
+func (v *Verifier) Verify(ctx context.Context, provider, document string, signature []byte, now time.Time) (*Identity, error) {
+	var (
+		identity *Identity
+		err      error
+	)
+
+	switch provider {
+	case ProviderAWS:
+		identity, err = v.verifyAWS(document, signature, now)
+	case ProviderGCP:
+		identity, err = v.verifyGCP(ctx, document, now)
+	case ProviderAzure:
+		identity, err = v.verifyAzure(ctx, document, now)
+	default:
+		return nil, errors.Wrap(pkgerrors.ErrInstanceIdentity, "unknown provider "+provider)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(v.accounts[provider], identity.Account) {
+		return nil, errors.Wrapf(pkgerrors.ErrInstanceIdentity, "%s account %s not allowed", provider, identity.Account)
+	}
+
+	return identity, nil
+}
+
+// verifyAWS verifies the AWS instance identity document with the SHA-256 RSA signature of the instance metadata
+// service, signed by the key of the region certificate, and issued for an instance launched within the maximum age.
+func (v *Verifier) verifyAWS(document string, signature []byte, now time.Time) (*Identity, error) {
+	digest := sha256.Sum256([]byte(document))
+
+	verified := slices.ContainsFunc(v.aws, func(cert *x509.Certificate) bool {
+		key, ok := cert.PublicKey.(*rsa.PublicKey)
+
+		return ok && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	})
+	if !verified {
+		return nil, errors.Wrap(pkgerrors.ErrInstanceIdentity, "invalid AWS document signature")
+	}
+
+	var claims struct {
+		AccountID   string    `json:"accountId"`
+		InstanceID  string    `json:"instanceId"`
+		PendingTime time.Time `json:"pendingTime"`
+	}
+
+	if err := json.Unmarshal([]byte(document), &claims); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrInstanceIdentity, "invalid AWS document: "+err.Error())
+	}
+
+	if v.awsMaxAge > 0 {
+		switch {
+		case claims.PendingTime.IsZero():
+			return nil, errors.Wrap(pkgerrors.ErrInstanceIdentity, "AWS document without the pendingTime")
+		case now.Sub(claims.PendingTime) > v.awsMaxAge+clockLeeway:
+			return nil, errors.Wrapf(pkgerrors.ErrInstanceIdentity, "AWS document of an instance launched at %s, older than %s",
+				claims.PendingTime.Format(time.RFC3339), v.awsMaxAge)
+		case now.Add(clockLeeway).Before(claims.PendingTime):
+			return nil, errors.Wrap(pkgerrors.ErrInstanceIdentity, "AWS document of an instance launched in the future")
+		}
+	}
+
+	return &Identity{Provider: ProviderAWS, Account: claims.AccountID, Instance: claims.InstanceID}, nil
+}
+
+// verifyGCP verifies the GCP instance identity token, requested in the full format.
+func (v *Verifier) verifyGCP(ctx context.Context, token string, now time.Time) (*Identity, error) {
+	var claims struct {
+		Google struct {
+			ComputeEngine struct {
+				ProjectID  string `json:"project_id"`
+				InstanceID string `json:"instance_id"`
+			} `json:"compute_engine"`
+		} `json:"google"`
+	}
+
+	if err := v.gcp.Verify(ctx, token, v.audience, now, &claims); err != nil {
+		return nil, err
+	}
+
+	engine := claims.Google.ComputeEngine
+	if engine.ProjectID == "" {
+		return nil, errors.Wrap(pkgerrors.ErrInstanceIdentity, "GCP token without the instance details: request the full format")
+	}
+
+	return &Identity{Provider: ProviderGCP, Account: engine.ProjectID, Instance: engine.InstanceID}, nil
+}
+
+// verifyAzure verifies the Azure managed identity token of the virtual machine, whose resource ID holds the
+// subscription.
+func (v *Verifier) verifyAzure(ctx context.Context, token string, now time.Time) (*Identity, error) {
+	var claims struct {
+		ResourceID string `json:"xms_mirid"`
+	}
+
+	if err := v.azure.Verify(ctx, token, v.audience, now, &claims); err != nil {
+		return nil, err
+	}
+
+	// The resource ID is /subscriptions/<subscription>/resourceGroups/<group>/providers/Microsoft.Compute/virtualMachines/<name>
+	parts := strings.Split(strings.TrimPrefix(claims.ResourceID, "/"), "/")
+	if len(parts) < 2 || !strings.EqualFold(parts[0], "subscriptions") {
+		return nil, errors.Wrap(pkgerrors.ErrInstanceIdentity, "Azure token without the resource ID of a managed identity")
+	}
+
+	return &Identity{Provider: ProviderAzure, Account: parts[1], Instance: parts[len(parts)-1]}, nil
+}
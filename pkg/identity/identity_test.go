@@ -0,0 +1,224 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// awsCertificate returns the PEM encoded certificate of the key, standing for the AWS certificate of a region.
+func awsCertificate(t *testing.T, key *rsa.PrivateKey) []byte {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Amazon Web Services LLC"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestVerifyAWS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, err := NewVerifier(Options{
+		Accounts:        []string{"aws:123456789012"},
+		AWSCertificates: awsCertificate(t, key),
+		AWSMaxAge:       24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	document := func(account string, pendingTime time.Time) string {
+		claims := map[string]any{"accountId": account, "instanceId": "i-0123456789abcdef0", "region": "eu-west-1"}
+		if !pendingTime.IsZero() {
+			claims["pendingTime"] = pendingTime.Format(time.RFC3339)
+		}
+
+		data, _ := json.Marshal(claims)
+
+		return string(data)
+	}
+
+	sign := func(key *rsa.PrivateKey, document string) []byte {
+		digest := sha256.Sum256([]byte(document))
+
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return signature
+	}
+
+	tests := []struct {
+		name     string
+		document string
+		key      *rsa.PrivateKey
+		valid    bool
+	}{
+		{name: "launched an hour ago", document: document("123456789012", now.Add(-time.Hour)), key: key, valid: true},
+		{name: "launched at the maximum age", document: document("123456789012", now.Add(-24*time.Hour)), key: key, valid: true},
+		{name: "launched before the maximum age", document: document("123456789012", now.Add(-25*time.Hour)), key: key},
+		{name: "launched in the future", document: document("123456789012", now.Add(time.Hour)), key: key},
+		{name: "without pendingTime", document: document("123456789012", time.Time{}), key: key},
+		{name: "other account", document: document("210987654321", now.Add(-time.Hour)), key: key},
+		{name: "signed by another key", document: document("123456789012", now.Add(-time.Hour)), key: otherKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := verifier.Verify(t.Context(), ProviderAWS, tt.document, sign(tt.key, tt.document), now)
+
+			if !tt.valid {
+				if !errors.Is(err, pkgerrors.ErrInstanceIdentity) {
+					t.Fatalf("expected an invalid identity, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if identity.String() != "aws:123456789012:i-0123456789abcdef0" {
+				t.Fatalf("unexpected identity %s", identity)
+			}
+		})
+	}
+}
+
+func TestVerifyTokens(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fetches atomic.Int32
+
+	srv := keySetServer(t, "key-1", &key.PublicKey, &fetches)
+	now := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	tenant := "00000000-0000-0000-0000-000000000001"
+
+	verifier, err := NewVerifier(Options{
+		Accounts:     []string{"gcp:my-project", "azure:11111111-2222-3333-4444-555555555555"},
+		Audience:     audience,
+		GCPKeysURL:   srv.URL,
+		AzureKeysURL: srv.URL,
+		AzureTenants: []string{tenant},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := func(issuer string, claims map[string]any) string {
+		claims["iss"], claims["aud"], claims["exp"] = issuer, audience, now.Add(time.Hour).Unix()
+
+		return issue(t, key, map[string]any{"alg": "RS256", "kid": "key-1"}, claims)
+	}
+
+	gcp := func(project string) map[string]any {
+		return map[string]any{"google": map[string]any{"compute_engine": map[string]string{"project_id": project, "instance_id": "42"}}}
+	}
+
+	azure := func(subscription string) map[string]any {
+		return map[string]any{"xms_mirid": "/subscriptions/" + subscription + "/resourceGroups/nodes/providers/Microsoft.Compute/virtualMachines/worker-1"}
+	}
+
+	tests := []struct {
+		name     string
+		provider string
+		token    string
+		identity string
+	}{
+		{name: "GCP", provider: ProviderGCP, token: token(issuer, gcp("my-project")), identity: "gcp:my-project:42"},
+		{name: "GCP issuer without scheme", provider: ProviderGCP, token: token("accounts.google.com", gcp("my-project")), identity: "gcp:my-project:42"},
+		{name: "GCP other project", provider: ProviderGCP, token: token(issuer, gcp("other-project"))},
+		{name: "GCP token of the Azure issuer", provider: ProviderGCP, token: token("https://sts.windows.net/"+tenant+"/", gcp("my-project"))},
+		{name: "GCP standard format", provider: ProviderGCP, token: token(issuer, map[string]any{})},
+		{name: "Azure v1", provider: ProviderAzure, token: token("https://sts.windows.net/"+tenant+"/", azure("11111111-2222-3333-4444-555555555555")), identity: "azure:11111111-2222-3333-4444-555555555555:worker-1"},
+		{name: "Azure v2", provider: ProviderAzure, token: token("https://login.microsoftonline.com/"+tenant+"/v2.0", azure("11111111-2222-3333-4444-555555555555")), identity: "azure:11111111-2222-3333-4444-555555555555:worker-1"},
+		{name: "Azure other tenant", provider: ProviderAzure, token: token("https://sts.windows.net/00000000-0000-0000-0000-000000000002/", azure("11111111-2222-3333-4444-555555555555"))},
+		{name: "Azure token of the GCP issuer", provider: ProviderAzure, token: token(issuer, azure("11111111-2222-3333-4444-555555555555"))},
+		{name: "Azure without resource ID", provider: ProviderAzure, token: token("https://sts.windows.net/"+tenant+"/", map[string]any{})},
+		{name: "unknown provider", provider: "oci", token: token(issuer, gcp("my-project"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := verifier.Verify(t.Context(), tt.provider, tt.token, nil, now)
+
+			if tt.identity == "" {
+				if !errors.Is(err, pkgerrors.ErrInstanceIdentity) {
+					t.Fatalf("expected an invalid identity, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if identity.String() != tt.identity {
+				t.Fatalf("expected the identity %s, got %s", tt.identity, identity)
+			}
+		})
+	}
+}
+
+func TestNewVerifier(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "no account", opts: Options{}},
+		{name: "unknown provider", opts: Options{Accounts: []string{"oci:tenancy"}}},
+		{name: "account without provider", opts: Options{Accounts: []string{"123456789012"}}},
+		{name: "AWS without certificates", opts: Options{Accounts: []string{"aws:123456789012"}}},
+		{name: "GCP without audience", opts: Options{Accounts: []string{"gcp:my-project"}}},
+		{name: "Azure without tenants", opts: Options{Accounts: []string{"azure:subscription"}, Audience: audience}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewVerifier(tt.opts); !errors.Is(err, pkgerrors.ErrInstanceIdentity) {
+				t.Fatalf("expected invalid options, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/jwt"
+)
+
+// refreshInterval is the minimum time between two fetches of a key set, bounding the fetches triggered by the
+// tokens of unknown keys.
+const refreshInterval = 5 * time.Minute
+
+// clockLeeway is the clock skew tolerated on the token expiration and issuance time.
+const clockLeeway = time.Minute
+
+// KeySet is a JSON Web Key Set, fetched from its URL and refreshed when a token is signed by an unknown key, verifying
+// the tokens of its issuers.
+type KeySet struct {
+	url     string
+	issuers []string
+	client  *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// NewKeySet returns the KeySet served at the URL, accepting the tokens of the issuers only.
+func NewKeySet(url string, issuers []string) *KeySet {
+	return &KeySet{url: url, issuers: issuers, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Verify verifies the RS256 signed JSON Web Token, issued by one of the issuers for the audience and not expired,
+// decoding its claims.
+func (k *KeySet) Verify(ctx context.Context, token, audience string, now time.Time, claims any) error {
+	parsed, err := jwt.Parse(token)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrInstanceIdentity, err.Error())
+	}
+
+	if parsed.Algorithm != "RS256" {
+		return errors.Wrap(pkgerrors.ErrInstanceIdentity, "unsupported token algorithm "+parsed.Algorithm)
+	}
+
+	key, err := k.key(ctx, parsed.KeyID)
+	if err != nil {
+		return err
+	}
+
+	if err = parsed.Verify(key); err != nil {
+		return errors.Wrap(pkgerrors.ErrInstanceIdentity, err.Error())
+	}
+
+	var registered struct {
+		Issuer    string    `json:"iss"`
+		Audience  audiences `json:"aud"`
+		ExpiresAt int64     `json:"exp"`
+		IssuedAt  int64     `json:"iat"`
+	}
+
+	if err = parsed.Claims(&registered); err != nil {
+		return errors.Wrap(pkgerrors.ErrInstanceIdentity, err.Error())
+	}
+
+	switch {
+	case !slices.Contains(k.issuers, registered.Issuer):
+		return errors.Wrap(pkgerrors.ErrInstanceIdentity, "token not issued by a trusted issuer: "+registered.Issuer)
+	case !slices.Contains(registered.Audience, audience):
+		return errors.Wrap(pkgerrors.ErrInstanceIdentity, "token not issued for the audience "+audience)
+	case now.After(time.Unix(registered.ExpiresAt, 0).Add(clockLeeway)):
+		return errors.Wrap(pkgerrors.ErrInstanceIdentity, "token expired")
+	case now.Add(clockLeeway).Before(time.Unix(registered.IssuedAt, 0)):
+		return errors.Wrap(pkgerrors.ErrInstanceIdentity, "token issued in the future")
+	}
+
+	if err = parsed.Claims(claims); err != nil {
+		return errors.Wrap(pkgerrors.ErrInstanceIdentity, err.Error())
+	}
+
+	return nil
+}
+
+// key returns the key with the ID, fetching the key set when unknown.
+func (k *KeySet) key(ctx context.Context, id string) (*rsa.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if key, found := k.keys[id]; found {
+		return key, nil
+	}
+
+	if time.Since(k.fetchedAt) > refreshInterval {
+		if err := k.fetch(ctx); err != nil {
+			return nil, err
+		}
+
+		if key, found := k.keys[id]; found {
+			return key, nil
+		}
+	}
+
+	return nil, errors.Wrap(pkgerrors.ErrInstanceIdentity, "token signed by the unknown key "+id)
+}
+
+// fetch replaces the keys with the ones served at the URL.
+func (k *KeySet) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrInstanceIdentity, err.Error())
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrInstanceIdentity, "failed to fetch the token keys: "+err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Wrapf(pkgerrors.ErrInstanceIdentity, "failed to fetch the token keys: %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyID   string `json:"kid"`
+			Modulus string `json:"n"`
+			Exp     string `json:"e"`
+		} `json:"keys"`
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return errors.Wrap(pkgerrors.ErrInstanceIdentity, "invalid token keys: "+err.Error())
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+
+	for _, jwk := range set.Keys {
+		if jwk.KeyType != "RSA" {
+			continue
+		}
+
+		modulus, modulusErr := base64.RawURLEncoding.DecodeString(jwk.Modulus)
+		exponent, exponentErr := base64.RawURLEncoding.DecodeString(jwk.Exp)
+
+		if modulusErr != nil || exponentErr != nil || len(exponent) > 4 {
+			continue
+		}
+
+		keys[jwk.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(modulus),
+			E: int(new(big.Int).SetBytes(exponent).Int64()),
+		}
+	}
+
+	k.keys, k.fetchedAt = keys, time.Now()
+
+	return nil
+}
+
+// audiences is the aud claim, a single string or a list.
+type audiences []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *audiences) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audiences{single}
+
+		return nil
+	}
+
+	return json.Unmarshal(data, (*[]string)(a)) //nolint:wrapcheck
+}
@@ -0,0 +1,185 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+const (
+	issuer   = "https://accounts.google.com"
+	audience = "talos-csr-signer"
+)
+
+// issue returns the JSON Web Token of the claims signed by the key with the header.
+func issue(t *testing.T, key *rsa.PrivateKey, header, claims map[string]any) string {
+	t.Helper()
+
+	encode := func(value any) string {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	signingInput := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// tamper returns the token whose email claim is replaced, keeping the signature.
+func tamper(t *testing.T, token, email string) string {
+	t.Helper()
+
+	parts := strings.Split(token, ".")
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var claims map[string]any
+	if err = json.Unmarshal(data, &claims); err != nil {
+		t.Fatal(err)
+	}
+
+	claims["email"] = email
+	data, _ = json.Marshal(claims)
+
+	return parts[0] + "." + base64.RawURLEncoding.EncodeToString(data) + "." + parts[2]
+}
+
+// keySetServer serves the JSON Web Key Set of the key with the ID, counting the fetches.
+func keySetServer(t *testing.T, id string, key *rsa.PublicKey, fetches *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "EC", "kid": "ec", "crv": "P-256"},
+			{
+				"kty": "RSA",
+				"kid": id,
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			},
+		}})
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestKeySetVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fetches atomic.Int32
+
+	srv := keySetServer(t, "key-1", &key.PublicKey, &fetches)
+	now := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	claims := func(change func(map[string]any)) map[string]any {
+		claims := map[string]any{
+			"iss":   issuer,
+			"aud":   audience,
+			"iat":   now.Add(-time.Minute).Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+			"email": "node@example.com",
+		}
+
+		if change != nil {
+			change(claims)
+		}
+
+		return claims
+	}
+	header := map[string]any{"alg": "RS256", "kid": "key-1"}
+	valid := issue(t, key, header, claims(nil))
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{name: "valid token", token: valid, valid: true},
+		{name: "audience list", token: issue(t, key, header, claims(func(c map[string]any) { c["aud"] = []string{"other", audience} })), valid: true},
+		{name: "expired within the leeway", token: issue(t, key, header, claims(func(c map[string]any) { c["exp"] = now.Add(-30 * time.Second).Unix() })), valid: true},
+		{name: "other issuer", token: issue(t, key, header, claims(func(c map[string]any) { c["iss"] = "https://evil.example.com" }))},
+		{name: "no issuer", token: issue(t, key, header, claims(func(c map[string]any) { delete(c, "iss") }))},
+		{name: "other audience", token: issue(t, key, header, claims(func(c map[string]any) { c["aud"] = "other" }))},
+		{name: "expired", token: issue(t, key, header, claims(func(c map[string]any) { c["exp"] = now.Add(-2 * time.Minute).Unix() }))},
+		{name: "issued in the future", token: issue(t, key, header, claims(func(c map[string]any) { c["iat"] = now.Add(2 * time.Minute).Unix() }))},
+		{name: "other key", token: issue(t, otherKey, header, claims(nil))},
+		{name: "unknown key", token: issue(t, key, map[string]any{"alg": "RS256", "kid": "key-2"}, claims(nil))},
+		{name: "other algorithm", token: issue(t, key, map[string]any{"alg": "HS256", "kid": "key-1"}, claims(nil))},
+		{name: "no algorithm", token: issue(t, key, map[string]any{"alg": "none", "kid": "key-1"}, claims(nil))},
+		{name: "tampered claims", token: tamper(t, valid, "other@example.com")},
+		{name: "malformed", token: "header.claims"},
+	}
+
+	keys := NewKeySet(srv.URL, GCPIssuers)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decoded struct {
+				Email string `json:"email"`
+			}
+
+			err := keys.Verify(t.Context(), tt.token, audience, now, &decoded)
+
+			if !tt.valid {
+				if !errors.Is(err, pkgerrors.ErrInstanceIdentity) {
+					t.Fatalf("expected an invalid token, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if decoded.Email != "node@example.com" {
+				t.Fatalf("unexpected claims %+v", decoded)
+			}
+		})
+	}
+
+	// The key set is fetched once, the unknown key not refreshing it before the refresh interval
+	if fetches.Load() != 1 {
+		t.Fatalf("expected the key set to be fetched once, got %d", fetches.Load())
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package journal persists the pending and deferred work of the signer, such as in-flight signings,
+// so a restart doesn't silently drop node enrollments.
+package journal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// KindSigning is the kind of the entries tracking an in-flight certificate signing.
+const KindSigning = "signing"
+
+// Entry is a unit of pending work.
+type Entry struct {
+	ID        string          `json:"id"`
+	Kind      string          `json:"kind"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// Journal stores each pending Entry as a file in a directory, written atomically.
+// A nil Journal is valid and doesn't persist anything.
+type Journal struct {
+	dir string
+}
+
+// Open returns the Journal backed by the given directory, creating it when missing.
+func Open(dir string) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrJournal, err.Error())
+	}
+
+	return &Journal{dir: dir}, nil
+}
+
+// Add persists a new pending Entry of the given kind, returning its identifier.
+func (j *Journal) Add(kind string, payload any) (string, error) {
+	if j == nil {
+		return "", nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", errors.Wrap(pkgerrors.ErrJournal, err.Error())
+	}
+
+	random := make([]byte, 8)
+	if _, err = rand.Read(random); err != nil {
+		return "", errors.Wrap(pkgerrors.ErrJournal, err.Error())
+	}
+
+	entry := Entry{
+		ID:        kind + "-" + hex.EncodeToString(random),
+		Kind:      kind,
+		CreatedAt: time.Now(),
+		Payload:   data,
+	}
+
+	if data, err = json.Marshal(entry); err != nil {
+		return "", errors.Wrap(pkgerrors.ErrJournal, err.Error())
+	}
+
+	tmp := filepath.Join(j.dir, "."+entry.ID+".tmp")
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return "", errors.Wrap(pkgerrors.ErrJournal, err.Error())
+	}
+
+	if err = os.Rename(tmp, j.path(entry.ID)); err != nil {
+		return "", errors.Wrap(pkgerrors.ErrJournal, err.Error())
+	}
+
+	return entry.ID, nil
+}
+
+// Done removes the Entry with the given identifier, once its work has been completed.
+func (j *Journal) Done(id string) error {
+	if j == nil || id == "" {
+		return nil
+	}
+
+	if err := os.Remove(j.path(id)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(pkgerrors.ErrJournal, err.Error())
+	}
+
+	return nil
+}
+
+// Pending returns the entries of the given kind not completed yet, oldest first.
+func (j *Journal) Pending(kind string) ([]Entry, error) {
+	if j == nil {
+		return nil, nil
+	}
+
+	files, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrJournal, err.Error())
+	}
+
+	var entries []Entry
+
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), kind+"-") || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+
+		data, readErr := os.ReadFile(filepath.Join(j.dir, file.Name()))
+		if readErr != nil {
+			return nil, errors.Wrap(pkgerrors.ErrJournal, readErr.Error())
+		}
+
+		var entry Entry
+		if err = json.Unmarshal(data, &entry); err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrJournal, file.Name()+": "+err.Error())
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, k int) bool {
+		return entries[i].CreatedAt.Before(entries[k].CreatedAt)
+	})
+
+	return entries, nil
+}
+
+func (j *Journal) path(id string) string {
+	return filepath.Join(j.dir, id+".json")
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package jwt verifies the JSON Web Tokens, such as the instance identity documents and the signatures of the Open
+// Policy Agent bundles.
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// Token is a JSON Web Token in its compact serialization, its signature being verified by Verify.
+type Token struct {
+	// Algorithm is the signature algorithm of the header.
+	Algorithm string
+	// KeyID is the ID of the signing key of the header, empty when missing.
+	KeyID string
+
+	signingInput string
+	payload      string
+	signature    []byte
+}
+
+// Parse returns the Token of the header, the payload, and the signature, dot separated.
+func Parse(token string) (*Token, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.Wrap(pkgerrors.ErrJWT, "malformed token")
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrJWT, "malformed token signature")
+	}
+
+	return &Token{
+		Algorithm:    header.Algorithm,
+		KeyID:        header.KeyID,
+		signingInput: parts[0] + "." + parts[1],
+		payload:      parts[1],
+		signature:    signature,
+	}, nil
+}
+
+// Verify verifies the signature of the Token with the key: RS256, RS384, RS512, PS256, PS384, PS512, ES256, ES384, or
+// ES512.
+func (t *Token) Verify(key crypto.PublicKey) error {
+	var hash crypto.Hash
+
+	switch t.Algorithm[min(2, len(t.Algorithm)):] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return errors.Wrap(pkgerrors.ErrJWT, "unsupported signature algorithm "+t.Algorithm)
+	}
+
+	digester := hash.New()
+	digester.Write([]byte(t.signingInput))
+	digest := digester.Sum(nil)
+
+	var verified bool
+
+	switch public := key.(type) {
+	case *rsa.PublicKey:
+		switch t.Algorithm[:2] {
+		case "RS":
+			verified = rsa.VerifyPKCS1v15(public, hash, digest, t.signature) == nil
+		case "PS":
+			verified = rsa.VerifyPSS(public, hash, digest, t.signature, nil) == nil
+		}
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		if t.Algorithm[:2] == "ES" && len(t.signature) == 2*size {
+			r, s := new(big.Int).SetBytes(t.signature[:size]), new(big.Int).SetBytes(t.signature[size:])
+			verified = ecdsa.Verify(public, digest, r, s)
+		}
+	default:
+		return errors.Wrapf(pkgerrors.ErrJWT, "unsupported key type %T", key)
+	}
+
+	if !verified {
+		return errors.Wrap(pkgerrors.ErrJWT, "invalid token signature")
+	}
+
+	return nil
+}
+
+// Payload returns the decoded payload of the Token.
+func (t *Token) Payload() ([]byte, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(t.payload)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrJWT, "malformed token")
+	}
+
+	return payload, nil
+}
+
+// Claims decodes the JSON payload of the Token into the value.
+func (t *Token) Claims(value any) error {
+	return decodeSegment(t.payload, value)
+}
+
+// decodeSegment decodes the base64url encoded JSON segment of the token.
+func decodeSegment(segment string, value any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrJWT, "malformed token")
+	}
+
+	if err = json.Unmarshal(data, value); err != nil {
+		return errors.Wrap(pkgerrors.ErrJWT, "malformed token: "+err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"testing"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// sign returns the token of the claims signed by the key with the algorithm.
+func sign(t *testing.T, key crypto.Signer, algorithm, claims string) string {
+	t.Helper()
+
+	signingInput := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"`+algorithm+`","kid":"key-1"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(claims))
+
+	hash := map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}[algorithm[2:]]
+	digester := hash.New()
+	digester.Write([]byte(signingInput))
+	digest := digester.Sum(nil)
+
+	var (
+		signature []byte
+		err       error
+	)
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if algorithm[:2] == "PS" {
+			signature, err = rsa.SignPSS(rand.Reader, k, hash, digest, nil)
+		} else {
+			signature, err = rsa.SignPKCS1v15(rand.Reader, k, hash, digest)
+		}
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest)
+		if err == nil {
+			size := (k.Curve.Params().BitSize + 7) / 8
+			signature = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+		}
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		key   crypto.PublicKey
+		valid bool
+	}{
+		{name: "RS256", token: sign(t, rsaKey, "RS256", `{"sub":"worker-1"}`), key: &rsaKey.PublicKey, valid: true},
+		{name: "PS384", token: sign(t, rsaKey, "PS384", `{"sub":"worker-1"}`), key: &rsaKey.PublicKey, valid: true},
+		{name: "ES256", token: sign(t, ecKey, "ES256", `{"sub":"worker-1"}`), key: &ecKey.PublicKey, valid: true},
+		{name: "other key", token: sign(t, otherKey, "RS256", `{"sub":"worker-1"}`), key: &rsaKey.PublicKey},
+		{name: "algorithm of another key type", token: sign(t, rsaKey, "RS256", `{"sub":"worker-1"}`), key: &ecKey.PublicKey},
+		{name: "unsupported key", token: sign(t, rsaKey, "RS256", `{"sub":"worker-1"}`), key: "key"},
+		{name: "unsupported algorithm", token: base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + ".e30.", key: &rsaKey.PublicKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := Parse(tt.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = token.Verify(tt.key)
+			if !tt.valid {
+				if !errors.Is(err, pkgerrors.ErrJWT) {
+					t.Fatalf("expected the token to be refused, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var claims struct {
+				Subject string `json:"sub"`
+			}
+
+			if err = token.Claims(&claims); err != nil || claims.Subject != "worker-1" {
+				t.Fatalf("unexpected claims %+v: %v", claims, err)
+			}
+
+			if token.KeyID != "key-1" {
+				t.Fatalf("unexpected key ID %s", token.KeyID)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, token := range []string{"header.claims", "e30.e30.!", "!.e30.", "bm90IGpzb24.e30."} {
+		if _, err := Parse(token); !errors.Is(err, pkgerrors.ErrJWT) {
+			t.Fatalf("expected the token %s to be refused, got %v", token, err)
+		}
+	}
+}
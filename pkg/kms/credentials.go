@@ -0,0 +1,274 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package kms
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// imdsURL is the address of the EC2 instance metadata service.
+const imdsURL = "http://169.254.169.254"
+
+// refreshMargin is the time before their expiration the temporary credentials are renewed.
+const refreshMargin = 5 * time.Minute
+
+// Credentials are the AWS credentials signing the requests.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Expiration is the expiration of the temporary credentials, zero for the static ones.
+	Expiration time.Time
+}
+
+// CredentialsProvider returns the AWS credentials, renewing the temporary ones before they expire.
+type CredentialsProvider struct {
+	// Region is the region of the STS endpoint the web identity is exchanged with.
+	Region string
+	// Client sends the requests to STS and to the instance metadata service.
+	Client *http.Client
+	// File is the AWS shared credentials file, read at every request so the rotated keys are used: empty uses the
+	// environment variables and the instance role.
+	File string
+	// Profile is the profile of the shared credentials file: empty for the default one.
+	Profile string
+
+	mu     sync.Mutex
+	cached Credentials
+}
+
+// Retrieve returns the credentials of the shared credentials file when set, otherwise the ones of the standard AWS
+// environment variables, in order: the static AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, the web identity of
+// AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE such as the IAM Roles for Service Accounts, and the role of the EC2
+// instance.
+func (p *CredentialsProvider) Retrieve(ctx context.Context) (Credentials, error) {
+	if p.File != "" {
+		return p.sharedFile()
+	}
+
+	if accessKeyID := os.Getenv("AWS_ACCESS_KEY_ID"); accessKeyID != "" {
+		return Credentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cached.AccessKeyID != "" && time.Until(p.cached.Expiration) > refreshMargin {
+		return p.cached, nil
+	}
+
+	var (
+		creds Credentials
+		err   error
+	)
+
+	if roleARN, tokenFile := os.Getenv("AWS_ROLE_ARN"), os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"); roleARN != "" && tokenFile != "" {
+		creds, err = p.webIdentity(ctx, roleARN, tokenFile)
+	} else {
+		creds, err = p.instanceRole(ctx)
+	}
+
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	p.cached = creds
+
+	return creds, nil
+}
+
+// sharedFile returns the credentials of the profile of the INI shared credentials file.
+func (p *CredentialsProvider) sharedFile() (Credentials, error) {
+	data, err := os.ReadFile(p.File)
+	if err != nil {
+		return Credentials{}, errors.Wrap(pkgerrors.ErrKMS, "failed to read the AWS credentials: "+err.Error())
+	}
+
+	profile := p.Profile
+	if profile == "" {
+		profile = "default"
+	}
+
+	var (
+		creds   Credentials
+		section string
+	)
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+
+			continue
+		case section != profile:
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, "=")
+
+		switch strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			creds.SessionToken = strings.TrimSpace(value)
+		}
+	}
+
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return Credentials{}, errors.Wrap(pkgerrors.ErrKMS, "no credentials for the profile "+profile+" in "+p.File)
+	}
+
+	return creds, nil
+}
+
+// webIdentity exchanges the web identity token for the credentials of the role.
+func (p *CredentialsProvider) webIdentity(ctx context.Context, roleARN, tokenFile string) (Credentials, error) {
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return Credentials{}, errors.Wrap(pkgerrors.ErrKMS, "failed to read the web identity token: "+err.Error())
+	}
+
+	sessionName := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if sessionName == "" {
+		sessionName = "talos-csr-signer"
+	}
+
+	query := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {sessionName},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://sts."+p.Region+".amazonaws.com/",
+		strings.NewReader(query.Encode()))
+	if err != nil {
+		return Credentials{}, errors.Wrap(pkgerrors.ErrKMS, err.Error())
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	data, err := p.do(req)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	var response struct {
+		Credentials struct {
+			AccessKeyID     string    `xml:"AccessKeyId"`
+			SecretAccessKey string    `xml:"SecretAccessKey"`
+			SessionToken    string    `xml:"SessionToken"`
+			Expiration      time.Time `xml:"Expiration"`
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+
+	if err = xml.Unmarshal(data, &response); err != nil {
+		return Credentials{}, errors.Wrap(pkgerrors.ErrKMS, "invalid STS response: "+err.Error())
+	}
+
+	return Credentials(response.Credentials), nil
+}
+
+// instanceRole returns the credentials of the EC2 instance role, through the instance metadata service v2.
+func (p *CredentialsProvider) instanceRole(ctx context.Context) (Credentials, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, imdsURL+"/latest/api/token", nil)
+	if err != nil {
+		return Credentials{}, errors.Wrap(pkgerrors.ErrKMS, err.Error())
+	}
+
+	req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "300")
+
+	token, err := p.do(req)
+	if err != nil {
+		return Credentials{}, errors.Wrap(err, "no AWS credentials found")
+	}
+
+	get := func(path string) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, imdsURL+"/latest/meta-data/iam/security-credentials/"+path, nil)
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrKMS, err.Error())
+		}
+
+		req.Header.Set("X-Aws-Ec2-Metadata-Token", string(token))
+
+		return p.do(req)
+	}
+
+	role, err := get("")
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	data, err := get(strings.TrimSpace(strings.SplitN(string(role), "\n", 2)[0]))
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	var response struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+
+	if err = json.Unmarshal(data, &response); err != nil {
+		return Credentials{}, errors.Wrap(pkgerrors.ErrKMS, "invalid instance credentials: "+err.Error())
+	}
+
+	return Credentials{
+		AccessKeyID:     response.AccessKeyID,
+		SecretAccessKey: response.SecretAccessKey,
+		SessionToken:    response.Token,
+		Expiration:      response.Expiration,
+	}, nil
+}
+
+// do sends the request, returning the body of the successful responses.
+func (p *CredentialsProvider) do(req *http.Request) ([]byte, error) {
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrKMS, err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrKMS, err.Error())
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Wrap(pkgerrors.ErrKMS, req.URL.Host+": "+resp.Status)
+	}
+
+	return data, nil
+}
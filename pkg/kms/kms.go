@@ -0,0 +1,284 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package kms implements the crypto.Signer of a CA private key held by AWS KMS, signing the digests through the KMS
+// Sign API so the key never leaves KMS, along with the decryption of the secrets encrypted by KMS.
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// Options configures the requests to KMS.
+type Options struct {
+	// KeyID is the ARN, the ID, or the alias of the KMS key: the asymmetric one with the SIGN_VERIFY usage for the
+	// Signer, optional for Decrypt.
+	KeyID string
+	// Region is the region of the key: empty uses the one of the key ARN.
+	Region string
+	// Endpoint is the URL of the KMS API, such as a VPC endpoint: empty uses the regional one.
+	Endpoint string
+	// Timeout bounds each request to KMS, crypto.Signer carrying no context: zero disables it.
+	Timeout time.Duration
+	// Client sends the requests to KMS: nil uses http.DefaultClient.
+	Client *http.Client
+	// Credentials sign the requests: nil uses the standard AWS environment variables and the instance role.
+	Credentials *CredentialsProvider
+	// EncryptionContext is the encryption context the ciphertext of Decrypt was encrypted with, if any.
+	EncryptionContext map[string]string
+}
+
+// Signer is the crypto.Signer of the KMS key, its public key being fetched once and cached.
+type Signer struct {
+	opts      Options
+	publicKey crypto.PublicKey
+}
+
+// New returns the signer of the KMS key, fetching its public key.
+func New(ctx context.Context, opts Options) (*Signer, error) {
+	if opts.KeyID == "" {
+		return nil, errors.Wrap(pkgerrors.ErrKMS, "the key ID is required")
+	}
+
+	opts, err := defaults(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Signer{opts: opts}
+
+	var response struct {
+		PublicKey string `json:"PublicKey"`
+		KeyUsage  string `json:"KeyUsage"`
+	}
+
+	if err = call(ctx, opts, "GetPublicKey", map[string]any{"KeyId": opts.KeyID}, &response); err != nil {
+		return nil, err
+	}
+
+	if response.KeyUsage != "SIGN_VERIFY" {
+		return nil, errors.Wrap(pkgerrors.ErrKMS, "the key usage is "+response.KeyUsage+", expected SIGN_VERIFY")
+	}
+
+	der, err := base64.StdEncoding.DecodeString(response.PublicKey)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrKMS, "invalid public key: "+err.Error())
+	}
+
+	if s.publicKey, err = x509.ParsePKIXPublicKey(der); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrKMS, "invalid public key: "+err.Error())
+	}
+
+	return s, nil
+}
+
+// Public implements crypto.Signer, returning the cached public key.
+func (s *Signer) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+// Sign implements crypto.Signer, signing the digest with KMS.
+func (s *Signer) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	algorithm, err := s.algorithm(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+
+	if s.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, s.opts.Timeout)
+		defer cancel()
+	}
+
+	var response struct {
+		Signature string `json:"Signature"`
+	}
+
+	if err = call(ctx, s.opts, "Sign", map[string]any{
+		"KeyId":            s.opts.KeyID,
+		"Message":          digest,
+		"MessageType":      "DIGEST",
+		"SigningAlgorithm": algorithm,
+	}, &response); err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(response.Signature)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrKMS, "invalid signature: "+err.Error())
+	}
+
+	return signature, nil
+}
+
+// algorithm returns the KMS signing algorithm of the key type and the signer options.
+func (s *Signer) algorithm(opts crypto.SignerOpts) (string, error) {
+	var bits string
+
+	switch opts.HashFunc() {
+	case crypto.SHA256:
+		bits = "256"
+	case crypto.SHA384:
+		bits = "384"
+	case crypto.SHA512:
+		bits = "512"
+	default:
+		return "", errors.Wrap(pkgerrors.ErrKMS, "unsupported hash "+opts.HashFunc().String())
+	}
+
+	switch s.publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return "ECDSA_SHA_" + bits, nil
+	case *rsa.PublicKey:
+		if _, ok := opts.(*rsa.PSSOptions); ok {
+			return "RSASSA_PSS_SHA_" + bits, nil
+		}
+
+		return "RSASSA_PKCS1_V1_5_SHA_" + bits, nil
+	default:
+		return "", errors.Wrap(pkgerrors.ErrUnsupportedKey, fmt.Sprintf("KMS key %T", s.publicKey))
+	}
+}
+
+// Decrypt returns the plaintext of the ciphertext blob encrypted by KMS, the KeyID of the options being optional.
+func Decrypt(ctx context.Context, opts Options, ciphertext []byte) ([]byte, error) {
+	opts, err := defaults(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	request := map[string]any{"CiphertextBlob": ciphertext}
+	if opts.KeyID != "" {
+		request["KeyId"] = opts.KeyID
+	}
+
+	if len(opts.EncryptionContext) > 0 {
+		request["EncryptionContext"] = opts.EncryptionContext
+	}
+
+	var response struct {
+		Plaintext []byte `json:"Plaintext"`
+	}
+
+	if err = call(ctx, opts, "Decrypt", request, &response); err != nil {
+		return nil, err
+	}
+
+	return response.Plaintext, nil
+}
+
+// defaults returns the options completed with the region of the key ARN, the regional endpoint, the default client,
+// and the credentials of the environment.
+func defaults(opts Options) (Options, error) {
+	if opts.Region == "" {
+		opts.Region = regionOf(opts.KeyID)
+	}
+
+	if opts.Region == "" {
+		return opts, errors.Wrap(pkgerrors.ErrKMS, "the region is required when not in the key ARN")
+	}
+
+	if opts.Endpoint == "" {
+		opts.Endpoint = "https://kms." + opts.Region + ".amazonaws.com"
+	}
+
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+
+	if opts.Credentials == nil {
+		opts.Credentials = &CredentialsProvider{Region: opts.Region, Client: opts.Client}
+	}
+
+	return opts, nil
+}
+
+// call sends the action of the KMS API, decoding the response into out.
+func call(ctx context.Context, opts Options, action string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrKMS, err.Error())
+	}
+
+	creds, err := opts.Credentials.Retrieve(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(opts.Endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrKMS, err.Error())
+	}
+
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+
+	if err = SignV4(ctx, req, body, creds, opts.Region, "kms", time.Now()); err != nil {
+		return errors.Wrap(pkgerrors.ErrKMS, err.Error())
+	}
+
+	resp, err := opts.Client.Do(req)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrBackendTransient, "AWS KMS "+action+": "+err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrKMS, err.Error())
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// The throttled requests and the service failures may succeed later, unlike the rejected ones
+		cause := pkgerrors.ErrKMS
+		if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+			cause = pkgerrors.ErrBackendTransient
+		}
+
+		var failure struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+
+		if json.Unmarshal(data, &failure) == nil && failure.Type != "" {
+			return errors.Wrap(cause, fmt.Sprintf("%s %s: %s", action, failure.Type, failure.Message))
+		}
+
+		return errors.Wrap(cause, action+": "+resp.Status)
+	}
+
+	if err = json.Unmarshal(data, out); err != nil {
+		return errors.Wrap(pkgerrors.ErrKMS, action+": "+err.Error())
+	}
+
+	return nil
+}
+
+// regionOf returns the region of the key ARN, such as arn:aws:kms:eu-west-1:111122223333:key/..., empty otherwise.
+func regionOf(keyID string) string {
+	parts := strings.SplitN(keyID, ":", 6)
+	if len(parts) < 6 || parts[0] != "arn" || parts[2] != "kms" {
+		return ""
+	}
+
+	return parts[3]
+}
@@ -0,0 +1,26 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package kms
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// SignV4 signs the request with AWS Signature Version 4, the body being the payload sent along with it.
+func SignV4(ctx context.Context, req *http.Request, body []byte, creds Credentials, region, service string, now time.Time) error {
+	payloadHash := sha256.Sum256(body)
+
+	return v4.NewSigner().SignHTTP(ctx, aws.Credentials{ //nolint:wrapcheck
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+	}, req, hex.EncodeToString(payloadHash[:]), service, region, now)
+}
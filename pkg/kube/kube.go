@@ -0,0 +1,261 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package kube implements the in-cluster Kubernetes client reading and watching the Secrets holding the CA, such as
+// the tenant CA Secret rotated by Kamaji.
+package kube
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+// serviceAccountDir is the directory of the mounted service account credentials.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// errExpired is returned by the watches of an expired resource version, answered with 410 Gone.
+var errExpired = errors.Wrap(pkgerrors.ErrKubernetes, "resource version expired")
+
+// Secret is the Kubernetes Secret, its data being decoded.
+type Secret struct {
+	Metadata struct {
+		Namespace       string `json:"namespace"`
+		Name            string `json:"name"`
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Data map[string][]byte `json:"data"`
+}
+
+// Client is the Kubernetes API client of the pod service account.
+type Client struct {
+	// Host is the URL of the Kubernetes API server.
+	Host string
+	// TokenPath is the file of the service account token, read at every request so the projected ones are renewed.
+	TokenPath string
+	// HTTP sends the requests, verifying the API server with the service account CA.
+	HTTP *http.Client
+}
+
+// InCluster returns the client of the pod service account.
+func InCluster() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.Wrap(pkgerrors.ErrKubernetes, "not running in a cluster, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set")
+	}
+
+	caPEM, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrKubernetes, "failed to read the service account CA: "+err.Error())
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return nil, errors.Wrap(pkgerrors.ErrPemDecoding, "service account CA")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.TLSClientConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+
+	return &Client{
+		Host:      "https://" + net.JoinHostPort(host, port),
+		TokenPath: serviceAccountDir + "/token",
+		HTTP:      &http.Client{Transport: transport},
+	}, nil
+}
+
+// ParseRef returns the namespace and the name of the namespace/name reference.
+func ParseRef(ref string) (string, string, error) {
+	namespace, name, ok := strings.Cut(ref, "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", errors.Wrap(pkgerrors.ErrKubernetes, "invalid Secret reference "+ref+", expected namespace/name")
+	}
+
+	return namespace, name, nil
+}
+
+// GetSecret returns the Secret.
+func (c *Client) GetSecret(ctx context.Context, namespace, name string) (*Secret, error) {
+	resp, err := c.get(ctx, "/api/v1/namespaces/"+url.PathEscape(namespace)+"/secrets/"+url.PathEscape(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var secret Secret
+	if err = json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(&secret); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrKubernetes, "secret "+namespace+"/"+name+": "+err.Error())
+	}
+
+	return &secret, nil
+}
+
+// WatchSecret lists then watches the Secret until the context is done, like an informer: onChange is called with the
+// Secret at every new resource version, starting from the listed one. The watch is resumed from the last resource
+// version, kept current by the bookmarks, when closed by the API server, and the Secret listed again when the version
+// expired.
+func (c *Client) WatchSecret(ctx context.Context, namespace, name string, retryInterval time.Duration, onChange func(*Secret)) {
+	resourceVersion := ""
+
+	for ctx.Err() == nil {
+		if resourceVersion == "" {
+			secret, err := c.GetSecret(ctx, namespace, name)
+			if err != nil {
+				logging.FromContext(ctx).Warn("Failed to read the Secret", "namespace", namespace, "name", name, "error", err)
+				sleep(ctx, retryInterval)
+
+				continue
+			}
+
+			resourceVersion = secret.Metadata.ResourceVersion
+
+			onChange(secret)
+		}
+
+		var err error
+		if resourceVersion, err = c.watch(ctx, namespace, name, resourceVersion, onChange); err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).Warn("Failed to watch the Secret", "namespace", namespace, "name", name, "error", err)
+			sleep(ctx, retryInterval)
+		}
+	}
+}
+
+// watch streams the events of the Secret from the resource version, returning the last one seen: empty when expired.
+func (c *Client) watch(ctx context.Context, namespace, name, resourceVersion string, onChange func(*Secret)) (string, error) {
+	resp, err := c.get(ctx, "/api/v1/namespaces/"+url.PathEscape(namespace)+"/secrets", url.Values{
+		"watch":               {"true"},
+		"allowWatchBookmarks": {"true"},
+		"fieldSelector":       {"metadata.name=" + name},
+		"resourceVersion":     {resourceVersion},
+	})
+	if errors.Is(err, errExpired) {
+		return "", nil
+	} else if err != nil {
+		return resourceVersion, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	decoder := json.NewDecoder(bufio.NewReader(resp.Body))
+
+	for {
+		var event struct {
+			Type   string          `json:"type"`
+			Object json.RawMessage `json:"object"`
+		}
+
+		if err = decoder.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) {
+				return resourceVersion, nil
+			}
+
+			return resourceVersion, errors.Wrap(pkgerrors.ErrKubernetes, err.Error())
+		}
+
+		switch event.Type {
+		case "ADDED", "MODIFIED":
+			var secret Secret
+			if err = json.Unmarshal(event.Object, &secret); err != nil {
+				return resourceVersion, errors.Wrap(pkgerrors.ErrKubernetes, err.Error())
+			}
+
+			resourceVersion = secret.Metadata.ResourceVersion
+
+			onChange(&secret)
+		case "BOOKMARK":
+			var bookmark Secret
+			if err = json.Unmarshal(event.Object, &bookmark); err != nil {
+				return resourceVersion, errors.Wrap(pkgerrors.ErrKubernetes, err.Error())
+			}
+
+			resourceVersion = bookmark.Metadata.ResourceVersion
+		case "DELETED":
+			logging.FromContext(ctx).Warn("The Secret was deleted, keeping the previous CA", "namespace", namespace, "name", name)
+		case "ERROR":
+			var status struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			}
+
+			_ = json.Unmarshal(event.Object, &status)
+
+			if status.Code == http.StatusGone {
+				// The resource version expired: list the Secret again.
+				return "", nil
+			}
+
+			return resourceVersion, errors.Wrap(pkgerrors.ErrKubernetes, status.Message)
+		}
+	}
+}
+
+// get sends the GET request to the API server, returning the successful responses.
+func (c *Client) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	endpoint := strings.TrimSuffix(c.Host, "/") + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrKubernetes, err.Error())
+	}
+
+	if c.TokenPath != "" {
+		token, readErr := os.ReadFile(c.TokenPath)
+		if readErr != nil {
+			return nil, errors.Wrap(pkgerrors.ErrKubernetes, "failed to read the service account token: "+readErr.Error())
+		}
+
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrKubernetes, err.Error())
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+
+		if resp.StatusCode == http.StatusGone {
+			return nil, errExpired
+		}
+
+		return nil, errors.Wrap(pkgerrors.ErrKubernetes, fmt.Sprintf("%s: %s", path, resp.Status))
+	}
+
+	return resp, nil
+}
+
+// sleep waits for the duration or the context to be done.
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// secretJSON returns the Secret at the resource version, its data holding the version.
+func secretJSON(resourceVersion string) string {
+	return fmt.Sprintf(`{"metadata":{"namespace":"tenants","name":"ca","resourceVersion":%q},"data":{"version":"dg=="}}`, resourceVersion)
+}
+
+func TestParseRef(t *testing.T) {
+	namespace, name, err := ParseRef("tenants/ca")
+	if err != nil || namespace != "tenants" || name != "ca" {
+		t.Fatalf("unexpected reference %s/%s: %v", namespace, name, err)
+	}
+
+	for _, ref := range []string{"ca", "/ca", "tenants/", "tenants/ca/key"} {
+		if _, _, err = ParseRef(ref); !errors.Is(err, pkgerrors.ErrKubernetes) {
+			t.Fatalf("expected the reference %s to be rejected, got %v", ref, err)
+		}
+	}
+}
+
+func TestGetSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/tenants/secrets/ca" {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		_, _ = fmt.Fprint(w, secretJSON("7"))
+	}))
+	defer server.Close()
+
+	client := &Client{Host: server.URL, HTTP: server.Client()}
+
+	secret, err := client.GetSecret(t.Context(), "tenants", "ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if secret.Metadata.ResourceVersion != "7" || string(secret.Data["version"]) != "v" {
+		t.Fatalf("unexpected Secret %+v", secret)
+	}
+
+	if _, err = client.GetSecret(t.Context(), "tenants", "missing"); !errors.Is(err, pkgerrors.ErrKubernetes) {
+		t.Fatalf("expected the missing Secret to be refused, got %v", err)
+	}
+}
+
+func TestWatchSecret(t *testing.T) {
+	tests := []struct {
+		name string
+		// watches answer the successive watch requests, keyed by their resource version: the last one blocks.
+		watches  map[string]func(w http.ResponseWriter)
+		expected []string
+	}{
+		{
+			name: "resumed after closed",
+			watches: map[string]func(w http.ResponseWriter){
+				"1": func(w http.ResponseWriter) {
+					_, _ = fmt.Fprintf(w, `{"type":"MODIFIED","object":%s}`+"\n", secretJSON("2"))
+				},
+				"2": func(w http.ResponseWriter) {
+					_, _ = fmt.Fprint(w, `{"type":"BOOKMARK","object":{"metadata":{"resourceVersion":"3"}}}`+"\n")
+				},
+				"3": func(w http.ResponseWriter) {
+					_, _ = fmt.Fprintf(w, `{"type":"MODIFIED","object":%s}`+"\n", secretJSON("4"))
+				},
+			},
+			expected: []string{"1", "2", "4"},
+		},
+		{
+			name: "listed again after an expired event",
+			watches: map[string]func(w http.ResponseWriter){
+				"1": func(w http.ResponseWriter) {
+					_, _ = fmt.Fprint(w, `{"type":"ERROR","object":{"code":410,"message":"too old resource version"}}`+"\n")
+				},
+			},
+			expected: []string{"1", "1"},
+		},
+		{
+			name: "listed again after an expired watch",
+			watches: map[string]func(w http.ResponseWriter){
+				"1": func(w http.ResponseWriter) { w.WriteHeader(http.StatusGone) },
+			},
+			expected: []string{"1", "1"},
+		},
+		{
+			name: "retried after a failed watch",
+			watches: map[string]func(w http.ResponseWriter){
+				"1": func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+			},
+			expected: []string{"1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+
+			var (
+				mu      sync.Mutex
+				served  = make(map[string]int)
+				changes = make(chan string, 10)
+			)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("watch") != "true" {
+					_, _ = fmt.Fprint(w, secretJSON("1"))
+
+					return
+				}
+
+				if r.URL.Query().Get("fieldSelector") != "metadata.name=ca" {
+					http.Error(w, "unexpected field selector", http.StatusBadRequest)
+
+					return
+				}
+
+				resourceVersion := r.URL.Query().Get("resourceVersion")
+
+				mu.Lock()
+				served[resourceVersion]++
+				first := served[resourceVersion] == 1
+				mu.Unlock()
+
+				// Each watch is answered once, the next ones blocking as idle until the test is done
+				if answer, ok := tt.watches[resourceVersion]; ok && first {
+					answer(w)
+
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+
+				select {
+				case <-r.Context().Done():
+				case <-ctx.Done():
+				}
+			}))
+			defer server.Close()
+
+			client := &Client{Host: server.URL, HTTP: server.Client()}
+
+			done := make(chan struct{})
+
+			go func() {
+				defer close(done)
+
+				client.WatchSecret(ctx, "tenants", "ca", time.Millisecond, func(secret *Secret) {
+					changes <- secret.Metadata.ResourceVersion
+				})
+			}()
+
+			var versions []string
+
+			for len(versions) < len(tt.expected) {
+				select {
+				case version := <-changes:
+					versions = append(versions, version)
+				case <-time.After(5 * time.Second):
+					t.Fatalf("expected the resource versions %v, got %v", tt.expected, versions)
+				}
+			}
+
+			if !slices.Equal(versions, tt.expected) {
+				t.Fatalf("expected the resource versions %v, got %v", tt.expected, versions)
+			}
+
+			cancel()
+			<-done
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package ledger
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// File is the embedded Ledger: the state is kept in memory and the records are persisted
+// to a Snapshot file on every change. Quota counters and cached responses are not persisted.
+type File struct {
+	*Memory
+
+	path string
+	// mu serializes the writes of the snapshot file.
+	mu sync.Mutex
+}
+
+// NewFile returns the embedded Ledger persisted at the given path, loading the existing records.
+func NewFile(path string) (*File, error) {
+	f := &File{Memory: NewMemory(), path: path}
+
+	data, err := os.Open(path)
+
+	switch {
+	case os.IsNotExist(err):
+		return f, nil
+	case err != nil:
+		return nil, errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+	}
+
+	defer func() { _ = data.Close() }()
+
+	if _, err = Restore(context.Background(), f.Memory, data); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Store implements Ledger.
+func (f *File) Store(ctx context.Context, record Record) error {
+	_ = f.Memory.Store(ctx, record)
+
+	return f.persist(ctx)
+}
+
+// Delete implements Ledger.
+func (f *File) Delete(ctx context.Context, serial string) error {
+	_ = f.Memory.Delete(ctx, serial)
+
+	return f.persist(ctx)
+}
+
+// Revoke implements Ledger.
+func (f *File) Revoke(ctx context.Context, serial string, reason int, at time.Time) error {
+	if err := f.Memory.Revoke(ctx, serial, reason, at); err != nil {
+		return err
+	}
+
+	return f.persist(ctx)
+}
+
+func (f *File) persist(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return writeFileAtomic(f.path, func(w io.Writer) error {
+		_, err := Backup(ctx, f.Memory, w)
+
+		return err
+	})
+}
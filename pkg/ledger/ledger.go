@@ -0,0 +1,163 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ledger keeps the issuance state shared across signer replicas: serials, quotas, retry caches and revocations.
+package ledger
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// Peer is the identity of the client which requested a certificate.
+type Peer struct {
+	Address       string `json:"address"`
+	TLS           string `json:"tls,omitempty"`
+	ClientSubject string `json:"clientSubject,omitempty"`
+}
+
+// TransparencyEntry is the proof of an issued certificate published to a transparency log.
+type TransparencyEntry struct {
+	Log                  string          `json:"log"`
+	UUID                 string          `json:"uuid"`
+	LogIndex             int64           `json:"logIndex"`
+	IntegratedTime       int64           `json:"integratedTime"`
+	InclusionProof       json.RawMessage `json:"inclusionProof,omitempty"`
+	SignedEntryTimestamp string          `json:"signedEntryTimestamp,omitempty"`
+}
+
+// Approval is the approval of a privileged certificate by one of the approvers.
+type Approval struct {
+	Approver string    `json:"approver"`
+	Time     time.Time `json:"time"`
+}
+
+// Record is the ledger entry describing an issued certificate.
+type Record struct {
+	Serial           string             `json:"serial"`
+	Fingerprint      string             `json:"fingerprint,omitempty"`
+	SPKIHash         string             `json:"spkiSHA256,omitempty"`
+	CommonName       string             `json:"commonName"`
+	Organization     []string           `json:"organization,omitempty"`
+	DNSNames         []string           `json:"dnsNames,omitempty"`
+	IPAddresses      []string           `json:"ipAddresses,omitempty"`
+	NotBefore        time.Time          `json:"notBefore"`
+	NotAfter         time.Time          `json:"notAfter"`
+	Backend          string             `json:"backend,omitempty"`
+	Role             string             `json:"role,omitempty"`
+	Profile          string             `json:"profile,omitempty"`
+	NodeUUID         string             `json:"nodeUUID,omitempty"`
+	TokenClass       string             `json:"tokenClass,omitempty"`
+	AttestationKey   string             `json:"attestationKeySHA256,omitempty"`
+	InstanceIdentity string             `json:"instanceIdentity,omitempty"`
+	Transparency     *TransparencyEntry `json:"transparency,omitempty"`
+	Approvals        []Approval         `json:"approvals,omitempty"`
+	Peer             *Peer              `json:"peer,omitempty"`
+	RevokedAt        *time.Time         `json:"revokedAt,omitempty"`
+	RevocationReason int                `json:"revocationReason,omitempty"`
+}
+
+// NewRecord returns the Record of the certificate signed by the given backend.
+func NewRecord(cert *x509.Certificate, signedBy string) Record {
+	ips := make([]string, 0, len(cert.IPAddresses))
+	for _, ip := range cert.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	return Record{
+		Serial:       cert.SerialNumber.Text(16),
+		Fingerprint:  hex.EncodeToString(fingerprint[:]),
+		SPKIHash:     SPKIHash(cert),
+		CommonName:   cert.Subject.CommonName,
+		Organization: cert.Subject.Organization,
+		DNSNames:     cert.DNSNames,
+		IPAddresses:  ips,
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+		Backend:      signedBy,
+	}
+}
+
+// SPKIHash returns the base64 encoded SHA-256 hash of the certificate Subject Public Key Info, the pin format
+// of RFC 7469, stable across the renewals keeping the same key.
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Revoked returns true when the certificate has been revoked.
+func (r Record) Revoked() bool {
+	return r.RevokedAt != nil
+}
+
+// Ledger is the storage used by the server to keep track of the issuance state.
+// Implementations must be safe for concurrent use, and shared implementations must be
+// safe across multiple replicas.
+type Ledger interface {
+	// ReserveSerial atomically claims the given serial, returning false if it has been already claimed or issued.
+	// The reservation is kept apart from the records until the record of the serial is stored.
+	ReserveSerial(ctx context.Context, serial string) (bool, error)
+	// ReleaseSerial releases the reservation of the serial, such as after a failed issuance.
+	ReleaseSerial(ctx context.Context, serial string) error
+	// Store persists the issued certificate record.
+	Store(ctx context.Context, record Record) error
+	// Get returns the record for the given serial, or ErrLedgerNotFound.
+	Get(ctx context.Context, serial string) (Record, error)
+	// List returns all the issued certificate records.
+	List(ctx context.Context) ([]Record, error)
+	// Delete removes the record and the reservation of the given serial.
+	Delete(ctx context.Context, serial string) error
+	// Revoke marks the certificate with the given serial as revoked.
+	Revoke(ctx context.Context, serial string, reason int, at time.Time) error
+	// Increment increases the counter for the given key, starting a new window when it expired, returning the new value.
+	Increment(ctx context.Context, key string, window time.Duration) (int64, error)
+	// CacheResponse stores the response for the given key for the provided duration.
+	CacheResponse(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// CachedResponse returns the previously cached response for the given key, if any.
+	CachedResponse(ctx context.Context, key string) ([]byte, bool, error)
+	// Heartbeat registers the member as alive for the given duration.
+	Heartbeat(ctx context.Context, member string, ttl time.Duration) error
+	// Members returns the members with a heartbeat not expired yet.
+	Members(ctx context.Context) ([]string, error)
+	// Close releases the resources held by the ledger.
+	Close() error
+}
+
+// New returns the Ledger matching the URL scheme: memory:// keeps the state in the process,
+// file:// persists it to a local file, redis:// and rediss:// share it through a Redis (or compatible) server.
+func New(ledgerURL, keyPrefix string) (Ledger, error) {
+	u, err := url.Parse(ledgerURL)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrLedgerURL, err.Error())
+	}
+
+	switch u.Scheme {
+	case "", "memory":
+		return NewMemory(), nil
+	case "file":
+		return NewFile(u.Path)
+	case "redis", "rediss":
+		opts, optsErr := redis.ParseURL(ledgerURL)
+		if optsErr != nil {
+			return nil, errors.Wrap(pkgerrors.ErrLedgerURL, optsErr.Error())
+		}
+
+		return NewRedis(redis.NewClient(opts), keyPrefix), nil
+	default:
+		return nil, errors.Wrap(pkgerrors.ErrUnsupportedLedger, u.Scheme)
+	}
+}
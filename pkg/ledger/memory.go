@@ -0,0 +1,287 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package ledger
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+type counter struct {
+	value     int64
+	expiresAt time.Time
+}
+
+type cachedResponse struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+const (
+	// maxCachedResponses bounds the cached responses: once reached, the ones expiring first are evicted.
+	maxCachedResponses = 10000
+	// sweepInterval is the interval the expired counters and cached responses are removed at, on the next write.
+	sweepInterval = time.Minute
+)
+
+// Memory is the in-process Ledger, suitable for single replica deployments.
+type Memory struct {
+	mu        sync.Mutex
+	records   map[string]Record
+	reserved  map[string]struct{}
+	counters  map[string]counter
+	responses map[string]cachedResponse
+	members   map[string]time.Time
+	sweptAt   time.Time
+}
+
+// NewMemory returns an empty in-process Ledger.
+func NewMemory() *Memory {
+	return &Memory{
+		records:   make(map[string]Record),
+		reserved:  make(map[string]struct{}),
+		counters:  make(map[string]counter),
+		responses: make(map[string]cachedResponse),
+		members:   make(map[string]time.Time),
+	}
+}
+
+// ReserveSerial implements Ledger.
+func (m *Memory) ReserveSerial(_ context.Context, serial string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.records[serial]; ok {
+		return false, nil
+	}
+
+	if _, ok := m.reserved[serial]; ok {
+		return false, nil
+	}
+
+	m.reserved[serial] = struct{}{}
+
+	return true, nil
+}
+
+// ReleaseSerial implements Ledger.
+func (m *Memory) ReleaseSerial(_ context.Context, serial string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.reserved, serial)
+
+	return nil
+}
+
+// Store implements Ledger.
+func (m *Memory) Store(_ context.Context, record Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.records[record.Serial] = record
+	delete(m.reserved, record.Serial)
+
+	return nil
+}
+
+// Get implements Ledger.
+func (m *Memory) Get(_ context.Context, serial string) (Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, ok := m.records[serial]
+	if !ok {
+		return Record{}, pkgerrors.ErrLedgerNotFound
+	}
+
+	return record, nil
+}
+
+// List implements Ledger.
+func (m *Memory) List(_ context.Context) ([]Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	records := make([]Record, 0, len(m.records))
+	for _, record := range m.records {
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].NotBefore.Before(records[j].NotBefore)
+	})
+
+	return records, nil
+}
+
+// Delete implements Ledger.
+func (m *Memory) Delete(_ context.Context, serial string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, serial)
+	delete(m.reserved, serial)
+
+	return nil
+}
+
+// Revoke implements Ledger.
+func (m *Memory) Revoke(_ context.Context, serial string, reason int, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, ok := m.records[serial]
+	if !ok {
+		return pkgerrors.ErrLedgerNotFound
+	}
+
+	record.RevokedAt = &at
+	record.RevocationReason = reason
+	m.records[serial] = record
+
+	return nil
+}
+
+// Increment implements Ledger.
+func (m *Memory) Increment(_ context.Context, key string, window time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+
+	c, ok := m.counters[key]
+	if !ok || now.After(c.expiresAt) {
+		c = counter{expiresAt: now.Add(window)}
+	}
+
+	c.value++
+	m.counters[key] = c
+
+	return c.value, nil
+}
+
+// CacheResponse implements Ledger.
+func (m *Memory) CacheResponse(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+
+	if _, ok := m.responses[key]; !ok && len(m.responses) >= maxCachedResponses {
+		m.evictResponse(now)
+	}
+
+	m.responses[key] = cachedResponse{value: value, expiresAt: now.Add(ttl)}
+
+	return nil
+}
+
+// sweep removes the expired counters and cached responses, at most once per sweep interval.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.sweptAt) < sweepInterval {
+		return
+	}
+
+	m.sweptAt = now
+
+	for key, c := range m.counters {
+		if now.After(c.expiresAt) {
+			delete(m.counters, key)
+		}
+	}
+
+	for key, response := range m.responses {
+		if now.After(response.expiresAt) {
+			delete(m.responses, key)
+		}
+	}
+}
+
+// evictResponse removes the expired cached responses, or the one expiring first when none expired.
+func (m *Memory) evictResponse(now time.Time) {
+	var (
+		first     string
+		expiresAt time.Time
+	)
+
+	for key, response := range m.responses {
+		if now.After(response.expiresAt) {
+			delete(m.responses, key)
+
+			continue
+		}
+
+		if first == "" || response.expiresAt.Before(expiresAt) {
+			first, expiresAt = key, response.expiresAt
+		}
+	}
+
+	if len(m.responses) >= maxCachedResponses {
+		delete(m.responses, first)
+	}
+}
+
+// CachedResponse implements Ledger.
+func (m *Memory) CachedResponse(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	response, ok := m.responses[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	if time.Now().After(response.expiresAt) {
+		delete(m.responses, key)
+
+		return nil, false, nil
+	}
+
+	return response.value, true, nil
+}
+
+// Heartbeat implements Ledger.
+func (m *Memory) Heartbeat(_ context.Context, member string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.members[member] = time.Now().Add(ttl)
+
+	return nil
+}
+
+// Members implements Ledger.
+func (m *Memory) Members(_ context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	members := make([]string, 0, len(m.members))
+
+	for member, expiresAt := range m.members {
+		if now.After(expiresAt) {
+			delete(m.members, member)
+
+			continue
+		}
+
+		members = append(members, member)
+	}
+
+	sort.Strings(members)
+
+	return members, nil
+}
+
+// Close implements Ledger.
+func (m *Memory) Close() error {
+	return nil
+}
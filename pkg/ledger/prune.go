@@ -0,0 +1,59 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package ledger
+
+import (
+	"context"
+	"time"
+)
+
+// Retention is the policy bounding the growth of the ledger.
+type Retention struct {
+	// MaxAge is the time a record is kept after the certificate expiration: zero keeps them regardless of the age.
+	MaxAge time.Duration
+	// MaxRecords is the number of records above which the oldest prunable ones are removed: zero disables it.
+	MaxRecords int
+}
+
+// prunable returns true when the record can be safely removed: records of certificates still valid
+// and of revoked ones are never pruned.
+func prunable(record Record, now time.Time) bool {
+	return !record.Revoked() && now.After(record.NotAfter)
+}
+
+// Prune removes the records exceeding the retention policy, returning the number of removed records.
+func Prune(ctx context.Context, l Ledger, retention Retention) (int, error) {
+	records, err := l.List(ctx)
+	if err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+
+	now := time.Now()
+	// Records are sorted by issuance: the oldest are pruned first when above the maximum size.
+	exceeding := 0
+	if retention.MaxRecords > 0 {
+		exceeding = max(len(records)-retention.MaxRecords, 0)
+	}
+
+	pruned := 0
+
+	for _, record := range records {
+		if !prunable(record, now) {
+			continue
+		}
+
+		expired := retention.MaxAge > 0 && now.After(record.NotAfter.Add(retention.MaxAge))
+		if !expired && pruned >= exceeding {
+			continue
+		}
+
+		if err = l.Delete(ctx, record.Serial); err != nil {
+			return pruned, err //nolint:wrapcheck
+		}
+
+		pruned++
+	}
+
+	return pruned, nil
+}
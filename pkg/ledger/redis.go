@@ -0,0 +1,268 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// Redis is the Ledger backed by a Redis (or compatible) server, allowing multiple
+// stateless replicas to share the same issuance state.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedis returns a Ledger storing its keys in the given client, namespaced by the prefix.
+func NewRedis(client *redis.Client, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix}
+}
+
+func (r *Redis) key(parts ...string) string {
+	key := r.prefix
+	for _, part := range parts {
+		key += ":" + part
+	}
+
+	return key
+}
+
+// reserveSerial claims the serial in the set of the reserved ones (KEYS[2]), unless already issued (KEYS[1]).
+var reserveSerial = redis.NewScript(`
+if redis.call("SISMEMBER", KEYS[1], ARGV[1]) == 1 then
+  return 0
+end
+return redis.call("SADD", KEYS[2], ARGV[1])
+`)
+
+// ReserveSerial implements Ledger.
+func (r *Redis) ReserveSerial(ctx context.Context, serial string) (bool, error) {
+	added, err := reserveSerial.Run(ctx, r.client, []string{r.key("serials"), r.key("reserved")}, serial).Int()
+	if err != nil {
+		return false, errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+	}
+
+	return added == 1, nil
+}
+
+// ReleaseSerial implements Ledger.
+func (r *Redis) ReleaseSerial(ctx context.Context, serial string) error {
+	if err := r.client.SRem(ctx, r.key("reserved"), serial).Err(); err != nil {
+		return errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+	}
+
+	return nil
+}
+
+// Store implements Ledger.
+func (r *Redis) Store(ctx context.Context, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, r.key("record", record.Serial), data, 0)
+		pipe.SAdd(ctx, r.key("serials"), record.Serial)
+		pipe.SRem(ctx, r.key("reserved"), record.Serial)
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+	}
+
+	return nil
+}
+
+// Get implements Ledger.
+func (r *Redis) Get(ctx context.Context, serial string) (Record, error) {
+	data, err := r.client.Get(ctx, r.key("record", serial)).Bytes()
+
+	switch {
+	case errors.Is(err, redis.Nil):
+		return Record{}, pkgerrors.ErrLedgerNotFound
+	case err != nil:
+		return Record{}, errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+	}
+
+	var record Record
+	if err = json.Unmarshal(data, &record); err != nil {
+		return Record{}, errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+	}
+
+	return record, nil
+}
+
+// List implements Ledger.
+func (r *Redis) List(ctx context.Context) ([]Record, error) {
+	serials, err := r.client.SMembers(ctx, r.key("serials")).Result()
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+	}
+
+	records := make([]Record, 0, len(serials))
+
+	for _, serial := range serials {
+		record, getErr := r.Get(ctx, serial)
+
+		switch {
+		case errors.Is(getErr, pkgerrors.ErrLedgerNotFound):
+			// Serial reserved by a previous version, which kept the reservations among the issued ones.
+			continue
+		case getErr != nil:
+			return nil, getErr
+		}
+
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].NotBefore.Before(records[j].NotBefore)
+	})
+
+	return records, nil
+}
+
+// Delete implements Ledger.
+func (r *Redis) Delete(ctx context.Context, serial string) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, r.key("record", serial))
+		pipe.SRem(ctx, r.key("serials"), serial)
+		pipe.SRem(ctx, r.key("reserved"), serial)
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+	}
+
+	return nil
+}
+
+// Revoke implements Ledger.
+func (r *Redis) Revoke(ctx context.Context, serial string, reason int, at time.Time) error {
+	key := r.key("record", serial)
+
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+
+		switch {
+		case errors.Is(err, redis.Nil):
+			return pkgerrors.ErrLedgerNotFound
+		case err != nil:
+			return errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+		}
+
+		var record Record
+		if err = json.Unmarshal(data, &record); err != nil {
+			return errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+		}
+
+		record.RevokedAt = &at
+		record.RevocationReason = reason
+
+		if data, err = json.Marshal(record); err != nil {
+			return errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, 0)
+
+			return nil
+		})
+
+		return err //nolint:wrapcheck
+	}, key)
+	if err != nil && !errors.Is(err, pkgerrors.ErrLedgerNotFound) && !errors.Is(err, pkgerrors.ErrLedgerBackend) {
+		return errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+	}
+
+	return err //nolint:wrapcheck
+}
+
+// Increment implements Ledger.
+func (r *Redis) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	counterKey := r.key("counter", key)
+
+	var incr *redis.IntCmd
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, counterKey)
+		pipe.ExpireNX(ctx, counterKey, window)
+
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+	}
+
+	return incr.Val(), nil
+}
+
+// CacheResponse implements Ledger.
+func (r *Redis) CacheResponse(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := r.client.Set(ctx, r.key("response", key), value, ttl).Err(); err != nil {
+		return errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+	}
+
+	return nil
+}
+
+// CachedResponse implements Ledger.
+func (r *Redis) CachedResponse(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, r.key("response", key)).Bytes()
+
+	switch {
+	case errors.Is(err, redis.Nil):
+		return nil, false, nil
+	case err != nil:
+		return nil, false, errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+	}
+
+	return value, true, nil
+}
+
+// Heartbeat implements Ledger.
+func (r *Redis) Heartbeat(ctx context.Context, member string, ttl time.Duration) error {
+	expiresAt := float64(time.Now().Add(ttl).UnixMilli())
+
+	if err := r.client.ZAdd(ctx, r.key("members"), redis.Z{Score: expiresAt, Member: member}).Err(); err != nil {
+		return errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+	}
+
+	return nil
+}
+
+// Members implements Ledger.
+func (r *Redis) Members(ctx context.Context) ([]string, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	if err := r.client.ZRemRangeByScore(ctx, r.key("members"), "-inf", now).Err(); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+	}
+
+	members, err := r.client.ZRange(ctx, r.key("members"), 0, -1).Result()
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrLedgerBackend, err.Error())
+	}
+
+	sort.Strings(members)
+
+	return members, nil
+}
+
+// Close implements Ledger.
+func (r *Redis) Close() error {
+	return r.client.Close() //nolint:wrapcheck
+}
@@ -0,0 +1,184 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// snapshotVersion is the version of the Snapshot format.
+const snapshotVersion = 1
+
+// Snapshot is the portable representation of the ledger records, used to back up, restore,
+// and migrate the issuance history and revocation state between backends.
+type Snapshot struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Records   []Record  `json:"records"`
+}
+
+// Backup writes the Snapshot of the given ledger records.
+func Backup(ctx context.Context, l Ledger, w io.Writer) (int, error) {
+	records, err := l.List(ctx)
+	if err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err = encoder.Encode(Snapshot{Version: snapshotVersion, CreatedAt: time.Now().UTC(), Records: records}); err != nil {
+		return 0, errors.Wrap(pkgerrors.ErrLedgerSnapshot, err.Error())
+	}
+
+	return len(records), nil
+}
+
+// Restore imports the records of the Snapshot into the given ledger, overwriting the ones with the same serial.
+func Restore(ctx context.Context, l Ledger, r io.Reader) (int, error) {
+	snapshot, err := readSnapshot(r)
+	if err != nil {
+		return 0, err
+	}
+
+	return restore(ctx, l, snapshot.Records)
+}
+
+// Replicate mirrors the records of the Snapshot into the given ledger, deleting the ones missing from the Snapshot,
+// such as the records pruned from the source. It returns the number of records stored, and of the ones deleted.
+func Replicate(ctx context.Context, l Ledger, r io.Reader) (int, int, error) {
+	snapshot, err := readSnapshot(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	stored, err := restore(ctx, l, snapshot.Records)
+	if err != nil {
+		return stored, 0, err
+	}
+
+	current := make(map[string]struct{}, len(snapshot.Records))
+	for _, record := range snapshot.Records {
+		current[record.Serial] = struct{}{}
+	}
+
+	records, err := l.List(ctx)
+	if err != nil {
+		return stored, 0, err //nolint:wrapcheck
+	}
+
+	var deleted int
+
+	for _, record := range records {
+		if _, found := current[record.Serial]; found {
+			continue
+		}
+
+		if err = l.Delete(ctx, record.Serial); err != nil {
+			return stored, deleted, err //nolint:wrapcheck
+		}
+
+		deleted++
+	}
+
+	return stored, deleted, nil
+}
+
+// readSnapshot decodes the Snapshot, checking its version.
+func readSnapshot(r io.Reader) (Snapshot, error) {
+	var snapshot Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return snapshot, errors.Wrap(pkgerrors.ErrLedgerSnapshot, err.Error())
+	}
+
+	if snapshot.Version != snapshotVersion {
+		return snapshot, errors.Wrapf(pkgerrors.ErrLedgerSnapshot, "unsupported version %d", snapshot.Version)
+	}
+
+	return snapshot, nil
+}
+
+// restore stores the records, reserving their serials.
+func restore(ctx context.Context, l Ledger, records []Record) (int, error) {
+	for i, record := range records {
+		if _, err := l.ReserveSerial(ctx, record.Serial); err != nil {
+			return i, err //nolint:wrapcheck
+		}
+
+		if err := l.Store(ctx, record); err != nil {
+			return i, err //nolint:wrapcheck
+		}
+	}
+
+	return len(records), nil
+}
+
+// WriteSnapshot backs up the ledger into a timestamped file of the given directory,
+// keeping only the most recent retain snapshots.
+func WriteSnapshot(ctx context.Context, l Ledger, dir string, retain int) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", errors.Wrap(pkgerrors.ErrLedgerSnapshot, err.Error())
+	}
+
+	path := filepath.Join(dir, "ledger-"+time.Now().UTC().Format("20060102T150405Z")+".json")
+
+	if err := writeFileAtomic(path, func(w io.Writer) error {
+		_, err := Backup(ctx, l, w)
+
+		return err
+	}); err != nil {
+		return "", err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "ledger-*.json"))
+	if err != nil {
+		return "", errors.Wrap(pkgerrors.ErrLedgerSnapshot, err.Error())
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+
+	for _, file := range files[min(max(retain, 1), len(files)):] {
+		if err = os.Remove(file); err != nil {
+			return "", errors.Wrap(pkgerrors.ErrLedgerSnapshot, err.Error())
+		}
+	}
+
+	return path, nil
+}
+
+// writeFileAtomic writes the file content in a temporary file renamed once complete.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))+"-*.tmp")
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrLedgerSnapshot, err.Error())
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err = write(tmp); err != nil {
+		_ = tmp.Close()
+
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errors.Wrap(pkgerrors.ErrLedgerSnapshot, err.Error())
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrap(pkgerrors.ErrLedgerSnapshot, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package logging threads the request-scoped structured logger through the context.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of the context carrying the logger.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by the context, or the default one outside a request.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok && logger != nil {
+		return logger
+	}
+
+	return slog.Default()
+}
+
+// NewRequestID returns a random identifier correlating the log lines of a request.
+func NewRequestID() string {
+	id := make([]byte, 8)
+	// crypto/rand never fails on the supported platforms.
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,56 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package metrics contains the Prometheus metrics of the signer, served by the admin API.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "talos_csr_signer"
+
+var (
+	// Registry is the registry of the signer metrics.
+	Registry = prometheus.NewRegistry()
+
+	// FeatureEnabled reports the state of the feature gates.
+	FeatureEnabled = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "feature_enabled",
+		Help:      "Whether the feature gate is enabled (1) or disabled (0).",
+	}, []string{"name", "stage"})
+
+	// BuildInfo reports the build metadata of the signer, always set to 1.
+	BuildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "build_info",
+		Help:      "Build metadata of the signer, always set to 1.",
+	}, []string{"version", "git_commit", "build_date", "go_version"})
+
+	// PolicyVerdicts counts the verdicts of the policy validators on the CSRs.
+	PolicyVerdicts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "policy_verdicts_total",
+		Help:      "Verdicts of the policy validators on the CSRs, by validator and outcome: allow, deny, or error.",
+	}, []string{"validator", "outcome"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		FeatureEnabled,
+		BuildInfo,
+		PolicyVerdicts,
+	)
+}
+
+// Handler returns the HTTP handler exposing the metrics.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,362 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package oci pulls the artifacts, such as the Open Policy Agent bundles, from the OCI registries with the
+// distribution API, verifying the digests of their manifest and layers.
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// maxBlobSize bounds the size of the manifests and layers read from the registry.
+const maxBlobSize = 32 << 20
+
+// manifestMediaTypes are the accepted media types of the image manifests.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Reference is the reference of an artifact, such as ghcr.io/acme/policies:v1 or
+// ghcr.io/acme/policies@sha256:<digest>, the latter pinning its manifest.
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses the reference of an artifact: the registry is required, the tag defaulting to latest.
+func ParseReference(reference string) (Reference, error) {
+	var ref Reference
+
+	name, digest, pinned := strings.Cut(reference, "@")
+	if pinned {
+		if !strings.HasPrefix(digest, "sha256:") || len(digest) != len("sha256:")+2*sha256.Size {
+			return ref, errors.Wrap(pkgerrors.ErrOCI, "unsupported digest of the reference "+reference)
+		}
+
+		ref.Digest = digest
+	}
+
+	registry, repository, found := strings.Cut(name, "/")
+	if !found || repository == "" || !strings.ContainsAny(registry, ".:") && registry != "localhost" {
+		return ref, errors.Wrap(pkgerrors.ErrOCI, "the reference must name its registry: "+reference)
+	}
+
+	ref.Registry, ref.Repository = registry, repository
+
+	// The tag follows the last colon after the last slash, the registry holding the port
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		ref.Repository, ref.Tag = repository[:i], repository[i+1:]
+	}
+
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	return ref, nil
+}
+
+// String returns the reference as registry/repository[:tag][@digest].
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+
+	return s
+}
+
+// Artifact is the layer of an artifact pulled from the registry.
+type Artifact struct {
+	// Digest is the digest of the manifest of the artifact.
+	Digest string
+	// MediaType is the media type of the layer.
+	MediaType string
+	// Data is the content of the layer.
+	Data []byte
+}
+
+// Client pulls the artifacts from the registries, authenticating with the credentials when challenged.
+type Client struct {
+	client   *http.Client
+	username string
+	password string
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+// NewClient returns the Client of the registries with the credentials, empty for the anonymous pulls.
+func NewClient(username, password string, timeout time.Duration) *Client {
+	return &Client{
+		client:   &http.Client{Timeout: timeout},
+		username: username,
+		password: password,
+		tokens:   make(map[string]string),
+	}
+}
+
+// Pull returns the first layer of the artifact of one of the media types, once the digests of its manifest, matching
+// the pinned one if any, and of the layer are verified.
+func (c *Client) Pull(ctx context.Context, ref Reference, mediaTypes ...string) (*Artifact, error) {
+	tagOrDigest := ref.Tag
+	if ref.Digest != "" {
+		tagOrDigest = ref.Digest
+	}
+
+	data, err := c.get(ctx, ref, "manifests/"+tagOrDigest, strings.Join(manifestMediaTypes, ", "))
+	if err != nil {
+		return nil, err
+	}
+
+	digest := digestOf(data)
+	if ref.Digest != "" && digest != ref.Digest {
+		return nil, errors.Wrapf(pkgerrors.ErrOCI, "the manifest of %s has the digest %s", ref, digest)
+	}
+
+	var manifest struct {
+		Layers []struct {
+			MediaType string `json:"mediaType"`
+			Digest    string `json:"digest"`
+		} `json:"layers"`
+	}
+
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrOCI, "invalid manifest of "+ref.String()+": "+err.Error())
+	}
+
+	for _, layer := range manifest.Layers {
+		if !slices.Contains(mediaTypes, layer.MediaType) {
+			continue
+		}
+
+		if data, err = c.get(ctx, ref, "blobs/"+layer.Digest, "*/*"); err != nil {
+			return nil, err
+		}
+
+		if layerDigest := digestOf(data); layerDigest != layer.Digest {
+			return nil, errors.Wrapf(pkgerrors.ErrOCI, "the layer %s of %s has the digest %s", layer.Digest, ref, layerDigest)
+		}
+
+		return &Artifact{Digest: digest, MediaType: layer.MediaType, Data: data}, nil
+	}
+
+	return nil, errors.Wrapf(pkgerrors.ErrOCI, "%s has no layer of the media types %s", ref, strings.Join(mediaTypes, ", "))
+}
+
+// get returns the content of the path of the repository, authenticating once when challenged by the registry.
+func (c *Client) get(ctx context.Context, ref Reference, path, accept string) ([]byte, error) {
+	endpoint := "https://" + ref.Registry + "/v2/" + ref.Repository + "/" + path
+
+	resp, err := c.do(ctx, endpoint, accept, c.authorization(ref.Registry))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		_ = resp.Body.Close()
+
+		authorization, authErr := c.authenticate(ctx, ref, challenge)
+		if authErr != nil {
+			return nil, authErr
+		}
+
+		if resp, err = c.do(ctx, endpoint, accept, authorization); err != nil {
+			return nil, err
+		}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Wrapf(pkgerrors.ErrOCI, "%s answered %s", endpoint, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBlobSize+1))
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrOCI, err.Error())
+	}
+
+	if len(data) > maxBlobSize {
+		return nil, errors.Wrapf(pkgerrors.ErrOCI, "%s is larger than %d bytes", endpoint, maxBlobSize)
+	}
+
+	return data, nil
+}
+
+func (c *Client) do(ctx context.Context, endpoint, accept, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrOCI, err.Error())
+	}
+
+	req.Header.Set("Accept", accept)
+
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrOCI, err.Error())
+	}
+
+	return resp, nil
+}
+
+// authorization returns the Authorization header of the last token of the registry, empty for none.
+func (c *Client) authorization(registry string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.tokens[registry]
+}
+
+// authenticate returns the Authorization header answering the challenge of the registry: the credentials for the
+// Basic one, or the token of the realm for the Bearer one, kept for the next requests.
+func (c *Client) authenticate(ctx context.Context, ref Reference, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+
+	var authorization string
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if c.username == "" {
+			return "", errors.Wrap(pkgerrors.ErrOCI, ref.Registry+" requires the credentials")
+		}
+
+		authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(c.username+":"+c.password))
+	case "bearer":
+		token, err := c.token(ctx, ref, parseChallenge(params))
+		if err != nil {
+			return "", err
+		}
+
+		authorization = "Bearer " + token
+	default:
+		return "", errors.Wrap(pkgerrors.ErrOCI, "unsupported authentication challenge of "+ref.Registry+": "+challenge)
+	}
+
+	c.mu.Lock()
+	c.tokens[ref.Registry] = authorization
+	c.mu.Unlock()
+
+	return authorization, nil
+}
+
+// token returns the token of the realm of the Bearer challenge pulling the repository, requested with the
+// credentials if any.
+func (c *Client) token(ctx context.Context, ref Reference, params map[string]string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Scheme != "https" {
+		return "", errors.Wrap(pkgerrors.ErrOCI, "invalid authentication realm of "+ref.Registry+": "+params["realm"])
+	}
+
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + ref.Repository + ":pull"
+	}
+
+	query := realm.Query()
+	query.Set("scope", scope)
+
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", errors.Wrap(pkgerrors.ErrOCI, err.Error())
+	}
+
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(pkgerrors.ErrOCI, err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Wrapf(pkgerrors.ErrOCI, "the authentication realm of %s answered %s", ref.Registry, resp.Status)
+	}
+
+	var answer struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxBlobSize)).Decode(&answer); err != nil {
+		return "", errors.Wrap(pkgerrors.ErrOCI, "invalid token of "+ref.Registry+": "+err.Error())
+	}
+
+	if answer.Token == "" {
+		answer.Token = answer.AccessToken
+	}
+
+	if answer.Token == "" {
+		return "", errors.Wrap(pkgerrors.ErrOCI, "no token answered by the authentication realm of "+ref.Registry)
+	}
+
+	return answer.Token, nil
+}
+
+// parseChallenge returns the parameters of the challenge, such as realm="https://ghcr.io/token",service="ghcr.io".
+func parseChallenge(params string) map[string]string {
+	parsed := make(map[string]string)
+
+	for params != "" {
+		var (
+			key, value string
+			found      bool
+		)
+
+		key, params, found = strings.Cut(strings.TrimLeft(params, ", "), "=")
+		if !found {
+			break
+		}
+
+		if strings.HasPrefix(params, `"`) {
+			value, params, _ = strings.Cut(params[1:], `"`)
+		} else {
+			value, params, _ = strings.Cut(params, ",")
+		}
+
+		parsed[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+
+	return parsed
+}
+
+// digestOf returns the SHA-256 digest of the content, as sha256:<hex>.
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+
+	tests := []struct {
+		reference string
+		expected  Reference
+		valid     bool
+	}{
+		{"ghcr.io/acme/policy:v1", Reference{Registry: "ghcr.io", Repository: "acme/policy", Tag: "v1"}, true},
+		{"ghcr.io/acme/policy", Reference{Registry: "ghcr.io", Repository: "acme/policy", Tag: "latest"}, true},
+		{"localhost:5000/policy@" + digest, Reference{Registry: "localhost:5000", Repository: "policy", Digest: digest}, true},
+		{"registry.local/a/b:v2@" + digest, Reference{Registry: "registry.local", Repository: "a/b", Tag: "v2", Digest: digest}, true},
+		{"acme/policy:v1", Reference{}, false},
+		{"ghcr.io", Reference{}, false},
+		{"ghcr.io/acme/policy@sha256:abc", Reference{}, false},
+		{"ghcr.io/acme/policy@md5:" + strings.Repeat("ab", 32), Reference{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reference, func(t *testing.T) {
+			ref, err := ParseReference(tt.reference)
+
+			switch {
+			case !tt.valid && err == nil:
+				t.Fatalf("expected an invalid reference, got %+v", ref)
+			case tt.valid && err != nil:
+				t.Fatal(err)
+			case tt.valid && ref != tt.expected:
+				t.Fatalf("expected %+v, got %+v", tt.expected, ref)
+			}
+		})
+	}
+}
+
+// registry serves the artifact of the layer with a Bearer token, answering the tampered layer when asked to.
+func registry(t *testing.T, layer []byte, tampered bool) (*httptest.Server, string) {
+	t.Helper()
+
+	layerDigest := digestOf(layer)
+	manifest, _ := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"layers":        []map[string]any{{"mediaType": "application/x-test", "digest": layerDigest, "size": len(layer)}},
+	})
+
+	var srv *httptest.Server
+
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:acme/policy:pull" {
+				w.WriteHeader(http.StatusForbidden)
+
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"token": "secret"}`))
+
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",service="test",scope="repository:acme/policy:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/acme/policy/manifests/v1", "/v2/acme/policy/manifests/" + digestOf(manifest):
+			_, _ = w.Write(manifest)
+		case "/v2/acme/policy/blobs/" + layerDigest:
+			if tampered {
+				_, _ = w.Write(append(layer, '!'))
+
+				return
+			}
+
+			_, _ = w.Write(layer)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, digestOf(manifest)
+}
+
+func TestPull(t *testing.T) {
+	layer := []byte("bundle")
+
+	tests := []struct {
+		name     string
+		tampered bool
+		pin      func(digest string) string
+		valid    bool
+	}{
+		{name: "tag", pin: func(string) string { return ":v1" }, valid: true},
+		{name: "pinned digest", pin: func(digest string) string { return "@" + digest }, valid: true},
+		{name: "other pinned digest", pin: func(string) string { return ":v1@sha256:" + strings.Repeat("00", 32) }},
+		{name: "tampered layer", tampered: true, pin: func(string) string { return ":v1" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, digest := registry(t, layer, tt.tampered)
+
+			ref, err := ParseReference(strings.TrimPrefix(srv.URL, "https://") + "/acme/policy" + tt.pin(digest))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := NewClient("", "", 5*time.Second)
+			client.client = srv.Client()
+
+			artifact, err := client.Pull(t.Context(), ref, "application/x-test")
+
+			if !tt.valid {
+				if !errors.Is(err, pkgerrors.ErrOCI) {
+					t.Fatalf("expected a pull failure, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if artifact.Digest != digest || string(artifact.Data) != string(layer) {
+				t.Fatalf("unexpected artifact %+v", artifact)
+			}
+		})
+	}
+}
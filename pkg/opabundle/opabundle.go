@@ -0,0 +1,419 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package opabundle rolls out the signed Open Policy Agent bundles pulled from an OCI registry, pushing their Rego
+// policies and data documents to the agent deciding the admission of the CSRs, so the policy updates reach every
+// signer without a redeployment.
+package opabundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/jwt"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/oci"
+)
+
+// MediaType is the media type of the layer holding the bundle tarball, as pushed by opa and oras.
+const MediaType = "application/vnd.oci.image.layer.v1.tar+gzip"
+
+const (
+	// signaturesFile is the file of the bundle holding its signatures.
+	signaturesFile = ".signatures.json"
+	// policyPrefix is the prefix of the IDs of the policies pushed to the agent.
+	policyPrefix = "talos-csr-signer/"
+)
+
+// Bundle is an Open Policy Agent bundle whose signature has been verified.
+type Bundle struct {
+	// Digest is the digest of the manifest of the bundle artifact.
+	Digest string
+	// Policies are the Rego policies, keyed by their path in the bundle.
+	Policies map[string][]byte
+	// Data are the data documents, keyed by the path of their directory in the bundle, empty for the root one.
+	Data map[string]json.RawMessage
+}
+
+// Parse returns the bundle of the gzipped tarball, once the signature of every file is verified with the key.
+func Parse(data []byte, key crypto.PublicKey) (*Bundle, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrOPABundle, err.Error())
+	}
+
+	files := make(map[string][]byte)
+	archive := tar.NewReader(gz)
+
+	for {
+		header, nextErr := archive.Next()
+		if errors.Is(nextErr, io.EOF) {
+			break
+		}
+
+		if nextErr != nil {
+			return nil, errors.Wrap(pkgerrors.ErrOPABundle, nextErr.Error())
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		content, readErr := io.ReadAll(archive)
+		if readErr != nil {
+			return nil, errors.Wrap(pkgerrors.ErrOPABundle, readErr.Error())
+		}
+
+		files[cleanPath(header.Name)] = content
+	}
+
+	signatures, signed := files[signaturesFile]
+	if !signed {
+		return nil, errors.Wrap(pkgerrors.ErrOPABundle, "the bundle is not signed")
+	}
+
+	delete(files, signaturesFile)
+
+	if err = Verify(files, signatures, key); err != nil {
+		return nil, err
+	}
+
+	bundle := &Bundle{Policies: make(map[string][]byte), Data: make(map[string]json.RawMessage)}
+
+	for name, content := range files {
+		switch {
+		case strings.HasSuffix(name, ".rego"):
+			bundle.Policies[name] = content
+		case path.Base(name) == "data.json":
+			if !json.Valid(content) {
+				return nil, errors.Wrap(pkgerrors.ErrOPABundle, "invalid data document "+name)
+			}
+
+			dir := path.Dir(name)
+			if dir == "." {
+				dir = ""
+			}
+
+			bundle.Data[dir] = content
+		}
+	}
+
+	return bundle, nil
+}
+
+// Verify verifies the signatures of the bundle, the JWT of its .signatures.json file signed by the key and listing the
+// SHA-256 hash of every other file.
+func Verify(files map[string][]byte, signatures []byte, key crypto.PublicKey) error {
+	var document struct {
+		Signatures []string `json:"signatures"`
+	}
+
+	if err := json.Unmarshal(signatures, &document); err != nil {
+		return errors.Wrap(pkgerrors.ErrOPABundle, "invalid signatures: "+err.Error())
+	}
+
+	if len(document.Signatures) != 1 {
+		return errors.Wrapf(pkgerrors.ErrOPABundle, "expected a single signature, got %d", len(document.Signatures))
+	}
+
+	token, err := jwt.Parse(document.Signatures[0])
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrOPABundle, "invalid signature: "+err.Error())
+	}
+
+	if err = token.Verify(key); err != nil {
+		return errors.Wrap(pkgerrors.ErrOPABundle, "invalid signature of the bundle: "+err.Error())
+	}
+
+	payload, err := token.Payload()
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrOPABundle, "invalid signature: "+err.Error())
+	}
+
+	var claims struct {
+		Files []struct {
+			Name      string `json:"name"`
+			Hash      string `json:"hash"`
+			Algorithm string `json:"algorithm"`
+		} `json:"files"`
+	}
+
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return errors.Wrap(pkgerrors.ErrOPABundle, "invalid signature claims: "+err.Error())
+	}
+
+	signed := make(map[string]bool, len(claims.Files))
+
+	for _, file := range claims.Files {
+		name := cleanPath(file.Name)
+
+		content, found := files[name]
+		if !found {
+			return errors.Wrap(pkgerrors.ErrOPABundle, "signed file missing from the bundle: "+name)
+		}
+
+		if !strings.EqualFold(file.Algorithm, "SHA-256") {
+			return errors.Wrap(pkgerrors.ErrOPABundle, "unsupported hash algorithm "+file.Algorithm+" of "+name)
+		}
+
+		hash, hashErr := fileHash(name, content)
+		if hashErr != nil {
+			return hashErr
+		}
+
+		if hash != file.Hash {
+			return errors.Wrap(pkgerrors.ErrOPABundle, "the file "+name+" does not match its signature")
+		}
+
+		signed[name] = true
+	}
+
+	for name := range files {
+		if !signed[name] {
+			return errors.Wrap(pkgerrors.ErrOPABundle, "the file "+name+" is not signed")
+		}
+	}
+
+	return nil
+}
+
+// fileHash returns the hex encoded SHA-256 hash of the file as computed by opa build: the data documents and the
+// manifest are hashed in their compact encoding with sorted keys and no HTML escaping, the other files as is.
+func fileHash(name string, content []byte) (string, error) {
+	if base := path.Base(name); base == "data.json" || base == ".manifest" {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return "", errors.Wrap(pkgerrors.ErrOPABundle, "invalid JSON file "+name+": "+err.Error())
+		}
+
+		var normalized bytes.Buffer
+
+		encoder := json.NewEncoder(&normalized)
+		encoder.SetEscapeHTML(false)
+
+		if err := encoder.Encode(value); err != nil {
+			return "", errors.Wrap(pkgerrors.ErrOPABundle, err.Error())
+		}
+
+		content = bytes.TrimSuffix(normalized.Bytes(), []byte("\n"))
+	}
+
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// cleanPath returns the path of the file relative to the root of the bundle.
+func cleanPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// ParsePublicKey returns the PEM encoded PKIX public key verifying the bundles.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.Wrap(pkgerrors.ErrOPABundle, "no PEM encoded public key")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrOPABundle, "invalid public key: "+err.Error())
+	}
+
+	return key, nil
+}
+
+// Options configures the Syncer.
+type Options struct {
+	// Reference is the reference of the bundle artifact: a tag is pulled again at every sync, while a digest pins it.
+	Reference oci.Reference
+	// Registry pulls the bundle artifact.
+	Registry *oci.Client
+	// PublicKey verifies the signature of the bundles.
+	PublicKey crypto.PublicKey
+	// AgentURL is the URL of the Open Policy Agent, such as http://localhost:8181, the bundles are pushed to.
+	AgentURL string
+	// Timeout is the timeout of the requests to the agent.
+	Timeout time.Duration
+}
+
+// Syncer pushes the bundle of the registry to the agent whenever its digest changes.
+type Syncer struct {
+	opts    Options
+	client  *http.Client
+	current *Bundle
+}
+
+// NewSyncer returns the Syncer of the options.
+func NewSyncer(opts Options) *Syncer {
+	return &Syncer{opts: opts, client: &http.Client{Timeout: opts.Timeout}}
+}
+
+// Sync pulls the bundle and pushes it to the agent when its digest changed, returning whether it was pushed.
+func (s *Syncer) Sync(ctx context.Context) (bool, error) {
+	artifact, err := s.opts.Registry.Pull(ctx, s.opts.Reference, MediaType)
+	if err != nil {
+		return false, err //nolint:wrapcheck
+	}
+
+	if s.current != nil && s.current.Digest == artifact.Digest {
+		return false, nil
+	}
+
+	bundle, err := Parse(artifact.Data, s.opts.PublicKey)
+	if err != nil {
+		return false, err
+	}
+
+	bundle.Digest = artifact.Digest
+
+	if err = s.push(ctx, bundle); err != nil {
+		return false, err
+	}
+
+	s.current = bundle
+
+	return true, nil
+}
+
+// Run syncs the bundle every interval until the context is done, keeping the previous one on failures.
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pushed, err := s.Sync(ctx)
+
+		switch {
+		case err != nil:
+			logging.FromContext(ctx).Warn("Failed to sync the Open Policy Agent bundle, keeping the previous one",
+				"reference", s.opts.Reference, "error", err)
+		case pushed:
+			logging.FromContext(ctx).Info("Pushed the Open Policy Agent bundle", "reference", s.opts.Reference,
+				"digest", s.current.Digest)
+		}
+	}
+}
+
+// Digest returns the digest of the bundle pushed to the agent, empty before the first sync.
+func (s *Syncer) Digest() string {
+	if s.current == nil {
+		return ""
+	}
+
+	return s.current.Digest
+}
+
+// push replaces the policies and the data documents of the agent with the ones of the bundle, removing the ones of
+// the previous bundle left out of it: the stale data documents before writing the new ones, which may be nested in
+// them, and the stale policies once the new ones are written.
+func (s *Syncer) push(ctx context.Context, bundle *Bundle) error {
+	var previous Bundle
+	if s.current != nil {
+		previous = *s.current
+	}
+
+	for _, dir := range slices.Sorted(maps.Keys(previous.Data)) {
+		if _, found := bundle.Data[dir]; found {
+			continue
+		}
+
+		// The root document cannot be deleted, only emptied
+		method, body := http.MethodDelete, []byte(nil)
+		if dir == "" {
+			method, body = http.MethodPut, []byte("{}")
+		}
+
+		if err := s.request(ctx, method, dataPath(dir), "application/json", body); err != nil {
+			return err
+		}
+	}
+
+	for _, dir := range slices.Sorted(maps.Keys(bundle.Data)) {
+		if err := s.request(ctx, http.MethodPut, dataPath(dir), "application/json", bundle.Data[dir]); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(bundle.Policies)) {
+		if err := s.request(ctx, http.MethodPut, "/v1/policies/"+policyPrefix+name, "text/plain", bundle.Policies[name]); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(previous.Policies)) {
+		if _, found := bundle.Policies[name]; found {
+			continue
+		}
+
+		if err := s.request(ctx, http.MethodDelete, "/v1/policies/"+policyPrefix+name, "", nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Syncer) request(ctx context.Context, method, path, contentType string, body []byte) error {
+	endpoint := strings.TrimSuffix(s.opts.AgentURL, "/") + path
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrOPA, err.Error())
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrOPA, err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// The deleted documents may be already gone
+	if resp.StatusCode/100 != 2 && (method != http.MethodDelete || resp.StatusCode != http.StatusNotFound) {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+		return errors.Wrapf(pkgerrors.ErrOPA, "%s %s answered %s: %s", method, endpoint, resp.Status, bytes.TrimSpace(message))
+	}
+
+	return nil
+}
+
+// dataPath returns the path of the Data API of the document of the directory.
+func dataPath(dir string) string {
+	if dir == "" {
+		return "/v1/data"
+	}
+
+	return "/v1/data/" + dir
+}
@@ -0,0 +1,262 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package opabundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+const policy = "package talos.signer\n\ndefault allow := false\n"
+
+// signedFile is a file of the signatures claims.
+type signedFile struct {
+	Name      string `json:"name"`
+	Hash      string `json:"hash"`
+	Algorithm string `json:"algorithm"`
+}
+
+// sign returns the JWT of the claims listing the files, signed by the key with the algorithm.
+func sign(t *testing.T, key crypto.Signer, algorithm string, files []signedFile) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]string{"alg": algorithm})
+	payload, _ := json.Marshal(map[string]any{"files": files})
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// tarball returns the gzipped tarball of the files.
+func tarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+
+	for name, content := range files {
+		if err := archive.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := archive.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func hashOf(t *testing.T, name, content string) string {
+	t.Helper()
+
+	hash, err := fileHash(name, []byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return hash
+}
+
+func TestParse(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := `{"nodes": {"suffix": ".nodes.example.com", "max": 10}}`
+	signed := []signedFile{
+		{Name: "/talos/signer/policy.rego", Hash: hashOf(t, "talos/signer/policy.rego", policy), Algorithm: "SHA-256"},
+		{Name: "/talos/data.json", Hash: hashOf(t, "talos/data.json", data), Algorithm: "SHA-256"},
+	}
+
+	tests := []struct {
+		name      string
+		files     map[string]string
+		signature string
+		key       crypto.PublicKey
+		valid     bool
+	}{
+		{
+			name:      "RS256",
+			files:     map[string]string{"talos/signer/policy.rego": policy, "talos/data.json": data},
+			signature: sign(t, rsaKey, "RS256", signed),
+			key:       &rsaKey.PublicKey,
+			valid:     true,
+		},
+		{
+			name:      "ES256",
+			files:     map[string]string{"/talos/signer/policy.rego": policy, "./talos/data.json": data},
+			signature: sign(t, ecKey, "ES256", signed),
+			key:       &ecKey.PublicKey,
+			valid:     true,
+		},
+		{
+			name:      "data reformatted",
+			files:     map[string]string{"talos/signer/policy.rego": policy, "talos/data.json": "{\"nodes\":{\"max\":10,\"suffix\":\".nodes.example.com\"}}\n"},
+			signature: sign(t, rsaKey, "RS256", signed),
+			key:       &rsaKey.PublicKey,
+			valid:     true,
+		},
+		{
+			name:      "policy tampered",
+			files:     map[string]string{"talos/signer/policy.rego": policy + "allow := true\n", "talos/data.json": data},
+			signature: sign(t, rsaKey, "RS256", signed),
+			key:       &rsaKey.PublicKey,
+		},
+		{
+			name:      "unsigned file",
+			files:     map[string]string{"talos/signer/policy.rego": policy, "talos/data.json": data, "talos/extra.rego": policy},
+			signature: sign(t, rsaKey, "RS256", signed),
+			key:       &rsaKey.PublicKey,
+		},
+		{
+			name:      "signed file missing",
+			files:     map[string]string{"talos/signer/policy.rego": policy},
+			signature: sign(t, rsaKey, "RS256", signed),
+			key:       &rsaKey.PublicKey,
+		},
+		{
+			name:      "other key",
+			files:     map[string]string{"talos/signer/policy.rego": policy, "talos/data.json": data},
+			signature: sign(t, otherKey, "RS256", signed),
+			key:       &rsaKey.PublicKey,
+		},
+		{
+			name:      "algorithm of another key type",
+			files:     map[string]string{"talos/signer/policy.rego": policy, "talos/data.json": data},
+			signature: sign(t, rsaKey, "ES256", signed),
+			key:       &rsaKey.PublicKey,
+		},
+		{
+			name:  "not signed",
+			files: map[string]string{"talos/signer/policy.rego": policy, "talos/data.json": data},
+			key:   &rsaKey.PublicKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := tt.files
+			if tt.signature != "" {
+				files[signaturesFile] = `{"signatures": ["` + tt.signature + `"]}`
+			}
+
+			bundle, err := Parse(tarball(t, files), tt.key)
+
+			if !tt.valid {
+				if !errors.Is(err, pkgerrors.ErrOPABundle) {
+					t.Fatalf("expected an invalid bundle, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(bundle.Policies["talos/signer/policy.rego"]) != policy {
+				t.Errorf("unexpected policies %v", bundle.Policies)
+			}
+
+			if _, found := bundle.Data["talos"]; !found || len(bundle.Data) != 1 {
+				t.Errorf("unexpected data documents %v", bundle.Data)
+			}
+		})
+	}
+}
+
+// TestParseOPABuild verifies the bundle signed by opa build --signing-alg RS256, whose data document holds the escaped
+// HTML characters and the decimals, hashed unescaped and as is.
+func TestParseOPABuild(t *testing.T) {
+	data, err := os.ReadFile("testdata/bundle.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pem, err := os.ReadFile("testdata/bundle.pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ParsePublicKey(pem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := Parse(data, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found := bundle.Policies["policy/talos/signer/policy.rego"]; !found {
+		t.Errorf("unexpected policies %v", bundle.Policies)
+	}
+
+	if expected := `{"talos":{"allowed":["worker-1","a\u003cb\u0026c"],"n":1.50}}`; string(bytes.TrimSpace(bundle.Data[""])) != expected {
+		t.Errorf("expected the data document %s, got %s", expected, bundle.Data[""])
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = Parse(data, &otherKey.PublicKey); !errors.Is(err, pkgerrors.ErrOPABundle) {
+		t.Fatalf("expected the signature of another key to be refused, got %v", err)
+	}
+}
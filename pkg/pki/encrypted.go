@@ -0,0 +1,169 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package pki
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des" //nolint:gosec
+	"crypto/pbkdf2"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"hash"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+var (
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+)
+
+// pbkdf2PRFs are the hash functions of the PBKDF2 pseudorandom functions, by OID.
+var pbkdf2PRFs = map[string]func() hash.Hash{
+	"1.2.840.113549.2.7":  sha1.New,
+	"1.2.840.113549.2.9":  sha256.New,
+	"1.2.840.113549.2.10": sha512.New384,
+	"1.2.840.113549.2.11": sha512.New,
+}
+
+// pbes2Ciphers are the block ciphers of the PBES2 encryption schemes, along with their key size, by OID.
+var pbes2Ciphers = map[string]struct {
+	keySize  int
+	newBlock func(key []byte) (cipher.Block, error)
+}{
+	"2.16.840.1.101.3.4.1.2":  {16, aes.NewCipher},
+	"2.16.840.1.101.3.4.1.22": {24, aes.NewCipher},
+	"2.16.840.1.101.3.4.1.42": {32, aes.NewCipher},
+	"1.2.840.113549.3.7":      {24, des.NewTripleDESCipher},
+}
+
+// encryptedPrivateKeyInfo is the PKCS#8 EncryptedPrivateKeyInfo.
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// pbes2Params are the PBES2 parameters.
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params are the PBKDF2 parameters.
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// IsEncryptedKey reports whether the PEM block is a private key encrypted with a passphrase: a PKCS#8
+// ENCRYPTED PRIVATE KEY, or a legacy one with the Proc-Type header of OpenSSL.
+func IsEncryptedKey(block *pem.Block) bool {
+	return block.Type == "ENCRYPTED PRIVATE KEY" || x509.IsEncryptedPEMBlock(block) //nolint:staticcheck
+}
+
+// DecryptKey returns the PEM block of the private key encrypted with the passphrase, typed after its encoding.
+func DecryptKey(block *pem.Block, passphrase []byte) (*pem.Block, error) {
+	if block.Type != "ENCRYPTED PRIVATE KEY" {
+		// The legacy encryption of OpenSSL is insecure by design, but still written by `openssl rsa -aes256`.
+		der, err := x509.DecryptPEMBlock(block, passphrase) //nolint:staticcheck
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrKeyPassphrase, err.Error())
+		}
+
+		return &pem.Block{Type: block.Type, Bytes: der}, nil
+	}
+
+	der, err := decryptPKCS8(block.Bytes, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pem.Block{Type: "PRIVATE KEY", Bytes: der}, nil
+}
+
+// decryptPKCS8 returns the PKCS#8 private key encrypted with PBES2, such as written by `openssl pkcs8 -topk8`.
+func decryptPKCS8(data, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(data, &info); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrUnsupportedBlockType, "encrypted private key: "+err.Error())
+	}
+
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, errors.Wrap(pkgerrors.ErrUnsupportedBlockType, "encryption "+info.Algorithm.Algorithm.String()+", expected PBES2")
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrUnsupportedBlockType, "PBES2 parameters: "+err.Error())
+	}
+
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, errors.Wrap(pkgerrors.ErrUnsupportedBlockType, "key derivation "+params.KeyDerivationFunc.Algorithm.String()+", expected PBKDF2")
+	}
+
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrUnsupportedBlockType, "PBKDF2 parameters: "+err.Error())
+	}
+
+	prf := sha1.New
+	if len(kdf.PRF.Algorithm) > 0 {
+		var ok bool
+		if prf, ok = pbkdf2PRFs[kdf.PRF.Algorithm.String()]; !ok {
+			return nil, errors.Wrap(pkgerrors.ErrUnsupportedBlockType, "PBKDF2 function "+kdf.PRF.Algorithm.String())
+		}
+	}
+
+	scheme, ok := pbes2Ciphers[params.EncryptionScheme.Algorithm.String()]
+	if !ok {
+		return nil, errors.Wrap(pkgerrors.ErrUnsupportedBlockType, "cipher "+params.EncryptionScheme.Algorithm.String())
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrUnsupportedBlockType, "cipher IV: "+err.Error())
+	}
+
+	key, err := pbkdf2.Key(prf, string(passphrase), kdf.Salt, kdf.IterationCount, scheme.keySize)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrKeyPassphrase, err.Error())
+	}
+
+	block, err := scheme.newBlock(key)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrKeyPassphrase, err.Error())
+	}
+
+	if len(iv) != block.BlockSize() || len(info.EncryptedData) == 0 || len(info.EncryptedData)%block.BlockSize() != 0 {
+		return nil, errors.Wrap(pkgerrors.ErrUnsupportedBlockType, "invalid encrypted data length")
+	}
+
+	der := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(der, info.EncryptedData)
+
+	// A wrong passphrase is detected by the invalid padding, the PKCS#8 parsing catching the remaining cases.
+	padding := int(der[len(der)-1])
+	if padding == 0 || padding > block.BlockSize() {
+		return nil, pkgerrors.ErrKeyPassphrase
+	}
+
+	for _, b := range der[len(der)-padding:] {
+		if subtle.ConstantTimeByteEq(b, byte(padding)) != 1 {
+			return nil, pkgerrors.ErrKeyPassphrase
+		}
+	}
+
+	return der[:len(der)-padding], nil
+}
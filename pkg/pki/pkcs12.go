@@ -0,0 +1,35 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package pki
+
+import (
+	"crypto"
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+	"software.sslmate.com/src/go-pkcs12"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// DecodePKCS12 returns the private key and the certificates of the PKCS#12 bundle, such as written by
+// `openssl pkcs12 -export`: the integrity MAC is verified, and the bags encrypted with PBES2 or with the legacy
+// PKCS#12 PBEs are decrypted with the password.
+func DecodePKCS12(data, password []byte) (crypto.Signer, []*x509.Certificate, error) {
+	key, cert, chain, err := pkcs12.DecodeChain(data, string(password))
+	if err != nil {
+		if errors.Is(err, pkcs12.ErrIncorrectPassword) || errors.Is(err, pkcs12.ErrDecryption) {
+			return nil, nil, errors.Wrap(pkgerrors.ErrKeyPassphrase, err.Error())
+		}
+
+		return nil, nil, errors.Wrap(pkgerrors.ErrPKCS12, err.Error())
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.Wrapf(pkgerrors.ErrUnsupportedKey, "%T", key)
+	}
+
+	return signer, append([]*x509.Certificate{cert}, chain...), nil
+}
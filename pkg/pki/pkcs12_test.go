@@ -0,0 +1,114 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// encodeModern returns the bundle of a new CA encoded with AES-256 and a SHA-256 MAC, along with its certificate.
+func encodeModern(t *testing.T, password string) ([]byte, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template, err := CATemplate(pkix.Name{CommonName: "talos"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := Sign(template, key.Public(), template, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := pkcs12.Modern.Encode(key, cert, nil, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data, cert
+}
+
+// TestDecodePKCS12 decodes the bundles of testdata written by openssl pkcs12 -export, holding the intermediate CA of
+// ca.crt, its P-256 key, and the root CA when exported with -certfile.
+func TestDecodePKCS12(t *testing.T) {
+	certs, err := ParseCertificates(readFile(t, "testdata/ca.crt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, encodedCert := encodeModern(t, "secret")
+
+	tests := []struct {
+		name     string
+		data     []byte
+		password string
+		ca       *x509.Certificate
+		certs    int
+		expected error
+	}{
+		{name: "AES-256 and SHA-256 MAC", data: readFile(t, "testdata/modern.p12"), password: "secret", ca: certs[0], certs: 2},
+		{name: "legacy RC2 and 3DES", data: readFile(t, "testdata/legacy.p12"), password: "secret", ca: certs[0], certs: 2},
+		{name: "empty password", data: readFile(t, "testdata/empty.p12"), ca: certs[0], certs: 1},
+		{name: "encoded", data: encoded, password: "secret", ca: encodedCert, certs: 1},
+		{name: "wrong password", data: readFile(t, "testdata/modern.p12"), password: "other", expected: pkgerrors.ErrKeyPassphrase},
+		{name: "legacy wrong password", data: readFile(t, "testdata/legacy.p12"), password: "other", expected: pkgerrors.ErrKeyPassphrase},
+		{name: "no private key", data: readFile(t, "testdata/nokey.p12"), password: "secret", expected: pkgerrors.ErrPKCS12},
+		{name: "not a bundle", data: readFile(t, "testdata/ca.crt"), expected: pkgerrors.ErrPKCS12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, bundleCerts, err := DecodePKCS12(tt.data, []byte(tt.password))
+
+			if tt.expected != nil {
+				if !errors.Is(err, tt.expected) {
+					t.Fatalf("expected %v, got %v", tt.expected, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(bundleCerts) != tt.certs || !bundleCerts[0].Equal(tt.ca) {
+				t.Fatalf("unexpected certificates %d, the first being %s", len(bundleCerts), bundleCerts[0].Subject)
+			}
+
+			publicKey, ok := tt.ca.PublicKey.(*ecdsa.PublicKey)
+			if !ok || !publicKey.Equal(key.Public()) {
+				t.Fatal("the private key is not the one of the CA certificate")
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pki generates the keys and certificates managed by the signer subcommands.
+package pki
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// GenerateKeyLike returns a new private key of the same algorithm, and size, of the given public key.
+func GenerateKeyLike(publicKey crypto.PublicKey) (crypto.Signer, error) {
+	var (
+		key crypto.Signer
+		err error
+	)
+
+	switch pub := publicKey.(type) {
+	case ed25519.PublicKey:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	case *ecdsa.PublicKey:
+		key, err = ecdsa.GenerateKey(pub.Curve, rand.Reader)
+	case *rsa.PublicKey:
+		key, err = rsa.GenerateKey(rand.Reader, pub.N.BitLen())
+	default:
+		return nil, errors.Wrapf(pkgerrors.ErrUnsupportedKey, "%T", publicKey)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrGenerateKey, err.Error())
+	}
+
+	return key, nil
+}
+
+// GenerateKey returns a new private key of the given algorithm: ed25519, ecdsa (P-256), or rsa (2048 bits).
+func GenerateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case "ed25519":
+		return GenerateKeyLike(ed25519.PublicKey(nil))
+	case "ecdsa":
+		return GenerateKeyLike(&ecdsa.PublicKey{Curve: elliptic.P256()})
+	case "rsa":
+		return GenerateKeyLike(&rsa.PublicKey{N: new(big.Int).Lsh(big.NewInt(1), 2047)})
+	default:
+		return nil, errors.Wrap(pkgerrors.ErrUnsupportedKey, algorithm)
+	}
+}
+
+// KeyAlgorithm returns the name of the algorithm of the public key: ed25519, ecdsa-p256, ecdsa-p384, ecdsa-p521,
+// or rsa, empty when not supported.
+func KeyAlgorithm(publicKey crypto.PublicKey) string {
+	switch pub := publicKey.(type) {
+	case ed25519.PublicKey:
+		return "ed25519"
+	case *ecdsa.PublicKey:
+		return "ecdsa-" + strings.ToLower(strings.ReplaceAll(pub.Curve.Params().Name, "-", ""))
+	case *rsa.PublicKey:
+		return "rsa"
+	default:
+		return ""
+	}
+}
+
+// The bounds of the serial numbers size: RFC 5280 limits them to 20 octets, while the CA/Browser Forum
+// requires 64 bits of entropy.
+const (
+	MinSerialBits     = 64
+	MaxSerialBits     = 160
+	DefaultSerialBits = 128
+)
+
+// SerialFormat describes the serial numbers of the certificates: random positive non-zero integers, optionally
+// prefixed in their high bits, such as with a cluster identifier.
+type SerialFormat struct {
+	// Bits is the size of the serial numbers, prefix included: zero uses DefaultSerialBits.
+	Bits int
+	// Prefix is the value of the high bits of the serial numbers.
+	Prefix uint64
+	// PrefixBits is the number of high bits holding the Prefix: zero disables it.
+	PrefixBits int
+}
+
+// ParseSerialFormat returns the SerialFormat of the given size and hex encoded prefix, taking 4 bits per digit.
+func ParseSerialFormat(bits int, prefix string) (SerialFormat, error) {
+	format := SerialFormat{Bits: bits}
+
+	if prefix = strings.TrimPrefix(strings.ToLower(prefix), "0x"); prefix != "" {
+		value, err := strconv.ParseUint(prefix, 16, 64)
+		if err != nil {
+			return SerialFormat{}, errors.Wrap(pkgerrors.ErrSerialFormat, "invalid hex prefix "+prefix)
+		}
+
+		format.Prefix, format.PrefixBits = value, 4*len(prefix)
+	}
+
+	return format, format.Validate()
+}
+
+// Validate returns an error when the serial numbers would exceed 20 octets, or have less than 64 random bits.
+func (f SerialFormat) Validate() error {
+	bits := f.size()
+
+	switch {
+	case bits < MinSerialBits || bits > MaxSerialBits:
+		return errors.Wrapf(pkgerrors.ErrSerialFormat, "%d bits, expected %d to %d", bits, MinSerialBits, MaxSerialBits)
+	case f.PrefixBits < 0 || bits-f.PrefixBits < MinSerialBits:
+		return errors.Wrapf(pkgerrors.ErrSerialFormat, "a %d bits prefix leaves less than %d random bits", f.PrefixBits, MinSerialBits)
+	case f.PrefixBits < 64 && f.Prefix>>f.PrefixBits != 0:
+		return errors.Wrapf(pkgerrors.ErrSerialFormat, "prefix %x exceeds %d bits", f.Prefix, f.PrefixBits)
+	case bits == MaxSerialBits && f.PrefixBits > 0 && f.PrefixBits <= 64 && f.Prefix>>(f.PrefixBits-1) != 0:
+		return errors.Wrapf(pkgerrors.ErrSerialFormat, "prefix %x sets the highest bit of the %d-bit serials", f.Prefix, bits)
+	}
+
+	return nil
+}
+
+// size returns the size of the serial numbers.
+func (f SerialFormat) size() int {
+	if f.Bits == 0 {
+		return DefaultSerialBits
+	}
+
+	return f.Bits
+}
+
+// Generate returns a random serial number of the format. The highest bit of the 160-bit serials is cleared,
+// so their DER encoding, positive, fits in 20 octets.
+func (f SerialFormat) Generate() (*big.Int, error) {
+	bits := f.size()
+	random := bits - f.PrefixBits
+	prefix := new(big.Int).Lsh(new(big.Int).SetUint64(f.Prefix), uint(random)) //nolint:gosec
+
+	for {
+		serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), uint(random))) //nolint:gosec
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrGenerateKey, err.Error())
+		}
+
+		serial.Or(serial, prefix)
+
+		if bits == MaxSerialBits {
+			serial.SetBit(serial, MaxSerialBits-1, 0)
+		}
+
+		if serial.Sign() > 0 {
+			return serial, nil
+		}
+	}
+}
+
+// SerialNumber returns a random 128-bit certificate serial number.
+func SerialNumber() (*big.Int, error) {
+	return SerialFormat{}.Generate()
+}
+
+// CATemplate returns the template of a CA certificate with the given subject, valid from now for the given duration.
+func CATemplate(subject pkix.Name, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := SerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	return &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             now.Add(-5 * time.Minute), // Tolerate small clock skews
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil
+}
+
+// Sign issues the certificate from the template for the public key, signed by the parent: a nil parent self-signs it.
+func Sign(template *x509.Certificate, publicKey crypto.PublicKey, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, error) {
+	if parent == nil {
+		parent = template
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, publicKey, parentKey)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, err.Error())
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, err.Error())
+	}
+
+	return cert, nil
+}
+
+// EncodeCertificates returns the PEM encoded certificates, concatenated.
+func EncodeCertificates(certs ...*x509.Certificate) []byte {
+	var buf bytes.Buffer
+
+	for _, cert := range certs {
+		_ = pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+
+	return buf.Bytes()
+}
+
+// EncodePrivateKey returns the PEM encoded private key, using the block types of the Talos machine configuration.
+func EncodePrivateKey(key crypto.Signer) ([]byte, error) {
+	var (
+		block *pem.Block
+		err   error
+	)
+
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		block = &pem.Block{Type: "ED25519 PRIVATE KEY"}
+		block.Bytes, err = x509.MarshalPKCS8PrivateKey(k)
+	case *ecdsa.PrivateKey:
+		block = &pem.Block{Type: "EC PRIVATE KEY"}
+		block.Bytes, err = x509.MarshalECPrivateKey(k)
+	case *rsa.PrivateKey:
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}
+	default:
+		return nil, errors.Wrapf(pkgerrors.ErrUnsupportedKey, "%T", key)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrUnsupportedKey, err.Error())
+	}
+
+	return pem.EncodeToMemory(block), nil
+}
+
+// ParseCertificates returns all the certificates found in the PEM data.
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	for {
+		var block *pem.Block
+
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrDecodedCACertificate, err.Error())
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, pkgerrors.ErrPemDecoding
+	}
+
+	return certs, nil
+}
+
+// VerifyChain checks the chain leads from the intermediate CA up to a root: the first certificate must have issued the
+// CA, each one the previous one, and the last one be self-signed.
+func VerifyChain(ca *x509.Certificate, chain []*x509.Certificate) error {
+	if len(chain) == 0 {
+		return errors.Wrap(pkgerrors.ErrCAChain, "the chain is empty")
+	}
+
+	if ca.CheckSignatureFrom(ca) == nil {
+		return errors.Wrap(pkgerrors.ErrCAChain, "the CA "+ca.Subject.String()+" is self-signed, no chain is needed")
+	}
+
+	child := ca
+
+	for _, parent := range chain {
+		if err := child.CheckSignatureFrom(parent); err != nil {
+			return errors.Wrap(pkgerrors.ErrCAChain, child.Subject.String()+" is not issued by "+parent.Subject.String()+": "+err.Error())
+		}
+
+		child = parent
+	}
+
+	if err := child.CheckSignatureFrom(child); err != nil {
+		return errors.Wrap(pkgerrors.ErrCAChain, "the chain ends with "+child.Subject.String()+", which is not a root")
+	}
+
+	return nil
+}
+
+// MergeBundles concatenates the PEM certificates of the bundles, skipping the duplicated ones.
+func MergeBundles(bundles ...[]byte) []byte {
+	var buf bytes.Buffer
+
+	seen := make(map[string]struct{})
+
+	for _, data := range bundles {
+		for {
+			var block *pem.Block
+
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+
+			if _, ok := seen[string(block.Bytes)]; ok {
+				continue
+			}
+
+			seen[string(block.Bytes)] = struct{}{}
+
+			_ = pem.Encode(&buf, block)
+		}
+	}
+
+	return buf.Bytes()
+}
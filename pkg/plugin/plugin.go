@@ -0,0 +1,168 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package plugin runs the out-of-process plugins extending the signer, built with hashicorp/go-plugin:
+// organizations ship their proprietary Authenticator, policy Validator, or signing Backend as a separate
+// binary, without recompiling the signer.
+package plugin
+
+import (
+	"context"
+	"log/slog"
+	"os/exec"
+
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/policy"
+	"github.com/clastix/talos-csr-signer/pkg/server"
+)
+
+const (
+	// AuthenticatorName is the name of the plugin implementing server.Authenticator.
+	AuthenticatorName = "authenticator"
+	// ValidatorName is the name of the plugin implementing policy.Validator.
+	ValidatorName = "validator"
+	// SignerName is the name of the plugin implementing backend.Backend.
+	SignerName = "signer"
+	// manifestName is the name of the internal plugin listing the ones served.
+	manifestName = "manifest"
+)
+
+// Handshake is the handshake shared by the signer and its plugins, bumped on incompatible changes.
+var Handshake = goplugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "TALOS_CSR_SIGNER_PLUGIN",
+	MagicCookieValue: "talos-csr-signer",
+}
+
+// Plugins are the implementations served by a plugin binary: the nil ones are not served.
+// The contexts given to the implementations are not propagated from the signer.
+type Plugins struct {
+	Authenticator server.Authenticator
+	Validator     policy.Validator
+	Signer        backend.Backend
+}
+
+// Serve serves the plugins to the signer, called by the main function of the plugin binaries.
+func Serve(plugins Plugins) {
+	served := map[string]goplugin.Plugin{}
+
+	if plugins.Authenticator != nil {
+		served[AuthenticatorName] = &authenticatorPlugin{impl: plugins.Authenticator}
+	}
+
+	if plugins.Validator != nil {
+		served[ValidatorName] = &validatorPlugin{impl: plugins.Validator}
+	}
+
+	if plugins.Signer != nil {
+		served[SignerName] = &signerPlugin{impl: plugins.Signer}
+	}
+
+	names := make([]string, 0, len(served))
+	for name := range served {
+		names = append(names, name)
+	}
+
+	served[manifestName] = &manifestPlugin{names: names}
+
+	goplugin.Serve(&goplugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins:         served,
+	})
+}
+
+// Client is a running plugin binary, along with the implementations it serves.
+type Client struct {
+	client *goplugin.Client
+	// Path is the plugin binary.
+	Path string
+	// Plugins holds the implementations served by the plugin.
+	Plugins Plugins
+}
+
+// Load starts the plugin binary, returning the implementations it serves: its output is logged with the logger of
+// the context.
+func Load(ctx context.Context, path string) (*Client, error) {
+	output := slog.NewLogLogger(logging.FromContext(ctx).With("plugin", path).Handler(), slog.LevelInfo).Writer()
+
+	client := goplugin.NewClient(&goplugin.ClientConfig{
+		HandshakeConfig: Handshake,
+		Plugins: map[string]goplugin.Plugin{
+			AuthenticatorName: &authenticatorPlugin{},
+			ValidatorName:     &validatorPlugin{},
+			SignerName:        &signerPlugin{},
+			manifestName:      &manifestPlugin{},
+		},
+		Cmd:              exec.Command(path), //nolint:gosec
+		AllowedProtocols: []goplugin.Protocol{goplugin.ProtocolNetRPC},
+		AutoMTLS:         true,
+		Logger:           hclog.New(&hclog.LoggerOptions{Name: "plugin", Output: output, Level: hclog.Info}),
+	})
+
+	c := &Client{client: client, Path: path}
+	if err := c.dispense(); err != nil {
+		client.Kill()
+
+		return nil, errors.Wrapf(pkgerrors.ErrPlugin, "%s: %s", path, err.Error())
+	}
+
+	return c, nil
+}
+
+// dispense fetches the implementations served by the plugin.
+func (c *Client) dispense() error {
+	rpcClient, err := c.client.Client()
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	raw, err := rpcClient.Dispense(manifestName)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	names, err := raw.(*manifestRPCClient).Plugins()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if raw, err = rpcClient.Dispense(name); err != nil {
+			return err //nolint:wrapcheck
+		}
+
+		switch impl := raw.(type) {
+		case *authenticatorRPCClient:
+			c.Plugins.Authenticator = impl
+		case *validatorRPCClient:
+			if err = impl.init(); err != nil {
+				return err
+			}
+
+			c.Plugins.Validator = impl
+		case *signerRPCClient:
+			if err = impl.init(); err != nil {
+				return err
+			}
+
+			c.Plugins.Signer = impl
+		}
+	}
+
+	if len(names) == 0 {
+		return pkgerrors.ErrPluginEmpty
+	}
+
+	return nil
+}
+
+// Close stops the plugin binary.
+func (c *Client) Close() {
+	c.client.Kill()
+}
@@ -0,0 +1,343 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"net"
+	"net/rpc"
+	"time"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/policy"
+	"github.com/clastix/talos-csr-signer/pkg/server"
+)
+
+// The plugins are exposed with net/rpc, the values crossing the process boundary being gob encoded:
+// the certificates, keys, and CSRs travel DER encoded, the errors as their message.
+
+// manifestPlugin lists the plugins served by the binary.
+type manifestPlugin struct {
+	names []string
+}
+
+func (p *manifestPlugin) Server(*goplugin.MuxBroker) (any, error) {
+	return &manifestRPCServer{names: p.names}, nil
+}
+
+func (*manifestPlugin) Client(_ *goplugin.MuxBroker, client *rpc.Client) (any, error) {
+	return &manifestRPCClient{client: client}, nil
+}
+
+type manifestRPCServer struct {
+	names []string
+}
+
+func (s *manifestRPCServer) Plugins(_ any, names *[]string) error {
+	*names = s.names
+
+	return nil
+}
+
+type manifestRPCClient struct {
+	client *rpc.Client
+}
+
+func (c *manifestRPCClient) Plugins() ([]string, error) {
+	var names []string
+
+	return names, c.client.Call("Plugin.Plugins", new(any), &names) //nolint:wrapcheck
+}
+
+// authenticatorPlugin exposes a server.Authenticator.
+type authenticatorPlugin struct {
+	impl server.Authenticator
+}
+
+func (p *authenticatorPlugin) Server(*goplugin.MuxBroker) (any, error) {
+	return &authenticatorRPCServer{impl: p.impl}, nil
+}
+
+func (*authenticatorPlugin) Client(_ *goplugin.MuxBroker, client *rpc.Client) (any, error) {
+	return &authenticatorRPCClient{client: client}, nil
+}
+
+// AuthenticateArgs are the arguments of the Authenticate call.
+type AuthenticateArgs struct {
+	Token string
+	Peer  *ledger.Peer
+}
+
+type authenticatorRPCServer struct {
+	impl server.Authenticator
+}
+
+func (s *authenticatorRPCServer) Authenticate(args AuthenticateArgs, authenticated *bool) error {
+	var err error
+
+	*authenticated, err = s.impl.Authenticate(context.Background(), args.Token, args.Peer)
+
+	return err //nolint:wrapcheck
+}
+
+type authenticatorRPCClient struct {
+	client *rpc.Client
+}
+
+// Authenticate implements server.Authenticator.
+func (c *authenticatorRPCClient) Authenticate(_ context.Context, token string, peer *ledger.Peer) (bool, error) {
+	var authenticated bool
+
+	err := c.client.Call("Plugin.Authenticate", AuthenticateArgs{Token: token, Peer: peer}, &authenticated)
+
+	return authenticated, err //nolint:wrapcheck
+}
+
+// validatorPlugin exposes a policy.Validator.
+type validatorPlugin struct {
+	impl policy.Validator
+}
+
+func (p *validatorPlugin) Server(*goplugin.MuxBroker) (any, error) {
+	return &validatorRPCServer{impl: p.impl}, nil
+}
+
+func (*validatorPlugin) Client(_ *goplugin.MuxBroker, client *rpc.Client) (any, error) {
+	return &validatorRPCClient{client: client}, nil
+}
+
+// Verdict is the gob encoded policy.Verdict.
+type Verdict struct {
+	Validator   string
+	Outcome     policy.Outcome
+	Code        uint32
+	Reason      string
+	ErrorReason string
+	Err         string
+}
+
+type validatorRPCServer struct {
+	impl policy.Validator
+}
+
+func (s *validatorRPCServer) Name(_ any, name *string) error {
+	*name = s.impl.Name()
+
+	return nil
+}
+
+func (s *validatorRPCServer) Validate(csrDER []byte, verdict *Verdict) error {
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	decided := s.impl.Validate(context.Background(), csr)
+	*verdict = Verdict{
+		Validator:   decided.Validator,
+		Outcome:     decided.Outcome,
+		Code:        uint32(decided.Code),
+		Reason:      decided.Reason,
+		ErrorReason: decided.ErrorReason,
+	}
+
+	if decided.Err != nil {
+		verdict.Err = decided.Err.Error()
+	}
+
+	return nil
+}
+
+type validatorRPCClient struct {
+	client *rpc.Client
+	name   string
+}
+
+// init fetches the name of the validator, so it's not asked for every CSR.
+func (c *validatorRPCClient) init() error {
+	return c.client.Call("Plugin.Name", new(any), &c.name) //nolint:wrapcheck
+}
+
+// Name implements policy.Validator.
+func (c *validatorRPCClient) Name() string {
+	return c.name
+}
+
+// Validate implements policy.Validator: a plugin failure is an OutcomeError verdict.
+func (c *validatorRPCClient) Validate(_ context.Context, csr *x509.CertificateRequest) policy.Verdict {
+	var verdict Verdict
+
+	if err := c.client.Call("Plugin.Validate", csr.Raw, &verdict); err != nil {
+		return policy.Fail(c.name, "policy plugin unavailable", err)
+	}
+
+	decided := policy.Verdict{
+		Validator:   verdict.Validator,
+		Outcome:     verdict.Outcome,
+		Code:        codes.Code(verdict.Code),
+		Reason:      verdict.Reason,
+		ErrorReason: verdict.ErrorReason,
+	}
+
+	if verdict.Err != "" {
+		decided.Err = errors.New(verdict.Err) //nolint:err113
+	}
+
+	return decided
+}
+
+// signerPlugin exposes a backend.Backend.
+type signerPlugin struct {
+	impl backend.Backend
+}
+
+func (p *signerPlugin) Server(*goplugin.MuxBroker) (any, error) {
+	return &signerRPCServer{impl: p.impl}, nil
+}
+
+func (*signerPlugin) Client(_ *goplugin.MuxBroker, client *rpc.Client) (any, error) {
+	return &signerRPCClient{client: client}, nil
+}
+
+// SignerInfo describes the signing backend.
+type SignerInfo struct {
+	Name string
+	// Certificate is the DER encoded CA certificate.
+	Certificate []byte
+}
+
+// Template is the gob encoded certificate template, along with the public key to sign.
+type Template struct {
+	SerialNumber          *big.Int
+	Subject               []byte
+	NotBefore             time.Time
+	NotAfter              time.Time
+	KeyUsage              x509.KeyUsage
+	ExtKeyUsage           []x509.ExtKeyUsage
+	BasicConstraintsValid bool
+	IsCA                  bool
+	DNSNames              []string
+	IPAddresses           []net.IP
+	// PublicKey is the PKIX DER encoded public key.
+	PublicKey []byte
+}
+
+type signerRPCServer struct {
+	impl backend.Backend
+}
+
+func (s *signerRPCServer) Info(_ any, info *SignerInfo) error {
+	*info = SignerInfo{Name: s.impl.Name(), Certificate: s.impl.Certificate().Raw}
+
+	return nil
+}
+
+func (s *signerRPCServer) Sign(args Template, result *backend.Result) error {
+	var subject pkix.RDNSequence
+	if _, err := asn1.Unmarshal(args.Subject, &subject); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(args.PublicKey)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          args.SerialNumber,
+		NotBefore:             args.NotBefore,
+		NotAfter:              args.NotAfter,
+		KeyUsage:              args.KeyUsage,
+		ExtKeyUsage:           args.ExtKeyUsage,
+		BasicConstraintsValid: args.BasicConstraintsValid,
+		IsCA:                  args.IsCA,
+		DNSNames:              args.DNSNames,
+		IPAddresses:           args.IPAddresses,
+	}
+	template.Subject.FillFromRDNSequence(&subject)
+
+	signed, err := s.impl.Sign(context.Background(), template, publicKey)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	*result = *signed
+
+	return nil
+}
+
+type signerRPCClient struct {
+	client *rpc.Client
+	info   SignerInfo
+	cert   *x509.Certificate
+}
+
+// init fetches the name and the CA certificate of the backend.
+func (c *signerRPCClient) init() error {
+	if err := c.client.Call("Plugin.Info", new(any), &c.info); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	cert, err := x509.ParseCertificate(c.info.Certificate)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	c.cert = cert
+
+	return nil
+}
+
+// Name implements backend.Backend.
+func (c *signerRPCClient) Name() string {
+	return c.info.Name
+}
+
+// Certificate implements backend.Backend.
+func (c *signerRPCClient) Certificate() *x509.Certificate {
+	return c.cert
+}
+
+// Sign implements backend.Backend.
+func (c *signerRPCClient) Sign(_ context.Context, template *x509.Certificate, publicKey any) (*backend.Result, error) {
+	subject, err := asn1.Marshal(template.Subject.ToRDNSequence())
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	var result backend.Result
+
+	err = c.client.Call("Plugin.Sign", Template{
+		SerialNumber:          template.SerialNumber,
+		Subject:               subject,
+		NotBefore:             template.NotBefore,
+		NotAfter:              template.NotAfter,
+		KeyUsage:              template.KeyUsage,
+		ExtKeyUsage:           template.ExtKeyUsage,
+		BasicConstraintsValid: template.BasicConstraintsValid,
+		IsCA:                  template.IsCA,
+		DNSNames:              template.DNSNames,
+		IPAddresses:           template.IPAddresses,
+		PublicKey:             publicKeyDER,
+	}, &result)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return &result, nil
+}
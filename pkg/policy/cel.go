@@ -0,0 +1,144 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+
+	"github.com/google/cel-go/cel"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+)
+
+// celCostLimit bounds the evaluation of the CEL expressions, such as the comprehensions over the SANs.
+const celCostLimit = 1_000_000
+
+// CEL admits the CSRs the Common Expression Language expression evaluates to true for, such as:
+//
+//	csr.dnsNames.all(name, name.endsWith(".nodes.example.com")) && csr.keyAlgorithm == "ed25519"
+//
+// The expression sees the csr, with its commonName, organizations, organizationalUnits, dnsNames, ipAddresses,
+// emailAddresses, uris, keyAlgorithm, and keyBits, and the metadata of the request keyed by their lowercase name,
+// holding their first value, but for the token.
+type CEL struct {
+	expression string
+	program    cel.Program
+}
+
+// NewCEL returns the validator of the CEL expression, which must evaluate to a bool.
+func NewCEL(expression string) (*CEL, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("csr", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("metadata", cel.MapType(cel.StringType, cel.StringType)),
+	)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrPolicy, err.Error())
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, errors.Wrap(pkgerrors.ErrPolicy, "invalid CEL expression: "+issues.Err().Error())
+	}
+
+	if !ast.OutputType().IsExactType(cel.BoolType) {
+		return nil, errors.Wrap(pkgerrors.ErrPolicy, "the CEL expression evaluates to "+ast.OutputType().String()+", not to a bool")
+	}
+
+	program, err := env.Program(ast, cel.CostLimit(celCostLimit))
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrPolicy, "invalid CEL expression: "+err.Error())
+	}
+
+	return &CEL{expression: expression, program: program}, nil
+}
+
+// Name implements Validator.
+func (*CEL) Name() string {
+	return "cel"
+}
+
+// Validate implements Validator.
+func (c *CEL) Validate(ctx context.Context, csr *x509.CertificateRequest) Verdict {
+	out, _, err := c.program.ContextEval(ctx, map[string]any{
+		"csr":      csrAttributes(csr),
+		"metadata": requestMetadata(ctx),
+	})
+	if err != nil {
+		return Deny("cel", codes.PermissionDenied, "failed to evaluate the CEL expression: %v", err).WithRule("policy-cel")
+	}
+
+	if admitted, ok := out.Value().(bool); !ok || !admitted {
+		return Deny("cel", codes.PermissionDenied, "CSR rejected by the CEL expression %s", c.expression).WithRule("policy-cel")
+	}
+
+	return Allow("cel", "CSR admitted by the CEL expression")
+}
+
+// csrAttributes returns the fields of the CSR seen by the CEL expressions and the OPA policies.
+func csrAttributes(csr *x509.CertificateRequest) map[string]any {
+	ips := make([]string, 0, len(csr.IPAddresses))
+	for _, ip := range csr.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+
+	uris := make([]string, 0, len(csr.URIs))
+	for _, uri := range csr.URIs {
+		uris = append(uris, uri.String())
+	}
+
+	var keyBits int
+
+	switch key := csr.PublicKey.(type) {
+	case ed25519.PublicKey:
+		keyBits = ed25519.PublicKeySize * 8
+	case *ecdsa.PublicKey:
+		keyBits = key.Curve.Params().BitSize
+	case *rsa.PublicKey:
+		keyBits = key.N.BitLen()
+	}
+
+	return map[string]any{
+		"commonName":          csr.Subject.CommonName,
+		"organizations":       nonNil(csr.Subject.Organization),
+		"organizationalUnits": nonNil(csr.Subject.OrganizationalUnit),
+		"dnsNames":            nonNil(csr.DNSNames),
+		"ipAddresses":         ips,
+		"emailAddresses":      nonNil(csr.EmailAddresses),
+		"uris":                uris,
+		"keyAlgorithm":        pki.KeyAlgorithm(csr.PublicKey),
+		"keyBits":             keyBits,
+	}
+}
+
+// requestMetadata returns the metadata of the request seen by the CEL expressions and the OPA policies, leaving out
+// the token.
+func requestMetadata(ctx context.Context) map[string]string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := make(map[string]string, len(md))
+
+	for key, value := range md {
+		if key != "token" && len(value) > 0 {
+			values[key] = value[0]
+		}
+	}
+
+	return values
+}
+
+// nonNil returns the list, empty when nil, as the CEL lists cannot be null.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+
+	return values
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+)
+
+// DNSVerification resolves the DNS names requested by the CSRs, requiring them to point at the IP address of the
+// requesting peer, or at one of the trusted networks, so the nodes cannot claim hostnames they don't serve.
+type DNSVerification struct {
+	// Resolver resolves the DNS names: nil uses net.DefaultResolver.
+	Resolver *net.Resolver
+	// Networks are the networks the DNS names may resolve to in place of the peer address, such as a load balancer.
+	Networks []*net.IPNet
+	// Bypass are the DNS names not verified, as path.Match patterns such as *.internal.
+	Bypass []string
+	// CacheTTL is the duration the resolved addresses are cached for: zero disables the cache.
+	CacheTTL time.Duration
+	// SkipWildcards leaves the wildcard DNS names unverified, as they are stripped from the certificates.
+	SkipWildcards bool
+
+	mu    sync.Mutex
+	cache map[string]resolved
+}
+
+// resolved holds the addresses of a DNS name, cached until the expiration.
+type resolved struct {
+	addresses []net.IP
+	expiresAt time.Time
+}
+
+// Name implements Validator.
+func (*DNSVerification) Name() string {
+	return "dns"
+}
+
+// Validate implements Validator.
+func (v *DNSVerification) Validate(ctx context.Context, csr *x509.CertificateRequest) Verdict {
+	peerIP := peerAddress(ctx)
+
+	var verified []string
+
+	for _, name := range csr.DNSNames {
+		if v.SkipWildcards && IsWildcard(name) {
+			continue
+		}
+
+		if slices.ContainsFunc(v.Bypass, func(pattern string) bool {
+			matched, _ := path.Match(pattern, name)
+
+			return matched
+		}) {
+			continue
+		}
+
+		addresses, err := v.lookup(ctx, name)
+		if err != nil {
+			var dnsErr *net.DNSError
+			if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+				return Deny("dns", codes.PermissionDenied, "DNS name %s does not resolve", name).WithRule("policy-dns-verification")
+			}
+
+			return Fail("dns", "failed to resolve "+name, err)
+		}
+
+		if !slices.ContainsFunc(addresses, func(ip net.IP) bool {
+			return (peerIP != nil && ip.Equal(peerIP)) || slices.ContainsFunc(v.Networks, func(network *net.IPNet) bool { return network.Contains(ip) })
+		}) {
+			return Deny("dns", codes.PermissionDenied, "DNS name %s resolves to %v, not to the peer address %v", name, addresses, peerIP).
+				WithRule("policy-dns-verification")
+		}
+
+		verified = append(verified, name)
+	}
+
+	return Allow("dns", "DNS names %v point at the peer address %v", verified, peerIP)
+}
+
+// lookup returns the addresses of the DNS name, from the cache when not expired.
+func (v *DNSVerification) lookup(ctx context.Context, name string) ([]net.IP, error) {
+	now := time.Now()
+
+	v.mu.Lock()
+	entry, found := v.cache[name]
+	v.mu.Unlock()
+
+	if found && now.Before(entry.expiresAt) {
+		return entry.addresses, nil
+	}
+
+	resolver := v.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	ipAddrs, err := resolver.LookupIPAddr(ctx, name)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	addresses := make([]net.IP, 0, len(ipAddrs))
+	for _, ipAddr := range ipAddrs {
+		addresses = append(addresses, ipAddr.IP)
+	}
+
+	if v.CacheTTL > 0 {
+		v.mu.Lock()
+		if v.cache == nil {
+			v.cache = make(map[string]resolved)
+		}
+
+		// Dropping the expired entries keeps the cache bounded by the names resolved within the TTL
+		for cached, cachedEntry := range v.cache {
+			if !now.Before(cachedEntry.expiresAt) {
+				delete(v.cache, cached)
+			}
+		}
+
+		v.cache[name] = resolved{addresses: addresses, expiresAt: now.Add(v.CacheTTL)}
+		v.mu.Unlock()
+	}
+
+	return addresses, nil
+}
+
+// peerAddress returns the IP address of the requesting peer, nil when unknown such as with an offline evaluation.
+func peerAddress(ctx context.Context) net.IP {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return nil
+	}
+
+	return net.ParseIP(host)
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"context"
+	"crypto/x509"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/schedule"
+)
+
+// EnrollmentWindow restricts the enrollment of new identities to the maintenance windows, keeping the renewals
+// always allowed: the requests authenticated with a verified client certificate for the same Common Name, and the
+// ones of a Common Name holding a certificate in the ledger, not revoked.
+type EnrollmentWindow struct {
+	Ledger ledger.Ledger
+	// Windows are the time windows the new identities are enrolled in.
+	Windows []*schedule.Window
+	// Location is the time zone the windows are evaluated in: nil uses UTC.
+	Location *time.Location
+}
+
+// Name implements Validator.
+func (EnrollmentWindow) Name() string {
+	return "enrollment"
+}
+
+// Validate implements Validator.
+func (e EnrollmentWindow) Validate(ctx context.Context, csr *x509.CertificateRequest) Verdict {
+	location := e.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	now := time.Now().In(location)
+
+	for _, window := range e.Windows {
+		if window.Open(now) {
+			return Allow("enrollment", "enrollment window %q open", window)
+		}
+	}
+
+	if isRenewal(ctx, csr) {
+		return Allow("enrollment", "authenticated renewal of %q", csr.Subject.CommonName)
+	}
+
+	records, err := e.Ledger.List(ctx)
+	if err != nil {
+		return Fail("enrollment", "ledger unavailable", err)
+	}
+
+	for _, record := range records {
+		if record.CommonName == csr.Subject.CommonName && !record.Revoked() {
+			return Allow("enrollment", "renewal of the enrolled %q", csr.Subject.CommonName)
+		}
+	}
+
+	var next time.Time
+
+	for _, window := range e.Windows {
+		if opening, found := window.Next(now); found && (next.IsZero() || opening.Before(next)) {
+			next = opening
+		}
+	}
+
+	if next.IsZero() {
+		return Deny("enrollment", codes.PermissionDenied, "enrollment of new identities is closed outside the maintenance windows").
+			WithRule("enrollment-windows")
+	}
+
+	return Deny("enrollment", codes.PermissionDenied, "enrollment of new identities is closed until %s", next.Format(time.RFC3339)).
+		WithRule("enrollment-windows")
+}
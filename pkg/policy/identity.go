@@ -0,0 +1,135 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+	"path"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// TokenIdentity is the node identity the tokens of a token class are bound to.
+type TokenIdentity struct {
+	// Class is the name of the token class.
+	Class string
+	// CommonName is the Common Name of the node, empty to allow any.
+	CommonName string
+	// DNSPatterns are the allowed DNS names, as patterns whose * matches a single label: an empty list allows none.
+	DNSPatterns []string
+	// IPRanges are the networks the IP addresses must belong to: an empty list allows none.
+	IPRanges []*net.IPNet
+	// EmailPatterns are the allowed email addresses, as path.Match patterns: an empty list allows none.
+	EmailPatterns []string
+	// URIPatterns are the allowed URIs, as path.Match patterns: an empty list allows none.
+	URIPatterns []string
+}
+
+// Bound returns true when the identity restricts the CSRs.
+func (i TokenIdentity) Bound() bool {
+	return i.CommonName != "" || len(i.DNSPatterns) > 0 || len(i.IPRanges) > 0 || len(i.EmailPatterns) > 0 ||
+		len(i.URIPatterns) > 0
+}
+
+// MatchDNSName reports whether the DNS name matches the pattern, label by label: a * matches within a single label,
+// so *.example.com matches node.example.com but not a.node.example.com. The names are compared regardless of case.
+func MatchDNSName(pattern, name string) bool {
+	patternLabels := strings.Split(strings.ToLower(pattern), ".")
+	nameLabels := strings.Split(strings.ToLower(name), ".")
+
+	if len(patternLabels) != len(nameLabels) {
+		return false
+	}
+
+	for i, label := range patternLabels {
+		if matched, err := path.Match(label, nameLabels[i]); err != nil || !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesAny reports whether the value matches one of the path.Match patterns.
+func matchesAny(patterns []string, value string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		matched, _ := path.Match(pattern, value)
+
+		return matched
+	})
+}
+
+// TokenIdentityPolicy restricts the CSRs authenticated by a token bound to a node identity, carried by the context,
+// to the Common Name and the SANs of the node: a token can only obtain certificates for its own node. The SANs out of
+// the identity are refused, whatever the profile passes, so an identity of the Common Name only allows no SAN.
+type TokenIdentityPolicy struct{}
+
+// Name implements Validator.
+func (TokenIdentityPolicy) Name() string {
+	return "token-identity"
+}
+
+// Validate implements Validator.
+func (TokenIdentityPolicy) Validate(ctx context.Context, csr *x509.CertificateRequest) Verdict {
+	identity, ok := TokenIdentityFromContext(ctx)
+	if !ok {
+		return Allow("token-identity", "the token is bound to no node identity")
+	}
+
+	deny := func(format string, args ...any) Verdict {
+		return Deny("token-identity", codes.PermissionDenied, format, args...).
+			WithErrorReason(pkgerrors.ReasonTokenIdentity).WithRule("token-classes-path")
+	}
+
+	if identity.CommonName != "" && csr.Subject.CommonName != identity.CommonName {
+		return deny("the %s token class is bound to %s, not to %s", identity.Class, identity.CommonName, csr.Subject.CommonName)
+	}
+
+	for _, name := range csr.DNSNames {
+		if !slices.ContainsFunc(identity.DNSPatterns, func(pattern string) bool { return MatchDNSName(pattern, name) }) {
+			return deny("DNS name %s is not allowed to the %s token class", name, identity.Class)
+		}
+	}
+
+	for _, ip := range csr.IPAddresses {
+		if !slices.ContainsFunc(identity.IPRanges, func(ipRange *net.IPNet) bool { return ipRange.Contains(ip) }) {
+			return deny("IP address %s is not allowed to the %s token class", ip, identity.Class)
+		}
+	}
+
+	for _, email := range csr.EmailAddresses {
+		if !matchesAny(identity.EmailPatterns, email) {
+			return deny("email address %s is not allowed to the %s token class", email, identity.Class)
+		}
+	}
+
+	for _, uri := range csr.URIs {
+		if !matchesAny(identity.URIPatterns, uri.String()) {
+			return deny("URI %s is not allowed to the %s token class", uri, identity.Class)
+		}
+	}
+
+	return Allow("token-identity", "%s matches the identity of the %s token class", csr.Subject.CommonName, identity.Class)
+}
+
+// tokenIdentityContextKey is the context key of the node identity of the request token.
+type tokenIdentityContextKey struct{}
+
+// NewTokenIdentityContext returns the context carrying the node identity the token of the request is bound to.
+func NewTokenIdentityContext(ctx context.Context, identity TokenIdentity) context.Context {
+	return context.WithValue(ctx, tokenIdentityContextKey{}, identity)
+}
+
+// TokenIdentityFromContext returns the node identity carried by the context, false when the token is bound to none.
+func TokenIdentityFromContext(ctx context.Context) (TokenIdentity, bool) {
+	identity, ok := ctx.Value(tokenIdentityContextKey{}).(TokenIdentity)
+
+	return identity, ok
+}
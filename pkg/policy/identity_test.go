@@ -0,0 +1,131 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"testing"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+func TestMatchDNSName(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		matched bool
+	}{
+		{"worker-1.example.com", "worker-1.example.com", true},
+		{"worker-1.example.com", "WORKER-1.Example.COM", true},
+		{"*.example.com", "worker-1.example.com", true},
+		{"*.example.com", "a.worker-1.example.com", false},
+		{"*.example.com", "example.com", false},
+		{"worker-*.example.com", "worker-12.example.com", true},
+		{"worker-*.example.com", "control-1.example.com", false},
+		{"*.*.example.com", "a.b.example.com", true},
+		{"worker-[.example.com", "worker-[.example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			if matched := MatchDNSName(tt.pattern, tt.name); matched != tt.matched {
+				t.Fatalf("expected matched %t, got %t", tt.matched, matched)
+			}
+		})
+	}
+}
+
+func TestTokenIdentityPolicy(t *testing.T) {
+	_, nodes, _ := net.ParseCIDR("10.0.0.0/24")
+	identity := TokenIdentity{
+		Class:         "workers",
+		CommonName:    "worker-1",
+		DNSPatterns:   []string{"worker-1", "*.nodes.example.com"},
+		IPRanges:      []*net.IPNet{nodes},
+		EmailPatterns: []string{"*@nodes.example.com"},
+		URIPatterns:   []string{"spiffe://example.com/node/*"},
+	}
+
+	csr := func(change func(*x509.CertificateRequest)) *x509.CertificateRequest {
+		csr := &x509.CertificateRequest{
+			Subject:     pkix.Name{CommonName: "worker-1"},
+			DNSNames:    []string{"worker-1", "worker-1.nodes.example.com"},
+			IPAddresses: []net.IP{net.ParseIP("10.0.0.1")},
+		}
+
+		if change != nil {
+			change(csr)
+		}
+
+		return csr
+	}
+
+	tests := []struct {
+		name     string
+		identity *TokenIdentity
+		csr      *x509.CertificateRequest
+		allowed  bool
+	}{
+		{name: "unbound token", csr: csr(func(c *x509.CertificateRequest) { c.Subject.CommonName = "other" }), allowed: true},
+		{name: "identity of the node", identity: &identity, csr: csr(nil), allowed: true},
+		{
+			name:     "allowed email and URI",
+			identity: &identity,
+			csr: csr(func(c *x509.CertificateRequest) {
+				c.EmailAddresses = []string{"worker-1@nodes.example.com"}
+				c.URIs = []*url.URL{{Scheme: "spiffe", Host: "example.com", Path: "/node/worker-1"}}
+			}),
+			allowed: true,
+		},
+		{name: "other Common Name", identity: &identity, csr: csr(func(c *x509.CertificateRequest) { c.Subject.CommonName = "worker-2" })},
+		{name: "DNS name out of the patterns", identity: &identity, csr: csr(func(c *x509.CertificateRequest) { c.DNSNames = append(c.DNSNames, "worker-2") })},
+		{name: "DNS name of a subdomain", identity: &identity, csr: csr(func(c *x509.CertificateRequest) { c.DNSNames = []string{"a.worker-1.nodes.example.com"} })},
+		{name: "IP address out of the ranges", identity: &identity, csr: csr(func(c *x509.CertificateRequest) { c.IPAddresses = []net.IP{net.ParseIP("10.0.1.1")} })},
+		{name: "email out of the patterns", identity: &identity, csr: csr(func(c *x509.CertificateRequest) { c.EmailAddresses = []string{"admin@example.com"} })},
+		{name: "URI out of the patterns", identity: &identity, csr: csr(func(c *x509.CertificateRequest) { c.URIs = []*url.URL{{Scheme: "https", Host: "example.com"}} })},
+		{
+			name:     "email with an identity without email patterns",
+			identity: &TokenIdentity{Class: "workers", CommonName: "worker-1"},
+			csr:      csr(func(c *x509.CertificateRequest) { c.EmailAddresses = []string{"worker-1@nodes.example.com"} }),
+		},
+		{
+			name:     "DNS name with an identity of the Common Name only",
+			identity: &TokenIdentity{Class: "workers", CommonName: "worker-1"},
+			csr:      csr(func(c *x509.CertificateRequest) { c.DNSNames, c.IPAddresses = []string{"anything.example.com"}, nil }),
+		},
+		{
+			name:     "IP address with an identity of the Common Name only",
+			identity: &TokenIdentity{Class: "workers", CommonName: "worker-1"},
+			csr:      csr(func(c *x509.CertificateRequest) { c.DNSNames = nil }),
+		},
+		{
+			name:     "no SAN with an identity of the Common Name only",
+			identity: &TokenIdentity{Class: "workers", CommonName: "worker-1"},
+			csr:      csr(func(c *x509.CertificateRequest) { c.DNSNames, c.IPAddresses = nil, nil }),
+			allowed:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+			if tt.identity != nil {
+				ctx = NewTokenIdentityContext(ctx, *tt.identity)
+			}
+
+			verdict := TokenIdentityPolicy{}.Validate(ctx, tt.csr)
+
+			if verdict.Allowed() != tt.allowed {
+				t.Fatalf("expected allowed %t, got %+v", tt.allowed, verdict)
+			}
+
+			if !tt.allowed && verdict.ErrorReason != pkgerrors.ReasonTokenIdentity {
+				t.Fatalf("expected the %s reason, got %s", pkgerrors.ReasonTokenIdentity, verdict.ErrorReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// OPA delegates the admission of the CSRs to an Open Policy Agent, POSTing them to the Data API of the decision,
+// such as http://opa:8181/v1/data/talos/signer, as the input:
+//
+//	{"csr": {"commonName": "worker-1", "dnsNames": ["worker-1.nodes.example.com"], ...}, "metadata": {...}, "peer": "10.0.0.5"}
+//
+// The csr and the metadata are the ones seen by the CEL expressions. The decision is either a bool, or an object
+// holding the allow bool and the reasons of the denial: an undefined decision denies the CSR.
+type OPA struct {
+	url    string
+	client *http.Client
+}
+
+// NewOPA returns the validator querying the decision at the URL of the Open Policy Agent Data API.
+func NewOPA(url string, timeout time.Duration) *OPA {
+	return &OPA{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// opaDecision is the result of the Data API, either a bool or an object.
+type opaDecision struct {
+	Allow   bool     `json:"allow"`
+	Reasons []string `json:"reasons"`
+}
+
+// Name implements Validator.
+func (*OPA) Name() string {
+	return "opa"
+}
+
+// Validate implements Validator.
+func (o *OPA) Validate(ctx context.Context, csr *x509.CertificateRequest) Verdict {
+	input := map[string]any{
+		"csr":      csrAttributes(csr),
+		"metadata": requestMetadata(ctx),
+	}
+
+	if address := peerAddress(ctx); address != nil {
+		input["peer"] = address.String()
+	}
+
+	result, err := o.query(ctx, input)
+	if err != nil {
+		return Fail("opa", "Open Policy Agent unavailable", err)
+	}
+
+	if len(result) == 0 {
+		return Deny("opa", codes.PermissionDenied, "the policy decision is undefined").WithRule("policy-opa-url")
+	}
+
+	var decision opaDecision
+	if err = json.Unmarshal(result, &decision.Allow); err != nil {
+		if err = json.Unmarshal(result, &decision); err != nil {
+			return Fail("opa", "invalid policy decision", errors.Wrap(pkgerrors.ErrOPA, err.Error()))
+		}
+	}
+
+	if !decision.Allow {
+		if len(decision.Reasons) == 0 {
+			return Deny("opa", codes.PermissionDenied, "CSR denied by the Open Policy Agent").WithRule("policy-opa-url")
+		}
+
+		return Deny("opa", codes.PermissionDenied, "%s", strings.Join(decision.Reasons, ", ")).WithRule("policy-opa-url")
+	}
+
+	return Allow("opa", "CSR allowed by the Open Policy Agent")
+}
+
+// query returns the result of the Data API for the input, empty when the decision is undefined.
+func (o *OPA) query(ctx context.Context, input map[string]any) (json.RawMessage, error) {
+	body, err := json.Marshal(map[string]any{"input": input})
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrOPA, err.Error())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrOPA, err.Error())
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrOPA, err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Wrapf(pkgerrors.ErrOPA, "%s answered %s", o.url, resp.Status)
+	}
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrOPA, "invalid response: "+err.Error())
+	}
+
+	return response.Result, nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package policy contains the validation pipeline the CSRs go through before being signed: a chain of
+// Validators, each returning a typed Verdict consumed by the audit events and the metrics.
+package policy
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Outcome is the decision of a Validator.
+type Outcome string
+
+const (
+	// OutcomeAllow lets the CSR go through the next validators.
+	OutcomeAllow Outcome = "allow"
+	// OutcomeDeny rejects the CSR, as it violates the policy.
+	OutcomeDeny Outcome = "deny"
+	// OutcomeError rejects the CSR, as the validator could not decide, such as the ledger being unavailable.
+	OutcomeError Outcome = "error"
+	// OutcomeSimulate lets the CSR through the next validators, though a simulated validator rejected it.
+	OutcomeSimulate Outcome = "simulate"
+)
+
+// Verdict is the decision of a Validator on a CSR.
+type Verdict struct {
+	// Validator is the name of the validator which decided.
+	Validator string
+	// Outcome is the decision.
+	Outcome Outcome
+	// Code is the gRPC code answered to the client when the CSR is rejected.
+	Code codes.Code
+	// Reason explains the decision, answered to the client when the CSR is rejected.
+	Reason string
+	// ErrorReason is the ErrorInfo reason answered to the client when the CSR is rejected: empty answers the
+	// generic policy denial.
+	ErrorReason string
+	// Rule identifies the rule of the configuration which decided, as the name of its setting such as
+	// policy-ip-ranges: empty when the validator holds a single rule, or none such as the plugins.
+	Rule string
+	// Err is the failure preventing the decision, with OutcomeError.
+	Err error
+}
+
+// Allow returns the Verdict allowing the CSR.
+func Allow(validator, format string, args ...any) Verdict {
+	return Verdict{Validator: validator, Outcome: OutcomeAllow, Code: codes.OK, Reason: fmt.Sprintf(format, args...)}
+}
+
+// Deny returns the Verdict rejecting the CSR with the given gRPC code.
+func Deny(validator string, code codes.Code, format string, args ...any) Verdict {
+	return Verdict{Validator: validator, Outcome: OutcomeDeny, Code: code, Reason: fmt.Sprintf(format, args...)}
+}
+
+// WithErrorReason returns the Verdict answering the given ErrorInfo reason when the CSR is rejected.
+func (v Verdict) WithErrorReason(reason string) Verdict {
+	v.ErrorReason = reason
+
+	return v
+}
+
+// WithRule returns the Verdict decided by the rule of the configuration, named by its setting.
+func (v Verdict) WithRule(rule string) Verdict {
+	v.Rule = rule
+
+	return v
+}
+
+// Fail returns the Verdict rejecting the CSR as the validator could not decide.
+func Fail(validator, reason string, err error) Verdict {
+	return Verdict{Validator: validator, Outcome: OutcomeError, Code: codes.Unavailable, Reason: reason, Err: err}
+}
+
+// Allowed returns true when the CSR is allowed, or only rejected by a simulated validator.
+func (v Verdict) Allowed() bool {
+	return v.Outcome == OutcomeAllow || v.Outcome == OutcomeSimulate
+}
+
+// Validator evaluates the CSRs against a rule of the policy.
+type Validator interface {
+	// Name returns the identifier of the validator, reported in the verdicts.
+	Name() string
+	// Validate returns the verdict on the CSR, whose signature has been verified.
+	Validate(ctx context.Context, csr *x509.CertificateRequest) Verdict
+}
+
+// Chain is a sequence of Validators.
+type Chain []Validator
+
+// Then returns the Chain followed by the given validators.
+func (c Chain) Then(validators ...Validator) Chain {
+	chain := make(Chain, 0, len(c)+len(validators))
+
+	return append(append(chain, c...), validators...)
+}
+
+// Validate runs the validators in order, stopping at the first one rejecting the CSR.
+// It returns the verdicts of the validators run, the last one being the decisive one.
+func (c Chain) Validate(ctx context.Context, csr *x509.CertificateRequest) []Verdict {
+	verdicts := make([]Verdict, 0, len(c))
+
+	for _, validator := range c {
+		verdict := validator.Validate(ctx, csr)
+		verdicts = append(verdicts, verdict)
+
+		if !verdict.Allowed() {
+			break
+		}
+	}
+
+	return verdicts
+}
+
+// Evaluate runs all the validators, without stopping at the first one rejecting the CSR,
+// reporting every rule the CSR violates.
+func (c Chain) Evaluate(ctx context.Context, csr *x509.CertificateRequest) []Verdict {
+	verdicts := make([]Verdict, 0, len(c))
+
+	for _, validator := range c {
+		verdicts = append(verdicts, validator.Validate(ctx, csr))
+	}
+
+	return verdicts
+}
+
+// Decisive returns the verdict deciding the outcome of the chain: the first one rejecting the CSR,
+// or an allowing one when all the validators allowed it.
+func Decisive(verdicts []Verdict) Verdict {
+	for _, verdict := range verdicts {
+		if !verdict.Allowed() {
+			return verdict
+		}
+	}
+
+	return Allow("chain", "all the validators allowed the CSR")
+}
@@ -0,0 +1,62 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"context"
+	"crypto/x509"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+)
+
+// static is the Validator returning the same verdict for every CSR.
+type static struct {
+	name    string
+	allowed bool
+}
+
+func (s static) Name() string {
+	return s.name
+}
+
+func (s static) Validate(context.Context, *x509.CertificateRequest) Verdict {
+	if s.allowed {
+		return Allow(s.name, "allowed")
+	}
+
+	return Deny(s.name, codes.PermissionDenied, "denied")
+}
+
+func TestChain(t *testing.T) {
+	chain := Chain{static{name: "first", allowed: true}}.Then(static{name: "second"}, static{name: "third"})
+
+	validators := func(verdicts []Verdict) []string {
+		names := make([]string, 0, len(verdicts))
+		for _, verdict := range verdicts {
+			names = append(names, verdict.Validator)
+		}
+
+		return names
+	}
+
+	validated := chain.Validate(t.Context(), &x509.CertificateRequest{})
+	if !slices.Equal(validators(validated), []string{"first", "second"}) {
+		t.Fatalf("expected the chain to stop at the first denial, got %v", validators(validated))
+	}
+
+	evaluated := chain.Evaluate(t.Context(), &x509.CertificateRequest{})
+	if !slices.Equal(validators(evaluated), []string{"first", "second", "third"}) {
+		t.Fatalf("expected every validator to be evaluated, got %v", validators(evaluated))
+	}
+
+	if decisive := Decisive(evaluated); decisive.Validator != "second" || decisive.Code != codes.PermissionDenied {
+		t.Fatalf("unexpected decisive verdict %+v", decisive)
+	}
+
+	if decisive := Decisive(chain[:1].Validate(t.Context(), &x509.CertificateRequest{})); !decisive.Allowed() {
+		t.Fatalf("expected the chain to allow the CSR, got %+v", decisive)
+	}
+}
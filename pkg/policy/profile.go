@@ -0,0 +1,62 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"context"
+	"crypto/x509"
+
+	"google.golang.org/grpc/codes"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+)
+
+// ProfileKeyPolicy requires the CSR key algorithm mandated by the profile of the machine role, such as Ed25519 for
+// the Talos machine identities, on top of the algorithms allowed by the KeyPolicy. The role carried by the context
+// replaces the detected one.
+type ProfileKeyPolicy struct {
+	// Roles detects the machine role of the CSRs, and holds the profiles of the roles.
+	Roles *signer.Roles
+}
+
+// Name implements Validator.
+func (ProfileKeyPolicy) Name() string {
+	return "profile-key"
+}
+
+// Validate implements Validator.
+func (p ProfileKeyPolicy) Validate(ctx context.Context, csr *x509.CertificateRequest) Verdict {
+	role := p.Roles.Detect(csr)
+	if forced, ok := RoleFromContext(ctx); ok {
+		role = forced
+	}
+
+	profile := p.Roles.Profile(role)
+	algorithm := pki.KeyAlgorithm(csr.PublicKey)
+
+	if !profile.AllowsKey(csr.PublicKey) {
+		return Deny("profile-key", codes.InvalidArgument, "%s keys are not allowed for the %s profile, expected one of %v",
+			algorithm, role, profile.KeyAlgorithms).WithErrorReason(pkgerrors.ReasonKeyAlgorithm).WithRule(string(role) + "-key-algorithms")
+	}
+
+	return Allow("profile-key", "%s key allowed for the %s profile", algorithm, role)
+}
+
+// roleContextKey is the context key of the machine role of the request.
+type roleContextKey struct{}
+
+// NewRoleContext returns the context carrying the machine role of the request, such as the one of its token class,
+// replacing the role detected on the CSR.
+func NewRoleContext(ctx context.Context, role signer.Role) context.Context {
+	return context.WithValue(ctx, roleContextKey{}, role)
+}
+
+// RoleFromContext returns the machine role carried by the context, false when it's detected on the CSR.
+func RoleFromContext(ctx context.Context) (signer.Role, bool) {
+	role, ok := ctx.Value(roleContextKey{}).(signer.Role)
+
+	return role, ok
+}
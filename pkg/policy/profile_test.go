@@ -0,0 +1,61 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+)
+
+func TestProfileKeyPolicy(t *testing.T) {
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := ProfileKeyPolicy{Roles: &signer.Roles{
+		ControlPlane:              signer.Profile{KeyAlgorithms: []string{"ed25519"}},
+		ControlPlaneOrganizations: []string{"os:controlplane"},
+	}}
+
+	tests := []struct {
+		name          string
+		organizations []string
+		key           bool
+		allowed       bool
+	}{
+		{name: "worker key", allowed: true},
+		{name: "control-plane Ed25519 key", organizations: []string{"os:controlplane"}, key: true, allowed: true},
+		{name: "control-plane ECDSA key", organizations: []string{"os:controlplane"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := &x509.CertificateRequest{Subject: pkix.Name{CommonName: "node-1", Organization: tt.organizations}}
+
+			var key crypto.Signer
+			if tt.key {
+				key = ed25519Key
+			}
+
+			verdict := policy.Validate(t.Context(), newCSR(t, template, key))
+			if verdict.Allowed() != tt.allowed {
+				t.Fatalf("expected allowed %t, got %+v", tt.allowed, verdict)
+			}
+
+			if !tt.allowed && (verdict.Code != codes.InvalidArgument || verdict.ErrorReason != pkgerrors.ReasonKeyAlgorithm) {
+				t.Fatalf("expected an invalid key algorithm, got %+v", verdict)
+			}
+		})
+	}
+}
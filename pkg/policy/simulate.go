@@ -0,0 +1,53 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"context"
+	"crypto/x509"
+	"slices"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// Simulated is a Validator whose decisions are only reported: the CSRs it rejects, or could not decide on, are let
+// through with OutcomeSimulate, rolling out a policy change on a live fleet before enforcing it.
+type Simulated struct {
+	Validator
+}
+
+// Validate implements Validator.
+func (s Simulated) Validate(ctx context.Context, csr *x509.CertificateRequest) Verdict {
+	verdict := s.Validator.Validate(ctx, csr)
+	if verdict.Outcome != OutcomeAllow {
+		verdict.Outcome, verdict.Code = OutcomeSimulate, codes.OK
+	}
+
+	return verdict
+}
+
+// Simulate returns the Chain whose validators of the given names are Simulated ones. It returns an error when the
+// Chain holds no validator of a name, such as a disabled one.
+func (c Chain) Simulate(names []string) (Chain, error) {
+	for _, name := range names {
+		if !slices.ContainsFunc(c, func(validator Validator) bool { return validator.Name() == name }) {
+			return nil, errors.Wrap(pkgerrors.ErrPolicy, "no validator "+name+" to simulate")
+		}
+	}
+
+	chain := make(Chain, 0, len(c))
+
+	for _, validator := range c {
+		if slices.Contains(names, validator.Name()) {
+			validator = Simulated{Validator: validator}
+		}
+
+		chain = append(chain, validator)
+	}
+
+	return chain, nil
+}
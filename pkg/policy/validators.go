@@ -0,0 +1,446 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"net"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+)
+
+// Signature verifies the CSR is signed by the private key of its public key.
+type Signature struct{}
+
+// Name implements Validator.
+func (Signature) Name() string {
+	return "signature"
+}
+
+// Validate implements Validator.
+func (Signature) Validate(_ context.Context, csr *x509.CertificateRequest) Verdict {
+	if err := csr.CheckSignature(); err != nil {
+		return Deny("signature", codes.InvalidArgument, "invalid CSR signature: %v", err)
+	}
+
+	return Allow("signature", "%s signature verified", csr.SignatureAlgorithm)
+}
+
+// KeyPolicy restricts the algorithms and sizes of the CSR public keys.
+type KeyPolicy struct {
+	// Algorithms are the allowed key algorithms: ed25519, ecdsa, and rsa.
+	Algorithms []string
+	// MinRSABits is the minimum size of the RSA keys.
+	MinRSABits int
+}
+
+// Name implements Validator.
+func (KeyPolicy) Name() string {
+	return "key"
+}
+
+// Validate implements Validator.
+func (p KeyPolicy) Validate(_ context.Context, csr *x509.CertificateRequest) Verdict {
+	var algorithm string
+
+	switch key := csr.PublicKey.(type) {
+	case ed25519.PublicKey:
+		algorithm = "ed25519"
+	case *ecdsa.PublicKey:
+		algorithm = "ecdsa"
+	case *rsa.PublicKey:
+		algorithm = "rsa"
+
+		if bits := key.N.BitLen(); bits < p.MinRSABits {
+			return Deny("key", codes.InvalidArgument, "RSA key of %d bits, at least %d required", bits, p.MinRSABits).
+				WithRule("policy-min-rsa-bits")
+		}
+	default:
+		return Deny("key", codes.InvalidArgument, "unsupported public key %T", key).WithRule("policy-key-algorithms")
+	}
+
+	if !slices.Contains(p.Algorithms, algorithm) {
+		return Deny("key", codes.InvalidArgument, "%s keys are not allowed, expected one of %v", algorithm, p.Algorithms).
+			WithRule("policy-key-algorithms")
+	}
+
+	return Allow("key", "%s key allowed", algorithm)
+}
+
+// SizePolicy bounds the number of SANs and of extensions of the CSRs, protecting the signer and the verifiers of the
+// certificates from the oversized ones: zero disables a limit.
+type SizePolicy struct {
+	// MaxDNSNames is the maximum number of DNS names.
+	MaxDNSNames int
+	// MaxIPAddresses is the maximum number of IP addresses.
+	MaxIPAddresses int
+	// MaxExtensions is the maximum number of extensions, the SANs one included.
+	MaxExtensions int
+}
+
+// Name implements Validator.
+func (SizePolicy) Name() string {
+	return "size"
+}
+
+// Validate implements Validator.
+func (p SizePolicy) Validate(_ context.Context, csr *x509.CertificateRequest) Verdict {
+	if p.MaxDNSNames > 0 && len(csr.DNSNames) > p.MaxDNSNames {
+		return Deny("size", codes.InvalidArgument, "%d DNS names, at most %d allowed", len(csr.DNSNames), p.MaxDNSNames).
+			WithRule("policy-max-dns-names")
+	}
+
+	if p.MaxIPAddresses > 0 && len(csr.IPAddresses) > p.MaxIPAddresses {
+		return Deny("size", codes.InvalidArgument, "%d IP addresses, at most %d allowed", len(csr.IPAddresses), p.MaxIPAddresses).
+			WithRule("policy-max-ip-addresses")
+	}
+
+	if p.MaxExtensions > 0 && len(csr.Extensions) > p.MaxExtensions {
+		return Deny("size", codes.InvalidArgument, "%d extensions, at most %d allowed", len(csr.Extensions), p.MaxExtensions).
+			WithRule("policy-max-extensions")
+	}
+
+	return Allow("size", "%d DNS names, %d IP addresses, and %d extensions allowed",
+		len(csr.DNSNames), len(csr.IPAddresses), len(csr.Extensions))
+}
+
+const (
+	// SANsDrop issues the certificates of the CSRs without their email addresses or URIs.
+	SANsDrop = "drop"
+	// SANsPass copies the email addresses or URIs of the CSRs into the certificates, as long as they match the
+	// allowed patterns.
+	SANsPass = "pass"
+	// SANsReject refuses the CSRs with email addresses or URIs.
+	SANsReject = "reject"
+)
+
+const (
+	// WildcardsAllow signs the CSRs with wildcard DNS names, as long as the DNS name patterns allow them.
+	WildcardsAllow = "allow"
+	// WildcardsReject refuses the CSRs with wildcard DNS names.
+	WildcardsReject = "reject"
+	// WildcardsStrip issues the certificates of the CSRs without their wildcard DNS names.
+	WildcardsStrip = "strip"
+)
+
+// IsLocalIP returns true when the IP address is a loopback or a link-local one, such as 127.0.0.1, ::1, or
+// 169.254.0.1, meaningless outside of the node.
+func IsLocalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast()
+}
+
+// IsWildcard returns true when the DNS name holds a wildcard label, such as *.svc.cluster.local.
+func IsWildcard(name string) bool {
+	return strings.Contains(name, "*")
+}
+
+// SANPolicy restricts the Subject Alternative Names of the CSRs: an empty list allows any value.
+type SANPolicy struct {
+	// DNSPatterns are the allowed DNS names, as patterns such as *.nodes.example.com matched by MatchDNSName.
+	DNSPatterns []string
+	// DNSRegexps are the allowed DNS names, as regular expressions matching the whole name: a name matching either
+	// a pattern or a regular expression is allowed.
+	DNSRegexps []*regexp.Regexp
+	// IPRanges are the networks the IP addresses must belong to.
+	IPRanges []*net.IPNet
+	// StripLocalIPs leaves the loopback and link-local IP addresses out of the certificates, whatever the ranges.
+	StripLocalIPs bool
+	// WildcardAction is the action taken on the wildcard DNS names: empty allows them.
+	WildcardAction string
+	// EmailAction is the action taken on the email addresses: empty drops them.
+	EmailAction string
+	// EmailPatterns are the email addresses allowed when passed, as path.Match patterns such as *@example.com.
+	EmailPatterns []string
+	// URIAction is the action taken on the URIs: empty drops them.
+	URIAction string
+	// URIPatterns are the URIs allowed when passed, as path.Match patterns such as spiffe://example.com/ns/*/sa/*.
+	URIPatterns []string
+}
+
+// Name implements Validator.
+func (SANPolicy) Name() string {
+	return "san"
+}
+
+// Validate implements Validator.
+func (p SANPolicy) Validate(_ context.Context, csr *x509.CertificateRequest) Verdict {
+	if p.WildcardAction == WildcardsReject {
+		if index := slices.IndexFunc(csr.DNSNames, IsWildcard); index >= 0 {
+			return Deny("san", codes.PermissionDenied, "wildcard DNS name %s is not allowed", csr.DNSNames[index]).
+				WithRule("policy-wildcard-dns-names")
+		}
+	}
+
+	if len(p.DNSPatterns) > 0 || len(p.DNSRegexps) > 0 {
+		for _, name := range csr.DNSNames {
+			// The stripped names are left out of the certificate, whatever the patterns
+			if p.WildcardAction == WildcardsStrip && IsWildcard(name) {
+				continue
+			}
+
+			if !p.allowedDNSName(name) {
+				return Deny("san", codes.PermissionDenied, "DNS name %s is not allowed", name).WithRule(p.dnsRule())
+			}
+		}
+	}
+
+	if len(p.IPRanges) > 0 {
+		for _, ip := range csr.IPAddresses {
+			if p.StripLocalIPs && IsLocalIP(ip) {
+				continue
+			}
+
+			if !slices.ContainsFunc(p.IPRanges, func(ipRange *net.IPNet) bool { return ipRange.Contains(ip) }) {
+				return Deny("san", codes.PermissionDenied, "IP address %s is not allowed", ip).WithRule("policy-ip-ranges")
+			}
+		}
+	}
+
+	uris := make([]string, 0, len(csr.URIs))
+	for _, uri := range csr.URIs {
+		uris = append(uris, uri.String())
+	}
+
+	emailRules := sanRules{action: "policy-email-action", patterns: "policy-email-addresses"}
+	if verdict, denied := p.checkSANs("email address", csr.EmailAddresses, p.EmailAction, p.EmailPatterns, emailRules); denied {
+		return verdict
+	}
+
+	uriRules := sanRules{action: "policy-uri-action", patterns: "policy-uris"}
+	if verdict, denied := p.checkSANs("URI", uris, p.URIAction, p.URIPatterns, uriRules); denied {
+		return verdict
+	}
+
+	return Allow("san", "DNS names %v and IP addresses %v allowed", csr.DNSNames, csr.IPAddresses)
+}
+
+// sanRules are the settings of the action and of the patterns of the email addresses or URIs.
+type sanRules struct {
+	action   string
+	patterns string
+}
+
+// checkSANs returns the verdict denying the email addresses or URIs refused by the action, and true when one is.
+func (SANPolicy) checkSANs(kind string, values []string, action string, patterns []string, rules sanRules) (Verdict, bool) {
+	for _, value := range values {
+		switch {
+		case action == SANsReject:
+			return Deny("san", codes.PermissionDenied, "%s %s is not allowed", kind, value).WithRule(rules.action), true
+		case action == SANsPass && len(patterns) > 0 && !slices.ContainsFunc(patterns, func(pattern string) bool {
+			matched, _ := path.Match(pattern, value)
+
+			return matched
+		}):
+			return Deny("san", codes.PermissionDenied, "%s %s does not match %v", kind, value, patterns).
+				WithRule(rules.patterns), true
+		}
+	}
+
+	return Verdict{}, false
+}
+
+// dnsRule returns the setting of the DNS names allowed, the patterns unless only the regular expressions are set.
+func (p SANPolicy) dnsRule() string {
+	if len(p.DNSPatterns) == 0 {
+		return "policy-dns-regexps"
+	}
+
+	return "policy-dns-names"
+}
+
+// allowedDNSName reports whether the DNS name matches one of the patterns or of the regular expressions.
+func (p SANPolicy) allowedDNSName(name string) bool {
+	if slices.ContainsFunc(p.DNSPatterns, func(pattern string) bool { return MatchDNSName(pattern, name) }) {
+		return true
+	}
+
+	return slices.ContainsFunc(p.DNSRegexps, func(re *regexp.Regexp) bool { return re.MatchString(name) })
+}
+
+const (
+	// OrganizationsReject refuses the CSRs with a subject organization left out of the allowed ones.
+	OrganizationsReject = "reject"
+	// OrganizationsStrip issues the certificates of the CSRs without the subject organizations left out of the
+	// allowed ones.
+	OrganizationsStrip = "strip"
+)
+
+// SubjectPolicy restricts the subject of the CSRs.
+type SubjectPolicy struct {
+	// CommonName is the pattern the Common Name must match: nil allows any value.
+	CommonName *regexp.Regexp
+	// Organizations are the allowed subject organizations, such as os:reader: empty allows any value.
+	Organizations []string
+	// StripOrganizations allows the CSRs with other organizations, stripped by the profiles when signing.
+	StripOrganizations bool
+}
+
+// Name implements Validator.
+func (SubjectPolicy) Name() string {
+	return "subject"
+}
+
+// Validate implements Validator.
+func (p SubjectPolicy) Validate(_ context.Context, csr *x509.CertificateRequest) Verdict {
+	if p.CommonName != nil && !p.CommonName.MatchString(csr.Subject.CommonName) {
+		return Deny("subject", codes.PermissionDenied, "Common Name %q does not match %s", csr.Subject.CommonName, p.CommonName).
+			WithRule("policy-common-name")
+	}
+
+	if len(p.Organizations) > 0 && !p.StripOrganizations {
+		for _, organization := range csr.Subject.Organization {
+			if !slices.Contains(p.Organizations, organization) {
+				return Deny("subject", codes.PermissionDenied, "organization %q is not allowed, expected one of %v",
+					organization, p.Organizations).WithRule("policy-organizations")
+			}
+		}
+	}
+
+	return Allow("subject", "subject %q allowed", csr.Subject.String())
+}
+
+// UsagePolicy restricts the extended key usages the CSRs may request, issued in place of the ones of their profile.
+type UsagePolicy struct {
+	// ExtKeyUsages are the extended key usages the CSRs may request.
+	ExtKeyUsages []x509.ExtKeyUsage
+}
+
+// Name implements Validator.
+func (UsagePolicy) Name() string {
+	return "usage"
+}
+
+// Validate implements Validator.
+func (p UsagePolicy) Validate(_ context.Context, csr *x509.CertificateRequest) Verdict {
+	requested, err := signer.RequestedExtKeyUsages(csr)
+	if err != nil {
+		return Deny("usage", codes.InvalidArgument, "%s", err).WithRule("policy-extended-key-usages")
+	}
+
+	for _, usage := range requested {
+		if !slices.Contains(p.ExtKeyUsages, usage) {
+			return Deny("usage", codes.PermissionDenied, "extended key usage %s is not allowed", signer.ExtKeyUsageName(usage)).
+				WithRule("policy-extended-key-usages")
+		}
+	}
+
+	if len(requested) == 0 {
+		return Allow("usage", "no extended key usage requested, issuing the ones of the profile")
+	}
+
+	names := make([]string, 0, len(requested))
+	for _, usage := range requested {
+		names = append(names, signer.ExtKeyUsageName(usage))
+	}
+
+	return Allow("usage", "extended key usages %v allowed", names)
+}
+
+// Quota limits the certificates issued per Common Name in a time window, accounted in the ledger
+// shared across replicas: every validation consumes the quota.
+type Quota struct {
+	Ledger ledger.Ledger
+	// Limit is the maximum number of certificates issued per Common Name in the window.
+	Limit int64
+	// Window is the duration the certificates are accounted for.
+	Window time.Duration
+}
+
+// Name implements Validator.
+func (Quota) Name() string {
+	return "quota"
+}
+
+// Validate implements Validator.
+func (q Quota) Validate(ctx context.Context, csr *x509.CertificateRequest) Verdict {
+	count, err := q.Ledger.Increment(ctx, "quota:"+csr.Subject.CommonName, q.Window)
+	if err != nil {
+		return Fail("quota", "ledger unavailable", err)
+	}
+
+	if count > q.Limit {
+		return Deny("quota", codes.ResourceExhausted, "issuance quota exceeded").WithRule("issuance-quota")
+	}
+
+	return Allow("quota", "%d/%d certificates in %s", count, q.Limit, q.Window)
+}
+
+// Cooldown refuses a new certificate for the same identity, its Common Name and SANs, within the window, damping the
+// issuance loops of misconfigured nodes. Renewals authenticated with a verified client certificate for the same
+// Common Name are always allowed.
+type Cooldown struct {
+	Ledger ledger.Ledger
+	// Window is the duration a new certificate is refused for after the first request of the identity.
+	Window time.Duration
+}
+
+// Name implements Validator.
+func (Cooldown) Name() string {
+	return "cooldown"
+}
+
+// Validate implements Validator.
+func (c Cooldown) Validate(ctx context.Context, csr *x509.CertificateRequest) Verdict {
+	if isRenewal(ctx, csr) {
+		return Allow("cooldown", "authenticated renewal of %q", csr.Subject.CommonName)
+	}
+
+	count, err := c.Ledger.Increment(ctx, "cooldown:"+identity(csr), c.Window)
+	if err != nil {
+		return Fail("cooldown", "ledger unavailable", err)
+	}
+
+	if count > 1 {
+		return Deny("cooldown", codes.ResourceExhausted, "a certificate for the same identity was requested less than %s ago", c.Window).
+			WithRule("reissue-cooldown")
+	}
+
+	return Allow("cooldown", "no certificate requested for the same identity in the last %s", c.Window)
+}
+
+// identity returns the digest of the Common Name and of the SANs of the CSR, regardless of their order.
+func identity(csr *x509.CertificateRequest) string {
+	names := slices.Clone(csr.DNSNames)
+	for _, ip := range csr.IPAddresses {
+		names = append(names, ip.String())
+	}
+
+	slices.Sort(names)
+
+	digest := sha256.Sum256([]byte(csr.Subject.CommonName + "\n" + strings.Join(names, ",")))
+
+	return hex.EncodeToString(digest[:])
+}
+
+// isRenewal returns true when the request is authenticated with a client certificate verified by the signer,
+// issued for the Common Name of the CSR.
+func isRenewal(ctx context.Context, csr *x509.CertificateRequest) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 {
+		return false
+	}
+
+	return tlsInfo.State.VerifiedChains[0][0].Subject.CommonName == csr.Subject.CommonName
+}
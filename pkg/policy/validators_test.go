@@ -0,0 +1,321 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"net"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+)
+
+// newCSR returns the CSR of the template signed by the key, a new ECDSA one when nil.
+func newCSR(t *testing.T, template *x509.CertificateRequest, key crypto.Signer) *x509.CertificateRequest {
+	t.Helper()
+
+	if key == nil {
+		var err error
+		if key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return csr
+}
+
+func TestSignature(t *testing.T) {
+	csr := newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}}, nil)
+
+	if verdict := (Signature{}).Validate(t.Context(), csr); !verdict.Allowed() {
+		t.Fatalf("expected the signature to be verified, got %+v", verdict)
+	}
+
+	csr.Signature[len(csr.Signature)-1] ^= 0xff
+
+	if verdict := (Signature{}).Validate(t.Context(), csr); verdict.Allowed() || verdict.Code != codes.InvalidArgument {
+		t.Fatalf("expected the tampered signature to be denied, got %+v", verdict)
+	}
+}
+
+func TestKeyPolicy(t *testing.T) {
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		policy  KeyPolicy
+		key     crypto.Signer
+		allowed bool
+	}{
+		{name: "ecdsa", policy: KeyPolicy{Algorithms: []string{"ecdsa"}}, allowed: true},
+		{name: "ed25519", policy: KeyPolicy{Algorithms: []string{"ecdsa", "ed25519"}}, key: ed25519Key, allowed: true},
+		{name: "rsa", policy: KeyPolicy{Algorithms: []string{"rsa"}, MinRSABits: 2048}, key: rsaKey, allowed: true},
+		{name: "algorithm not allowed", policy: KeyPolicy{Algorithms: []string{"ed25519"}}},
+		{name: "rsa key too small", policy: KeyPolicy{Algorithms: []string{"rsa"}, MinRSABits: 3072}, key: rsaKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csr := newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}}, tt.key)
+
+			if verdict := tt.policy.Validate(t.Context(), csr); verdict.Allowed() != tt.allowed {
+				t.Fatalf("expected allowed %t, got %+v", tt.allowed, verdict)
+			}
+		})
+	}
+}
+
+func TestSANPolicy(t *testing.T) {
+	_, nodes, _ := net.ParseCIDR("10.0.0.0/24")
+	policy := SANPolicy{DNSPatterns: []string{"*.nodes.example.com", "localhost"}, IPRanges: []*net.IPNet{nodes}}
+	regexps := SANPolicy{DNSRegexps: []*regexp.Regexp{regexp.MustCompile(`^worker-[0-9]+\.example\.com$`)}}
+	both := SANPolicy{DNSPatterns: policy.DNSPatterns, DNSRegexps: regexps.DNSRegexps}
+	stripLocal := SANPolicy{IPRanges: policy.IPRanges, StripLocalIPs: true}
+
+	tests := []struct {
+		name     string
+		dnsNames []string
+		ips      []net.IP
+		policy   SANPolicy
+		allowed  bool
+	}{
+		{name: "allowed", dnsNames: []string{"worker-1.nodes.example.com", "localhost"}, ips: []net.IP{net.ParseIP("10.0.0.1")}, policy: policy, allowed: true},
+		{name: "no SANs", policy: policy, allowed: true},
+		{name: "empty policy", dnsNames: []string{"example.org"}, ips: []net.IP{net.ParseIP("192.168.0.1")}, allowed: true},
+		{name: "DNS name not allowed", dnsNames: []string{"worker-1.example.com"}, policy: policy},
+		{name: "DNS name of several labels matching the wildcard", dnsNames: []string{"evil.worker-1.nodes.example.com"}, policy: policy},
+		{name: "DNS name matching regardless of case", dnsNames: []string{"Worker-1.Nodes.Example.com"}, policy: policy, allowed: true},
+		{name: "IP address not allowed", ips: []net.IP{net.ParseIP("10.0.1.1")}, policy: policy},
+		{name: "DNS name matching a regular expression", dnsNames: []string{"worker-1.example.com"}, policy: regexps, allowed: true},
+		{name: "DNS name not matching the regular expressions", dnsNames: []string{"worker-a.example.com"}, policy: regexps},
+		{name: "DNS names matching a pattern or a regular expression", dnsNames: []string{"worker-1.example.com", "worker-1.nodes.example.com"}, policy: both, allowed: true},
+		{name: "local IP addresses out of the ranges", ips: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("127.0.0.1")}, policy: policy},
+		{name: "stripped local IP addresses", ips: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("127.0.0.1"), net.ParseIP("::1"), net.ParseIP("fe80::1"), net.ParseIP("169.254.0.1")}, policy: stripLocal, allowed: true},
+		{name: "stripped local IP addresses with an IP address not allowed", ips: []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("10.0.1.1")}, policy: stripLocal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csr := newCSR(t, &x509.CertificateRequest{DNSNames: tt.dnsNames, IPAddresses: tt.ips}, nil)
+
+			verdict := tt.policy.Validate(t.Context(), csr)
+			if verdict.Allowed() != tt.allowed || (!tt.allowed && verdict.Code != codes.PermissionDenied) {
+				t.Fatalf("expected allowed %t, got %+v", tt.allowed, verdict)
+			}
+		})
+	}
+}
+
+func TestSubjectPolicy(t *testing.T) {
+	policy := SubjectPolicy{CommonName: regexp.MustCompile(`^worker-[0-9]+$`)}
+
+	for commonName, allowed := range map[string]bool{"worker-1": true, "controlplane-1": false, "": false} {
+		csr := newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: commonName}}, nil)
+
+		if verdict := policy.Validate(t.Context(), csr); verdict.Allowed() != allowed {
+			t.Fatalf("expected %q allowed %t, got %+v", commonName, allowed, verdict)
+		}
+	}
+
+	if verdict := (SubjectPolicy{}).Validate(t.Context(), &x509.CertificateRequest{}); !verdict.Allowed() {
+		t.Fatalf("expected any subject to be allowed, got %+v", verdict)
+	}
+
+	organizations := []string{"os:reader", "os:operator"}
+
+	tests := []struct {
+		name          string
+		policy        SubjectPolicy
+		organizations []string
+		allowed       bool
+	}{
+		{name: "allowed organizations", policy: SubjectPolicy{Organizations: organizations}, organizations: []string{"os:reader"}, allowed: true},
+		{name: "no organization", policy: SubjectPolicy{Organizations: organizations}, allowed: true},
+		{name: "other organization", policy: SubjectPolicy{Organizations: organizations}, organizations: []string{"os:reader", "os:admin"}},
+		{name: "other organization stripped", policy: SubjectPolicy{Organizations: organizations, StripOrganizations: true}, organizations: []string{"os:admin"}, allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csr := &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1", Organization: tt.organizations}}
+
+			if verdict := tt.policy.Validate(t.Context(), csr); verdict.Allowed() != tt.allowed {
+				t.Fatalf("expected allowed %t, got %+v", tt.allowed, verdict)
+			}
+		})
+	}
+}
+
+func TestQuota(t *testing.T) {
+	quota := Quota{Ledger: ledger.NewMemory(), Limit: 2, Window: time.Hour}
+	csr := &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}}
+
+	for range 2 {
+		if verdict := quota.Validate(t.Context(), csr); !verdict.Allowed() {
+			t.Fatalf("expected the CSR within the quota to be allowed, got %+v", verdict)
+		}
+	}
+
+	if verdict := quota.Validate(t.Context(), csr); verdict.Allowed() || verdict.Code != codes.ResourceExhausted {
+		t.Fatalf("expected the quota to be exhausted, got %+v", verdict)
+	}
+
+	other := &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-2"}}
+	if verdict := quota.Validate(t.Context(), other); !verdict.Allowed() {
+		t.Fatalf("expected the quota to be per Common Name, got %+v", verdict)
+	}
+}
+
+func TestSizePolicy(t *testing.T) {
+	ips := []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}
+
+	tests := []struct {
+		name    string
+		policy  SizePolicy
+		csr     *x509.CertificateRequest
+		allowed bool
+	}{
+		{name: "no limits", csr: &x509.CertificateRequest{DNSNames: []string{"a", "b", "c"}, IPAddresses: ips}, allowed: true},
+		{name: "within the limits", policy: SizePolicy{MaxDNSNames: 3, MaxIPAddresses: 2, MaxExtensions: 1}, csr: &x509.CertificateRequest{DNSNames: []string{"a", "b", "c"}, IPAddresses: ips, Extensions: make([]pkix.Extension, 1)}, allowed: true},
+		{name: "too many DNS names", policy: SizePolicy{MaxDNSNames: 2}, csr: &x509.CertificateRequest{DNSNames: []string{"a", "b", "c"}}},
+		{name: "too many IP addresses", policy: SizePolicy{MaxIPAddresses: 1}, csr: &x509.CertificateRequest{IPAddresses: ips}},
+		{name: "too many extensions", policy: SizePolicy{MaxExtensions: 1}, csr: &x509.CertificateRequest{Extensions: make([]pkix.Extension, 2)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := tt.policy.Validate(t.Context(), tt.csr)
+			if verdict.Allowed() != tt.allowed || (!tt.allowed && verdict.Code != codes.InvalidArgument) {
+				t.Fatalf("expected allowed %t, got %+v", tt.allowed, verdict)
+			}
+		})
+	}
+}
+
+func TestSANPolicyEmailURI(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://example.com/ns/kube-system/sa/node")
+	other, _ := url.Parse("https://example.com/node")
+
+	tests := []struct {
+		name    string
+		policy  SANPolicy
+		emails  []string
+		uris    []*url.URL
+		allowed bool
+	}{
+		{name: "dropped", emails: []string{"node@example.com"}, uris: []*url.URL{other}, allowed: true},
+		{name: "email addresses rejected", policy: SANPolicy{EmailAction: SANsReject}, emails: []string{"node@example.com"}},
+		{name: "URIs rejected", policy: SANPolicy{URIAction: SANsReject}, uris: []*url.URL{spiffe}},
+		{name: "URIs rejected without URIs", policy: SANPolicy{URIAction: SANsReject}, emails: []string{"node@example.com"}, allowed: true},
+		{name: "email addresses passed", policy: SANPolicy{EmailAction: SANsPass, EmailPatterns: []string{"*@example.com"}}, emails: []string{"node@example.com"}, allowed: true},
+		{name: "email address not matching", policy: SANPolicy{EmailAction: SANsPass, EmailPatterns: []string{"*@example.com"}}, emails: []string{"node@example.org"}},
+		{name: "URIs passed", policy: SANPolicy{URIAction: SANsPass, URIPatterns: []string{"spiffe://example.com/ns/*/sa/*"}}, uris: []*url.URL{spiffe}, allowed: true},
+		{name: "URI not matching", policy: SANPolicy{URIAction: SANsPass, URIPatterns: []string{"spiffe://example.com/ns/*/sa/*"}}, uris: []*url.URL{spiffe, other}},
+		{name: "URIs passed without patterns", policy: SANPolicy{URIAction: SANsPass}, uris: []*url.URL{other}, allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csr := &x509.CertificateRequest{EmailAddresses: tt.emails, URIs: tt.uris}
+
+			if verdict := tt.policy.Validate(t.Context(), csr); verdict.Allowed() != tt.allowed {
+				t.Fatalf("expected allowed %t, got %+v", tt.allowed, verdict)
+			}
+		})
+	}
+}
+
+func TestSANPolicyWildcards(t *testing.T) {
+	patterns := []string{"*.nodes.example.com"}
+
+	tests := []struct {
+		name     string
+		policy   SANPolicy
+		dnsNames []string
+		allowed  bool
+	}{
+		{name: "allowed", dnsNames: []string{"*.nodes.example.com"}, policy: SANPolicy{DNSPatterns: patterns}, allowed: true},
+		{name: "rejected", dnsNames: []string{"worker-1.nodes.example.com", "*.nodes.example.com"}, policy: SANPolicy{WildcardAction: WildcardsReject}},
+		{name: "rejected without wildcards", dnsNames: []string{"worker-1.nodes.example.com"}, policy: SANPolicy{WildcardAction: WildcardsReject}, allowed: true},
+		{name: "stripped whatever the patterns", dnsNames: []string{"*.example.com", "worker-1.nodes.example.com"}, policy: SANPolicy{DNSPatterns: patterns, WildcardAction: WildcardsStrip}, allowed: true},
+		{name: "other names still checked", dnsNames: []string{"*.example.com", "worker-1.example.com"}, policy: SANPolicy{DNSPatterns: patterns, WildcardAction: WildcardsStrip}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if verdict := tt.policy.Validate(t.Context(), &x509.CertificateRequest{DNSNames: tt.dnsNames}); verdict.Allowed() != tt.allowed {
+				t.Fatalf("expected allowed %t, got %+v", tt.allowed, verdict)
+			}
+		})
+	}
+}
+
+func TestUsagePolicy(t *testing.T) {
+	policy := UsagePolicy{ExtKeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}}
+	serverAuth, clientAuth := asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 1}, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 2}
+
+	tests := []struct {
+		name    string
+		oids    []asn1.ObjectIdentifier
+		policy  UsagePolicy
+		allowed bool
+		code    codes.Code
+	}{
+		{name: "no request", policy: policy, allowed: true},
+		{name: "allowed usages", oids: []asn1.ObjectIdentifier{serverAuth, clientAuth}, policy: policy, allowed: true},
+		{name: "usage not allowed", oids: []asn1.ObjectIdentifier{serverAuth, clientAuth}, policy: UsagePolicy{ExtKeyUsages: policy.ExtKeyUsages[:1]}, code: codes.PermissionDenied},
+		{name: "unsupported usage", oids: []asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 3}}, policy: policy, code: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}}
+
+			if tt.oids != nil {
+				value, err := asn1.Marshal(tt.oids)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				template.ExtraExtensions = []pkix.Extension{{Id: asn1.ObjectIdentifier{2, 5, 29, 37}, Value: value}}
+			}
+
+			verdict := tt.policy.Validate(t.Context(), newCSR(t, template, nil))
+			if verdict.Allowed() != tt.allowed || (!tt.allowed && verdict.Code != tt.code) {
+				t.Fatalf("expected allowed %t, got %+v", tt.allowed, verdict)
+			}
+		})
+	}
+}
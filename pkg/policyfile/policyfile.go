@@ -0,0 +1,345 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package policyfile contains the policy file of a signer: the TTLs, the key, SAN, and subject rules, the size limits,
+// the admission expressions, and the quotas in a single YAML document, reloaded while serving in place of the separate
+// flags.
+package policyfile
+
+import (
+	"context"
+	"crypto/sha256"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/policy"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+)
+
+// kind is the type of the value of a setting.
+type kind int
+
+const (
+	kindString kind = iota
+	kindInt
+	kindBool
+	kindDuration
+	kindList
+	kindRegexp
+	kindRegexps
+	kindCIDRs
+)
+
+// list returns true when the values of the kind are lists.
+func (k kind) list() bool {
+	return k == kindList || k == kindRegexps || k == kindCIDRs
+}
+
+// field is a setting of the policy file, overriding the flag of the key.
+type field struct {
+	key  string
+	kind kind
+	// values are the allowed values, empty allowing any.
+	values []string
+}
+
+// schema holds the fields of the policy file, per section.
+var schema = map[string]map[string]field{
+	"ttl": {
+		"controlplane": {key: config.KeyControlPlaneValidity, kind: kindDuration},
+		"worker":       {key: config.KeyWorkerValidity, kind: kindDuration},
+		"max":          {key: config.KeyMaxTTL, kind: kindDuration},
+	},
+	"keys": {
+		"algorithms":   {key: config.KeyPolicyKeyAlgorithms, kind: kindList},
+		"min-rsa-bits": {key: config.KeyPolicyMinRSABits, kind: kindInt},
+		"controlplane": {key: config.KeyControlPlaneKeyAlgorithms, kind: kindList},
+		"worker":       {key: config.KeyWorkerKeyAlgorithms, kind: kindList},
+		"extended-key-usages": {
+			key:    config.KeyPolicyExtKeyUsages,
+			kind:   kindList,
+			values: []string{"server", "client"},
+		},
+	},
+	"san": {
+		"dns-names":       {key: config.KeyPolicyDNSNames, kind: kindList},
+		"dns-regexps":     {key: config.KeyPolicyDNSRegexps, kind: kindRegexps},
+		"ip-ranges":       {key: config.KeyPolicyIPRanges, kind: kindCIDRs},
+		"strip-local-ips": {key: config.KeyPolicyStripLocalIPs, kind: kindBool},
+		"wildcards": {
+			key:    config.KeyPolicyWildcards,
+			kind:   kindString,
+			values: []string{policy.WildcardsAllow, policy.WildcardsReject, policy.WildcardsStrip},
+		},
+		"email-action": {
+			key:    config.KeyPolicyEmailAction,
+			kind:   kindString,
+			values: []string{policy.SANsDrop, policy.SANsPass, policy.SANsReject},
+		},
+		"email-addresses": {key: config.KeyPolicyEmailAddresses, kind: kindList},
+		"uri-action": {
+			key:    config.KeyPolicyURIAction,
+			kind:   kindString,
+			values: []string{policy.SANsDrop, policy.SANsPass, policy.SANsReject},
+		},
+		"uris": {key: config.KeyPolicyURIs, kind: kindList},
+	},
+	"limits": {
+		"dns-names":    {key: config.KeyPolicyMaxDNSNames, kind: kindInt},
+		"ip-addresses": {key: config.KeyPolicyMaxIPAddresses, kind: kindInt},
+		"extensions":   {key: config.KeyPolicyMaxExtensions, kind: kindInt},
+	},
+	"subject": {
+		"common-name":   {key: config.KeyPolicyCommonName, kind: kindRegexp},
+		"organizations": {key: config.KeyPolicyOrganizations, kind: kindList},
+		"organization-action": {
+			key:    config.KeyPolicyOrganizationAction,
+			kind:   kindString,
+			values: []string{policy.OrganizationsReject, policy.OrganizationsStrip},
+		},
+		"set-organizations": {key: config.KeyPolicySubjectOrgs, kind: kindList},
+		"drop": {
+			key:  config.KeyPolicySubjectDrop,
+			kind: kindList,
+			values: []string{
+				signer.SubjectOrganizationalUnit, signer.SubjectCountry, signer.SubjectProvince, signer.SubjectLocality,
+				signer.SubjectStreetAddress, signer.SubjectPostalCode, signer.SubjectSerialNumber,
+			},
+		},
+		"common-name-template": {key: config.KeyPolicySubjectCNTemplate, kind: kindString},
+	},
+	"admission": {
+		"cel":         {key: config.KeyPolicyCEL, kind: kindString},
+		"opa-url":     {key: config.KeyPolicyOPAURL, kind: kindString},
+		"opa-timeout": {key: config.KeyPolicyOPATimeout, kind: kindDuration},
+		"simulate":    {key: config.KeyPolicySimulate, kind: kindList},
+	},
+	"quota": {
+		"limit":            {key: config.KeyIssuanceQuota, kind: kindInt},
+		"window":           {key: config.KeyQuotaWindow, kind: kindDuration},
+		"reissue-cooldown": {key: config.KeyReissueCooldown, kind: kindDuration},
+	},
+}
+
+// File is the policy of a signer, such as:
+//
+//	ttl:
+//	  worker: 720h
+//	  max: 24h
+//	san:
+//	  dns-names: ["*.nodes.example.com"]
+//	  ip-ranges: [10.0.0.0/8]
+//	subject:
+//	  organizations: [os:reader]
+//	  organization-action: strip
+//	quota:
+//	  limit: 10
+//	  window: 24h
+//
+// The lists are either YAML sequences or comma separated strings. The settings missing from the file are the
+// configured ones.
+type File struct {
+	settings map[string]any
+	digest   [sha256.Size]byte
+}
+
+// Parse returns the File of the YAML document, whose errors report their line.
+func Parse(data []byte) (*File, error) {
+	f := &File{settings: make(map[string]any), digest: sha256.Sum256(data)}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrPolicyFile, err.Error())
+	}
+
+	// An empty document keeps the configured settings
+	if len(document.Content) == 0 {
+		return f, nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.Wrapf(pkgerrors.ErrPolicyFile, "line %d: expected the mapping of the sections", root.Line)
+	}
+
+	err := eachPair(root, func(section string, fields *yaml.Node) error {
+		sectionSchema, found := schema[section]
+		if !found {
+			return errors.Wrapf(pkgerrors.ErrPolicyFile, "line %d: unknown section %s", fields.Line, section)
+		}
+
+		if fields.Kind != yaml.MappingNode {
+			return errors.Wrapf(pkgerrors.ErrPolicyFile, "line %d: expected the mapping of the %s settings", fields.Line, section)
+		}
+
+		return eachPair(fields, func(name string, value *yaml.Node) error {
+			setting, known := sectionSchema[name]
+			if !known {
+				return errors.Wrapf(pkgerrors.ErrPolicyFile, "line %d: unknown setting %s.%s", value.Line, section, name)
+			}
+
+			parsed, parseErr := setting.parse(value)
+			if parseErr != nil {
+				return errors.Wrapf(pkgerrors.ErrPolicyFile, "line %d: %s.%s: %s", value.Line, section, name, parseErr.Error())
+			}
+
+			f.settings[setting.key] = parsed
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// eachPair calls fn with the keys and the values of the mapping, refusing the duplicated keys.
+func eachPair(mapping *yaml.Node, fn func(key string, value *yaml.Node) error) error {
+	seen := make(map[string]bool, len(mapping.Content)/2)
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+
+		if seen[key.Value] {
+			return errors.Wrapf(pkgerrors.ErrPolicyFile, "line %d: duplicated key %s", key.Line, key.Value)
+		}
+
+		seen[key.Value] = true
+
+		if err := fn(key.Value, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parse returns the value of the setting, as the string the flag would hold.
+func (f field) parse(value *yaml.Node) (string, error) {
+	var items []string
+
+	switch {
+	case value.Kind == yaml.SequenceNode && f.kind.list():
+		for _, item := range value.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", errors.New("expected a list of strings")
+			}
+
+			items = append(items, item.Value)
+		}
+	case value.Kind == yaml.ScalarNode && f.kind.list():
+		items = config.SplitList(value.Value)
+	case value.Kind == yaml.ScalarNode:
+		items = []string{value.Value}
+	default:
+		return "", errors.New("expected a single value")
+	}
+
+	for _, item := range items {
+		if err := f.check(item); err != nil {
+			return "", err
+		}
+	}
+
+	return strings.Join(items, ","), nil
+}
+
+// check verifies an item of the value of the setting.
+func (f field) check(item string) error {
+	if len(f.values) > 0 && !slices.Contains(f.values, item) {
+		return errors.New("unsupported value " + item + ", expected one of " + strings.Join(f.values, ", "))
+	}
+
+	var err error
+
+	switch f.kind {
+	case kindInt:
+		_, err = strconv.Atoi(item)
+	case kindBool:
+		_, err = strconv.ParseBool(item)
+	case kindDuration:
+		_, err = time.ParseDuration(item)
+	case kindRegexp, kindRegexps:
+		_, err = regexp.Compile(item)
+	case kindCIDRs:
+		_, _, err = net.ParseCIDR(item)
+	case kindString, kindList:
+	}
+
+	return err //nolint:wrapcheck
+}
+
+// Load returns the File read from the path.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrReadFile, err.Error())
+	}
+
+	f, err := Parse(data)
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+
+	return f, nil
+}
+
+// Settings returns the settings of the policy file keyed by the flag names, overriding the configured ones.
+func (f *File) Settings() map[string]any {
+	return f.settings
+}
+
+// Watch reads the policy file every interval until the context is done, calling onChange with the file when its
+// content differs from the given one, such as a mounted ConfigMap being updated, or when a reload signal is received,
+// such as SIGHUP. Invalid files are ignored, keeping the previous one.
+func Watch(ctx context.Context, path string, interval time.Duration, current *File, reload <-chan os.Signal, onChange func(*File)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		forced := false
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-reload:
+			forced = true
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to read the policy file, keeping the previous one", "path", path, "error", err)
+
+			continue
+		}
+
+		if !forced && sha256.Sum256(data) == current.digest {
+			continue
+		}
+
+		f, err := Parse(data)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to reload the policy file, keeping the previous one", "path", path, "error", err)
+
+			continue
+		}
+
+		current = f
+
+		onChange(f)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pop implements the proof-of-possession challenge: the signer hands out a nonce the client signs with the
+// private key of its CSR, proving the live possession of the key beyond the static self-signature of the CSR.
+package pop
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+const (
+	// NonceMetadataKey is the metadata key of the nonce: answered in the response header of the challenged
+	// requests, and sent back by the client along with its signature.
+	NonceMetadataKey = "x-pop-nonce"
+	// SignatureMetadataKey is the metadata key of the nonce signature, base64 encoded.
+	SignatureMetadataKey = "x-pop-signature"
+)
+
+// NewNonce returns a random nonce, base64 encoded.
+func NewNonce() (string, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.Wrap(pkgerrors.ErrGenerateKey, err.Error())
+	}
+
+	return base64.RawURLEncoding.EncodeToString(nonce), nil
+}
+
+// message returns the signed message of the nonce, bound to its purpose.
+func message(nonce string) []byte {
+	return []byte("talos-csr-signer proof-of-possession\n" + nonce)
+}
+
+// Sign returns the signature of the nonce with the private key of the CSR, base64 encoded.
+// Ed25519 keys sign the message, ECDSA and RSA ones its SHA-256 digest, with PKCS #1 v1.5 for RSA.
+func Sign(key crypto.Signer, nonce string) (string, error) {
+	var (
+		signature []byte
+		err       error
+	)
+
+	if _, ok := key.(ed25519.PrivateKey); ok {
+		signature, err = key.Sign(rand.Reader, message(nonce), crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(message(nonce))
+		signature, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+
+	if err != nil {
+		return "", errors.Wrap(pkgerrors.ErrProofOfPossession, err.Error())
+	}
+
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks the signature of the nonce, base64 encoded, against the public key of the CSR.
+func Verify(publicKey any, nonce, signature string) error {
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrProofOfPossession, "invalid signature encoding")
+	}
+
+	digest := sha256.Sum256(message(nonce))
+
+	var valid bool
+
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, message(nonce), raw)
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, digest[:], raw)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], raw) == nil
+	default:
+		return errors.Wrap(pkgerrors.ErrProofOfPossession, fmt.Sprintf("unsupported public key %T", publicKey))
+	}
+
+	if !valid {
+		return errors.Wrap(pkgerrors.ErrProofOfPossession, "signature doesn't match the CSR public key")
+	}
+
+	return nil
+}
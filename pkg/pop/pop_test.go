@@ -0,0 +1,101 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package pop
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+func TestSignVerify(t *testing.T) {
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce, err := NewNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []crypto.Signer{ed25519Key, ecdsaKey, rsaKey} {
+		t.Run(keyName(key), func(t *testing.T) {
+			signature, err := Sign(key, nonce)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err = Verify(key.Public(), nonce, signature); err != nil {
+				t.Fatal(err)
+			}
+
+			if err = Verify(key.Public(), nonce+"x", signature); !errors.Is(err, pkgerrors.ErrProofOfPossession) {
+				t.Fatalf("expected the signature of another nonce to be rejected, got %v", err)
+			}
+
+			if err = Verify(otherKey.Public(), nonce, signature); !errors.Is(err, pkgerrors.ErrProofOfPossession) {
+				t.Fatalf("expected the signature of another key to be rejected, got %v", err)
+			}
+		})
+	}
+
+	if err = Verify(ecdsaKey.Public(), nonce, "not base64!"); !errors.Is(err, pkgerrors.ErrProofOfPossession) {
+		t.Fatalf("expected the malformed signature to be rejected, got %v", err)
+	}
+
+	if err = Verify("key", nonce, ""); !errors.Is(err, pkgerrors.ErrProofOfPossession) {
+		t.Fatalf("expected the unsupported key to be rejected, got %v", err)
+	}
+}
+
+func TestNewNonce(t *testing.T) {
+	first, err := NewNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := NewNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first == second || len(first) != 43 {
+		t.Fatalf("unexpected nonces %s and %s", first, second)
+	}
+}
+
+// keyName returns the algorithm of the key, naming the subtests.
+func keyName(key crypto.Signer) string {
+	switch key.(type) {
+	case ed25519.PrivateKey:
+		return "ed25519"
+	case *rsa.PrivateKey:
+		return "rsa"
+	default:
+		return "ecdsa"
+	}
+}
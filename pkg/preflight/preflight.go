@@ -0,0 +1,127 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package preflight collects the outcome of the startup checks into a structured report.
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+// Status is the outcome of a startup check.
+type Status string
+
+const (
+	// StatusPass is a successful check.
+	StatusPass Status = "PASS"
+	// StatusWarn is a check which didn't fail, yet requires attention: it fails the startup in strict mode.
+	StatusWarn Status = "WARN"
+	// StatusFail is a failed check, always preventing the startup.
+	StatusFail Status = "FAIL"
+	// StatusSkip is a check not applicable to the current configuration.
+	StatusSkip Status = "SKIP"
+)
+
+// Check is the outcome of a single startup check.
+type Check struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report is the checklist of the startup checks, in the order they ran.
+type Report struct {
+	Checks []Check `json:"checks"`
+}
+
+func (r *Report) add(name string, status Status, format string, args ...any) {
+	r.Checks = append(r.Checks, Check{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+}
+
+// Pass records a successful check.
+func (r *Report) Pass(name, format string, args ...any) {
+	r.add(name, StatusPass, format, args...)
+}
+
+// Warn records a check requiring attention.
+func (r *Report) Warn(name, format string, args ...any) {
+	r.add(name, StatusWarn, format, args...)
+}
+
+// Fail records a failed check.
+func (r *Report) Fail(name, format string, args ...any) {
+	r.add(name, StatusFail, format, args...)
+}
+
+// Skip records a check not applicable to the current configuration.
+func (r *Report) Skip(name, format string, args ...any) {
+	r.add(name, StatusSkip, format, args...)
+}
+
+// Count returns the number of checks with the given status.
+func (r *Report) Count(status Status) int {
+	var count int
+
+	for _, check := range r.Checks {
+		if check.Status == status {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Log logs the report with the logger of the context as a checklist, one check per line, the warnings and the
+// failures at their level.
+func (r *Report) Log(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	for _, check := range r.Checks {
+		level := slog.LevelInfo
+
+		switch check.Status {
+		case StatusWarn:
+			level = slog.LevelWarn
+		case StatusFail:
+			level = slog.LevelError
+		}
+
+		logger.Log(ctx, level, "Startup check", "check", check.Name, "status", check.Status, "message", check.Message)
+	}
+
+	logger.Info("Startup checks", "passed", r.Count(StatusPass), "warnings", r.Count(StatusWarn),
+		"failed", r.Count(StatusFail), "skipped", r.Count(StatusSkip))
+}
+
+// Print writes the report as a checklist, one check per line, for the human readers.
+func (r *Report) Print(w io.Writer) {
+	for _, check := range r.Checks {
+		_, _ = fmt.Fprintf(w, "[%s] %s: %s\n", check.Status, check.Name, check.Message)
+	}
+}
+
+// Err returns an error listing the failed checks, along with the warnings when strict.
+func (r *Report) Err(strict bool) error {
+	var failed []string
+
+	for _, check := range r.Checks {
+		if check.Status == StatusFail || (strict && check.Status == StatusWarn) {
+			failed = append(failed, check.Name)
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return errors.Wrap(pkgerrors.ErrStartupCheck, strings.Join(failed, ", "))
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package schedule contains the recurring time windows, opening at the times matching a cron expression
+// and lasting for a duration, such as the maintenance windows of the change-controlled environments.
+package schedule
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// maxDuration is the longest window supported, bounding the search of the matching opening times.
+const maxDuration = 7 * 24 * time.Hour
+
+// Window is a recurring time window.
+type Window struct {
+	spec     string
+	fields   [5]map[int]bool
+	duration time.Duration
+}
+
+// fieldRanges are the allowed values of the cron fields: minute, hour, day of month, month, and day of week.
+var fieldRanges = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+// Parse returns the Window of the spec, a standard 5-field cron expression followed by the duration,
+// such as "0 22 * * 6 4h" for Saturdays from 22:00 to 02:00. Fields support *, lists, ranges, and steps:
+// unlike cron, the day of month and the day of week must both match.
+func Parse(spec string) (*Window, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != 6 {
+		return nil, errors.Wrap(pkgerrors.ErrSchedule, "expected 5 cron fields and a duration: "+spec)
+	}
+
+	duration, err := time.ParseDuration(parts[5])
+	if err != nil || duration <= 0 || duration > maxDuration {
+		return nil, errors.Wrap(pkgerrors.ErrSchedule, "invalid duration, expected up to 168h: "+parts[5])
+	}
+
+	w := &Window{spec: spec, duration: duration}
+
+	for i, field := range parts[:5] {
+		if w.fields[i], err = parseField(field, fieldRanges[i][0], fieldRanges[i][1]); err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrSchedule, err.Error()+": "+spec)
+		}
+	}
+
+	return w, nil
+}
+
+// ParseList returns the Windows of the semicolon separated specs.
+func ParseList(specs string) ([]*Window, error) {
+	var windows []*Window
+
+	for _, spec := range strings.Split(specs, ";") {
+		if spec = strings.TrimSpace(spec); spec == "" {
+			continue
+		}
+
+		w, err := Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		windows = append(windows, w)
+	}
+
+	return windows, nil
+}
+
+// parseField returns the values matched by the cron field.
+func parseField(field string, lowest, highest int) (map[int]bool, error) {
+	values := make(map[int]bool)
+
+	for _, item := range strings.Split(field, ",") {
+		expr, stepValue, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepValue); err != nil || step <= 0 {
+				return nil, errors.Errorf("invalid step %q", item)
+			}
+		}
+
+		from, to := lowest, highest
+
+		if expr != "*" {
+			first, last, isRange := strings.Cut(expr, "-")
+
+			var err error
+			if from, err = strconv.Atoi(first); err != nil {
+				return nil, errors.Errorf("invalid value %q", item)
+			}
+
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(last); err != nil {
+					return nil, errors.Errorf("invalid range %q", item)
+				}
+			} else if hasStep {
+				to = highest
+			}
+		}
+
+		if from < lowest || to > highest || from > to {
+			return nil, errors.Errorf("%q out of the %d-%d range", item, lowest, highest)
+		}
+
+		for value := from; value <= to; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
+
+// String returns the spec of the Window.
+func (w *Window) String() string {
+	return w.spec
+}
+
+// matches returns true when the Window opens at the given minute.
+func (w *Window) matches(t time.Time) bool {
+	return w.fields[0][t.Minute()] && w.fields[1][t.Hour()] && w.fields[2][t.Day()] &&
+		w.fields[3][int(t.Month())] && w.fields[4][int(t.Weekday())]
+}
+
+// Open returns true when the Window is open at the given time, evaluated in the location of the time.
+func (w *Window) Open(t time.Time) bool {
+	start := t.Truncate(time.Minute)
+
+	for opening := start; t.Sub(opening) < w.duration; opening = opening.Add(-time.Minute) {
+		if w.matches(opening) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Next returns the next time the Window opens after the given one, false when it doesn't open within a week.
+func (w *Window) Next(t time.Time) (time.Time, bool) {
+	start := t.Truncate(time.Minute).Add(time.Minute)
+
+	for opening := start; opening.Sub(start) <= maxDuration; opening = opening.Add(time.Minute) {
+		if w.matches(opening) {
+			return opening, true
+		}
+	}
+
+	return time.Time{}, false
+}
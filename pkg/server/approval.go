@@ -0,0 +1,54 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/x509"
+	"strconv"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/events"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+// awaitApproval holds the privileged CSR, identified by its digest, until the approvers approved it: the requests
+// are answered with the approval ID to retry with the same CSR, and the approval trail once approved.
+func (s *Server) awaitApproval(ctx context.Context, csr *x509.CertificateRequest, digest string) ([]ledger.Approval, error) {
+	logger := logging.FromContext(ctx).With("approval", digest)
+
+	request, submitted, err := s.Approvals.Submit(ctx, digest, csr)
+	if err != nil {
+		logger.Error("Failed to queue the approval request", "error", err)
+		s.Watchdog.Failure(ctx, err)
+
+		return nil, pkgerrors.Backend(pkgerrors.ReasonLedgerUnavailable, "ledger unavailable", err)
+	}
+
+	if request.Approved() {
+		logger.Info("Privileged certificate approved", "approvals", len(request.Approvals))
+
+		return request.Approvals, nil
+	}
+
+	if submitted {
+		logger.Info("Privileged certificate held until approved", "threshold", request.Threshold)
+		s.Events.Emit(events.Event{
+			Type:       events.TypeApprovalRequested,
+			CommonName: request.CommonName,
+			Approval:   request.ID,
+			Peer:       peerFromContext(ctx),
+		})
+	}
+
+	approvals := strconv.Itoa(len(request.Approvals)) + "/" + strconv.Itoa(request.Threshold)
+
+	return nil, &pkgerrors.Error{
+		Kind:     pkgerrors.KindChallenge,
+		Reason:   pkgerrors.ReasonApprovalRequired,
+		Message:  "privileged certificate pending approval (" + approvals + "): retry with the same CSR once approved",
+		Metadata: map[string]string{"approval": request.ID, "approvals": approvals},
+	}
+}
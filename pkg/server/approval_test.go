@@ -0,0 +1,91 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/clastix/talos-csr-signer/pkg/approval"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+	pb "github.com/clastix/talos-csr-signer/pkg/proto"
+)
+
+func TestApproval(t *testing.T) {
+	s := newServer(t)
+	s.Approvals = &approval.Queue{
+		Ledger:        s.Ledger,
+		Organizations: []string{"os:admin"},
+		Approvers:     []approval.Approver{{Name: "alice", Token: "secret-1"}, {Name: "bob", Token: "secret-2"}},
+		Threshold:     2,
+		TTL:           time.Hour,
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "admin", Organization: []string{"os:admin"}},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &pb.CertificateRequest{Csr: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})}
+
+	// The unprivileged CSRs are not held
+	if _, err = s.Certificate(withToken(t.Context(), talosToken), &pb.CertificateRequest{Csr: newCSR(t, "worker-1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	var pending *pkgerrors.Error
+
+	for _, approverToken := range []string{"", "secret-1"} {
+		if approverToken != "" {
+			if _, _, err = s.Approvals.Approve(t.Context(), pending.Metadata["approval"], approverToken); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		_, err = s.Certificate(withToken(t.Context(), talosToken), req)
+		if !errors.As(err, &pending) || pending.Kind.Code() != codes.FailedPrecondition || pending.Reason != pkgerrors.ReasonApprovalRequired {
+			t.Fatalf("expected the privileged CSR to be held, got %v", err)
+		}
+	}
+
+	if _, _, err = s.Approvals.Approve(t.Context(), pending.Metadata["approval"], "secret-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.Certificate(withToken(t.Context(), talosToken), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certs, err := pki.ParseCertificates(resp.GetCrt())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := s.Ledger.Get(t.Context(), certs[0].SerialNumber.Text(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(record.Approvals) != 2 || record.Approvals[0].Approver != "alice" || record.Approvals[1].Approver != "bob" {
+		t.Fatalf("expected the approval trail to be recorded, got %+v", record.Approvals)
+	}
+}
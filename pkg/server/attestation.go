@@ -0,0 +1,61 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/tpm"
+)
+
+// TPMAttestationOptions configures the TPM quotes binding the CSRs to the hardware of the nodes.
+type TPMAttestationOptions struct {
+	// Verifier verifies the quotes against the endorsement roots: nil disables the attestation, ignoring the metadata.
+	Verifier *tpm.Verifier
+	// Required rejects the requests without a quote.
+	Required bool
+}
+
+// attest returns the SPKI hash of the attestation key whose quote binds the CSR to the node TPM, empty when not
+// sent or not enabled.
+func (s *Server) attest(ctx context.Context, md metadata.MD, csr *x509.CertificateRequest) (string, error) {
+	if s.TPMAttestation.Verifier == nil {
+		return "", nil
+	}
+
+	akCerts, quotes, signatures := md.Get(tpm.AKCertificateMetadataKey), md.Get(tpm.QuoteMetadataKey), md.Get(tpm.SignatureMetadataKey)
+	if len(akCerts) == 0 || len(quotes) == 0 || len(signatures) == 0 {
+		if s.TPMAttestation.Required {
+			return "", s.deny(ctx, csr.Subject.CommonName,
+				pkgerrors.Auth(pkgerrors.ReasonInvalidAttestation, "missing TPM quote: send the "+tpm.AKCertificateMetadataKey+", "+
+					tpm.QuoteMetadataKey+", and "+tpm.SignatureMetadataKey+" metadata"))
+		}
+
+		return "", nil
+	}
+
+	var decoded [3][]byte
+
+	for i, value := range []string{akCerts[0], quotes[0], signatures[0]} {
+		var err error
+		if decoded[i], err = base64.StdEncoding.DecodeString(value); err != nil {
+			return "", s.deny(ctx, csr.Subject.CommonName,
+				pkgerrors.Auth(pkgerrors.ReasonInvalidAttestation, "invalid TPM attestation encoding"))
+		}
+	}
+
+	akCert, err := s.TPMAttestation.Verifier.Verify(decoded[0], decoded[1], decoded[2], csr.Raw, time.Now())
+	if err != nil {
+		return "", s.deny(ctx, csr.Subject.CommonName, pkgerrors.Auth(pkgerrors.ReasonInvalidAttestation, err.Error()))
+	}
+
+	return ledger.SPKIHash(akCert), nil
+}
@@ -0,0 +1,16 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+)
+
+// Authenticator validates the tokens of the requests, replacing the check against the Tokens when set.
+type Authenticator interface {
+	// Authenticate returns true when the token sent by the peer is valid, or an error when it cannot decide.
+	Authenticate(ctx context.Context, token string, peer *ledger.Peer) (bool, error)
+}
@@ -0,0 +1,37 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/x509"
+
+	"google.golang.org/grpc/metadata"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// checkDenyList refuses the CSR when its Common Name, one of its SANs, its public key, or the node UUID of the
+// request is deny-listed.
+func (s *Server) checkDenyList(ctx context.Context, md metadata.MD, csr *x509.CertificateRequest) error {
+	s.mu.RLock()
+	list := s.DenyList
+	s.mu.RUnlock()
+
+	var nodeUUID string
+	if values := md.Get(NodeUUIDMetadataKey); len(values) > 0 {
+		nodeUUID = values[0]
+	}
+
+	denied, found := list.Match(csr, nodeUUID)
+	if !found {
+		return nil
+	}
+
+	return s.deny(ctx, csr.Subject.CommonName, &pkgerrors.Error{
+		Kind:    pkgerrors.KindPolicy,
+		Reason:  pkgerrors.ReasonDenyListed,
+		Message: "the " + denied + " is deny-listed",
+	})
+}
@@ -0,0 +1,49 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/clastix/talos-csr-signer/pkg/denylist"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	pb "github.com/clastix/talos-csr-signer/pkg/proto"
+)
+
+func TestDenyList(t *testing.T) {
+	s := newServer(t)
+	s.RetryCacheTTL = time.Hour
+
+	req := &pb.CertificateRequest{Csr: newCSR(t, "worker-3")}
+
+	// The certificate is cached before the identity gets deny-listed
+	if _, err := s.Certificate(withToken(t.Context(), talosToken), req); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := denylist.Parse([]byte("common-names: [worker-3]\nnode-uuids: [4c4c4544-0042-3510-8051-b2c04f4e3232]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.ReloadDenyList(list)
+
+	_, err = s.Certificate(withToken(t.Context(), talosToken), req)
+	if code, reason := errorInfo(t, err); code != codes.PermissionDenied || reason != pkgerrors.ReasonDenyListed {
+		t.Fatalf("expected the retried CSR of the deny-listed Common Name to be refused, got %v", err)
+	}
+
+	_, err = s.Certificate(withToken(t.Context(), talosToken, NodeUUIDMetadataKey, "4c4c4544-0042-3510-8051-b2c04f4e3232"),
+		&pb.CertificateRequest{Csr: newCSR(t, "worker-4")})
+	if code, reason := errorInfo(t, err); code != codes.PermissionDenied || reason != pkgerrors.ReasonDenyListed {
+		t.Fatalf("expected the deny-listed node UUID to be refused, got %v", err)
+	}
+
+	if _, err = s.Certificate(withToken(t.Context(), talosToken), &pb.CertificateRequest{Csr: newCSR(t, "worker-4")}); err != nil {
+		t.Fatal(err)
+	}
+}
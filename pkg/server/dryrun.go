@@ -0,0 +1,120 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+	pb "github.com/clastix/talos-csr-signer/pkg/proto"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+)
+
+// DryRunCertificateMetadataKey is the response trailer key holding the JSON encoded DryRunCertificate of the dry run
+// requests.
+const DryRunCertificateMetadataKey = "x-dry-run-certificate"
+
+// DryRunCertificate describes the certificate a dry run request would be issued, without its serial number.
+type DryRunCertificate struct {
+	Role           string    `json:"role"`
+	Profile        string    `json:"profile,omitempty"`
+	Subject        string    `json:"subject"`
+	NotBefore      time.Time `json:"notBefore"`
+	NotAfter       time.Time `json:"notAfter"`
+	KeyUsages      []string  `json:"keyUsages,omitempty"`
+	ExtKeyUsages   []string  `json:"extKeyUsages,omitempty"`
+	IsCA           bool      `json:"isCA,omitempty"`
+	DNSNames       []string  `json:"dnsNames,omitempty"`
+	IPAddresses    []string  `json:"ipAddresses,omitempty"`
+	EmailAddresses []string  `json:"emailAddresses,omitempty"`
+	URIs           []string  `json:"uris,omitempty"`
+	Extensions     []string  `json:"extensions,omitempty"`
+}
+
+// newDryRunCertificate returns the description of the certificate template.
+func newDryRunCertificate(role signer.Role, profile string, template *x509.Certificate) DryRunCertificate {
+	certificate := DryRunCertificate{
+		Role:           string(role),
+		Profile:        profile,
+		Subject:        template.Subject.String(),
+		NotBefore:      template.NotBefore,
+		NotAfter:       template.NotAfter,
+		KeyUsages:      signer.KeyUsageNames(template.KeyUsage),
+		IsCA:           template.IsCA,
+		DNSNames:       template.DNSNames,
+		EmailAddresses: template.EmailAddresses,
+	}
+
+	for _, usage := range template.ExtKeyUsage {
+		certificate.ExtKeyUsages = append(certificate.ExtKeyUsages, signer.ExtKeyUsageName(usage))
+	}
+
+	for _, ip := range template.IPAddresses {
+		certificate.IPAddresses = append(certificate.IPAddresses, ip.String())
+	}
+
+	for _, uri := range template.URIs {
+		certificate.URIs = append(certificate.URIs, uri.String())
+	}
+
+	for _, extension := range template.ExtraExtensions {
+		certificate.Extensions = append(certificate.Extensions, extension.Id.String())
+	}
+
+	return certificate
+}
+
+// dryRun answers the dry run request with the CA certificates, describing the certificate the CSR would be issued in
+// the response trailers, without signing it: the quota, the cooldown, and the approvals are not evaluated.
+//
+//nolint:wrapcheck
+func (s *Server) dryRun(ctx context.Context, csr *x509.CertificateRequest, pending pendingSigning) (*pb.CertificateResponse, error) {
+	role, profile, err := s.issuanceProfile(csr, pending)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.FromContext(ctx).With("role", role)
+
+	// Nothing is signed: the template errors are answered without reporting a failure of the backend to the watchdog
+	template, err := s.certificateSigner().Template(csr, profile)
+	if err != nil {
+		if rejected := templateError(logger, err); rejected != nil {
+			return nil, rejected
+		}
+
+		var classified *pkgerrors.Error
+		if errors.As(err, &classified) {
+			return nil, classified
+		}
+
+		logger.Error("Failed to build the certificate template", "error", err)
+
+		// The error is the one the signing would answer, a failure of the backend
+		return nil, pkgerrors.Backend(pkgerrors.ReasonBackendUnavailable, "failed to create certificate", err)
+	}
+
+	certificate, err := json.Marshal(newDryRunCertificate(role, pending.Profile, template))
+	if err != nil {
+		return nil, err
+	}
+
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(DryRunCertificateMetadataKey, string(certificate)))
+
+	logger.Info("Dry run request accepted, not signing", "subject", template.Subject.String(),
+		"not_after", template.NotAfter.Format(time.RFC3339))
+
+	return &pb.CertificateResponse{
+		Ca: s.caBundle(pki.EncodeCertificates(s.Backend.Certificate())),
+	}, nil
+}
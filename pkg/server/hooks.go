@@ -0,0 +1,91 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/x509"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+// Denial describes a rejected certificate request.
+type Denial struct {
+	// CommonName is the Common Name of the CSR, empty when rejected before parsing it.
+	CommonName string
+	// Code is the gRPC code answered to the client.
+	Code codes.Code
+	// Reason is the message answered to the client.
+	Reason string
+	// Policy is the name of the policy validator which rejected the CSR, empty for the other denials.
+	Policy string
+}
+
+// Hooks holds the callbacks invoked along the lifecycle of the certificate requests, letting embedders implement
+// side effects, such as inventory updates or notifications, without forking the RPC handler. The callbacks run
+// synchronously in the RPC, so they must be fast, and must be registered before serving: a panicking one is logged.
+type Hooks struct {
+	authenticated []func(ctx context.Context, peer *ledger.Peer)
+	validated     []func(ctx context.Context, csr *x509.CertificateRequest)
+	issued        []func(ctx context.Context, cert *x509.Certificate, record ledger.Record)
+	denied        []func(ctx context.Context, denial Denial)
+}
+
+// OnAuthenticated registers the callback invoked once the token of the request is validated.
+func (h *Hooks) OnAuthenticated(fn func(ctx context.Context, peer *ledger.Peer)) {
+	h.authenticated = append(h.authenticated, fn)
+}
+
+// OnValidated registers the callback invoked once the CSR satisfies the policy, dry runs included.
+func (h *Hooks) OnValidated(fn func(ctx context.Context, csr *x509.CertificateRequest)) {
+	h.validated = append(h.validated, fn)
+}
+
+// OnIssued registers the callback invoked once the certificate is signed and recorded in the ledger.
+func (h *Hooks) OnIssued(fn func(ctx context.Context, cert *x509.Certificate, record ledger.Record)) {
+	h.issued = append(h.issued, fn)
+}
+
+// OnDenied registers the callback invoked when the request is rejected, the internal failures excluded.
+func (h *Hooks) OnDenied(fn func(ctx context.Context, denial Denial)) {
+	h.denied = append(h.denied, fn)
+}
+
+func (h *Hooks) runAuthenticated(ctx context.Context, peer *ledger.Peer) {
+	for _, fn := range h.authenticated {
+		runHook(ctx, "OnAuthenticated", func() { fn(ctx, peer) })
+	}
+}
+
+func (h *Hooks) runValidated(ctx context.Context, csr *x509.CertificateRequest) {
+	for _, fn := range h.validated {
+		runHook(ctx, "OnValidated", func() { fn(ctx, csr) })
+	}
+}
+
+func (h *Hooks) runIssued(ctx context.Context, cert *x509.Certificate, record ledger.Record) {
+	for _, fn := range h.issued {
+		runHook(ctx, "OnIssued", func() { fn(ctx, cert, record) })
+	}
+}
+
+func (h *Hooks) runDenied(ctx context.Context, denial Denial) {
+	for _, fn := range h.denied {
+		runHook(ctx, "OnDenied", func() { fn(ctx, denial) })
+	}
+}
+
+// runHook invokes the callback, recovering from its panics so a faulty hook doesn't crash the signer.
+func runHook(ctx context.Context, name string, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.FromContext(ctx).Error("Hook panicked", "hook", name, "panic", r)
+		}
+	}()
+
+	fn()
+}
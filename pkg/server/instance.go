@@ -0,0 +1,68 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/identity"
+)
+
+// InstanceIdentityOptions configures the cloud instance identity documents presented by the nodes as an additional
+// authentication factor.
+type InstanceIdentityOptions struct {
+	// Verifier verifies the documents and their account: nil disables the factor, ignoring the metadata.
+	Verifier *identity.Verifier
+	// Required rejects the requests without an instance identity document.
+	Required bool
+}
+
+// instanceIdentity returns the verified cloud instance identity of the request, empty when not sent or not enabled.
+func (s *Server) instanceIdentity(ctx context.Context, md metadata.MD) (string, error) {
+	if s.InstanceIdentity.Verifier == nil {
+		return "", nil
+	}
+
+	providers, documents := md.Get(identity.ProviderMetadataKey), md.Get(identity.DocumentMetadataKey)
+	if len(providers) == 0 || len(documents) == 0 {
+		if s.InstanceIdentity.Required {
+			return "", s.deny(ctx, "", pkgerrors.Auth(pkgerrors.ReasonInvalidInstanceIdentity,
+				"missing "+identity.ProviderMetadataKey+" and "+identity.DocumentMetadataKey+" metadata"))
+		}
+
+		return "", nil
+	}
+
+	document := documents[0]
+
+	var signature []byte
+
+	// The AWS documents are JSON, base64 encoded along with their signature, while the tokens are sent as is
+	if providers[0] == identity.ProviderAWS {
+		decoded, err := base64.StdEncoding.DecodeString(document)
+		if err != nil {
+			return "", s.deny(ctx, "", pkgerrors.Auth(pkgerrors.ReasonInvalidInstanceIdentity, "invalid instance identity encoding"))
+		}
+
+		document = string(decoded)
+
+		if signatures := md.Get(identity.SignatureMetadataKey); len(signatures) > 0 {
+			if signature, err = base64.StdEncoding.DecodeString(signatures[0]); err != nil {
+				return "", s.deny(ctx, "", pkgerrors.Auth(pkgerrors.ReasonInvalidInstanceIdentity, "invalid instance identity encoding"))
+			}
+		}
+	}
+
+	instance, err := s.InstanceIdentity.Verifier.Verify(ctx, providers[0], document, signature, time.Now())
+	if err != nil {
+		return "", s.deny(ctx, "", pkgerrors.Auth(pkgerrors.ReasonInvalidInstanceIdentity, err.Error()))
+	}
+
+	return instance.String(), nil
+}
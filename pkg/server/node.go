@@ -0,0 +1,88 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+)
+
+// NodeUUIDMetadataKey is the metadata key of the Talos node UUID, the SMBIOS UUID of the machine,
+// embedded into the issued certificates when enabled.
+const NodeUUIDMetadataKey = "x-node-uuid"
+
+// nodeUUIDPattern matches the canonical, lower case, textual representation of the UUIDs.
+var nodeUUIDPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// NodeUUIDOptions configures the Talos node UUID sent by the clients, making the issued certificates traceable to
+// the hardware identities.
+type NodeUUIDOptions struct {
+	// Enabled accepts the node UUID, validated and embedded into the issued certificates: when disabled, the
+	// metadata is ignored.
+	Enabled bool
+	// Required rejects the requests without a node UUID.
+	Required bool
+	// ExtensionOID embeds the node UUID in a custom extension with the OID, in place of an urn:uuid URI SAN.
+	ExtensionOID asn1.ObjectIdentifier
+}
+
+// nodeUUID returns the validated node UUID of the request, empty when not sent or not enabled.
+func (s *Server) nodeUUID(ctx context.Context, md metadata.MD, csr *x509.CertificateRequest) (string, error) {
+	if !s.NodeUUID.Enabled {
+		return "", nil
+	}
+
+	values := md.Get(NodeUUIDMetadataKey)
+	if len(values) == 0 || values[0] == "" {
+		if s.NodeUUID.Required {
+			return "", s.deny(ctx, csr.Subject.CommonName,
+				pkgerrors.Invalid(pkgerrors.ReasonInvalidNodeUUID, "missing "+NodeUUIDMetadataKey+" metadata"))
+		}
+
+		return "", nil
+	}
+
+	nodeUUID := strings.ToLower(values[0])
+	if !nodeUUIDPattern.MatchString(nodeUUID) {
+		return "", s.deny(ctx, csr.Subject.CommonName,
+			pkgerrors.Invalid(pkgerrors.ReasonInvalidNodeUUID, "invalid node UUID "+values[0]))
+	}
+
+	return nodeUUID, nil
+}
+
+// embed returns the profile embedding the node UUID into the issued certificate, unchanged when empty.
+func (o NodeUUIDOptions) embed(profile signer.Profile, nodeUUID string) (signer.Profile, error) {
+	if nodeUUID == "" {
+		return profile, nil
+	}
+
+	profile.NodeUUID = nodeUUID
+
+	if len(o.ExtensionOID) == 0 {
+		profile.URIs = append(slices.Clone(profile.URIs), &url.URL{Scheme: "urn", Opaque: "uuid:" + nodeUUID})
+
+		return profile, nil
+	}
+
+	value, err := asn1.MarshalWithParams(nodeUUID, "utf8")
+	if err != nil {
+		return profile, err //nolint:wrapcheck
+	}
+
+	profile.ExtraExtensions = append(slices.Clone(profile.ExtraExtensions), pkix.Extension{Id: o.ExtensionOID, Value: value})
+
+	return profile, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/asn1"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+	pb "github.com/clastix/talos-csr-signer/pkg/proto"
+)
+
+func TestNodeUUID(t *testing.T) {
+	const nodeUUID = "4c4c4544-0038-4410-8057-b4c04f4e3232"
+
+	oid := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}
+
+	tests := []struct {
+		name      string
+		options   NodeUUIDOptions
+		nodeUUID  string
+		code      codes.Code
+		uris      []string
+		extension bool
+	}{
+		{name: "disabled", nodeUUID: "not a UUID"},
+		{name: "not sent", options: NodeUUIDOptions{Enabled: true}},
+		{name: "required", options: NodeUUIDOptions{Enabled: true, Required: true}, code: codes.InvalidArgument},
+		{name: "invalid", options: NodeUUIDOptions{Enabled: true}, nodeUUID: "4c4c4544", code: codes.InvalidArgument},
+		{name: "URI SAN", options: NodeUUIDOptions{Enabled: true}, nodeUUID: "4C4C4544-0038-4410-8057-B4C04F4E3232", uris: []string{"urn:uuid:" + nodeUUID}},
+		{name: "extension", options: NodeUUIDOptions{Enabled: true, ExtensionOID: oid}, nodeUUID: nodeUUID, extension: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			s.NodeUUID = tt.options
+
+			ctx := withToken(t.Context(), talosToken)
+			if tt.nodeUUID != "" {
+				ctx = withToken(t.Context(), talosToken, NodeUUIDMetadataKey, tt.nodeUUID)
+			}
+
+			resp, err := s.Certificate(ctx, &pb.CertificateRequest{Csr: newCSR(t, "worker-1")})
+
+			if tt.code != codes.OK {
+				if code, _ := errorInfo(t, err); code != tt.code {
+					t.Fatalf("expected %s, got %v", tt.code, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			certs, err := pki.ParseCertificates(resp.GetCrt())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			uris := make([]string, 0, len(certs[0].URIs))
+			for _, uri := range certs[0].URIs {
+				uris = append(uris, uri.String())
+			}
+
+			if !slices.Equal(uris, tt.uris) && (len(uris) != 0 || len(tt.uris) != 0) {
+				t.Fatalf("expected the URIs %v, got %v", tt.uris, uris)
+			}
+
+			var value string
+
+			for _, ext := range certs[0].Extensions {
+				if ext.Id.Equal(oid) {
+					if _, err = asn1.UnmarshalWithParams(ext.Value, &value, "utf8"); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			if tt.extension != (value == nodeUUID) {
+				t.Fatalf("unexpected node UUID extension %q", value)
+			}
+		})
+	}
+}
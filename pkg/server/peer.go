@@ -0,0 +1,76 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+// peerFromContext returns the identity of the gRPC peer: its address, the negotiated TLS parameters,
+// and the client certificate subject when mutual TLS is used. It returns nil outside an RPC.
+func peerFromContext(ctx context.Context) *ledger.Peer {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	info := &ledger.Peer{Address: p.Addr.String()}
+
+	if tlsInfo, isTLS := p.AuthInfo.(credentials.TLSInfo); isTLS {
+		state := tlsInfo.State
+		info.TLS = tls.VersionName(state.Version) + "/" + tls.CipherSuiteName(state.CipherSuite)
+
+		if len(state.PeerCertificates) > 0 {
+			info.ClientSubject = state.PeerCertificates[0].Subject.String()
+		}
+	}
+
+	return info
+}
+
+// withRequestLogger returns the context carrying the logger of the request, tagged with its ID and the
+// identity of the gRPC peer, along with the request ID: the one sent by the client, or a new one.
+func (s *Server) withRequestLogger(ctx context.Context) (context.Context, string) {
+	requestID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDMetadataKey); len(values) > 0 && len(values[0]) <= maxRequestIDLength {
+			requestID = values[0]
+		}
+	}
+
+	if requestID == "" {
+		requestID = logging.NewRequestID()
+	}
+
+	logger := s.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	logger = logger.With("request_id", requestID)
+
+	if info := peerFromContext(ctx); info != nil {
+		attrs := []any{slog.String("address", info.Address)}
+		if info.TLS != "" {
+			attrs = append(attrs, slog.String("tls", info.TLS))
+		}
+
+		if info.ClientSubject != "" {
+			attrs = append(attrs, slog.String("client", info.ClientSubject))
+		}
+
+		logger = logger.With(slog.Group("peer", attrs...))
+	}
+
+	return logging.NewContext(ctx, logger), requestID
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/x509"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/pop"
+)
+
+// proveKey runs the proof-of-possession challenge of the CSR, identified by its digest: the requests without a
+// nonce are answered with a new one, bound to the CSR in the ledger, to be signed with the CSR private key.
+// The nonces are valid once, for ProofOfPossessionTTL.
+func (s *Server) proveKey(ctx context.Context, md metadata.MD, csr *x509.CertificateRequest, digest string) error {
+	logger := logging.FromContext(ctx)
+
+	nonces, signatures := md.Get(pop.NonceMetadataKey), md.Get(pop.SignatureMetadataKey)
+	if len(nonces) == 0 || len(signatures) == 0 {
+		nonce, err := pop.NewNonce()
+		if err != nil {
+			return pkgerrors.Internal(pkgerrors.ReasonProofRequired, "failed to generate the nonce", err)
+		}
+
+		if err = s.Ledger.CacheResponse(ctx, "pop:"+nonce, []byte(digest), s.ProofOfPossessionTTL); err != nil {
+			logger.Error("Failed to store the proof-of-possession nonce", "error", err)
+			s.Watchdog.Failure(ctx, err)
+
+			return pkgerrors.Backend(pkgerrors.ReasonLedgerUnavailable, "ledger unavailable", err)
+		}
+
+		logger.Info("Challenging the proof of possession of the CSR key")
+		_ = grpc.SetHeader(ctx, metadata.Pairs(pop.NonceMetadataKey, nonce))
+
+		return &pkgerrors.Error{
+			Kind:     pkgerrors.KindChallenge,
+			Reason:   pkgerrors.ReasonProofRequired,
+			Message:  "proof of possession required: sign the " + pop.NonceMetadataKey + " nonce with the CSR private key",
+			Metadata: map[string]string{"nonce": nonce},
+		}
+	}
+
+	nonce := nonces[0]
+
+	bound, found, err := s.Ledger.CachedResponse(ctx, "pop:"+nonce)
+	if err != nil {
+		logger.Error("Failed to lookup the proof-of-possession nonce", "error", err)
+		s.Watchdog.Failure(ctx, err)
+
+		return pkgerrors.Backend(pkgerrors.ReasonLedgerUnavailable, "ledger unavailable", err)
+	}
+
+	if !found || string(bound) != digest {
+		logger.Error("Unknown or expired proof-of-possession nonce")
+
+		return s.deny(ctx, csr.Subject.CommonName, pkgerrors.Auth(pkgerrors.ReasonInvalidProof, "unknown or expired nonce"))
+	}
+
+	// The nonce is consumed by its first use, from any replica sharing the ledger
+	uses, err := s.Ledger.Increment(ctx, "pop-used:"+nonce, s.ProofOfPossessionTTL)
+	if err != nil {
+		logger.Error("Failed to consume the proof-of-possession nonce", "error", err)
+		s.Watchdog.Failure(ctx, err)
+
+		return pkgerrors.Backend(pkgerrors.ReasonLedgerUnavailable, "ledger unavailable", err)
+	}
+
+	if uses > 1 {
+		logger.Error("Proof-of-possession nonce replayed")
+
+		return s.deny(ctx, csr.Subject.CommonName, pkgerrors.Auth(pkgerrors.ReasonInvalidProof, "nonce already used"))
+	}
+
+	if err = pop.Verify(csr.PublicKey, nonce, signatures[0]); err != nil {
+		logger.Error("Invalid proof of possession", "error", err)
+
+		return s.deny(ctx, csr.Subject.CommonName, pkgerrors.Auth(pkgerrors.ReasonInvalidProof, err.Error()))
+	}
+
+	logger.Info("Proof of possession of the CSR key verified")
+
+	return nil
+}
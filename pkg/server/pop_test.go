@@ -0,0 +1,100 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/pop"
+	pb "github.com/clastix/talos-csr-signer/pkg/proto"
+)
+
+func TestProofOfPossession(t *testing.T) {
+	s := newServer(t)
+	s.ProofOfPossessionTTL = time.Minute
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &pb.CertificateRequest{Csr: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})}
+
+	_, err = s.Certificate(withToken(t.Context(), talosToken), req)
+
+	var challenge *pkgerrors.Error
+	if !errors.As(err, &challenge) || challenge.Kind.Code() != codes.FailedPrecondition || challenge.Metadata["nonce"] == "" {
+		t.Fatalf("expected the request to be challenged, got %v", err)
+	}
+
+	nonce := challenge.Metadata["nonce"]
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		nonce  string
+		key    *ecdsa.PrivateKey
+		code   codes.Code
+		reason string
+	}{
+		{name: "unknown nonce", nonce: "unknown", key: key, code: codes.Unauthenticated, reason: pkgerrors.ReasonInvalidProof},
+		{name: "signed by another key", nonce: nonce, key: otherKey, code: codes.Unauthenticated, reason: pkgerrors.ReasonInvalidProof},
+		// The nonce was consumed by the previous attempt
+		{name: "replayed nonce", nonce: nonce, key: key, code: codes.Unauthenticated, reason: pkgerrors.ReasonInvalidProof},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signature, signErr := pop.Sign(tt.key, tt.nonce)
+			if signErr != nil {
+				t.Fatal(signErr)
+			}
+
+			_, err := s.Certificate(withToken(t.Context(), talosToken, pop.NonceMetadataKey, tt.nonce, pop.SignatureMetadataKey, signature), req)
+
+			if code, reason := errorInfo(t, err); code != tt.code || reason != tt.reason {
+				t.Fatalf("expected %s with reason %s, got %s with reason %s", tt.code, tt.reason, code, reason)
+			}
+		})
+	}
+
+	_, err = s.Certificate(withToken(t.Context(), talosToken), req)
+	if !errors.As(err, &challenge) {
+		t.Fatalf("expected the request to be challenged again, got %v", err)
+	}
+
+	signature, err := pop.Sign(key, challenge.Metadata["nonce"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.Certificate(withToken(t.Context(), talosToken, pop.NonceMetadataKey, challenge.Metadata["nonce"], pop.SignatureMetadataKey, signature), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.GetCrt()) == 0 {
+		t.Fatal("expected the certificate to be issued once the key is proven")
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/x509"
+
+	"google.golang.org/grpc/metadata"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+)
+
+// ProfileMetadataKey is the metadata key of the named profile requested for the certificate, in place of the one of
+// the machine role, such as a client certificate for the tooling.
+const ProfileMetadataKey = "x-profile"
+
+// requestedProfile returns the name of the profile requested by the client, or the named profile of its token class,
+// empty when none, refusing the unknown profiles and the CSRs the profile is not allowed for. The tokens of a class may
+// only request its profile, and the CSRs requesting a CA certificate are refused, unless issued a CA profile through
+// its token class.
+func (s *Server) requestedProfile(ctx context.Context, md metadata.MD, csr *x509.CertificateRequest, tokenClass *TokenClass) (string, error) {
+	requestedCA, pathLen, err := signer.RequestedCA(csr)
+	if err != nil {
+		return "", s.deny(ctx, csr.Subject.CommonName, pkgerrors.Invalid(pkgerrors.ReasonMalformedCSR, err.Error()))
+	}
+
+	var requested, name string
+	if values := md.Get(ProfileMetadataKey); len(values) > 0 {
+		requested = values[0]
+	}
+
+	// The class of the token decides its profile, the requested one can't replace it
+	if tokenClass != nil && requested != "" && requested != tokenClass.Profile {
+		return "", s.deny(ctx, csr.Subject.CommonName, &pkgerrors.Error{
+			Kind:    pkgerrors.KindPolicy,
+			Reason:  pkgerrors.ReasonProfileNotAllowed,
+			Message: "the token class " + tokenClass.Name + " only issues the profile " + tokenClass.Profile,
+		})
+	}
+
+	switch {
+	case tokenClass != nil && tokenClass.Role() == "":
+		name = tokenClass.Profile
+	case tokenClass == nil && requested != "":
+		name = requested
+	case requestedCA:
+		return "", s.deny(ctx, csr.Subject.CommonName, caNotAllowed("CA certificates are only issued by the CA profiles"))
+	default:
+		return "", nil
+	}
+
+	_, roles := s.settings()
+
+	profile, found := roles.Named(name)
+	if !found {
+		return "", s.deny(ctx, csr.Subject.CommonName, pkgerrors.Invalid(pkgerrors.ReasonUnknownProfile, "unknown profile "+name))
+	}
+
+	if !profile.AllowsKey(csr.PublicKey) {
+		return "", s.deny(ctx, csr.Subject.CommonName,
+			pkgerrors.Invalid(pkgerrors.ReasonKeyAlgorithm, "the key algorithm is not allowed by the profile "+name))
+	}
+
+	if !profile.AllowsSubject(csr.Subject) {
+		return "", s.deny(ctx, csr.Subject.CommonName, &pkgerrors.Error{
+			Kind:    pkgerrors.KindPolicy,
+			Reason:  pkgerrors.ReasonPolicyDenied,
+			Message: "the Common Name " + csr.Subject.CommonName + " is not allowed by the profile " + name,
+		})
+	}
+
+	switch {
+	case requestedCA && !profile.CA:
+		return "", s.deny(ctx, csr.Subject.CommonName, caNotAllowed("the profile "+name+" does not issue CA certificates"))
+	case profile.CA && (tokenClass == nil || tokenClass.Profile != name):
+		return "", s.deny(ctx, csr.Subject.CommonName, caNotAllowed("the CA profile "+name+" is only issued to its token class"))
+	case profile.CA && !requestedCA:
+		return "", s.deny(ctx, csr.Subject.CommonName, caNotAllowed("the CA profile "+name+" requires a CSR requesting CA:TRUE"))
+	case profile.CA && pathLen > 0:
+		return "", s.deny(ctx, csr.Subject.CommonName, caNotAllowed("the CA profile "+name+" issues a path length of 0"))
+	}
+
+	return name, nil
+}
+
+// caNotAllowed returns the error of the CSRs refused a CA certificate.
+func caNotAllowed(message string) *pkgerrors.Error {
+	return &pkgerrors.Error{Kind: pkgerrors.KindPolicy, Reason: pkgerrors.ReasonCANotAllowed, Message: message}
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"time"
+
+	"github.com/clastix/talos-csr-signer/pkg/denylist"
+	"github.com/clastix/talos-csr-signer/pkg/policy"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+)
+
+// Reload replaces the signing policy and the machine roles while serving, such as after the configuration bundle
+// holding them was modified: the requests being served complete with the replaced ones.
+func (s *Server) Reload(signingPolicy policy.Chain, roles *signer.Roles) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Policy, s.Roles = signingPolicy, roles
+}
+
+// Limits are the limits of the issuance replaced by ReloadLimits while serving.
+type Limits struct {
+	// MaxTTL is the longest validity the clients may request with the TTL metadata: zero refuses the requested TTLs.
+	MaxTTL time.Duration
+	// IssuanceQuota is the maximum number of certificates issued per Common Name in QuotaWindow: zero disables it.
+	IssuanceQuota int64
+	// QuotaWindow is the time window the IssuanceQuota is accounted on.
+	QuotaWindow time.Duration
+	// ReissueCooldown is the duration a new certificate for the same identity is refused for: zero disables it.
+	ReissueCooldown time.Duration
+}
+
+// ReloadLimits replaces the longest requested TTL, the issuance quota, and the re-issuance cooldown while serving,
+// such as after the policy file holding them was modified.
+func (s *Server) ReloadLimits(limits Limits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.MaxTTL, s.IssuanceQuota, s.QuotaWindow, s.ReissueCooldown = limits.MaxTTL, limits.IssuanceQuota,
+		limits.QuotaWindow, limits.ReissueCooldown
+}
+
+// ReloadDenyList replaces the deny-list while serving, such as after the file holding it was modified.
+func (s *Server) ReloadDenyList(list *denylist.List) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.DenyList = list
+}
+
+// ReloadTrustBundle replaces the additional CA certificates returned to the nodes while serving, such as after the
+// CA bundle of the artifact server was updated.
+func (s *Server) ReloadTrustBundle(bundle []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.TrustBundle = bundle
+}
+
+// settings returns the signing policy and the machine roles the requests are served with.
+func (s *Server) settings() (policy.Chain, *signer.Roles) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.Policy, s.Roles
+}
+
+// limits returns the limits of the issuance the requests are served with.
+func (s *Server) limits() Limits {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return Limits{
+		MaxTTL:          s.MaxTTL,
+		IssuanceQuota:   s.IssuanceQuota,
+		QuotaWindow:     s.QuotaWindow,
+		ReissueCooldown: s.ReissueCooldown,
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	pb "github.com/clastix/talos-csr-signer/pkg/proto"
+	"github.com/clastix/talos-csr-signer/pkg/shard"
+)
+
+// ClusterIDMetadataKey is the metadata key of the ID of the cluster the request belongs to, routing it to the
+// server of the cluster in place of the TLS server name.
+const ClusterIDMetadataKey = "x-cluster-id"
+
+// Router serves the requests with the server of the cluster they belong to, each one with its own CA and tokens,
+// letting a single signer serve many tenant clusters on the same listener.
+type Router struct {
+	pb.UnimplementedSecurityServiceServer
+	// Default serves the requests of no known cluster: nil refuses them.
+	Default *Server
+	// Sharder assigns the clusters to the replicas, the ones of the other replicas being refused: nil serves them all.
+	Sharder *shard.Sharder
+
+	mu       sync.RWMutex
+	clusters map[string]*Server
+}
+
+// Certificate implements the SecurityService.Certificate RPC, with the server of the cluster of the request.
+//
+//nolint:wrapcheck
+func (r *Router) Certificate(ctx context.Context, req *pb.CertificateRequest) (*pb.CertificateResponse, error) {
+	srv, err := r.Route(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return srv.Certificate(ctx, req)
+}
+
+// Route returns the server of the cluster of the request: the one of the cluster ID metadata, otherwise the one of
+// the TLS server name, either the whole name or its first label, such as tenant-a for tenant-a.signer.example.com.
+// The requests naming an unknown cluster in their metadata are refused, while the other ones go to the Default, but
+// for the clusters assigned to another replica, refused with its name so the client retries.
+func (r *Router) Route(ctx context.Context) (*Server, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(ClusterIDMetadataKey); len(values) > 0 {
+			if srv, found := r.clusters[values[0]]; found {
+				return r.owned(values[0], srv)
+			}
+
+			return nil, pkgerrors.Invalid(pkgerrors.ReasonUnknownCluster, "unknown cluster "+values[0])
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, isTLS := p.AuthInfo.(credentials.TLSInfo); isTLS && tlsInfo.State.ServerName != "" {
+			serverName := tlsInfo.State.ServerName
+			label, _, _ := strings.Cut(serverName, ".")
+
+			for _, id := range []string{serverName, label} {
+				if srv, found := r.clusters[id]; found {
+					return r.owned(id, srv)
+				}
+			}
+		}
+	}
+
+	if r.Default == nil {
+		return nil, pkgerrors.Invalid(pkgerrors.ReasonUnknownCluster, "the cluster of the request is unknown")
+	}
+
+	return r.Default, nil
+}
+
+// owned returns the server of the cluster, refusing the clusters of the other replicas, which have none.
+func (r *Router) owned(id string, srv *Server) (*Server, error) {
+	if srv != nil {
+		return srv, nil
+	}
+
+	owner := r.Sharder.Owner(id)
+
+	return nil, &pkgerrors.Error{
+		Kind:     pkgerrors.KindUnavailable,
+		Reason:   pkgerrors.ReasonWrongShard,
+		Message:  "the cluster " + id + " is served by the replica " + owner,
+		Metadata: map[string]string{"owner": owner},
+	}
+}
+
+// Update replaces the servers of the clusters, keyed by their ID, while serving, such as after a tenant was added:
+// the requests being served complete with the replaced ones. The clusters assigned to another replica by the
+// Sharder have a nil server.
+func (r *Router) Update(clusters map[string]*Server) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.clusters = clusters
+}
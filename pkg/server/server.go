@@ -6,155 +6,868 @@ package server
 
 import (
 	"context"
-	"crypto/rand"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/pem"
-	"fmt"
-	"log"
+	"log/slog"
 	"math/big"
+	"sort"
+	"sync"
 	"time"
 
-	"google.golang.org/grpc/codes"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
 
+	"github.com/clastix/talos-csr-signer/pkg/approval"
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	"github.com/clastix/talos-csr-signer/pkg/clock"
+	"github.com/clastix/talos-csr-signer/pkg/denylist"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/events"
+	"github.com/clastix/talos-csr-signer/pkg/features"
+	"github.com/clastix/talos-csr-signer/pkg/journal"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/metrics"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+	"github.com/clastix/talos-csr-signer/pkg/policy"
 	pb "github.com/clastix/talos-csr-signer/pkg/proto"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+	"github.com/clastix/talos-csr-signer/pkg/standby"
+	"github.com/clastix/talos-csr-signer/pkg/token"
+	"github.com/clastix/talos-csr-signer/pkg/transparency"
+	"github.com/clastix/talos-csr-signer/pkg/watchdog"
 )
 
+const (
+	// DryRunMetadataKey is the metadata key of the requests to validate without signing, when set to true:
+	// the response only holds the CA certificates, the would-be certificate being described by the
+	// DryRunCertificateMetadataKey trailer.
+	DryRunMetadataKey = "x-dry-run"
+	// TimeMetadataKey is the response header key holding the signer time, in RFC 3339 format.
+	TimeMetadataKey = "x-signer-time"
+	// FingerprintMetadataKey is the response trailer key holding the hex encoded SHA-256 fingerprint of the issued
+	// certificate, when the fingerprint trailers are enabled.
+	FingerprintMetadataKey = "x-certificate-fingerprint"
+	// SPKIHashMetadataKey is the response trailer key holding the base64 encoded SHA-256 hash of the Subject Public
+	// Key Info of the issued certificate, when the fingerprint trailers are enabled.
+	SPKIHashMetadataKey = "x-spki-sha256"
+	// RequestIDMetadataKey is the metadata key of the request ID tagging the log lines of a request:
+	// generated when missing, and answered in the response header.
+	RequestIDMetadataKey = "x-request-id"
+)
+
+// maxRequestIDLength is the longest request ID accepted from the clients.
+const maxRequestIDLength = 128
+
+// maxSerialAttempts is the number of serial numbers tried before giving up on a collision.
+const maxSerialAttempts = 5
+
 // Server is the struct satisfying the SecurityServiceServer interface.
 type Server struct {
 	pb.UnimplementedSecurityServiceServer
-	CACert       []byte
-	CAPrivateKey interface{}
-	ValidToken   string
+	// Backend signs the certificates with the Talos Machine CA.
+	Backend backend.Backend
+	// Tokens holds the Talos tokens accepted from the nodes: nil accepts the ones of the TokenClasses only.
+	Tokens *token.Source
+	// TokenClasses are the classes of tokens issuing the certificates with their own profile, checked before the
+	// Tokens.
+	TokenClasses []TokenClass
+	// Authenticator validates the tokens in place of the Tokens: nil disables it.
+	Authenticator Authenticator
+	// TrustBundle holds the additional PEM encoded CA certificates returned to the nodes along with the
+	// signing one, trusting both the current and the next CA during a rotation: nil disables it.
+	TrustBundle []byte
+	// CAChain holds the PEM encoded certificates of the chain from the intermediate signing CA up to the root,
+	// returned to the nodes after the signing CA: nil when the signing CA is the root.
+	CAChain []byte
+	// ExtraRoots holds the additional PEM encoded root certificates returned to the nodes after the trust bundle, such
+	// as the previous CA or the OS-level extra roots, even though only one CA signs: nil disables it.
+	ExtraRoots []byte
+	// Features holds the state of the feature gates.
+	Features *features.Gates
+	// Clock refuses the issuance while the system clock is skewed: nil disables it.
+	Clock *clock.Checker
+	// Ledger keeps the issuance state, shared across replicas when backed by Redis.
+	Ledger ledger.Ledger
+	// Policy holds the validators the CSRs go through after their signature is verified, dry runs included.
+	Policy policy.Chain
+	// DenyList refuses the CSRs of the denied identities before the Policy, the retried ones included: nil disables it.
+	DenyList *denylist.List
+	// NodeUUID configures the Talos node UUID embedded into the issued certificates.
+	NodeUUID NodeUUIDOptions
+	// TPMAttestation configures the TPM quotes binding the CSRs to the hardware of the nodes.
+	TPMAttestation TPMAttestationOptions
+	// InstanceIdentity configures the cloud instance identity documents authenticating the nodes along with the token.
+	InstanceIdentity InstanceIdentityOptions
+	// SerialFormat is the format of the serial numbers of the issued certificates.
+	SerialFormat pki.SerialFormat
+	// MaxTTL is the longest validity the clients may request with the TTL metadata, the profile of the machine role
+	// bounding it as well: zero refuses the requests carrying it.
+	MaxTTL time.Duration
+	// CAExpiry is the action taken when a certificate would outlive the CA, truncate or reject: empty truncates it.
+	CAExpiry string
+	// Roles detects the machine role of the CSRs, issuing their certificates with the profile of the role:
+	// nil issues every certificate with the default profile.
+	Roles *signer.Roles
+	// RetryCacheTTL is the duration a signed certificate is served again for the very same CSR,
+	// letting nodes retrying after a lost response get a consistent answer from any replica: zero disables it.
+	RetryCacheTTL time.Duration
+	// IssuanceQuota is the maximum number of certificates issued per Common Name in QuotaWindow: zero disables it.
+	IssuanceQuota int64
+	// QuotaWindow is the time window the IssuanceQuota is accounted on.
+	QuotaWindow time.Duration
+	// ReissueCooldown is the duration a new certificate for the same Common Name and SANs is refused for,
+	// unless the request is an authenticated renewal: zero disables it.
+	ReissueCooldown time.Duration
+	// Approvals holds the privileged certificates until M of the N approvers approved them: nil disables it.
+	Approvals *approval.Queue
+	// ProofOfPossessionTTL is the lifetime of the nonces the clients sign with the CSR private key before a
+	// certificate is released, proving the live possession of the key: zero disables the challenge.
+	ProofOfPossessionTTL time.Duration
+	// FingerprintTrailers answers the fingerprint and the SPKI hash of the issued certificates in the response
+	// trailers, letting the clients pin them without parsing the certificate.
+	FingerprintTrailers bool
+	// Transparency publishes the issued certificates, storing the proof of their inclusion in the ledger records:
+	// nil disables it.
+	Transparency transparency.Log
+	// TransparencyRequired fails the issuance when the certificate cannot be published, rather than recording it
+	// without the proof.
+	TransparencyRequired bool
+	// Standby refuses the requests while replicating the primary signer, until promoted: nil disables it.
+	Standby *standby.Replica
+	// Watchdog is notified of internal failures, rejecting requests once it tripped: nil disables it.
+	Watchdog *watchdog.Watchdog
+	// Journal persists the in-flight signings, replayed after a restart: nil disables it.
+	Journal *journal.Journal
+	// Events receives the issuance and denial events, fanned out to its subscribers: nil disables it.
+	Events *events.Bus
+	// Hooks holds the callbacks registered by the embedders.
+	Hooks Hooks
+	// Logger is the structured logger the request-scoped ones derive from: nil uses slog.Default().
+	Logger *slog.Logger
+
+	// mu guards the Policy and the Roles replaced by Reload, the limits replaced by ReloadLimits, the DenyList
+	// replaced by ReloadDenyList, and the TrustBundle replaced by ReloadTrustBundle, while serving.
+	mu sync.RWMutex
 }
 
 // Certificate implements the SecurityService.Certificate RPC.
 //
 //nolint:wrapcheck
 func (s *Server) Certificate(ctx context.Context, req *pb.CertificateRequest) (*pb.CertificateResponse, error) {
-	log.Printf("=== New Certificate Request Received ===")
+	ctx, requestID := s.withRequestLogger(ctx)
+	logger := logging.FromContext(ctx)
+	logger.Info("New certificate request received")
+
+	// Answer the request ID, letting the clients correlate their requests with the signer logs
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, requestID))
+
+	if s.Watchdog.Tripped() {
+		logger.Error("Signer is not serving after unrecoverable internal failures")
+
+		return nil, pkgerrors.Unavailable(pkgerrors.ReasonNotServing, "signer is not serving", nil)
+	}
+
+	if s.Standby.Active() {
+		logger.Error("Signer is a standby, not issuing certificates until promoted")
+
+		return nil, pkgerrors.Unavailable(pkgerrors.ReasonStandby, "signer is a standby", nil)
+	}
+
+	if err := s.Clock.Err(); err != nil {
+		logger.Error("Refusing to issue certificates", "error", err)
+
+		return nil, pkgerrors.Unavailable(pkgerrors.ReasonClockSkew, "signer clock is skewed", err)
+	}
+
+	// Report the signer time, letting the clients detect clock skews
+	_ = grpc.SetHeader(ctx, metadata.Pairs(TimeMetadataKey, time.Now().UTC().Format(time.RFC3339Nano)))
 
 	// Extract and validate token from metadata
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		log.Printf("ERROR: No metadata in request")
+		logger.Error("No metadata in request")
 
-		return nil, status.Error(codes.Unauthenticated, "missing metadata")
+		return nil, s.deny(ctx, "", pkgerrors.Auth(pkgerrors.ReasonMissingMetadata, "missing metadata"))
 	}
 
-	log.Printf("Metadata extracted successfully")
-
 	// Talos sends token directly in metadata "token" field, not as authorization header
 	tokenHeader := md.Get("token")
 	if len(tokenHeader) == 0 {
-		log.Printf("ERROR: No token in metadata")
-		log.Printf("Available metadata keys: %v", md)
+		logger.Error("No token in metadata", "keys", metadataKeys(md))
 
-		return nil, status.Error(codes.Unauthenticated, "missing token")
+		return nil, s.deny(ctx, "", pkgerrors.Auth(pkgerrors.ReasonMissingToken, "missing token"))
 	}
 
-	log.Printf("Token found in metadata")
-
 	token := tokenHeader[0]
-	log.Printf("Token prefix: %s...", token[:min(8, len(token))])
+	logger.Info("Token found in metadata")
+
+	var tokenClass *TokenClass
+
+	if s.Authenticator != nil {
+		authenticated, authErr := s.Authenticator.Authenticate(ctx, token, peerFromContext(ctx))
+		if authErr != nil {
+			logger.Error("Failed to authenticate the token", "error", authErr)
 
-	if token != s.ValidToken {
-		log.Printf("ERROR: Invalid token received")
-		log.Printf("  Received: %s...", token[:min(8, len(token))])
-		log.Printf("  Expected: %s...", s.ValidToken[:min(8, len(s.ValidToken))])
+			return nil, pkgerrors.Backend(pkgerrors.ReasonAuthenticatorUnavailable, "authenticator unavailable", authErr)
+		}
 
-		return nil, status.Error(codes.Unauthenticated, "invalid token")
+		if !authenticated {
+			logger.Error("Token rejected by the authenticator")
+
+			return nil, s.deny(ctx, "", pkgerrors.Auth(pkgerrors.ReasonInvalidToken, "invalid token"))
+		}
+	} else if tokenClass = s.tokenClass(ctx, token); tokenClass != nil {
+		logger = logger.With("token_class", tokenClass.Name)
+		ctx = logging.NewContext(ctx, logger)
+
+		// The role of the class replaces the one detected on the CSR, the policy included
+		if role := tokenClass.Role(); role != "" {
+			ctx = policy.NewRoleContext(ctx, role)
+		}
+
+		// The tokens bound to a node identity only obtain its certificates
+		if tokenClass.Identity.Bound() {
+			ctx = policy.NewTokenIdentityContext(ctx, tokenClass.Identity)
+		}
+	} else if s.Tokens == nil {
+		logger.Error("Invalid token received")
+
+		return nil, s.deny(ctx, "", pkgerrors.Auth(pkgerrors.ReasonInvalidToken, "invalid token"))
+	} else if !s.Tokens.Get(ctx).Valid(token, time.Now()) {
+		logger.Error("Invalid token received")
+
+		return nil, s.deny(ctx, "", pkgerrors.Auth(pkgerrors.ReasonInvalidToken, "invalid token"))
 	}
 
-	log.Printf("Token validated successfully")
+	logger.Info("Token validated successfully")
 
-	// Parse the CSR
-	log.Printf("Parsing CSR (length: %d bytes)", len(req.GetCsr()))
+	instanceIdentity, err := s.instanceIdentity(ctx, md)
+	if err != nil {
+		logger.Error("Invalid instance identity", "error", err)
+
+		return nil, err
+	}
 
+	if instanceIdentity != "" {
+		logger = logger.With("instance", instanceIdentity)
+		ctx = logging.NewContext(ctx, logger)
+	}
+	s.Hooks.runAuthenticated(ctx, peerFromContext(ctx))
+
+	// Parse the CSR
 	block, _ := pem.Decode(req.GetCsr())
 	if block == nil {
-		log.Printf("ERROR: Failed to decode PEM CSR")
+		logger.Error("Failed to decode PEM CSR", "length", len(req.GetCsr()))
 
-		return nil, status.Error(codes.InvalidArgument, "failed to decode PEM CSR")
+		return nil, s.deny(ctx, "", pkgerrors.Invalid(pkgerrors.ReasonMalformedCSR, "failed to decode PEM CSR"))
 	}
 
-	log.Printf("CSR PEM decoded successfully")
-
 	csr, err := x509.ParseCertificateRequest(block.Bytes)
 	if err != nil {
-		log.Printf("ERROR: Failed to parse CSR: %v", err)
+		logger.Error("Failed to parse CSR", "error", err)
 
-		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("failed to parse CSR: %v", err))
+		return nil, s.deny(ctx, "", pkgerrors.Invalid(pkgerrors.ReasonMalformedCSR, "failed to parse CSR: "+err.Error()))
 	}
 
-	log.Printf("CSR parsed successfully")
+	// Refuse the deny-listed identities before anything else, the retry cache included
+	if err := s.checkDenyList(ctx, md, csr); err != nil {
+		logger.Warn("Deny-listed identity", "common_name", csr.Subject.CommonName, "error", err)
 
-	// Verify CSR signature
-	if err := csr.CheckSignature(); err != nil {
-		log.Printf("ERROR: Invalid CSR signature: %v", err)
+		return nil, err
+	}
 
-		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid CSR signature: %v", err))
+	// Validate the CSR against the policy
+	signingPolicy, _ := s.settings()
+	if err := s.enforce(ctx, policy.Chain{policy.Signature{}}.Then(signingPolicy...), csr); err != nil {
+		return nil, err
 	}
 
-	log.Printf("CSR signature verified")
+	logger = logger.With("common_name", csr.Subject.CommonName)
+	ctx = logging.NewContext(ctx, logger)
+
+	logger.Info("CSR validated against the policy", "dns_names", csr.DNSNames, "ip_addresses", csr.IPAddresses)
+
+	nodeUUID, err := s.nodeUUID(ctx, md, csr)
+	if err != nil {
+		logger.Error("Invalid node UUID", "error", err)
 
-	log.Printf("CSR Details: Subject=%s, DNSNames=%v, IPAddresses=%v",
-		csr.Subject.CommonName, csr.DNSNames, csr.IPAddresses)
+		return nil, err
+	}
 
-	// Parse CA certificate
-	caBlock, _ := pem.Decode(s.CACert)
-	if caBlock == nil {
-		return nil, status.Error(codes.Internal, "failed to decode CA certificate")
+	if nodeUUID != "" {
+		logger = logger.With("node_uuid", nodeUUID)
+		ctx = logging.NewContext(ctx, logger)
 	}
 
-	caCert, err := x509.ParseCertificate(caBlock.Bytes)
+	ttl, err := s.requestedTTL(ctx, md, csr)
 	if err != nil {
-		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to parse CA cert: %v", err))
+		logger.Error("Invalid requested TTL", "error", err)
+
+		return nil, err
 	}
 
-	// Create certificate template
-	serialNumber, err := generateSerialNumber()
+	if ttl > 0 {
+		logger = logger.With("ttl", ttl.String())
+		ctx = logging.NewContext(ctx, logger)
+	}
+
+	profileName, err := s.requestedProfile(ctx, md, csr, tokenClass)
 	if err != nil {
-		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to generate serial: %v", err))
+		logger.Error("Invalid requested profile", "error", err)
+
+		return nil, err
 	}
 
-	template := &x509.Certificate{
-		SerialNumber:          serialNumber,
-		Subject:               csr.Subject,
-		NotBefore:             time.Now(),
-		NotAfter:              time.Now().Add(365 * 24 * time.Hour), // 1 year validity
-		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-		BasicConstraintsValid: true,
-		DNSNames:              csr.DNSNames,
-		IPAddresses:           csr.IPAddresses,
+	if profileName != "" {
+		logger = logger.With("profile", profileName)
+		ctx = logging.NewContext(ctx, logger)
 	}
 
-	// Sign the certificate
-	certDER, err := x509.CreateCertificate(nil, template, caCert, csr.PublicKey, s.CAPrivateKey)
+	attestationKey, err := s.attest(ctx, md, csr)
 	if err != nil {
-		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to create certificate: %v", err))
+		logger.Error("Invalid TPM attestation", "error", err)
+
+		return nil, err
+	}
+
+	if attestationKey != "" {
+		logger = logger.With("attestation_key", attestationKey)
+		ctx = logging.NewContext(ctx, logger)
+	}
+
+	s.Hooks.runValidated(ctx, csr)
+
+	// Dry run requests are validated without signing, letting the nodes diagnose their setup
+	if values := md.Get(DryRunMetadataKey); len(values) > 0 && values[0] == "true" {
+		pending := pendingSigning{NodeUUID: nodeUUID, TTL: ttl, Profile: profileName}
+		if tokenClass != nil {
+			pending.TokenClass, pending.Role = tokenClass.Name, tokenClass.Role()
+		}
+
+		return s.dryRun(ctx, csr, pending)
+	}
+
+	// The retried requests are identified by their CSR along with the requested TTL and profile: a retry requesting
+	// another validity or profile isn't served the certificate cached for the previous one
+	digester := sha256.New()
+	digester.Write(block.Bytes)
+	digester.Write([]byte("\x00" + ttl.String() + "\x00" + profileName))
+	retryKey := hex.EncodeToString(digester.Sum(nil))
+
+	if s.ProofOfPossessionTTL > 0 {
+		if err := s.proveKey(ctx, md, csr, retryKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.RetryCacheTTL > 0 {
+		cached, found, cacheErr := s.Ledger.CachedResponse(ctx, retryKey)
+		if cacheErr != nil {
+			logger.Error("Failed to lookup retry cache", "error", cacheErr)
+			s.Watchdog.Failure(ctx, cacheErr)
+
+			return nil, pkgerrors.Backend(pkgerrors.ReasonLedgerUnavailable, "ledger unavailable", cacheErr)
+		}
+
+		// The cached response is the signed certificate PEM block, followed by the CA ones: a malformed one is missed.
+		crtBlock, caPEM := pem.Decode(cached)
+		if found && crtBlock == nil {
+			logger.Warn("Ignoring the malformed cached certificate of the retried CSR")
+		}
+
+		if crtBlock != nil {
+			logger.Info("Serving cached certificate for retried CSR")
+
+			if cert, parseErr := x509.ParseCertificate(crtBlock.Bytes); parseErr == nil && s.FingerprintTrailers {
+				setFingerprintTrailers(ctx, cert)
+			}
+
+			return &pb.CertificateResponse{
+				Ca:  caPEM,
+				Crt: pem.EncodeToMemory(crtBlock),
+			}, nil
+		}
+	}
+
+	// The quota and the cooldown are enforced after the retry cache, so the retried requests don't consume them
+	var issuance policy.Chain
+
+	limits := s.limits()
+
+	if limits.IssuanceQuota > 0 {
+		issuance = issuance.Then(policy.Quota{Ledger: s.Ledger, Limit: limits.IssuanceQuota, Window: limits.QuotaWindow})
+	}
+
+	if limits.ReissueCooldown > 0 {
+		issuance = issuance.Then(policy.Cooldown{Ledger: s.Ledger, Window: limits.ReissueCooldown})
+	}
+
+	if err := s.enforce(ctx, issuance, csr); err != nil {
+		return nil, err
+	}
+
+	var approvals []ledger.Approval
+
+	if s.Approvals != nil && s.Approvals.Privileged(csr) {
+		if approvals, err = s.awaitApproval(ctx, csr, retryKey); err != nil {
+			return nil, err
+		}
+	}
+
+	// Track the in-flight signing, so it's not lost if the signer restarts meanwhile
+	pending := pendingSigning{
+		CSR:              req.GetCsr(),
+		RetryKey:         retryKey,
+		NodeUUID:         nodeUUID,
+		TTL:              ttl,
+		Profile:          profileName,
+		AttestationKey:   attestationKey,
+		InstanceIdentity: instanceIdentity,
+		Approvals:        approvals,
+	}
+
+	if tokenClass != nil {
+		pending.TokenClass, pending.Role = tokenClass.Name, tokenClass.Role()
+	}
+
+	journalID, err := s.Journal.Add(journal.KindSigning, pending)
+	if err != nil {
+		logger.Warn("Failed to journal the pending signing", "error", err)
+	}
+
+	defer func() {
+		if doneErr := s.Journal.Done(journalID); doneErr != nil {
+			logger.Warn("Failed to complete the journal entry", "entry", journalID, "error", doneErr)
+		}
+	}()
+
+	return s.issue(ctx, csr, pending)
+}
+
+// metadataKeys returns the sorted keys of the metadata, leaving out their values.
+func metadataKeys(md metadata.MD) []string {
+	keys := make([]string, 0, len(md))
+	for key := range md {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// caBundle returns the CA certificates returned to the nodes: the signing one followed by its chain, along with the
+// trust bundle and the extra roots.
+func (s *Server) caBundle(signingCA []byte) []byte {
+	s.mu.RLock()
+	trustBundle := s.TrustBundle
+	s.mu.RUnlock()
+
+	if len(trustBundle) == 0 && len(s.CAChain) == 0 && len(s.ExtraRoots) == 0 {
+		return signingCA
+	}
+
+	return pki.MergeBundles(signingCA, s.CAChain, trustBundle, s.ExtraRoots)
+}
+
+// CABundle returns the CA certificates returned to the nodes: the signing one followed by its chain, along with the
+// trust bundle and the extra roots.
+func (s *Server) CABundle() []byte {
+	return s.caBundle(pki.EncodeCertificates(s.Backend.Certificate()))
+}
+
+// deny publishes the denial of the request and runs the hooks, returning the error answered to the client.
+func (s *Server) deny(ctx context.Context, commonName string, err *pkgerrors.Error) error {
+	denial := Denial{
+		CommonName: commonName,
+		Code:       err.Kind.Code(),
+		Reason:     err.Message,
+		Policy:     err.Metadata["validator"],
+	}
+
+	s.Events.Emit(events.Event{
+		Type:       events.TypeDenied,
+		CommonName: denial.CommonName,
+		Reason:     denial.Reason,
+		Policy:     denial.Policy,
+		Peer:       peerFromContext(ctx),
+	})
+	s.Hooks.runDenied(ctx, denial)
+
+	return err
+}
+
+// enforce runs the validators on the CSR, recording their verdicts, and returns the error answered
+// to the client when rejected.
+func (s *Server) enforce(ctx context.Context, chain policy.Chain, csr *x509.CertificateRequest) error {
+	logger := logging.FromContext(ctx)
+	verdicts := chain.Validate(ctx, csr)
+
+	for _, verdict := range verdicts {
+		metrics.PolicyVerdicts.WithLabelValues(verdict.Validator, string(verdict.Outcome)).Inc()
+	}
+
+	verdict := policy.Decisive(verdicts)
+	logDecision(ctx, csr.Subject.CommonName, verdict, verdicts)
+
+	metadata := map[string]string{"validator": verdict.Validator}
+	if verdict.Rule != "" {
+		metadata["rule"] = verdict.Rule
+	}
+
+	switch verdict.Outcome {
+	case policy.OutcomeAllow:
+		return nil
+	case policy.OutcomeError:
+		logger.Error("Validator failed", "validator", verdict.Validator, "error", verdict.Err)
+		s.Watchdog.Failure(ctx, verdict.Err)
+
+		return &pkgerrors.Error{
+			Kind:     pkgerrors.KindBackend,
+			Reason:   pkgerrors.ReasonValidatorFailed,
+			Message:  verdict.Reason,
+			Metadata: metadata,
+			Cause:    verdict.Err,
+		}
+	default:
+		logger.Error("CSR rejected by the policy", "validator", verdict.Validator, "rule", verdict.Rule, "reason", verdict.Reason)
+
+		reason := verdict.ErrorReason
+		if reason == "" {
+			reason = pkgerrors.ReasonPolicyDenied
+		}
+
+		return s.deny(ctx, csr.Subject.CommonName, &pkgerrors.Error{
+			Kind:     pkgerrors.KindOf(verdict.Code),
+			Reason:   reason,
+			Message:  verdict.Reason,
+			Metadata: metadata,
+		})
+	}
+}
+
+// logDecision logs the policy decision on the CSR, along with the verdict of every validator evaluated and the rule of
+// the configuration which decided it, letting the operators match the rejections against the policy settings.
+func logDecision(ctx context.Context, commonName string, decisive policy.Verdict, verdicts []policy.Verdict) {
+	attrs := []any{"common_name", commonName, "outcome", decisive.Outcome, "validator", decisive.Validator}
+	if decisive.Rule != "" {
+		attrs = append(attrs, "rule", decisive.Rule)
+	}
+
+	level := slog.LevelInfo
+	if !decisive.Allowed() {
+		level = slog.LevelWarn
+	}
+
+	for _, verdict := range verdicts {
+		// The simulated rejections are the ones to look at before enforcing the validators
+		if verdict.Outcome == policy.OutcomeSimulate {
+			level = slog.LevelWarn
+		}
+
+		group := []any{"outcome", verdict.Outcome, "reason", verdict.Reason}
+		if verdict.Rule != "" {
+			group = append(group, "rule", verdict.Rule)
+		}
+
+		attrs = append(attrs, slog.Group(verdict.Validator, group...))
+	}
+
+	logging.FromContext(ctx).Log(ctx, level, "Policy decision", attrs...)
+}
+
+// pendingSigning is the journal payload of an in-flight certificate signing.
+type pendingSigning struct {
+	CSR              []byte            `json:"csr"`
+	RetryKey         string            `json:"retryKey"`
+	NodeUUID         string            `json:"nodeUUID,omitempty"`
+	TTL              time.Duration     `json:"ttl,omitempty"`
+	Profile          string            `json:"profile,omitempty"`
+	AttestationKey   string            `json:"attestationKey,omitempty"`
+	InstanceIdentity string            `json:"instanceIdentity,omitempty"`
+	Approvals        []ledger.Approval `json:"approvals,omitempty"`
+	TokenClass       string            `json:"tokenClass,omitempty"`
+	Role             signer.Role       `json:"role,omitempty"`
+}
+
+// ReplayJournal completes the signings interrupted by a restart: the certificates are stored in the
+// retry cache, so nodes retrying with the same CSR get them from any replica.
+func (s *Server) ReplayJournal(ctx context.Context) error {
+	// The standby keeps them pending, its ledger being replaced by the one of the primary
+	if s.Standby.Active() {
+		logging.FromContext(ctx).Warn("Keeping the interrupted signings pending until promoted")
+
+		return nil
+	}
+
+	entries, err := s.Journal.Pending(journal.KindSigning)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	for _, entry := range entries {
+		logger := logging.FromContext(ctx).With("entry", entry.ID)
+
+		if s.RetryCacheTTL > 0 {
+			var pending pendingSigning
+			if err = json.Unmarshal(entry.Payload, &pending); err != nil {
+				logger.Warn("Dropping malformed journal entry", "error", err)
+			} else if replayErr := s.replay(logging.NewContext(ctx, logger), pending); replayErr != nil {
+				logger.Warn("Failed to replay the interrupted signing", "error", replayErr)
+			} else {
+				logger.Info("Replayed the interrupted signing")
+			}
+		} else {
+			logger.Warn("Dropping the interrupted signing, the retry cache is disabled")
+		}
+
+		if err = s.Journal.Done(entry.ID); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) replay(ctx context.Context, pending pendingSigning) error {
+	block, _ := pem.Decode(pending.CSR)
+	if block == nil {
+		return pkgerrors.ErrPemDecoding
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	_, err = s.issue(ctx, csr, pending)
+
+	return err
+}
+
+// issue signs the certificate for the validated CSR, recording it in the Ledger.
+//
+//nolint:wrapcheck
+func (s *Server) issue(ctx context.Context, csr *x509.CertificateRequest, pending pendingSigning) (*pb.CertificateResponse, error) {
+	role, profile, err := s.issuanceProfile(csr, pending)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.FromContext(ctx).With("role", role)
+
+	// Sign the certificate with the profile of the machine role, or the requested one
+	issued, err := s.certificateSigner().Issue(ctx, csr, profile)
+	if err != nil {
+		return nil, s.issuanceError(ctx, logger, err)
 	}
 
 	// Encode signed certificate to PEM
-	certPEM := pem.EncodeToMemory(&pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: certDER,
+	certPEM := pki.EncodeCertificates(issued.Certificate)
+	caPEM := s.caBundle(issued.CA)
+
+	record := ledger.NewRecord(issued.Certificate, issued.Backend)
+	record.Peer = peerFromContext(ctx)
+	record.Role = string(role)
+	record.Profile = pending.Profile
+	record.TokenClass = pending.TokenClass
+	record.NodeUUID = pending.NodeUUID
+	record.AttestationKey = pending.AttestationKey
+	record.InstanceIdentity = pending.InstanceIdentity
+	record.Approvals = pending.Approvals
+
+	// Publish the certificate to the transparency log, making the issuance externally auditable
+	if s.Transparency != nil {
+		if record.Transparency, err = s.Transparency.Submit(ctx, issued.Certificate, issued.CA); err != nil {
+			logger.Error("Failed to publish the certificate to the transparency log", "serial", record.Serial, "error", err)
+
+			if s.TransparencyRequired {
+				return nil, pkgerrors.Backend(pkgerrors.ReasonTransparencyUnavailable, "transparency log unavailable", err)
+			}
+		} else {
+			logger.Info("Certificate published to the transparency log",
+				"serial", record.Serial, "log_index", record.Transparency.LogIndex)
+		}
+	}
+
+	if err = s.Ledger.Store(ctx, record); err != nil {
+		logger.Error("Failed to record issued certificate", "serial", record.Serial, "error", err)
+		s.Watchdog.Failure(ctx, err)
+
+		return nil, pkgerrors.Backend(pkgerrors.ReasonLedgerUnavailable, "ledger unavailable", err)
+	}
+
+	if s.RetryCacheTTL > 0 {
+		if err = s.Ledger.CacheResponse(ctx, pending.RetryKey, append(certPEM, caPEM...), s.RetryCacheTTL); err != nil {
+			logger.Warn("Failed to cache the response for retries", "error", err)
+		}
+	}
+
+	s.Watchdog.Success()
+	s.Events.Emit(events.Event{
+		Type:        events.TypeIssued,
+		Serial:      record.Serial,
+		Fingerprint: record.Fingerprint,
+		SPKIHash:    record.SPKIHash,
+		CommonName:  record.CommonName,
+		NotAfter:    &record.NotAfter,
+		Backend:     record.Backend,
+		Peer:        record.Peer,
 	})
+	s.Hooks.runIssued(ctx, issued.Certificate, record)
 
-	log.Printf("✓ Certificate signed successfully for: %s (valid until: %s)",
-		csr.Subject.CommonName, template.NotAfter.Format(time.RFC3339))
-	log.Printf("=== Certificate Request Completed Successfully ===")
+	logger.Info("Certificate signed successfully",
+		"serial", record.Serial,
+		"fingerprint", record.Fingerprint,
+		"spki_sha256", record.SPKIHash,
+		"backend", issued.Backend,
+		"not_after", issued.Certificate.NotAfter.Format(time.RFC3339))
+
+	if s.FingerprintTrailers {
+		setFingerprintTrailers(ctx, issued.Certificate)
+	}
 
 	return &pb.CertificateResponse{
-		Ca:  s.CACert,
+		Ca:  caPEM,
 		Crt: certPEM,
 	}, nil
 }
 
-func generateSerialNumber() (*big.Int, error) {
-	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
+// issuanceProfile returns the machine role of the CSR, along with the profile its certificate is issued with: the one
+// of the role, or the requested one, embedding the node UUID and shortened by the requested TTL.
+//
+//nolint:wrapcheck
+func (s *Server) issuanceProfile(csr *x509.CertificateRequest, pending pendingSigning) (signer.Role, signer.Profile, error) {
+	_, roles := s.settings()
+
+	role := roles.Detect(csr)
+	if pending.Role != "" {
+		role = pending.Role
+	}
+
+	// The profile requested by the client replaces the one of the machine role
+	profile := roles.Profile(role)
+	if pending.Profile != "" {
+		named, found := roles.Named(pending.Profile)
+		if !found {
+			return role, profile, pkgerrors.Invalid(pkgerrors.ReasonUnknownProfile, "unknown profile "+pending.Profile)
+		}
+
+		profile = named
+	}
+
+	profile, err := s.NodeUUID.embed(profile, pending.NodeUUID)
+	if err != nil {
+		return role, profile, pkgerrors.Internal(pkgerrors.ReasonInvalidNodeUUID, "failed to embed the node UUID", err)
+	}
+
+	// The requested TTL only shortens the validity of the profile
+	if pending.TTL > 0 && pending.TTL < profile.Validity {
+		profile.Validity = pending.TTL
+	}
+
+	return role, profile, nil
+}
+
+// certificateSigner returns the Signer of the certificates.
+func (s *Server) certificateSigner() *signer.Signer {
+	return signer.New(signer.Options{
+		Backend:             s.Backend,
+		CAExpiry:            s.CAExpiry,
+		SerialNumber:        s.reserveSerialNumber,
+		ReleaseSerialNumber: s.releaseSerialNumber,
+	})
+}
+
+// issuanceError returns the error answered to the client when the certificate cannot be issued.
+func (s *Server) issuanceError(ctx context.Context, logger *slog.Logger, err error) error {
+	if rejected := templateError(logger, err); rejected != nil {
+		return rejected
+	}
+
+	s.Watchdog.Failure(ctx, err)
+
+	if errors.Is(err, pkgerrors.ErrSerialNumber) {
+		return pkgerrors.Internal(pkgerrors.ReasonSerialNumber, "failed to generate serial", err)
+	}
+
+	logger.Error("Failed to sign certificate", "error", err)
 
-	return rand.Int(rand.Reader, serialNumberLimit) //nolint:wrapcheck
+	return pkgerrors.Backend(pkgerrors.ReasonBackendUnavailable, "failed to create certificate", err)
+}
+
+// templateError returns the error answered to the client when the certificate template cannot be built for the CSR,
+// nil when the error is not one of the template.
+func templateError(logger *slog.Logger, err error) *pkgerrors.Error {
+	if errors.Is(err, pkgerrors.ErrOutlivesCA) {
+		logger.Error("Refused to issue a certificate outliving the CA", "error", err)
+
+		return pkgerrors.Unavailable(pkgerrors.ReasonCAExpiring, "the certificate would outlive the CA", err)
+	}
+
+	if errors.Is(err, pkgerrors.ErrNameConstraints) {
+		logger.Warn("Refused to issue a certificate violating the CA name constraints", "error", err)
+
+		return &pkgerrors.Error{Kind: pkgerrors.KindPolicy, Reason: pkgerrors.ReasonNameConstraints, Message: err.Error()}
+	}
+
+	if errors.Is(err, pkgerrors.ErrSubject) {
+		logger.Error("Failed to rewrite the subject", "error", err)
+
+		return &pkgerrors.Error{Kind: pkgerrors.KindPolicy, Reason: pkgerrors.ReasonPolicyDenied, Message: err.Error()}
+	}
+
+	if errors.Is(err, pkgerrors.ErrSPIFFEID) {
+		logger.Error("Failed to derive the SPIFFE ID", "error", err)
+
+		return &pkgerrors.Error{Kind: pkgerrors.KindPolicy, Reason: pkgerrors.ReasonPolicyDenied, Message: err.Error()}
+	}
+
+	return nil
+}
+
+// setFingerprintTrailers answers the fingerprint and the SPKI hash of the issued certificate in the response trailers.
+func setFingerprintTrailers(ctx context.Context, cert *x509.Certificate) {
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(
+		FingerprintMetadataKey, hex.EncodeToString(fingerprint[:]),
+		SPKIHashMetadataKey, ledger.SPKIHash(cert),
+	))
+}
+
+// reserveSerialNumber generates a random serial of the SerialFormat and claims it in the Ledger,
+// retrying on collisions with the serials issued by any replica.
+func (s *Server) reserveSerialNumber(ctx context.Context) (*big.Int, error) {
+	for range maxSerialAttempts {
+		serialNumber, err := s.SerialFormat.Generate()
+		if err != nil {
+			return nil, err
+		}
+
+		reserved, err := s.Ledger.ReserveSerial(ctx, serialNumber.Text(16))
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+
+		if reserved {
+			return serialNumber, nil
+		}
+
+		logging.FromContext(ctx).Warn("Serial number collision, generating a new one")
+	}
+
+	return nil, pkgerrors.ErrSerialCollision
+}
+
+// releaseSerialNumber releases the serial reserved in the Ledger for a certificate which has not been issued.
+func (s *Server) releaseSerialNumber(ctx context.Context, serialNumber *big.Int) {
+	if err := s.Ledger.ReleaseSerial(ctx, serialNumber.Text(16)); err != nil {
+		logging.FromContext(ctx).Warn("Failed to release the reserved serial number", "error", err)
+	}
 }
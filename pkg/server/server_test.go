@@ -0,0 +1,255 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"regexp"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+	"github.com/clastix/talos-csr-signer/pkg/policy"
+	pb "github.com/clastix/talos-csr-signer/pkg/proto"
+	"github.com/clastix/talos-csr-signer/pkg/token"
+)
+
+const talosToken = "abcdef.0123456789abcdef"
+
+// newServer returns the Server signing with a new CA, accepting the talosToken.
+func newServer(t *testing.T) *Server {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template, err := pki.CATemplate(pkix.Name{CommonName: "talos"}, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca, err := pki.Sign(template, key.Public(), template, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local, err := backend.NewLocal("local", pki.EncodeCertificates(ca), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := token.NewStatic(talosToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Server{
+		Backend: local,
+		Tokens:  tokens,
+		Ledger:  ledger.NewMemory(),
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+// newCSR returns the PEM encoded CSR of a new key for the Common Name.
+func newCSR(t *testing.T, commonName string) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: commonName}}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
+// withToken returns the incoming context of a request sending the token, along with the metadata pairs.
+func withToken(ctx context.Context, talosToken string, pairs ...string) context.Context {
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(append([]string{"token", talosToken}, pairs...)...))
+}
+
+// errorInfo returns the gRPC code and the ErrorInfo reason answered for the error.
+func errorInfo(t *testing.T, err error) (codes.Code, string) {
+	t.Helper()
+
+	st := status.Convert(err)
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return st.Code(), info.GetReason()
+		}
+	}
+
+	return st.Code(), ""
+}
+
+func TestCertificateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    func(context.Context) context.Context
+		csr    []byte
+		code   codes.Code
+		reason string
+	}{
+		{
+			name:   "no metadata",
+			ctx:    func(ctx context.Context) context.Context { return ctx },
+			code:   codes.Unauthenticated,
+			reason: pkgerrors.ReasonMissingMetadata,
+		},
+		{
+			name: "no token",
+			ctx: func(ctx context.Context) context.Context {
+				return metadata.NewIncomingContext(ctx, metadata.Pairs("other", "value"))
+			},
+			code:   codes.Unauthenticated,
+			reason: pkgerrors.ReasonMissingToken,
+		},
+		{
+			name:   "invalid token",
+			ctx:    func(ctx context.Context) context.Context { return withToken(ctx, "zzzzzz.0123456789abcdef") },
+			code:   codes.Unauthenticated,
+			reason: pkgerrors.ReasonInvalidToken,
+		},
+		{
+			name:   "malformed CSR",
+			ctx:    func(ctx context.Context) context.Context { return withToken(ctx, talosToken) },
+			csr:    []byte("not a CSR"),
+			code:   codes.InvalidArgument,
+			reason: pkgerrors.ReasonMalformedCSR,
+		},
+		{
+			name:   "policy denial",
+			ctx:    func(ctx context.Context) context.Context { return withToken(ctx, talosToken) },
+			csr:    newCSR(t, "controlplane-1"),
+			code:   codes.PermissionDenied,
+			reason: pkgerrors.ReasonPolicyDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			s.Policy = policy.Chain{policy.SubjectPolicy{CommonName: regexp.MustCompile(`^worker-`)}}
+
+			_, err := s.Certificate(tt.ctx(t.Context()), &pb.CertificateRequest{Csr: tt.csr})
+
+			if code, reason := errorInfo(t, err); code != tt.code || reason != tt.reason {
+				t.Fatalf("expected %s with reason %s, got %s with reason %s", tt.code, tt.reason, code, reason)
+			}
+		})
+	}
+}
+
+func TestCertificate(t *testing.T) {
+	s := newServer(t)
+	s.RetryCacheTTL = time.Hour
+	s.IssuanceQuota, s.QuotaWindow = 1, time.Hour
+
+	csr := newCSR(t, "worker-1")
+
+	dryRun, err := s.Certificate(withToken(t.Context(), talosToken, DryRunMetadataKey, "true"), &pb.CertificateRequest{Csr: csr})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dryRun.GetCrt()) != 0 || len(dryRun.GetCa()) == 0 {
+		t.Fatal("expected the dry run to answer the CA certificates only")
+	}
+
+	resp, err := s.Certificate(withToken(t.Context(), talosToken), &pb.CertificateRequest{Csr: csr})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certs, err := pki.ParseCertificates(resp.GetCrt())
+	if err != nil || len(certs) != 1 || certs[0].Subject.CommonName != "worker-1" {
+		t.Fatalf("unexpected certificate %v, %v", certs, err)
+	}
+
+	if _, err = s.Ledger.Get(t.Context(), certs[0].SerialNumber.Text(16)); err != nil {
+		t.Fatalf("expected the certificate to be recorded: %v", err)
+	}
+
+	// The retried CSR is served from the cache, without consuming the quota
+	retried, err := s.Certificate(withToken(t.Context(), talosToken), &pb.CertificateRequest{Csr: csr})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(retried.GetCrt()) != string(resp.GetCrt()) || string(retried.GetCa()) != string(resp.GetCa()) {
+		t.Fatal("expected the retried CSR to get the same certificate")
+	}
+
+	_, err = s.Certificate(withToken(t.Context(), talosToken), &pb.CertificateRequest{Csr: newCSR(t, "worker-1")})
+	if code, _ := errorInfo(t, err); code != codes.ResourceExhausted {
+		t.Fatalf("expected the quota to be exhausted, got %v", err)
+	}
+}
+
+// malformedCache is the ledger whose cached responses are all malformed.
+type malformedCache struct {
+	ledger.Ledger
+}
+
+// CachedResponse implements ledger.Ledger.
+func (malformedCache) CachedResponse(context.Context, string) ([]byte, bool, error) {
+	return []byte("malformed"), true, nil
+}
+
+func TestCertificateRetryCache(t *testing.T) {
+	s := newServer(t)
+	s.RetryCacheTTL, s.MaxTTL = time.Hour, 24*time.Hour
+
+	csr := newCSR(t, "worker-1")
+
+	certificate := func(ttl string) string {
+		t.Helper()
+
+		resp, err := s.Certificate(withToken(t.Context(), talosToken, TTLMetadataKey, ttl), &pb.CertificateRequest{Csr: csr})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return string(resp.GetCrt())
+	}
+
+	issued := certificate("1h")
+
+	if certificate("1h") != issued {
+		t.Fatal("expected the retried CSR to get the same certificate")
+	}
+
+	if certificate("2h") == issued {
+		t.Fatal("expected the CSR requesting another TTL to get a new certificate")
+	}
+
+	s.Ledger = malformedCache{Ledger: s.Ledger}
+
+	if certificate("1h") == issued {
+		t.Fatal("expected the malformed cached certificate to be missed")
+	}
+}
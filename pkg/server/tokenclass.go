@@ -0,0 +1,49 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/clastix/talos-csr-signer/pkg/policy"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+	"github.com/clastix/talos-csr-signer/pkg/token"
+)
+
+// TokenClass is a class of tokens, such as the control-plane and the worker ones, issuing the certificates with the
+// profile of a machine role or with a named profile, in place of the one of the role detected on the CSR.
+type TokenClass struct {
+	// Name is the name of the class, logged and stored in the ledger records.
+	Name string
+	// Profile is the machine role, controlplane or worker, or the named profile the certificates are issued with.
+	Profile string
+	// Tokens holds the tokens of the class, reloaded from their file when modified.
+	Tokens *token.Source
+	// Identity is the node identity the tokens are bound to, such as the one of the per-node classes.
+	Identity policy.TokenIdentity
+}
+
+// Role returns the machine role of the class, empty when it issues a named profile.
+func (c TokenClass) Role() signer.Role {
+	switch role := signer.Role(c.Profile); role {
+	case signer.RoleControlPlane, signer.RoleWorker:
+		return role
+	default:
+		return ""
+	}
+}
+
+// tokenClass returns the class of the token, nil when the token belongs to none.
+func (s *Server) tokenClass(ctx context.Context, token string) *TokenClass {
+	now := time.Now()
+
+	for i := range s.TokenClasses {
+		if s.TokenClasses[i].Tokens.Get(ctx).Valid(token, now) {
+			return &s.TokenClasses[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/x509"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// TTLMetadataKey is the metadata key of the validity requested for the certificate, as a Go duration such as 24h,
+// shortening the one of the profile of the machine role.
+const TTLMetadataKey = "x-ttl"
+
+// requestedTTL returns the validity requested by the client, capped by the MaxTTL, zero when not sent.
+func (s *Server) requestedTTL(ctx context.Context, md metadata.MD, csr *x509.CertificateRequest) (time.Duration, error) {
+	values := md.Get(TTLMetadataKey)
+	if len(values) == 0 || values[0] == "" {
+		return 0, nil
+	}
+
+	maxTTL := s.limits().MaxTTL
+	if maxTTL <= 0 {
+		return 0, s.deny(ctx, csr.Subject.CommonName,
+			pkgerrors.Invalid(pkgerrors.ReasonInvalidTTL, "the "+TTLMetadataKey+" metadata is not accepted by the signer"))
+	}
+
+	ttl, err := time.ParseDuration(values[0])
+	if err != nil || ttl <= 0 {
+		return 0, s.deny(ctx, csr.Subject.CommonName,
+			pkgerrors.Invalid(pkgerrors.ReasonInvalidTTL, "invalid TTL "+values[0]+", expected a positive duration such as 24h"))
+	}
+
+	return min(ttl, maxTTL), nil
+}
@@ -0,0 +1,176 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package shard distributes the tenants across the signer replicas with consistent hashing,
+// so each replica only loads the CA material of the tenants it owns.
+package shard
+
+import (
+	"context"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+// virtualNodes is the number of points each member has on the Ring, smoothing the distribution.
+const virtualNodes = 64
+
+// Ring is the consistent hashing ring of the replicas.
+type Ring struct {
+	points []uint32
+	owners map[uint32]string
+}
+
+// NewRing returns the Ring of the given members.
+func NewRing(members []string) *Ring {
+	r := &Ring{owners: make(map[uint32]string, len(members)*virtualNodes)}
+
+	for _, member := range members {
+		for i := range virtualNodes {
+			point := hash(member + "#" + strconv.Itoa(i))
+			r.points = append(r.points, point)
+			r.owners[point] = member
+		}
+	}
+
+	slices.Sort(r.points)
+
+	return r
+}
+
+// Owner returns the member owning the given key, empty when the Ring has no members.
+func (r *Ring) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+
+	point := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= point })
+
+	if i == len(r.points) {
+		i = 0
+	}
+
+	return r.owners[r.points[i]]
+}
+
+func hash(key string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+
+	return h.Sum32()
+}
+
+// Sharder keeps the membership view of the replicas through heartbeats in the shared Ledger,
+// deciding which tenants the local replica owns. A nil Sharder owns every tenant.
+type Sharder struct {
+	ledger   ledger.Ledger
+	self     string
+	interval time.Duration
+
+	mu      sync.RWMutex
+	ring    *Ring
+	members []string
+	changed chan struct{}
+}
+
+// New returns a Sharder for the replica with the given identifier, refreshing the membership at interval.
+func New(l ledger.Ledger, self string, interval time.Duration) *Sharder {
+	return &Sharder{
+		ledger:   l,
+		self:     self,
+		interval: interval,
+		ring:     NewRing([]string{self}),
+		members:  []string{self},
+		changed:  make(chan struct{}, 1),
+	}
+}
+
+// Run sends the heartbeats and refreshes the membership view until the context is done.
+func (s *Sharder) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.Refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh sends a heartbeat and refreshes the membership view once, such as before loading the tenants.
+func (s *Sharder) Refresh(ctx context.Context) {
+	// Members missing three heartbeats in a row are considered gone.
+	if err := s.ledger.Heartbeat(ctx, s.self, 3*s.interval); err != nil {
+		logging.FromContext(ctx).Warn("Failed to send the shard heartbeat", "error", err)
+
+		return
+	}
+
+	members, err := s.ledger.Members(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to refresh the shard membership", "error", err)
+
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.Equal(members, s.members) {
+		return
+	}
+
+	logging.FromContext(ctx).Info("Shard membership changed", "members", members)
+
+	s.members = members
+	s.ring = NewRing(members)
+
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Changed returns the channel notified when the membership changed, moving the tenants across the replicas.
+func (s *Sharder) Changed() <-chan struct{} {
+	if s == nil {
+		return nil
+	}
+
+	return s.changed
+}
+
+// Owns returns true when the tenant is assigned to the local replica.
+func (s *Sharder) Owns(tenant string) bool {
+	if s == nil {
+		return true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ring.Owner(tenant) == s.self
+}
+
+// Owner returns the replica the tenant is assigned to.
+func (s *Sharder) Owner(tenant string) string {
+	if s == nil {
+		return ""
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ring.Owner(tenant)
+}
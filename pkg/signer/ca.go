@@ -0,0 +1,45 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package signer
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// oidBasicConstraints is the OID of the basic constraints extension.
+var oidBasicConstraints = asn1.ObjectIdentifier{2, 5, 29, 19}
+
+// basicConstraints is the ASN.1 BasicConstraints of RFC 5280, section 4.2.1.9.
+type basicConstraints struct {
+	IsCA       bool `asn1:"optional"`
+	MaxPathLen int  `asn1:"optional,default:-1"`
+}
+
+// RequestedCA returns true when the CSR requests a CA certificate, CA:TRUE in its basic constraints extension, along
+// with the requested path length, -1 when unlimited.
+func RequestedCA(csr *x509.CertificateRequest) (bool, int, error) {
+	for _, extension := range csr.Extensions {
+		if !extension.Id.Equal(oidBasicConstraints) {
+			continue
+		}
+
+		var constraints basicConstraints
+		if rest, err := asn1.Unmarshal(extension.Value, &constraints); err != nil || len(rest) > 0 {
+			return false, 0, errors.Wrap(pkgerrors.ErrPolicyViolation, "invalid basic constraints extension")
+		}
+
+		if !constraints.IsCA {
+			return false, 0, nil
+		}
+
+		return true, constraints.MaxPathLen, nil
+	}
+
+	return false, 0, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package signer
+
+import (
+	"crypto/x509"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// checkNameConstraints returns an error when a SAN of the certificate violates the name constraints of the CA, as the
+// certificate would then fail the path validation of the clients.
+func checkNameConstraints(ca, template *x509.Certificate) error {
+	for _, name := range template.DNSNames {
+		if !allowedByConstraints(name, ca.PermittedDNSDomains, ca.ExcludedDNSDomains, matchDomain) {
+			return errors.Wrap(pkgerrors.ErrNameConstraints, "DNS name "+name+" is not allowed by the CA name constraints")
+		}
+	}
+
+	for _, ip := range template.IPAddresses {
+		if !allowedByConstraints(ip, ca.PermittedIPRanges, ca.ExcludedIPRanges, func(ip net.IP, ipRange *net.IPNet) bool {
+			return ipRange.Contains(ip)
+		}) {
+			return errors.Wrap(pkgerrors.ErrNameConstraints, "IP address "+ip.String()+" is not allowed by the CA name constraints")
+		}
+	}
+
+	for _, address := range template.EmailAddresses {
+		if !allowedByConstraints(address, ca.PermittedEmailAddresses, ca.ExcludedEmailAddresses, matchEmail) {
+			return errors.Wrap(pkgerrors.ErrNameConstraints, "email address "+address+" is not allowed by the CA name constraints")
+		}
+	}
+
+	for _, uri := range template.URIs {
+		if len(ca.PermittedURIDomains) == 0 && len(ca.ExcludedURIDomains) == 0 {
+			break
+		}
+
+		// The URIs without a host, such as the urn:uuid: ones, cannot be matched against the constraints
+		if !allowedByConstraints(uri.Hostname(), ca.PermittedURIDomains, ca.ExcludedURIDomains, func(host, constraint string) bool {
+			return host != "" && net.ParseIP(host) == nil && matchDomain(host, constraint)
+		}) {
+			return errors.Wrap(pkgerrors.ErrNameConstraints, "URI "+uri.String()+" is not allowed by the CA name constraints")
+		}
+	}
+
+	return nil
+}
+
+// allowedByConstraints returns true when the name matches none of the excluded constraints, and one of the permitted
+// ones unless there are none.
+func allowedByConstraints[N, C any](name N, permitted, excluded []C, match func(N, C) bool) bool {
+	matches := func(constraint C) bool { return match(name, constraint) }
+
+	if slices.ContainsFunc(excluded, matches) {
+		return false
+	}
+
+	return len(permitted) == 0 || slices.ContainsFunc(permitted, matches)
+}
+
+// matchDomain returns true when the domain name is the constraint or one of its subdomains, only its subdomains when
+// the constraint starts with a dot.
+func matchDomain(name, constraint string) bool {
+	name, constraint = strings.ToLower(name), strings.ToLower(constraint)
+
+	if constraint == "" {
+		return true
+	}
+
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(name, constraint)
+	}
+
+	return name == constraint || strings.HasSuffix(name, "."+constraint)
+}
+
+// matchEmail returns true when the email address is the mailbox of the constraint, or belongs to its domain.
+func matchEmail(address, constraint string) bool {
+	if strings.Contains(constraint, "@") {
+		return strings.EqualFold(address, constraint)
+	}
+
+	_, domain, found := strings.Cut(address, "@")
+	if !found {
+		return false
+	}
+
+	if strings.HasPrefix(constraint, ".") {
+		return matchDomain(domain, constraint)
+	}
+
+	return strings.EqualFold(domain, constraint)
+}
@@ -0,0 +1,181 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package signer
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// reservedExtensions are the OIDs of the extensions built by the Signer, which the extra ones cannot replace: the
+// subject key identifier, the key usage, the SANs, the basic constraints, the name constraints, the authority key
+// identifier, and the extended key usage.
+var reservedExtensions = []asn1.ObjectIdentifier{
+	{2, 5, 29, 14}, {2, 5, 29, 15}, {2, 5, 29, 17}, {2, 5, 29, 19}, {2, 5, 29, 30}, {2, 5, 29, 35}, {2, 5, 29, 37},
+}
+
+// oidCertificatePolicies is the OID of the certificate policies extension, and oidCPS the one of the CPS URI
+// qualifiers of the policies.
+var (
+	oidCertificatePolicies = asn1.ObjectIdentifier{2, 5, 29, 32}
+	oidCPS                 = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 2, 1}
+)
+
+// policyInformation is the ASN.1 PolicyInformation of RFC 5280, section 4.2.1.4.
+type policyInformation struct {
+	Policy     asn1.ObjectIdentifier
+	Qualifiers []policyQualifierInfo `asn1:"optional,omitempty"`
+}
+
+// policyQualifierInfo is the ASN.1 PolicyQualifierInfo of RFC 5280, section 4.2.1.4.
+type policyQualifierInfo struct {
+	PolicyQualifierID asn1.ObjectIdentifier
+	Qualifier         string `asn1:"ia5"`
+}
+
+// CertificatePolicies returns the certificate policies extension of the policy OIDs, each one qualified by the URI
+// of the Certification Practice Statement when set.
+func CertificatePolicies(oids []string, cpsURI string) (pkix.Extension, error) {
+	extension := pkix.Extension{Id: oidCertificatePolicies}
+
+	if cpsURI != "" {
+		if parsed, err := url.Parse(cpsURI); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return extension, errors.Wrap(pkgerrors.ErrProfile, "invalid CPS URI "+cpsURI+", expected an http or https one")
+		}
+	}
+
+	policies := make([]policyInformation, 0, len(oids))
+
+	for _, oid := range oids {
+		policy, err := parseOID(oid)
+		if err != nil {
+			return extension, errors.Wrap(err, "certificate policy")
+		}
+
+		information := policyInformation{Policy: policy}
+		if cpsURI != "" {
+			information.Qualifiers = []policyQualifierInfo{{PolicyQualifierID: oidCPS, Qualifier: cpsURI}}
+		}
+
+		policies = append(policies, information)
+	}
+
+	if len(policies) == 0 {
+		return extension, errors.Wrap(pkgerrors.ErrProfile, "at least a certificate policy is required")
+	}
+
+	var err error
+	if extension.Value, err = asn1.Marshal(policies); err != nil {
+		return extension, errors.Wrap(pkgerrors.ErrProfile, "invalid certificate policies: "+err.Error())
+	}
+
+	return extension, nil
+}
+
+// Extensions returns the extensions of the list, added to the issued certificates, each one as
+// [critical:]<oid>=<type>:<value>, such as 1.3.6.1.4.1.99999.2=utf8:asset-tag. The value is encoded as an ASN.1
+// UTF8String with utf8, an IA5String with ia5, an INTEGER with integer, or given as hex encoded DER with der.
+func Extensions(specs []string) ([]pkix.Extension, error) {
+	extensions := make([]pkix.Extension, 0, len(specs))
+
+	for _, spec := range specs {
+		extension, err := parseExtension(spec)
+		if err != nil {
+			return nil, errors.Wrap(err, "extension "+spec)
+		}
+
+		if slices.ContainsFunc(extensions, func(other pkix.Extension) bool { return other.Id.Equal(extension.Id) }) {
+			return nil, errors.Wrap(pkgerrors.ErrProfile, "duplicated extension "+extension.Id.String())
+		}
+
+		extensions = append(extensions, extension)
+	}
+
+	return extensions, nil
+}
+
+// parseExtension returns the extension of the [critical:]<oid>=<type>:<value> specification.
+func parseExtension(spec string) (pkix.Extension, error) {
+	var extension pkix.Extension
+
+	oid, typedValue, found := strings.Cut(spec, "=")
+	if !found {
+		return extension, errors.Wrap(pkgerrors.ErrProfile, "expected [critical:]<oid>=<type>:<value>")
+	}
+
+	if rest, critical := strings.CutPrefix(oid, "critical:"); critical {
+		oid, extension.Critical = rest, true
+	}
+
+	var err error
+
+	if extension.Id, err = parseOID(oid); err != nil {
+		return extension, err
+	}
+
+	if slices.ContainsFunc(reservedExtensions, extension.Id.Equal) {
+		return extension, errors.Wrap(pkgerrors.ErrProfile, "the extension "+oid+" is built by the signer")
+	}
+
+	kind, value, found := strings.Cut(typedValue, ":")
+	if !found {
+		return extension, errors.Wrap(pkgerrors.ErrProfile, "expected <type>:<value>, such as utf8:asset-tag")
+	}
+
+	switch kind {
+	case "utf8":
+		extension.Value, err = asn1.MarshalWithParams(value, "utf8")
+	case "ia5":
+		extension.Value, err = asn1.MarshalWithParams(value, "ia5")
+	case "integer":
+		var number int64
+		if number, err = strconv.ParseInt(value, 10, 64); err == nil {
+			extension.Value, err = asn1.Marshal(number)
+		}
+	case "der":
+		if extension.Value, err = hex.DecodeString(value); err == nil {
+			var raw asn1.RawValue
+			if rest, unmarshalErr := asn1.Unmarshal(extension.Value, &raw); unmarshalErr != nil || len(rest) > 0 {
+				err = errors.New("not a single DER encoded value")
+			}
+		}
+	default:
+		return extension, errors.Wrap(pkgerrors.ErrProfile, "unsupported type "+kind+", expected utf8, ia5, integer, or der")
+	}
+
+	if err != nil {
+		return extension, errors.Wrap(pkgerrors.ErrProfile, "invalid "+kind+" value: "+err.Error())
+	}
+
+	return extension, nil
+}
+
+// parseOID returns the object identifier of its dotted representation, such as 1.3.6.1.4.1.99999.1.
+func parseOID(oid string) (asn1.ObjectIdentifier, error) {
+	var identifier asn1.ObjectIdentifier
+
+	for _, arc := range strings.Split(oid, ".") {
+		value, err := strconv.Atoi(arc)
+		if err != nil || value < 0 {
+			return nil, errors.Wrap(pkgerrors.ErrProfile, "invalid OID "+oid)
+		}
+
+		identifier = append(identifier, value)
+	}
+
+	if len(identifier) < 2 {
+		return nil, errors.Wrap(pkgerrors.ErrProfile, "invalid OID "+oid)
+	}
+
+	return identifier, nil
+}
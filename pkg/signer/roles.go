@@ -0,0 +1,225 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package signer
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// Role is the Talos machine role a certificate is issued for.
+type Role string
+
+const (
+	// RoleControlPlane is the role of the control-plane nodes.
+	RoleControlPlane Role = "controlplane"
+	// RoleWorker is the role of the worker nodes, and of the nodes not detected as control-plane ones.
+	RoleWorker Role = "worker"
+)
+
+// Roles detects the machine role of the CSRs, issuing their certificates with the profile of the role.
+// A nil Roles issues every certificate with the DefaultProfile.
+type Roles struct {
+	// ControlPlane is the profile of the control-plane certificates.
+	ControlPlane Profile
+	// Worker is the profile of the worker certificates.
+	Worker Profile
+	// ControlPlaneOrganizations are the CSR subject organizations identifying the control-plane nodes.
+	ControlPlaneOrganizations []string
+	// ControlPlaneCommonName matches the CSR Common Name of the control-plane nodes: nil disables it.
+	ControlPlaneCommonName *regexp.Regexp
+	// Profiles are the named profiles the clients may request in place of the one of their role, such as client
+	// certificates for the tooling.
+	Profiles map[string]Profile
+}
+
+// Detect returns the machine role of the CSR: control-plane when its subject matches any of the control-plane
+// organizations or Common Name pattern, worker otherwise.
+func (r *Roles) Detect(csr *x509.CertificateRequest) Role {
+	if r == nil {
+		return RoleWorker
+	}
+
+	for _, organization := range csr.Subject.Organization {
+		if slices.Contains(r.ControlPlaneOrganizations, organization) {
+			return RoleControlPlane
+		}
+	}
+
+	if r.ControlPlaneCommonName != nil && r.ControlPlaneCommonName.MatchString(csr.Subject.CommonName) {
+		return RoleControlPlane
+	}
+
+	return RoleWorker
+}
+
+// Profile returns the profile of the certificates issued for the role.
+func (r *Roles) Profile(role Role) Profile {
+	switch {
+	case r == nil:
+		return DefaultProfile
+	case role == RoleControlPlane:
+		return r.ControlPlane
+	default:
+		return r.Worker
+	}
+}
+
+// Named returns the named profile, false when unknown.
+func (r *Roles) Named(name string) (Profile, bool) {
+	if r == nil {
+		return Profile{}, false
+	}
+
+	profile, found := r.Profiles[name]
+
+	return profile, found
+}
+
+// KeyAlgorithms returns the key algorithms named in the list, validated against the ones known by pki.KeyAlgorithm.
+func KeyAlgorithms(names []string) ([]string, error) {
+	algorithms := make([]string, 0, len(names))
+
+	for _, name := range names {
+		algorithm := strings.ToLower(name)
+
+		switch algorithm {
+		case "ed25519", "ecdsa", "ecdsa-p256", "ecdsa-p384", "ecdsa-p521", "rsa":
+			algorithms = append(algorithms, algorithm)
+		default:
+			return nil, errors.Wrap(pkgerrors.ErrProfile, "unknown key algorithm "+name)
+		}
+	}
+
+	return algorithms, nil
+}
+
+// KeyUsages returns the key usages named in the list: digital-signature, key-encipherment, and key-agreement.
+func KeyUsages(names []string) (x509.KeyUsage, error) {
+	var usages x509.KeyUsage
+
+	for _, name := range names {
+		switch strings.ToLower(name) {
+		case "digital-signature":
+			usages |= x509.KeyUsageDigitalSignature
+		case "key-encipherment":
+			usages |= x509.KeyUsageKeyEncipherment
+		case "key-agreement":
+			usages |= x509.KeyUsageKeyAgreement
+		default:
+			return 0, errors.Wrap(pkgerrors.ErrProfile, "unknown key usage "+name)
+		}
+	}
+
+	if usages == 0 {
+		return 0, errors.Wrap(pkgerrors.ErrProfile, "at least a key usage is required")
+	}
+
+	return usages, nil
+}
+
+// KeyUsageNames returns the names of the key usages, as named by KeyUsages, along with cert-sign and crl-sign.
+func KeyUsageNames(usages x509.KeyUsage) []string {
+	var names []string
+
+	for _, usage := range []struct {
+		usage x509.KeyUsage
+		name  string
+	}{
+		{x509.KeyUsageDigitalSignature, "digital-signature"},
+		{x509.KeyUsageKeyEncipherment, "key-encipherment"},
+		{x509.KeyUsageKeyAgreement, "key-agreement"},
+		{x509.KeyUsageCertSign, "cert-sign"},
+		{x509.KeyUsageCRLSign, "crl-sign"},
+	} {
+		if usages&usage.usage != 0 {
+			names = append(names, usage.name)
+		}
+	}
+
+	return names
+}
+
+// ExtKeyUsages returns the extended key usages named in the list: server, and client.
+func ExtKeyUsages(names []string) ([]x509.ExtKeyUsage, error) {
+	usages := make([]x509.ExtKeyUsage, 0, len(names))
+
+	for _, name := range names {
+		switch strings.ToLower(name) {
+		case "server":
+			usages = append(usages, x509.ExtKeyUsageServerAuth)
+		case "client":
+			usages = append(usages, x509.ExtKeyUsageClientAuth)
+		default:
+			return nil, errors.Wrap(pkgerrors.ErrProfile, "unknown extended key usage "+name)
+		}
+	}
+
+	if len(usages) == 0 {
+		return nil, errors.Wrap(pkgerrors.ErrProfile, "at least an extended key usage is required")
+	}
+
+	return usages, nil
+}
+
+// ExtKeyUsageName returns the name of the extended key usage, as named by ExtKeyUsages: unknown when not known.
+func ExtKeyUsageName(usage x509.ExtKeyUsage) string {
+	switch usage { //nolint:exhaustive
+	case x509.ExtKeyUsageServerAuth:
+		return "server"
+	case x509.ExtKeyUsageClientAuth:
+		return "client"
+	default:
+		return "unknown"
+	}
+}
+
+// oidExtKeyUsage is the OID of the extended key usage extension, and oidExtKeyUsages the ones of the usages known by
+// ExtKeyUsages.
+var (
+	oidExtKeyUsage  = asn1.ObjectIdentifier{2, 5, 29, 37}
+	oidExtKeyUsages = map[string]x509.ExtKeyUsage{
+		"1.3.6.1.5.5.7.3.1": x509.ExtKeyUsageServerAuth,
+		"1.3.6.1.5.5.7.3.2": x509.ExtKeyUsageClientAuth,
+	}
+)
+
+// RequestedExtKeyUsages returns the extended key usages requested by the CSR extension, none when it holds no such
+// extension. It returns an error when a usage is not known by ExtKeyUsages.
+func RequestedExtKeyUsages(csr *x509.CertificateRequest) ([]x509.ExtKeyUsage, error) {
+	for _, extension := range csr.Extensions {
+		if !extension.Id.Equal(oidExtKeyUsage) {
+			continue
+		}
+
+		var oids []asn1.ObjectIdentifier
+		if rest, err := asn1.Unmarshal(extension.Value, &oids); err != nil || len(rest) > 0 {
+			return nil, errors.Wrap(pkgerrors.ErrPolicyViolation, "invalid extended key usage extension")
+		}
+
+		usages := make([]x509.ExtKeyUsage, 0, len(oids))
+
+		for _, oid := range oids {
+			usage, found := oidExtKeyUsages[oid.String()]
+			if !found {
+				return nil, errors.Wrap(pkgerrors.ErrPolicyViolation, "unsupported extended key usage "+oid.String())
+			}
+
+			if !slices.Contains(usages, usage) {
+				usages = append(usages, usage)
+			}
+		}
+
+		return usages, nil
+	}
+
+	return nil, nil
+}
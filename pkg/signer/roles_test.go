@@ -0,0 +1,139 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+func TestDetect(t *testing.T) {
+	roles := &Roles{
+		ControlPlaneOrganizations: []string{"os:controlplane"},
+		ControlPlaneCommonName:    regexp.MustCompile(`^cp-\d+$`),
+	}
+
+	tests := []struct {
+		name     string
+		roles    *Roles
+		subject  pkix.Name
+		expected Role
+	}{
+		{name: "control-plane organization", roles: roles, subject: pkix.Name{CommonName: "node-1", Organization: []string{"os:controlplane"}}, expected: RoleControlPlane},
+		{name: "control-plane Common Name", roles: roles, subject: pkix.Name{CommonName: "cp-1"}, expected: RoleControlPlane},
+		{name: "worker", roles: roles, subject: pkix.Name{CommonName: "cp-1a", Organization: []string{"os:worker"}}, expected: RoleWorker},
+		{name: "no roles", subject: pkix.Name{CommonName: "cp-1", Organization: []string{"os:controlplane"}}, expected: RoleWorker},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if role := tt.roles.Detect(&x509.CertificateRequest{Subject: tt.subject}); role != tt.expected {
+				t.Fatalf("expected the role %s, got %s", tt.expected, role)
+			}
+		})
+	}
+}
+
+func TestRolesProfile(t *testing.T) {
+	roles := &Roles{
+		ControlPlane: Profile{Validity: time.Hour},
+		Worker:       Profile{Validity: 2 * time.Hour},
+	}
+
+	if profile := roles.Profile(RoleControlPlane); profile.Validity != time.Hour {
+		t.Fatalf("expected the control-plane profile, got %+v", profile)
+	}
+
+	if profile := roles.Profile(RoleWorker); profile.Validity != 2*time.Hour {
+		t.Fatalf("expected the worker profile, got %+v", profile)
+	}
+
+	if profile := (*Roles)(nil).Profile(RoleControlPlane); profile.Validity != DefaultProfile.Validity {
+		t.Fatalf("expected the default profile without roles, got %+v", profile)
+	}
+}
+
+func TestExtKeyUsages(t *testing.T) {
+	usages, err := ExtKeyUsages([]string{"server", "Client"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(usages, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}) {
+		t.Fatalf("unexpected extended key usages %v", usages)
+	}
+
+	for _, names := range [][]string{nil, {"server", "code-signing"}} {
+		if _, err = ExtKeyUsages(names); !errors.Is(err, pkgerrors.ErrProfile) {
+			t.Fatalf("expected the extended key usages %v to be rejected, got %v", names, err)
+		}
+	}
+}
+
+func TestKeyAlgorithms(t *testing.T) {
+	algorithms, err := KeyAlgorithms([]string{"Ed25519", "ecdsa", "ecdsa-p384"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(algorithms, []string{"ed25519", "ecdsa", "ecdsa-p384"}) {
+		t.Fatalf("unexpected key algorithms %v", algorithms)
+	}
+
+	if _, err = KeyAlgorithms([]string{"ed25519", "dsa"}); !errors.Is(err, pkgerrors.ErrProfile) {
+		t.Fatalf("expected the unknown key algorithm to be rejected, got %v", err)
+	}
+}
+
+func TestAllowsKey(t *testing.T) {
+	ed25519Key, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		algorithms []string
+		key        crypto.PublicKey
+		allowed    bool
+	}{
+		{name: "any key", key: rsaKey.Public(), allowed: true},
+		{name: "required algorithm", algorithms: []string{"ed25519"}, key: ed25519Key, allowed: true},
+		{name: "any curve", algorithms: []string{"ecdsa"}, key: p384Key.Public(), allowed: true},
+		{name: "required curve", algorithms: []string{"ecdsa-p384"}, key: p384Key.Public(), allowed: true},
+		{name: "other curve", algorithms: []string{"ecdsa-p256"}, key: p384Key.Public()},
+		{name: "other algorithm", algorithms: []string{"ed25519", "ecdsa"}, key: rsaKey.Public()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if allowed := (Profile{KeyAlgorithms: tt.algorithms}).AllowsKey(tt.key); allowed != tt.allowed {
+				t.Fatalf("expected allowed %t, got %t", tt.allowed, allowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package signer
+
+import (
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// SANs are the Subject Alternative Names added to the certificates beyond the ones of the CSRs, such as the virtual
+// IP of the control plane.
+type SANs struct {
+	// DNSNames are the DNS names added to the certificates.
+	DNSNames []string
+	// IPAddresses are the IP addresses added to the certificates.
+	IPAddresses []net.IP
+}
+
+// ParseSANs returns the SANs of the list, the IP addresses and the DNS names.
+func ParseSANs(values []string) (SANs, error) {
+	var sans SANs
+
+	for _, value := range values {
+		if ip := net.ParseIP(value); ip != nil {
+			sans.IPAddresses = append(sans.IPAddresses, ip)
+
+			continue
+		}
+
+		if value == "" || strings.ContainsAny(value, " /:@") {
+			return SANs{}, errors.Wrap(pkgerrors.ErrProfile, "invalid SAN "+value+", expected a DNS name or an IP address")
+		}
+
+		sans.DNSNames = append(sans.DNSNames, strings.ToLower(value))
+	}
+
+	return sans, nil
+}
+
+// add returns the DNS names and the IP addresses along with the SANs missing from them.
+func (s SANs) add(dnsNames []string, ipAddresses []net.IP) ([]string, []net.IP) {
+	for _, name := range s.DNSNames {
+		if !slices.ContainsFunc(dnsNames, func(dnsName string) bool { return strings.EqualFold(dnsName, name) }) {
+			dnsNames = append(slices.Clip(dnsNames), name)
+		}
+	}
+
+	for _, ip := range s.IPAddresses {
+		if !slices.ContainsFunc(ipAddresses, ip.Equal) {
+			ipAddresses = append(slices.Clip(ipAddresses), ip)
+		}
+	}
+
+	return dnsNames, ipAddresses
+}
@@ -0,0 +1,357 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package signer builds the certificates issued for the Talos CSRs and signs them with a Backend,
+// shared by the gRPC server and the CLI tools.
+package signer
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+)
+
+// Profile describes the certificate issued for a CSR.
+type Profile struct {
+	// Validity is the duration the certificate is valid for, from its issuance.
+	Validity time.Duration
+	// KeyUsage is the key usage of the certificate.
+	KeyUsage x509.KeyUsage
+	// ExtKeyUsage is the extended key usage of the certificate.
+	ExtKeyUsage []x509.ExtKeyUsage
+	// URIs are the URI SANs added to the certificate, such as the node UUID.
+	URIs []*url.URL
+	// ExtraExtensions are the extensions added to the certificate.
+	ExtraExtensions []pkix.Extension
+	// KeyAlgorithms are the CSR key algorithms the profile requires, as named by pki.KeyAlgorithm, ecdsa
+	// standing for any curve: empty allows any key.
+	KeyAlgorithms []string
+	// Organizations are the only CSR subject organizations kept in the certificate, the other ones being stripped:
+	// empty copies them verbatim.
+	Organizations []string
+	// CommonName is the pattern the CSR Common Name must match to be issued the profile: nil allows any.
+	CommonName *regexp.Regexp
+	// Subject rewrites the CSR subject copied into the certificate.
+	Subject SubjectRules
+	// StripWildcards leaves the wildcard DNS names of the CSR out of the certificate.
+	StripWildcards bool
+	// StripLocalIPs leaves the loopback and link-local IP addresses of the CSR out of the certificate.
+	StripLocalIPs bool
+	// PassEmailAddresses copies the CSR email addresses into the certificate, left out otherwise.
+	PassEmailAddresses bool
+	// PassURIs copies the CSR URIs into the certificate, along with the profile ones, left out otherwise.
+	PassURIs bool
+	// ExtraSANs are added to the certificate beyond the CSR SANs.
+	ExtraSANs SANs
+	// NodeSANs are added to the certificates of the CSR Common Names they are keyed by, such as the external hostnames
+	// of the nodes.
+	NodeSANs map[string]SANs
+	// CA issues subordinate CA certificates, with a path length of 0, to the CSRs requesting CA:TRUE.
+	CA bool
+	// SPIFFEID derives the SPIFFE ID of the certificate from the CSR, its single URI SAN making it an X.509 SVID: nil
+	// issues none.
+	SPIFFEID *template.Template
+	// NodeUUID is the node UUID of the certificate, seen by the SPIFFE ID template.
+	NodeUUID string
+	// RequestableExtKeyUsages are the extended key usages the CSR may request, issued in place of the profile ones
+	// when all the requested ones are among them: empty ignores the requested ones.
+	RequestableExtKeyUsages []x509.ExtKeyUsage
+}
+
+// AllowsKey returns true when the algorithm of the CSR public key is required by the profile.
+func (p Profile) AllowsKey(publicKey any) bool {
+	if len(p.KeyAlgorithms) == 0 {
+		return true
+	}
+
+	algorithm := pki.KeyAlgorithm(publicKey)
+
+	for _, allowed := range p.KeyAlgorithms {
+		if allowed == algorithm || (allowed == "ecdsa" && strings.HasPrefix(algorithm, "ecdsa-")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowsSubject returns true when the CSR subject matches the Common Name pattern of the profile.
+func (p Profile) AllowsSubject(subject pkix.Name) bool {
+	return p.CommonName == nil || p.CommonName.MatchString(subject.CommonName)
+}
+
+// Rewrites returns the settings of the profile issuing the certificates out of the CSR subject and SANs, which the
+// backends signing the CSRs verbatim, such as Vault and the upstream signer, cannot honor.
+func (p Profile) Rewrites() []string {
+	var rewrites []string
+
+	if len(p.Organizations) > 0 {
+		rewrites = append(rewrites, "organizations")
+	}
+
+	if !p.Subject.Empty() {
+		rewrites = append(rewrites, "subject")
+	}
+
+	if p.StripWildcards {
+		rewrites = append(rewrites, "wildcard DNS names")
+	}
+
+	if p.StripLocalIPs {
+		rewrites = append(rewrites, "local IP addresses")
+	}
+
+	if len(p.ExtraSANs.DNSNames) > 0 || len(p.ExtraSANs.IPAddresses) > 0 || len(p.NodeSANs) > 0 {
+		rewrites = append(rewrites, "extra SANs")
+	}
+
+	if p.SPIFFEID != nil {
+		rewrites = append(rewrites, "SPIFFE ID")
+	}
+
+	if len(p.ExtraExtensions) > 0 {
+		rewrites = append(rewrites, "extensions")
+	}
+
+	return rewrites
+}
+
+// DefaultProfile is the server certificate issued to the Talos nodes, valid for one year.
+var DefaultProfile = Profile{
+	Validity:    365 * 24 * time.Hour,
+	KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+}
+
+const (
+	// CAExpiryTruncate issues the certificates which would outlive the CA until its expiration.
+	CAExpiryTruncate = "truncate"
+	// CAExpiryReject refuses to issue the certificates which would outlive the CA.
+	CAExpiryReject = "reject"
+)
+
+// Options configures the Signer.
+type Options struct {
+	// Backend signs the certificates with the Talos Machine CA.
+	Backend backend.Backend
+	// CAExpiry is the action taken when the certificate would outlive the CA: empty truncates it.
+	CAExpiry string
+	// SerialNumber returns the serial number of the next certificate: nil generates random ones.
+	SerialNumber func(ctx context.Context) (*big.Int, error)
+	// ReleaseSerialNumber releases the serial number returned by SerialNumber when it has not been issued: nil ignores it.
+	ReleaseSerialNumber func(ctx context.Context, serialNumber *big.Int)
+	// Now returns the issuance time: nil uses the system clock.
+	Now func() time.Time
+}
+
+// Issued is a certificate signed by the Signer.
+type Issued struct {
+	// Certificate is the signed certificate.
+	Certificate *x509.Certificate
+	// CA is the PEM encoded CA certificate the issued certificate chains to.
+	CA []byte
+	// Backend is the name of the backend which signed the certificate.
+	Backend string
+}
+
+// Signer issues the certificates for the validated CSRs.
+type Signer struct {
+	opts Options
+}
+
+// New returns the Signer with the given options.
+func New(opts Options) *Signer {
+	if opts.SerialNumber == nil {
+		opts.SerialNumber = func(context.Context) (*big.Int, error) { return pki.SerialNumber() }
+	}
+
+	if opts.ReleaseSerialNumber == nil {
+		opts.ReleaseSerialNumber = func(context.Context, *big.Int) {}
+	}
+
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	return &Signer{opts: opts}
+}
+
+// Sign issues the certificate for the CSR with the profile, returning it along with the PEM encoded CA it chains to.
+func (s *Signer) Sign(ctx context.Context, csr *x509.CertificateRequest, profile Profile) (*x509.Certificate, []byte, error) {
+	issued, err := s.Issue(ctx, csr, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return issued.Certificate, issued.CA, nil
+}
+
+// Issue issues the certificate for the CSR with the profile, reporting the backend which signed it, as built by
+// Template: the CSR must have been validated beforehand.
+func (s *Signer) Issue(ctx context.Context, csr *x509.CertificateRequest, profile Profile) (*Issued, error) {
+	template, err := s.Template(csr, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if template.SerialNumber, err = s.opts.SerialNumber(ctx); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrSerialNumber, err.Error())
+	}
+
+	signed, err := s.opts.Backend.Sign(backend.NewCSRContext(ctx, csr), template, csr.PublicKey)
+	if err != nil {
+		s.opts.ReleaseSerialNumber(ctx, template.SerialNumber)
+
+		return nil, err //nolint:wrapcheck
+	}
+
+	cert, err := x509.ParseCertificate(signed.Certificate)
+	if err != nil {
+		s.opts.ReleaseSerialNumber(ctx, template.SerialNumber)
+
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, err.Error())
+	}
+
+	// The backends signing the CSRs themselves, such as Vault, issue their own serial numbers
+	if cert.SerialNumber.Cmp(template.SerialNumber) != 0 {
+		s.opts.ReleaseSerialNumber(ctx, template.SerialNumber)
+	}
+
+	// The backends signing the CSRs themselves, such as Vault, do not issue the CA certificates of the template
+	if profile.CA && (!cert.IsCA || cert.MaxPathLen != 0) {
+		return nil, errors.Wrap(pkgerrors.ErrBackendSign, "the backend "+signed.Backend+" did not issue a CA certificate with a path length of 0")
+	}
+
+	// Nor the rewrites of the profile, which the certificates issued verbatim would bypass
+	if err = verifyIssued(cert, template); err != nil {
+		return nil, errors.Wrap(err, "backend "+signed.Backend)
+	}
+
+	return &Issued{
+		Certificate: cert,
+		CA:          signed.CA,
+		Backend:     signed.Backend,
+	}, nil
+}
+
+// Template returns the certificate the CSR would be issued with the profile, but for its serial number, without
+// signing it. The CSR subject, DNS names, and IP addresses are copied verbatim, but for the organizations left out of
+// the profile, the subject rewritten by its rules, and the wildcard DNS names and local IP addresses it strips, the
+// email addresses and URIs only when passed by the profile, along with its extra SANs.
+func (s *Signer) Template(csr *x509.CertificateRequest, profile Profile) (*x509.Certificate, error) {
+	now := s.opts.Now()
+	notAfter := now.Add(profile.Validity)
+
+	// The certificates outliving the CA would fail to verify once it expired
+	if caNotAfter := s.opts.Backend.Certificate().NotAfter; notAfter.After(caNotAfter) {
+		if s.opts.CAExpiry == CAExpiryReject || !caNotAfter.After(now) {
+			return nil, errors.Wrapf(pkgerrors.ErrOutlivesCA, "valid until %s, the CA expiring at %s",
+				notAfter.Format(time.RFC3339), caNotAfter.Format(time.RFC3339))
+		}
+
+		notAfter = caNotAfter
+	}
+
+	subject := csr.Subject
+	if len(profile.Organizations) > 0 {
+		subject.Organization = slices.DeleteFunc(slices.Clone(subject.Organization), func(organization string) bool {
+			return !slices.Contains(profile.Organizations, organization)
+		})
+	}
+
+	subject, err := profile.Subject.apply(csr, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		Subject:               subject,
+		NotBefore:             now,
+		NotAfter:              notAfter,
+		KeyUsage:              profile.KeyUsage,
+		ExtKeyUsage:           profile.ExtKeyUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  profile.CA,
+		MaxPathLenZero:        profile.CA,
+		DNSNames:              csr.DNSNames,
+		IPAddresses:           csr.IPAddresses,
+		URIs:                  profile.URIs,
+		ExtraExtensions:       profile.ExtraExtensions,
+	}
+
+	// The usages requested by the CSR replace the profile ones, as long as the policy allows them all
+	if len(profile.RequestableExtKeyUsages) > 0 {
+		requested, err := RequestedExtKeyUsages(csr)
+		if err == nil && len(requested) > 0 && !slices.ContainsFunc(requested, func(usage x509.ExtKeyUsage) bool {
+			return !slices.Contains(profile.RequestableExtKeyUsages, usage)
+		}) {
+			template.ExtKeyUsage = requested
+		}
+	}
+
+	if profile.StripWildcards {
+		template.DNSNames = slices.DeleteFunc(slices.Clone(csr.DNSNames), func(name string) bool {
+			return strings.Contains(name, "*")
+		})
+	}
+
+	if profile.StripLocalIPs {
+		template.IPAddresses = slices.DeleteFunc(slices.Clone(csr.IPAddresses), func(ip net.IP) bool {
+			return ip.IsLoopback() || ip.IsLinkLocalUnicast()
+		})
+	}
+
+	template.DNSNames, template.IPAddresses = profile.ExtraSANs.add(template.DNSNames, template.IPAddresses)
+	template.DNSNames, template.IPAddresses = profile.NodeSANs[csr.Subject.CommonName].add(template.DNSNames, template.IPAddresses)
+
+	if profile.PassEmailAddresses {
+		template.EmailAddresses = csr.EmailAddresses
+	}
+
+	if profile.PassURIs {
+		template.URIs = slices.Clone(profile.URIs)
+
+		for _, uri := range csr.URIs {
+			// The node UUID of the profile may be the one the CSR holds already
+			if !slices.ContainsFunc(template.URIs, func(profileURI *url.URL) bool { return profileURI.String() == uri.String() }) {
+				template.URIs = append(template.URIs, uri)
+			}
+		}
+	}
+
+	// The X.509 SVIDs hold the SPIFFE ID as their single URI SAN
+	if profile.SPIFFEID != nil {
+		if len(template.URIs) > 0 {
+			return nil, errors.Wrap(pkgerrors.ErrSPIFFEID, "the SPIFFE ID must be the single URI SAN of the certificate, "+
+				"which holds "+template.URIs[0].String())
+		}
+
+		spiffeID, err := profile.spiffeID(csr)
+		if err != nil {
+			return nil, err
+		}
+
+		template.URIs = []*url.URL{spiffeID}
+	}
+
+	// The certificates violating the name constraints of the CA would fail the path validation of the clients
+	if err := checkNameConstraints(s.opts.Backend.Certificate(), template); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
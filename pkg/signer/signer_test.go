@@ -0,0 +1,1238 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package signer
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+)
+
+var now = time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+// newSigner returns the Signer of a new CA valid for the duration from now, taking the CA expiry action, and
+// permitting the DNS domains if any.
+func newSigner(t *testing.T, validity time.Duration, caExpiry string, permittedDNSDomains ...string) *Signer {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template, err := pki.CATemplate(pkix.Name{CommonName: "talos"}, validity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template.NotBefore, template.NotAfter = now.Add(-time.Hour), now.Add(validity)
+	template.PermittedDNSDomains = permittedDNSDomains
+
+	cert, err := pki.Sign(template, key.Public(), template, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local, err := backend.NewLocal("local", pki.EncodeCertificates(cert), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return New(Options{Backend: local, CAExpiry: caExpiry, Now: func() time.Time { return now }})
+}
+
+// newCSR returns the CSR of a new key for the template.
+func newCSR(t *testing.T, template *x509.CertificateRequest) *x509.CertificateRequest {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return csr
+}
+
+// failingBackend fails to sign the certificates.
+type failingBackend struct {
+	backend.Backend
+}
+
+func (failingBackend) Sign(context.Context, *x509.Certificate, any) (*backend.Result, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestIssue(t *testing.T) {
+	csr := newCSR(t, &x509.CertificateRequest{
+		Subject:     pkix.Name{CommonName: "worker-1", Organization: []string{"os:server"}},
+		DNSNames:    []string{"worker-1"},
+		IPAddresses: []net.IP{net.ParseIP("10.0.0.1")},
+	})
+
+	signer := newSigner(t, 10*365*24*time.Hour, "")
+	signer.opts.SerialNumber = func(context.Context) (*big.Int, error) { return big.NewInt(42), nil }
+
+	var released []*big.Int
+
+	signer.opts.ReleaseSerialNumber = func(_ context.Context, serialNumber *big.Int) { released = append(released, serialNumber) }
+
+	issued, err := signer.Issue(t.Context(), csr, DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert := issued.Certificate
+
+	if cert.SerialNumber.Int64() != 42 || cert.Subject.CommonName != "worker-1" || !slices.Equal(cert.Subject.Organization, []string{"os:server"}) {
+		t.Fatalf("unexpected certificate %s with serial %s", cert.Subject, cert.SerialNumber)
+	}
+
+	if !slices.Equal(cert.DNSNames, csr.DNSNames) || len(cert.IPAddresses) != 1 || !cert.IPAddresses[0].Equal(csr.IPAddresses[0]) {
+		t.Fatalf("unexpected SANs %v and %v", cert.DNSNames, cert.IPAddresses)
+	}
+
+	if !cert.NotBefore.Equal(now) || !cert.NotAfter.Equal(now.Add(DefaultProfile.Validity)) {
+		t.Fatalf("unexpected validity from %s to %s", cert.NotBefore, cert.NotAfter)
+	}
+
+	if cert.KeyUsage != DefaultProfile.KeyUsage || !slices.Equal(cert.ExtKeyUsage, DefaultProfile.ExtKeyUsage) {
+		t.Fatalf("unexpected usages %v and %v", cert.KeyUsage, cert.ExtKeyUsage)
+	}
+
+	if issued.Backend != "local" || !bytes.Equal(issued.CA, pki.EncodeCertificates(signer.opts.Backend.Certificate())) {
+		t.Fatalf("unexpected backend %s", issued.Backend)
+	}
+
+	if err = cert.CheckSignatureFrom(signer.opts.Backend.Certificate()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(released) != 0 {
+		t.Fatalf("expected the issued serial number to be kept, got %v released", released)
+	}
+
+	// The serial number of the certificate the backend failed to sign is released, to be reserved again
+	signer.opts.Backend = failingBackend{Backend: signer.opts.Backend}
+
+	if _, err = signer.Issue(t.Context(), csr, DefaultProfile); err == nil {
+		t.Fatal("expected the backend failure to be reported")
+	}
+
+	if len(released) != 1 || released[0].Int64() != 42 {
+		t.Fatalf("expected the serial number 42 to be released, got %v", released)
+	}
+
+	signer.opts.SerialNumber = func(context.Context) (*big.Int, error) { return nil, errors.New("exhausted") }
+
+	if _, _, err = signer.Sign(t.Context(), csr, DefaultProfile); !errors.Is(err, pkgerrors.ErrSerialNumber) {
+		t.Fatalf("expected the serial number failure to be reported, got %v", err)
+	}
+}
+
+func TestIssueCAExpiry(t *testing.T) {
+	tests := []struct {
+		name       string
+		caValidity time.Duration
+		caExpiry   string
+		notAfter   time.Time
+		expected   error
+	}{
+		{name: "within the CA validity", caValidity: 2 * 365 * 24 * time.Hour, caExpiry: CAExpiryReject, notAfter: now.Add(DefaultProfile.Validity)},
+		{name: "truncated", caValidity: 30 * 24 * time.Hour, notAfter: now.Add(30 * 24 * time.Hour)},
+		{name: "truncated explicitly", caValidity: 30 * 24 * time.Hour, caExpiry: CAExpiryTruncate, notAfter: now.Add(30 * 24 * time.Hour)},
+		{name: "rejected", caValidity: 30 * 24 * time.Hour, caExpiry: CAExpiryReject, expected: pkgerrors.ErrOutlivesCA},
+		{name: "expired CA", caValidity: -time.Minute, expected: pkgerrors.ErrOutlivesCA},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csr := newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}})
+
+			cert, _, err := newSigner(t, tt.caValidity, tt.caExpiry).Sign(t.Context(), csr, DefaultProfile)
+
+			if tt.expected != nil {
+				if !errors.Is(err, tt.expected) {
+					t.Fatalf("expected %v, got %v", tt.expected, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cert.NotAfter.Equal(tt.notAfter) {
+				t.Fatalf("expected the certificate to expire at %s, got %s", tt.notAfter, cert.NotAfter)
+			}
+		})
+	}
+}
+
+// mustCommonNameTemplate returns the Common Name template of the text.
+func mustCommonNameTemplate(t *testing.T, text string) *template.Template {
+	t.Helper()
+
+	tmpl, err := CommonNameTemplate(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tmpl
+}
+
+func TestIssueProfile(t *testing.T) {
+	spiffeID, _ := url.Parse("spiffe://example.com/ns/kube-system/sa/node")
+	nodeURI, _ := url.Parse("urn:uuid:4c4c4544-0042-3510-8051-b2c04f4e3232")
+
+	tests := []struct {
+		name     string
+		csr      *x509.CertificateRequest
+		profile  func(Profile) Profile
+		expected pkix.Name
+		dnsNames []string
+		emails   []string
+		uris     []string
+		err      error
+	}{
+		{
+			name:     "subject copied verbatim",
+			csr:      &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1", Organization: []string{"os:reader", "os:admin"}}},
+			expected: pkix.Name{CommonName: "worker-1", Organization: []string{"os:reader", "os:admin"}},
+		},
+		{
+			name: "organizations stripped",
+			csr:  &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1", Organization: []string{"os:reader", "os:admin"}}},
+			profile: func(p Profile) Profile {
+				p.Organizations = []string{"os:reader"}
+
+				return p
+			},
+			expected: pkix.Name{CommonName: "worker-1", Organization: []string{"os:reader"}},
+		},
+		{
+			name: "subject rewritten",
+			csr: &x509.CertificateRequest{Subject: pkix.Name{
+				CommonName:         "worker-1",
+				Organization:       []string{"os:reader"},
+				OrganizationalUnit: []string{"nodes"},
+				Country:            []string{"IT"},
+				Locality:           []string{"Milan"},
+			}},
+			profile: func(p Profile) Profile {
+				p.Subject = SubjectRules{
+					Organizations: []string{"system:nodes"},
+					Drop:          []string{SubjectOrganizationalUnit, SubjectCountry},
+					CommonName:    mustCommonNameTemplate(t, "system:node:{{.CommonName}}"),
+				}
+
+				return p
+			},
+			expected: pkix.Name{CommonName: "system:node:worker-1", Organization: []string{"system:nodes"}, Locality: []string{"Milan"}},
+		},
+		{
+			name: "Common Name of the SANs",
+			csr:  &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, DNSNames: []string{"worker-1.example.com"}},
+			profile: func(p Profile) Profile {
+				p.Subject.CommonName = mustCommonNameTemplate(t, "{{index .DNSNames 0}}")
+
+				return p
+			},
+			expected: pkix.Name{CommonName: "worker-1.example.com"},
+			dnsNames: []string{"worker-1.example.com"},
+		},
+		{
+			name:     "email addresses and URIs dropped",
+			csr:      &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, EmailAddresses: []string{"node@example.com"}, URIs: []*url.URL{spiffeID}},
+			expected: pkix.Name{CommonName: "worker-1"},
+		},
+		{
+			name: "email addresses and URIs passed",
+			csr:  &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, EmailAddresses: []string{"node@example.com"}, URIs: []*url.URL{spiffeID, nodeURI}},
+			profile: func(p Profile) Profile {
+				p.PassEmailAddresses, p.PassURIs = true, true
+				p.URIs = []*url.URL{nodeURI}
+
+				return p
+			},
+			expected: pkix.Name{CommonName: "worker-1"},
+			emails:   []string{"node@example.com"},
+			uris:     []string{nodeURI.String(), spiffeID.String()},
+		},
+		{
+			name:     "wildcard DNS names copied",
+			csr:      &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, DNSNames: []string{"worker-1.example.com", "*.example.com"}},
+			expected: pkix.Name{CommonName: "worker-1"},
+			dnsNames: []string{"worker-1.example.com", "*.example.com"},
+		},
+		{
+			name: "wildcard DNS names stripped",
+			csr:  &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, DNSNames: []string{"worker-1.example.com", "*.example.com"}},
+			profile: func(p Profile) Profile {
+				p.StripWildcards = true
+
+				return p
+			},
+			expected: pkix.Name{CommonName: "worker-1"},
+			dnsNames: []string{"worker-1.example.com"},
+		},
+		{
+			name: "empty Common Name",
+			csr:  &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}},
+			profile: func(p Profile) Profile {
+				p.Subject.CommonName = mustCommonNameTemplate(t, "{{range .DNSNames}}{{.}}{{end}}")
+
+				return p
+			},
+			err: pkgerrors.ErrSubject,
+		},
+		{
+			name: "Common Name template failing",
+			csr:  &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}},
+			profile: func(p Profile) Profile {
+				p.Subject.CommonName = mustCommonNameTemplate(t, "{{index .DNSNames 0}}")
+
+				return p
+			},
+			err: pkgerrors.ErrSubject,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := DefaultProfile
+			if tt.profile != nil {
+				profile = tt.profile(profile)
+			}
+
+			csr := newCSR(t, tt.csr)
+			organizations := slices.Clone(csr.Subject.Organization)
+
+			cert, _, err := newSigner(t, 10*365*24*time.Hour, "").Sign(t.Context(), csr, profile)
+
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cert.Subject.CommonName != tt.expected.CommonName ||
+				!slices.Equal(slices.Sorted(slices.Values(cert.Subject.Organization)), slices.Sorted(slices.Values(tt.expected.Organization))) ||
+				!slices.Equal(cert.Subject.OrganizationalUnit, tt.expected.OrganizationalUnit) ||
+				!slices.Equal(cert.Subject.Country, tt.expected.Country) ||
+				!slices.Equal(cert.Subject.Locality, tt.expected.Locality) {
+				t.Fatalf("expected the subject %s, got %s", tt.expected, cert.Subject)
+			}
+
+			uris := make([]string, 0, len(cert.URIs))
+			for _, uri := range cert.URIs {
+				uris = append(uris, uri.String())
+			}
+
+			if !slices.Equal(cert.DNSNames, tt.dnsNames) {
+				t.Fatalf("expected the DNS names %v, got %v", tt.dnsNames, cert.DNSNames)
+			}
+
+			if !slices.Equal(cert.EmailAddresses, tt.emails) || !slices.Equal(uris, tt.uris) {
+				t.Fatalf("expected the email addresses %v and URIs %v, got %v and %v", tt.emails, tt.uris, cert.EmailAddresses, uris)
+			}
+
+			if !slices.Equal(csr.Subject.Organization, organizations) {
+				t.Fatalf("expected the CSR to be left untouched, got %s", csr.Subject)
+			}
+		})
+	}
+}
+
+func TestSubjectFields(t *testing.T) {
+	fields, err := SubjectFields([]string{"Country", "organizational-unit"})
+	if err != nil || !slices.Equal(fields, []string{SubjectCountry, SubjectOrganizationalUnit}) {
+		t.Fatalf("unexpected fields %v, %v", fields, err)
+	}
+
+	if _, err = SubjectFields([]string{"common-name"}); !errors.Is(err, pkgerrors.ErrProfile) {
+		t.Fatalf("expected the Common Name not to be dropped, got %v", err)
+	}
+
+	if _, err = CommonNameTemplate("{{.CommonName"); !errors.Is(err, pkgerrors.ErrProfile) {
+		t.Fatalf("expected the invalid template to be refused, got %v", err)
+	}
+}
+
+func TestIssueNameConstraints(t *testing.T) {
+	signer := newSigner(t, 10*365*24*time.Hour, "", "nodes.example.com")
+
+	allowed := newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, DNSNames: []string{"worker-1.nodes.example.com"}})
+	if _, _, err := signer.Sign(t.Context(), allowed, DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+
+	violating := newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, DNSNames: []string{"worker-1.example.com"}})
+	if _, _, err := signer.Sign(t.Context(), violating, DefaultProfile); !errors.Is(err, pkgerrors.ErrNameConstraints) {
+		t.Fatalf("expected the name constraints to be violated, got %v", err)
+	}
+}
+
+func TestCheckNameConstraints(t *testing.T) {
+	_, nodes, _ := net.ParseCIDR("10.0.0.0/24")
+	_, excluded, _ := net.ParseCIDR("10.0.0.128/25")
+	spiffe, _ := url.Parse("spiffe://example.com/node")
+	other, _ := url.Parse("spiffe://example.org/node")
+	nodeURI, _ := url.Parse("urn:uuid:4c4c4544-0042-3510-8051-b2c04f4e3232")
+
+	ca := &x509.Certificate{
+		PermittedDNSDomains:     []string{"example.com"},
+		ExcludedDNSDomains:      []string{".internal.example.com"},
+		PermittedIPRanges:       []*net.IPNet{nodes},
+		ExcludedIPRanges:        []*net.IPNet{excluded},
+		PermittedEmailAddresses: []string{"example.com", "admin@example.org"},
+		PermittedURIDomains:     []string{"example.com"},
+	}
+
+	tests := []struct {
+		name     string
+		template *x509.Certificate
+		valid    bool
+	}{
+		{name: "no SANs", template: &x509.Certificate{}, valid: true},
+		{name: "permitted", template: &x509.Certificate{
+			DNSNames:       []string{"example.com", "worker-1.EXAMPLE.com"},
+			IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+			EmailAddresses: []string{"node@example.com", "admin@example.org"},
+			URIs:           []*url.URL{spiffe},
+		}, valid: true},
+		{name: "DNS name not permitted", template: &x509.Certificate{DNSNames: []string{"notexample.com"}}},
+		{name: "DNS name excluded", template: &x509.Certificate{DNSNames: []string{"worker-1.internal.example.com"}}},
+		{name: "IP address not permitted", template: &x509.Certificate{IPAddresses: []net.IP{net.ParseIP("10.0.1.1")}}},
+		{name: "IP address excluded", template: &x509.Certificate{IPAddresses: []net.IP{net.ParseIP("10.0.0.200")}}},
+		{name: "email address not permitted", template: &x509.Certificate{EmailAddresses: []string{"node@example.org"}}},
+		{name: "URI not permitted", template: &x509.Certificate{URIs: []*url.URL{other}}},
+		{name: "URI without host", template: &x509.Certificate{URIs: []*url.URL{nodeURI}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkNameConstraints(ca, tt.template)
+			if tt.valid != (err == nil) || (err != nil && !errors.Is(err, pkgerrors.ErrNameConstraints)) {
+				t.Fatalf("expected valid %t, got %v", tt.valid, err)
+			}
+		})
+	}
+
+	if err := checkNameConstraints(&x509.Certificate{}, &x509.Certificate{URIs: []*url.URL{nodeURI}}); err != nil {
+		t.Fatalf("expected the URIs to be allowed without constraints, got %v", err)
+	}
+}
+
+func TestExtensions(t *testing.T) {
+	tests := []struct {
+		name     string
+		specs    []string
+		expected []pkix.Extension
+		valid    bool
+	}{
+		{name: "none", valid: true, expected: []pkix.Extension{}},
+		{
+			name:  "typed values",
+			specs: []string{"1.3.6.1.4.1.99999.1=utf8:asset-tag", "critical:1.3.6.1.4.1.99999.2=ia5:rack-1", "1.3.6.1.4.1.99999.3=integer:42", "1.3.6.1.4.1.99999.4=der:0500"},
+			expected: []pkix.Extension{
+				{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}, Value: []byte{0x0c, 0x09, 'a', 's', 's', 'e', 't', '-', 't', 'a', 'g'}},
+				{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 2}, Critical: true, Value: []byte{0x16, 0x06, 'r', 'a', 'c', 'k', '-', '1'}},
+				{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 3}, Value: []byte{0x02, 0x01, 42}},
+				{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 4}, Value: []byte{0x05, 0x00}},
+			},
+			valid: true,
+		},
+		{name: "missing value", specs: []string{"1.3.6.1.4.1.99999.1"}},
+		{name: "missing type", specs: []string{"1.3.6.1.4.1.99999.1=asset-tag"}},
+		{name: "unsupported type", specs: []string{"1.3.6.1.4.1.99999.1=bool:true"}},
+		{name: "invalid OID", specs: []string{"1.3.a=utf8:asset-tag"}},
+		{name: "single arc OID", specs: []string{"1=utf8:asset-tag"}},
+		{name: "invalid integer", specs: []string{"1.3.6.1.4.1.99999.1=integer:forty-two"}},
+		{name: "invalid DER", specs: []string{"1.3.6.1.4.1.99999.1=der:0500ff"}},
+		{name: "reserved extension", specs: []string{"2.5.29.17=der:3000"}},
+		{name: "duplicated extension", specs: []string{"1.3.6.1.4.1.99999.1=utf8:a", "1.3.6.1.4.1.99999.1=utf8:b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extensions, err := Extensions(tt.specs)
+
+			if !tt.valid {
+				if !errors.Is(err, pkgerrors.ErrProfile) {
+					t.Fatalf("expected an invalid extension, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.EqualFunc(extensions, tt.expected, func(a, b pkix.Extension) bool {
+				return a.Id.Equal(b.Id) && a.Critical == b.Critical && bytes.Equal(a.Value, b.Value)
+			}) {
+				t.Fatalf("expected the extensions %v, got %v", tt.expected, extensions)
+			}
+		})
+	}
+
+	extensions, err := Extensions([]string{"critical:1.3.6.1.4.1.99999.1=utf8:asset-tag"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile := DefaultProfile
+	profile.ExtraExtensions = extensions
+
+	cert, _, err := newSigner(t, 10*365*24*time.Hour, "").Sign(t.Context(), newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}}), profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.ContainsFunc(cert.Extensions, func(ext pkix.Extension) bool { return ext.Id.Equal(extensions[0].Id) && ext.Critical }) {
+		t.Fatalf("expected the certificate to hold the extension, got %v", cert.Extensions)
+	}
+}
+
+func TestCertificatePolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		oids   []string
+		cpsURI string
+		valid  bool
+	}{
+		{name: "policies", oids: []string{"2.23.140.1.2.1", "1.3.6.1.4.1.99999.1"}, valid: true},
+		{name: "policies with CPS URI", oids: []string{"1.3.6.1.4.1.99999.1"}, cpsURI: "https://pki.example.com/cps", valid: true},
+		{name: "no policy", cpsURI: "https://pki.example.com/cps"},
+		{name: "invalid OID", oids: []string{"1.3.a"}},
+		{name: "invalid CPS URI", oids: []string{"1.3.6.1.4.1.99999.1"}, cpsURI: "ldap://pki.example.com/cps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extension, err := CertificatePolicies(tt.oids, tt.cpsURI)
+
+			if !tt.valid {
+				if !errors.Is(err, pkgerrors.ErrProfile) {
+					t.Fatalf("expected invalid certificate policies, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			profile := DefaultProfile
+			profile.ExtraExtensions = []pkix.Extension{extension}
+
+			cert, _, err := newSigner(t, 10*365*24*time.Hour, "").Sign(t.Context(), newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}}), profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			policies := make([]string, 0, len(cert.PolicyIdentifiers))
+			for _, policy := range cert.PolicyIdentifiers {
+				policies = append(policies, policy.String())
+			}
+
+			if !slices.Equal(policies, tt.oids) {
+				t.Fatalf("expected the policies %v, got %v", tt.oids, policies)
+			}
+
+			var information []policyInformation
+			if _, err = asn1.Unmarshal(extension.Value, &information); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, policy := range information {
+				if tt.cpsURI == "" && len(policy.Qualifiers) != 0 || tt.cpsURI != "" && policy.Qualifiers[0].Qualifier != tt.cpsURI {
+					t.Fatalf("unexpected qualifiers %+v", policy.Qualifiers)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSANs(t *testing.T) {
+	sans, err := ParseSANs([]string{"API.example.com", "10.0.0.1", "fd00::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(sans.DNSNames, []string{"api.example.com"}) || len(sans.IPAddresses) != 2 {
+		t.Fatalf("unexpected SANs %+v", sans)
+	}
+
+	for _, value := range []string{"", "api example.com", "https://api.example.com", "admin@example.com"} {
+		if _, err = ParseSANs([]string{value}); !errors.Is(err, pkgerrors.ErrProfile) {
+			t.Fatalf("expected the SAN %q to be rejected, got %v", value, err)
+		}
+	}
+}
+
+func TestIssueExtraSANs(t *testing.T) {
+	extraSANs, err := ParseSANs([]string{"api.example.com", "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodeSANs, err := ParseSANs([]string{"worker-1.example.net", "WORKER-1.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile := DefaultProfile
+	profile.ExtraSANs = extraSANs
+	profile.NodeSANs = map[string]SANs{"worker-1": nodeSANs}
+
+	signer := newSigner(t, 10*365*24*time.Hour, "")
+
+	tests := []struct {
+		commonName  string
+		dnsNames    []string
+		ipAddresses []string
+	}{
+		{commonName: "worker-1", dnsNames: []string{"worker-1.example.com", "API.example.com", "worker-1.example.net"}, ipAddresses: []string{"10.0.0.2", "10.0.0.1"}},
+		{commonName: "worker-2", dnsNames: []string{"worker-2.example.com", "API.example.com"}, ipAddresses: []string{"10.0.0.2", "10.0.0.1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.commonName, func(t *testing.T) {
+			csr := newCSR(t, &x509.CertificateRequest{
+				Subject:     pkix.Name{CommonName: tt.commonName},
+				DNSNames:    []string{tt.commonName + ".example.com", "API.example.com"},
+				IPAddresses: []net.IP{net.ParseIP("10.0.0.2")},
+			})
+
+			cert, _, err := signer.Sign(t.Context(), csr, profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ipAddresses := make([]string, 0, len(cert.IPAddresses))
+			for _, ip := range cert.IPAddresses {
+				ipAddresses = append(ipAddresses, ip.String())
+			}
+
+			if !slices.Equal(cert.DNSNames, tt.dnsNames) || !slices.Equal(ipAddresses, tt.ipAddresses) {
+				t.Fatalf("unexpected SANs %v and %v", cert.DNSNames, ipAddresses)
+			}
+		})
+	}
+}
+
+func TestIssueLocalIPs(t *testing.T) {
+	csr := newCSR(t, &x509.CertificateRequest{
+		Subject:     pkix.Name{CommonName: "worker-1"},
+		IPAddresses: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("127.0.0.1"), net.ParseIP("::1"), net.ParseIP("fe80::1"), net.ParseIP("169.254.0.1")},
+	})
+
+	signer := newSigner(t, 10*365*24*time.Hour, "")
+
+	for _, strip := range []bool{false, true} {
+		profile := DefaultProfile
+		profile.StripLocalIPs = strip
+
+		cert, _, err := signer.Sign(t.Context(), csr, profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := 5
+		if strip {
+			expected = 1
+		}
+
+		if len(cert.IPAddresses) != expected || !cert.IPAddresses[0].Equal(net.ParseIP("10.0.0.1")) {
+			t.Fatalf("expected %d IP addresses with strip %t, got %v", expected, strip, cert.IPAddresses)
+		}
+	}
+}
+
+func TestSPIFFEIDTemplate(t *testing.T) {
+	for text, valid := range map[string]bool{
+		"spiffe://example.com/talos/{{.CommonName}}":                     true,
+		"spiffe://example.com/talos/{{.NodeUUID}}/{{index .DNSNames 0}}": true,
+		"https://example.com/talos/{{.CommonName}}":                      false,
+		"spiffe://example.com/talos/{{.CommonName":                       false,
+	} {
+		if _, err := SPIFFEIDTemplate(text); (err == nil) != valid || (!valid && !errors.Is(err, pkgerrors.ErrProfile)) {
+			t.Fatalf("expected valid %t for %s, got %v", valid, text, err)
+		}
+	}
+}
+
+func TestParseSPIFFEID(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{id: "spiffe://example.com/talos/worker-1", valid: true},
+		{id: "spiffe://cluster.local/ns/kube-system/sa/talos_node.1", valid: true},
+		{id: "https://example.com/talos/worker-1"},
+		{id: "spiffe://Example.com/talos/worker-1"},
+		{id: "spiffe:///talos/worker-1"},
+		{id: "spiffe://example.com"},
+		{id: "spiffe://example.com/"},
+		{id: "spiffe://example.com/talos//worker-1"},
+		{id: "spiffe://example.com/talos/../worker-1"},
+		{id: "spiffe://example.com/talos/worker 1"},
+		{id: "spiffe://example.com/talos/worker-1?query"},
+		{id: "spiffe://example.com/" + strings.Repeat("a", 2048)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			id, err := parseSPIFFEID(tt.id)
+
+			if !tt.valid {
+				if !errors.Is(err, pkgerrors.ErrSPIFFEID) {
+					t.Fatalf("expected an invalid SPIFFE ID, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if id.String() != tt.id {
+				t.Fatalf("expected the SPIFFE ID %s, got %s", tt.id, id)
+			}
+		})
+	}
+}
+
+func TestIssueSPIFFEID(t *testing.T) {
+	spiffeID, err := SPIFFEIDTemplate("spiffe://example.com/talos/{{.NodeUUID}}/{{.CommonName}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodeURI, err := url.Parse("urn:uuid:7a3f2c4e-1b2d-4c5e-8f90-123456789abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		commonName string
+		uris       []*url.URL
+		expected   string
+	}{
+		{name: "SPIFFE ID", commonName: "worker-1", expected: "spiffe://example.com/talos/7a3f2c4e-1b2d-4c5e-8f90-123456789abc/worker-1"},
+		{name: "SPIFFE ID along with another URI", commonName: "worker-1", uris: []*url.URL{nodeURI}},
+		{name: "invalid SPIFFE ID", commonName: "worker 1"},
+	}
+
+	signer := newSigner(t, 10*365*24*time.Hour, "")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := DefaultProfile
+			profile.SPIFFEID = spiffeID
+			profile.NodeUUID = "7a3f2c4e-1b2d-4c5e-8f90-123456789abc"
+			profile.URIs = tt.uris
+
+			cert, _, err := signer.Sign(t.Context(), newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: tt.commonName}}), profile)
+
+			if tt.expected == "" {
+				if !errors.Is(err, pkgerrors.ErrSPIFFEID) {
+					t.Fatalf("expected the SPIFFE ID to be refused, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(cert.URIs) != 1 || cert.URIs[0].String() != tt.expected {
+				t.Fatalf("expected the single URI %s, got %v", tt.expected, cert.URIs)
+			}
+		})
+	}
+}
+
+// extKeyUsageExtension returns the extended key usage extension of the OIDs, as requested by a CSR.
+func extKeyUsageExtension(t *testing.T, oids ...asn1.ObjectIdentifier) pkix.Extension {
+	t.Helper()
+
+	value, err := asn1.Marshal(oids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pkix.Extension{Id: oidExtKeyUsage, Value: value}
+}
+
+func TestIssueExtKeyUsage(t *testing.T) {
+	signer := newSigner(t, 10*365*24*time.Hour, "")
+	serverAuth, clientAuth := asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 1}, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 2}
+	codeSigning := asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 3}
+
+	tests := []struct {
+		name        string
+		extensions  []pkix.Extension
+		requestable []x509.ExtKeyUsage
+		expected    []x509.ExtKeyUsage
+	}{
+		{
+			name:     "no request",
+			expected: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		},
+		{
+			name:       "request ignored by the profile",
+			extensions: []pkix.Extension{extKeyUsageExtension(t, clientAuth)},
+			expected:   []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		},
+		{
+			name:        "requestable usages",
+			extensions:  []pkix.Extension{extKeyUsageExtension(t, clientAuth, serverAuth, clientAuth)},
+			requestable: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			expected:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		},
+		{
+			name:        "usage not requestable",
+			extensions:  []pkix.Extension{extKeyUsageExtension(t, serverAuth, clientAuth)},
+			requestable: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			expected:    []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		},
+		{
+			name:        "unknown usage",
+			extensions:  []pkix.Extension{extKeyUsageExtension(t, clientAuth, codeSigning)},
+			requestable: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			expected:    []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		},
+		{
+			name:        "empty request",
+			extensions:  []pkix.Extension{extKeyUsageExtension(t)},
+			requestable: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			expected:    []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := DefaultProfile
+			profile.RequestableExtKeyUsages = tt.requestable
+
+			csr := newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, ExtraExtensions: tt.extensions})
+
+			cert, _, err := signer.Sign(t.Context(), csr, profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(cert.ExtKeyUsage, tt.expected) {
+				t.Fatalf("expected the extended key usages %v, got %v", tt.expected, cert.ExtKeyUsage)
+			}
+		})
+	}
+}
+
+func TestRequestedExtKeyUsages(t *testing.T) {
+	tests := []struct {
+		name       string
+		extensions []pkix.Extension
+		expected   []x509.ExtKeyUsage
+		err        error
+	}{
+		{name: "no extension"},
+		{
+			name:       "server and client",
+			extensions: []pkix.Extension{extKeyUsageExtension(t, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 1}, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 2})},
+			expected:   []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		},
+		{
+			name:       "unsupported usage",
+			extensions: []pkix.Extension{extKeyUsageExtension(t, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 3})},
+			err:        pkgerrors.ErrPolicyViolation,
+		},
+		{
+			name:       "malformed extension",
+			extensions: []pkix.Extension{{Id: oidExtKeyUsage, Value: []byte{0x30, 0x05}}},
+			err:        pkgerrors.ErrPolicyViolation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usages, err := RequestedExtKeyUsages(&x509.CertificateRequest{Extensions: tt.extensions})
+
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(usages, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, usages)
+			}
+		})
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	mustTemplate := func(text string, parse func(string) (*template.Template, error)) *template.Template {
+		tmpl, err := parse(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return tmpl
+	}
+
+	nodeUUID := &url.URL{Scheme: "urn", Opaque: "uuid:4d1c3b5c-0000-4000-8000-000000000001"}
+
+	tests := []struct {
+		name     string
+		signer   *Signer
+		csr      x509.CertificateRequest
+		profile  Profile
+		expected func(t *testing.T, template *x509.Certificate)
+		err      error
+	}{
+		{
+			name:    "default profile",
+			signer:  newSigner(t, 10*365*24*time.Hour, ""),
+			csr:     x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, DNSNames: []string{"worker-1"}, IPAddresses: []net.IP{net.ParseIP("10.0.0.1")}},
+			profile: DefaultProfile,
+			expected: func(t *testing.T, template *x509.Certificate) {
+				t.Helper()
+
+				if template.Subject.CommonName != "worker-1" || !template.NotAfter.Equal(now.Add(365*24*time.Hour)) ||
+					template.KeyUsage != x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment ||
+					!slices.Equal(template.ExtKeyUsage, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}) || template.IsCA {
+					t.Errorf("unexpected template %+v", template)
+				}
+			},
+		},
+		{
+			name:    "validity truncated to the CA expiration",
+			signer:  newSigner(t, 24*time.Hour, CAExpiryTruncate),
+			csr:     x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}},
+			profile: DefaultProfile,
+			expected: func(t *testing.T, template *x509.Certificate) {
+				t.Helper()
+
+				if !template.NotAfter.Equal(now.Add(24 * time.Hour)) {
+					t.Errorf("expected the validity to end with the CA, got %s", template.NotAfter)
+				}
+			},
+		},
+		{
+			name:    "validity outliving the CA rejected",
+			signer:  newSigner(t, 24*time.Hour, CAExpiryReject),
+			csr:     x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}},
+			profile: DefaultProfile,
+			err:     pkgerrors.ErrOutlivesCA,
+		},
+		{
+			name:   "organizations and subject rules",
+			signer: newSigner(t, 10*365*24*time.Hour, ""),
+			csr: x509.CertificateRequest{Subject: pkix.Name{
+				CommonName:         "worker-1",
+				Organization:       []string{"os:machine", "os:admin"},
+				OrganizationalUnit: []string{"nodes"},
+				Country:            []string{"IT"},
+			}},
+			profile: Profile{
+				Validity:      time.Hour,
+				Organizations: []string{"os:machine"},
+				Subject: SubjectRules{
+					Drop:       []string{SubjectCountry},
+					CommonName: mustTemplate("{{.CommonName}}.nodes", CommonNameTemplate),
+				},
+			},
+			expected: func(t *testing.T, template *x509.Certificate) {
+				t.Helper()
+
+				subject := template.Subject
+				if subject.CommonName != "worker-1.nodes" || !slices.Equal(subject.Organization, []string{"os:machine"}) ||
+					len(subject.Country) != 0 || !slices.Equal(subject.OrganizationalUnit, []string{"nodes"}) {
+					t.Errorf("unexpected subject %+v", subject)
+				}
+			},
+		},
+		{
+			name:   "SANs stripped and added",
+			signer: newSigner(t, 10*365*24*time.Hour, ""),
+			csr: x509.CertificateRequest{
+				Subject:        pkix.Name{CommonName: "worker-1"},
+				DNSNames:       []string{"worker-1", "*.example.com"},
+				IPAddresses:    []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("127.0.0.1"), net.ParseIP("fe80::1")},
+				EmailAddresses: []string{"admin@example.com"},
+				URIs:           []*url.URL{{Scheme: "https", Host: "example.com"}},
+			},
+			profile: Profile{
+				Validity:       time.Hour,
+				StripWildcards: true,
+				StripLocalIPs:  true,
+				ExtraSANs:      SANs{DNSNames: []string{"WORKER-1", "api.example.com"}, IPAddresses: []net.IP{net.ParseIP("10.0.0.100")}},
+				NodeSANs:       map[string]SANs{"worker-1": {DNSNames: []string{"worker-1.public.example.com"}}, "worker-2": {DNSNames: []string{"worker-2"}}},
+			},
+			expected: func(t *testing.T, template *x509.Certificate) {
+				t.Helper()
+
+				if !slices.Equal(template.DNSNames, []string{"worker-1", "api.example.com", "worker-1.public.example.com"}) {
+					t.Errorf("unexpected DNS names %v", template.DNSNames)
+				}
+
+				if len(template.IPAddresses) != 2 || !template.IPAddresses[1].Equal(net.ParseIP("10.0.0.100")) {
+					t.Errorf("unexpected IP addresses %v", template.IPAddresses)
+				}
+
+				if len(template.EmailAddresses) != 0 || len(template.URIs) != 0 {
+					t.Errorf("expected the email addresses and URIs to be left out, got %v and %v", template.EmailAddresses, template.URIs)
+				}
+			},
+		},
+		{
+			name:   "email addresses and URIs passed",
+			signer: newSigner(t, 10*365*24*time.Hour, ""),
+			csr: x509.CertificateRequest{
+				Subject:        pkix.Name{CommonName: "worker-1"},
+				EmailAddresses: []string{"admin@example.com"},
+				URIs:           []*url.URL{nodeUUID, {Scheme: "https", Host: "example.com"}},
+			},
+			profile: Profile{Validity: time.Hour, PassEmailAddresses: true, PassURIs: true, URIs: []*url.URL{nodeUUID}},
+			expected: func(t *testing.T, template *x509.Certificate) {
+				t.Helper()
+
+				if !slices.Equal(template.EmailAddresses, []string{"admin@example.com"}) || len(template.URIs) != 2 ||
+					template.URIs[0].String() != nodeUUID.String() || template.URIs[1].String() != "https://example.com" {
+					t.Errorf("unexpected email addresses %v and URIs %v", template.EmailAddresses, template.URIs)
+				}
+			},
+		},
+		{
+			name:    "SPIFFE ID",
+			signer:  newSigner(t, 10*365*24*time.Hour, ""),
+			csr:     x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}},
+			profile: Profile{Validity: time.Hour, SPIFFEID: mustTemplate("spiffe://example.com/talos/{{.CommonName}}", SPIFFEIDTemplate)},
+			expected: func(t *testing.T, template *x509.Certificate) {
+				t.Helper()
+
+				if len(template.URIs) != 1 || template.URIs[0].String() != "spiffe://example.com/talos/worker-1" {
+					t.Errorf("unexpected URIs %v", template.URIs)
+				}
+			},
+		},
+		{
+			name:    "SPIFFE ID along with another URI",
+			signer:  newSigner(t, 10*365*24*time.Hour, ""),
+			csr:     x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}},
+			profile: Profile{Validity: time.Hour, URIs: []*url.URL{nodeUUID}, SPIFFEID: mustTemplate("spiffe://example.com/talos/{{.CommonName}}", SPIFFEIDTemplate)},
+			err:     pkgerrors.ErrSPIFFEID,
+		},
+		{
+			name:    "invalid SPIFFE ID",
+			signer:  newSigner(t, 10*365*24*time.Hour, ""),
+			csr:     x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker 1"}},
+			profile: Profile{Validity: time.Hour, SPIFFEID: mustTemplate("spiffe://example.com/talos/{{.CommonName}}", SPIFFEIDTemplate)},
+			err:     pkgerrors.ErrSPIFFEID,
+		},
+		{
+			name:    "DNS name within the name constraints",
+			signer:  newSigner(t, 10*365*24*time.Hour, "", "example.com"),
+			csr:     x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, DNSNames: []string{"worker-1.example.com"}},
+			profile: DefaultProfile,
+		},
+		{
+			name:    "DNS name out of the name constraints",
+			signer:  newSigner(t, 10*365*24*time.Hour, "", "example.com"),
+			csr:     x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, DNSNames: []string{"worker-1.example.org"}},
+			profile: DefaultProfile,
+			err:     pkgerrors.ErrNameConstraints,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := tt.signer.Template(&tt.csr, tt.profile)
+
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.expected != nil {
+				tt.expected(t, template)
+			}
+		})
+	}
+}
+
+// verbatimBackend signs the CSR of the context verbatim, ignoring the subject and the SANs of the template, as Vault
+// and the upstream signer do.
+type verbatimBackend struct {
+	backend.Backend
+}
+
+func (b verbatimBackend) Sign(ctx context.Context, template *x509.Certificate, publicKey any) (*backend.Result, error) {
+	csr := backend.CSRFromContext(ctx)
+
+	verbatim := *template
+	verbatim.Subject, verbatim.DNSNames, verbatim.IPAddresses = csr.Subject, csr.DNSNames, csr.IPAddresses
+	verbatim.EmailAddresses, verbatim.URIs, verbatim.ExtraExtensions = csr.EmailAddresses, csr.URIs, nil
+
+	return b.Backend.Sign(ctx, &verbatim, publicKey)
+}
+
+func TestIssueVerbatim(t *testing.T) {
+	local := newSigner(t, 10*365*24*time.Hour, "")
+	signer := New(Options{Backend: verbatimBackend{local.opts.Backend}, Now: local.opts.Now})
+
+	csr := newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1", Organization: []string{"os:reader", "os:admin"}}})
+
+	if _, _, err := signer.Sign(t.Context(), csr, DefaultProfile); err != nil {
+		t.Fatalf("expected the certificate of the profile to be issued verbatim, got %v", err)
+	}
+
+	profile := DefaultProfile
+	profile.Organizations = []string{"os:reader"}
+
+	if _, _, err := signer.Sign(t.Context(), csr, profile); !errors.Is(err, pkgerrors.ErrBackendSign) {
+		t.Fatalf("expected the stripped organizations issued verbatim to be refused, got %v", err)
+	}
+
+	profile = DefaultProfile
+	profile.Subject = SubjectRules{Drop: []string{SubjectCountry}}
+
+	csr = newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1", Country: []string{"IT"}}})
+	if _, _, err := signer.Sign(t.Context(), csr, profile); !errors.Is(err, pkgerrors.ErrBackendSign) {
+		t.Fatalf("expected the rewritten subject issued verbatim to be refused, got %v", err)
+	}
+
+	csr = newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, EmailAddresses: []string{"admin@example.com"}})
+	if _, _, err := signer.Sign(t.Context(), csr, DefaultProfile); !errors.Is(err, pkgerrors.ErrBackendSign) {
+		t.Fatalf("expected the dropped email addresses issued verbatim to be refused, got %v", err)
+	}
+
+	passed := DefaultProfile
+	passed.PassEmailAddresses = true
+
+	if _, _, err := signer.Sign(t.Context(), csr, passed); err != nil {
+		t.Fatalf("expected the passed email addresses to be issued verbatim, got %v", err)
+	}
+
+	csr = newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, URIs: []*url.URL{{Scheme: "https", Host: "example.com"}}})
+	if _, _, err := signer.Sign(t.Context(), csr, DefaultProfile); !errors.Is(err, pkgerrors.ErrBackendSign) {
+		t.Fatalf("expected the dropped URIs issued verbatim to be refused, got %v", err)
+	}
+
+	stripped := DefaultProfile
+	stripped.StripWildcards = true
+
+	csr = newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, DNSNames: []string{"worker-1", "*.example.com"}})
+	if _, _, err := signer.Sign(t.Context(), csr, stripped); !errors.Is(err, pkgerrors.ErrBackendSign) {
+		t.Fatalf("expected the stripped wildcard DNS names issued verbatim to be refused, got %v", err)
+	}
+
+	stripped = DefaultProfile
+	stripped.StripLocalIPs = true
+
+	csr = newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, IPAddresses: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("127.0.0.1")}})
+	if _, _, err := signer.Sign(t.Context(), csr, stripped); !errors.Is(err, pkgerrors.ErrBackendSign) {
+		t.Fatalf("expected the stripped local IP addresses issued verbatim to be refused, got %v", err)
+	}
+
+	extended := DefaultProfile
+	extended.ExtraSANs = SANs{DNSNames: []string{"api.example.com"}}
+
+	csr = newCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "worker-1"}, DNSNames: []string{"worker-1"}})
+	if _, _, err := signer.Sign(t.Context(), csr, extended); !errors.Is(err, pkgerrors.ErrBackendSign) {
+		t.Fatalf("expected the extra SANs left out by the backend to be refused, got %v", err)
+	}
+
+	spiffeID, err := SPIFFEIDTemplate("spiffe://example.com/talos/{{.CommonName}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svid := DefaultProfile
+	svid.SPIFFEID = spiffeID
+
+	if _, _, err = signer.Sign(t.Context(), csr, svid); !errors.Is(err, pkgerrors.ErrBackendSign) {
+		t.Fatalf("expected the SPIFFE ID left out by the backend to be refused, got %v", err)
+	}
+
+	extensions, err := Extensions([]string{"1.3.6.1.4.1.99999.2=utf8:datacenter-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tagged := DefaultProfile
+	tagged.ExtraExtensions = extensions
+
+	if _, _, err = signer.Sign(t.Context(), csr, tagged); !errors.Is(err, pkgerrors.ErrBackendSign) {
+		t.Fatalf("expected the extensions left out by the backend to be refused, got %v", err)
+	}
+
+	profile.Organizations, profile.StripWildcards, profile.StripLocalIPs = []string{"os:reader"}, true, true
+	profile.ExtraExtensions = extensions
+	profile.NodeSANs = map[string]SANs{"worker-1": {DNSNames: []string{"worker-1.example.com"}}}
+	profile.SPIFFEID = spiffeID
+	if rewrites := profile.Rewrites(); !slices.Equal(rewrites, []string{"organizations", "subject", "wildcard DNS names", "local IP addresses", "extra SANs", "SPIFFE ID", "extensions"}) {
+		t.Fatalf("unexpected rewrites %v", rewrites)
+	}
+
+	if rewrites := DefaultProfile.Rewrites(); len(rewrites) != 0 {
+		t.Fatalf("expected the default profile to rewrite nothing, got %v", rewrites)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package signer
+
+import (
+	"crypto/x509"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// spiffeScheme is the scheme of the SPIFFE IDs.
+const spiffeScheme = "spiffe://"
+
+// SPIFFEIDTemplate returns the template deriving the SPIFFE ID of the certificates from the CSR, such as
+// spiffe://example.com/talos/{{.CommonName}}, seeing the fields of the Common Name templates along with the NodeUUID.
+func SPIFFEIDTemplate(text string) (*template.Template, error) {
+	if !strings.HasPrefix(text, spiffeScheme) {
+		return nil, errors.Wrap(pkgerrors.ErrProfile, "invalid SPIFFE ID template "+text+", expected spiffe://<trust domain>/<path>")
+	}
+
+	tmpl, err := template.New("spiffe-id").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrProfile, "invalid SPIFFE ID template: "+err.Error())
+	}
+
+	return tmpl, nil
+}
+
+// spiffeIDData is the CSR seen by the SPIFFE ID templates.
+type spiffeIDData struct {
+	commonNameData
+	NodeUUID string
+}
+
+// spiffeID returns the SPIFFE ID of the certificate, rendered by the template of the profile.
+func (p Profile) spiffeID(csr *x509.CertificateRequest) (*url.URL, error) {
+	var id strings.Builder
+	if err := p.SPIFFEID.Execute(&id, spiffeIDData{commonNameData: newCommonNameData(csr), NodeUUID: p.NodeUUID}); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrSPIFFEID, err.Error())
+	}
+
+	return parseSPIFFEID(id.String())
+}
+
+// parseSPIFFEID returns the URI of the SPIFFE ID, refusing the ones violating the SPIFFE ID specification: a
+// lowercase trust domain, followed by a path of non-empty segments, neither . nor .., of letters, digits, dots, dashes,
+// and underscores.
+func parseSPIFFEID(id string) (*url.URL, error) {
+	invalid := func(reason string) error {
+		return errors.Wrap(pkgerrors.ErrSPIFFEID, "invalid SPIFFE ID "+id+": "+reason)
+	}
+
+	rest, found := strings.CutPrefix(id, spiffeScheme)
+	if !found || len(id) > 2048 {
+		return nil, invalid("expected spiffe://<trust domain>/<path> of at most 2048 characters")
+	}
+
+	trustDomain, path, found := strings.Cut(rest, "/")
+	if trustDomain == "" || strings.Trim(trustDomain, "abcdefghijklmnopqrstuvwxyz0123456789.-_") != "" {
+		return nil, invalid("the trust domain holds only lowercase letters, digits, dots, dashes, and underscores")
+	}
+
+	if !found {
+		return nil, invalid("the path is missing")
+	}
+
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return nil, invalid("the path segments cannot be empty, . or ..")
+		}
+
+		if strings.Trim(segment, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789.-_") != "" {
+			return nil, invalid("the path holds only letters, digits, dots, dashes, and underscores")
+		}
+	}
+
+	return &url.URL{Scheme: "spiffe", Host: trustDomain, Path: "/" + path}, nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package signer
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// Subject fields dropped by the SubjectRules.
+const (
+	SubjectOrganizationalUnit = "organizational-unit"
+	SubjectCountry            = "country"
+	SubjectProvince           = "province"
+	SubjectLocality           = "locality"
+	SubjectStreetAddress      = "street-address"
+	SubjectPostalCode         = "postal-code"
+	SubjectSerialNumber       = "serial-number"
+)
+
+// SubjectRules rewrite the CSR subject copied into the certificate, applied after the organizations of the profile
+// are filtered.
+type SubjectRules struct {
+	// Organizations replace the CSR subject organizations: empty keeps them.
+	Organizations []string
+	// Drop are the subject fields left out of the certificate.
+	Drop []string
+	// CommonName derives the Common Name from the CSR, such as system:node:{{.CommonName}}: nil keeps it.
+	CommonName *template.Template
+}
+
+// Empty returns true when the rules copy the CSR subject verbatim.
+func (r SubjectRules) Empty() bool {
+	return len(r.Organizations) == 0 && len(r.Drop) == 0 && r.CommonName == nil
+}
+
+// SubjectFields returns the fields of the names, as dropped by the SubjectRules.
+func SubjectFields(names []string) ([]string, error) {
+	fields := make([]string, 0, len(names))
+
+	for _, name := range names {
+		switch field := strings.ToLower(name); field {
+		case SubjectOrganizationalUnit, SubjectCountry, SubjectProvince, SubjectLocality, SubjectStreetAddress,
+			SubjectPostalCode, SubjectSerialNumber:
+			fields = append(fields, field)
+		default:
+			return nil, errors.Wrap(pkgerrors.ErrProfile, "unsupported subject field "+name+
+				", expected organizational-unit, country, province, locality, street-address, postal-code, or serial-number")
+		}
+	}
+
+	return fields, nil
+}
+
+// CommonNameTemplate returns the template deriving the Common Name from the CSR, seeing its CommonName,
+// Organizations, OrganizationalUnits, DNSNames, and IPAddresses.
+func CommonNameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("common-name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrProfile, "invalid Common Name template: "+err.Error())
+	}
+
+	return tmpl, nil
+}
+
+// commonNameData is the CSR seen by the Common Name templates.
+type commonNameData struct {
+	CommonName          string
+	Organizations       []string
+	OrganizationalUnits []string
+	DNSNames            []string
+	IPAddresses         []string
+}
+
+// newCommonNameData returns the CSR seen by the Common Name templates.
+func newCommonNameData(csr *x509.CertificateRequest) commonNameData {
+	data := commonNameData{
+		CommonName:          csr.Subject.CommonName,
+		Organizations:       csr.Subject.Organization,
+		OrganizationalUnits: csr.Subject.OrganizationalUnit,
+		DNSNames:            csr.DNSNames,
+	}
+
+	for _, ip := range csr.IPAddresses {
+		data.IPAddresses = append(data.IPAddresses, ip.String())
+	}
+
+	return data
+}
+
+// apply returns the subject rewritten by the rules.
+func (r SubjectRules) apply(csr *x509.CertificateRequest, subject pkix.Name) (pkix.Name, error) {
+	if len(r.Organizations) > 0 {
+		subject.Organization = r.Organizations
+	}
+
+	for _, field := range r.Drop {
+		switch field {
+		case SubjectOrganizationalUnit:
+			subject.OrganizationalUnit = nil
+		case SubjectCountry:
+			subject.Country = nil
+		case SubjectProvince:
+			subject.Province = nil
+		case SubjectLocality:
+			subject.Locality = nil
+		case SubjectStreetAddress:
+			subject.StreetAddress = nil
+		case SubjectPostalCode:
+			subject.PostalCode = nil
+		case SubjectSerialNumber:
+			subject.SerialNumber = ""
+		}
+	}
+
+	if r.CommonName != nil {
+		var commonName strings.Builder
+		if err := r.CommonName.Execute(&commonName, newCommonNameData(csr)); err != nil {
+			return subject, errors.Wrap(pkgerrors.ErrSubject, err.Error())
+		}
+
+		if commonName.Len() == 0 {
+			return subject, errors.Wrap(pkgerrors.ErrSubject, "the Common Name template rendered an empty Common Name")
+		}
+
+		subject.CommonName = commonName.String()
+	}
+
+	return subject, nil
+}
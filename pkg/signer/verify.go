@@ -0,0 +1,111 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package signer
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// verifyIssued returns an error when the certificate issued by the backend differs from the template, as the backends
+// signing the CSRs themselves, such as Vault and the upstream signer, issue their subject and SANs verbatim rather than
+// the ones rewritten by the profile, such as the wildcard DNS names and local IP addresses it strips, the extra SANs it
+// adds, and the email addresses and URIs it drops, nor do they add the extensions of the profile.
+func verifyIssued(cert, template *x509.Certificate) error {
+	if cert.Subject.String() != template.Subject.String() {
+		return errors.Wrap(pkgerrors.ErrBackendSign, "issued the subject "+cert.Subject.String()+
+			" in place of "+template.Subject.String())
+	}
+
+	if dnsNames, expected := lowerStrings(cert.DNSNames), lowerStrings(template.DNSNames); !sameElements(dnsNames, expected) {
+		return errors.Wrapf(pkgerrors.ErrBackendSign, "issued the DNS names %v in place of %v", cert.DNSNames, template.DNSNames)
+	}
+
+	if ips, expected := ipStrings(cert.IPAddresses), ipStrings(template.IPAddresses); !sameElements(ips, expected) {
+		return errors.Wrapf(pkgerrors.ErrBackendSign, "issued the IP addresses %v in place of %v", ips, expected)
+	}
+
+	if !sameElements(cert.EmailAddresses, template.EmailAddresses) {
+		return errors.Wrapf(pkgerrors.ErrBackendSign, "issued the email addresses %v in place of %v",
+			cert.EmailAddresses, template.EmailAddresses)
+	}
+
+	if uris, expected := uriStrings(cert.URIs), uriStrings(template.URIs); !sameElements(uris, expected) {
+		return errors.Wrapf(pkgerrors.ErrBackendSign, "issued the URIs %v in place of %v", uris, expected)
+	}
+
+	if !sameElements(cert.ExtKeyUsage, template.ExtKeyUsage) {
+		return errors.Wrap(pkgerrors.ErrBackendSign, "did not issue the extended key usages of the profile")
+	}
+
+	for _, extension := range template.ExtraExtensions {
+		if !slices.ContainsFunc(cert.Extensions, func(issued pkix.Extension) bool {
+			return issued.Id.Equal(extension.Id) && issued.Critical == extension.Critical && bytes.Equal(issued.Value, extension.Value)
+		}) {
+			return errors.Wrap(pkgerrors.ErrBackendSign, "did not issue the extension "+extension.Id.String())
+		}
+	}
+
+	return nil
+}
+
+// lowerStrings returns the DNS names in lowercase, to be compared.
+func lowerStrings(names []string) []string {
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		values = append(values, strings.ToLower(name))
+	}
+
+	return values
+}
+
+// ipStrings returns the IP addresses as strings, to be compared.
+func ipStrings(ips []net.IP) []string {
+	values := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		values = append(values, ip.String())
+	}
+
+	return values
+}
+
+// uriStrings returns the URIs as strings, to be compared.
+func uriStrings(uris []*url.URL) []string {
+	values := make([]string, 0, len(uris))
+	for _, uri := range uris {
+		values = append(values, uri.String())
+	}
+
+	return values
+}
+
+// sameElements returns true when the slices hold the same elements, whatever their order.
+func sameElements[T comparable](issued, expected []T) bool {
+	if len(issued) != len(expected) {
+		return false
+	}
+
+	for _, element := range issued {
+		if !slices.Contains(expected, element) {
+			return false
+		}
+	}
+
+	for _, element := range expected {
+		if !slices.Contains(issued, element) {
+			return false
+		}
+	}
+
+	return true
+}
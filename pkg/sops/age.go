@@ -0,0 +1,79 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package sops
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// ageIntro is the first line of the age files.
+const ageIntro = "age-encryption.org/v1\n"
+
+// IsAge reports whether the data is an age file, binary or ASCII armored.
+func IsAge(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(ageIntro)) || bytes.HasPrefix(bytes.TrimSpace(data), []byte(armor.Header))
+}
+
+// ParseAgeIdentities returns the identities of the AGE-SECRET-KEY-1 lines of the data, such as the keys.txt file of
+// age-keygen, skipping the empty lines and the # comments.
+func ParseAgeIdentities(data []byte) ([]age.Identity, error) {
+	// age refuses the files without identities, the settings leaving them unset.
+	empty := true
+
+	for line := range strings.Lines(string(data)) {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			empty = false
+		}
+	}
+
+	if empty {
+		return nil, nil
+	}
+
+	identities, err := age.ParseIdentities(bytes.NewReader(data))
+	if err != nil {
+		// The error of age quotes the invalid line, holding the secret key.
+		return nil, errors.Wrap(pkgerrors.ErrDecrypt, "invalid age identity, expected AGE-SECRET-KEY-1...")
+	}
+
+	return identities, nil
+}
+
+// DecryptAge returns the plaintext of the age file encrypted to the recipient of one of the identities.
+func DecryptAge(data []byte, identities []age.Identity) ([]byte, error) {
+	if len(identities) == 0 {
+		return nil, errors.Wrap(pkgerrors.ErrDecrypt, "no age identity, set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE")
+	}
+
+	var src io.Reader = bytes.NewReader(data)
+	if !bytes.HasPrefix(data, []byte(ageIntro)) {
+		src = armor.NewReader(bufio.NewReader(bytes.NewReader(bytes.TrimSpace(data))))
+	}
+
+	plaintext, err := age.Decrypt(src, identities...)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return nil, errors.Wrap(pkgerrors.ErrDecrypt, "the age file is not encrypted to any of the identities")
+		}
+
+		return nil, errors.Wrap(pkgerrors.ErrDecrypt, "invalid age file: "+err.Error())
+	}
+
+	data, err = io.ReadAll(plaintext)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrDecrypt, "invalid age payload: "+err.Error())
+	}
+
+	return data, nil
+}
@@ -0,0 +1,322 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sops decrypts the files encrypted by sops, their data key being encrypted with age or AWS KMS, along with the
+// files encrypted by age, so the CA material can be committed encrypted to the GitOps repositories.
+package sops
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/pkg/errors"
+	"go.yaml.in/yaml/v3"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/kms"
+)
+
+// encryptedValue matches the values encrypted by sops.
+var encryptedValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// Keys are the keys decrypting the files.
+type Keys struct {
+	// AgeIdentities decrypt the age files, and the sops data keys encrypted with age.
+	AgeIdentities []age.Identity
+	// KMS decrypts the sops data keys encrypted with AWS KMS, its KeyID and Region being the ones of each sops key.
+	KMS kms.Options
+}
+
+// metadata is the sops metadata of the encrypted files, under their sops key.
+type metadata struct {
+	KMS []struct {
+		ARN     string            `yaml:"arn"`
+		Enc     string            `yaml:"enc"`
+		Context map[string]string `yaml:"context"`
+	} `yaml:"kms"`
+	Age []struct {
+		Recipient string `yaml:"recipient"`
+		Enc       string `yaml:"enc"`
+	} `yaml:"age"`
+	KeyGroups        []any  `yaml:"key_groups"`
+	LastModified     string `yaml:"lastmodified"`
+	MAC              string `yaml:"mac"`
+	MACOnlyEncrypted bool   `yaml:"mac_only_encrypted"`
+}
+
+// IsEncrypted reports whether the data is an age file, or a YAML or JSON document encrypted by sops.
+func IsEncrypted(data []byte) bool {
+	if IsAge(data) {
+		return true
+	}
+
+	if !bytes.Contains(data, []byte("sops")) {
+		return false
+	}
+
+	_, meta, err := parse(data)
+
+	return err == nil && meta != nil
+}
+
+// Decrypt returns the plaintext of the age file, or of the sops document: the data of the files of the binary
+// format, such as the private keys, otherwise the decrypted YAML document without its sops metadata.
+func Decrypt(ctx context.Context, data []byte, keys Keys) ([]byte, error) {
+	if IsAge(data) {
+		return DecryptAge(data, keys.AgeIdentities)
+	}
+
+	doc, meta, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if meta == nil {
+		return nil, errors.Wrap(pkgerrors.ErrDecrypt, "the document holds no sops metadata")
+	}
+
+	dataKey, err := meta.dataKey(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decryption{key: dataKey, macOnlyEncrypted: meta.MACOnlyEncrypted, hash: sha512.New()}
+	root := doc.Content[0]
+
+	if err = d.walk(root, nil); err != nil {
+		return nil, err
+	}
+
+	mac, _, err := decryptValue(dataKey, meta.MAC, meta.LastModified)
+	if err != nil {
+		return nil, errors.Wrap(err, "MAC")
+	}
+
+	if mac != fmt.Sprintf("%X", d.hash.Sum(nil)) {
+		return nil, errors.Wrap(pkgerrors.ErrDecrypt, "the MAC doesn't match the values, the document was tampered with")
+	}
+
+	// The binary format of sops is a JSON document of the data key only.
+	if root.Style&yaml.FlowStyle != 0 && len(root.Content) == 2 && root.Content[0].Value == "data" {
+		return []byte(root.Content[1].Value), nil
+	}
+
+	plaintext, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrDecrypt, err.Error())
+	}
+
+	return plaintext, nil
+}
+
+// parse returns the YAML or JSON document, without its sops metadata, along with the metadata, nil when none.
+func parse(data []byte) (*yaml.Node, *metadata, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, errors.Wrap(pkgerrors.ErrDecrypt, err.Error())
+	}
+
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, errors.Wrap(pkgerrors.ErrDecrypt, "expected a YAML or JSON object")
+	}
+
+	root := doc.Content[0]
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "sops" {
+			continue
+		}
+
+		var meta metadata
+		if err := root.Content[i+1].Decode(&meta); err != nil || meta.MAC == "" {
+			return &doc, nil, nil //nolint:nilerr
+		}
+
+		root.Content = append(root.Content[:i], root.Content[i+2:]...)
+
+		return &doc, &meta, nil
+	}
+
+	return &doc, nil, nil
+}
+
+// dataKey returns the data key of the document, decrypted with the age identities or with AWS KMS.
+func (m *metadata) dataKey(ctx context.Context, keys Keys) ([]byte, error) {
+	if len(m.KeyGroups) > 0 {
+		return nil, errors.Wrap(pkgerrors.ErrDecrypt, "the sops key groups are not supported")
+	}
+
+	var failures []string
+
+	if len(keys.AgeIdentities) > 0 {
+		for _, entry := range m.Age {
+			dataKey, err := DecryptAge([]byte(entry.Enc), keys.AgeIdentities)
+			if err == nil {
+				return dataKey, nil
+			}
+
+			failures = append(failures, "age "+entry.Recipient+": "+err.Error())
+		}
+	}
+
+	for _, entry := range m.KMS {
+		blob, err := base64.StdEncoding.DecodeString(entry.Enc)
+		if err != nil {
+			failures = append(failures, "KMS "+entry.ARN+": "+err.Error())
+
+			continue
+		}
+
+		opts := keys.KMS
+		opts.KeyID, opts.Region, opts.EncryptionContext = entry.ARN, "", entry.Context
+
+		dataKey, err := kms.Decrypt(ctx, opts, blob)
+		if err == nil {
+			return dataKey, nil
+		}
+
+		failures = append(failures, "KMS "+entry.ARN+": "+err.Error())
+	}
+
+	if len(failures) == 0 {
+		return nil, errors.Wrap(pkgerrors.ErrDecrypt, "no age identity nor AWS KMS key of the document, set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE")
+	}
+
+	return nil, errors.Wrap(pkgerrors.ErrDecrypt, "the data key cannot be decrypted: "+strings.Join(failures, "; "))
+}
+
+// decryption decrypts the values of a document in place, hashing them in order for the MAC.
+type decryption struct {
+	key              []byte
+	macOnlyEncrypted bool
+	hash             hash.Hash
+}
+
+// walk decrypts the values of the node, the path being the one of the keys of the mappings leading to it, which
+// authenticates the encrypted values: the sequence items share the path of their sequence.
+func (d *decryption) walk(node *yaml.Node, path []string) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := d.walk(node.Content[i+1], append(path[:len(path):len(path)], node.Content[i].Value)); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if err := d.walk(item, path); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		return d.leaf(node, path)
+	default:
+		return errors.Wrap(pkgerrors.ErrDecrypt, "unsupported YAML node at "+strings.Join(path, "."))
+	}
+
+	return nil
+}
+
+// leaf decrypts the scalar node when encrypted, restoring its type, and hashes its value.
+func (d *decryption) leaf(node *yaml.Node, path []string) error {
+	plaintext, kind, err := decryptValue(d.key, node.Value, strings.Join(path, ":")+":")
+	if err != nil {
+		return errors.Wrap(err, strings.Join(path, "."))
+	}
+
+	var value any
+
+	encrypted := kind != ""
+	if encrypted {
+		node.Value, node.Style, node.Tag = plaintext, 0, "!!str"
+		value = plaintext
+
+		switch kind {
+		case "int":
+			node.Tag = "!!int"
+			value, err = strconv.Atoi(plaintext)
+		case "float":
+			node.Tag = "!!float"
+			value, err = strconv.ParseFloat(plaintext, 64)
+		case "bool":
+			node.Tag = "!!bool"
+			value, err = strconv.ParseBool(plaintext)
+		}
+	} else {
+		err = node.Decode(&value)
+	}
+
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrDecrypt, strings.Join(path, ".")+": "+err.Error())
+	}
+
+	if !d.macOnlyEncrypted || encrypted {
+		d.hash.Write(toBytes(value))
+	}
+
+	return nil
+}
+
+// toBytes returns the bytes of the value hashed by the MAC, after its type like sops.
+func toBytes(value any) []byte {
+	switch value := value.(type) {
+	case string:
+		return []byte(value)
+	case int:
+		return []byte(strconv.Itoa(value))
+	case float64:
+		return []byte(strconv.FormatFloat(value, 'f', -1, 64))
+	case bool:
+		if value {
+			return []byte("True")
+		}
+
+		return []byte("False")
+	default:
+		return []byte(fmt.Sprint(value))
+	}
+}
+
+// decryptValue returns the plaintext of the value encrypted by sops, authenticated with the additional data, along
+// with its type, empty when the value is not encrypted.
+func decryptValue(key []byte, value, additionalData string) (string, string, error) {
+	match := encryptedValue.FindStringSubmatch(value)
+	if match == nil {
+		return value, "", nil
+	}
+
+	data, dataErr := base64.StdEncoding.DecodeString(match[1])
+	iv, ivErr := base64.StdEncoding.DecodeString(match[2])
+	tag, tagErr := base64.StdEncoding.DecodeString(match[3])
+
+	if dataErr != nil || ivErr != nil || tagErr != nil || len(iv) == 0 {
+		return "", "", errors.Wrap(pkgerrors.ErrDecrypt, "invalid encrypted value")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", "", errors.Wrap(pkgerrors.ErrDecrypt, err.Error())
+	}
+
+	aead, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return "", "", errors.Wrap(pkgerrors.ErrDecrypt, err.Error())
+	}
+
+	plaintext, err := aead.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return "", "", errors.Wrap(pkgerrors.ErrDecrypt, "the value cannot be authenticated, wrong data key or tampered value")
+	}
+
+	return string(plaintext), match[4], nil
+}
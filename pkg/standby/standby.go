@@ -0,0 +1,254 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package standby implements the warm standby replica: it pulls the ledger and the effective configuration of the
+// primary signer through its admin API, without issuing certificates, until promoted when the primary site is lost.
+package standby
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+const (
+	// SnapshotPath is the admin API path of the primary ledger snapshot.
+	SnapshotPath = "/ledger/snapshot"
+	// ConfigPath is the admin API path of the primary effective configuration.
+	ConfigPath = "/config"
+)
+
+// localSettings are the configuration keys only meaningful to the local process, not compared with the primary.
+var localSettings = []string{"port", "admin-address", "admin-token", "ledger-url", "ledger-key-prefix", "config"}
+
+// Status is the replication state of the standby.
+type Status struct {
+	Primary  string         `json:"primary"`
+	Promoted bool           `json:"promoted"`
+	LastSync *time.Time     `json:"lastSync,omitempty"`
+	Records  int            `json:"records"`
+	Error    string         `json:"error,omitempty"`
+	Config   map[string]any `json:"config,omitempty"`
+	Drift    []string       `json:"drift,omitempty"`
+}
+
+// Replica replicates the primary signer until promoted.
+type Replica struct {
+	// PrimaryURL is the admin API URL of the primary.
+	PrimaryURL string
+	// Token is the bearer token of the primary admin API.
+	Token string
+	// Ledger receives the records of the primary.
+	Ledger ledger.Ledger
+	// LocalConfig is the effective configuration of the standby, compared with the one of the primary.
+	LocalConfig map[string]any
+	// Client sends the requests to the primary: nil uses http.DefaultClient.
+	Client *http.Client
+	// OnPromote is called once promoted: nil disables it.
+	OnPromote func()
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Active reports whether the replica is still a standby, false when nil.
+func (r *Replica) Active() bool {
+	if r == nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return !r.status.Promoted
+}
+
+// Status returns the replication state.
+func (r *Replica) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := r.status
+	status.Primary = r.PrimaryURL
+
+	return status
+}
+
+// Promote stops the replication, returning false when already promoted.
+func (r *Replica) Promote() bool {
+	r.mu.Lock()
+
+	if r.status.Promoted {
+		r.mu.Unlock()
+
+		return false
+	}
+
+	r.status.Promoted = true
+	r.mu.Unlock()
+
+	if r.OnPromote != nil {
+		r.OnPromote()
+	}
+
+	return true
+}
+
+// Run replicates the primary at the given interval, until promoted or the context is done.
+func (r *Replica) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if !r.Active() {
+			return
+		}
+
+		if err := r.Sync(ctx); err != nil {
+			logging.FromContext(ctx).Error("Failed to replicate the primary", "primary", r.PrimaryURL, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync pulls the ledger and the configuration of the primary, mirroring its records into the Ledger.
+func (r *Replica) Sync(ctx context.Context) error {
+	err := r.sync(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.status.Error = ""
+	if err != nil {
+		r.status.Error = err.Error()
+	}
+
+	return err
+}
+
+func (r *Replica) sync(ctx context.Context) error {
+	var config map[string]any
+
+	if err := r.get(ctx, ConfigPath, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&config) //nolint:wrapcheck
+	}); err != nil {
+		return err
+	}
+
+	var stored, deleted int
+
+	if err := r.get(ctx, SnapshotPath, func(body io.Reader) error {
+		var replicateErr error
+		stored, deleted, replicateErr = ledger.Replicate(ctx, r.Ledger, body)
+
+		return replicateErr //nolint:wrapcheck
+	}); err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		logging.FromContext(ctx).Info("Deleted the ledger records pruned from the primary", "deleted", deleted)
+	}
+
+	drift := Drift(r.LocalConfig, config)
+	now := time.Now().UTC()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(drift) > 0 && !slices.Equal(drift, r.status.Drift) {
+		logging.FromContext(ctx).Warn("The configuration differs from the primary one", "drift", drift)
+	}
+
+	r.status.LastSync, r.status.Records, r.status.Config, r.status.Drift = &now, stored, config, drift
+
+	return nil
+}
+
+// get reads the admin API path of the primary.
+func (r *Replica) get(ctx context.Context, path string, read func(body io.Reader) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(r.PrimaryURL, "/")+path, nil)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrStandby, err.Error())
+	}
+
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	}
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrStandby, err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Wrap(pkgerrors.ErrStandby, fmt.Sprintf("%s: %s", path, resp.Status))
+	}
+
+	if err = read(resp.Body); err != nil {
+		return errors.Wrap(pkgerrors.ErrStandby, path+": "+err.Error())
+	}
+
+	return nil
+}
+
+// Drift returns the sorted keys of the settings differing between the local and the primary configuration, ignoring
+// the standby ones and the localSettings.
+func Drift(local, primary map[string]any) []string {
+	if local == nil || primary == nil {
+		return nil
+	}
+
+	keys := make(map[string]struct{}, len(local))
+	for key := range local {
+		keys[key] = struct{}{}
+	}
+
+	for key := range primary {
+		keys[key] = struct{}{}
+	}
+
+	var drift []string
+
+	for key := range keys {
+		if strings.HasPrefix(key, "standby-") || slices.Contains(localSettings, key) {
+			continue
+		}
+		// The primary configuration is decoded from JSON, so the local values are compared in the same encoding.
+		localValue, _ := json.Marshal(local[key])
+		primaryValue, _ := json.Marshal(primary[key])
+
+		if !bytes.Equal(localValue, primaryValue) {
+			drift = append(drift, key)
+		}
+	}
+
+	sort.Strings(drift)
+
+	return drift
+}
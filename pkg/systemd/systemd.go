@@ -0,0 +1,138 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package systemd integrates the signer with systemd when running as a service on a management host:
+// the socket activation, and the readiness and watchdog notifications of sd_notify.
+package systemd
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// listenFDsStart is the first file descriptor passed by the socket activation.
+const listenFDsStart = 3
+
+// The states notified to the service manager.
+const (
+	StateReady    = "READY=1"
+	StateStopping = "STOPPING=1"
+	StateWatchdog = "WATCHDOG=1"
+)
+
+// Listeners returns the listeners passed by the socket activation, keyed by their FileDescriptorName,
+// the unnamed ones being named after their position. It returns nil when the process is not socket activated.
+func Listeners() (map[string]net.Listener, error) {
+	defer func() {
+		_ = os.Unsetenv("LISTEN_PID")
+		_ = os.Unsetenv("LISTEN_FDS")
+		_ = os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, nil //nolint:nilerr
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil //nolint:nilerr
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	listeners := make(map[string]net.Listener, count)
+
+	for i := range count {
+		fd := listenFDsStart + i
+		syscall.CloseOnExec(fd)
+
+		name := strconv.Itoa(i)
+		if i < len(names) && names[i] != "" && names[i] != "unknown" {
+			name = names[i]
+		}
+
+		file := os.NewFile(uintptr(fd), name) //nolint:gosec
+
+		listener, listenerErr := net.FileListener(file)
+		_ = file.Close()
+
+		if listenerErr != nil {
+			return nil, errors.Wrap(pkgerrors.ErrSocketActivation, name+": "+listenerErr.Error())
+		}
+
+		listeners[name] = listener
+	}
+
+	return listeners, nil
+}
+
+// Notify sends the state to the service manager, returning false when not running under systemd.
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	// Abstract namespace sockets start with @
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, errors.Wrap(pkgerrors.ErrNotify, err.Error())
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err = conn.Write([]byte(state)); err != nil {
+		return false, errors.Wrap(pkgerrors.ErrNotify, err.Error())
+	}
+
+	return true, nil
+}
+
+// WatchdogInterval returns the interval the service manager expects the watchdog notifications at,
+// zero when the watchdog is disabled.
+func WatchdogInterval() time.Duration {
+	if pid, err := strconv.Atoi(os.Getenv("WATCHDOG_PID")); err == nil && pid != os.Getpid() {
+		return 0
+	}
+
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+
+	return time.Duration(usec) * time.Microsecond
+}
+
+// RunWatchdog notifies the service manager at half the watchdog interval while healthy, until the context is done:
+// once unhealthy, the notifications stop and the service manager restarts the signer.
+func RunWatchdog(ctx context.Context, healthy func() bool) {
+	interval := WatchdogInterval()
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		if healthy() {
+			_, _ = Notify(StateWatchdog)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
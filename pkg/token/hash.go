@@ -0,0 +1,54 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+// hashPrefix is the prefix of the hashed tokens: sha256:<salt>:<hex SHA-256 digest of the salt followed by the token>.
+const hashPrefix = "sha256:"
+
+// Hash returns the token hashed with a random salt, accepted in place of the token.
+func Hash(token string) string {
+	salt := rand.Text()
+
+	return hashPrefix + salt + ":" + digest(salt, token)
+}
+
+// digest returns the hex SHA-256 digest of the salt followed by the token.
+func digest(salt, token string) string {
+	sum := sha256.Sum256([]byte(salt + token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// hashed returns the hashed token, hashing it when in plaintext so the tokens are never kept in memory.
+func hashed(token string) (string, error) {
+	if !strings.HasPrefix(token, hashPrefix) {
+		return Hash(token), nil
+	}
+
+	salt, sum, found := strings.Cut(strings.TrimPrefix(token, hashPrefix), ":")
+	if decoded, err := hex.DecodeString(sum); !found || salt == "" || err != nil || len(decoded) != sha256.Size {
+		return "", errors.Wrap(pkgerrors.ErrToken, "a hashed token must be sha256:<salt>:<hex SHA-256 digest>")
+	}
+
+	return token, nil
+}
+
+// matches returns true when the token is the hashed one, comparing their digests in constant time.
+func matches(hash, token string) bool {
+	salt, sum, _ := strings.Cut(strings.TrimPrefix(hash, hashPrefix), ":")
+
+	return subtle.ConstantTimeCompare([]byte(digest(salt, token)), []byte(sum)) == 1
+}
@@ -0,0 +1,303 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package token contains the Talos machine tokens accepted by the signer: the current one, along with
+// the previous ones still accepted during the grace period of a rotation.
+package token
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+// alphabet is the set of characters of the Talos tokens.
+const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// Generate returns a random token in the Talos format: 6 and 16 lowercase alphanumeric characters, dot separated.
+func Generate() (string, error) {
+	var token strings.Builder
+
+	for i := range 23 {
+		if i == 6 {
+			token.WriteByte('.')
+
+			continue
+		}
+
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", errors.Wrap(pkgerrors.ErrToken, err.Error())
+		}
+
+		token.WriteByte(alphabet[n.Int64()])
+	}
+
+	return token.String(), nil
+}
+
+// Previous is a token still accepted until its expiration, or until removed when zero.
+type Previous struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// expired returns true when the token is not accepted anymore.
+func (p Previous) expired(now time.Time) bool {
+	return !p.ExpiresAt.IsZero() && !now.Before(p.ExpiresAt)
+}
+
+// Set holds the hashed tokens accepted by the signer.
+type Set struct {
+	// Current is the token of the machine configuration.
+	Current string
+	// Previous are the replaced tokens, accepted during the grace period of the rotation.
+	Previous []Previous
+}
+
+// Parse returns the Set encoded one token per line: the current one first, then the previous ones
+// optionally followed by their RFC 3339 expiration, accepted until removed otherwise. Empty lines and the
+// ones starting with # are ignored, so a single token is a valid Set. The tokens are either hashed, as
+// returned by Hash, or in plaintext, hashed once parsed.
+func Parse(data []byte) (*Set, error) {
+	set := &Set{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		if set.Current == "" {
+			if len(fields) != 1 {
+				return nil, errors.Wrap(pkgerrors.ErrToken, "the current token must not expire")
+			}
+
+			current, err := hashed(fields[0])
+			if err != nil {
+				return nil, err
+			}
+
+			set.Current = current
+
+			continue
+		}
+
+		if len(fields) > 2 {
+			return nil, errors.Wrap(pkgerrors.ErrToken, "a previous token must only be followed by its expiration")
+		}
+
+		token, err := hashed(fields[0])
+		if err != nil {
+			return nil, err
+		}
+
+		previous := Previous{Token: token}
+
+		if len(fields) == 2 {
+			expiresAt, err := time.Parse(time.RFC3339, fields[1])
+			if err != nil {
+				return nil, errors.Wrap(pkgerrors.ErrToken, "invalid expiration: "+err.Error())
+			}
+
+			previous.ExpiresAt = expiresAt
+		}
+
+		set.Previous = append(set.Previous, previous)
+	}
+
+	if set.Current == "" {
+		return nil, pkgerrors.ErrMissingToken
+	}
+
+	return set, nil
+}
+
+// Encode returns the Set in the format read by Parse, without the expired previous tokens.
+func (s *Set) Encode(now time.Time) []byte {
+	var encoded bytes.Buffer
+
+	encoded.WriteString(s.Current + "\n")
+
+	for _, previous := range s.Previous {
+		switch {
+		case previous.ExpiresAt.IsZero():
+			encoded.WriteString(previous.Token + "\n")
+		case !previous.expired(now):
+			fmt.Fprintf(&encoded, "%s %s\n", previous.Token, previous.ExpiresAt.UTC().Format(time.RFC3339))
+		}
+	}
+
+	return encoded.Bytes()
+}
+
+// Rotate returns the Set with the given current token, hashed, accepting the replaced one for the grace period:
+// zero stops accepting it immediately.
+func (s *Set) Rotate(current string, grace time.Duration, now time.Time) *Set {
+	rotated := &Set{Current: Hash(current)}
+
+	for _, previous := range s.Previous {
+		if !previous.expired(now) {
+			rotated.Previous = append(rotated.Previous, previous)
+		}
+	}
+
+	if grace > 0 {
+		rotated.Previous = append(rotated.Previous, Previous{Token: s.Current, ExpiresAt: now.Add(grace)})
+	}
+
+	return rotated
+}
+
+// Valid returns true when the token is the current one, or a previous one not expired yet. All the tokens are
+// compared, in constant time, not to leak which one matched.
+func (s *Set) Valid(token string, now time.Time) bool {
+	valid := matches(s.Current, token)
+
+	for _, previous := range s.Previous {
+		if matches(previous.Token, token) && !previous.expired(now) {
+			valid = true
+		}
+	}
+
+	return valid
+}
+
+// Source provides the Set, reloaded from its file when modified, such as a mounted Secret being updated.
+type Source struct {
+	path string
+
+	mu       sync.Mutex
+	set      *Set
+	modTime  time.Time
+	accepted []string
+}
+
+// NewStatic returns the Source of the Set encoded in the value.
+func NewStatic(value string) (*Source, error) {
+	set, err := Parse([]byte(value))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Source{set: set}, nil
+}
+
+// NewFile returns the Source of the Set read from the file.
+func NewFile(path string) (*Source, error) {
+	s := &Source{path: path}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Path returns the file the Set is read from, empty for a static one.
+func (s *Source) Path() string {
+	return s.path
+}
+
+// Get returns the Set, reloading it when the file was modified. On a reload failure the last Set is kept, logged
+// with the logger of the context.
+func (s *Source) Get(ctx context.Context) *Set {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path == "" {
+		return s.set
+	}
+
+	if info, err := os.Stat(s.path); err == nil && !info.ModTime().Equal(s.modTime) {
+		if err = s.load(); err != nil {
+			logging.FromContext(ctx).Warn("Failed to reload the tokens, keeping the previous ones", "path", s.path, "error", err)
+		} else {
+			logging.FromContext(ctx).Info("Reloaded the tokens", "path", s.path)
+		}
+	}
+
+	return s.set
+}
+
+// Update replaces the Set of a static Source, such as after the configuration bundle holding it was modified.
+func (s *Source) Update(set *Set) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.set = s.accept(set)
+}
+
+// Accept adds the previous tokens, accepted until removed, to the Set of the Source, the reloaded and the updated
+// ones included.
+func (s *Source) Accept(tokens []string) error {
+	accepted := make([]string, 0, len(tokens))
+
+	for _, token := range tokens {
+		hash, err := hashed(token)
+		if err != nil {
+			return err
+		}
+
+		accepted = append(accepted, hash)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.accepted = accepted
+	s.set = s.accept(s.set)
+
+	return nil
+}
+
+// accept returns a copy of the Set along with the accepted tokens.
+func (s *Source) accept(set *Set) *Set {
+	if len(s.accepted) == 0 {
+		return set
+	}
+
+	accepted := &Set{Current: set.Current, Previous: slices.Clone(set.Previous)}
+	for _, token := range s.accepted {
+		accepted.Previous = append(accepted.Previous, Previous{Token: token})
+	}
+
+	return accepted
+}
+
+// load reads the Set from the file.
+func (s *Source) load() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrReadFile, err.Error())
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrReadFile, err.Error())
+	}
+
+	set, err := Parse(data)
+	if err != nil {
+		return err
+	}
+
+	s.set, s.modTime = s.accept(set), info.ModTime()
+
+	return nil
+}
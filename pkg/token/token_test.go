@@ -0,0 +1,226 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package token
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+const (
+	current  = "abcdef.0123456789abcdef"
+	previous = "ghijkl.0123456789abcdef"
+	// currentHash is the current token hashed with the QWERTYUIOPASDFGH salt.
+	currentHash = "sha256:QWERTYUIOPASDFGH:0c74fa9fff1b751a8edb2cb3b0374c60ef626d5bca0344b682ebe47d2beaa646"
+)
+
+func TestGenerate(t *testing.T) {
+	first, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	format := regexp.MustCompile(`^[a-z0-9]{6}\.[a-z0-9]{16}$`)
+	if !format.MatchString(first) || !format.MatchString(second) || first == second {
+		t.Fatalf("unexpected tokens %s and %s", first, second)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		previous int
+		expected error
+	}{
+		{name: "plaintext token", data: current + "\n"},
+		{name: "hashed token", data: currentHash},
+		{name: "previous tokens", data: "# rotated\n" + current + "\n\n" + previous + " 2025-06-01T00:00:00Z\n" + Hash(previous) + "\n", previous: 2},
+		{name: "empty", data: "\n# no token\n", expected: pkgerrors.ErrMissingToken},
+		{name: "expiring current token", data: current + " 2025-06-01T00:00:00Z\n", expected: pkgerrors.ErrToken},
+		{name: "invalid expiration", data: current + "\n" + previous + " tomorrow\n", expected: pkgerrors.ErrToken},
+		{name: "extra field", data: current + "\n" + previous + " 2025-06-01T00:00:00Z extra\n", expected: pkgerrors.ErrToken},
+		{name: "hash without salt", data: "sha256::0c74fa9fff1b751a8edb2cb3b0374c60ef626d5bca0344b682ebe47d2beaa646", expected: pkgerrors.ErrToken},
+		{name: "hash without digest", data: "sha256:QWERTYUIOPASDFGH", expected: pkgerrors.ErrToken},
+		{name: "truncated digest", data: currentHash[:len(currentHash)-2], expected: pkgerrors.ErrToken},
+		{name: "digest not hex", data: currentHash[:len(currentHash)-1] + "z", expected: pkgerrors.ErrToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := Parse([]byte(tt.data))
+
+			if tt.expected != nil {
+				if !errors.Is(err, tt.expected) {
+					t.Fatalf("expected %v, got %v", tt.expected, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.HasPrefix(set.Current, hashPrefix) || len(set.Previous) != tt.previous {
+				t.Fatalf("unexpected set %+v", set)
+			}
+
+			for _, p := range set.Previous {
+				if !strings.HasPrefix(p.Token, hashPrefix) {
+					t.Fatalf("the previous token %s is kept in plaintext", p.Token)
+				}
+			}
+		})
+	}
+}
+
+func TestValid(t *testing.T) {
+	now := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	set, err := Parse([]byte(currentHash + "\n" +
+		previous + " " + now.Add(time.Hour).Format(time.RFC3339) + "\n" +
+		"expire.0123456789abcdef " + now.Format(time.RFC3339) + "\n" +
+		"forevr.0123456789abcdef\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{name: "current token", token: current, valid: true},
+		{name: "previous token in its grace period", token: previous, valid: true},
+		{name: "previous token without expiration", token: "forevr.0123456789abcdef", valid: true},
+		{name: "expired previous token", token: "expire.0123456789abcdef"},
+		{name: "other token", token: "zzzzzz.0123456789abcdef"},
+		{name: "prefix of the current token", token: current[:6]},
+		{name: "hash of the current token", token: currentHash},
+		{name: "digest of the current token", token: strings.TrimPrefix(currentHash, "sha256:QWERTYUIOPASDFGH:")},
+		{name: "empty token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if valid := set.Valid(tt.token, now); valid != tt.valid {
+				t.Fatalf("expected valid %t, got %t", tt.valid, valid)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	first, second := Hash(current), Hash(current)
+	if first == second {
+		t.Fatal("expected the hashes of the token to be salted")
+	}
+
+	for _, hash := range []string{first, second, currentHash} {
+		if !matches(hash, current) || matches(hash, previous) {
+			t.Fatalf("unexpected match of the hash %s", hash)
+		}
+	}
+}
+
+func TestRotate(t *testing.T) {
+	now := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	set, err := Parse([]byte(current))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := set.Rotate(previous, time.Hour, now)
+
+	if !rotated.Valid(previous, now) || !rotated.Valid(current, now) || rotated.Valid(current, now.Add(time.Hour)) {
+		t.Fatal("expected the replaced token to be accepted during the grace period only")
+	}
+
+	reparsed, err := Parse(rotated.Encode(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reparsed.Valid(current, now) || strings.Contains(string(rotated.Encode(now)), current) {
+		t.Fatal("expected the encoded set to keep the hashed tokens")
+	}
+}
+
+func TestNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+
+	if _, err := NewFile(path); err == nil {
+		t.Fatal("expected the missing file to be rejected")
+	}
+
+	if err := os.WriteFile(path, []byte(current), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if source.Path() != path || !source.Get(t.Context()).Valid(current, time.Now()) {
+		t.Fatalf("unexpected source %s with %+v", source.Path(), source.Get(t.Context()))
+	}
+
+	reload := func(data string, at time.Time) {
+		if err = os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = os.Chtimes(path, at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reload("", time.Now().Add(time.Minute))
+
+	if !source.Get(t.Context()).Valid(current, time.Now()) {
+		t.Fatal("expected the previous tokens to be kept on a reload failure")
+	}
+
+	reload(previous, time.Now().Add(2*time.Minute))
+
+	if !source.Get(t.Context()).Valid(previous, time.Now()) || source.Get(t.Context()).Valid(current, time.Now()) {
+		t.Fatal("expected the modified file to be reloaded")
+	}
+}
+
+func TestSourceAccept(t *testing.T) {
+	now := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	source, err := NewStatic(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source.Accept([]string{previous})
+
+	if !source.Get(t.Context()).Valid(previous, now) || !source.Get(t.Context()).Valid(current, now) {
+		t.Fatal("expected the accepted token to be valid along with the current one")
+	}
+
+	source.Update(&Set{Current: "forevr.0123456789abcdef"})
+
+	if !source.Get(t.Context()).Valid(previous, now) || source.Get(t.Context()).Valid(current, now) {
+		t.Fatal("expected the accepted token to be kept across the updates")
+	}
+}
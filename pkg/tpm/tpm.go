@@ -0,0 +1,183 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tpm verifies the TPM quotes binding the CSRs to the hardware of the nodes: the quote is signed by an
+// attestation key whose certificate chains to the configured endorsement roots, and carries the CSR digest as its
+// qualifying data.
+package tpm
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+const (
+	// AKCertificateMetadataKey is the metadata key of the attestation key certificate, base64 DER encoded.
+	AKCertificateMetadataKey = "x-tpm-ak-certificate"
+	// QuoteMetadataKey is the metadata key of the quote, the TPMS_ATTEST structure base64 encoded.
+	QuoteMetadataKey = "x-tpm-quote"
+	// SignatureMetadataKey is the metadata key of the quote signature by the attestation key, base64 encoded:
+	// ASN.1 DER for ECDSA, and PKCS #1 v1.5 or PSS for RSA, over the SHA-256 digest of the quote.
+	SignatureMetadataKey = "x-tpm-quote-signature"
+)
+
+const (
+	// generatedValue is the magic value of the structures generated by a TPM, TPM_GENERATED_VALUE.
+	generatedValue = 0xff544347
+	// attestQuote is the structure tag of the quotes, TPM_ST_ATTEST_QUOTE.
+	attestQuote = 0x8018
+)
+
+// oidSubjectAltName is the Subject Alternative Name extension, critical in the EK and AK certificates holding the
+// TPM manufacturer, model, and version as a directory name, not handled by crypto/x509.
+var oidSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+
+// Verifier verifies the quotes against the endorsement roots.
+type Verifier struct {
+	roots         *x509.CertPool
+	intermediates *x509.CertPool
+}
+
+// NewVerifier returns the Verifier of the PEM encoded endorsement certificates: the self-signed ones are the roots,
+// the others the intermediates.
+func NewVerifier(endorsementPEM []byte) (*Verifier, error) {
+	v := &Verifier{roots: x509.NewCertPool(), intermediates: x509.NewCertPool()}
+
+	for rest := endorsementPEM; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrAttestation, "invalid endorsement certificate: "+err.Error())
+		}
+
+		if bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil {
+			v.roots.AddCert(cert)
+		} else {
+			v.intermediates.AddCert(cert)
+		}
+	}
+
+	if len(v.roots.Subjects()) == 0 { //nolint:staticcheck
+		return nil, errors.Wrap(pkgerrors.ErrAttestation, "no endorsement root")
+	}
+
+	return v, nil
+}
+
+// Verify returns the attestation key certificate once it chains to the endorsement roots, and the quote it signed
+// carries the SHA-256 digest of the DER encoded CSR as its qualifying data.
+func (v *Verifier) Verify(akCertDER, quote, signature, csrDER []byte, now time.Time) (*x509.Certificate, error) {
+	akCert, err := x509.ParseCertificate(akCertDER)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrAttestation, "invalid attestation key certificate: "+err.Error())
+	}
+
+	akCert.UnhandledCriticalExtensions = slices.DeleteFunc(akCert.UnhandledCriticalExtensions, oidSubjectAltName.Equal)
+
+	if _, err = akCert.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: v.intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrAttestation, "attestation key certificate not endorsed: "+err.Error())
+	}
+
+	digest := sha256.Sum256(quote)
+	if err = verifySignature(akCert.PublicKey, digest[:], signature); err != nil {
+		return nil, err
+	}
+
+	extraData, err := QualifyingData(quote)
+	if err != nil {
+		return nil, err
+	}
+
+	csrDigest := sha256.Sum256(csrDER)
+	if !bytes.Equal(extraData, csrDigest[:]) {
+		return nil, errors.Wrap(pkgerrors.ErrAttestation, "the quote is not bound to the CSR")
+	}
+
+	return akCert, nil
+}
+
+// QualifyingData returns the qualifying data of the quote, the extraData of the TPMS_ATTEST structure.
+func QualifyingData(quote []byte) ([]byte, error) {
+	reader := bytes.NewReader(quote)
+
+	var header struct {
+		Magic uint32
+		Type  uint16
+	}
+
+	if err := binary.Read(reader, binary.BigEndian, &header); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrAttestation, "truncated quote")
+	}
+
+	if header.Magic != generatedValue || header.Type != attestQuote {
+		return nil, errors.Wrap(pkgerrors.ErrAttestation, "not a quote generated by a TPM")
+	}
+
+	// Skip the qualified name of the signing key
+	if _, err := readSized(reader); err != nil {
+		return nil, err
+	}
+
+	return readSized(reader)
+}
+
+// readSized reads a TPM2B structure, the 16-bit size followed by the bytes.
+func readSized(reader *bytes.Reader) ([]byte, error) {
+	var size uint16
+	if err := binary.Read(reader, binary.BigEndian, &size); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrAttestation, "truncated quote")
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrAttestation, "truncated quote")
+	}
+
+	return data, nil
+}
+
+// verifySignature verifies the signature of the digest with the attestation public key.
+func verifySignature(publicKey any, digest, signature []byte) error {
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if ecdsa.VerifyASN1(key, digest, signature) {
+			return nil
+		}
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature) == nil ||
+			rsa.VerifyPSS(key, crypto.SHA256, digest, signature, nil) == nil {
+			return nil
+		}
+	default:
+		return errors.Wrapf(pkgerrors.ErrAttestation, "unsupported attestation key %T", publicKey)
+	}
+
+	return errors.Wrap(pkgerrors.ErrAttestation, "invalid quote signature")
+}
@@ -0,0 +1,165 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package tpm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+)
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// otherRoot returns the PEM encoded self-signed certificate of another endorsement root.
+func otherRoot(t *testing.T) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test TPM EK Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// TestVerify verifies the quote of testdata, produced by TPM2_Quote of the reference TPM simulator: its ECC
+// attestation key signed the SHA-256 digest of csr.der as the qualifying data, ak.der certifying the key, with the
+// critical directory name of the TPM, under the endorsement root of root.pem.
+func TestVerify(t *testing.T) {
+	root, akCert, csr := readFile(t, "testdata/root.pem"), readFile(t, "testdata/ak.der"), readFile(t, "testdata/csr.der")
+	quote, signature := readFile(t, "testdata/quote.bin"), readFile(t, "testdata/quote.sig")
+	now := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	tampered := bytes.Clone(quote)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name      string
+		roots     []byte
+		quote     []byte
+		signature []byte
+		csr       []byte
+		now       time.Time
+		valid     bool
+	}{
+		{name: "quote of the CSR", roots: root, quote: quote, signature: signature, csr: csr, now: now, valid: true},
+		{name: "other CSR", roots: root, quote: quote, signature: signature, csr: append(bytes.Clone(csr), 0), now: now},
+		{name: "tampered PCR digest", roots: root, quote: tampered, signature: signature, csr: csr, now: now},
+		{name: "truncated signature", roots: root, quote: quote, signature: signature[:len(signature)-1], csr: csr, now: now},
+		{name: "other endorsement root", roots: otherRoot(t), quote: quote, signature: signature, csr: csr, now: now},
+		{name: "expired attestation key", roots: root, quote: quote, signature: signature, csr: csr, now: now.AddDate(200, 0, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier, err := NewVerifier(tt.roots)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cert, err := verifier.Verify(akCert, tt.quote, tt.signature, tt.csr, tt.now)
+
+			if !tt.valid {
+				if !errors.Is(err, pkgerrors.ErrAttestation) {
+					t.Fatalf("expected an invalid attestation, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cert.Subject.CommonName != "Test TPM AK" {
+				t.Fatalf("unexpected attestation key certificate %s", cert.Subject)
+			}
+		})
+	}
+}
+
+func TestNewVerifier(t *testing.T) {
+	if _, err := NewVerifier(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: readFile(t, "testdata/ak.der")})); !errors.Is(err, pkgerrors.ErrAttestation) {
+		t.Fatalf("expected the verifier without endorsement root to be refused, got %v", err)
+	}
+}
+
+func TestQualifyingData(t *testing.T) {
+	quote := readFile(t, "testdata/quote.bin")
+	csrDigest := sha256.Sum256(readFile(t, "testdata/csr.der"))
+
+	otherType := bytes.Clone(quote)
+	otherType[5] = 0x17 // TPM_ST_ATTEST_CERTIFY
+
+	tests := []struct {
+		name     string
+		quote    []byte
+		expected []byte
+	}{
+		{name: "quote", quote: quote, expected: csrDigest[:]},
+		{name: "certification", quote: otherType},
+		{name: "not generated by a TPM", quote: append([]byte{0, 0, 0, 0}, quote[4:]...)},
+		{name: "truncated qualified name", quote: quote[:20]},
+		{name: "truncated qualifying data", quote: quote[:50]},
+		{name: "empty", quote: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extraData, err := QualifyingData(tt.quote)
+
+			if tt.expected == nil {
+				if !errors.Is(err, pkgerrors.ErrAttestation) {
+					t.Fatalf("expected an invalid quote, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(extraData, tt.expected) {
+				t.Fatalf("expected the qualifying data %x, got %x", tt.expected, extraData)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package transparency publishes the issued certificates to a transparency log, making the issuance by the CA
+// externally auditable: the proof of inclusion returned by the log is stored in the ledger.
+package transparency
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+)
+
+// Log publishes the issued certificates.
+type Log interface {
+	// Submit publishes the certificate signed by the PEM encoded CA, returning the proof of its inclusion.
+	Submit(ctx context.Context, cert *x509.Certificate, caPEM []byte) (*ledger.TransparencyEntry, error)
+}
+
+// Rekor is the Log backed by a Sigstore Rekor server: every certificate is a rekord entry of its TBSCertificate,
+// signed by the CA, so the log verifies the CA signature before integrating it.
+type Rekor struct {
+	url    string
+	client *http.Client
+}
+
+// NewRekor returns the Rekor Log of the server at the URL, such as https://rekor.sigstore.dev.
+func NewRekor(url string, timeout time.Duration) *Rekor {
+	return &Rekor{url: strings.TrimSuffix(url, "/"), client: &http.Client{Timeout: timeout}}
+}
+
+// rekordEntry is the proposed entry of the rekord type.
+type rekordEntry struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       struct {
+		Signature struct {
+			Format    string `json:"format"`
+			Content   string `json:"content"`
+			PublicKey struct {
+				Content string `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+		Data struct {
+			Content string `json:"content"`
+		} `json:"data"`
+	} `json:"spec"`
+}
+
+// logEntry is the integrated entry answered by the log.
+type logEntry struct {
+	LogIndex       int64 `json:"logIndex"`
+	IntegratedTime int64 `json:"integratedTime"`
+	Verification   struct {
+		InclusionProof       json.RawMessage `json:"inclusionProof"`
+		SignedEntryTimestamp string          `json:"signedEntryTimestamp"`
+	} `json:"verification"`
+}
+
+// Submit implements Log.
+func (r *Rekor) Submit(ctx context.Context, cert *x509.Certificate, caPEM []byte) (*ledger.TransparencyEntry, error) {
+	entry := rekordEntry{APIVersion: "0.0.1", Kind: "rekord"}
+	entry.Spec.Signature.Format = "x509"
+	entry.Spec.Signature.Content = base64.StdEncoding.EncodeToString(cert.Signature)
+	entry.Spec.Signature.PublicKey.Content = base64.StdEncoding.EncodeToString(caPEM)
+	entry.Spec.Data.Content = base64.StdEncoding.EncodeToString(cert.RawTBSCertificate)
+
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrTransparencyLog, err.Error())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url+"/api/v1/log/entries", bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrTransparencyLog, err.Error())
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrTransparencyLog, err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, errors.Wrapf(pkgerrors.ErrTransparencyLog, "%s answered %s", r.url, resp.Status)
+	}
+
+	var entries map[string]logEntry
+	if err = json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrTransparencyLog, "invalid response: "+err.Error())
+	}
+
+	for uuid, integrated := range entries {
+		return &ledger.TransparencyEntry{
+			Log:                  r.url,
+			UUID:                 uuid,
+			LogIndex:             integrated.LogIndex,
+			IntegratedTime:       integrated.IntegratedTime,
+			InclusionProof:       integrated.Verification.InclusionProof,
+			SignedEntryTimestamp: integrated.Verification.SignedEntryTimestamp,
+		}, nil
+	}
+
+	return nil, errors.Wrap(pkgerrors.ErrTransparencyLog, "no entry answered")
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package version reports the build metadata of the signer, injected at build time through the linker flags:
+//
+//	-X github.com/clastix/talos-csr-signer/pkg/version.Version=v1.0.0
+//	-X github.com/clastix/talos-csr-signer/pkg/version.GitCommit=abc1234
+//	-X github.com/clastix/talos-csr-signer/pkg/version.BuildDate=2025-01-01T00:00:00Z
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+const unknown = "unknown"
+
+var (
+	// Version is the release of the signer.
+	Version = "dev"
+	// GitCommit is the commit the signer has been built from.
+	GitCommit = ""
+	// BuildDate is the date the signer has been built at.
+	BuildDate = ""
+)
+
+// Info is the build metadata of the signer.
+type Info struct {
+	Version         string `json:"version"`
+	GitCommit       string `json:"gitCommit"`
+	BuildDate       string `json:"buildDate"`
+	GoVersion       string `json:"goVersion"`
+	ProtobufVersion string `json:"protobufVersion"`
+	GRPCVersion     string `json:"grpcVersion"`
+	Platform        string `json:"platform"`
+}
+
+// Get returns the build metadata, falling back to the VCS information stamped by the Go toolchain
+// when the linker flags are not set.
+func Get() Info {
+	info := Info{
+		Version:         Version,
+		GitCommit:       GitCommit,
+		BuildDate:       BuildDate,
+		GoVersion:       runtime.Version(),
+		ProtobufVersion: unknown,
+		GRPCVersion:     unknown,
+		Platform:        runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range buildInfo.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.GitCommit == "":
+				info.GitCommit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+
+		for _, dep := range buildInfo.Deps {
+			switch dep.Path {
+			case "google.golang.org/protobuf":
+				info.ProtobufVersion = dep.Version
+			case "google.golang.org/grpc":
+				info.GRPCVersion = dep.Version
+			}
+		}
+	}
+
+	if info.GitCommit == "" {
+		info.GitCommit = unknown
+	}
+
+	if info.BuildDate == "" {
+		info.BuildDate = unknown
+	}
+
+	return info
+}
+
+// String returns the single line description of the build, used by the startup banner.
+func (i Info) String() string {
+	return fmt.Sprintf("version=%s commit=%s built=%s go=%s protobuf=%s grpc=%s platform=%s",
+		i.Version, i.GitCommit, i.BuildDate, i.GoVersion, i.ProtobufVersion, i.GRPCVersion, i.Platform)
+}
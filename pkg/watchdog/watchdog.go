@@ -0,0 +1,85 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+// Package watchdog detects persistent internal failures of the signer, such as an unusable CA key
+// or a permanently failing backend, so the instance can be replaced instead of black-holing requests.
+package watchdog
+
+import (
+	"context"
+	"sync"
+
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+)
+
+// ExitCode is the exit code used when the watchdog detects unrecoverable failures,
+// distinct from the generic failures so orchestrators and operators can tell them apart.
+const ExitCode = 3
+
+// Watchdog trips once the configured number of consecutive internal failures is reached.
+// A nil Watchdog is valid and never trips.
+type Watchdog struct {
+	threshold int
+	onTrip    func(err error)
+
+	mu       sync.Mutex
+	failures int
+	tripped  bool
+}
+
+// New returns a Watchdog calling onTrip once, after threshold consecutive failures.
+func New(threshold int, onTrip func(err error)) *Watchdog {
+	return &Watchdog{
+		threshold: max(threshold, 1),
+		onTrip:    onTrip,
+	}
+}
+
+// Success resets the consecutive failures count.
+func (w *Watchdog) Success() {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.failures = 0
+}
+
+// Failure records an internal failure, tripping the Watchdog when the threshold is reached, logged with the logger of
+// the context.
+func (w *Watchdog) Failure(ctx context.Context, err error) {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.failures++
+
+	if w.tripped || w.failures < w.threshold {
+		return
+	}
+
+	logging.FromContext(ctx).Error("Watchdog tripped after consecutive internal failures", "failures", w.failures, "error", err)
+
+	w.tripped = true
+
+	if w.onTrip != nil {
+		w.onTrip(err)
+	}
+}
+
+// Tripped returns true when the signer is considered unrecoverable.
+func (w *Watchdog) Tripped() bool {
+	if w == nil {
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.tripped
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/plugin"
+	"github.com/clastix/talos-csr-signer/pkg/policy"
+	"github.com/clastix/talos-csr-signer/pkg/server"
+)
+
+// loadedPlugins are the implementations served by the configured plugins.
+type loadedPlugins struct {
+	clients []*plugin.Client
+	// authenticator replaces the token check: at most one plugin serves it.
+	authenticator server.Authenticator
+	// validators are appended to the signing policy.
+	validators policy.Chain
+	// signer replaces the local CA backend: at most one plugin serves it.
+	signer backend.Backend
+}
+
+// loadPlugins starts the plugin binaries, collecting the implementations they serve.
+func loadPlugins(ctx context.Context, paths []string) (*loadedPlugins, error) {
+	loaded := &loadedPlugins{}
+
+	for _, path := range paths {
+		client, err := plugin.Load(ctx, path)
+		if err != nil {
+			loaded.close()
+
+			return nil, err //nolint:wrapcheck
+		}
+
+		loaded.clients = append(loaded.clients, client)
+
+		if client.Plugins.Authenticator != nil {
+			if loaded.authenticator != nil {
+				loaded.close()
+
+				return nil, errors.Wrap(pkgerrors.ErrPlugin, "more than one plugin serves the authenticator")
+			}
+
+			loaded.authenticator = client.Plugins.Authenticator
+			logging.FromContext(ctx).Info("Plugin serves the authenticator", "plugin", path)
+		}
+
+		if client.Plugins.Validator != nil {
+			loaded.validators = append(loaded.validators, client.Plugins.Validator)
+			logging.FromContext(ctx).Info("Plugin serves a policy validator", "plugin", path, "validator", client.Plugins.Validator.Name())
+		}
+
+		if client.Plugins.Signer != nil {
+			if loaded.signer != nil {
+				loaded.close()
+
+				return nil, errors.Wrap(pkgerrors.ErrPlugin, "more than one plugin serves the signer")
+			}
+
+			loaded.signer = client.Plugins.Signer
+			logging.FromContext(ctx).Info("Plugin serves the signing backend", "plugin", path, "backend", client.Plugins.Signer.Name())
+		}
+	}
+
+	return loaded, nil
+}
+
+// close stops the plugin binaries.
+func (p *loadedPlugins) close() {
+	for _, client := range p.clients {
+		client.Close()
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/policy"
+	"github.com/clastix/talos-csr-signer/pkg/schedule"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+)
+
+// newPolicy returns the validators of the configured signing policy, run on every CSR after its signature is verified.
+func newPolicy(cfg config.Policy) (policy.Chain, error) {
+	keyPolicy := policy.KeyPolicy{
+		Algorithms: cfg.KeyAlgorithms,
+		MinRSABits: cfg.MinRSABits,
+	}
+
+	sanPolicy := policy.SANPolicy{
+		DNSPatterns:    cfg.DNSNames,
+		StripLocalIPs:  cfg.StripLocalIPs,
+		WildcardAction: cfg.Wildcards,
+		EmailAction:    cfg.EmailAction,
+		EmailPatterns:  cfg.EmailAddresses,
+		URIAction:      cfg.URIAction,
+		URIPatterns:    cfg.URIs,
+	}
+
+	for _, pattern := range cfg.DNSRegexps {
+		// Anchor the expressions, a partial match of a name allowing any name embedding it
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrPolicy, "invalid DNS name regular expression "+pattern+": "+err.Error())
+		}
+
+		sanPolicy.DNSRegexps = append(sanPolicy.DNSRegexps, re)
+	}
+
+	for _, cidr := range cfg.IPRanges {
+		_, ipRange, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrPolicy, "invalid IP range "+cidr)
+		}
+
+		sanPolicy.IPRanges = append(sanPolicy.IPRanges, ipRange)
+	}
+
+	subjectPolicy := policy.SubjectPolicy{
+		Organizations:      cfg.Organizations,
+		StripOrganizations: cfg.OrganizationAction == policy.OrganizationsStrip,
+	}
+
+	if pattern := cfg.CommonName; pattern != "" {
+		commonName, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrPolicy, "invalid Common Name pattern: "+err.Error())
+		}
+
+		subjectPolicy.CommonName = commonName
+	}
+
+	// The oversized CSRs are refused before matching their SANs
+	sizePolicy := policy.SizePolicy{
+		MaxDNSNames:    cfg.MaxDNSNames,
+		MaxIPAddresses: cfg.MaxIPAddresses,
+		MaxExtensions:  cfg.MaxExtensions,
+	}
+
+	chain := policy.Chain{sizePolicy, keyPolicy, sanPolicy, subjectPolicy}
+
+	// The requested extended key usages are ignored, issuing the ones of the profiles, unless some are allowed
+	if len(cfg.ExtKeyUsages) > 0 {
+		extKeyUsages, err := signer.ExtKeyUsages(cfg.ExtKeyUsages)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+
+		chain = chain.Then(policy.UsagePolicy{ExtKeyUsages: extKeyUsages})
+	}
+
+	if cfg.CEL != "" {
+		celPolicy, err := policy.NewCEL(cfg.CEL)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+
+		chain = chain.Then(celPolicy)
+	}
+
+	return chain, nil
+}
+
+// newDNSVerification returns the validator resolving the CSR DNS names, nil when disabled: it's not part of the
+// offline policy, as it depends on the peer address and on the live DNS records.
+func newDNSVerification(cfg config.Policy) (*policy.DNSVerification, error) {
+	if !cfg.DNSVerification {
+		return nil, nil //nolint:nilnil
+	}
+
+	dnsVerification := &policy.DNSVerification{
+		Bypass:        cfg.DNSVerificationBypass,
+		CacheTTL:      cfg.DNSVerificationCacheTTL,
+		SkipWildcards: cfg.Wildcards == policy.WildcardsStrip,
+	}
+
+	for _, cidr := range cfg.DNSVerificationRanges {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrPolicy, "invalid DNS verification range "+cidr)
+		}
+
+		dnsVerification.Networks = append(dnsVerification.Networks, network)
+	}
+
+	return dnsVerification, nil
+}
+
+// newEnrollmentWindow returns the validator restricting the enrollment of new identities to the maintenance windows,
+// nil when no window is configured.
+func newEnrollmentWindow(cfg config.Policy, issuanceLedger ledger.Ledger) (*policy.EnrollmentWindow, error) {
+	windows, err := schedule.ParseList(cfg.EnrollmentWindows)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrPolicy, err.Error())
+	}
+
+	if len(windows) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	location, err := time.LoadLocation(cfg.EnrollmentTimezone)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrPolicy, "invalid enrollment time zone: "+err.Error())
+	}
+
+	return &policy.EnrollmentWindow{Ledger: issuanceLedger, Windows: windows, Location: location}, nil
+}
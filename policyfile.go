@@ -0,0 +1,89 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/policy"
+	"github.com/clastix/talos-csr-signer/pkg/policyfile"
+	"github.com/clastix/talos-csr-signer/pkg/server"
+)
+
+// loadPolicyFile returns the policy file at the configured path, along with the configuration overridden by its
+// settings: without a policy file, the configuration is returned as is.
+func loadPolicyFile(ctx context.Context, v *viper.Viper, cfg *config.Config) (*policyfile.File, *config.Config, error) {
+	path := cfg.Policy.FilePath
+	if path == "" {
+		return nil, cfg, nil
+	}
+
+	file, err := policyfile.Load(path)
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck
+	}
+
+	overridden, err := config.Override(v, file.Settings())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "policy file "+path)
+	}
+
+	logging.FromContext(ctx).Info("Loaded the policy file", "path", path)
+
+	return file, overridden, nil
+}
+
+// watchPolicyFile reloads the policy file when modified, or on SIGHUP, until the context is done, replacing the
+// signing policy, the profiles, and the limits the server issues the certificates with. A policy file failing to load
+// is ignored, keeping the previous one.
+func watchPolicyFile(ctx context.Context, v *viper.Viper, cfg *config.Config, current *policyfile.File, srv *server.Server, validators []policy.Validator) {
+	path := cfg.Policy.FilePath
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	defer signal.Stop(hangup)
+
+	policyfile.Watch(ctx, path, cfg.Policy.FileReloadInterval, current, hangup, func(file *policyfile.File) {
+		if err := reloadPolicyFile(v, file, srv, validators); err != nil {
+			logging.FromContext(ctx).Warn("Failed to reload the policy file, keeping the previous one", "path", path, "error", err)
+
+			return
+		}
+
+		logging.FromContext(ctx).Info("Reloaded the policy file", "path", path)
+	})
+}
+
+// reloadPolicyFile replaces the signing policy, the profiles, and the limits of the server with the ones of the policy
+// file, once all of them are valid.
+func reloadPolicyFile(v *viper.Viper, file *policyfile.File, srv *server.Server, validators []policy.Validator) error {
+	cfg, err := config.Override(v, file.Settings())
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	signingPolicy, roles, err := newSigningPolicy(cfg, srv.Ledger, validators)
+	if err != nil {
+		return err
+	}
+
+	srv.Reload(signingPolicy, roles)
+	srv.ReloadLimits(server.Limits{
+		MaxTTL:          cfg.Issuance.MaxTTL,
+		IssuanceQuota:   cfg.Issuance.Quota,
+		QuotaWindow:     cfg.Issuance.QuotaWindow,
+		ReissueCooldown: cfg.Issuance.ReissueCooldown,
+	})
+
+	return nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"os"
+	"time"
+
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/preflight"
+)
+
+// expiryWarning is how long before its expiration the serving certificate is reported by the startup checks.
+const expiryWarning = 30 * 24 * time.Hour
+
+// runPreflight runs all the startup checks, collecting their outcome rather than failing at the first one.
+// The CA files are not checked when the CA is held elsewhere, such as by the signer plugin: caHolder names it, and
+// only the certificate of the held CA is checked, its private key being checked when loaded, if held locally.
+func runPreflight(ctx context.Context, cfg *config.Config, caHolder string, heldCA backend.Backend) *preflight.Report {
+	report := &preflight.Report{}
+
+	checkPaths(report, cfg, caHolder != "")
+
+	if caHolder != "" {
+		report.Skip("ca", "the CA is held by the %s", caHolder)
+		checkCACertificate(report, "ca", heldCA.Certificate(), cfg.CA.ExpiryWarning)
+	} else {
+		checkCA(report, "ca", cfg.CA.CertificatePath, cfg.CA.PrivateKeyPath, cfg.CA.ExpiryWarning)
+	}
+
+	if fallbackKeyPath := cfg.CA.FallbackPrivateKeyPath; fallbackKeyPath != "" {
+		fallbackCertPath := cfg.CA.FallbackCertificatePath
+		if fallbackCertPath == "" {
+			fallbackCertPath = cfg.CA.CertificatePath
+		}
+
+		checkCA(report, "fallback-ca", fallbackCertPath, fallbackKeyPath, cfg.CA.ExpiryWarning)
+	} else {
+		report.Skip("fallback-ca", "no fallback CA configured")
+	}
+
+	checkTLS(report, cfg.Server)
+	checkClientCA(report, cfg.Server.ClientCAPath)
+	checkLedger(ctx, report, cfg.Ledger)
+
+	return report
+}
+
+// checkPaths verifies the configured files are readable.
+func checkPaths(report *preflight.Report, cfg *config.Config, heldCA bool) {
+	paths := [][2]string{
+		{config.KeyTLSCertificatePath, cfg.Server.TLSCertificatePath},
+		{config.KeyTLSPrivateKeyPath, cfg.Server.TLSPrivateKeyPath},
+		{config.KeyCABundlePath, cfg.CA.BundlePath},
+		{config.KeyCAChainPath, cfg.CA.ChainPath},
+		{config.KeyCAPKCS12Path, cfg.CA.PKCS12Path},
+		{config.KeyFallbackCACertificatePath, cfg.CA.FallbackCertificatePath},
+		{config.KeyFallbackCAPrivateKeyPath, cfg.CA.FallbackPrivateKeyPath},
+		{config.KeyCAHybridKeyPath, cfg.CA.HybridKeyPath},
+		{config.KeyClientCAPath, cfg.Server.ClientCAPath},
+		{config.KeyTPMEndorsementRootsPath, cfg.Issuance.TPMEndorsementRoots},
+		{config.KeyInstanceIdentityAWSCerts, cfg.Instance.AWSCertsPath},
+		{config.KeyProfilesPath, cfg.Roles.ProfilesPath},
+		{config.KeyPolicyFilePath, cfg.Policy.FilePath},
+		{config.KeyDenyListPath, cfg.Policy.DenyListPath},
+		{config.KeyTokenClassesPath, cfg.Tokens.ClassesPath},
+		{config.KeyExtraSANsPath, cfg.Issuance.ExtraSANsPath},
+	}
+	for _, rootsPath := range cfg.CA.ExtraRootsPaths {
+		paths = append(paths, [2]string{config.KeyCAExtraRootsPath, rootsPath})
+	}
+
+	if !heldCA {
+		paths = append([][2]string{
+			{config.KeyCACertificatePath, cfg.CA.CertificatePath},
+			{config.KeyCAPrivateKeyPath, cfg.CA.PrivateKeyPath},
+		}, paths...)
+	}
+
+	for _, keyPath := range paths {
+		key, path := keyPath[0], keyPath[1]
+		if path == "" {
+			continue
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			report.Fail("path/"+key, "%v", err)
+
+			continue
+		}
+
+		_ = file.Close()
+
+		report.Pass("path/"+key, "%s is readable", path)
+	}
+}
+
+// checkCA verifies the CA certificate is usable to sign and matches its private key.
+func checkCA(report *preflight.Report, name, certPath, keyPath string, expiryWarning time.Duration) {
+	caBackend, err := loadLocalBackend(name, certPath, keyPath)
+	if err != nil {
+		report.Fail(name, "%v", err)
+
+		return
+	}
+
+	report.Pass(name, "private key matches the certificate %q", caBackend.Certificate().Subject)
+
+	checkCACertificate(report, name, caBackend.Certificate(), expiryWarning)
+}
+
+// checkCACertificate verifies the CA certificate is currently valid and allowed to sign certificates.
+func checkCACertificate(report *preflight.Report, name string, caCert *x509.Certificate, expiryWarning time.Duration) {
+	checkValidity(report, name, caCert, expiryWarning)
+
+	if !caCert.IsCA || caCert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		report.Fail(name+"/usage", "certificate %q is not marked as a CA allowed to sign certificates", caCert.Subject)
+	} else {
+		report.Pass(name+"/usage", "certificate %q can sign certificates", caCert.Subject)
+	}
+}
+
+// checkTLS verifies the serving certificate and its private key.
+func checkTLS(report *preflight.Report, cfg config.Server) {
+	pair, err := loadX509KeyPair(cfg.TLSCertificatePath, cfg.TLSPrivateKeyPath)
+	if err != nil {
+		report.Fail("tls", "%v", err)
+
+		return
+	}
+
+	checkValidity(report, "tls", pair.Leaf, expiryWarning)
+}
+
+// checkClientCA verifies the bundle used to verify the client certificates.
+func checkClientCA(report *preflight.Report, clientCAPath string) {
+	if clientCAPath == "" {
+		report.Skip("client-ca", "client certificates are not verified")
+
+		return
+	}
+
+	clientCAPEM, err := os.ReadFile(clientCAPath)
+	if err != nil {
+		report.Fail("client-ca", "%v", err)
+
+		return
+	}
+
+	if !x509.NewCertPool().AppendCertsFromPEM(clientCAPEM) {
+		report.Fail("client-ca", "no PEM certificate found in %s", clientCAPath)
+
+		return
+	}
+
+	report.Pass("client-ca", "loaded from %s", clientCAPath)
+}
+
+// checkValidity verifies the certificate is currently valid, warning when it expires within the given window.
+func checkValidity(report *preflight.Report, name string, cert *x509.Certificate, expiryWarning time.Duration) {
+	now := time.Now()
+
+	switch {
+	case now.Before(cert.NotBefore):
+		report.Fail(name+"/validity", "certificate %q is not valid before %s", cert.Subject, cert.NotBefore.Format(time.RFC3339))
+	case now.After(cert.NotAfter):
+		report.Fail(name+"/validity", "certificate %q expired at %s", cert.Subject, cert.NotAfter.Format(time.RFC3339))
+	case cert.NotAfter.Sub(now) < expiryWarning:
+		report.Warn(name+"/validity", "certificate %q expires soon, at %s", cert.Subject, cert.NotAfter.Format(time.RFC3339))
+	default:
+		report.Pass(name+"/validity", "certificate %q valid until %s", cert.Subject, cert.NotAfter.Format(time.RFC3339))
+	}
+}
+
+// checkLedger verifies the ledger is reachable.
+func checkLedger(ctx context.Context, report *preflight.Report, cfg config.Ledger) {
+	ledgerURL := cfg.URL
+
+	issuanceLedger, err := ledger.New(ledgerURL, cfg.KeyPrefix)
+	if err != nil {
+		report.Fail("ledger", "%v", err)
+
+		return
+	}
+	defer func() { _ = issuanceLedger.Close() }()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, _, err = issuanceLedger.CachedResponse(ctx, "preflight"); err != nil {
+		report.Fail("ledger", "%v", err)
+
+		return
+	}
+
+	report.Pass("ledger", "%s is reachable", redactedURL(ledgerURL))
+}
@@ -0,0 +1,327 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+)
+
+// newRoles returns the detection of the machine roles, along with the profiles of the certificates issued per role.
+func newRoles(cfg config.Roles) (*signer.Roles, error) {
+	roles := &signer.Roles{
+		ControlPlane:              signer.DefaultProfile,
+		Worker:                    signer.DefaultProfile,
+		ControlPlaneOrganizations: cfg.ControlPlaneOrganizations,
+	}
+
+	if pattern := cfg.ControlPlaneCommonName; pattern != "" {
+		commonName, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrProfile, "invalid control-plane Common Name pattern: "+err.Error())
+		}
+
+		roles.ControlPlaneCommonName = commonName
+	}
+
+	var err error
+
+	roles.ControlPlane.Validity = cfg.ControlPlaneValidity
+	if roles.ControlPlane.ExtKeyUsage, err = signer.ExtKeyUsages(cfg.ControlPlaneUsages); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if roles.ControlPlane.KeyAlgorithms, err = signer.KeyAlgorithms(cfg.ControlPlaneKeyAlgorithms); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	roles.Worker.Validity = cfg.WorkerValidity
+	if roles.Worker.ExtKeyUsage, err = signer.ExtKeyUsages(cfg.WorkerUsages); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if roles.Worker.KeyAlgorithms, err = signer.KeyAlgorithms(cfg.WorkerKeyAlgorithms); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if cfg.ProfilesPath != "" {
+		if roles.Profiles, err = loadProfiles(cfg.ProfilesPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return roles, nil
+}
+
+// newSubjectRules returns the rules rewriting the CSR subject: the organizations replacing the CSR ones, the subject
+// fields left out, and the template deriving the Common Name.
+func newSubjectRules(organizations, drop []string, commonNameTemplate string) (signer.SubjectRules, error) {
+	rules := signer.SubjectRules{Organizations: organizations}
+
+	var err error
+
+	if rules.Drop, err = signer.SubjectFields(drop); err != nil {
+		return signer.SubjectRules{}, err //nolint:wrapcheck
+	}
+
+	if commonNameTemplate != "" {
+		if rules.CommonName, err = signer.CommonNameTemplate(commonNameTemplate); err != nil {
+			return signer.SubjectRules{}, err //nolint:wrapcheck
+		}
+	}
+
+	return rules, nil
+}
+
+// profileSettings are the settings of the named profiles.
+var profileSettings = []string{
+	"validity", "usages", "key-usages", "key-algorithms", "common-name", "organizations",
+	"subject-organizations", "subject-drop", "common-name-template", "extensions", "ca", "spiffe-id",
+}
+
+// loadProfiles returns the named profiles of the YAML file, keyed by their lowercase name, such as:
+//
+//	client:
+//	  usages: client
+//	  validity: 24h
+//	dual:
+//	  usages: server,client
+//	  key-usages: digital-signature
+//	  common-name: ^admin-
+//	  organizations: os:reader
+//	  subject-drop: organizational-unit
+//	  common-name-template: admin:{{.CommonName}}
+//	  extensions: 1.3.6.1.4.1.99999.2=utf8:admin
+//	regional:
+//	  ca: true
+//	  validity: 8760h
+//	mesh:
+//	  usages: server,client
+//	  spiffe-id: spiffe://example.com/talos/{{.CommonName}}
+//
+// The settings are the ones of the machine roles, along with the key usages, the pattern the CSR Common Name must
+// match, the only organizations kept in the certificate, the rules rewriting its subject, the extensions added to the
+// certificate, whether it is a subordinate CA one, and the template of its SPIFFE ID: the missing ones are the ones of
+// the default profile.
+func loadProfiles(path string) (map[string]signer.Profile, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrProfile, "failed to read the profiles file "+path+": "+err.Error())
+	}
+
+	profiles := make(map[string]signer.Profile)
+
+	for name := range v.AllSettings() {
+		settings := v.Sub(name)
+		if settings == nil {
+			return nil, errors.Wrap(pkgerrors.ErrProfile, "the profile "+name+" holds no settings")
+		}
+
+		profile, err := newNamedProfile(settings)
+		if err != nil {
+			return nil, errors.Wrap(err, "profile "+name)
+		}
+
+		profiles[name] = profile
+	}
+
+	return profiles, nil
+}
+
+// newNamedProfile returns the named profile of the settings.
+func newNamedProfile(settings *viper.Viper) (signer.Profile, error) {
+	for _, key := range settings.AllKeys() {
+		if !slices.Contains(profileSettings, key) {
+			return signer.Profile{}, errors.Wrap(pkgerrors.ErrProfile, "unknown setting "+key)
+		}
+	}
+
+	// The lists are either YAML sequences or comma separated strings
+	list := func(key string) []string {
+		return config.SplitList(strings.Join(settings.GetStringSlice(key), ","))
+	}
+
+	profile := signer.DefaultProfile
+
+	var err error
+
+	if settings.IsSet("validity") {
+		if profile.Validity, err = time.ParseDuration(settings.GetString("validity")); err != nil || profile.Validity <= 0 {
+			return signer.Profile{}, errors.Wrap(pkgerrors.ErrProfile, "invalid validity "+settings.GetString("validity"))
+		}
+	}
+
+	if settings.IsSet("usages") {
+		if profile.ExtKeyUsage, err = signer.ExtKeyUsages(list("usages")); err != nil {
+			return signer.Profile{}, err //nolint:wrapcheck
+		}
+	}
+
+	if settings.IsSet("key-usages") {
+		if profile.KeyUsage, err = signer.KeyUsages(list("key-usages")); err != nil {
+			return signer.Profile{}, err //nolint:wrapcheck
+		}
+	}
+
+	if profile.KeyAlgorithms, err = signer.KeyAlgorithms(list("key-algorithms")); err != nil {
+		return signer.Profile{}, err //nolint:wrapcheck
+	}
+
+	if pattern := settings.GetString("common-name"); pattern != "" {
+		if profile.CommonName, err = regexp.Compile(pattern); err != nil {
+			return signer.Profile{}, errors.Wrap(pkgerrors.ErrProfile, "invalid Common Name pattern: "+err.Error())
+		}
+	}
+
+	profile.Organizations = list("organizations")
+
+	if profile.Subject, err = newSubjectRules(list("subject-organizations"), list("subject-drop"),
+		settings.GetString("common-name-template")); err != nil {
+		return signer.Profile{}, err
+	}
+
+	if profile.ExtraExtensions, err = signer.Extensions(list("extensions")); err != nil {
+		return signer.Profile{}, err //nolint:wrapcheck
+	}
+
+	// The subordinate CAs sign the certificates of any usage, unless restricted by the extended key usages
+	if profile.CA = settings.GetBool("ca"); profile.CA {
+		if settings.IsSet("key-usages") {
+			return signer.Profile{}, errors.Wrap(pkgerrors.ErrProfile, "the key usages of a CA profile cannot be set")
+		}
+
+		profile.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+		if !settings.IsSet("usages") {
+			profile.ExtKeyUsage = nil
+		}
+	}
+
+	if text := settings.GetString("spiffe-id"); text != "" {
+		if profile.SPIFFEID, err = newSPIFFEID(text, profile); err != nil {
+			return signer.Profile{}, err
+		}
+	}
+
+	return profile, nil
+}
+
+// newSPIFFEID returns the template of the SPIFFE ID of the profile certificates, refusing the profiles whose
+// certificates cannot be X.509 SVIDs: the CA ones, and the ones without the digital signature key usage.
+func newSPIFFEID(text string, profile signer.Profile) (*template.Template, error) {
+	switch {
+	case profile.CA:
+		return nil, errors.Wrap(pkgerrors.ErrProfile, "the certificates of a CA profile cannot hold a SPIFFE ID")
+	case profile.KeyUsage&x509.KeyUsageDigitalSignature == 0:
+		return nil, errors.Wrap(pkgerrors.ErrProfile, "the SPIFFE ID requires the digital-signature key usage")
+	}
+
+	return signer.SPIFFEIDTemplate(text) //nolint:wrapcheck
+}
+
+// extKeyUsageNames returns the names of the extended key usages, as configured.
+func extKeyUsageNames(usages []x509.ExtKeyUsage) []string {
+	names := make([]string, 0, len(usages))
+
+	for _, usage := range usages {
+		names = append(names, signer.ExtKeyUsageName(usage))
+	}
+
+	return names
+}
+
+// newExtensions returns the extensions added to the certificates of every profile, the certificate policies included,
+// refusing the one of the node UUID.
+func newExtensions(cfg config.Issuance) ([]pkix.Extension, error) {
+	extensions, err := signer.Extensions(cfg.Extensions)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if len(cfg.CertificatePolicies) > 0 || cfg.CertificatePoliciesCPSURI != "" {
+		certificatePolicies, err := signer.CertificatePolicies(cfg.CertificatePolicies, cfg.CertificatePoliciesCPSURI)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+
+		if slices.ContainsFunc(extensions, func(extension pkix.Extension) bool { return extension.Id.Equal(certificatePolicies.Id) }) {
+			return nil, errors.Wrap(pkgerrors.ErrProfile, "the extension 2.5.29.32 cannot be set along with the certificate policies")
+		}
+
+		extensions = append(extensions, certificatePolicies)
+	}
+
+	if oid := cfg.NodeUUIDExtensionOID; oid != "" && slices.ContainsFunc(extensions, func(extension pkix.Extension) bool {
+		return extension.Id.String() == oid
+	}) {
+		return nil, errors.Wrap(pkgerrors.ErrProfile, "the extension "+oid+" embeds the node UUID")
+	}
+
+	return extensions, nil
+}
+
+// mergeExtensions returns the extensions of a profile along with the given ones, the profile ones replacing the given
+// ones of the same OID.
+func mergeExtensions(extensions, profileExtensions []pkix.Extension) []pkix.Extension {
+	merged := slices.DeleteFunc(slices.Clone(extensions), func(extension pkix.Extension) bool {
+		return slices.ContainsFunc(profileExtensions, func(profileExtension pkix.Extension) bool {
+			return profileExtension.Id.Equal(extension.Id)
+		})
+	})
+
+	return append(merged, profileExtensions...)
+}
+
+// checkVerbatimSigning refuses the profiles rewriting the certificates when the CSRs are signed verbatim by Vault or
+// the upstream signer, which would issue the CSR subject and SANs in place of the rewritten ones.
+func checkVerbatimSigning(cfg *config.Config, roles *signer.Roles) error {
+	var holder string
+
+	switch {
+	case cfg.Vault.Address != "":
+		holder = "Vault"
+	case cfg.Upstream.Endpoint != "":
+		holder = "the upstream signer"
+	default:
+		return nil
+	}
+
+	if nodeUUID := cfg.Issuance.NodeUUID; nodeUUID != "" && nodeUUID != "disabled" {
+		return errors.Wrap(pkgerrors.ErrConfig, "the node UUID cannot be embedded by "+holder+" signing the CSRs verbatim")
+	}
+
+	if len(cfg.Issuance.CertificatePolicies) > 0 || cfg.Issuance.CertificatePoliciesCPSURI != "" {
+		return errors.Wrap(pkgerrors.ErrConfig, "the certificate policies cannot be embedded by "+holder+
+			" signing the CSRs verbatim")
+	}
+
+	profiles := map[string]signer.Profile{"control-plane profile": roles.ControlPlane, "worker profile": roles.Worker}
+	for name, profile := range roles.Profiles {
+		profiles["profile "+name] = profile
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(profiles)) {
+		if rewrites := profiles[name].Rewrites(); len(rewrites) > 0 {
+			return errors.Wrap(pkgerrors.ErrConfig, "the "+name+" rewrites the "+strings.Join(rewrites, ", ")+
+				" of the certificates, which "+holder+" signing the CSRs verbatim cannot honor")
+		}
+	}
+
+	return nil
+}
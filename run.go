@@ -0,0 +1,554 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	channelzservice "google.golang.org/grpc/channelz/service"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/clastix/talos-csr-signer/pkg/admin"
+	"github.com/clastix/talos-csr-signer/pkg/bundle"
+	"github.com/clastix/talos-csr-signer/pkg/clock"
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	"github.com/clastix/talos-csr-signer/pkg/crl"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/events"
+	"github.com/clastix/talos-csr-signer/pkg/features"
+	"github.com/clastix/talos-csr-signer/pkg/journal"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/metrics"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+	"github.com/clastix/talos-csr-signer/pkg/policyfile"
+	pb "github.com/clastix/talos-csr-signer/pkg/proto"
+	"github.com/clastix/talos-csr-signer/pkg/server"
+	"github.com/clastix/talos-csr-signer/pkg/standby"
+	"github.com/clastix/talos-csr-signer/pkg/systemd"
+	"github.com/clastix/talos-csr-signer/pkg/version"
+	"github.com/clastix/talos-csr-signer/pkg/watchdog"
+)
+
+// run serves the certificate requests of the configuration, along with the ones of the clusters, until the context
+// is done.
+func run(ctx context.Context, cfg *config.Config, clusters []config.Cluster) error {
+	defer setupLogFile(cfg.Log)()
+
+	buildInfo := version.Get()
+	logging.FromContext(ctx).Info("Starting Talos CSR Signer", "version", buildInfo.String())
+	metrics.BuildInfo.WithLabelValues(buildInfo.Version, buildInfo.GitCommit, buildInfo.BuildDate, buildInfo.GoVersion).Set(1)
+
+	gates, err := features.Parse(cfg.Server.FeatureGates)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	logFeatureGates(ctx, gates)
+
+	// Start the plugins, which may hold the CA key material in place of the mounted files
+	plugins, err := loadPlugins(ctx, cfg.Server.Plugins)
+	if err != nil {
+		return err
+	}
+	defer plugins.close()
+
+	// Wait for the mounted secrets, which may show up late during the cluster bring-up
+	if timeout := cfg.Server.StartupWaitTimeout; timeout > 0 {
+		if err = waitForFiles(ctx, timeout, startupFiles(cfg, plugins)...); err != nil {
+			return err
+		}
+	}
+
+	// Override the settings with the ones of the configuration bundle, which may also hold the CA and the tokens
+	configBundle, cfg, err := loadBundle(ctx, viper.GetViper(), cfg)
+	if err != nil {
+		return err
+	}
+
+	// Override the policy settings with the ones of the policy file, reloaded while serving
+	policyFile, cfg, err := loadPolicyFile(ctx, viper.GetViper(), cfg)
+	if err != nil {
+		return err
+	}
+
+	sources, err := loadCASources(ctx, cfg, configBundle, plugins)
+	if err != nil {
+		return err
+	}
+
+	if err = sources.validate(cfg); err != nil {
+		return err
+	}
+
+	heldCA, caHolder, closeCA, err := sources.backend(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeCA()
+
+	// Run all the startup checks before loading anything, reporting them as a checklist
+	report := runPreflight(ctx, cfg, caHolder, heldCA)
+	report.Log(ctx)
+
+	if err = report.Err(cfg.Server.StrictStartup); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	// Load the CA signing backend, guarded by a fallback one when configured
+	signingBackend, err := newSigningBackend(cfg.CA, heldCA)
+	if err != nil {
+		return err
+	}
+
+	// Add the post-quantum signature of the hybrid key to the certificates, along with the hybrid CA
+	if signingBackend, err = newHybridBackend(ctx, cfg.CA, gates, signingBackend); err != nil {
+		return err
+	}
+
+	creds, err := newServerCredentials(cfg.Server)
+	if err != nil {
+		return err
+	}
+
+	issuanceLedger, err := openLedger(ctx, cfg.Ledger)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = issuanceLedger.Close() }()
+
+	// Create gRPC Server with TLS
+	srv, err := newServer(ctx, cfg, signingBackend, issuanceLedger, plugins)
+	if err != nil {
+		return err
+	}
+
+	srv.Features = gates
+
+	caURLBundle, err := configureServer(ctx, cfg, gates, srv, sources, issuanceLedger)
+	if err != nil {
+		return err
+	}
+
+	// Fan out the certificate lifecycle events, published to Kafka or NATS when configured
+	srv.Events = events.NewBus()
+	defer srv.Events.Close()
+
+	if sinkURL := cfg.Events.SinkURL; sinkURL != "" {
+		publisher, publisherErr := newEventPublisher(ctx, srv.Events, cfg.Events)
+		if publisherErr != nil {
+			return publisherErr
+		}
+		defer func() { _ = publisher.Close() }()
+	}
+
+	if err = watchSources(ctx, cfg, srv, sources, configBundle, policyFile, caURLBundle); err != nil {
+		return err
+	}
+
+	crlCache, err := newCRLCache(ctx, cfg, gates, sources, issuanceLedger)
+	if err != nil {
+		return err
+	}
+
+	// Check the clock sanity at startup and periodically
+	clockChecker, err := clock.NewChecker(signingBackend.Certificate(), cfg.Clock.NTPServer, cfg.Clock.MaxSkew, cfg.Clock.SkewAction)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	_ = clockChecker.Check(ctx)
+	srv.Clock = clockChecker
+
+	go clockChecker.Run(ctx, cfg.Clock.CheckInterval)
+
+	// Replay the signings interrupted by a previous restart
+	if journalDir := cfg.Server.JournalDir; journalDir != "" {
+		pendingJournal, journalErr := journal.Open(journalDir)
+		if journalErr != nil {
+			return journalErr //nolint:wrapcheck
+		}
+
+		srv.Journal = pendingJournal
+
+		if err = srv.ReplayJournal(ctx); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	lis, adminLis, err := listen(ctx, cfg.Server.Port)
+	if err != nil {
+		return err
+	}
+
+	grpcServer := newGRPCServer(ctx, cfg, creds, srv)
+
+	if err = registerSecurityService(ctx, cfg, gates, grpcServer, srv, plugins, issuanceLedger); err != nil {
+		return err
+	}
+
+	// Admin API, used by the operators to inspect and manage the running signer
+	if cfg.Admin.Address != "" || adminLis != nil {
+		if err = serveAdmin(ctx, cfg, gates, srv, issuanceLedger, crlCache, adminLis); err != nil {
+			return err
+		}
+	}
+
+	// Serve the clusters declared in the configuration file along with the top level one, each on its own listener
+	for _, cluster := range clusters {
+		clusterSrv, clusterErr := newClusterServer(ctx, cluster, gates, srv.Events)
+		if clusterErr != nil {
+			return clusterErr
+		}
+		defer clusterSrv.close()
+
+		go clusterSrv.serve(ctx)
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		logging.FromContext(ctx).Info("Shutting down, draining the in-flight requests")
+		_, _ = systemd.Notify(systemd.StateStopping)
+		grpcServer.GracefulStop()
+	}()
+
+	// Notify systemd once serving, pinging its watchdog while the internal one is not tripped
+	if notified, notifyErr := systemd.Notify(systemd.StateReady); notifyErr != nil {
+		logging.FromContext(ctx).Warn("Failed to notify systemd", "error", notifyErr)
+	} else if notified {
+		go systemd.RunWatchdog(ctx, func() bool { return !srv.Watchdog.Tripped() })
+	}
+
+	logging.FromContext(ctx).Info("Talos CSR Signer listening with TLS enabled", "address", lis.Addr().String())
+
+	if err = grpcServer.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return errors.Wrap(pkgerrors.ErrGRPCServerServe, err.Error())
+	}
+
+	return nil
+}
+
+// setupLogFile writes the logs to a rotating file when stdout is not collected, returning the function closing it.
+func setupLogFile(cfg config.Log) func() {
+	if cfg.File == "" {
+		return func() {}
+	}
+
+	logger := &lumberjack.Logger{
+		Filename:   cfg.File,
+		MaxSize:    cfg.MaxSize,
+		MaxAge:     cfg.MaxAge,
+		MaxBackups: cfg.MaxBackups,
+		Compress:   cfg.Compress,
+	}
+
+	log.SetOutput(logger)
+
+	return func() { _ = logger.Close() }
+}
+
+// logFeatureGates logs the feature gates, exporting them as metrics.
+func logFeatureGates(ctx context.Context, gates *features.Gates) {
+	for feature, enabled := range gates.All() {
+		logging.FromContext(ctx).Info("Feature gate", "feature", string(feature), "enabled", enabled)
+
+		gauge := metrics.FeatureEnabled.WithLabelValues(string(feature), features.Known[feature].Stage)
+		if enabled {
+			gauge.Set(1)
+		} else {
+			gauge.Set(0)
+		}
+	}
+}
+
+// openLedger opens the ledger, shared across replicas when backed by Redis, snapshotting and pruning it in the
+// background when configured.
+func openLedger(ctx context.Context, cfg config.Ledger) (ledger.Ledger, error) {
+	issuanceLedger, err := ledger.New(cfg.URL, cfg.KeyPrefix)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if interval := cfg.SnapshotInterval; interval > 0 {
+		go snapshotLedger(ctx, issuanceLedger, cfg.SnapshotDir, interval, cfg.SnapshotRetain)
+	}
+
+	if retention := ledgerRetention(cfg); retention.MaxAge > 0 || retention.MaxRecords > 0 {
+		go pruneLedger(ctx, issuanceLedger, retention, cfg.PruneInterval)
+	}
+
+	return issuanceLedger, nil
+}
+
+// configureServer sets the CA chain, the trust bundle, the approval queue, and the warm standby of the server,
+// returning the CA bundle of the artifact server, nil when not configured.
+func configureServer(ctx context.Context, cfg *config.Config, gates *features.Gates, srv *server.Server, sources *caSources, issuanceLedger ledger.Ledger) (*urlBundle, error) {
+	// Intermediate signing CA of the PKCS#12 bundle, returned to the nodes along with its chain up to the root
+	if sources.pkcs12 != nil && len(sources.pkcs12.chain) > 0 {
+		srv.CAChain = pki.EncodeCertificates(sources.pkcs12.chain...)
+	}
+
+	// CA bundle of the artifact server, returned to the nodes along with the signing CA
+	caURLBundle, err := newURLBundle(ctx, cfg.CA)
+	if err != nil {
+		return nil, err
+	}
+
+	if caURLBundle != nil {
+		srv.TrustBundle = caURLBundle.data
+	}
+
+	// Hold the privileged certificates until M of the N approvers approved them through the admin API
+	if gates.Enabled(features.ApprovalQueue) {
+		if srv.Approvals, err = newApprovalQueue(cfg.Approval, issuanceLedger); err != nil {
+			return nil, err
+		}
+
+		if srv.Approvals != nil {
+			logging.FromContext(ctx).Info("Holding the certificates of the organizations until approved",
+				"organizations", cfg.Approval.Organizations, "threshold", cfg.Approval.Threshold,
+				"approvers", len(srv.Approvals.Approvers))
+		}
+	}
+
+	// Replicate the primary signer while a warm standby, refusing the certificate requests until promoted
+	if primaryURL := cfg.Standby.PrimaryURL; primaryURL != "" {
+		srv.Standby = &standby.Replica{
+			PrimaryURL:  primaryURL,
+			Token:       cfg.Standby.PrimaryToken,
+			Ledger:      issuanceLedger,
+			LocalConfig: effectiveConfig(gates),
+		}
+
+		logging.FromContext(ctx).Info("Serving as a warm standby until promoted", "primary", primaryURL)
+	}
+
+	return caURLBundle, nil
+}
+
+// watchSources reloads in the background the configuration bundle, the policy file, the deny-list, the Open Policy
+// Agent bundle, and the CA of its source, replacing them in the server when updated.
+func watchSources(ctx context.Context, cfg *config.Config, srv *server.Server, sources *caSources, configBundle *bundle.Bundle, policyFile *policyfile.File, caURLBundle *urlBundle) error {
+	// Reload the configuration bundle while serving, replacing the CA, the tokens, the policy, and the profiles
+	if configBundle != nil {
+		go watchBundle(ctx, viper.GetViper(), cfg, configBundle, srv, sources.bundle, sources.plugins.validators)
+	}
+	// Reload the policy file when modified or on SIGHUP, replacing the policy, the profiles, and the limits
+	if policyFile != nil {
+		go watchPolicyFile(ctx, viper.GetViper(), cfg, policyFile, srv, sources.plugins.validators)
+	}
+	// Reload the deny-list when modified, locking out the identities without a restart
+	if srv.DenyList != nil {
+		go watchDenyList(ctx, cfg, srv)
+	}
+	// Push the signed policy bundle of the OCI registry to the Open Policy Agent, and again when updated
+	opaBundle, err := newOPABundle(ctx, cfg.Policy)
+	if err != nil {
+		return err
+	}
+
+	if opaBundle != nil && cfg.Policy.OPABundleInterval > 0 {
+		go opaBundle.Run(ctx, cfg.Policy.OPABundleInterval)
+	}
+	// Watch the Secret holding the CA, replacing it when rotated
+	if sources.secret != nil {
+		go sources.secret.watch(ctx, srv)
+	}
+	// Read the secret of the CA source again, replacing the CA and the token when updated
+	if sources.cloud != nil && cfg.CA.SourceRefreshInterval > 0 {
+		go sources.cloud.refresh(ctx, srv, cfg.CA.SourceRefreshInterval)
+	}
+
+	// Fetch the CA bundle of the artifact server again, replacing it when updated
+	if caURLBundle != nil && cfg.CA.CertURLRefreshInterval > 0 {
+		go caURLBundle.refresh(ctx, srv, cfg.CA.CertURLRefreshInterval)
+	}
+
+	return nil
+}
+
+// newCRLCache returns the Certificate Revocation List of the certificates revoked in the ledger, signed by the CA
+// and regenerated in the background, nil when not served.
+func newCRLCache(ctx context.Context, cfg *config.Config, gates *features.Gates, sources *caSources, issuanceLedger ledger.Ledger) (*crl.Cache, error) {
+	if !gates.Enabled(features.CRLServing) {
+		return nil, nil //nolint:nilnil
+	}
+
+	caCert, caKey, err := sources.signingKey(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	crlCache := crl.NewCache(issuanceLedger, caCert, caKey, cfg.CRL.Validity)
+	if err = crlCache.Regenerate(ctx); err != nil {
+		logging.FromContext(ctx).Error("Failed to generate the CRL", "error", err)
+	}
+
+	go crlCache.Run(ctx, cfg.CRL.Interval)
+
+	return crlCache, nil
+}
+
+// listen returns the listener of the gRPC server, the socket passed by systemd when socket activated, along with the
+// admin one passed by systemd, nil when none.
+func listen(ctx context.Context, port int) (net.Listener, net.Listener, error) {
+	// Serve on the sockets passed by systemd when socket activated: the admin one is named admin
+	activated, err := systemd.Listeners()
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck
+	}
+
+	adminLis := activated["admin"]
+	delete(activated, "admin")
+
+	lis := activated["grpc"]
+
+	if lis == nil {
+		lis = activated["0"]
+	}
+
+	if lis == nil {
+		if lis, err = net.Listen("tcp", fmt.Sprintf(":%d", port)); err != nil {
+			return nil, nil, errors.Wrap(pkgerrors.ErrServerListen, fmt.Sprintf("%d: %s", port, err.Error()))
+		}
+	} else {
+		logging.FromContext(ctx).Info("Serving on the socket passed by systemd", "address", lis.Addr().String())
+	}
+
+	return lis, adminLis, nil
+}
+
+// newGRPCServer returns the gRPC server with TLS, along with its health checking, flipped to SERVING once the warm
+// standby is promoted and to NOT_SERVING by the watchdog.
+func newGRPCServer(ctx context.Context, cfg *config.Config, creds credentials.TransportCredentials, srv *server.Server) *grpc.Server {
+	// Bound the connections lifetime: GOAWAY makes the nodes reconnect, rebalancing them across replicas after a scale-out
+	grpcServer := grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionAge:      cfg.Server.MaxConnectionAge,
+			MaxConnectionAgeGrace: cfg.Server.MaxConnectionAgeGrace,
+		}),
+	)
+	// Health checking, flipped to NOT_SERVING by the watchdog
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	if srv.Standby != nil {
+		healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+		srv.Standby.OnPromote = func() {
+			healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+		}
+
+		go srv.Standby.Run(ctx, cfg.Standby.SyncInterval)
+	}
+
+	if threshold := cfg.Watchdog.Threshold; threshold > 0 {
+		exitOnTrip := cfg.Watchdog.Exit
+
+		srv.Watchdog = watchdog.New(threshold, func(error) {
+			healthServer.Shutdown()
+
+			if exitOnTrip {
+				logging.FromContext(ctx).Error("Exiting to get the instance replaced", "code", watchdog.ExitCode)
+				os.Exit(watchdog.ExitCode)
+			}
+		})
+	}
+
+	return grpcServer
+}
+
+// registerSecurityService registers the server on the gRPC one, or the router of the tenant clusters when serving
+// many tenants on the same listener.
+func registerSecurityService(ctx context.Context, cfg *config.Config, gates *features.Gates, grpcServer *grpc.Server, srv *server.Server, plugins *loadedPlugins, issuanceLedger ledger.Ledger) error {
+	// Route the requests to the CA of their tenant cluster, serving many tenants on the same listener
+	if cfg.CA.Dir != "" && !gates.Enabled(features.MultiTenantRouting) {
+		return errors.Wrap(pkgerrors.ErrConfig, config.KeyCADir+" requires the "+string(features.MultiTenantRouting)+" feature gate")
+	}
+
+	sharder, err := newSharder(ctx, cfg, issuanceLedger)
+	if err != nil {
+		return err
+	}
+
+	tenants, err := newTenantCAs(ctx, cfg, srv, plugins, sharder)
+	if err != nil {
+		return err
+	}
+
+	if tenants == nil {
+		pb.RegisterSecurityServiceServer(grpcServer, srv)
+
+		return nil
+	}
+
+	pb.RegisterSecurityServiceServer(grpcServer, tenants.router)
+
+	if cfg.CA.DirRefreshInterval > 0 || sharder != nil {
+		go tenants.refresh(ctx, cfg.CA.DirRefreshInterval)
+	}
+
+	return nil
+}
+
+// serveAdmin serves the admin API in the background on the listener passed by systemd, or on the configured address
+// when none.
+func serveAdmin(ctx context.Context, cfg *config.Config, gates *features.Gates, srv *server.Server, issuanceLedger ledger.Ledger, crlCache *crl.Cache, adminLis net.Listener) error {
+	adminServer := admin.New(cfg.Admin.Token)
+	adminServer.HandleFunc("GET /config", configHandler(gates))
+	adminServer.Handle("GET /metrics", metrics.Handler())
+	adminServer.HandleFunc("GET /version", versionHandler)
+	adminServer.HandleFunc("GET /ca", caHandler(srv))
+	adminServer.HandleFunc("GET /certificates", certificatesHandler(issuanceLedger))
+	adminServer.HandleFunc("GET /ledger/snapshot", ledgerSnapshotHandler(issuanceLedger))
+	adminServer.HandleFunc("POST /revoke", readOnly(srv.Standby, revokeHandler(issuanceLedger, srv.Events, crlCache)))
+
+	if crlCache != nil {
+		adminServer.Handle("GET /crl", crlCache)
+	}
+
+	if srv.Approvals != nil {
+		adminServer.HandleFunc("GET /approvals/{id}", approvalHandler(srv.Approvals))
+		adminServer.HandleFunc("POST /approvals/{id}/approve", readOnly(srv.Standby, approveHandler(srv.Approvals, srv.Events)))
+	}
+
+	if srv.Standby != nil {
+		adminServer.HandleFunc("GET /standby", standbyHandler(srv.Standby))
+		adminServer.HandleFunc("POST /standby/promote", promoteHandler(srv.Standby))
+	}
+
+	// Inspect the live connections, streams, and sockets of the process, such as with grpcdebug
+	if cfg.Admin.Channelz {
+		channelzServer := grpc.NewServer()
+		channelzservice.RegisterChannelzServiceToServer(channelzServer)
+		adminServer.HandleGRPC(channelzServer)
+	}
+
+	if adminLis == nil {
+		var err error
+		if adminLis, err = net.Listen("tcp", cfg.Admin.Address); err != nil {
+			return errors.Wrap(pkgerrors.ErrServerListen, fmt.Sprintf("%s: %s", cfg.Admin.Address, err.Error()))
+		}
+	}
+
+	go func() {
+		if err := adminServer.Serve(ctx, adminLis); err != nil {
+			logging.FromContext(ctx).Error("Failed to serve the admin API", "error", err)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/events"
+	"github.com/clastix/talos-csr-signer/pkg/kube"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/server"
+)
+
+// secretRetryInterval is the interval the Secret holding the CA is read again after a failure.
+const secretRetryInterval = 10 * time.Second
+
+// secretCA is the CA held by a Kubernetes Secret, replaced while serving when the Secret is updated.
+type secretCA struct {
+	client    *kube.Client
+	namespace string
+	name      string
+	ca        *backend.Reloadable
+}
+
+// newSecretCA reads the CA of the namespace/name Secret with the in-cluster client, nil when not configured.
+func newSecretCA(ctx context.Context, ref string) (*secretCA, error) {
+	if ref == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	namespace, name, err := kube.ParseRef(ref)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	client, err := kube.InCluster()
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	secret, err := client.GetSecret(ctx, namespace, name)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	local, _, err := parseSecretCA(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Loaded the CA from the Secret", "secret", ref, "serial", local.Certificate().SerialNumber.Text(16))
+
+	return &secretCA{client: client, namespace: namespace, name: name, ca: backend.NewReloadable(local)}, nil
+}
+
+// load reads the CA certificate and its private key from the Secret.
+func (s *secretCA) load(ctx context.Context) (*x509.Certificate, crypto.Signer, error) {
+	secret, err := s.client.GetSecret(ctx, s.namespace, s.name)
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck
+	}
+
+	local, caPrivateKey, err := parseSecretCA(secret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return local.Certificate(), caPrivateKey, nil
+}
+
+// watch replaces the CA when the Secret is updated until the context is done, such as when Kamaji rotates the tenant
+// CA. A Secret failing to parse is ignored, keeping the previous CA.
+func (s *secretCA) watch(ctx context.Context, srv *server.Server) {
+	s.client.WatchSecret(ctx, s.namespace, s.name, secretRetryInterval, func(secret *kube.Secret) {
+		local, _, err := parseSecretCA(secret)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to reload the CA from the Secret, keeping the previous one",
+				"secret", s.namespace+"/"+s.name, "error", err)
+
+			return
+		}
+
+		if local.Certificate().Equal(s.ca.Certificate()) {
+			return
+		}
+
+		s.ca.Replace(local)
+
+		logging.FromContext(ctx).Info("Reloaded the signing CA from the Secret", "secret", s.namespace+"/"+s.name,
+			"serial", local.Certificate().SerialNumber.Text(16))
+		srv.Events.Emit(events.Event{
+			Type:    events.TypeCAReloaded,
+			Serial:  local.Certificate().SerialNumber.Text(16),
+			Backend: local.Name(),
+		})
+	})
+}
+
+// parseSecretCA returns the local backend of the CA held by the ca.crt and ca.key keys of the Secret, or by the
+// tls.crt and tls.key ones of the kubernetes.io/tls Secrets.
+func parseSecretCA(secret *kube.Secret) (*backend.Local, crypto.Signer, error) {
+	return parseCAKeys(secret.Data, pkgerrors.ErrKubernetes, "Secret "+secret.Metadata.Namespace+"/"+secret.Metadata.Name)
+}
+
+// parseCAKeys returns the local backend of the CA held by the ca.crt and ca.key keys of the data, or by the tls.crt
+// and tls.key ones, the missing keys failing with the sentinel error of the source.
+func parseCAKeys(data map[string][]byte, sentinel error, source string) (*backend.Local, crypto.Signer, error) {
+	caCertPEM, caKeyPEM := data["ca.crt"], data["ca.key"]
+	if len(caCertPEM) == 0 || len(caKeyPEM) == 0 {
+		caCertPEM, caKeyPEM = data["tls.crt"], data["tls.key"]
+	}
+
+	if len(caCertPEM) == 0 || len(caKeyPEM) == 0 {
+		return nil, nil, errors.Wrap(sentinel, "the "+source+" holds neither ca.crt and ca.key nor tls.crt and tls.key")
+	}
+
+	caPrivateKey, err := parsePrivateKey(caKeyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	local, err := backend.NewLocal("local", caCertPEM, caPrivateKey)
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck
+	}
+
+	return local, caPrivateKey, nil
+}
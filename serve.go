@@ -0,0 +1,674 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/clastix/talos-csr-signer/pkg/approval"
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	"github.com/clastix/talos-csr-signer/pkg/denylist"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/features"
+	"github.com/clastix/talos-csr-signer/pkg/identity"
+	"github.com/clastix/talos-csr-signer/pkg/kms"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+	"github.com/clastix/talos-csr-signer/pkg/policy"
+	"github.com/clastix/talos-csr-signer/pkg/server"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+	"github.com/clastix/talos-csr-signer/pkg/token"
+	"github.com/clastix/talos-csr-signer/pkg/tpm"
+	"github.com/clastix/talos-csr-signer/pkg/transparency"
+)
+
+// newSigningBackend returns the CA signing backend, the given one when set, such as the plugin signer, and the local
+// CA otherwise, guarded by a fallback one and by the queue when configured.
+func newSigningBackend(cfg config.CA, held backend.Backend) (backend.Backend, error) {
+	var signingBackend, primary backend.Backend
+
+	if held != nil {
+		primary = held
+	} else {
+		local, err := loadLocalBackend("local", cfg.CertificatePath, cfg.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		primary = local
+	}
+
+	signingBackend = primary
+
+	if fallbackKeyPath := cfg.FallbackPrivateKeyPath; fallbackKeyPath != "" {
+		fallbackCertPath := cfg.FallbackCertificatePath
+		if fallbackCertPath == "" {
+			fallbackCertPath = cfg.CertificatePath
+		}
+
+		fallback, err := loadLocalBackend("local-fallback", fallbackCertPath, fallbackKeyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		signingBackend = backend.NewFailover(primary, fallback, cfg.CircuitFailureThreshold, cfg.CircuitCooldown)
+	}
+
+	if queueSize := cfg.QueueSize; queueSize > 0 {
+		signingBackend = backend.NewQueue(signingBackend, queueSize, cfg.QueueRetryInterval, cfg.QueueMaxWait)
+	}
+
+	return signingBackend, nil
+}
+
+// newVaultBackend returns the backend delegating the issuance to the Vault PKI secrets engine, nil when not configured.
+func newVaultBackend(ctx context.Context, cfg config.Vault) (backend.Backend, error) {
+	if cfg.Address == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+
+	if caPath := cfg.CertificatePath; caPath != "" {
+		caPEM, err := os.ReadFile(caPath)
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read the Vault CA: "+err.Error())
+		}
+
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(caPEM) {
+			return nil, errors.Wrap(pkgerrors.ErrPemDecoding, "Vault CA")
+		}
+
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	}
+
+	vault, err := backend.NewVault(ctx, backend.VaultOptions{
+		Address:   cfg.Address,
+		Mount:     cfg.Mount,
+		Role:      cfg.Role,
+		Token:     cfg.Token,
+		TokenPath: cfg.TokenPath,
+		Namespace: cfg.Namespace,
+		Client:    &http.Client{Transport: transport, Timeout: cfg.Timeout},
+	})
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	logging.FromContext(ctx).Info("Signing the certificates with the Vault PKI secrets engine", "mount", cfg.Mount,
+		"address", cfg.Address)
+
+	return vault, nil
+}
+
+// newKMSBackend returns the local backend signing with the AWS KMS key in place of the CA private key, along with the
+// CA certificate, nil when not configured.
+func newKMSBackend(ctx context.Context, cfg config.KMS, caCertPath string) (backend.Backend, error) {
+	if cfg.KeyARN == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	caCertPEM, err := os.ReadFile(caCertPath)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read CA certificate: "+err.Error())
+	}
+
+	client := &http.Client{Timeout: cfg.Timeout}
+
+	signer, err := kms.New(ctx, kms.Options{
+		KeyID:    cfg.KeyARN,
+		Region:   cfg.Region,
+		Endpoint: cfg.Endpoint,
+		Timeout:  cfg.Timeout,
+		Client:   client,
+		Credentials: &kms.CredentialsProvider{
+			Region:  cfg.Region,
+			Client:  client,
+			File:    cfg.CredentialsPath,
+			Profile: cfg.Profile,
+		},
+	})
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	local, err := backend.NewLocal("kms", caCertPEM, signer)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if key, ok := local.Certificate().PublicKey.(interface{ Equal(x crypto.PublicKey) bool }); !ok || !key.Equal(signer.Public()) {
+		return nil, errors.Wrap(pkgerrors.ErrKMS, "the KMS key "+cfg.KeyARN+" does not match the CA certificate")
+	}
+
+	logging.FromContext(ctx).Info("Signing the certificates with the AWS KMS key", "key", cfg.KeyARN)
+
+	return local, nil
+}
+
+// newUpstreamBackend returns the backend forwarding the CSRs to the upstream signer over mutual TLS, its serving
+// certificate being verified with the CA certificate unless other CAs are configured.
+func newUpstreamBackend(ctx context.Context, cfg config.Upstream, caCertPath string) (*backend.Upstream, error) {
+	caCertPEM, err := os.ReadFile(caCertPath)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read CA certificate: "+err.Error())
+	}
+
+	rootsPEM := caCertPEM
+
+	if caPath := cfg.CAPath; caPath != "" {
+		if rootsPEM, err = os.ReadFile(caPath); err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read the upstream CA: "+err.Error())
+		}
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(rootsPEM) {
+		return nil, errors.Wrap(pkgerrors.ErrPemDecoding, "upstream CA")
+	}
+
+	tlsConfig := &tls.Config{RootCAs: roots, ServerName: cfg.ServerName, MinVersion: tls.VersionTLS12}
+
+	if cfg.TLSCertificatePath != "" {
+		cert, certErr := loadX509KeyPair(cfg.TLSCertificatePath, cfg.TLSPrivateKeyPath)
+		if certErr != nil {
+			return nil, errors.Wrap(pkgerrors.ErrLoadingCertificate, "upstream client certificate: "+certErr.Error())
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	upstream, err := backend.NewUpstream(backend.UpstreamOptions{
+		Endpoint:  cfg.Endpoint,
+		Token:     cfg.Token,
+		TLSConfig: tlsConfig,
+		CACertPEM: caCertPEM,
+	})
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	logging.FromContext(ctx).Info("Forwarding the validated CSRs to the upstream signer", "endpoint", cfg.Endpoint)
+
+	return upstream, nil
+}
+
+// newHybridBackend returns the backend adding the ML-DSA signature of the hybrid key to the certificates of the given
+// one, the given one itself when not configured.
+func newHybridBackend(ctx context.Context, cfg config.CA, gates *features.Gates, signingBackend backend.Backend) (backend.Backend, error) {
+	if cfg.HybridKeyPath == "" {
+		return signingBackend, nil
+	}
+
+	if !gates.Enabled(features.HybridSigning) {
+		return nil, errors.Wrap(pkgerrors.ErrConfig, config.KeyCAHybridKeyPath+" requires the "+string(features.HybridSigning)+" feature gate")
+	}
+
+	altKey, err := loadPrivateKey(cfg.HybridKeyPath)
+	if err != nil {
+		return nil, errors.Wrap(err, config.KeyCAHybridKeyPath)
+	}
+
+	hybrid, err := backend.NewHybrid(ctx, signingBackend, altKey)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	logging.FromContext(ctx).Warn("EXPERIMENTAL: Adding the post-quantum signature of the hybrid key to the certificates",
+		"path", cfg.HybridKeyPath, "serial", hybrid.Certificate().SerialNumber.Text(16))
+
+	return hybrid, nil
+}
+
+// newServerCredentials returns the TLS credentials of the gRPC server, verifying the client certificates
+// when presented and a client CA is configured.
+func newServerCredentials(cfg config.Server) (credentials.TransportCredentials, error) {
+	cert, err := loadX509KeyPair(cfg.TLSCertificatePath, cfg.TLSPrivateKeyPath)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrLoadingCertificate, err.Error())
+	}
+	// Create TLS credentials
+	tlsConfig := &tls.Config{ //nolint:gosec
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.NoClientCert, // Don't require client certificates
+	}
+	// Verify the client certificates when presented, attaching their subject to logs and ledger records
+	if clientCAPath := cfg.ClientCAPath; clientCAPath != "" {
+		clientCAPEM, clientCAErr := os.ReadFile(clientCAPath)
+		if clientCAErr != nil {
+			return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read client CA: "+clientCAErr.Error())
+		}
+
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(clientCAPEM) {
+			return nil, errors.Wrap(pkgerrors.ErrPemDecoding, "client CA")
+		}
+
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// newServer returns the gRPC server issuing the certificates with the signing backend, recording them in the ledger,
+// with the tokens, the signing policy, the profiles, and the trust bundle of the configuration.
+// The plugins replace the tokens with their authenticator, and extend the signing policy with their validators.
+func newServer(ctx context.Context, cfg *config.Config, signingBackend backend.Backend, issuanceLedger ledger.Ledger, plugins *loadedPlugins) (*server.Server, error) {
+	var tokens *token.Source
+
+	// The token classes alone may authenticate the nodes
+	if plugins.authenticator == nil && (cfg.Tokens.ClassesPath == "" || cfg.Tokens.Token != "" || cfg.Tokens.Path != "") {
+		var err error
+		if tokens, err = loadTokens(cfg.Tokens); err != nil {
+			return nil, err
+		}
+
+		if err = tokens.Accept(cfg.Tokens.Previous); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+	}
+
+	signingPolicy, roles, err := newSigningPolicy(cfg, issuanceLedger, plugins.validators)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokenClasses []server.TokenClass
+
+	if classesPath := cfg.Tokens.ClassesPath; classesPath != "" && plugins.authenticator == nil {
+		if tokenClasses, err = loadTokenClasses(classesPath, roles); err != nil {
+			return nil, err
+		}
+
+		logging.FromContext(ctx).Info("Issuing the certificates with the profiles of the token classes", "classes", len(tokenClasses),
+			"path", classesPath)
+	}
+
+	serialFormat, err := pki.ParseSerialFormat(cfg.Issuance.SerialBits, cfg.Issuance.SerialPrefix)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	nodeUUID, err := newNodeUUIDOptions(cfg.Issuance)
+	if err != nil {
+		return nil, err
+	}
+
+	tpmAttestation, err := newTPMAttestationOptions(cfg.Issuance)
+	if err != nil {
+		return nil, err
+	}
+
+	instanceIdentity, err := newInstanceIdentityOptions(cfg.Instance)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &server.Server{
+		Backend:              signingBackend,
+		Tokens:               tokens,
+		TokenClasses:         tokenClasses,
+		Authenticator:        plugins.authenticator,
+		Policy:               signingPolicy,
+		Roles:                roles,
+		SerialFormat:         serialFormat,
+		CAExpiry:             cfg.Issuance.CAExpiry,
+		MaxTTL:               cfg.Issuance.MaxTTL,
+		FingerprintTrailers:  cfg.Issuance.FingerprintTrailers,
+		NodeUUID:             nodeUUID,
+		TPMAttestation:       tpmAttestation,
+		InstanceIdentity:     instanceIdentity,
+		TransparencyRequired: cfg.Issuance.TransparencyRequired,
+		Ledger:               issuanceLedger,
+		RetryCacheTTL:        cfg.Issuance.RetryCacheTTL,
+		IssuanceQuota:        cfg.Issuance.Quota,
+		QuotaWindow:          cfg.Issuance.QuotaWindow,
+		ReissueCooldown:      cfg.Issuance.ReissueCooldown,
+		ProofOfPossessionTTL: cfg.Issuance.ProofOfPossessionTTL,
+	}
+
+	if logURL := cfg.Issuance.TransparencyLogURL; logURL != "" {
+		srv.Transparency = transparency.NewRekor(logURL, cfg.Issuance.TransparencyTimeout)
+
+		logging.FromContext(ctx).Info("Publishing the issued certificates to the transparency log", "url", logURL)
+	}
+
+	// Identities never issued a certificate, reloaded while serving
+	if denyListPath := cfg.Policy.DenyListPath; denyListPath != "" {
+		if srv.DenyList, err = denylist.Load(denyListPath); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+
+		logging.FromContext(ctx).Info("Refusing the identities of the deny-list", "path", denyListPath)
+	}
+
+	// Dual-trust mode, returning the CA certificates of a rotation along with the signing one
+	if bundlePath := cfg.CA.BundlePath; bundlePath != "" {
+		bundle, bundleErr := os.ReadFile(bundlePath)
+		if bundleErr != nil {
+			return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read CA bundle: "+bundleErr.Error())
+		}
+
+		srv.TrustBundle = bundle
+
+		logging.FromContext(ctx).Info("Returning the CA bundle along with the signing CA", "path", bundlePath)
+	}
+
+	// Additional roots the nodes should trust, returned after the bundle
+	for _, rootsPath := range cfg.CA.ExtraRootsPaths {
+		rootsPEM, rootsErr := os.ReadFile(rootsPath)
+		if rootsErr != nil {
+			return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read the extra roots: "+rootsErr.Error())
+		}
+
+		roots, rootsErr := pki.ParseCertificates(rootsPEM)
+		if rootsErr != nil {
+			return nil, errors.Wrap(rootsErr, "extra roots "+rootsPath)
+		}
+
+		srv.ExtraRoots = pki.MergeBundles(srv.ExtraRoots, pki.EncodeCertificates(roots...))
+
+		logging.FromContext(ctx).Info("Returning the extra roots along with the signing CA", "roots", len(roots), "path", rootsPath)
+	}
+
+	// Intermediate signing CA, returned to the nodes along with its chain up to the root
+	if chainPath := cfg.CA.ChainPath; chainPath != "" {
+		chainPEM, chainErr := os.ReadFile(chainPath)
+		if chainErr != nil {
+			return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read CA chain: "+chainErr.Error())
+		}
+
+		chain, chainErr := pki.ParseCertificates(chainPEM)
+		if chainErr != nil {
+			return nil, errors.Wrap(chainErr, "CA chain "+chainPath)
+		}
+
+		if chainErr = pki.VerifyChain(signingBackend.Certificate(), chain); chainErr != nil {
+			return nil, chainErr //nolint:wrapcheck
+		}
+
+		srv.CAChain = pki.EncodeCertificates(chain...)
+
+		logging.FromContext(ctx).Info("Signing with the intermediate CA, returned along with the chain",
+			"subject", signingBackend.Certificate().Subject.String(), "path", chainPath)
+	}
+
+	return srv, nil
+}
+
+// newSigningPolicy returns the signing policy of the configuration followed by the given validators, along with the
+// machine roles whose profiles the certificates are issued with.
+func newSigningPolicy(cfg *config.Config, issuanceLedger ledger.Ledger, validators []policy.Validator) (policy.Chain, *signer.Roles, error) {
+	signingPolicy, err := newPolicy(cfg.Policy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	roles, err := newRoles(cfg.Roles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The subordinate CA certificates are only issued when explicitly enabled
+	for name, profile := range roles.Profiles {
+		if profile.CA && !cfg.Issuance.SubordinateCA {
+			return nil, nil, errors.Wrap(pkgerrors.ErrProfile, "the profile "+name+" issues CA certificates, which requires "+config.KeySubordinateCA+" to be enabled")
+		}
+	}
+
+	// The organizations left out of the allowed ones are stripped when signing, rather than refused by the policy
+	if cfg.Policy.OrganizationAction == policy.OrganizationsStrip {
+		roles.ControlPlane.Organizations = cfg.Policy.Organizations
+		roles.Worker.Organizations = cfg.Policy.Organizations
+
+		for name, profile := range roles.Profiles {
+			if len(profile.Organizations) == 0 {
+				profile.Organizations = cfg.Policy.Organizations
+				roles.Profiles[name] = profile
+			}
+		}
+	}
+
+	// The subject rules of the policy apply to the profiles without their own
+	subjectRules, err := newSubjectRules(cfg.Policy.SubjectOrganizations, cfg.Policy.SubjectDrop,
+		cfg.Policy.SubjectCommonNameTemplate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	roles.ControlPlane.Subject, roles.Worker.Subject = subjectRules, subjectRules
+
+	for name, profile := range roles.Profiles {
+		if profile.Subject.Empty() {
+			profile.Subject = subjectRules
+			roles.Profiles[name] = profile
+		}
+	}
+
+	extensions, err := newExtensions(cfg.Issuance)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	extraSANs, nodeSANs, err := newExtraSANs(cfg.Issuance)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The wildcard DNS names, local IP addresses, email addresses, URIs, extensions, and extra SANs are handled alike
+	// by every profile, the named ones replacing the extensions of the same OID
+	stripWildcards := cfg.Policy.Wildcards == policy.WildcardsStrip
+	passEmailAddresses, passURIs := cfg.Policy.EmailAction == policy.SANsPass, cfg.Policy.URIAction == policy.SANsPass
+
+	roles.ControlPlane.StripWildcards, roles.ControlPlane.ExtraExtensions = stripWildcards, extensions
+	roles.ControlPlane.PassEmailAddresses, roles.ControlPlane.PassURIs = passEmailAddresses, passURIs
+	roles.Worker.StripWildcards, roles.Worker.ExtraExtensions = stripWildcards, extensions
+	roles.Worker.PassEmailAddresses, roles.Worker.PassURIs = passEmailAddresses, passURIs
+	roles.ControlPlane.ExtraSANs, roles.ControlPlane.NodeSANs = extraSANs, nodeSANs
+	roles.Worker.ExtraSANs, roles.Worker.NodeSANs = extraSANs, nodeSANs
+	roles.ControlPlane.StripLocalIPs, roles.Worker.StripLocalIPs = cfg.Policy.StripLocalIPs, cfg.Policy.StripLocalIPs
+
+	// The extended key usages requested by the CSRs replace the profile ones but the CA ones, when allowed by the policy
+	var requestableExtKeyUsages []x509.ExtKeyUsage
+	if len(cfg.Policy.ExtKeyUsages) > 0 {
+		if requestableExtKeyUsages, err = signer.ExtKeyUsages(cfg.Policy.ExtKeyUsages); err != nil {
+			return nil, nil, err //nolint:wrapcheck
+		}
+	}
+
+	roles.ControlPlane.RequestableExtKeyUsages, roles.Worker.RequestableExtKeyUsages = requestableExtKeyUsages, requestableExtKeyUsages
+
+	for name, profile := range roles.Profiles {
+		if !profile.CA {
+			profile.RequestableExtKeyUsages = requestableExtKeyUsages
+		}
+
+		profile.StripWildcards, profile.ExtraExtensions = stripWildcards, mergeExtensions(extensions, profile.ExtraExtensions)
+		profile.PassEmailAddresses, profile.PassURIs = passEmailAddresses, passURIs
+		profile.ExtraSANs, profile.NodeSANs = extraSANs, nodeSANs
+		profile.StripLocalIPs = cfg.Policy.StripLocalIPs
+		roles.Profiles[name] = profile
+	}
+
+	// The SPIFFE ID applies to the profiles without their own, but the CA ones
+	if text := cfg.Issuance.SPIFFEIDTemplate; text != "" {
+		if roles.ControlPlane.SPIFFEID, err = newSPIFFEID(text, roles.ControlPlane); err != nil {
+			return nil, nil, errors.Wrap(err, "control-plane profile")
+		}
+
+		if roles.Worker.SPIFFEID, err = newSPIFFEID(text, roles.Worker); err != nil {
+			return nil, nil, errors.Wrap(err, "worker profile")
+		}
+
+		for name, profile := range roles.Profiles {
+			if profile.SPIFFEID != nil || profile.CA {
+				continue
+			}
+
+			if profile.SPIFFEID, err = newSPIFFEID(text, profile); err != nil {
+				return nil, nil, errors.Wrap(err, "profile "+name)
+			}
+
+			roles.Profiles[name] = profile
+		}
+	}
+
+	if err = checkVerbatimSigning(cfg, roles); err != nil {
+		return nil, nil, err
+	}
+
+	signingPolicy = signingPolicy.Then(policy.ProfileKeyPolicy{Roles: roles})
+
+	if cfg.Tokens.ClassesPath != "" {
+		signingPolicy = signingPolicy.Then(policy.TokenIdentityPolicy{})
+	}
+
+	if opaURL := cfg.Policy.OPAURL; opaURL != "" {
+		signingPolicy = signingPolicy.Then(policy.NewOPA(opaURL, cfg.Policy.OPATimeout))
+	}
+
+	dnsVerification, err := newDNSVerification(cfg.Policy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if dnsVerification != nil {
+		signingPolicy = signingPolicy.Then(dnsVerification)
+	}
+
+	enrollmentWindow, err := newEnrollmentWindow(cfg.Policy, issuanceLedger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if enrollmentWindow != nil {
+		signingPolicy = signingPolicy.Then(enrollmentWindow)
+	}
+
+	// The simulated validators only report their rejections, the plugins included
+	signingPolicy, err = signingPolicy.Then(validators...).Simulate(cfg.Policy.Simulate)
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck
+	}
+
+	return signingPolicy, roles, nil
+}
+
+// newTPMAttestationOptions returns the verification of the TPM quotes sent by the clients.
+func newTPMAttestationOptions(cfg config.Issuance) (server.TPMAttestationOptions, error) {
+	var opts server.TPMAttestationOptions
+
+	switch cfg.TPMAttestation {
+	case "", "disabled":
+		return opts, nil
+	case "optional":
+	case "required":
+		opts.Required = true
+	default:
+		return opts, errors.Wrap(pkgerrors.ErrConfig, "TPM attestation must be disabled, optional, or required: "+cfg.TPMAttestation)
+	}
+
+	if cfg.TPMEndorsementRoots == "" {
+		return opts, errors.Wrap(pkgerrors.ErrMissingPath, "TPM endorsement roots path is missing")
+	}
+
+	endorsementPEM, err := os.ReadFile(cfg.TPMEndorsementRoots)
+	if err != nil {
+		return opts, errors.Wrap(pkgerrors.ErrReadFile, "failed to read the TPM endorsement roots: "+err.Error())
+	}
+
+	if opts.Verifier, err = tpm.NewVerifier(endorsementPEM); err != nil {
+		return opts, err //nolint:wrapcheck
+	}
+
+	return opts, nil
+}
+
+// newApprovalQueue returns the queue holding the privileged certificates until approved, nil when no organization
+// is privileged.
+func newApprovalQueue(cfg config.Approval, issuanceLedger ledger.Ledger) (*approval.Queue, error) {
+	if len(cfg.Organizations) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	if cfg.ApproversPath == "" {
+		return nil, errors.Wrap(pkgerrors.ErrMissingPath, "approvers path is missing")
+	}
+
+	data, err := os.ReadFile(cfg.ApproversPath)
+	if err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrReadFile, "failed to read the approvers: "+err.Error())
+	}
+
+	approvers, err := approval.ParseApprovers(data)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	queue := &approval.Queue{
+		Ledger:        issuanceLedger,
+		Organizations: cfg.Organizations,
+		Approvers:     approvers,
+		Threshold:     cfg.Threshold,
+		TTL:           cfg.TTL,
+	}
+
+	if err = queue.Validate(); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return queue, nil
+}
+
+// newInstanceIdentityOptions returns the verification of the cloud instance identity documents sent by the clients.
+func newInstanceIdentityOptions(cfg config.InstanceIdentity) (server.InstanceIdentityOptions, error) {
+	var opts server.InstanceIdentityOptions
+
+	switch cfg.Mode {
+	case "", "disabled":
+		return opts, nil
+	case "optional":
+	case "required":
+		opts.Required = true
+	default:
+		return opts, errors.Wrap(pkgerrors.ErrConfig, "instance identity must be disabled, optional, or required: "+cfg.Mode)
+	}
+
+	var awsCertificates []byte
+
+	if path := cfg.AWSCertsPath; path != "" {
+		var err error
+		if awsCertificates, err = os.ReadFile(path); err != nil {
+			return opts, errors.Wrap(pkgerrors.ErrReadFile, "failed to read the AWS certificates: "+err.Error())
+		}
+	}
+
+	verifier, err := identity.NewVerifier(identity.Options{
+		Accounts:        cfg.Accounts,
+		AWSCertificates: awsCertificates,
+		AWSMaxAge:       cfg.AWSMaxAge,
+		Audience:        cfg.Audience,
+		GCPKeysURL:      cfg.GCPKeysURL,
+		AzureKeysURL:    cfg.AzureKeysURL,
+		AzureTenants:    cfg.AzureTenants,
+	})
+	if err != nil {
+		return opts, err //nolint:wrapcheck
+	}
+
+	opts.Verifier = verifier
+
+	return opts, nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	"github.com/clastix/talos-csr-signer/pkg/sops"
+)
+
+// decryptFile returns the data of the file, decrypted with the keys of the settings when encrypted by sops or age.
+func decryptFile(path string, data []byte) ([]byte, error) {
+	if !sops.IsEncrypted(data) {
+		return data, nil
+	}
+
+	keys, err := config.DecryptionKeys(viper.GetViper())
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	plaintext, err := sops.Decrypt(context.Background(), data, keys)
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+
+	return plaintext, nil
+}
+
+// loadX509KeyPair is tls.LoadX509KeyPair, the private key being decrypted when encrypted by sops or age.
+func loadX509KeyPair(certPath, keyPath string) (tls.Certificate, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return tls.Certificate{}, err //nolint:wrapcheck
+	}
+
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return tls.Certificate{}, err //nolint:wrapcheck
+	}
+
+	if keyPEM, err = decryptFile(keyPath, keyPEM); err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.X509KeyPair(certPEM, keyPEM) //nolint:wrapcheck
+}
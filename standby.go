@@ -0,0 +1,49 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+
+	"github.com/clastix/talos-csr-signer/pkg/admin"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/standby"
+)
+
+// standbyHandler serves the replication state of the standby.
+func standbyHandler(replica *standby.Replica) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, replica.Status())
+	}
+}
+
+// promoteHandler promotes the standby, stopping the replication and serving the certificate requests.
+func promoteHandler(replica *standby.Replica) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !replica.Promote() {
+			admin.WriteError(w, http.StatusConflict, pkgerrors.ErrPromoted)
+
+			return
+		}
+
+		logging.FromContext(r.Context()).Info("Promoted to primary, no longer replicating", "primary", replica.PrimaryURL)
+
+		admin.WriteJSON(w, http.StatusOK, replica.Status())
+	}
+}
+
+// readOnly refuses the requests changing the state while the replica is a standby, its ledger being replaced by the
+// one of the primary at the next replication.
+func readOnly(replica *standby.Replica, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if replica.Active() {
+			admin.WriteError(w, http.StatusServiceUnavailable, pkgerrors.ErrStandbyReadOnly)
+
+			return
+		}
+
+		handler(w, r)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/clastix/talos-csr-signer/pkg/backend"
+	"github.com/clastix/talos-csr-signer/pkg/config"
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/events"
+	"github.com/clastix/talos-csr-signer/pkg/ledger"
+	"github.com/clastix/talos-csr-signer/pkg/logging"
+	"github.com/clastix/talos-csr-signer/pkg/pki"
+	"github.com/clastix/talos-csr-signer/pkg/server"
+	"github.com/clastix/talos-csr-signer/pkg/shard"
+)
+
+// tenantFiles are the files of the directory of a tenant cluster: its CA, and optionally its Talos tokens.
+var tenantFiles = []string{"ca.crt", "ca.key", "tls.crt", "tls.key", "token"}
+
+// tenant is the server of a tenant cluster, along with the digest of the files it was built from.
+type tenant struct {
+	digest [sha256.Size]byte
+	srv    *server.Server
+}
+
+// tenantCAs is the directory of the CAs of the tenant clusters, one subdirectory per cluster ID holding its ca.crt
+// and ca.key (or tls.crt and tls.key) files, and optionally its token file, such as the Secrets of the Kamaji tenants
+// projected into a single volume. The requests are routed to the server of their cluster by the router. With a
+// sharder, only the tenant clusters assigned to the local replica are loaded.
+type tenantCAs struct {
+	path    string
+	cfg     *config.Config
+	base    *server.Server
+	plugins *loadedPlugins
+	sharder *shard.Sharder
+	router  *server.Router
+	tenants map[string]tenant
+}
+
+// newTenantCAs loads the tenant clusters of the CA directory, nil when not configured. Their servers share the
+// settings, the ledger, the validators, and the events of the top level one, which serves the requests of no known
+// cluster, and share its tokens unless their directory holds a token file.
+func newTenantCAs(ctx context.Context, cfg *config.Config, base *server.Server, plugins *loadedPlugins, sharder *shard.Sharder) (*tenantCAs, error) {
+	if cfg.CA.Dir == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	t := &tenantCAs{
+		path:    cfg.CA.Dir,
+		cfg:     cfg,
+		base:    base,
+		plugins: plugins,
+		sharder: sharder,
+		router:  &server.Router{Default: base, Sharder: sharder},
+		tenants: map[string]tenant{},
+	}
+
+	if err := t.reload(ctx, true); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Routing the requests of the tenant clusters of the CA directory", "tenants", len(t.tenants),
+		"dir", t.path)
+
+	return t, nil
+}
+
+// newSharder returns the Sharder of the tenant clusters across the replicas sharing the ledger, nil when not
+// configured, once its first heartbeat was sent, so the tenants of the other replicas are not loaded at startup.
+func newSharder(ctx context.Context, cfg *config.Config, l ledger.Ledger) (*shard.Sharder, error) {
+	if !cfg.CA.DirSharding {
+		return nil, nil //nolint:nilnil
+	}
+
+	self := cfg.CA.DirShardID
+	if self == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, errors.Wrap(pkgerrors.ErrConfig, "failed to get the hostname identifying the shard: "+err.Error())
+		}
+
+		self = hostname
+	}
+
+	sharder := shard.New(l, self, cfg.CA.DirShardHeartbeat)
+	sharder.Refresh(ctx)
+
+	go sharder.Run(ctx)
+
+	logging.FromContext(ctx).Info("Sharding the tenant clusters of the CA directory", "replica", self)
+
+	return sharder, nil
+}
+
+// refresh reads the CA directory again at every interval, if any, and when the shard membership changed, until the
+// context is done, adding, replacing, and removing the tenant clusters. A tenant failing to load keeps its previous
+// server, if any.
+func (t *tenantCAs) refresh(ctx context.Context, interval time.Duration) {
+	var tick <-chan time.Time
+
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-t.sharder.Changed():
+		}
+
+		if err := t.reload(ctx, false); err != nil {
+			logging.FromContext(ctx).Warn("Failed to read the CA directory, keeping the previous tenants", "dir", t.path, "error", err)
+		}
+	}
+}
+
+// reload replaces the tenant clusters with the ones of the CA directory, failing on the first tenant failing to
+// load when strict, otherwise keeping its previous server.
+func (t *tenantCAs) reload(ctx context.Context, strict bool) error {
+	entries, err := os.ReadDir(t.path)
+	if err != nil {
+		return errors.Wrap(pkgerrors.ErrReadFile, "failed to read the CA directory: "+err.Error())
+	}
+
+	tenants := make(map[string]tenant, len(entries))
+	servers := make(map[string]*server.Server, len(entries))
+
+	for _, entry := range entries {
+		id := entry.Name()
+		// The projected volumes hold their data in hidden directories, the tenant ones being symbolic links to them
+		if strings.HasPrefix(id, ".") {
+			continue
+		}
+
+		if info, statErr := os.Stat(filepath.Join(t.path, id)); statErr != nil || !info.IsDir() {
+			continue
+		}
+
+		// The tenants of the other replicas are routed with no server, their CA not being loaded
+		if !t.sharder.Owns(id) {
+			servers[id] = nil
+
+			if _, found := t.tenants[id]; found {
+				logging.FromContext(ctx).Info("Released the tenant cluster", "cluster", id, "replica", t.sharder.Owner(id))
+			}
+
+			continue
+		}
+
+		loaded, loadErr := t.load(ctx, id)
+
+		switch {
+		case loadErr != nil && strict:
+			return loadErr
+		case loadErr != nil:
+			logging.FromContext(ctx).Warn("Failed to load the tenant cluster, keeping the previous one, if any", "cluster", id,
+				"error", loadErr)
+
+			if previous, found := t.tenants[id]; found {
+				tenants[id] = previous
+			}
+		default:
+			tenants[id] = loaded
+		}
+	}
+
+	for id := range t.tenants {
+		_, loaded := tenants[id]
+		if _, released := servers[id]; !loaded && !released {
+			logging.FromContext(ctx).Info("Removed the tenant cluster", "cluster", id)
+		}
+	}
+
+	t.tenants = tenants
+
+	for id, loaded := range tenants {
+		servers[id] = loaded.srv
+	}
+
+	t.router.Update(servers)
+
+	return nil
+}
+
+// load returns the server of the tenant cluster, the previous one when its files are unchanged.
+func (t *tenantCAs) load(ctx context.Context, id string) (tenant, error) {
+	dir := filepath.Join(t.path, id)
+	files := make(map[string][]byte, len(tenantFiles))
+	hash := sha256.New()
+
+	for _, name := range tenantFiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil && !os.IsNotExist(err) {
+			return tenant{}, errors.Wrap(pkgerrors.ErrReadFile, err.Error())
+		}
+
+		files[name] = data
+		hash.Write([]byte(name))
+		hash.Write(data)
+		hash.Write([]byte{0})
+	}
+
+	var digest [sha256.Size]byte
+
+	copy(digest[:], hash.Sum(nil))
+
+	previous, found := t.tenants[id]
+	if found && previous.digest == digest {
+		return previous, nil
+	}
+
+	local, caPrivateKey, err := parseCAKeys(files, pkgerrors.ErrReadFile, "CA directory "+dir)
+	if err != nil {
+		return tenant{}, err
+	}
+
+	// Name the backend after the tenant, telling its certificates apart in the shared ledger
+	if local, err = backend.NewLocal("tenant/"+id, pki.EncodeCertificates(local.Certificate()), caPrivateKey); err != nil {
+		return tenant{}, err //nolint:wrapcheck
+	}
+
+	cfg := *t.cfg
+	cfg.CA.BundlePath, cfg.CA.ChainPath = "", ""
+
+	plugins := &loadedPlugins{authenticator: t.plugins.authenticator, validators: t.plugins.validators}
+	if len(files["token"]) > 0 {
+		cfg.Tokens = config.Tokens{Path: filepath.Join(dir, "token")}
+		plugins.authenticator = nil
+	}
+
+	srv, err := newServer(ctx, &cfg, local, t.base.Ledger, plugins)
+	if err != nil {
+		return tenant{}, errors.Wrap(err, "tenant cluster "+id)
+	}
+
+	if len(files["token"]) == 0 {
+		srv.Tokens, srv.Authenticator = t.base.Tokens, t.base.Authenticator
+	}
+
+	srv.Features = t.base.Features
+	srv.Events = t.base.Events
+	srv.Clock = t.base.Clock
+	srv.Watchdog = t.base.Watchdog
+	srv.Logger = logging.FromContext(ctx).With("cluster", id)
+
+	serial := local.Certificate().SerialNumber.Text(16)
+
+	if found {
+		srv.Logger.Info("Reloaded the tenant cluster", "serial", serial)
+		srv.Events.Emit(events.Event{Type: events.TypeCAReloaded, Serial: serial, Backend: local.Name()})
+	} else {
+		srv.Logger.Info("Loaded the tenant cluster", "serial", serial)
+	}
+
+	return tenant{digest: digest, srv: srv}, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 Clastix Labs
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net"
+	"path"
+	"slices"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	pkgerrors "github.com/clastix/talos-csr-signer/pkg/errors"
+	"github.com/clastix/talos-csr-signer/pkg/policy"
+	"github.com/clastix/talos-csr-signer/pkg/server"
+	"github.com/clastix/talos-csr-signer/pkg/signer"
+	"github.com/clastix/talos-csr-signer/pkg/token"
+)
+
+// tokenClassSettings are the settings of the token classes.
+var tokenClassSettings = []string{
+	"token", "token-path", "profile", "common-name", "dns-names", "ip-ranges", "email-addresses", "uris",
+}
+
+// loadTokenClasses returns the token classes of the YAML file, keyed by their lowercase name, such as:
+//
+//	controlplane:
+//	  token-path: /etc/talos-tokens/controlplane
+//	  profile: controlplane
+//	worker:
+//	  token: u6uqzx.tyjgn2livk54o170
+//	  profile: worker
+//	tooling:
+//	  token-path: /etc/talos-tokens/tooling
+//	  profile: client
+//	worker-1:
+//	  token-path: /etc/talos-tokens/worker-1
+//	  profile: worker
+//	  common-name: worker-1
+//	  dns-names: [worker-1, "*.worker-1.nodes.example.com"]
+//	  ip-ranges: [10.0.0.21/32]
+//
+// The tokens are either a single one, or the file of the TALOS_TOKEN_PATH format reloaded when modified. The profile
+// is the machine role, or one of the named profiles. The common name, the DNS names, the IP ranges, the email
+// addresses, and the URIs bind the tokens to the identity of a node.
+func loadTokenClasses(path string, roles *signer.Roles) ([]server.TokenClass, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, errors.Wrap(pkgerrors.ErrToken, "failed to read the token classes file "+path+": "+err.Error())
+	}
+
+	names := make([]string, 0, len(v.AllSettings()))
+	for name := range v.AllSettings() {
+		names = append(names, name)
+	}
+
+	// The classes are checked in a stable order, reported by the logs
+	sort.Strings(names)
+
+	classes := make([]server.TokenClass, 0, len(names))
+
+	for _, name := range names {
+		settings := v.Sub(name)
+		if settings == nil {
+			return nil, errors.Wrap(pkgerrors.ErrToken, "the token class "+name+" holds no settings")
+		}
+
+		class, err := newTokenClass(name, settings, roles)
+		if err != nil {
+			return nil, errors.Wrap(err, "token class "+name)
+		}
+
+		classes = append(classes, class)
+	}
+
+	return classes, nil
+}
+
+// newTokenClass returns the token class of the settings.
+func newTokenClass(name string, settings *viper.Viper, roles *signer.Roles) (server.TokenClass, error) {
+	for _, key := range settings.AllKeys() {
+		if !slices.Contains(tokenClassSettings, key) {
+			return server.TokenClass{}, errors.Wrap(pkgerrors.ErrToken, "unknown setting "+key)
+		}
+	}
+
+	class := server.TokenClass{Name: name, Profile: settings.GetString("profile")}
+
+	if class.Profile == "" {
+		return server.TokenClass{}, errors.Wrap(pkgerrors.ErrToken, "the profile is missing")
+	}
+
+	if _, found := roles.Named(class.Profile); class.Role() == "" && !found {
+		return server.TokenClass{}, errors.Wrap(pkgerrors.ErrToken, "unknown profile "+class.Profile+
+			", expected controlplane, worker, or a named profile")
+	}
+
+	var err error
+
+	switch tokenValue, tokenPath := settings.GetString("token"), settings.GetString("token-path"); {
+	case (tokenValue == "") == (tokenPath == ""):
+		return server.TokenClass{}, errors.Wrap(pkgerrors.ErrToken, "either the token or the token path is required")
+	case tokenPath != "":
+		class.Tokens, err = token.NewFile(tokenPath)
+	default:
+		class.Tokens, err = token.NewStatic(tokenValue)
+	}
+
+	if err != nil {
+		return server.TokenClass{}, err //nolint:wrapcheck
+	}
+
+	if class.Identity, err = newTokenIdentity(name, settings); err != nil {
+		return server.TokenClass{}, err
+	}
+
+	return class, nil
+}
+
+// newTokenIdentity returns the node identity the tokens of the class are bound to.
+func newTokenIdentity(name string, settings *viper.Viper) (policy.TokenIdentity, error) {
+	identity := policy.TokenIdentity{
+		Class:         name,
+		CommonName:    settings.GetString("common-name"),
+		DNSPatterns:   settings.GetStringSlice("dns-names"),
+		EmailPatterns: settings.GetStringSlice("email-addresses"),
+		URIPatterns:   settings.GetStringSlice("uris"),
+	}
+
+	for _, pattern := range slices.Concat(identity.DNSPatterns, identity.EmailPatterns, identity.URIPatterns) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return policy.TokenIdentity{}, errors.Wrap(pkgerrors.ErrToken, "invalid pattern "+pattern)
+		}
+	}
+
+	for _, cidr := range settings.GetStringSlice("ip-ranges") {
+		_, ipRange, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return policy.TokenIdentity{}, errors.Wrap(pkgerrors.ErrToken, "invalid IP range "+cidr)
+		}
+
+		identity.IPRanges = append(identity.IPRanges, ipRange)
+	}
+
+	return identity, nil
+}